@@ -0,0 +1,388 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Agg describes an aggregate of an attribute, which Summarize computes for
+// each group of tuples.  The result is the attribute named by As.
+type Agg struct {
+	// fn is the aggregate function: COUNT, SUM, AVG, MIN, MAX, or
+	// PERCENTILE
+	fn  string
+	att string
+
+	// p is the fraction of a percentile, disc makes it discrete instead of
+	// interpolated, and approx allows an approximation
+	p      float64
+	disc   bool
+	approx bool
+
+	As string
+}
+
+// Count counts the tuples of each group
+func Count(as string) Agg {
+	return Agg{fn: "COUNT", As: as}
+}
+
+// Sum adds up the values of the attribute in each group
+func Sum(att, as string) Agg {
+	return Agg{fn: "SUM", att: att, As: as}
+}
+
+// Avg is the mean of the values of the attribute in each group
+func Avg(att, as string) Agg {
+	return Agg{fn: "AVG", att: att, As: as}
+}
+
+// Min is the least value of the attribute in each group
+func Min(att, as string) Agg {
+	return Agg{fn: "MIN", att: att, As: as}
+}
+
+// Max is the greatest value of the attribute in each group
+func Max(att, as string) Agg {
+	return Agg{fn: "MAX", att: att, As: as}
+}
+
+// Percentile is the value below which a fraction p of the values of the
+// attribute fall in each group, interpolated between the two nearest values
+// like PERCENTILE_CONT.
+func Percentile(att string, p float64, as string) Agg {
+	return Agg{fn: "PERCENTILE", att: att, p: p, As: as}
+}
+
+// PercentileDisc is the first value of the attribute in each group whose
+// cumulative distribution is at least p, like PERCENTILE_DISC.
+func PercentileDisc(att string, p float64, as string) Agg {
+	return Agg{fn: "PERCENTILE", att: att, p: p, disc: true, As: as}
+}
+
+// Median is the interpolated 50th percentile of the attribute in each group
+func Median(att, as string) Agg {
+	return Percentile(att, 0.5, as)
+}
+
+// ApproxPercentile is like Percentile, but allows dialects with an
+// approximate percentile, which is much cheaper on large tables, to use it.
+// Other dialects, and client side evaluation, compute it exactly.
+func ApproxPercentile(att string, p float64, as string) Agg {
+	return Agg{fn: "PERCENTILE", att: att, p: p, approx: true, As: as}
+}
+
+// String returns a text representation of the aggregate
+func (a Agg) String() string {
+	switch {
+	case a.fn == "COUNT":
+		return a.As + " := COUNT(*)"
+	case a.fn == "PERCENTILE":
+		return fmt.Sprintf("%s := PERCENTILE(%s, %v)", a.As, a.att, a.p)
+	}
+	return a.As + " := " + a.fn + "(" + a.att + ")"
+}
+
+// percentiler is implemented by dialects that can compute percentiles in
+// aggregate queries.  Percentile returns the aggregate expression, or an
+// empty string if the dialect can't compute that kind of percentile, in which
+// case the aggregation is evaluated client side.
+type percentiler interface {
+	Percentile(col string, p float64, disc, approx bool) string
+}
+
+// percentileString returns the dialect's percentile aggregate, which is the
+// ordered set aggregate of standard sql by default.
+func percentileString(d Dialect, col string, p float64, disc, approx bool) string {
+	if pc, ok := d.(percentiler); ok {
+		return pc.Percentile(col, p, disc, approx)
+	}
+	return orderedPercentile(col, p, disc)
+}
+
+// orderedPercentile returns the PERCENTILE_CONT or PERCENTILE_DISC ordered
+// set aggregate
+func orderedPercentile(col string, p float64, disc bool) string {
+	fn := "PERCENTILE_CONT"
+	if disc {
+		fn = "PERCENTILE_DISC"
+	}
+	return fn + "(" + strconv.FormatFloat(p, 'g', -1, 64) + ") WITHIN GROUP (ORDER BY " + col + ")"
+}
+
+// sql returns the aggregate expression for the dialect, or an empty string if
+// the dialect can't compute it.
+func (a Agg) sql(d Dialect) string {
+	switch a.fn {
+	case "COUNT":
+		return "COUNT(*)"
+	case "PERCENTILE":
+		return percentileString(d, a.att, a.p, a.disc, a.approx)
+	}
+	return a.fn + "(" + a.att + ")"
+}
+
+// Summarize creates a relation with a tuple for each group of the tuples of
+// r, which are grouped by the values of the group attributes, holding the
+// group attributes and the aggregates.  zero is the type of the resulting
+// tuples, which has the group attributes and an attribute for each of the
+// aggregates, named by its As.  With no group attributes, the result has a
+// single tuple which aggregates the whole relation.
+//
+// If r is from this package, and its dialect can compute every one of the
+// aggregates, the relation is compiled into a GROUP BY query.  Otherwise the
+// tuples are read and aggregated client side.
+func Summarize(r rel.Relation, group []string, zero interface{}, aggs ...Agg) rel.Relation {
+	e1, e2 := reflect.TypeOf(r.Zero()), reflect.TypeOf(zero)
+	ckeystr := [][]string{group}
+	if len(group) == 0 {
+		ckeystr = nil
+	}
+	fail := func(err error) rel.Relation {
+		return &sqlTable{zero: zero, cKeys: rel.DefaultKeys(zero), err: err}
+	}
+	if err := checkZero(e2); err != nil {
+		return fail(err)
+	}
+	if err := checkSummary(e1, e2, group, aggs); err != nil {
+		return fail(err)
+	}
+	cKeys := rel.DefaultKeys(zero)
+	if len(group) > 0 {
+		cKeys = rel.String2CandKeys(ckeystr)
+	}
+	atts := append(append([]string{}, group...), aggAttributes(aggs)...)
+	if r1, ok := r.(*sqlTable); ok && r1.err == nil && r1.composable() && !r1.touchesEncrypted(atts...) {
+		pushable := true
+		for _, a := range aggs {
+			pushable = pushable && a.sql(r1.dialect()) != ""
+		}
+		if pushable {
+			return &sqlTable{
+				db:             r1.db,
+				conn:           r1.conn,
+				q:              r1.q,
+				src:            &summarySource{r1, group, aggs},
+				cols:           colNames(zero),
+				zero:           zero,
+				cKeys:          cKeys,
+				sourceDistinct: true,
+				opts:           r1.opts,
+			}
+		}
+	}
+	return &clientOp{
+		op:     "Summarize",
+		str:    summaryString(r, group, aggs),
+		inputs: []rel.Relation{r},
+		zero:   zero,
+		cKeys:  cKeys,
+		run: func(send func(reflect.Value) error) error {
+			return summarize(r, group, e2, aggs, send)
+		},
+	}
+}
+
+// checkSummary returns an error if the attributes of the summary of tuples
+// of type e1 don't match the fields of e2.
+func checkSummary(e1, e2 reflect.Type, group []string, aggs []Agg) error {
+	names := make(map[string]bool)
+	for _, att := range group {
+		f1, ok := e1.FieldByName(att)
+		if !ok {
+			return fmt.Errorf("relsql: group attribute %s is not in the heading of %v", att, e1)
+		}
+		if f2, ok := e2.FieldByName(att); !ok || f2.Type != f1.Type {
+			return fmt.Errorf("relsql: group attribute %s is not in the heading of %v", att, e2)
+		}
+		names[att] = true
+	}
+	for _, a := range aggs {
+		if a.fn != "COUNT" {
+			if _, ok := e1.FieldByName(a.att); !ok {
+				return fmt.Errorf("relsql: aggregated attribute %s is not in the heading of %v", a.att, e1)
+			}
+		}
+		if a.fn == "PERCENTILE" && (a.p < 0 || a.p > 1) {
+			return fmt.Errorf("relsql: percentile %v of %s is not between 0 and 1", a.p, a.att)
+		}
+		if _, ok := e2.FieldByName(a.As); !ok || names[a.As] {
+			return fmt.Errorf("relsql: aggregate %s is not in the heading of %v", a.As, e2)
+		}
+		names[a.As] = true
+	}
+	if len(names) != e2.NumField() {
+		return fmt.Errorf("relsql: %v has attributes which are neither grouped nor aggregated", e2)
+	}
+	return nil
+}
+
+// summaryString returns the text representation of a summary
+func summaryString(r rel.Relation, group []string, aggs []Agg) string {
+	strs := make([]string, len(aggs))
+	for i, a := range aggs {
+		strs[i] = a.String()
+	}
+	return "γ{" + strings.Join(group, ", ") + "; " + strings.Join(strs, ", ") + "}(" + exprString(r) + ")"
+}
+
+// summarySource is the GROUP BY query of a summary
+type summarySource struct {
+	r     *sqlTable
+	group []string
+	aggs  []Agg
+}
+
+// build returns the GROUP BY query, which only reads the grouped and
+// aggregated attributes from the relation.
+func (s *summarySource) build(b *builder, needed map[column]bool) string {
+	n := make(map[rel.Attribute]bool)
+	sel := make([]string, 0, len(s.group)+len(s.aggs))
+	for _, att := range s.group {
+		n[rel.Attribute(att)] = true
+		sel = append(sel, att)
+	}
+	d := b.dialectOrANSI()
+	for _, a := range s.aggs {
+		if a.att != "" {
+			n[rel.Attribute(a.att)] = true
+		}
+		sel = append(sel, a.sql(d)+" AS "+a.As)
+	}
+	if len(n) == 0 {
+		// counting the tuples of the whole relation still has to read them
+		n = nil
+	}
+	str := "SELECT " + strings.Join(sel, ", ") + " FROM (" + s.r.build(b, n, true) + ")" + b.alias("g")
+	if len(s.group) > 0 {
+		str += " GROUP BY " + strings.Join(s.group, ", ")
+	}
+	return "(" + str + ")" + b.alias("a")
+}
+
+// String returns a text representation of the summary
+func (s *summarySource) String() string {
+	return summaryString(s.r, s.group, s.aggs)
+}
+
+// summarize reads the tuples of r and sends the aggregates of each group as
+// tuples of type e
+func summarize(r rel.Relation, group []string, e reflect.Type, aggs []Agg, send func(reflect.Value) error) error {
+	groups := make(map[string][]reflect.Value)
+	var keys []string
+	err := forEach(r, func(tup reflect.Value) error {
+		vals := make([]interface{}, len(group))
+		for i, att := range group {
+			vals[i] = tup.FieldByName(att).Interface()
+		}
+		k := fmt.Sprintf("%#v", vals)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], tup)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(group) == 0 && len(keys) == 0 {
+		// the whole of an empty relation is still summarized
+		keys = append(keys, "")
+	}
+	for _, k := range keys {
+		tups := groups[k]
+		res := reflect.New(e).Elem()
+		for _, att := range group {
+			res.FieldByName(att).Set(tups[0].FieldByName(att))
+		}
+		for _, a := range aggs {
+			if err := setAggregate(res.FieldByName(a.As), a, tups); err != nil {
+				return err
+			}
+		}
+		if err := send(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setAggregate computes the aggregate of the tuples, and sets the field to it
+func setAggregate(f reflect.Value, a Agg, tups []reflect.Value) error {
+	if a.fn == "COUNT" {
+		return setNumber(f, float64(len(tups)))
+	}
+	if len(tups) == 0 {
+		// aggregates of no values are NULL, which is the zero value
+		return nil
+	}
+	vals := make([]reflect.Value, len(tups))
+	for i, tup := range tups {
+		vals[i] = tup.FieldByName(a.att)
+	}
+	switch a.fn {
+	case "MIN", "MAX":
+		best := vals[0]
+		for _, v := range vals[1:] {
+			c, _ := compareValues(v.Interface(), best.Interface())
+			if (a.fn == "MIN" && c < 0) || (a.fn == "MAX" && c > 0) {
+				best = v
+			}
+		}
+		if !best.Type().ConvertibleTo(f.Type()) {
+			return fmt.Errorf("relsql: can't convert %s of %v to %v", a.fn, best.Type(), f.Type())
+		}
+		f.Set(best.Convert(f.Type()))
+		return nil
+	}
+	nums := make([]float64, len(vals))
+	for i, v := range vals {
+		if !isNumber(v) {
+			return fmt.Errorf("relsql: can't compute %s of %v", a.fn, v.Type())
+		}
+		nums[i] = numberValue(v)
+	}
+	switch a.fn {
+	case "SUM", "AVG":
+		var sum float64
+		for _, x := range nums {
+			sum += x
+		}
+		if a.fn == "AVG" {
+			sum /= float64(len(nums))
+		}
+		return setNumber(f, sum)
+	}
+	sort.Float64s(nums)
+	if a.disc {
+		i := int(math.Ceil(a.p*float64(len(nums)))) - 1
+		if i < 0 {
+			i = 0
+		}
+		return setNumber(f, nums[i])
+	}
+	rank := a.p * float64(len(nums)-1)
+	lo := int(math.Floor(rank))
+	hi := int(math.Ceil(rank))
+	return setNumber(f, nums[lo]+(rank-float64(lo))*(nums[hi]-nums[lo]))
+}
+
+// setNumber sets an integer or float field to a number
+func setNumber(f reflect.Value, x float64) error {
+	switch {
+	case f.Kind() == reflect.Float32 || f.Kind() == reflect.Float64:
+		f.SetFloat(x)
+	case isUint(f):
+		f.SetUint(uint64(x))
+	case isInt(f.Type()):
+		f.SetInt(int64(x))
+	default:
+		return fmt.Errorf("relsql: can't store a number in %v", f.Type())
+	}
+	return nil
+}
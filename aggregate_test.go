@@ -0,0 +1,136 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// test aggregates in the database, and percentiles client side
+func TestSummarize(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:summarize?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type saleTup struct {
+		Region string
+		Rep    string
+		Amount int
+	}
+	type totalTup struct {
+		Region string
+		N      int
+		Total  int
+		Mean   float64
+	}
+	type medianTup struct {
+		Region string
+		Median float64
+		P90    int
+	}
+	keys := [][]string{[]string{"Rep"}}
+	sales := []saleTup{
+		{"east", "ann", 10}, {"east", "bob", 30}, {"east", "cy", 20}, {"east", "di", 40},
+		{"west", "eve", 5}, {"west", "fay", 1},
+	}
+	if err := CreateTable(db, "sales", saleTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "sales", rel.New(sales, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "sales", saleTup{}, keys, WithDialect(SQLite))
+	mem := rel.New(sales, keys)
+	region := []string{"Region"}
+	totals := []Agg{Count("N"), Sum("Amount", "Total"), Avg("Amount", "Mean")}
+	medians := []Agg{Median("Amount", "Median"), PercentileDisc("Amount", 0.9, "P90")}
+
+	var summarizeTest = []struct {
+		name string
+		r    rel.Relation
+		want string
+	}{
+		{"table", Summarize(table, region, totalTup{}, totals...), "[{east 4 100 25} {west 2 6 3}]"},
+		{"memory", Summarize(mem, region, totalTup{}, totals...), "[{east 4 100 25} {west 2 6 3}]"},
+		{"memory", Summarize(table, region, medianTup{}, medians...), "[{east 25 40} {west 3 5}]"},
+		{"memory", Summarize(mem, region, medianTup{}, medians...), "[{east 25 40} {west 3 5}]"},
+		{"table", Summarize(table, region, totalTup{}, totals...).Restrict(Attribute("Region").EQ("west")), "[{west 2 6 3}]"},
+	}
+	for i, tt := range summarizeTest {
+		if _, ok := tt.r.(*sqlTable); ok != (tt.name == "table") {
+			t.Errorf("%d has Summarize() => %T", i, tt.r)
+		}
+		var res []string
+		switch z := tt.r.Zero().(type) {
+		case totalTup:
+			ch := make(chan totalTup)
+			tt.r.TupleChan(ch)
+			for tup := range ch {
+				res = append(res, fmt.Sprint(tup))
+			}
+		case medianTup:
+			ch := make(chan medianTup)
+			tt.r.TupleChan(ch)
+			for tup := range ch {
+				res = append(res, fmt.Sprint(tup))
+			}
+		default:
+			t.Errorf("%d has Zero() => %T", i, z)
+		}
+		sort.Strings(res)
+		if err := tt.r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+		if fmt.Sprint(res) != tt.want {
+			t.Errorf("%d %s has tuples %v, want %s", i, tt.name, res, tt.want)
+		}
+	}
+
+	// summaries of a whole relation have a single tuple, even when it is empty
+	type countTup struct {
+		N int
+	}
+	for i, r := range []rel.Relation{
+		Summarize(table.Restrict(Attribute("Amount").GT(100)), nil, countTup{}, Count("N")),
+		Summarize(mem.Restrict(rel.Attribute("Amount").GT(100)), nil, countTup{}, Count("N")),
+	} {
+		ch := make(chan countTup)
+		r.TupleChan(ch)
+		var res []countTup
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		if len(res) != 1 || res[0].N != 0 {
+			t.Errorf("%d has tuples %v, want [{0}]", i, res)
+		}
+	}
+
+	// dialects with percentiles compile them into the query
+	type pTup struct {
+		Region string
+		Median float64
+	}
+	q, _, err := SQL(Summarize(New(db, "sales", saleTup{}, keys, WithDialect(ANSI)), region, pTup{}, Median("Amount", "Median")))
+	want := "SELECT Region, Median FROM (SELECT Region, PERCENTILE_CONT(0.5) WITHIN GROUP (ORDER BY Amount) AS Median FROM (SELECT Region, Amount FROM sales) AS g GROUP BY Region) AS a"
+	if err != nil || q != want {
+		t.Errorf("SQL() => %q, %v, want %q", q, err, want)
+	}
+
+	for i, r := range []rel.Relation{
+		Summarize(table, region, pTup{}),
+		Summarize(table, []string{"City"}, pTup{}, Median("Amount", "Median")),
+		Summarize(table, region, pTup{}, Median("Price", "Median")),
+		Summarize(table, region, pTup{}, Percentile("Amount", 2, "Median")),
+	} {
+		if r.Err() == nil {
+			t.Errorf("%d has Err() => nil, want an error", i)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package relsql
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// AuditRecord describes a statement executed by a relation or a write.
+type AuditRecord struct {
+	// Time is when the statement started
+	Time time.Time
+
+	// Tag identifies the caller, and is set with WithAudit
+	Tag string
+
+	// SQL is the statement
+	SQL string
+
+	// ArgsHash is a hash of the statement's arguments, so that the values
+	// themselves aren't kept in the audit trail
+	ArgsHash string
+
+	// Duration is how long the statement took, including reading its rows
+	Duration time.Duration
+
+	// Rows is the number of rows read or written
+	Rows int64
+
+	// Err is the error of the statement, or empty if it succeeded
+	Err string
+}
+
+// Auditor records executed statements.  If Audit returns an error, the
+// operation that executed the statement fails with it.
+type Auditor interface {
+	Audit(rec AuditRecord) error
+}
+
+// WithAudit sets the auditor which records every statement that the relation
+// or write executes, identified by tag.
+func WithAudit(a Auditor, tag string) Option {
+	return func(o *options) {
+		o.auditor = a
+		o.auditTag = tag
+	}
+}
+
+// audit records the execution of a statement, if there is an auditor
+func (o *options) audit(q string, args []interface{}, start time.Time, rows int64, err error) error {
+	if o.auditor == nil {
+		return nil
+	}
+	rec := AuditRecord{
+		Time:     start,
+		Tag:      o.auditTag,
+		SQL:      q,
+		ArgsHash: argsHash(args),
+		Duration: time.Since(start),
+		Rows:     rows,
+	}
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	return o.auditor.Audit(rec)
+}
+
+// exec executes a statement with e, and audits it
+func (o *options) exec(ctx context.Context, e ExecerContext, q string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := e.ExecContext(ctx, q, args...)
+	var rows int64
+	if err == nil {
+		rows, _ = res.RowsAffected()
+	}
+	if aerr := o.audit(q, args, start, rows, err); err == nil {
+		err = aerr
+	}
+	return res, err
+}
+
+// queryRow executes a query that returns a single row with q, scans the row
+// into dest, and audits it
+func (o *options) queryRow(ctx context.Context, q QueryerContext, query string, args []interface{}, dest ...interface{}) error {
+	start := time.Now()
+	err := q.QueryRowContext(ctx, query, args...).Scan(dest...)
+	var rows int64
+	if err == nil {
+		rows = 1
+	}
+	if aerr := o.audit(query, args, start, rows, err); err == nil {
+		err = aerr
+	}
+	return err
+}
+
+// auditedQueryer executes queries with q and audits them, for code that only
+// takes a QueryerContext, like the estimates of dialects.  Queries are
+// recorded once they have started, without their rows, and the first error of
+// the auditor is kept in err, for the caller to fail with.
+type auditedQueryer struct {
+	q   QueryerContext
+	o   *options
+	err error
+}
+
+// QueryContext executes a query that returns rows
+func (a *auditedQueryer) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := a.q.QueryContext(ctx, query, args...)
+	a.record(query, args, start, err)
+	return rows, err
+}
+
+// QueryRowContext executes a query that returns a single row
+func (a *auditedQueryer) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := a.q.QueryRowContext(ctx, query, args...)
+	a.record(query, args, start, row.Err())
+	return row
+}
+
+// record audits a query, and keeps the first error of the auditor
+func (a *auditedQueryer) record(query string, args []interface{}, start time.Time, err error) {
+	if aerr := a.o.audit(query, args, start, 0, err); a.err == nil {
+		a.err = aerr
+	}
+}
+
+// argsHash returns a hash of the types and values of the arguments
+func argsHash(args []interface{}) string {
+	h := sha256.New()
+	for _, arg := range args {
+		fmt.Fprintf(h, "%T:%v\x00", arg, arg)
+	}
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
+// AuditWriter returns an auditor that writes each record to w as a line of
+// JSON.  It is safe for concurrent use.
+func AuditWriter(w io.Writer) Auditor {
+	return &auditWriter{enc: json.NewEncoder(w)}
+}
+
+// auditWriter writes audit records as JSON lines
+type auditWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// Audit writes the record
+func (a *auditWriter) Audit(rec AuditRecord) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.enc.Encode(rec)
+}
+
+// AuditTable returns an auditor that inserts each record into a table, which
+// has a column for each of the fields of AuditRecord, and can be created with
+// CreateTable(db, tableName, AuditRecord{}, nil).  The opts configure the
+// table's dialect.
+func AuditTable(db *sql.DB, tableName string, opts ...Option) Auditor {
+	a := &auditTable{db: db}
+	for _, opt := range opts {
+		opt(&a.opts)
+	}
+	d := a.opts.dialectOrANSI()
+	a.stmt = insertString(d, tableName, colNames(AuditRecord{}))
+	return a
+}
+
+// auditTable inserts audit records into a table
+type auditTable struct {
+	db   *sql.DB
+	stmt string
+	opts options
+}
+
+// Audit inserts the record
+func (a *auditTable) Audit(rec AuditRecord) error {
+	d := a.opts.dialectOrANSI()
+	vals := []interface{}{rec.Time, rec.Tag, rec.SQL, rec.ArgsHash, rec.Duration, rec.Rows, rec.Err}
+	for i, v := range vals {
+		v, err := encodeArg(d, v)
+		if err != nil {
+			return err
+		}
+		vals[i] = v
+	}
+	_, err := a.db.Exec(a.stmt, bindArgs(d, vals)...)
+	return err
+}
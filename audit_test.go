@@ -0,0 +1,240 @@
+package relsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// failAuditor fails to record every statement
+type failAuditor struct{}
+
+func (failAuditor) Audit(rec AuditRecord) error { return fmt.Errorf("audit unavailable") }
+
+// test recording executed statements
+func TestAudit(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:audit?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type itemTup struct {
+		ID   int
+		Name string
+	}
+	if err := CreateTable(db, "items", itemTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	var buf bytes.Buffer
+	audit := WithAudit(AuditWriter(&buf), "loader")
+	items := rel.New([]itemTup{{1, "a"}, {2, "b"}, {3, "c"}}, [][]string{[]string{"ID"}})
+	if _, err := Insert(db, "items", items, audit, WithBatchSize(2)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	r := New(db, "items", itemTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite), WithAudit(AuditWriter(&buf), "reader"))
+	ch := make(chan itemTup)
+	r.Restrict(Attribute("ID").GE(2)).TupleChan(ch)
+	for range ch {
+	}
+
+	var recs []AuditRecord
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var rec AuditRecord
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Errorf("audit line %q => %v", line, err)
+		}
+		recs = append(recs, rec)
+	}
+	var auditTest = []struct {
+		tag  string
+		sql  string
+		rows int64
+	}{
+		{"loader", "INSERT INTO items (ID, Name) VALUES (?, ?), (?, ?)", 2},
+		{"loader", "INSERT INTO items (ID, Name) VALUES (?, ?)", 1},
+		{"reader", "SELECT ID, Name FROM items WHERE ID >= ?", 2},
+	}
+	if len(recs) != len(auditTest) {
+		t.Errorf("audit records => %v, want %d", recs, len(auditTest))
+		return
+	}
+	for i, tt := range auditTest {
+		rec := recs[i]
+		if rec.Tag != tt.tag || rec.SQL != tt.sql || rec.Rows != tt.rows || rec.Err != "" || len(rec.ArgsHash) != 32 {
+			t.Errorf("%d has audit record %+v, want %v %q with %d rows", i, rec, tt.tag, tt.sql, tt.rows)
+		}
+	}
+	if recs[0].ArgsHash == recs[1].ArgsHash {
+		t.Errorf("different arguments have the same hash")
+	}
+
+	// records can be kept in a table
+	if err := CreateTable(db, "audit_log", AuditRecord{}, nil); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	r = New(db, "items", itemTup{}, [][]string{[]string{"ID"}}, WithAudit(AuditTable(db, "audit_log"), "table"))
+	ch = make(chan itemTup)
+	r.TupleChan(ch)
+	for range ch {
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+	var tag, q string
+	var rows int64
+	db.QueryRow("select Tag, SQL, Rows from audit_log").Scan(&tag, &q, &rows)
+	if tag != "table" || q != "SELECT ID, Name FROM items" || rows != 3 {
+		t.Errorf("audit table row => %v %q %d", tag, q, rows)
+	}
+
+	// a statement that can't be audited fails
+	r = New(db, "items", itemTup{}, [][]string{[]string{"ID"}}, WithAudit(failAuditor{}, ""))
+	ch = make(chan itemTup)
+	r.TupleChan(ch)
+	for range ch {
+	}
+	if err := r.Err(); err == nil {
+		t.Errorf("Err() with failed audit => nil")
+	}
+}
+
+// recordAuditor keeps the records in memory
+type recordAuditor struct {
+	mu   sync.Mutex
+	recs []AuditRecord
+}
+
+func (a *recordAuditor) Audit(rec AuditRecord) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.recs = append(a.recs, rec)
+	return nil
+}
+
+// sqls returns the statements of the records after the first n
+func (a *recordAuditor) sqls(n int) []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var sqls []string
+	for _, rec := range a.recs[n:] {
+		sqls = append(sqls, rec.SQL)
+	}
+	return sqls
+}
+
+// test recording the statements that are executed besides reading tuples
+func TestAuditAuxiliary(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:auditaux?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type docTup struct {
+		ID   int
+		Body Lazy
+	}
+	if err := CreateTable(db, "docs", docTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	docs := rel.New([]docTup{{1, LazyBytes([]byte("first"))}}, [][]string{[]string{"ID"}})
+	if _, err := Insert(db, "docs", docs); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	type idTup struct {
+		ID int
+	}
+	type pairTup struct {
+		ID    int
+		Other int
+	}
+	type otherTup struct {
+		Other int
+	}
+	a := &recordAuditor{}
+	audit := WithAudit(a, "aux")
+	ids := func(opts ...Option) rel.Relation {
+		opts = append([]Option{WithDialect(SQLite), audit}, opts...)
+		return New(db, "docs", idTup{}, [][]string{[]string{"ID"}}, opts...)
+	}
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Errorf("Conn() => %v", err)
+		return
+	}
+	defer conn.Close()
+
+	var auxTest = []struct {
+		name string
+		f    func() error
+		want string
+	}{
+		{"lazy", func() error {
+			var body Lazy
+			err := forEach(New(db, "docs", docTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite), audit), func(tup reflect.Value) error {
+				body = tup.Interface().(docTup).Body
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			_, err = body.Bytes()
+			return err
+		}, "SELECT Body FROM docs WHERE ID = ?"},
+		{"estimate", func() error {
+			_, err := EstimateCard(ctx, ids())
+			return err
+		}, "docs"},
+		{"hash", func() error {
+			_, err := Hash(ctx, ids(WithDialect(sumDialect{})))
+			return err
+		}, "SELECT COUNT(*), SUM(ID)"},
+		{"freshness", func() error {
+			_, err := LastModified(ctx, ids(WithFreshnessProbe("SELECT '2024-01-01'")))
+			return err
+		}, "SELECT '2024-01-01'"},
+		{"cross join", func() error {
+			others := New(db, "docs", idTup{}, nil, WithDialect(SQLite), audit).Rename(otherTup{})
+			return drainErr(CrossJoin(ids(WithCrossJoinLimit(10)), others, pairTup{}))
+		}, "SELECT COUNT(*)"},
+		{"register", func() error {
+			return Register(conn, "temp_ids", rel.New([]idTup{{1}}, [][]string{[]string{"ID"}}), WithDialect(SQLite), audit).Err()
+		}, "CREATE TEMPORARY TABLE temp_ids"},
+		{"session", func() error {
+			return drainErr(ids(WithDialect(foldDialect{session: []string{"PRAGMA busy_timeout = 1000"}})))
+		}, "PRAGMA busy_timeout = 1000"},
+		{"settings", func() error {
+			return drainErr(ids(WithWorkload(NewWorkload("audited", 0, "PRAGMA cache_size = 100"))))
+		}, "PRAGMA cache_size = 100"},
+	}
+	for _, tt := range auxTest {
+		n := len(a.sqls(0))
+		if err := tt.f(); err != nil {
+			t.Errorf("%s => %v", tt.name, err)
+			continue
+		}
+		found := false
+		for _, q := range a.sqls(n) {
+			found = found || strings.Contains(q, tt.want)
+		}
+		if !found {
+			t.Errorf("%s audited %q, want %q", tt.name, a.sqls(n), tt.want)
+		}
+	}
+}
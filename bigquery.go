@@ -0,0 +1,150 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// BigQuery is the dialect for Google BigQuery's standard sql.  Tables are
+// named by their project.dataset.table path, which is quoted with backticks,
+// and query arguments are bound to named parameters @p1, @p2, and so on.
+// BigQuery reads from a snapshot of its tables without a transaction, and it
+// has no multi statement transactions for writes, so relations that use it
+// are read only in practice.
+var BigQuery Dialect = bigQueryDialect{}
+
+// bigQueryDialect is the dialect for BigQuery
+type bigQueryDialect struct{}
+
+// Name returns the name of the dialect
+func (bigQueryDialect) Name() string {
+	return "bigquery"
+}
+
+// Placeholder returns the named parameter for the i'th argument of a query
+func (bigQueryDialect) Placeholder(i int) string {
+	return fmt.Sprintf("@p%d", i)
+}
+
+// ReadTx returns false, because every BigQuery query reads from a consistent
+// snapshot.
+func (bigQueryDialect) ReadTx() bool {
+	return false
+}
+
+// Transactions returns false, because BigQuery's database/sql drivers can't
+// begin transactions
+func (bigQueryDialect) Transactions() bool {
+	return false
+}
+
+// Features declares TABLESAMPLE, which BigQuery supports with SYSTEM
+// sampling
+func (bigQueryDialect) Features() map[Feature]bool {
+	return map[Feature]bool{FeatureTableSample: true}
+}
+
+// NamedArgs returns true, because BigQuery's placeholders are named
+func (bigQueryDialect) NamedArgs() bool {
+	return true
+}
+
+// ProcStyle returns ProcTable, so that table functions are selected from
+// with TABLE(fn(...)).
+func (bigQueryDialect) ProcStyle() ProcStyle {
+	return ProcTable
+}
+
+// QuoteTable quotes a table path with backticks.  A path that is already
+// quoted is left alone.
+func (bigQueryDialect) QuoteTable(tableName string) string {
+	if strings.HasPrefix(tableName, "`") {
+		return tableName
+	}
+	return "`" + tableName + "`"
+}
+
+// Dual returns a single row table, because BigQuery doesn't allow a WHERE
+// clause without a FROM clause.
+func (bigQueryDialect) Dual() string {
+	return "UNNEST([1])"
+}
+
+// Limit limits the query to its first n rows with LIMIT
+func (bigQueryDialect) Limit(query string, n int) string {
+	return fmt.Sprintf("%s LIMIT %d", query, n)
+}
+
+// SetOperator returns the set operator, which BigQuery requires to be
+// qualified with DISTINCT or ALL.
+func (bigQueryDialect) SetOperator(op string) string {
+	if op == "UNION ALL" {
+		return op
+	}
+	return op + " DISTINCT"
+}
+
+// TypeName returns BigQuery's names for the column types of Go types
+func (bigQueryDialect) TypeName(t reflect.Type) string {
+	switch t {
+	case timeType:
+		return "TIMESTAMP"
+	case decimalType:
+		return "NUMERIC"
+	case lazyType:
+		return "BYTES"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "BOOL"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return "INT64"
+	case reflect.Uint, reflect.Uint64:
+		return "NUMERIC"
+	case reflect.Float32, reflect.Float64:
+		return "FLOAT64"
+	case reflect.String:
+		return "STRING"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BYTES"
+		}
+	}
+	return ""
+}
+
+// AlterColumnType returns SET DATA TYPE, which BigQuery uses instead of TYPE
+func (bigQueryDialect) AlterColumnType(tableName, colName, typeName string, nullable bool) string {
+	return "ALTER TABLE " + tableName + " ALTER COLUMN " + colName + " SET DATA TYPE " + typeName
+}
+
+// HashAggregate returns the exclusive or of the fingerprints of the rows in a
+// group, which doesn't depend on their order
+func (bigQueryDialect) HashAggregate(cols []string) string {
+	return "BIT_XOR(FARM_FINGERPRINT(TO_JSON_STRING(STRUCT(" + strings.Join(cols, ", ") + "))))"
+}
+
+// Percentile returns APPROX_QUANTILES for approximate percentiles.  BigQuery
+// only has exact percentiles as analytic functions, so they are computed
+// client side.
+func (bigQueryDialect) Percentile(col string, p float64, disc, approx bool) string {
+	if !approx {
+		return ""
+	}
+	return fmt.Sprintf("APPROX_QUANTILES(%s, 100)[OFFSET(%d)]", col, int(p*100+0.5))
+}
+
+// TruncateTime returns TIMESTAMP_TRUNC, with ISO weeks that start on Monday
+func (bigQueryDialect) TruncateTime(col string, unit TimeUnit) string {
+	part := strings.ToUpper(string(unit))
+	if unit == Week {
+		part = "ISOWEEK"
+	}
+	return "TIMESTAMP_TRUNC(" + col + ", " + part + ")"
+}
+
+func init() {
+	RegisterDialect("bigquery", BigQuery)
+}
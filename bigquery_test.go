@@ -0,0 +1,62 @@
+package relsql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// test the sql generated for BigQuery
+func TestBigQuery(t *testing.T) {
+	type saleTup struct {
+		ID     int64
+		Region string
+		Amount float64
+	}
+	type regionTup struct {
+		Region string
+	}
+	type emptyTup struct{}
+	ckeys := [][]string{[]string{"ID"}}
+	sales := New(nil, "proj.ds.sales", saleTup{}, ckeys, WithDialect(BigQuery))
+	returns := New(nil, "proj.ds.returns", saleTup{}, ckeys, WithDialect(BigQuery))
+
+	var queryTest = []struct {
+		r    interface{}
+		q    string
+		args []interface{}
+	}{
+		{sales.Restrict(Attribute("Region").EQ("west")),
+			"SELECT ID, Region, Amount FROM `proj.ds.sales` WHERE Region = @p1",
+			[]interface{}{sql.Named("p1", "west")}},
+		{sales.Diff(returns),
+			"SELECT ID, Region, Amount FROM (SELECT ID, Region, Amount FROM `proj.ds.sales` EXCEPT DISTINCT SELECT ID, Region, Amount FROM `proj.ds.returns`) AS s",
+			nil},
+		{sales.Project(regionTup{}).Union(returns.Project(regionTup{})),
+			"SELECT Region FROM (SELECT DISTINCT Region FROM `proj.ds.sales` UNION DISTINCT SELECT DISTINCT Region FROM `proj.ds.returns`) AS s",
+			nil},
+		{sales.Project(emptyTup{}),
+			"SELECT 1 FROM UNNEST([1]) WHERE EXISTS (SELECT 1 FROM `proj.ds.sales`)",
+			nil},
+	}
+	for i, tt := range queryTest {
+		q, args, err := tt.r.(*sqlTable).queryString()
+		if err != nil {
+			t.Errorf("%d has queryString() => %v", i, err)
+		}
+		if q != tt.q {
+			t.Errorf("%d has queryString() => %q, want %q", i, q, tt.q)
+		}
+		if args = bindArgs(BigQuery, args); len(args) > 0 && !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%d has args => %v, want %v", i, args, tt.args)
+		}
+	}
+
+	ddl, err := CreateTableString(BigQuery, "proj.ds.sales", saleTup{}, nil)
+	if err != nil {
+		t.Errorf("CreateTableString() => %v", err)
+	}
+	if want := "CREATE TABLE `proj.ds.sales` (ID INT64 NOT NULL, Region STRING NOT NULL, Amount FLOAT64 NOT NULL)"; ddl != want {
+		t.Errorf("CreateTableString() => %q, want %q", ddl, want)
+	}
+}
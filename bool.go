@@ -0,0 +1,99 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// BoolCodec is a Codec for bool attributes in databases that store booleans
+// as other values, like 1 and 0 in a tinyint(1) or integer column, or 'Y' and
+// 'N' in a char(1) column.  It can be registered for a named bool type with
+// RegisterCodec, and is used for every bool attribute by dialects that have
+// no boolean column type.
+type BoolCodec struct {
+	// True and False are the stored values
+	True, False interface{}
+
+	// Type is the column type
+	Type string
+}
+
+var (
+	// IntBools stores booleans as 1 and 0
+	IntBools = BoolCodec{int64(1), int64(0), "SMALLINT"}
+
+	// YNBools stores booleans as 'Y' and 'N'
+	YNBools = BoolCodec{"Y", "N", "CHAR(1)"}
+)
+
+// Encode returns the stored value of a bool
+func (c BoolCodec) Encode(d Dialect, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Bool {
+		return nil, fmt.Errorf("relsql: can't encode %T as a boolean", v)
+	}
+	if rv.Bool() {
+		return c.True, nil
+	}
+	return c.False, nil
+}
+
+// Decode sets a bool from a stored value, which may be the codec's True or
+// False, any number, or a string that strconv.ParseBool accepts.
+func (c BoolCodec) Decode(d Dialect, src interface{}, dst reflect.Value) error {
+	if b, ok := src.([]byte); ok {
+		src = string(b)
+	}
+	switch v := src.(type) {
+	case bool:
+		dst.SetBool(v)
+		return nil
+	case string:
+		switch {
+		case strings.EqualFold(v, fmt.Sprint(c.True)):
+			dst.SetBool(true)
+			return nil
+		case strings.EqualFold(v, fmt.Sprint(c.False)):
+			dst.SetBool(false)
+			return nil
+		}
+		if b, err := strconv.ParseBool(v); err == nil {
+			dst.SetBool(b)
+			return nil
+		}
+	case nil:
+	default:
+		if rv := reflect.ValueOf(v); isNumber(rv) {
+			dst.SetBool(numberValue(rv) != 0)
+			return nil
+		}
+	}
+	return decodeError(src, dst)
+}
+
+// TypeName returns the column type
+func (c BoolCodec) TypeName(d Dialect) string {
+	return c.Type
+}
+
+// boolCodecer is implemented by dialects without a boolean column type, whose
+// bool attributes are stored with a codec, unless their type has a codec of
+// its own.
+type boolCodecer interface {
+	BoolCodec() Codec
+}
+
+// codecFor returns the codec for values of type t in the dialect, which is the
+// codec registered for t, or the dialect's codec for bools.  It returns nil
+// if values of type t are passed to the driver as they are.
+func codecFor(d Dialect, t reflect.Type) Codec {
+	if c := lookupCodec(t); c != nil {
+		return c
+	}
+	if bc, ok := d.(boolCodecer); ok && t.Kind() == reflect.Bool {
+		return bc.BoolCodec()
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// ynDialect is sqlite, storing booleans as 'Y' and 'N'
+type ynDialect struct {
+	sqliteDialect
+}
+
+// BoolCodec returns the codec for 'Y' and 'N'
+func (ynDialect) BoolCodec() Codec {
+	return YNBools
+}
+
+// test bool attributes stored as other values, for a dialect and for a named
+// type
+func TestBoolCodec(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:boolcodec?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type flag bool
+	RegisterCodec(reflect.TypeOf(flag(false)), IntBools)
+	type userTup struct {
+		ID     int
+		Active bool
+		Admin  flag
+	}
+	keys := [][]string{[]string{"ID"}}
+	d := ynDialect{}
+	if err := CreateTable(db, "users", userTup{}, keys, WithDialect(d)); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	users := []userTup{{1, true, false}, {2, false, true}, {3, true, true}}
+	if _, err := Insert(db, "users", rel.New(users, keys), WithDialect(d)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	var active, admin string
+	if err := db.QueryRow("SELECT Active, Admin FROM users WHERE ID = 2").Scan(&active, &admin); err != nil || active != "N" || admin != "1" {
+		t.Errorf("stored %q, %q, %v, want N, 1", active, admin, err)
+	}
+
+	table := New(db, "users", userTup{}, keys, WithDialect(d))
+	var boolCodecTest = []struct {
+		r    rel.Relation
+		want string
+	}{
+		{table, "[{1 true false} {2 false true} {3 true true}]"},
+		{table.Restrict(Attribute("Active").EQ(true)), "[{1 true false} {3 true true}]"},
+		{table.Restrict(Attribute("Admin").EQ(flag(true)).And(Attribute("Active").EQ(false))), "[{2 false true}]"},
+	}
+	for i, tt := range boolCodecTest {
+		ch := make(chan userTup)
+		tt.r.TupleChan(ch)
+		res := make([]userTup, 0)
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		if err := tt.r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+		sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+		if fmt.Sprint(res) != tt.want {
+			t.Errorf("%d has tuples %v, want %s", i, res, tt.want)
+		}
+	}
+
+	// Oracle has no boolean type, so it compares with numbers
+	_, args, err := SQL(New(db, "users", userTup{}, keys, WithDialect(Oracle)).Restrict(Attribute("Active").EQ(true)))
+	if err != nil || fmt.Sprint(args) != "[1]" {
+		t.Errorf("Oracle SQL() has args %v, %v, want [1]", args, err)
+	}
+}
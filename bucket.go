@@ -0,0 +1,147 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// EqualWidth returns the edges of n bins of equal width from min to max, for
+// Bucketize.
+func EqualWidth(min, max float64, n int) []float64 {
+	edges := make([]float64, n+1)
+	for i := range edges {
+		edges[i] = min + (max-min)*float64(i)/float64(n)
+	}
+	return edges
+}
+
+// Bucketize creates a histogram of the numeric attribute att of r.  The
+// values are put into bins by the increasing edges: bin 0 holds the values
+// less than edges[0], bin i the values from edges[i-1] up to but not
+// including edges[i], and bin len(edges) the values from the last edge up.
+// Each tuple of the result has the bin number in the attribute named as, and
+// the aggregates of the tuples in the bin, such as Count.  zero is the type of
+// the resulting tuples, whose attribute as has an integer type.  Bins without
+// any tuples are left out.
+//
+// If r is from this package, the bins are computed with a CASE expression
+// and counted with a GROUP BY query, so only the histogram is read from the
+// database.
+func Bucketize(r rel.Relation, att string, edges []float64, as string, zero interface{}, aggs ...Agg) rel.Relation {
+	e1, e2 := reflect.TypeOf(r.Zero()), reflect.TypeOf(zero)
+	fail := func(err error) rel.Relation {
+		return &sqlTable{zero: zero, cKeys: rel.DefaultKeys(zero), err: err}
+	}
+	if err := checkZero(e2); err != nil {
+		return fail(err)
+	}
+	if f, ok := e1.FieldByName(att); !ok || !isNumber(reflect.Zero(f.Type)) {
+		return fail(fmt.Errorf("relsql: %s is not a numeric attribute of %v", att, e1))
+	}
+	if len(edges) == 0 {
+		return fail(fmt.Errorf("relsql: histogram of %s has no bins", att))
+	}
+	for i := 1; i < len(edges); i++ {
+		if edges[i] <= edges[i-1] {
+			return fail(fmt.Errorf("relsql: bin edges %v are not increasing", edges))
+		}
+	}
+	f, ok := e2.FieldByName(as)
+	if !ok || !isInt(f.Type) {
+		return fail(fmt.Errorf("relsql: bin attribute %s is not an integer attribute of %v", as, e2))
+	}
+	if _, ok := e1.FieldByName(as); ok {
+		return fail(fmt.Errorf("relsql: bin attribute %s is already in the heading of %v", as, e1))
+	}
+
+	cases := make([]string, len(edges))
+	for i, edge := range edges {
+		cases[i] = "WHEN " + att + " < " + strconv.FormatFloat(edge, 'g', -1, 64) + " THEN " + strconv.Itoa(i)
+	}
+	expr := "CASE " + strings.Join(cases, " ") + " ELSE " + strconv.Itoa(len(edges)) + " END"
+	b := binned(r, as, f.Type, expr, fmt.Sprintf("bin{%s; %v}", att, edges), func(tup, res reflect.Value) {
+		setInt(res, int64(bin(numberValue(tup.FieldByName(att)), edges)))
+	})
+	return Summarize(b, []string{as}, zero, aggs...)
+}
+
+// binned extends the tuples of r with the attribute as, of type t, which is
+// computed by the sql expression expr if r is from this package, and by
+// setting it with f otherwise.  op is the text representation of the
+// operation.
+func binned(r rel.Relation, as string, t reflect.Type, expr, op string, f func(tup, res reflect.Value)) rel.Relation {
+	e1 := reflect.TypeOf(r.Zero())
+	fields := make([]reflect.StructField, 0, e1.NumField()+1)
+	for i := 0; i < e1.NumField(); i++ {
+		fields = append(fields, e1.Field(i))
+	}
+	fields = append(fields, reflect.StructField{Name: as, Type: t})
+	e := reflect.StructOf(fields)
+	zero := reflect.New(e).Elem().Interface()
+	if r1, ok := r.(*sqlTable); ok && r1.err == nil && r1.composable() && expr != "" {
+		return &sqlTable{
+			db:             r1.db,
+			conn:           r1.conn,
+			q:              r1.q,
+			src:            &binSource{r1, expr, as, op},
+			cols:           colNames(zero),
+			zero:           zero,
+			cKeys:          r1.cKeys,
+			sourceDistinct: true,
+			opts:           r1.opts,
+		}
+	}
+	return &clientOp{
+		op:     "Bin",
+		str:    op + "(" + exprString(r) + ")",
+		inputs: []rel.Relation{r},
+		zero:   zero,
+		cKeys:  r.CKeys(),
+		run: func(send func(reflect.Value) error) error {
+			return forEach(r, func(tup reflect.Value) error {
+				res := reflect.New(e).Elem()
+				for i := 0; i < tup.NumField(); i++ {
+					res.Field(i).Set(tup.Field(i))
+				}
+				f(tup, res.Field(tup.NumField()))
+				return send(res)
+			})
+		},
+	}
+}
+
+// bin returns the number of the bin that holds x
+func bin(x float64, edges []float64) int {
+	for i, edge := range edges {
+		if x < edge {
+			return i
+		}
+	}
+	return len(edges)
+}
+
+// binSource extends the rows of a relation with a bin computed by an sql
+// expression
+type binSource struct {
+	r    *sqlTable
+	expr string
+	as   string
+
+	// op is the text representation of the operation
+	op string
+}
+
+// build returns a query that selects the expression along with the rows of
+// the relation.  The expression only refers to attributes of the relation, so
+// all of them are read.
+func (s *binSource) build(b *builder, needed map[column]bool) string {
+	return "(SELECT w.*, " + s.expr + " AS " + s.as + " FROM (" + s.r.build(b, nil, true) + ")" + b.alias("w") + ")" + b.alias("k")
+}
+
+// String returns a text representation of the binning
+func (s *binSource) String() string {
+	return s.op + "(" + s.r.expr() + ")"
+}
@@ -0,0 +1,81 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// test histograms in the database and client side
+func TestBucketize(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:bucketize?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type orderTup struct {
+		ID    int
+		Price float64
+	}
+	type binTup struct {
+		Bin   int
+		N     int
+		Total float64
+	}
+	keys := [][]string{[]string{"ID"}}
+	orders := []orderTup{{1, 5}, {2, 12}, {3, 15}, {4, 25}, {5, 99}, {6, -1}}
+	if err := CreateTable(db, "orders", orderTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "orders", rel.New(orders, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "orders", orderTup{}, keys, WithDialect(SQLite))
+	mem := rel.New(orders, keys)
+	edges := EqualWidth(0, 30, 3)
+	want := "[{0 1 -1} {1 1 5} {2 2 27} {3 1 25} {4 1 99}]"
+
+	var bucketizeTest = []struct {
+		name string
+		r    rel.Relation
+	}{
+		{"table", Bucketize(table, "Price", edges, "Bin", binTup{}, Count("N"), Sum("Price", "Total"))},
+		{"memory", Bucketize(mem, "Price", edges, "Bin", binTup{}, Count("N"), Sum("Price", "Total"))},
+	}
+	for i, tt := range bucketizeTest {
+		if _, ok := tt.r.(*sqlTable); ok != (tt.name == "table") {
+			t.Errorf("%d has Bucketize() => %T", i, tt.r)
+		}
+		ch := make(chan binTup)
+		tt.r.TupleChan(ch)
+		var res []binTup
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		sort.Slice(res, func(i, j int) bool { return res[i].Bin < res[j].Bin })
+		if err := tt.r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+		if fmt.Sprint(res) != want {
+			t.Errorf("%d %s has bins %v, want %s", i, tt.name, res, want)
+		}
+	}
+
+	for i, r := range []rel.Relation{
+		Bucketize(table, "Price", nil, "Bin", binTup{}, Count("N")),
+		Bucketize(table, "Price", []float64{10, 5}, "Bin", binTup{}, Count("N")),
+		Bucketize(table, "Weight", edges, "Bin", binTup{}, Count("N")),
+		Bucketize(table, "Price", edges, "Total", binTup{}, Count("N")),
+		Bucketize(table, "Price", edges, "ID", binTup{}, Count("N")),
+	} {
+		if r.Err() == nil {
+			t.Errorf("%d has Err() => nil, want an error", i)
+		}
+	}
+}
@@ -0,0 +1,439 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"hash/fnv"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// WriteProgress describes how far a write has gotten.  It is passed to the
+// function set with WithProgress after each batch is written.
+type WriteProgress struct {
+	// Rows is the number of rows written so far
+	Rows int64
+
+	// Elapsed is the time since the write started
+	Elapsed time.Duration
+}
+
+// WithBatchSize sets the number of rows written by each INSERT statement.
+// Larger batches make fewer round trips to the database, but databases limit
+// the number of placeholders in a single statement.  The default is 1.
+func WithBatchSize(n int) Option {
+	return func(o *options) {
+		o.batchSize = n
+	}
+}
+
+// WithWorkers sets the number of concurrent writers used by Insert.  Tuples
+// are partitioned between the writers by a hash of their first candidate key,
+// and each writer uses its own transaction.  Writes in a Session always use a
+// single writer.
+func WithWorkers(n int) Option {
+	return func(o *options) {
+		o.workers = n
+	}
+}
+
+// WithProgress sets a function that is called after each batch of rows is
+// written.  With multiple workers it may be called concurrently.
+func WithProgress(f func(WriteProgress)) Option {
+	return func(o *options) {
+		o.progress = f
+	}
+}
+
+// WriteResult summarizes a write.  It is returned along with any error, in
+// which case it describes the writes that were made before the error.  The
+// writes of a Session are counted once they are executed, but those of Insert
+// only once they are committed, so batches that were rolled back aren't.
+type WriteResult struct {
+	// Rows is the number of rows written
+	Rows int64
+
+	// Batches is the number of INSERT statements executed
+	Batches int
+
+	// Duration is the time that the write took
+	Duration time.Duration
+
+	// Errors are the errors of the batches that failed.  A single writer
+	// stops at its first error, but with multiple workers, each of them may
+	// fail.
+	Errors []BatchError
+}
+
+// BatchError is the error of a batch of rows that wasn't written
+type BatchError struct {
+	// Batch is the number of batches that had been written when the batch
+	// failed
+	Batch int
+
+	// Rows is the number of rows in the batch
+	Rows int
+
+	Err error
+}
+
+// Error implements the error interface
+func (e BatchError) Error() string {
+	return fmt.Sprintf("relsql: batch %d of %d rows failed: %v", e.Batch, e.Rows, e.Err)
+}
+
+// progress tracks the rows written by one or more writers, and those of them
+// that have been committed
+type progress struct {
+	mu      sync.Mutex
+	start   time.Time
+	rows    int64
+	batches int
+	errs    []BatchError
+	f       func(WriteProgress)
+
+	committedRows    int64
+	committedBatches int
+}
+
+// newProgress starts tracking the progress of a write
+func newProgress(f func(WriteProgress)) *progress {
+	return &progress{start: time.Now(), f: f}
+}
+
+// add records that n more rows have been written
+func (p *progress) add(n int) {
+	p.mu.Lock()
+	p.rows += int64(n)
+	p.batches++
+	wp := WriteProgress{p.rows, time.Since(p.start)}
+	p.mu.Unlock()
+	if p.f != nil {
+		p.f(wp)
+	}
+}
+
+// fail records that a batch of n rows failed
+func (p *progress) fail(n int, err error) {
+	p.mu.Lock()
+	p.errs = append(p.errs, BatchError{p.batches, n, err})
+	p.mu.Unlock()
+}
+
+// commit records that a writer's rows and batches have been committed
+func (p *progress) commit(w *batchWriter) {
+	p.mu.Lock()
+	p.committedRows += w.rows
+	p.committedBatches += w.batches
+	p.mu.Unlock()
+}
+
+// result returns the summary of the write so far
+func (p *progress) result() WriteResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return WriteResult{p.rows, p.batches, time.Since(p.start), p.errs}
+}
+
+// committed returns the summary of the write so far, counting only the rows
+// and batches that have been committed
+func (p *progress) committed() WriteResult {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return WriteResult{p.committedRows, p.committedBatches, time.Since(p.start), p.errs}
+}
+
+// preparer prepares statements, and is implemented by *sql.Tx and *sql.Conn.
+// Writers whose executor can't prepare statements execute each batch
+// directly.
+type preparer interface {
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// batchWriter inserts tuples into a table in batches of multiple rows
+type batchWriter struct {
+	tx        ExecerContext
+	d         Dialect
+	tableName string
+	cols      []column
+	size      int
+
+	// enums checks the tuples before they are written, and transforms
+	// converts their values
+	enums      enumCheck
+	transforms fieldTransforms
+
+	// stmt is the prepared statement for full batches
+	stmt *sql.Stmt
+
+	// pending holds the values of the tuples that have not been written, and
+	// n is the number of those tuples
+	pending []interface{}
+	n       int
+
+	// rows and batches count what the writer has written
+	rows    int64
+	batches int
+
+	progress *progress
+
+	// audit records the executed statements
+	audit func(q string, args []interface{}, start time.Time, rows int64, err error) error
+}
+
+// newBatchWriter creates a writer of tuples like z into the table that uses
+// the session's batch size.
+func (s *Session) newBatchWriter(tableName string, z interface{}, p *progress) (*batchWriter, error) {
+	return newBatchWriter(s.tx, &s.opts, tableName, z, p)
+}
+
+// newBatchWriter creates a writer of tuples like z into the table, which
+// executes its statements with tx and uses the batch size of the options.
+func newBatchWriter(tx ExecerContext, o *options, tableName string, z interface{}, p *progress) (*batchWriter, error) {
+	size := o.batchSize
+	if size < 1 {
+		size = 1
+	}
+	cols := colNames(z)
+	ft, err := o.fieldTransforms(reflect.TypeOf(z), cols)
+	if err != nil {
+		return nil, err
+	}
+	return &batchWriter{tx: tx, d: o.dialectOrANSI(), tableName: tableName, cols: cols, size: size, enums: o.enumCheck(cols), transforms: ft, progress: p, audit: o.audit}, nil
+}
+
+// add queues a tuple to be written, and writes a batch when it is full
+func (w *batchWriter) add(tup reflect.Value) error {
+	if err := w.enums.check(tup); err != nil {
+		return err
+	}
+	for i := range w.cols {
+		v, err := w.transforms.write(i, tup.Field(i).Interface())
+		if err != nil {
+			return err
+		}
+		if v, err = encodeArg(w.d, v); err != nil {
+			return err
+		}
+		w.pending = append(w.pending, v)
+	}
+	w.n++
+	if w.n < w.size {
+		return nil
+	}
+	q := insertRowsString(w.d, w.tableName, w.cols, w.size)
+	p, ok := w.tx.(preparer)
+	if w.stmt == nil && ok {
+		stmt, err := p.PrepareContext(context.Background(), q)
+		if err != nil {
+			return err
+		}
+		w.stmt = stmt
+	}
+	start := time.Now()
+	var res sql.Result
+	var err error
+	if w.stmt != nil {
+		res, err = w.stmt.Exec(bindArgs(w.d, w.pending)...)
+	} else {
+		res, err = w.tx.ExecContext(context.Background(), q, bindArgs(w.d, w.pending)...)
+	}
+	if err := w.audited(q, start, res, err); err != nil {
+		return err
+	}
+	w.done()
+	return nil
+}
+
+// audited records the execution of a batch, and returns its error or the
+// error of recording it.
+func (w *batchWriter) audited(q string, start time.Time, res sql.Result, err error) error {
+	if w.audit == nil {
+		return err
+	}
+	var rows int64
+	if err == nil {
+		rows, _ = res.RowsAffected()
+	}
+	if aerr := w.audit(q, w.pending, start, rows, err); err == nil {
+		err = aerr
+	}
+	return err
+}
+
+// flush writes any tuples left over from the last full batch
+func (w *batchWriter) flush() error {
+	if w.n == 0 {
+		return nil
+	}
+	q := insertRowsString(w.d, w.tableName, w.cols, w.n)
+	start := time.Now()
+	res, err := w.tx.ExecContext(context.Background(), q, bindArgs(w.d, w.pending)...)
+	if err := w.audited(q, start, res, err); err != nil {
+		return err
+	}
+	w.done()
+	return nil
+}
+
+// done records that the pending tuples were written
+func (w *batchWriter) done() {
+	w.rows += int64(w.n)
+	w.batches++
+	w.progress.add(w.n)
+	w.pending = w.pending[:0]
+	w.n = 0
+}
+
+// close releases the prepared statement
+func (w *batchWriter) close() {
+	if w.stmt != nil {
+		w.stmt.Close()
+	}
+}
+
+// Insert writes every tuple of r into the table as a new row, outside of any
+// session.  With more than one worker the writers commit independently, so if
+// one of them fails the rows written by the others are kept, and the result
+// counts them.  If reading r fails, every worker rolls back instead, and no
+// rows are written.
+func Insert(db *sql.DB, tableName string, r rel.Relation, opts ...Option) (WriteResult, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.workers <= 1 {
+		s, err := Begin(db, opts...)
+		if err != nil {
+			return WriteResult{}, err
+		}
+		res, err := s.Insert(tableName, r)
+		if err == nil {
+			err = s.Commit()
+		} else {
+			s.Rollback()
+		}
+		if err != nil {
+			// nothing was written once the transaction is rolled back
+			res.Rows, res.Batches = 0, 0
+		}
+		return res, err
+	}
+	if err := checkZero(reflect.TypeOf(r.Zero())); err != nil {
+		return WriteResult{}, err
+	}
+	p := newProgress(o.progress)
+
+	// each worker writes the tuples sent to it in its own session
+	chans := make([]chan reflect.Value, o.workers)
+	errs := make([]error, o.workers)
+	abort := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := range chans {
+		chans[i] = make(chan reflect.Value)
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = insertWorker(db, tableName, r.Zero(), p, chans[i], abort, opts)
+		}(i)
+	}
+
+	keyIndex := keyFields(reflect.TypeOf(r.Zero()), r.CKeys())
+	err := forEach(r, func(tup reflect.Value) error {
+		chans[partition(tup, keyIndex, o.workers)] <- tup
+		return nil
+	})
+	if err != nil {
+		// the workers have only been sent part of r, which they discard
+		close(abort)
+	}
+	for _, ch := range chans {
+		close(ch)
+	}
+	wg.Wait()
+	if err != nil {
+		return p.committed(), err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return p.committed(), err
+		}
+	}
+	return p.committed(), nil
+}
+
+// insertWorker writes the tuples received from ch in a new session, which is
+// rolled back instead of committed if abort is closed before ch is.  If the
+// write fails, the rest of the tuples are drained so that the producer isn't
+// blocked.
+func insertWorker(db *sql.DB, tableName string, z interface{}, p *progress, ch <-chan reflect.Value, abort <-chan struct{}, opts []Option) (err error) {
+	defer func() {
+		for range ch {
+		}
+	}()
+	s, err := Begin(db, opts...)
+	if err != nil {
+		p.fail(0, err)
+		return err
+	}
+	w, err := s.newBatchWriter(tableName, z, p)
+	if err != nil {
+		p.fail(0, err)
+		s.Rollback()
+		return err
+	}
+	defer w.close()
+	for tup := range ch {
+		if err = w.add(tup); err != nil {
+			p.fail(w.n, err)
+			s.Rollback()
+			return err
+		}
+	}
+	select {
+	case <-abort:
+		return s.Rollback()
+	default:
+	}
+	if err = w.flush(); err != nil {
+		p.fail(w.n, err)
+		s.Rollback()
+		return err
+	}
+	if err = s.Commit(); err != nil {
+		return err
+	}
+	p.commit(w)
+	return nil
+}
+
+// keyFields returns the field indexes of the first candidate key, or of every
+// field if there are no keys.
+func keyFields(e reflect.Type, cKeys rel.CandKeys) []int {
+	if len(cKeys) == 0 {
+		index := make([]int, e.NumField())
+		for i := range index {
+			index[i] = i
+		}
+		return index
+	}
+	var index []int
+	for _, att := range cKeys[0] {
+		if f, ok := e.FieldByName(string(att)); ok {
+			index = append(index, f.Index[0])
+		}
+	}
+	return index
+}
+
+// partition returns the worker for a tuple, from a hash of its key fields
+func partition(tup reflect.Value, keyIndex []int, workers int) int {
+	h := fnv.New32a()
+	for _, i := range keyIndex {
+		fmt.Fprintf(h, "%v\x00", tup.Field(i).Interface())
+	}
+	return int(h.Sum32() % uint32(workers))
+}
@@ -0,0 +1,110 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"sync"
+	"time"
+)
+
+// cacheTable records when each table of a disk cache was last refreshed, so
+// that a cache file that is opened again can be used without a refresh.
+const cacheTable = "relsql_cache"
+
+// DiskCache is a copy of a relation, usually from a slow remote database, in
+// a table of a local sqlite database.  Reads of the cache, including
+// restrictions, joins, and other operations that are pushed down, are
+// evaluated by sqlite from the local copy, which is only updated from the
+// source by Refresh.  Because the copy is kept in the sqlite file, a cache
+// that is opened again, for example when the source is unreachable, serves
+// the tuples of its last refresh.
+type DiskCache struct {
+	db        *sql.DB
+	tableName string
+	src       rel.Relation
+	keys      [][]string
+	opts      []Option
+
+	// mu serializes refreshes
+	mu sync.Mutex
+}
+
+// NewDiskCache creates a cache of src in the table of the sqlite database,
+// which is created from the tuple type of src if it doesn't exist.  dsn is the
+// data source name that db was opened with.  The options are used for the
+// table and for the relations returned by Relation.
+func NewDiskCache(db *sql.DB, dsn, tableName string, src rel.Relation, opts ...Option) (*DiskCache, error) {
+	if err := CheckSQLiteDSN(dsn); err != nil {
+		return nil, err
+	}
+	if err := src.Err(); err != nil {
+		return nil, err
+	}
+	keys := keyStrings(src.CKeys())
+	c := &DiskCache{db: db, tableName: tableName, src: src, keys: keys, opts: append([]Option{WithDialect(SQLite)}, opts...)}
+	if _, err := db.Exec("CREATE TABLE IF NOT EXISTS " + cacheTable + " (name TEXT PRIMARY KEY, refreshed TEXT NOT NULL)"); err != nil {
+		return nil, err
+	}
+	var n int
+	if err := db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", tableName).Scan(&n); err != nil {
+		return nil, err
+	}
+	if n == 0 {
+		if err := CreateTable(db, tableName, src.Zero(), keys, c.opts...); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Refresh replaces the tuples in the cache with the current tuples of the
+// source, in one transaction, so that concurrent reads of the cache see
+// either the old tuples or the new ones.
+func (c *DiskCache) Refresh() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, err := Begin(c.db, append(c.opts, WithWriteMode(Truncate))...)
+	if err != nil {
+		return err
+	}
+	if _, err := s.Materialize(c.tableName, c.src); err != nil {
+		s.Rollback()
+		return err
+	}
+	now := time.Now().UTC().Format(time.RFC3339Nano)
+	if _, err := s.exec("INSERT OR REPLACE INTO "+cacheTable+" (name, refreshed) VALUES (?, ?)", c.tableName, now); err != nil {
+		s.Rollback()
+		return err
+	}
+	return s.Commit()
+}
+
+// Refreshed returns the time of the cache's last refresh, which may have been
+// made by an earlier process, or the zero time if it has never been
+// refreshed.
+func (c *DiskCache) Refreshed() (time.Time, error) {
+	var v string
+	err := c.db.QueryRow("SELECT refreshed FROM "+cacheTable+" WHERE name = ?", c.tableName).Scan(&v)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339Nano, v)
+}
+
+// Relation returns a relation that reads from the cache, refreshing it first
+// if it has never been refreshed.  If that fails, the result is an error
+// relation.
+func (c *DiskCache) Relation() rel.Relation {
+	last, err := c.Refreshed()
+	if err == nil && last.IsZero() {
+		err = c.Refresh()
+	}
+	r := New(c.db, c.tableName, c.src.Zero(), c.keys, c.opts...)
+	if err != nil {
+		r.(*sqlTable).err = err
+	}
+	return r
+}
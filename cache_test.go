@@ -0,0 +1,93 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test caching a relation in a local sqlite database
+func TestDiskCache(t *testing.T) {
+	remote, err := sql.Open("sqlite3", "file:cacheremote?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer remote.Close()
+	dsn := "file:cachelocal?mode=memory&cache=shared"
+	local, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer local.Close()
+
+	type cacheTup struct {
+		Name  string
+		Count int
+	}
+	if err := CreateTable(remote, "counts", cacheTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(remote, "counts", rel.New([]cacheTup{{"a", 1}, {"b", 2}}, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	src := New(remote, "counts", cacheTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite))
+
+	c, err := NewDiskCache(local, dsn, "counts_cache", src)
+	if err != nil {
+		t.Errorf("NewDiskCache() => %v", err)
+		return
+	}
+	if last, err := c.Refreshed(); err != nil || !last.IsZero() {
+		t.Errorf("Refreshed() before a refresh => %v, %v, want the zero time", last, err)
+	}
+	read := func(r rel.Relation) []cacheTup {
+		ch := make(chan cacheTup)
+		r.TupleChan(ch)
+		var res []cacheTup
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		return res
+	}
+	r := c.Relation().Restrict(Attribute("Count").GT(1))
+	if q, _, err := SQL(r); err != nil || q != "SELECT Name, Count FROM counts_cache WHERE Count > ?" {
+		t.Errorf("cached restriction => %v, %v", q, err)
+	}
+	if res, want := read(r), []cacheTup{{"b", 2}}; !reflect.DeepEqual(res, want) {
+		t.Errorf("cached restriction => %v, want %v", res, want)
+	}
+
+	// changes to the source aren't seen until a refresh
+	if _, err := Insert(remote, "counts", rel.New([]cacheTup{{"c", 3}}, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if res := read(c.Relation()); len(res) != 2 {
+		t.Errorf("cache before refresh => %v, want 2 tuples", res)
+	}
+	if err := c.Refresh(); err != nil {
+		t.Errorf("Refresh() => %v", err)
+	}
+	if res := read(c.Relation()); len(res) != 3 {
+		t.Errorf("cache after refresh => %v, want 3 tuples", res)
+	}
+
+	// a cache opened again uses the earlier copy
+	c2, err := NewDiskCache(local, dsn, "counts_cache", src)
+	if err != nil {
+		t.Errorf("NewDiskCache() again => %v", err)
+		return
+	}
+	if last, err := c2.Refreshed(); err != nil || last.IsZero() {
+		t.Errorf("Refreshed() of a reopened cache => %v, %v", last, err)
+	}
+
+	if _, err := NewDiskCache(local, "file::memory:", "counts_cache", src); err == nil {
+		t.Errorf("NewDiskCache() with an unshared in memory database succeeded")
+	}
+}
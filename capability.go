@@ -0,0 +1,90 @@
+package relsql
+
+// Feature is an optional feature of sql, which a dialect may not support.
+// Operations that need a feature the dialect lacks are evaluated client side.
+type Feature string
+
+const (
+	// FeatureExcept is the EXCEPT set operation, which Diff compiles to
+	FeatureExcept Feature = "EXCEPT"
+
+	// FeatureIntersect is the INTERSECT set operation
+	FeatureIntersect Feature = "INTERSECT"
+
+	// FeatureWindowFunctions are window functions, like ROW_NUMBER() OVER,
+	// which TopN and Running compile to
+	FeatureWindowFunctions Feature = "window functions"
+
+	// FeatureReturning is INSERT ... RETURNING, which InsertReturning uses to
+	// read generated attributes
+	FeatureReturning Feature = "RETURNING"
+
+	// FeatureLateral are lateral joins, which Lateral compiles to
+	FeatureLateral Feature = "LATERAL"
+
+	// FeatureTableSample is the TABLESAMPLE clause
+	FeatureTableSample Feature = "TABLESAMPLE"
+
+	// FeatureTransactions are transactions, which reads begin when the
+	// dialect's ReadTx is true, or to run the setup of a script
+	FeatureTransactions Feature = "transactions"
+)
+
+// Features are the features of sql that Capabilities reports on
+var Features = []Feature{
+	FeatureExcept,
+	FeatureIntersect,
+	FeatureWindowFunctions,
+	FeatureReturning,
+	FeatureLateral,
+	FeatureTableSample,
+	FeatureTransactions,
+}
+
+// featurer is implemented by dialects which declare whether they support
+// features.  The map only has to hold the features whose support differs
+// from what the dialect's other methods imply, and those that have no other
+// method, like window functions.
+type featurer interface {
+	Features() map[Feature]bool
+}
+
+// Supports returns true if the dialect supports the feature.  A dialect
+// declares its features with a Features method that returns a map from
+// features to whether it supports them.  Features it doesn't declare are
+// inferred from its other methods: Returning, LateralJoin, SetOperator and
+// Transactions, and otherwise default to those of ANSI sql, which has every
+// feature but TABLESAMPLE.
+func Supports(d Dialect, f Feature) bool {
+	if fd, ok := d.(featurer); ok {
+		if v, ok := fd.Features()[f]; ok {
+			return v
+		}
+	}
+	switch f {
+	case FeatureExcept, FeatureIntersect:
+		return setOperatorString(d, string(f)) != ""
+	case FeatureReturning:
+		r, ok := d.(interface {
+			Returning() bool
+		})
+		return ok && r.Returning()
+	case FeatureLateral:
+		return lateralJoinString(d) != ""
+	case FeatureTransactions:
+		return transactions(d)
+	case FeatureWindowFunctions:
+		return true
+	}
+	return false
+}
+
+// Capabilities returns the dialect's support for each of the Features, so
+// that code which builds queries by hand can branch on them.
+func Capabilities(d Dialect) map[Feature]bool {
+	res := make(map[Feature]bool, len(Features))
+	for _, f := range Features {
+		res[f] = Supports(d, f)
+	}
+	return res
+}
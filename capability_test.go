@@ -0,0 +1,72 @@
+package relsql
+
+import (
+	"database/sql"
+	"testing"
+)
+
+// windowlessDialect is the ANSI dialect without window functions or EXCEPT
+type windowlessDialect struct {
+	ansiDialect
+}
+
+func (windowlessDialect) Features() map[Feature]bool {
+	return map[Feature]bool{FeatureWindowFunctions: false, FeatureExcept: false}
+}
+
+// test the features that dialects support, and that operations which need
+// features a dialect lacks are evaluated client side
+func TestCapabilities(t *testing.T) {
+	var capabilitiesTest = []struct {
+		d    Dialect
+		f    Feature
+		want bool
+	}{
+		{ANSI, FeatureExcept, true},
+		{ANSI, FeatureLateral, true},
+		{ANSI, FeatureTableSample, false},
+		{ANSI, FeatureReturning, false},
+		{SQLite, FeatureReturning, true},
+		{SQLite, FeatureLateral, false},
+		{Oracle, FeatureLateral, true},
+		{Oracle11, FeatureLateral, false},
+		{Oracle, FeatureExcept, true},
+		{BigQuery, FeatureTransactions, false},
+		{BigQuery, FeatureTableSample, true},
+		{Snowflake, FeatureTableSample, true},
+		{noTxDialect{}, FeatureTransactions, false},
+		{windowlessDialect{}, FeatureWindowFunctions, false},
+		{windowlessDialect{}, FeatureIntersect, true},
+	}
+	for i, tt := range capabilitiesTest {
+		if got := Supports(tt.d, tt.f); got != tt.want {
+			t.Errorf("%d has Supports(%s, %s) => %v, want %v", i, tt.d.Name(), tt.f, got, tt.want)
+		}
+	}
+	if c := Capabilities(SQLite); len(c) != len(Features) || !c[FeatureTransactions] || c[FeatureTableSample] {
+		t.Errorf("Capabilities(sqlite) => %v", c)
+	}
+
+	db, err := sql.Open("sqlite3", "file:capabilities?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	type saleTup struct {
+		ID     int
+		Amount int
+	}
+	keys := [][]string{[]string{"ID"}}
+	for _, d := range []Dialect{ANSI, windowlessDialect{}} {
+		r := New(db, "sales", saleTup{}, keys, WithDialect(d))
+		_, pushed := TopN(r, 1, nil, OrderBy{"Amount", true}).(*sqlTable)
+		if want := d == ANSI; pushed != want {
+			t.Errorf("%s has TopN() in the database %v, want %v", d.Name(), pushed, want)
+		}
+		_, pushed = r.Diff(New(db, "sales", saleTup{}, keys, WithDialect(d))).(*sqlTable)
+		if want := d == ANSI; pushed != want {
+			t.Errorf("%s has Diff() in the database %v, want %v", d.Name(), pushed, want)
+		}
+	}
+}
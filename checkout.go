@@ -0,0 +1,54 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// WithCheckoutTimeout sets how long a read waits for a connection from the
+// database's pool, which is exhausted when other reads, often streams of
+// other relations whose consumers are slow, hold all of its connections.  A
+// read that waits longer fails with a CheckoutError, instead of waiting until
+// a connection is released.  Zero, the default, waits as long as it takes.
+func WithCheckoutTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.checkoutTimeout = d
+	}
+}
+
+// CheckoutError is the error of a read that timed out waiting for a
+// connection from the pool.
+type CheckoutError struct {
+	// Relation is the text of the relation that was read
+	Relation string
+
+	// Timeout is the checkout timeout that was exceeded
+	Timeout time.Duration
+
+	// Stats are the statistics of the pool when the read gave up, where
+	// InUse is usually MaxOpenConnections and WaitCount the number of
+	// reads that have had to wait
+	Stats sql.DBStats
+}
+
+// Error returns a description of the error
+func (e *CheckoutError) Error() string {
+	return fmt.Sprintf("relsql: no connection available for %s after %v: %d of %d connections in use, %d waits totaling %v",
+		e.Relation, e.Timeout, e.Stats.InUse, e.Stats.MaxOpenConnections, e.Stats.WaitCount, e.Stats.WaitDuration)
+}
+
+// checkout takes a connection from the relation's pool within the checkout
+// timeout, and logs the pool's statistics if it can't.
+func (r1 *sqlTable) checkout(ctx context.Context) (*sql.Conn, error) {
+	tctx, cancel := context.WithTimeout(ctx, r1.opts.checkoutTimeout)
+	defer cancel()
+	conn, err := r1.db.Conn(tctx)
+	if err == nil || ctx.Err() != nil || tctx.Err() != context.DeadlineExceeded {
+		return conn, err
+	}
+	cerr := &CheckoutError{Relation: r1.String(), Timeout: r1.opts.checkoutTimeout, Stats: r1.db.Stats()}
+	r1.opts.warnf("%v", cerr)
+	return nil, cerr
+}
@@ -0,0 +1,73 @@
+package relsql
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test timing out reads that wait for a connection from an exhausted pool
+func TestCheckoutTimeout(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:checkout?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type checkoutTup struct {
+		Name string
+	}
+	if err := CreateTable(db, "checkouts", checkoutTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "checkouts", rel.New([]checkoutTup{{"a"}, {"b"}}, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	db.SetMaxOpenConns(1)
+	var logged []string
+	logf := func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	newRel := func() rel.Relation {
+		return New(db, "checkouts", checkoutTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithCheckoutTimeout(20*time.Millisecond), WithLogger(logf))
+	}
+	read := func(r rel.Relation) (int, error) {
+		ch := make(chan checkoutTup)
+		r.TupleChan(ch)
+		n := 0
+		for range ch {
+			n++
+		}
+		return n, r.Err()
+	}
+	if n, err := read(newRel()); n != 2 || err != nil {
+		t.Errorf("read => %d, %v, want 2 tuples", n, err)
+	}
+
+	// a stream whose consumer stalls holds the only connection
+	held := make(chan checkoutTup)
+	cancel := newRel().TupleChan(held)
+	<-held
+	_, err = read(newRel())
+	var cerr *CheckoutError
+	if !errors.As(err, &cerr) {
+		t.Errorf("read from an exhausted pool => %v, want a CheckoutError", err)
+	} else if cerr.Stats.InUse != 1 || cerr.Stats.MaxOpenConnections != 1 || cerr.Relation != newRel().String() {
+		t.Errorf("CheckoutError => %v, %+v", cerr, cerr.Stats)
+	}
+	if len(logged) == 0 || !strings.Contains(logged[len(logged)-1], "no connection available") {
+		t.Errorf("pool saturation was logged as %v", logged)
+	}
+	close(cancel)
+	time.Sleep(10 * time.Millisecond)
+	if n, err := read(newRel()); n != 2 || err != nil {
+		t.Errorf("read after the connection is released => %d, %v, want 2 tuples", n, err)
+	}
+}
@@ -0,0 +1,132 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Cipher encrypts and decrypts the values of sensitive attributes.
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// WithEncryption declares an attribute, which has to be a string or a []byte,
+// whose values are encrypted with the cipher before they are written, and
+// decrypted after they are scanned.  The column holds the ciphertext, so
+// tables created with the option use a BLOB column for it.  Predicates,
+// joins and set operations involving the relation, and summaries, orderings,
+// hashes and DISTINCT projections that involve the attribute, are evaluated
+// client side.
+// Encryption is applied before any other transformer when reading, and after
+// them when writing.
+func WithEncryption(att string, c Cipher) Option {
+	return func(o *options) {
+		o.ciphers = append(o.ciphers, transform{att, cipherTransform{c}})
+	}
+}
+
+// encrypted returns true if the column is encrypted
+func (o *options) encrypted(colName string) bool {
+	for _, tr := range o.ciphers {
+		if tr.att == colName {
+			return true
+		}
+	}
+	return false
+}
+
+// anyEncrypted returns true if any of the columns are encrypted
+func (o *options) anyEncrypted(cols []column) bool {
+	for _, c := range cols {
+		if o.encrypted(c.name) {
+			return true
+		}
+	}
+	return false
+}
+
+// touchesEncrypted returns true if any of the attributes of the relation are
+// held in encrypted columns.  The database only sees their ciphertext, so an
+// operation that compares, orders, groups, aggregates or hashes them has to
+// be evaluated client side.
+func (r1 *sqlTable) touchesEncrypted(atts ...string) bool {
+	if len(r1.opts.ciphers) == 0 {
+		return false
+	}
+	e := reflect.TypeOf(r1.zero)
+	for _, att := range atts {
+		if f, ok := e.FieldByName(att); ok && r1.opts.encrypted(r1.cols[f.Index[0]].name) {
+			return true
+		}
+	}
+	return false
+}
+
+// headingNames returns the names of the attributes of tuples of type e
+func headingNames(e reflect.Type) []string {
+	names := make([]string, e.NumField())
+	for i := range names {
+		names[i] = e.Field(i).Name
+	}
+	return names
+}
+
+// aggAttributes returns the attributes of the aggregates, which Count has
+// none of
+func aggAttributes(aggs []Agg) []string {
+	var atts []string
+	for _, a := range aggs {
+		if a.att != "" {
+			atts = append(atts, a.att)
+		}
+	}
+	return atts
+}
+
+// orderAttributes returns the attributes of an ordering
+func orderAttributes(order []OrderBy) []string {
+	atts := make([]string, len(order))
+	for i, o := range order {
+		atts[i] = o.Attribute
+	}
+	return atts
+}
+
+// cipherTransform is the transformer for an encrypted attribute
+type cipherTransform struct {
+	c Cipher
+}
+
+// Read decrypts a scanned value
+func (t cipherTransform) Read(v interface{}) (interface{}, error) {
+	b, err := cipherBytes(v)
+	if err != nil {
+		return nil, err
+	}
+	if b, err = t.c.Decrypt(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Write encrypts a value before it is written
+func (t cipherTransform) Write(v interface{}) (interface{}, error) {
+	b, err := cipherBytes(v)
+	if err != nil {
+		return nil, err
+	}
+	return t.c.Encrypt(b)
+}
+
+// cipherBytes returns the bytes of a string or []byte value
+func cipherBytes(v interface{}) ([]byte, error) {
+	rv := reflect.ValueOf(v)
+	switch {
+	case rv.Kind() == reflect.String:
+		return []byte(rv.String()), nil
+	case rv.Kind() == reflect.Slice && rv.Type().Elem().Kind() == reflect.Uint8:
+		return rv.Bytes(), nil
+	}
+	return nil, fmt.Errorf("relsql: can't encrypt %T", v)
+}
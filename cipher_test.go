@@ -0,0 +1,214 @@
+package relsql
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// xorCipher is a toy cipher for tests
+type xorCipher byte
+
+func (c xorCipher) Encrypt(b []byte) ([]byte, error) {
+	res := make([]byte, len(b)+1)
+	res[0] = '!'
+	for i, x := range b {
+		res[i+1] = x ^ byte(c)
+	}
+	return res, nil
+}
+
+func (c xorCipher) Decrypt(b []byte) ([]byte, error) {
+	res := make([]byte, len(b)-1)
+	for i, x := range b[1:] {
+		res[i] = x ^ byte(c)
+	}
+	return res, nil
+}
+
+// test encrypted attributes
+func TestEncryption(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type patientTup struct {
+		ID  int
+		SSN string `relsql:"trim"`
+	}
+	ckeys := [][]string{[]string{"ID"}}
+	enc := WithEncryption("SSN", xorCipher(0x5a))
+	if str, _ := CreateTableString(ANSI, "patients", patientTup{}, ckeys, enc); str != "CREATE TABLE patients (ID BIGINT NOT NULL, SSN BLOB NOT NULL, PRIMARY KEY (ID))" {
+		t.Errorf("CreateTableString() => %v", str)
+	}
+	if err := CreateTable(db, "patients", patientTup{}, ckeys, enc); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	patients := rel.New([]patientTup{{1, " 123-45-6789"}, {2, "987-65-4321"}}, ckeys)
+	if _, err := Insert(db, "patients", patients, enc); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var stored []byte
+	db.QueryRow("select SSN from patients where ID = 1").Scan(&stored)
+	if want, _ := xorCipher(0x5a).Encrypt([]byte("123-45-6789")); !bytes.Equal(stored, want) {
+		t.Errorf("stored ssn => %q, want %q", stored, want)
+	}
+
+	r := New(db, "patients", patientTup{}, ckeys, enc)
+	if _, ok := r.Restrict(Attribute("SSN").EQ("987-65-4321")).(*sqlTable); ok {
+		t.Errorf("restriction on encrypted attribute was pushed down")
+	}
+	if _, ok := r.Restrict(Attribute("ID").EQ(2)).(*sqlTable); !ok {
+		t.Errorf("restriction on plain attribute was not pushed down")
+	}
+	if _, ok := r.Union(New(db, "patients", patientTup{}, ckeys, enc)).(*sqlTable); ok {
+		t.Errorf("union of encrypted relations was pushed down")
+	}
+
+	found := r.Restrict(Attribute("SSN").EQ("987-65-4321"))
+	ch := make(chan patientTup)
+	found.TupleChan(ch)
+	var res []patientTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if want := []patientTup{{2, "987-65-4321"}}; !reflect.DeepEqual(res, want) {
+		t.Errorf("decrypted patients => %v, want %v", res, want)
+	}
+}
+
+// nonceCipher is a toy cipher whose ciphertexts of the same plaintext
+// differ, like those of a randomized cipher
+type nonceCipher struct {
+	n *byte
+}
+
+func (c nonceCipher) Encrypt(b []byte) ([]byte, error) {
+	*c.n++
+	return append([]byte{*c.n}, b...), nil
+}
+
+func (c nonceCipher) Decrypt(b []byte) ([]byte, error) {
+	return b[1:], nil
+}
+
+// test that operations on encrypted attributes are evaluated client side
+func TestEncryptionPushdown(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:encryptionpushdown?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type patientTup struct {
+		ID    int
+		Ward  string
+		Score int
+	}
+	type wardTup struct {
+		Ward string
+	}
+	type countTup struct {
+		Ward string
+		N    int
+	}
+	ckeys := [][]string{[]string{"ID"}}
+	var n byte
+	enc := WithEncryption("Ward", nonceCipher{&n})
+	if err := CreateTable(db, "patients", patientTup{}, ckeys, enc); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	patients := rel.New([]patientTup{{1, "A", 3}, {2, "A", 5}, {3, "B", 4}}, ckeys)
+	if _, err := Insert(db, "patients", patients, enc, WithDialect(SQLite)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	r := New(db, "patients", patientTup{}, ckeys, enc, WithDialect(SQLite))
+	hashed := New(db, "patients", patientTup{}, ckeys, enc, WithDialect(sumDialect{}))
+
+	var pushTest = []struct {
+		op     string
+		r      rel.Relation
+		pushed bool
+	}{
+		{"Project", r.Project(wardTup{}), false},
+		{"Project", r.Project(struct{ ID int }{}), true},
+		{"Project", r.Project(struct {
+			ID   int
+			Ward string
+		}{}), true},
+		{"Summarize", Summarize(r, []string{"Ward"}, countTup{}, Count("N")), false},
+		{"Summarize", Summarize(r, nil, struct{ Ward string }{}, Max("Ward", "Ward")), false},
+		{"Summarize", Summarize(r, nil, struct{ Score int }{}, Max("Score", "Score")), true},
+		{"TopN", TopN(r, 1, []string{"Ward"}, OrderBy{"Score", true}), false},
+		{"TopN", TopN(r, 1, nil, OrderBy{"Ward", false}), false},
+		{"TopN", TopN(r, 1, nil, OrderBy{"Score", true}), true},
+		{"Running", Running(r, []string{"Ward"}, []OrderBy{{"ID", false}}, struct {
+			ID    int
+			Ward  string
+			Score int
+			Total int
+		}{}, Sum("Score", "Total")), false},
+		{"Running", Running(r, nil, []OrderBy{{"ID", false}}, struct {
+			ID    int
+			Ward  string
+			Score int
+			Total int
+		}{}, Sum("Score", "Total")), true},
+		{"Bucketize", Bucketize(r, "Score", []float64{4}, "Bin", struct {
+			Bin  int
+			Ward string
+		}{}, Max("Ward", "Ward")), false},
+		{"Bucketize", Bucketize(r, "Score", []float64{4}, "Bin", struct {
+			Bin int
+			N   int
+		}{}, Count("N")), true},
+	}
+	for i, tt := range pushTest {
+		if _, ok := tt.r.(*sqlTable); ok != tt.pushed {
+			t.Errorf("%d has %s pushed down %v, want %v", i, tt.op, ok, tt.pushed)
+		}
+		if err := drainErr(tt.r); err != nil {
+			t.Errorf("%d has %s error %v", i, tt.op, err)
+		}
+	}
+
+	// the client side results are of the plaintext
+	ch := make(chan wardTup)
+	r.Project(wardTup{}).TupleChan(ch)
+	var wards []wardTup
+	for tup := range ch {
+		wards = append(wards, tup)
+	}
+	if len(wards) != 2 {
+		t.Errorf("projection => %v, want 2 wards", wards)
+	}
+	counts := make(chan countTup)
+	Summarize(r, []string{"Ward"}, countTup{}, Count("N")).TupleChan(counts)
+	got := make(map[string]int)
+	for tup := range counts {
+		got[tup.Ward] = tup.N
+	}
+	if want := map[string]int{"A": 2, "B": 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("summary => %v, want %v", got, want)
+	}
+
+	c, err := Hash(context.Background(), hashed)
+	if err != nil || c.Method != "client" {
+		t.Errorf("Hash() => %+v, %v, want a client side hash", c, err)
+	}
+	if c2, _ := Hash(context.Background(), hashed.Project(struct{ ID int }{})); c2.Method == "client" {
+		t.Errorf("Hash() of plain attributes => %+v, want a server side hash", c2)
+	}
+}
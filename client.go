@@ -0,0 +1,130 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+)
+
+// clientOp is an operation that is evaluated client side by a function that
+// reads its inputs and sends the resulting tuples.
+type clientOp struct {
+	// op is the name of the operation, and str its text representation
+	op  string
+	str string
+
+	inputs []rel.Relation
+	zero   interface{}
+	cKeys  rel.CandKeys
+
+	// run sends the tuples of the operation with send, and stops if send
+	// returns an error
+	run func(send func(tup reflect.Value) error) error
+
+	err error
+}
+
+// TupleChan sends the tuples of the operation on the channel
+func (r *clientOp) TupleChan(t interface{}) chan<- struct{} {
+	cancel := make(chan struct{})
+	chv := reflect.ValueOf(t)
+	if err := rel.EnsureChan(chv.Type(), r.zero); err != nil {
+		r.err = err
+		return cancel
+	}
+	if err := r.Err(); err != nil {
+		chv.Close()
+		return cancel
+	}
+	go func() {
+		canSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cancel)}
+		err := r.run(func(tup reflect.Value) error {
+			resSel := reflect.SelectCase{Dir: reflect.SelectSend, Chan: chv, Send: tup}
+			if chosen, _, _ := reflect.Select([]reflect.SelectCase{canSel, resSel}); chosen == 0 {
+				return errStopped
+			}
+			return nil
+		})
+		if err == errStopped {
+			return
+		}
+		if err != nil {
+			r.err = err
+		}
+		chv.Close()
+	}()
+	return cancel
+}
+
+// Zero returns the zero value of the operation's tuples
+func (r *clientOp) Zero() interface{} {
+	return r.zero
+}
+
+// CKeys returns the candidate keys of the operation
+func (r *clientOp) CKeys() rel.CandKeys {
+	return r.cKeys
+}
+
+// GoString returns a text representation of the operation
+func (r *clientOp) GoString() string {
+	return fmt.Sprintf("relsql.clientOp{%s, %#v}", r.op, r.inputs)
+}
+
+// String returns a text representation of the operation
+func (r *clientOp) String() string {
+	return r.str
+}
+
+// Project is evaluated client side
+func (r *clientOp) Project(z2 interface{}) rel.Relation {
+	return rel.NewProject(r, z2)
+}
+
+// Restrict is evaluated client side
+func (r *clientOp) Restrict(p rel.Predicate) rel.Relation {
+	return rel.NewRestrict(r, p)
+}
+
+// Rename is evaluated client side
+func (r *clientOp) Rename(z2 interface{}) rel.Relation {
+	return rel.NewRename(r, z2)
+}
+
+// Union is evaluated client side
+func (r *clientOp) Union(r2 rel.Relation) rel.Relation {
+	return rel.NewUnion(r, r2)
+}
+
+// Diff is evaluated client side
+func (r *clientOp) Diff(r2 rel.Relation) rel.Relation {
+	return rel.NewDiff(r, r2)
+}
+
+// Join is evaluated client side
+func (r *clientOp) Join(r2 rel.Relation, zero interface{}) rel.Relation {
+	return rel.NewJoin(r, r2, zero)
+}
+
+// GroupBy is evaluated client side
+func (r *clientOp) GroupBy(t2, gfcn interface{}) rel.Relation {
+	return rel.NewGroupBy(r, t2, gfcn)
+}
+
+// Map is evaluated client side
+func (r *clientOp) Map(mfcn interface{}, ckeystr [][]string) rel.Relation {
+	return rel.NewMap(r, mfcn, ckeystr)
+}
+
+// Err returns the first error of the operation or its inputs
+func (r *clientOp) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	for _, in := range r.inputs {
+		if err := in.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
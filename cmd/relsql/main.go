@@ -0,0 +1,214 @@
+// Command relsql opens a database, discovers its tables as relations, and
+// reads relational expressions over them interactively, printing the sql that
+// each one compiles to and its first tuples.  It is both a demonstration of
+// relsql and a tool for exploring a schema.
+//
+// Usage:
+//
+//	relsql [-driver sqlite3] [-rows 20] dsn
+//
+// Each line is one of:
+//
+//	tables                   list the relations and their headings
+//	describe expr            describe a relation
+//	sql expr                 print the sql of a relation
+//	name = expr              name a relation
+//	expr                     print the sql and the first tuples of a relation
+//	help, quit
+//
+// where expr is the name of a table or relation, or one of
+//
+//	project(expr, Att, ...)
+//	restrict(expr, Att op value, ...)
+//	join(expr, expr)
+//	union(expr, expr)
+//	diff(expr, expr)
+//
+// Attributes are named after the columns, with the first letter upper cased
+// so that they are exported.  op is one of = <> < <= > >=, and values are
+// numbers or 'quoted strings'.
+//
+// Only the sqlite3 driver is linked in.  Other databases can be explored by
+// adding their driver's import to this command.
+package main
+
+import (
+	"bufio"
+	"database/sql"
+	"flag"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"github.com/jonlawlor/relsql"
+	_ "github.com/mattn/go-sqlite3"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+func main() {
+	driver := flag.String("driver", "sqlite3", "the database/sql driver")
+	rows := flag.Int("rows", 20, "the most tuples to print for each expression")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		fmt.Fprintln(os.Stderr, "usage: relsql [-driver sqlite3] [-rows 20] dsn")
+		os.Exit(2)
+	}
+	dsn := flag.Arg(0)
+	db, err := sql.Open(*driver, dsn)
+	if err == nil && relsql.DialectOf(db) == relsql.SQLite {
+		err = relsql.CheckSQLiteDSN(dsn)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer db.Close()
+
+	rels, err := discover(db)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	r := &repl{rels: rels, rows: *rows}
+	r.run(bufio.NewScanner(os.Stdin), os.Stdout, true)
+}
+
+// tablesQuery returns the query that lists the tables of a database in the
+// dialect
+func tablesQuery(d relsql.Dialect) string {
+	switch d {
+	case relsql.SQLite:
+		return "SELECT name FROM sqlite_master WHERE type IN ('table', 'view') AND name NOT LIKE 'sqlite_%' ORDER BY name"
+	case relsql.Oracle, relsql.Oracle11:
+		return "SELECT table_name FROM user_tables ORDER BY table_name"
+	}
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema NOT IN ('information_schema', 'pg_catalog') ORDER BY table_name"
+}
+
+// discover returns a relation for each of the tables of the database, by
+// name.
+func discover(db *sql.DB) (map[string]rel.Relation, error) {
+	rows, err := db.Query(tablesQuery(relsql.DialectOf(db)))
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rels := make(map[string]rel.Relation)
+	for _, name := range names {
+		r, err := tableRelation(db, name)
+		if err != nil {
+			return nil, err
+		}
+		rels[name] = r
+	}
+	return rels, nil
+}
+
+// tableRelation returns a relation for a table, whose tuple type is built
+// from the types of its columns.  Columns whose names aren't exported Go
+// identifiers, apart from their case, are renamed in a query that selects
+// from the table.
+func tableRelation(db *sql.DB, tableName string) (rel.Relation, error) {
+	rows, err := db.Query("SELECT * FROM " + tableName + " WHERE 1 = 0")
+	if err != nil {
+		return nil, err
+	}
+	colTypes, err := rows.ColumnTypes()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]reflect.StructField, len(colTypes))
+	sel := make([]string, len(colTypes))
+	names := make(map[string]string)
+	renamed := false
+	for i, ct := range colTypes {
+		name := fieldName(ct.Name(), i)
+		nullable, ok := ct.Nullable()
+		fields[i] = reflect.StructField{Name: name, Type: goType(ct.DatabaseTypeName(), nullable || !ok)}
+		col := ct.Name()
+		if !isIdent(col) {
+			col = `"` + strings.Replace(col, `"`, `""`, -1) + `"`
+		}
+		sel[i] = col + " AS " + name
+		names[ct.Name()] = name
+		renamed = renamed || !strings.EqualFold(name, ct.Name())
+	}
+	z := reflect.New(reflect.StructOf(fields)).Elem().Interface()
+
+	var keys [][]string
+	if relsql.DialectOf(db) == relsql.SQLite {
+		ckeystr, err := relsql.SQLiteKeys(db, tableName)
+		if err != nil {
+			return nil, err
+		}
+		for _, ck := range ckeystr {
+			key := make([]string, len(ck))
+			for i, col := range ck {
+				key[i] = names[col]
+			}
+			keys = append(keys, key)
+		}
+	}
+	if renamed {
+		q := "SELECT " + strings.Join(sel, ", ") + " FROM " + tableName
+		return relsql.NewFromScript(db, nil, q, z, keys), nil
+	}
+	return relsql.New(db, tableName, z, keys), nil
+}
+
+// fieldName returns an exported Go identifier for the i'th column
+func fieldName(col string, i int) string {
+	rs := []rune(col)
+	for j, c := range rs {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			rs[j] = '_'
+		}
+	}
+	if len(rs) == 0 || !unicode.IsLetter(rs[0]) {
+		return fmt.Sprintf("C%d%s", i+1, string(rs))
+	}
+	rs[0] = unicode.ToUpper(rs[0])
+	return string(rs)
+}
+
+// goType returns the Go type of a column from its database type name.
+// Columns that can hold NULL have the sql.Null type of the Go type.
+func goType(typeName string, nullable bool) reflect.Type {
+	typeName = strings.ToUpper(typeName)
+	var t, nt reflect.Type
+	switch {
+	case strings.Contains(typeName, "INT"):
+		t, nt = reflect.TypeOf(int64(0)), reflect.TypeOf(sql.NullInt64{})
+	case strings.Contains(typeName, "REAL"), strings.Contains(typeName, "FLOA"), strings.Contains(typeName, "DOUB"),
+		strings.Contains(typeName, "NUMERIC"), strings.Contains(typeName, "DECIMAL"):
+		t, nt = reflect.TypeOf(float64(0)), reflect.TypeOf(sql.NullFloat64{})
+	case strings.Contains(typeName, "BOOL"):
+		t, nt = reflect.TypeOf(false), reflect.TypeOf(sql.NullBool{})
+	case strings.Contains(typeName, "DATE"), strings.Contains(typeName, "TIME"):
+		t, nt = reflect.TypeOf(time.Time{}), reflect.TypeOf(sql.NullTime{})
+	case strings.Contains(typeName, "BLOB"), strings.Contains(typeName, "BYTEA"), strings.Contains(typeName, "BINARY"):
+		// a nil slice is NULL
+		return reflect.TypeOf([]byte(nil))
+	default:
+		t, nt = reflect.TypeOf(""), reflect.TypeOf(sql.NullString{})
+	}
+	if nullable {
+		return nt
+	}
+	return t
+}
@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"github.com/jonlawlor/relsql"
+	"io"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+)
+
+// repl reads relational expressions and prints their sql and tuples
+type repl struct {
+	// rels are the relations that expressions can refer to, by name
+	rels map[string]rel.Relation
+
+	// rows is the most tuples to print for each expression
+	rows int
+}
+
+// run reads lines from in until it ends or a line is quit, and writes the
+// results of each to out.  If prompt is true, a prompt is written before
+// each line.
+func (r *repl) run(in *bufio.Scanner, out io.Writer, prompt bool) {
+	for {
+		if prompt {
+			fmt.Fprint(out, "relsql> ")
+		}
+		if !in.Scan() {
+			return
+		}
+		line := strings.TrimSpace(in.Text())
+		if line == "quit" || line == "exit" {
+			return
+		}
+		if err := r.exec(line, out); err != nil {
+			fmt.Fprintln(out, "error:", err)
+		}
+	}
+}
+
+// exec executes one line
+func (r *repl) exec(line string, out io.Writer) error {
+	cmd, rest := line, ""
+	if i := strings.IndexFunc(line, unicode.IsSpace); i >= 0 {
+		cmd, rest = line[:i], strings.TrimSpace(line[i:])
+	}
+	switch cmd {
+	case "":
+		return nil
+	case "help":
+		fmt.Fprintln(out, "tables | describe expr | sql expr | name = expr | expr | quit")
+		fmt.Fprintln(out, "expr: name | project(expr, Att, ...) | restrict(expr, Att op value, ...) | join(expr, expr) | union(expr, expr) | diff(expr, expr)")
+		return nil
+	case "tables":
+		names := make([]string, 0, len(r.rels))
+		for name := range r.rels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(out, "%s%v\n", name, rel.Heading(r.rels[name]))
+		}
+		return nil
+	case "describe", "sql":
+		rl, err := r.parse(rest)
+		if err != nil {
+			return err
+		}
+		if cmd == "sql" {
+			q, _, err := relsql.SQL(rl)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintln(out, q)
+			return nil
+		}
+		desc, err := relsql.Describe(context.Background(), rl)
+		if err != nil {
+			return err
+		}
+		fmt.Fprint(out, desc)
+		return nil
+	}
+	if i := strings.Index(line, "="); i > 0 && isIdent(strings.TrimSpace(line[:i])) {
+		name := strings.TrimSpace(line[:i])
+		rl, err := r.parse(line[i+1:])
+		if err != nil {
+			return err
+		}
+		r.rels[name] = rl
+		fmt.Fprintf(out, "%s%v\n", name, rel.Heading(rl))
+		return nil
+	}
+	rl, err := r.parse(line)
+	if err != nil {
+		return err
+	}
+	return r.print(rl, out)
+}
+
+// errEnough stops the export of a relation once enough tuples are printed
+var errEnough = errors.New("enough tuples")
+
+// print writes the sql of a relation, or the operations that are evaluated
+// client side, and then its first tuples as a table.
+func (r *repl) print(rl rel.Relation, out io.Writer) error {
+	if q, _, err := relsql.SQL(rl); err == nil {
+		fmt.Fprintln(out, "--", q)
+	}
+	for _, d := range relsql.Diagnostics(rl) {
+		fmt.Fprintf(out, "-- client side: %s %s: %s\n", d.Op, d.Expr, d.Reason)
+	}
+	enc := &tableEncoder{w: tabwriter.NewWriter(out, 0, 4, 2, ' ', 0), max: r.rows}
+	err := relsql.Export(rl, enc)
+	if err == errEnough {
+		err = enc.Close()
+		fmt.Fprintf(out, "(first %d tuples)\n", r.rows)
+	}
+	return err
+}
+
+// tableEncoder writes tuples as an aligned table, and stops after max
+// tuples
+type tableEncoder struct {
+	w   *tabwriter.Writer
+	n   int
+	max int
+}
+
+func (e *tableEncoder) Heading(names []string) error {
+	_, err := fmt.Fprintln(e.w, strings.Join(names, "\t"))
+	return err
+}
+
+func (e *tableEncoder) Tuple(values []interface{}) error {
+	if e.n == e.max {
+		return errEnough
+	}
+	e.n++
+	strs := make([]string, len(values))
+	for i, v := range values {
+		if vr, ok := v.(driver.Valuer); ok {
+			v, _ = vr.Value()
+		}
+		if b, ok := v.([]byte); ok {
+			v = string(b)
+		}
+		if v == nil {
+			v = "NULL"
+		}
+		strs[i] = fmt.Sprint(v)
+	}
+	_, err := fmt.Fprintln(e.w, strings.Join(strs, "\t"))
+	return err
+}
+
+func (e *tableEncoder) Close() error {
+	return e.w.Flush()
+}
+
+// parser parses a relational expression from its tokens
+type parser struct {
+	r    *repl
+	toks []string
+	pos  int
+}
+
+// parse parses an expression, which has to make up all of s
+func (r *repl) parse(s string) (rel.Relation, error) {
+	toks, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{r: r, toks: toks}
+	rl, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(toks) {
+		return nil, fmt.Errorf("unexpected %q", toks[p.pos])
+	}
+	return rl, rl.Err()
+}
+
+// tokenize splits an expression into identifiers, numbers, quoted strings,
+// comparison operators and punctuation.
+func tokenize(s string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'':
+			j := i + 1
+			for ; j < len(s); j++ {
+				if s[j] == '\'' {
+					if j+1 < len(s) && s[j+1] == '\'' {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j == len(s) {
+				return nil, errors.New("unterminated string")
+			}
+			toks = append(toks, s[i:j+1])
+			i = j + 1
+		case strings.ContainsRune("(),", c):
+			toks = append(toks, string(c))
+			i++
+		case strings.ContainsRune("<>=!", c):
+			j := i + 1
+			for j < len(s) && strings.ContainsRune("<>=", rune(s[j])) {
+				j++
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		default:
+			j := i
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || s[j] == '-' && j == i || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			if j == i {
+				return nil, fmt.Errorf("unexpected %q", c)
+			}
+			toks = append(toks, s[i:j])
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+// isIdent returns true if s is a name
+func isIdent(s string) bool {
+	for i, c := range s {
+		if !(c == '_' || unicode.IsLetter(c) || i > 0 && unicode.IsDigit(c)) {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// next returns the next token, or an empty string at the end
+func (p *parser) next() string {
+	if p.pos == len(p.toks) {
+		return ""
+	}
+	p.pos++
+	return p.toks[p.pos-1]
+}
+
+// expect consumes the token tok
+func (p *parser) expect(tok string) error {
+	if t := p.next(); t != tok {
+		return fmt.Errorf("expected %q, got %q", tok, t)
+	}
+	return nil
+}
+
+// expr parses a name or an operation
+func (p *parser) expr() (rel.Relation, error) {
+	name := p.next()
+	if p.pos == len(p.toks) || p.toks[p.pos] != "(" {
+		rl, ok := p.r.rels[name]
+		if !ok {
+			return nil, fmt.Errorf("no relation named %q", name)
+		}
+		return rl, nil
+	}
+	p.next()
+	r1, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+	var res rel.Relation
+	switch name {
+	case "project":
+		res, err = p.project(r1)
+	case "restrict":
+		res, err = p.restrict(r1)
+	case "join", "union", "diff":
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		r2, err := p.expr()
+		if err != nil {
+			return nil, err
+		}
+		if name != "join" && reflect.TypeOf(r1.Zero()) != reflect.TypeOf(r2.Zero()) {
+			return nil, fmt.Errorf("%s of %v with %v requires identical headings", name, rel.Heading(r1), rel.Heading(r2))
+		}
+		switch name {
+		case "join":
+			res = r1.Join(r2, joinZero(r1, r2))
+		case "union":
+			res = r1.Union(r2)
+		case "diff":
+			res = r1.Diff(r2)
+		}
+	default:
+		return nil, fmt.Errorf("unknown operation %q", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return res, p.expect(")")
+}
+
+// project parses the attributes of a projection of r
+func (p *parser) project(r rel.Relation) (rel.Relation, error) {
+	e := reflect.TypeOf(r.Zero())
+	var fields []reflect.StructField
+	for p.pos < len(p.toks) && p.toks[p.pos] == "," {
+		p.next()
+		att := p.next()
+		f, ok := e.FieldByName(att)
+		if !ok {
+			return nil, fmt.Errorf("attribute %s is not in %v", att, rel.Heading(r))
+		}
+		fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type})
+	}
+	return r.Project(reflect.New(reflect.StructOf(fields)).Elem().Interface()), nil
+}
+
+// restrict parses the comparisons of a restriction of r, which are and'ed
+func (p *parser) restrict(r rel.Relation) (rel.Relation, error) {
+	e := reflect.TypeOf(r.Zero())
+	var preds []relsql.Pred
+	for p.pos < len(p.toks) && p.toks[p.pos] == "," {
+		p.next()
+		att, op, lit := p.next(), p.next(), p.next()
+		f, ok := e.FieldByName(att)
+		if !ok {
+			return nil, fmt.Errorf("attribute %s is not in %v", att, rel.Heading(r))
+		}
+		v, err := value(lit, f.Type)
+		if err != nil {
+			return nil, err
+		}
+		a := relsql.Attribute(att)
+		switch op {
+		case "=":
+			preds = append(preds, a.EQ(v))
+		case "<>", "!=":
+			preds = append(preds, a.NE(v))
+		case "<":
+			preds = append(preds, a.LT(v))
+		case "<=":
+			preds = append(preds, a.LE(v))
+		case ">":
+			preds = append(preds, a.GT(v))
+		case ">=":
+			preds = append(preds, a.GE(v))
+		default:
+			return nil, fmt.Errorf("unknown comparison %q", op)
+		}
+	}
+	if len(preds) == 0 {
+		return r, nil
+	}
+	return r.Restrict(relsql.And(preds[0], preds[1:]...)), nil
+}
+
+// value converts a literal to a value that can be compared with an attribute
+// of type t
+func value(lit string, t reflect.Type) (interface{}, error) {
+	if strings.HasPrefix(lit, "'") {
+		return strings.Replace(lit[1:len(lit)-1], "''", "'", -1), nil
+	}
+	if n, err := strconv.ParseInt(lit, 10, 64); err == nil {
+		if t.Kind() == reflect.Float64 || t == reflect.TypeOf(sql.NullFloat64{}) {
+			return float64(n), nil
+		}
+		return n, nil
+	}
+	if x, err := strconv.ParseFloat(lit, 64); err == nil {
+		return x, nil
+	}
+	return nil, fmt.Errorf("%q is not a number or a quoted string", lit)
+}
+
+// joinZero returns the zero tuple of the natural join of two relations,
+// which has the attributes of r1 followed by those of r2 that aren't in r1.
+func joinZero(r1, r2 rel.Relation) interface{} {
+	e1, e2 := reflect.TypeOf(r1.Zero()), reflect.TypeOf(r2.Zero())
+	var fields []reflect.StructField
+	for i := 0; i < e1.NumField(); i++ {
+		fields = append(fields, reflect.StructField{Name: e1.Field(i).Name, Type: e1.Field(i).Type})
+	}
+	for i := 0; i < e2.NumField(); i++ {
+		if _, ok := e1.FieldByName(e2.Field(i).Name); !ok {
+			fields = append(fields, reflect.StructField{Name: e2.Field(i).Name, Type: e2.Field(i).Type})
+		}
+	}
+	return reflect.New(reflect.StructOf(fields)).Elem().Interface()
+}
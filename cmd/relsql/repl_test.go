@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+// test discovering the tables of a database, and evaluating expressions
+// over them
+func TestREPL(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:repl?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE users (user_id INTEGER PRIMARY KEY, name TEXT NOT NULL, city TEXT)",
+		`CREATE TABLE orders (OrderID INTEGER PRIMARY KEY, User_id INTEGER, Total REAL, "paid at" TEXT)`,
+		"INSERT INTO users VALUES (1, 'ann', 'paris'), (2, 'bob', NULL), (3, 'cy', 'rome')",
+		"INSERT INTO orders VALUES (10, 1, 5.5, NULL), (11, 1, 2, NULL), (12, 3, 9, '2024-01-02')",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Errorf("Exec(%q) => %v", stmt, err)
+			return
+		}
+	}
+	rels, err := discover(db)
+	if err != nil {
+		t.Errorf("discover() => %v", err)
+		return
+	}
+
+	var replTest = []struct {
+		line string
+		want []string
+	}{
+		{"tables", []string{"orders[OrderID User_id Total Paid_at]", "users[User_id Name City]"}},
+		{"users", []string{"-- SELECT User_id, Name, City FROM users", "bob", "NULL", "(first 2 tuples)"}},
+		{"sql orders", []string{`FROM (SELECT OrderID AS OrderID, User_id AS User_id, Total AS Total, "paid at" AS Paid_at FROM orders) AS s`}},
+		{"restrict(users, User_id > 1, Name <> 'cy')", []string{"WHERE User_id > ? AND Name <> ?", "bob"}},
+		{"big = restrict(orders, Total >= 5)", []string{"big[OrderID User_id Total Paid_at]"}},
+		{"project(join(users, big), Name, Total)", []string{"JOIN", "ann   5.5", "cy    9"}},
+		{"sql project(users, City)", []string{"SELECT DISTINCT City FROM"}},
+		{"describe users", []string{"User_id sql.NullInt64", "keys: {User_id}"}},
+		{"union(users, orders)", []string{"error: union of"}},
+		{"restrict(users, Missing = 1)", []string{"error: attribute Missing"}},
+		{"nope", []string{`error: no relation named "nope"`}},
+	}
+	for i, tt := range replTest {
+		var out bytes.Buffer
+		r := &repl{rels: rels, rows: 2}
+		r.run(bufio.NewScanner(strings.NewReader(tt.line)), &out, false)
+		for _, want := range tt.want {
+			if !strings.Contains(out.String(), want) {
+				t.Errorf("%d %s => %q, want %q", i, tt.line, out.String(), want)
+			}
+		}
+	}
+}
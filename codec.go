@@ -0,0 +1,103 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec converts between the values of a Go type and the representation of
+// those values in a database column.  Codecs are registered for a Go type with
+// RegisterCodec, and are then used for every attribute of that type: when
+// scanning query results, when binding predicate values and written tuples as
+// query arguments, and when generating DDL.
+type Codec interface {
+	// Encode converts a Go value into a value that the driver accepts as a
+	// query argument.
+	Encode(d Dialect, v interface{}) (interface{}, error)
+
+	// Decode converts a value scanned from the driver, which may be nil for
+	// NULL, and stores it in dst, which is addressable.
+	Decode(d Dialect, src interface{}, dst reflect.Value) error
+
+	// TypeName returns the column type used for values in the dialect.
+	TypeName(d Dialect) string
+}
+
+// codecs holds the registered codecs, by Go type
+var codecs = struct {
+	sync.RWMutex
+	m map[reflect.Type]Codec
+}{m: make(map[reflect.Type]Codec)}
+
+// RegisterCodec sets the codec used for attributes of type t, replacing any
+// codec that was already registered for it.
+func RegisterCodec(t reflect.Type, c Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.m[t] = c
+}
+
+// lookupCodec returns the codec registered for t, or nil if there is none
+func lookupCodec(t reflect.Type) Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.m[t]
+}
+
+// encodeArg converts a query argument with the codec for its type in the
+// dialect, if there is one.
+func encodeArg(d Dialect, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	c := codecFor(d, reflect.TypeOf(v))
+	if c == nil {
+		if rv := reflect.ValueOf(v); isUint(rv) {
+			return encodeUint(d, rv.Uint())
+		}
+		return v, nil
+	}
+	return c.Encode(d, v)
+}
+
+// codecScanner scans a column into a field with a codec
+type codecScanner struct {
+	c   Codec
+	d   Dialect
+	dst reflect.Value
+}
+
+// Scan implements the sql.Scanner interface
+func (s *codecScanner) Scan(src interface{}) error {
+	return s.c.Decode(s.d, src, s.dst)
+}
+
+// scanDest returns the destination to pass to Scan for a field of a tuple,
+// which is the address of the field unless its type has a codec or is an
+// unsigned integer.  If exact is true, numeric fields are checked for lossy
+// conversions.
+func scanDest(d Dialect, field reflect.Value, exact bool) interface{} {
+	if c := codecFor(d, field.Type()); c != nil {
+		return &codecScanner{c, d, field}
+	}
+	if field.Type() == lazyType {
+		// the column is a placeholder for a value that is fetched later
+		return new(interface{})
+	}
+	if isUint(field) {
+		return &uintScanner{field}
+	}
+	if exact {
+		switch k := field.Kind(); {
+		case field.Type() == decimalType, k == reflect.Float32, k == reflect.Float64:
+			return &exactScanner{field}
+		}
+	}
+	return field.Addr().Interface()
+}
+
+// decodeError returns an error for a value that a codec can't decode
+func decodeError(src interface{}, dst reflect.Value) error {
+	return fmt.Errorf("relsql: can't convert %T (%v) to %v", src, src, dst.Type())
+}
@@ -0,0 +1,282 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CompositeCodec returns a codec for a struct type which is stored in a column
+// of the postgres composite type named typeName, whose fields are the fields
+// of the struct in order.  Values are exchanged in the text form of records,
+// like (1,"Main St",t), so fields can be strings, numbers, bools, times,
+// pointers to them for NULLs, and other structs with a CompositeCodec.
+// Register it for the struct type with RegisterCodec, and create the type with
+// the statement from CreateTypeString.
+func CompositeCodec(typeName string) Codec {
+	return compositeCodec{typeName}
+}
+
+// compositeCodec converts structs to and from the text form of records
+type compositeCodec struct {
+	typeName string
+}
+
+// Encode returns the text form of the record for a struct
+func (c compositeCodec) Encode(d Dialect, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("relsql: %T is not a struct", v)
+	}
+	return encodeRecord(d, rv)
+}
+
+// encodeRecord returns the text form of the record for a struct, with NULL
+// fields left empty and other fields quoted where needed.
+func encodeRecord(d Dialect, rv reflect.Value) (string, error) {
+	fields := make([]string, rv.NumField())
+	for i := range fields {
+		f := rv.Field(i)
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				continue
+			}
+			f = f.Elem()
+		}
+		var s string
+		switch {
+		case f.Type() == timeType:
+			s = f.Interface().(time.Time).Format(time.RFC3339Nano)
+		case f.Kind() == reflect.Bool:
+			s = "f"
+			if f.Bool() {
+				s = "t"
+			}
+		case f.Kind() == reflect.Struct:
+			var err error
+			if s, err = encodeRecord(d, f); err != nil {
+				return "", err
+			}
+		case f.Kind() == reflect.String || isNumber(f):
+			s = fmt.Sprint(f.Interface())
+		default:
+			return "", fmt.Errorf("relsql: can't store %v in a composite type", f.Type())
+		}
+		fields[i] = quoteRecordField(s)
+	}
+	return "(" + strings.Join(fields, ",") + ")", nil
+}
+
+// quoteRecordField quotes a field of a record if it is empty or contains
+// characters that delimit fields, doubling quotes and backslashes.
+func quoteRecordField(s string) string {
+	if s != "" && !strings.ContainsAny(s, `,()"\ `) {
+		return s
+	}
+	return `"` + strings.NewReplacer(`"`, `""`, `\`, `\\`).Replace(s) + `"`
+}
+
+// Decode parses the text form of a record into a struct
+func (c compositeCodec) Decode(d Dialect, src interface{}, dst reflect.Value) error {
+	switch v := src.(type) {
+	case string:
+		return decodeRecord(v, dst)
+	case []byte:
+		return decodeRecord(string(v), dst)
+	}
+	return decodeError(src, dst)
+}
+
+// decodeRecord parses the text form of a record into the fields of a struct
+func decodeRecord(s string, dst reflect.Value) error {
+	fields, err := parseRecord(s)
+	if err != nil {
+		return err
+	}
+	if len(fields) != dst.NumField() {
+		return fmt.Errorf("relsql: record %s has %d fields, but %v has %d", s, len(fields), dst.Type(), dst.NumField())
+	}
+	for i, field := range fields {
+		f := dst.Field(i)
+		if field == nil {
+			f.Set(reflect.Zero(f.Type()))
+			continue
+		}
+		if f.Kind() == reflect.Ptr {
+			f.Set(reflect.New(f.Type().Elem()))
+			f = f.Elem()
+		}
+		if err := setRecordField(*field, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setRecordField sets a field of a struct from the text of a record field
+func setRecordField(s string, f reflect.Value) error {
+	var err error
+	switch {
+	case f.Type() == timeType:
+		var t time.Time
+		if t, err = timeValue(s); err == nil {
+			f.Set(reflect.ValueOf(t))
+		}
+	case f.Kind() == reflect.Struct:
+		err = decodeRecord(s, f)
+	case f.Kind() == reflect.String:
+		f.SetString(s)
+	case f.Kind() == reflect.Bool:
+		f.SetBool(s == "t" || s == "true")
+	case isUint(f):
+		var n uint64
+		if n, err = strconv.ParseUint(s, 10, f.Type().Bits()); err == nil {
+			f.SetUint(n)
+		}
+	case isInt(f.Type()):
+		var n int64
+		if n, err = strconv.ParseInt(s, 10, f.Type().Bits()); err == nil {
+			f.SetInt(n)
+		}
+	case f.Kind() == reflect.Float32 || f.Kind() == reflect.Float64:
+		var x float64
+		if x, err = strconv.ParseFloat(s, f.Type().Bits()); err == nil {
+			f.SetFloat(x)
+		}
+	default:
+		err = fmt.Errorf("can't convert to %v", f.Type())
+	}
+	if err != nil {
+		return fmt.Errorf("relsql: invalid record field %q: %v", s, err)
+	}
+	return nil
+}
+
+// parseRecord splits the text form of a record, like (1,"a ""b""",), into
+// its fields.  Fields that are empty and unquoted are NULL, which is nil.
+func parseRecord(s string) ([]*string, error) {
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return nil, fmt.Errorf("relsql: invalid record %q", s)
+	}
+	var fields []*string
+	var b strings.Builder
+	quoted, inQuotes := false, false
+	body := s[1 : len(s)-1]
+	for i := 0; i < len(body); i++ {
+		ch := body[i]
+		switch {
+		case inQuotes && ch == '"' && i+1 < len(body) && body[i+1] == '"':
+			b.WriteByte('"')
+			i++
+		case ch == '"':
+			inQuotes, quoted = !inQuotes, true
+		case ch == '\\' && i+1 < len(body):
+			i++
+			b.WriteByte(body[i])
+		case ch == ',' && !inQuotes:
+			fields = append(fields, recordField(b.String(), quoted))
+			b.Reset()
+			quoted = false
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	if inQuotes {
+		return nil, fmt.Errorf("relsql: invalid record %q", s)
+	}
+	return append(fields, recordField(b.String(), quoted)), nil
+}
+
+// recordField returns a field of a record, which is NULL if it is empty and
+// wasn't quoted.
+func recordField(s string, quoted bool) *string {
+	if s == "" && !quoted {
+		return nil
+	}
+	return &s
+}
+
+// TypeName returns the name of the composite type
+func (c compositeCodec) TypeName(d Dialect) string {
+	return c.typeName
+}
+
+// CreateTypeString returns the CREATE TYPE statement for a composite type
+// with a field for each field of z.
+func CreateTypeString(d Dialect, typeName string, z interface{}) (string, error) {
+	e := reflect.TypeOf(z)
+	if err := checkZero(e); err != nil {
+		return "", err
+	}
+	defs := make([]string, e.NumField())
+	for i := range defs {
+		typeName, _, err := columnType(d, e.Field(i).Type)
+		if err != nil {
+			return "", err
+		}
+		defs[i] = e.Field(i).Name + " " + typeName
+	}
+	return "CREATE TYPE " + typeName + " AS (" + strings.Join(defs, ", ") + ")", nil
+}
+
+// CompositeField is a field of a composite attribute, which can be compared to
+// a value.
+type CompositeField struct {
+	att   Attribute
+	field string
+}
+
+// Field returns a field of a composite attribute
+func (att Attribute) Field(name string) CompositeField {
+	return CompositeField{att, name}
+}
+
+// fieldAccess is the right side of a comparison with a CompositeField, which
+// holds the field along with the value it is compared to.
+type fieldAccess struct {
+	field string
+	val   interface{}
+}
+
+// EQ creates a predicate that is true when the field is equal to the value.
+// It is pushed down as a comparison with (att).field.
+func (f CompositeField) EQ(v interface{}) Pred {
+	cp := clientPred{rel.Attribute(f.att), fmt.Sprintf("%s.%s == %v", f.att, f.field, v), func(s interface{}) bool {
+		v2, ok := lookupField(s, f.field)
+		return ok && v2 == v
+	}}
+	return Pred{cp, "=", rel.Attribute(f.att), fieldAccess{f.field, v}, nil}
+}
+
+// NE creates a predicate that is true when the field is not equal to the
+// value.  It is pushed down as a comparison with (att).field.
+func (f CompositeField) NE(v interface{}) Pred {
+	cp := clientPred{rel.Attribute(f.att), fmt.Sprintf("%s.%s != %v", f.att, f.field, v), func(s interface{}) bool {
+		v2, ok := lookupField(s, f.field)
+		return ok && v2 != v
+	}}
+	return Pred{cp, "<>", rel.Attribute(f.att), fieldAccess{f.field, v}, nil}
+}
+
+// lookupField returns the value of a field of a struct, which is nil for a nil
+// pointer field
+func lookupField(s interface{}, field string) (interface{}, bool) {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+	f := rv.FieldByName(field)
+	if !f.IsValid() {
+		return nil, false
+	}
+	if f.Kind() == reflect.Ptr {
+		if f.IsNil() {
+			return nil, false
+		}
+		f = f.Elem()
+	}
+	return f.Interface(), true
+}
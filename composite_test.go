@@ -0,0 +1,108 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// test composite attributes, stored in their text form
+func TestCompositeCodec(t *testing.T) {
+	type point struct {
+		X, Y float64
+	}
+	type address struct {
+		Street string
+		Zip    *int
+		Since  time.Time
+		Where  point
+	}
+	RegisterCodec(reflect.TypeOf(point{}), CompositeCodec("point2"))
+	RegisterCodec(reflect.TypeOf(address{}), CompositeCodec("address"))
+	zip := 12345
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	var recordTest = []struct {
+		v    address
+		text string
+	}{
+		{address{"Main St", &zip, since, point{1, 2.5}}, `("Main St",12345,2024-01-02T03:04:05Z,"(1,2.5)")`},
+		{address{`a "b", c\d`, nil, since, point{}}, `("a ""b"", c\\d",,2024-01-02T03:04:05Z,"(0,0)")`},
+		{address{"", nil, since, point{}}, `("",,2024-01-02T03:04:05Z,"(0,0)")`},
+	}
+	c := CompositeCodec("address")
+	for i, tt := range recordTest {
+		text, err := c.Encode(ANSI, tt.v)
+		if err != nil || text != tt.text {
+			t.Errorf("%d has Encode() => %v, %v, want %s", i, text, err, tt.text)
+		}
+		var v address
+		if err := c.Decode(ANSI, []byte(tt.text), reflect.ValueOf(&v).Elem()); err != nil || !reflect.DeepEqual(v, tt.v) {
+			t.Errorf("%d has Decode() => %+v, %v, want %+v", i, v, err, tt.v)
+		}
+	}
+
+	// postgres writes times in records with a short offset
+	var v address
+	if err := c.Decode(ANSI, `(x,1,"2024-01-02 03:04:05+00","(3,4)")`, reflect.ValueOf(&v).Elem()); err != nil || !v.Since.Equal(since) || v.Where.Y != 4 {
+		t.Errorf("Decode() => %+v, %v", v, err)
+	}
+	for i, text := range []string{"x", "(1,2", `("a)`, "(a,1,2024-01-02,(0,0),extra)", "(a,b,2024-01-02,\"(0,0)\")"} {
+		if err := c.Decode(ANSI, text, reflect.ValueOf(&v).Elem()); err == nil {
+			t.Errorf("%d has Decode(%q) => nil, want an error", i, text)
+		}
+	}
+
+	type customerTup struct {
+		ID   int
+		Home address
+	}
+	q, err := CreateTypeString(ANSI, "address", address{})
+	if want := "CREATE TYPE address AS (Street TEXT, Zip BIGINT, Since TIMESTAMP, Where point2)"; err != nil || q != want {
+		t.Errorf("CreateTypeString() => %q, %v, want %q", q, err, want)
+	}
+
+	db, err := sql.Open("sqlite3", "file:composite?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "customers", customerTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	customers := []customerTup{{1, recordTest[0].v}, {2, recordTest[1].v}}
+	if _, err := Insert(db, "customers", rel.New(customers, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "customers", customerTup{}, keys)
+	ch := make(chan customerTup)
+	table.TupleChan(ch)
+	n := 0
+	for tup := range ch {
+		if !reflect.DeepEqual(tup, customers[tup.ID-1]) {
+			t.Errorf("read %+v, want %+v", tup, customers[tup.ID-1])
+		}
+		n++
+	}
+	if err := table.Err(); err != nil || n != 2 {
+		t.Errorf("read %d tuples, Err() => %v", n, err)
+	}
+
+	// fields are compared in the database with the field selection syntax,
+	// and client side otherwise
+	q, args, err := SQL(table.Restrict(Attribute("Home").Field("Street").EQ("Main St")))
+	if want := "SELECT ID, Home FROM customers WHERE (Home).Street = ?"; err != nil || q != want || fmt.Sprint(args) != "[Main St]" {
+		t.Errorf("SQL() => %q, %v, %v, want %q", q, args, err, want)
+	}
+	mem := rel.New(customers, keys).Restrict(Attribute("Home").Field("Street").NE("Main St"))
+	if card := rel.Card(mem); card != 1 {
+		t.Errorf("client side Card() => %d, want 1", card)
+	}
+}
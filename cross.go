@@ -0,0 +1,80 @@
+package relsql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+)
+
+// WithCrossJoinLimit sets the most rows that a cross join in the database
+// may produce.  Before the query of a relation with cross joins is executed,
+// the rows of both sides of each cross join are counted, and the query fails
+// if their product is more than the limit.  Zero, the default, doesn't check
+// cross joins.
+func WithCrossJoinLimit(n int64) Option {
+	return func(o *options) {
+		o.crossJoinLimit = n
+	}
+}
+
+// CrossJoin creates the cartesian product of two relations, which have no
+// attributes in common, like the days of a calendar with every store.  It is
+// compiled into a CROSS JOIN when both relations are on the same database,
+// and evaluated client side otherwise.  It is an error for the relations to
+// share attributes, because the natural join would then match on them.
+func CrossJoin(r1, r2 rel.Relation, zero interface{}) rel.Relation {
+	if common := commonAttributes(reflect.TypeOf(r1.Zero()), reflect.TypeOf(r2.Zero())); len(common) > 0 {
+		return &sqlTable{
+			zero:  zero,
+			cKeys: rel.DefaultKeys(zero),
+			err:   fmt.Errorf("relsql: cross join of %v and %v has common attributes %v", r1, r2, common),
+		}
+	}
+	return r1.Join(r2, zero)
+}
+
+// checkCrossJoins returns an error if any of the cross joins in the relation
+// would produce more rows than the limit, counting them with the executor of
+// the read.
+func (r1 *sqlTable) checkCrossJoins(ctx context.Context, q QueryerContext) error {
+	limit := r1.opts.crossJoinLimit
+	if limit <= 0 {
+		return nil
+	}
+	var err error
+	Inspect(r1, func(n Node) bool {
+		j, ok := n.(*Join)
+		if err != nil || !ok || len(j.On) > 0 {
+			return err == nil
+		}
+		var rows [2]int64
+		for i, side := range []*Query{j.Left, j.Right} {
+			if rows[i], err = side.Relation.(*sqlTable).count(ctx, q); err != nil {
+				return false
+			}
+		}
+		if rows[0] != 0 && rows[1] > limit/rows[0] {
+			err = fmt.Errorf("relsql: cross join of %v and %v would produce %d rows, more than the limit of %d", j.Left.Relation, j.Right.Relation, rows[0]*rows[1], limit)
+		}
+		return err == nil
+	})
+	return err
+}
+
+// count returns the number of tuples of the relation, counted by the
+// executor
+func (r1 *sqlTable) count(ctx context.Context, q QueryerContext) (int64, error) {
+	b := builder{dialect: r1.dialect()}
+	query := "SELECT COUNT(*) FROM (" + r1.build(&b, nil, true) + ")" + b.alias("c")
+	if b.err != nil {
+		return 0, b.err
+	}
+	query, args, err := r1.applyPolicies(query, b.args)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	err = r1.opts.queryRow(ctx, q, query, bindArgs(r1.dialect(), args), &n)
+	return n, err
+}
@@ -0,0 +1,83 @@
+package relsql
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+)
+
+// test cross joins, with and without a limit on their size
+func TestCrossJoin(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:cross?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type dayTup struct {
+		Day int
+	}
+	type storeTup struct {
+		Store string
+	}
+	type dayStoreTup struct {
+		Day   int
+		Store string
+	}
+	if err := CreateTable(db, "days", dayTup{}, nil); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if err := CreateTable(db, "stores", storeTup{}, nil); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "days", rel.New([]dayTup{{1}, {2}, {3}}, nil)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "stores", rel.New([]storeTup{{"east"}, {"west"}}, nil)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var crossJoinTest = []struct {
+		limit int64
+		n     int
+		fail  bool
+	}{
+		{0, 6, false},
+		{6, 6, false},
+		{5, 0, true},
+	}
+	for i, tt := range crossJoinTest {
+		days := New(db, "days", dayTup{}, nil, WithCrossJoinLimit(tt.limit))
+		stores := New(db, "stores", storeTup{}, nil)
+		r := CrossJoin(days, stores, dayStoreTup{})
+		if q, _, _ := SQL(r); !strings.Contains(q, "CROSS JOIN") {
+			t.Errorf("%d has query %s, want a CROSS JOIN", i, q)
+		}
+		ch := make(chan dayStoreTup)
+		r.TupleChan(ch)
+		n := 0
+		for range ch {
+			n++
+		}
+		err := r.Err()
+		if n != tt.n || (err != nil) != tt.fail {
+			t.Errorf("%d read %d tuples with error %v, want %d and failure %v", i, n, err, tt.n, tt.fail)
+		}
+		var perr *PartialError
+		if tt.fail && !errors.As(err, &perr) {
+			t.Errorf("%d has Err() => %v, want a PartialError", i, err)
+		}
+	}
+
+	days := New(db, "days", dayTup{}, nil)
+	if err := CrossJoin(days, days, dayTup{}).Err(); err == nil {
+		t.Errorf("CrossJoin() with common attributes => nil error")
+	}
+}
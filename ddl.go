@@ -0,0 +1,426 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// typeNamer is implemented by dialects which use their own names for the
+// column types of some Go types.  TypeName returns an empty string for types
+// which use the ANSI name.
+type typeNamer interface {
+	TypeName(t reflect.Type) string
+}
+
+// columnRetyper is implemented by dialects which change column types with a
+// different statement than ALTER TABLE ... ALTER COLUMN ... TYPE.  It returns
+// an empty string if the dialect can't change the type of a column.  nullable
+// is for dialects whose statement redefines the whole column, so that it
+// doesn't lose its NOT NULL.
+type columnRetyper interface {
+	AlterColumnType(tableName, colName, typeName string, nullable bool) string
+}
+
+// keyFinder is implemented by dialects which can find the candidate keys that
+// are already declared for a table.  The statements that they execute are
+// audited with opts.
+type keyFinder interface {
+	Keys(db *sql.DB, tableName string, opts *options) ([][]string, error)
+}
+
+// timeType is the type of time.Time
+var timeType = reflect.TypeOf(time.Time{})
+
+// columnType returns the sql type of a column which holds values of type t.
+// Go values can't be NULL unless they are pointers or sql.Null* types, so
+// other columns are NOT NULL.
+func columnType(d Dialect, t reflect.Type) (typeName string, nullable bool, err error) {
+	if t.Kind() == reflect.Ptr {
+		typeName, _, err = columnType(d, t.Elem())
+		return typeName, true, err
+	}
+	if v, ok := nullTypes[t]; ok {
+		typeName, _, err = columnType(d, v)
+		return typeName, true, err
+	}
+	if c := codecFor(d, t); c != nil {
+		return c.TypeName(d), false, nil
+	}
+	if n, ok := d.(typeNamer); ok {
+		if typeName = n.TypeName(t); typeName != "" {
+			return typeName, false, nil
+		}
+	}
+	if t == timeType {
+		return "TIMESTAMP", false, nil
+	}
+	if t == decimalType {
+		return "NUMERIC", false, nil
+	}
+	if t == lazyType {
+		return "BLOB", false, nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "BOOLEAN", false, nil
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "SMALLINT", false, nil
+	case reflect.Int32, reflect.Uint16:
+		return "INTEGER", false, nil
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "BIGINT", false, nil
+	case reflect.Uint, reflect.Uint64:
+		return "NUMERIC(20)", false, nil
+	case reflect.Float32:
+		return "REAL", false, nil
+	case reflect.Float64:
+		return "DOUBLE PRECISION", false, nil
+	case reflect.String:
+		return "TEXT", false, nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "BLOB", false, nil
+		}
+	}
+	return "", false, fmt.Errorf("relsql: no sql column type for %v", t)
+}
+
+// nullTypes maps the sql.Null* types to the types of their values
+var nullTypes = map[reflect.Type]reflect.Type{
+	reflect.TypeOf(sql.NullBool{}):    reflect.TypeOf(false),
+	reflect.TypeOf(sql.NullInt32{}):   reflect.TypeOf(int32(0)),
+	reflect.TypeOf(sql.NullInt64{}):   reflect.TypeOf(int64(0)),
+	reflect.TypeOf(sql.NullFloat64{}): reflect.TypeOf(float64(0)),
+	reflect.TypeOf(sql.NullString{}):  reflect.TypeOf(""),
+	reflect.TypeOf(sql.NullTime{}):    timeType,
+}
+
+// columnDef returns the definition of a column for CREATE TABLE or ADD
+// COLUMN.  Columns which are added to an existing table need a default so
+// that the existing rows satisfy NOT NULL, which for enums is the first of
+// their values.
+func columnDef(o *options, f reflect.StructField, withDefault bool) (string, error) {
+	d := o.dialectOrANSI()
+	typeName, nullable, err := columnType(d, f.Type)
+	if err != nil {
+		return "", err
+	}
+	if o.encrypted(f.Name) {
+		// the column holds ciphertext
+		typeName, _, _ = columnType(d, bytesType)
+	}
+	var constraint string
+	values := o.enumValues(f.Name)
+	if values != nil {
+		var enumType string
+		if enumType, constraint = enumConstraint(d, f.Name, values); enumType != "" {
+			typeName = enumType
+		}
+	}
+	def := f.Name + " " + typeName
+	if !nullable {
+		def += " NOT NULL"
+		if withDefault && values != nil {
+			def += " DEFAULT " + literal(values[0])
+		} else if withDefault {
+			def += " DEFAULT " + zeroLiteral(d, f.Type)
+		}
+	}
+	if constraint != "" {
+		def += " " + constraint
+	}
+	return def, nil
+}
+
+// zeroLiteral returns the sql literal for the zero value of a type
+func zeroLiteral(d Dialect, t reflect.Type) string {
+	if c := codecFor(d, t); c != nil {
+		if v, err := c.Encode(d, reflect.Zero(t).Interface()); err == nil {
+			return literal(v)
+		}
+	}
+	if t == timeType {
+		return "'0001-01-01 00:00:00'"
+	}
+	if t == decimalType {
+		return "0"
+	}
+	if t == lazyType {
+		return "X''"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "FALSE"
+	case reflect.String:
+		return "''"
+	case reflect.Slice:
+		return "X''"
+	}
+	return "0"
+}
+
+// literal returns the sql literal for a value encoded by a codec
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return "'" + strings.Replace(v, "'", "''", -1) + "'"
+	case []byte:
+		return fmt.Sprintf("X'%X'", v)
+	case bool:
+		if v {
+			return "TRUE"
+		}
+		return "FALSE"
+	}
+	return fmt.Sprint(v)
+}
+
+// CreateTableString returns the CREATE TABLE statement for a table with a
+// column for each attribute of z.  The first candidate key becomes the
+// primary key, and the others are unique constraints.  Attributes declared
+// with WithEnum are constrained to their values.
+func CreateTableString(d Dialect, tableName string, z interface{}, ckeystr [][]string, opts ...Option) (string, error) {
+	o := options{dialect: d}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	e := reflect.TypeOf(z)
+	if err := checkZero(e); err != nil {
+		return "", err
+	}
+	defs := make([]string, 0, e.NumField()+len(ckeystr))
+	for i := 0; i < e.NumField(); i++ {
+		def, err := columnDef(&o, e.Field(i), false)
+		if err != nil {
+			return "", err
+		}
+		defs = append(defs, def)
+	}
+	for i, ck := range ckeystr {
+		if i == 0 {
+			defs = append(defs, "PRIMARY KEY ("+strings.Join(ck, ", ")+")")
+		} else {
+			defs = append(defs, "UNIQUE ("+strings.Join(ck, ", ")+")")
+		}
+	}
+	return "CREATE TABLE " + quoteTable(d, tableName) + " (" + strings.Join(defs, ", ") + ")", nil
+}
+
+// CreateTable creates a table with a column for each attribute of z, and
+// constraints for the candidate keys.
+func CreateTable(db *sql.DB, tableName string, z interface{}, ckeystr [][]string, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	stmt, err := CreateTableString(o.dialectOrANSI(), tableName, z, ckeystr, opts...)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec(stmt)
+	return err
+}
+
+// typeFamilies groups the names that databases report for column types into
+// families that can hold the same Go values.  The first matching substring
+// determines the family.
+var typeFamilies = []struct {
+	substr, family string
+}{
+	{"INTERVAL", "interval"},
+	{"INT", "int"},
+	{"BOOL", "bool"},
+	{"UUID", "uuid"},
+	{"INET", "inet"},
+	{"CIDR", "inet"},
+	{"MACADDR", "mac"},
+	{"CHAR", "text"},
+	{"TEXT", "text"},
+	{"CLOB", "text"},
+	{"STRING", "text"},
+	{"REAL", "float"},
+	{"FLOA", "float"},
+	{"DOUB", "float"},
+	{"NUMERIC", "numeric"},
+	{"DECIMAL", "numeric"},
+	{"BLOB", "bytes"},
+	{"BYTEA", "bytes"},
+	{"BINARY", "bytes"},
+	{"TIME", "time"},
+	{"DATE", "time"},
+}
+
+// typeFamily returns the family of a column type name, or an empty string if
+// it is not known.
+func typeFamily(typeName string) string {
+	typeName = strings.ToUpper(typeName)
+	for _, tf := range typeFamilies {
+		if strings.Contains(typeName, tf.substr) {
+			return tf.family
+		}
+	}
+	return ""
+}
+
+// PlanMigration compares an existing table with the tuple type z and its
+// candidate keys, and returns the statements that change the table to match:
+// columns are added for new attributes, dropped for removed attributes, and
+// retyped when the existing type can't hold the attribute's values, and
+// unique indexes are created for new candidate keys.  Candidate keys that
+// already exist are only detected for dialects that can find them, like
+// SQLite.
+func PlanMigration(db *sql.DB, tableName string, z interface{}, ckeystr [][]string, opts ...Option) ([]string, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	d := o.dialectOrANSI()
+	e := reflect.TypeOf(z)
+	if err := checkZero(e); err != nil {
+		return nil, err
+	}
+
+	// read the existing columns from an empty result
+	rows, err := db.Query("SELECT * FROM " + tableName + " WHERE 1 = 0")
+	if err != nil {
+		return nil, err
+	}
+	colTypes, err := rows.ColumnTypes()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*sql.ColumnType)
+	for _, ct := range colTypes {
+		existing[foldIdentifier(d, ct.Name())] = ct
+	}
+
+	var stmts []string
+	for i := 0; i < e.NumField(); i++ {
+		f := e.Field(i)
+		ct, ok := existing[foldIdentifier(d, f.Name)]
+		if !ok {
+			def, err := columnDef(&o, f, true)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, "ALTER TABLE "+tableName+" ADD COLUMN "+def)
+			continue
+		}
+		delete(existing, foldIdentifier(d, f.Name))
+		typeName, nullable, err := columnType(d, f.Type)
+		if err != nil {
+			return nil, err
+		}
+		have, want := typeFamily(ct.DatabaseTypeName()), typeFamily(typeName)
+		if have == "" || want == "" || have == want {
+			continue
+		}
+		stmt := "ALTER TABLE " + tableName + " ALTER COLUMN " + f.Name + " TYPE " + typeName
+		if r, ok := d.(columnRetyper); ok {
+			stmt = r.AlterColumnType(tableName, f.Name, typeName, nullable)
+		}
+		if stmt == "" {
+			return nil, fmt.Errorf("relsql: %s can't change the type of %s.%s from %s to %s", d.Name(), tableName, f.Name, ct.DatabaseTypeName(), typeName)
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	// the columns that are left have no attribute
+	var drop []string
+	for name := range existing {
+		drop = append(drop, name)
+	}
+	sort.Strings(drop)
+	for _, name := range drop {
+		stmts = append(stmts, "ALTER TABLE "+tableName+" DROP COLUMN "+name)
+	}
+
+	// add unique indexes for candidate keys that don't exist yet
+	var have [][]string
+	if kf, ok := d.(keyFinder); ok {
+		if have, err = kf.Keys(db, tableName, &o); err != nil {
+			return nil, err
+		}
+	}
+	for _, ck := range ckeystr {
+		folded := make([]string, len(ck))
+		for i, name := range ck {
+			folded[i] = foldIdentifier(d, name)
+		}
+		if containsKey(have, folded) {
+			continue
+		}
+		stmts = append(stmts, "CREATE UNIQUE INDEX "+tableName+"_"+strings.Join(ck, "_")+"_key ON "+tableName+" ("+strings.Join(ck, ", ")+")")
+	}
+	return stmts, nil
+}
+
+// containsKey returns true if one of the keys has the same attributes as ck,
+// in any order.
+func containsKey(keys [][]string, ck []string) bool {
+	for _, k := range keys {
+		if len(k) == len(ck) && containsAll(k, ck) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsAll returns true if every one of the names is in names1
+func containsAll(names1, names []string) bool {
+	for _, name := range names {
+		found := false
+		for _, name1 := range names1 {
+			if name == name1 {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// Migrate changes an existing table to match the tuple type z and its
+// candidate keys, by executing the statements from PlanMigration.  On
+// databases with transactional DDL, like Postgres and SQLite, they are
+// executed in a single transaction, so that either all or none of them are
+// applied.  Other databases, like MySQL and Oracle, commit each DDL statement
+// as it is executed, so the statements are executed one at a time, and if one
+// fails the table keeps the changes of those before it.
+func Migrate(db *sql.DB, tableName string, z interface{}, ckeystr [][]string, opts ...Option) error {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	stmts, err := PlanMigration(db, tableName, z, ckeystr, opts...)
+	if err != nil {
+		return err
+	}
+	if !transactionalDDL(o.dialectOrANSI()) {
+		for _, stmt := range stmts {
+			if _, err := db.Exec(stmt); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	for _, stmt := range stmts {
+		if _, err := tx.Exec(stmt); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
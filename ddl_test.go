@@ -0,0 +1,176 @@
+package relsql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// test CREATE TABLE generation
+func TestCreateTableString(t *testing.T) {
+	type eventTup struct {
+		ID      int64
+		Name    string
+		Score   float64
+		Note    *string
+		Updated sql.NullTime
+		At      time.Time
+	}
+	want := "CREATE TABLE events (ID BIGINT NOT NULL, Name TEXT NOT NULL, Score DOUBLE PRECISION NOT NULL, Note TEXT, Updated TIMESTAMP, At TIMESTAMP NOT NULL, PRIMARY KEY (ID), UNIQUE (Name, At))"
+	str, err := CreateTableString(ANSI, "events", eventTup{}, [][]string{[]string{"ID"}, []string{"Name", "At"}})
+	if err != nil {
+		t.Errorf("CreateTableString() => %v", err)
+	}
+	if str != want {
+		t.Errorf("CreateTableString() => %v, want %v", str, want)
+	}
+	want = "CREATE TABLE files (Name TEXT NOT NULL, Body BYTEA NOT NULL)"
+	if str, err := CreateTableString(Postgres, "files", struct {
+		Name string
+		Body []byte
+	}{}, nil); str != want || err != nil {
+		t.Errorf("CreateTableString() => %v, %v, want %v", str, err, want)
+	}
+	if _, err := CreateTableString(ANSI, "events", struct{ C chan int }{}, nil); err == nil {
+		t.Errorf("CreateTableString() with a chan attribute => nil error")
+	}
+}
+
+// test migration of an existing table to a changed tuple type
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type productV1 struct {
+		ID   int64
+		Name string
+		Old  string
+	}
+	type productV2 struct {
+		ID    int64
+		Name  string
+		Price float64
+	}
+	type productV3 struct {
+		ID    int64
+		Name  string
+		Price string
+	}
+	if err := CreateTable(db, "products", productV1{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := db.Exec("insert into products (ID, Name, Old) values (1, 'Nut', 'x')"); err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	keys := [][]string{[]string{"ID"}, []string{"Name"}}
+	stmts, err := PlanMigration(db, "products", productV2{}, keys, WithDialect(SQLite))
+	if err != nil {
+		t.Errorf("PlanMigration() => %v", err)
+		return
+	}
+	want := []string{
+		"ALTER TABLE products ADD COLUMN Price DOUBLE PRECISION NOT NULL DEFAULT 0",
+		"ALTER TABLE products DROP COLUMN Old",
+		"CREATE UNIQUE INDEX products_Name_key ON products (Name)",
+	}
+	if !reflect.DeepEqual(stmts, want) {
+		t.Errorf("PlanMigration() => %v, want %v", stmts, want)
+	}
+	if err := Migrate(db, "products", productV2{}, keys, WithDialect(SQLite)); err != nil {
+		t.Errorf("Migrate() => %v", err)
+	}
+	stmts, err = PlanMigration(db, "products", productV2{}, keys, WithDialect(SQLite))
+	if err != nil || len(stmts) != 0 {
+		t.Errorf("PlanMigration() after Migrate => %v, %v, want no statements", stmts, err)
+	}
+
+	// sqlite can't retype columns, but ansi databases can
+	if _, err := PlanMigration(db, "products", productV3{}, keys, WithDialect(SQLite)); err == nil {
+		t.Errorf("PlanMigration() of sqlite retype => nil error")
+	}
+	var retypeTest = []struct {
+		d        Dialect
+		expected string
+	}{
+		{ANSI, "ALTER TABLE products ALTER COLUMN Price TYPE TEXT"},
+		{MySQL, "ALTER TABLE products MODIFY COLUMN Price TEXT NOT NULL"},
+		{Oracle, "ALTER TABLE products MODIFY (Price VARCHAR2(4000))"},
+		{SQLServer, "ALTER TABLE products ALTER COLUMN Price NVARCHAR(MAX) NOT NULL"},
+		{BigQuery, "ALTER TABLE products ALTER COLUMN Price SET DATA TYPE STRING"},
+	}
+	for i, tt := range retypeTest {
+		stmts, err := PlanMigration(db, "products", productV3{}, nil, WithDialect(tt.d))
+		if err != nil || !reflect.DeepEqual(stmts, []string{tt.expected}) {
+			t.Errorf("%d has PlanMigration() => %v, %v, want %v", i, stmts, err, tt.expected)
+		}
+	}
+}
+
+// ddlDialect is a sqlite dialect whose DDL isn't transactional
+type ddlDialect struct {
+	sqliteDialect
+}
+
+func (ddlDialect) TransactionalDDL() bool {
+	return false
+}
+
+// test that migrations are only rolled back on databases with transactional
+// DDL
+func TestMigrateTransactionalDDL(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:migrateddl?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type partV1 struct {
+		ID   int64
+		Name string
+	}
+	type partV2 struct {
+		ID    int64
+		Name  string
+		Price float64
+	}
+	var ddlTest = []struct {
+		d     Dialect
+		added bool
+	}{
+		{SQLite, false},
+		{ddlDialect{}, true},
+	}
+	for i, tt := range ddlTest {
+		if _, err := db.Exec("DROP TABLE IF EXISTS parts"); err != nil {
+			t.Errorf(err.Error())
+			return
+		}
+		if err := CreateTable(db, "parts", partV1{}, [][]string{[]string{"ID"}}); err != nil {
+			t.Errorf("CreateTable() => %v", err)
+			return
+		}
+		if _, err := db.Exec("insert into parts (ID, Name) values (1, 'Nut'), (2, 'Nut')"); err != nil {
+			t.Errorf(err.Error())
+			return
+		}
+		// the price column is added before the unique index on the
+		// duplicated names fails
+		keys := [][]string{[]string{"ID"}, []string{"Name"}}
+		if err := Migrate(db, "parts", partV2{}, keys, WithDialect(tt.d)); err == nil {
+			t.Errorf("%d has Migrate() => nil error", i)
+		}
+		stmts, err := PlanMigration(db, "parts", partV2{}, nil, WithDialect(tt.d))
+		if added := err == nil && len(stmts) == 0; added != tt.added {
+			t.Errorf("%d has PlanMigration() after a failed Migrate => %v, %v", i, stmts, err)
+		}
+	}
+}
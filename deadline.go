@@ -0,0 +1,64 @@
+package relsql
+
+import (
+	"context"
+	"time"
+)
+
+// WithReadTimeout limits how long each read of the relation may take, from
+// executing its query to sending its last tuple, for best effort reads of slow
+// sources, like dashboards.  When the time is up, the query is aborted and
+// the tuples that were sent are kept: the channel is closed, and the
+// relation's Err is a PartialError whose Err is context.DeadlineExceeded, with
+// the number of tuples that were sent.  Reads that time out aren't retried.
+func WithReadTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.readTimeout = d
+	}
+}
+
+// readContext returns the context of a read, which is done when its
+// controller aborts it or when its timeout passes, and a function that
+// releases it.
+func (r1 *sqlTable) readContext() (context.Context, context.CancelFunc) {
+	ctx := r1.opts.controller.context()
+	if r1.opts.readTimeout > 0 {
+		return context.WithTimeout(ctx, r1.opts.readTimeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// stopError returns the error of a read that was stopped because its context
+// is done, or nil if it wasn't, or if its consumer stopped it by closing
+// cancel.
+func stopError(ctx context.Context, cancel <-chan struct{}) error {
+	select {
+	case <-cancel:
+		return nil
+	default:
+	}
+	switch ctx.Err() {
+	case nil:
+		return nil
+	case context.DeadlineExceeded:
+		return context.DeadlineExceeded
+	}
+	return ErrShutdown
+}
+
+// watch returns a channel that is closed when either cancel or done is
+// closed, and a function that stops watching them.
+func watch(cancel, done <-chan struct{}) (<-chan struct{}, func()) {
+	halt := make(chan struct{})
+	unwatch := make(chan struct{})
+	go func() {
+		select {
+		case <-cancel:
+		case <-done:
+		case <-unwatch:
+			return
+		}
+		close(halt)
+	}()
+	return halt, func() { close(unwatch) }
+}
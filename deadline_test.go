@@ -0,0 +1,62 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jonlawlor/rel"
+	"testing"
+	"time"
+)
+
+// test keeping the tuples of reads that time out
+func TestReadTimeout(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:deadline?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type deadlineTup struct {
+		ID int
+	}
+	if err := CreateTable(db, "deadlines", deadlineTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "deadlines", rel.New([]deadlineTup{{1}, {2}, {3}, {4}}, [][]string{[]string{"ID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var deadlineTest = []struct {
+		delay time.Duration
+		sent  int
+	}{
+		{0, 4},
+		// the consumer is slower than the timeout allows after two tuples
+		{40 * time.Millisecond, 2},
+	}
+	for i, tt := range deadlineTest {
+		r := New(db, "deadlines", deadlineTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite), WithReadTimeout(60*time.Millisecond))
+		ch := make(chan deadlineTup)
+		r.TupleChan(ch)
+		n := 0
+		for range ch {
+			n++
+			time.Sleep(tt.delay)
+		}
+		err := r.Err()
+		if n != tt.sent {
+			t.Errorf("%d has %d tuples, want %d", i, n, tt.sent)
+		}
+		var perr *PartialError
+		switch {
+		case tt.sent == 4 && err != nil:
+			t.Errorf("%d has Err() => %v, want nil", i, err)
+		case tt.sent < 4 && (!errors.As(err, &perr) || perr.Sent != tt.sent || !errors.Is(err, context.DeadlineExceeded)):
+			t.Errorf("%d has Err() => %v, want a PartialError after %d tuples", i, err, tt.sent)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package relsql
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number in its text form, like "1234.5678".
+// Attributes of type Decimal are stored in NUMERIC columns and scanned from
+// the text the driver returns, so they don't lose precision by passing through
+// float64, and they are bound as exact values in predicates.  Decimals are
+// compared as text when a predicate is evaluated client side.
+type Decimal string
+
+// Scan implements the sql.Scanner interface.  Drivers that return NUMERIC
+// values as float64, such as sqlite3, may already have lost precision; the
+// WithExactNumerics option turns that into an error.
+func (d *Decimal) Scan(src interface{}) error {
+	switch s := src.(type) {
+	case string:
+		*d = Decimal(s)
+	case []byte:
+		*d = Decimal(s)
+	case int64:
+		*d = Decimal(strconv.FormatInt(s, 10))
+	case float64:
+		*d = Decimal(strconv.FormatFloat(s, 'f', -1, 64))
+	case nil:
+		return fmt.Errorf("relsql: can't convert NULL to Decimal")
+	default:
+		return fmt.Errorf("relsql: can't convert %T to Decimal", src)
+	}
+	return nil
+}
+
+// Value implements the driver.Valuer interface, binding the decimal as text
+// so that the server converts it exactly.
+func (d Decimal) Value() (driver.Value, error) {
+	if _, ok := new(big.Rat).SetString(string(d)); !ok {
+		return nil, fmt.Errorf("relsql: invalid decimal %q", string(d))
+	}
+	return string(d), nil
+}
+
+// decimalType is the type of Decimal
+var decimalType = reflect.TypeOf(Decimal(""))
+
+// WithExactNumerics makes scanning fail instead of silently losing precision:
+// Decimal attributes can't be scanned from float64 values, and float
+// attributes can't be scanned from numeric text which they can't represent
+// exactly.
+func WithExactNumerics() Option {
+	return func(o *options) {
+		o.exactNumerics = true
+	}
+}
+
+// exactScanner scans a numeric column into a Decimal or float field, with an
+// error if the conversion would lose precision.
+type exactScanner struct {
+	dst reflect.Value
+}
+
+// Scan implements the sql.Scanner interface
+func (s *exactScanner) Scan(src interface{}) error {
+	if s.dst.Type() == decimalType {
+		if _, ok := src.(float64); ok {
+			return fmt.Errorf("relsql: lossy conversion of float64 %v to Decimal", src)
+		}
+		return s.dst.Addr().Interface().(*Decimal).Scan(src)
+	}
+
+	// float fields
+	var text string
+	switch v := src.(type) {
+	case string:
+		text = v
+	case []byte:
+		text = string(v)
+	case float64:
+		s.dst.SetFloat(v)
+		return nil
+	case int64:
+		f := float64(v)
+		if int64(f) != v {
+			return fmt.Errorf("relsql: lossy conversion of %d to %v", v, s.dst.Type())
+		}
+		s.dst.SetFloat(f)
+		return nil
+	default:
+		return fmt.Errorf("relsql: can't convert %T to %v", src, s.dst.Type())
+	}
+	bits := s.dst.Type().Bits()
+	f, err := strconv.ParseFloat(text, bits)
+	if err != nil {
+		return err
+	}
+	exact, ok := new(big.Rat).SetString(text)
+	if !ok || new(big.Rat).SetFloat64(f).Cmp(exact) != 0 {
+		return fmt.Errorf("relsql: lossy conversion of %s to %v", strings.TrimSpace(text), s.dst.Type())
+	}
+	s.dst.SetFloat(f)
+	return nil
+}
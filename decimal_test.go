@@ -0,0 +1,83 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test lossy conversion detection
+func TestExactScanner(t *testing.T) {
+	var d Decimal
+	var f float64
+	var scanTest = []struct {
+		dst   interface{}
+		src   interface{}
+		isErr bool
+	}{
+		{&d, []byte("12.345678901234567890"), false},
+		{&d, int64(12), false},
+		{&d, 12.5, true},
+		{&f, []byte("12.5"), false},
+		{&f, []byte("0.1"), true},
+		{&f, "12.345678901234567890", true},
+		{&f, 0.1, false},
+		{&f, int64(1) << 62, false},
+		{&f, int64(1)<<62 + 1, true},
+	}
+	for i, tt := range scanTest {
+		s := &exactScanner{reflect.ValueOf(tt.dst).Elem()}
+		if err := s.Scan(tt.src); (err != nil) != tt.isErr {
+			t.Errorf("%d has Scan(%v) => %v, want error %v", i, tt.src, err, tt.isErr)
+		}
+	}
+	if _, err := Decimal("1.2.3").Value(); err == nil {
+		t.Errorf("Value() of invalid decimal => nil error")
+	}
+}
+
+// test scanning and restricting on decimal attributes
+func TestDecimalColumn(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	// sqlite returns text columns as text, which is exact
+	_, err = db.Exec(`
+	create table prices (SKU text not null primary key, Price text not null);
+	insert into prices (SKU, Price) values ('a', '10.10'), ('b', '12345678901234567.89');
+	`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	type priceTup struct {
+		SKU   string
+		Price Decimal
+	}
+	prices := New(db, "prices", priceTup{}, [][]string{[]string{"SKU"}}, WithExactNumerics())
+	b := prices.Restrict(Attribute("Price").EQ(Decimal("12345678901234567.89")))
+	ch := make(chan priceTup)
+	b.TupleChan(ch)
+	var res []priceTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if want := []priceTup{{"b", "12345678901234567.89"}}; !reflect.DeepEqual(res, want) {
+		t.Errorf("restricted prices => %v, want %v", res, want)
+	}
+
+	type floatTup struct {
+		SKU   string
+		Price float64
+	}
+	floats := New(db, "prices", floatTup{}, nil, WithExactNumerics())
+	rel.Card(floats)
+	if floats.Err() == nil {
+		t.Errorf("lossy float scan has Err() => nil")
+	}
+}
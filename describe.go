@@ -0,0 +1,170 @@
+package relsql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+)
+
+// Description is a report of a relation's metadata, for tools that explore
+// a schema through relsql, like a command line or a notebook.
+type Description struct {
+	// Relation is the relational expression of the relation
+	Relation string
+
+	// Dialect is the name of the dialect that the relation is compiled to
+	Dialect string
+
+	// Attributes are the attributes of the heading, in order
+	Attributes []AttributeDescription
+
+	// Keys are the candidate keys
+	Keys [][]string
+
+	// Cardinality is the estimated number of tuples, or -1 if it couldn't be
+	// estimated
+	Cardinality int64
+
+	// Tables are the sorted names of the tables that the relation reads from
+	Tables []string
+
+	// SQL is the query that reads the relation, or empty if part of it is
+	// evaluated client side
+	SQL string
+
+	// ClientSide are the operations that are evaluated client side
+	ClientSide []Diagnostic
+
+	// Verbosity is the level of detail of the report's text, from the
+	// relation's options
+	Verbosity Verbosity
+
+	// Internal is the internal state of the relation, which is only set at
+	// VerbosityInternal
+	Internal string
+}
+
+// AttributeDescription describes an attribute of a relation
+type AttributeDescription struct {
+	Name string
+
+	// Type is the Go type of the attribute
+	Type reflect.Type
+
+	// SQLType is the column type of the attribute in the relation's
+	// dialect, and Nullable is true if it can hold NULL
+	SQLType  string
+	Nullable bool
+
+	// Columns are the table.column names that the attribute comes from
+	Columns []string
+}
+
+// Describe returns a report of the heading, with the sql types of its
+// attributes, the candidate keys, the estimated cardinality, the tables and
+// query that a relation reads from, and which of its operations are evaluated
+// client side.  Estimating the cardinality can execute a query, which is
+// canceled with ctx.  It returns an error if the relation has one.
+func Describe(ctx context.Context, r rel.Relation) (*Description, error) {
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	d := relationDialect(r)
+	desc := &Description{
+		Relation:    exprString(r),
+		Dialect:     d.Name(),
+		Keys:        keyStrings(r.CKeys()),
+		Cardinality: -1,
+		Tables:      Tables(r),
+		ClientSide:  Diagnostics(r),
+		Verbosity:   relationVerbosity(r),
+	}
+	if desc.Verbosity >= VerbosityInternal {
+		desc.Internal = fmt.Sprintf("%#v", r)
+	}
+	lin := Lineage(r)
+	e := reflect.TypeOf(r.Zero())
+	for i := 0; i < e.NumField(); i++ {
+		f := e.Field(i)
+		typeName, nullable, err := columnType(d, f.Type)
+		if err != nil {
+			typeName = "?"
+		}
+		desc.Attributes = append(desc.Attributes, AttributeDescription{f.Name, f.Type, typeName, nullable, lin[rel.Attribute(f.Name)]})
+	}
+	if q, _, err := SQL(r); err == nil {
+		desc.SQL = q
+	}
+	if n, err := EstimateCard(ctx, r); err == nil {
+		desc.Cardinality = n
+	} else if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return desc, nil
+}
+
+// relationDialect returns the dialect of the first query in the relation, or
+// ANSI if it has none.
+func relationDialect(r rel.Relation) Dialect {
+	d := ANSI
+	found := false
+	Inspect(r, func(n Node) bool {
+		if q, ok := n.(*Query); ok && !found {
+			d, found = q.Relation.(*sqlTable).dialect(), true
+		}
+		return !found
+	})
+	return d
+}
+
+// String returns the report as text, with one line for each attribute.  At
+// VerbosityHeading it only has the relation and its attributes, and at
+// VerbosityKeys it adds the keys and cardinality.  The default is
+// VerbositySQL, which has the rest of the report.
+func (desc *Description) String() string {
+	v := desc.Verbosity
+	if v == DefaultVerbosity {
+		v = VerbositySQL
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n", desc.Relation)
+	for _, a := range desc.Attributes {
+		null := ""
+		if a.Nullable {
+			null = " NULL"
+		}
+		fmt.Fprintf(&b, "  %s %v %s%s", a.Name, a.Type, a.SQLType, null)
+		if len(a.Columns) > 0 {
+			fmt.Fprintf(&b, " <- %s", strings.Join(a.Columns, ", "))
+		}
+		b.WriteString("\n")
+	}
+	if v < VerbosityKeys {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "keys: %s\n", keysText(desc.Keys))
+	if desc.Cardinality >= 0 {
+		fmt.Fprintf(&b, "cardinality: ~%d\n", desc.Cardinality)
+	} else {
+		b.WriteString("cardinality: unknown\n")
+	}
+	if v < VerbositySQL {
+		return b.String()
+	}
+	fmt.Fprintf(&b, "dialect: %s\n", desc.Dialect)
+	if len(desc.Tables) > 0 {
+		fmt.Fprintf(&b, "tables: %s\n", strings.Join(desc.Tables, ", "))
+	}
+	if desc.SQL != "" {
+		fmt.Fprintf(&b, "sql: %s\n", desc.SQL)
+	}
+	for _, d := range desc.ClientSide {
+		fmt.Fprintf(&b, "client side: %s %s: %s\n", d.Op, d.Expr, d.Reason)
+	}
+	if desc.Internal != "" {
+		fmt.Fprintf(&b, "internal: %s\n", desc.Internal)
+	}
+	return b.String()
+}
@@ -0,0 +1,77 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+)
+
+// test describing relations in the database and client side
+func TestDescribe(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:describe?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type userTup struct {
+		UserID int
+		Email  string
+		Nick   *string
+	}
+	keys := [][]string{[]string{"UserID"}}
+	if err := CreateTable(db, "users", userTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	nick := "a"
+	if _, err := Insert(db, "users", rel.New([]userTup{{1, "a@example.com", &nick}, {2, "b@example.com", nil}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	users := New(db, "users", userTup{}, keys)
+	ctx := context.Background()
+
+	desc, err := Describe(ctx, users.Restrict(Attribute("UserID").GT(1)))
+	if err != nil {
+		t.Errorf("Describe() => %v", err)
+		return
+	}
+	var attributeTest = []struct {
+		sqlType  string
+		nullable bool
+		column   string
+	}{
+		{"BIGINT", false, "users.UserID"},
+		{"TEXT", false, "users.Email"},
+		{"TEXT", true, "users.Nick"},
+	}
+	for i, tt := range attributeTest {
+		a := desc.Attributes[i]
+		if a.SQLType != tt.sqlType || a.Nullable != tt.nullable || len(a.Columns) != 1 || a.Columns[0] != tt.column {
+			t.Errorf("%d has attribute %+v", i, a)
+		}
+	}
+	if desc.Dialect != "sqlite3" || desc.Cardinality != 2 || len(desc.Keys) != 1 || desc.Keys[0][0] != "UserID" ||
+		desc.SQL != "SELECT UserID, Email, Nick FROM users WHERE UserID > ?" || len(desc.ClientSide) != 0 {
+		t.Errorf("Describe() => %+v", desc)
+	}
+	for _, want := range []string{"Nick *string TEXT NULL <- users.Nick", "keys: {UserID}", "cardinality: ~2", "tables: users", "sql: SELECT"} {
+		if !strings.Contains(desc.String(), want) {
+			t.Errorf("String() => %q, want %q", desc.String(), want)
+		}
+	}
+
+	// a predicate that isn't from this package is evaluated client side
+	desc, err = Describe(ctx, users.Restrict(rel.Attribute("UserID").GT(1)))
+	if err != nil || desc.SQL != "" || len(desc.ClientSide) != 1 || desc.Cardinality != -1 || !strings.Contains(desc.String(), "client side: Restrict") {
+		t.Errorf("Describe() => %+v, %v", desc, err)
+	}
+
+	if _, err := Describe(ctx, New(db, "users", userTup{}, [][]string{[]string{"Missing"}})); err == nil {
+		t.Errorf("Describe() of a relation with an error succeeded")
+	}
+}
@@ -0,0 +1,156 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sync/atomic"
+)
+
+// Diagnostic describes a part of a relation that couldn't be compiled into
+// sql, and is evaluated client side instead.
+type Diagnostic struct {
+	// Op is the relational operation, like Restrict
+	Op string
+
+	// Expr is the text of the operation's argument, like the predicate
+	Expr string
+
+	// Reason is why the operation couldn't be compiled
+	Reason string
+
+	// Rows is the number of rows that were read from the database to
+	// evaluate the operation, the last time it was evaluated.  It is zero
+	// until then.
+	Rows int64
+}
+
+// String returns a description of the diagnostic
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("relsql: %s %s evaluated client-side over ~%d rows: %s", d.Op, d.Expr, d.Rows, d.Reason)
+}
+
+// WithLogger sets a function that is called with a message for each
+// diagnostic, once the operation it describes has been evaluated, and with a
+// warning for each natural join in the database that matches on attributes
+// which are neither a candidate key nor a foreign key, because they may only
+// have the same name by coincidence.  log.Printf and testing.T.Logf can be
+// used directly.
+func WithLogger(f func(format string, args ...interface{})) Option {
+	return func(o *options) {
+		o.logf = f
+	}
+}
+
+// diagnostic is a Diagnostic whose row count is updated while the operation
+// is evaluated
+type diagnostic struct {
+	d    Diagnostic
+	rows int64
+}
+
+// get returns the diagnostic
+func (d *diagnostic) get() Diagnostic {
+	res := d.d
+	res.Rows = atomic.LoadInt64(&d.rows)
+	return res
+}
+
+// clientSide is a relation that is evaluated client side, instead of being
+// compiled into sql, along with the reasons for it.
+type clientSide struct {
+	rel.Relation
+	diags []*diagnostic
+
+	// input is the relation that the operation reads from, and apply
+	// applies the operation to it, for Bind
+	input *sqlTable
+	apply func(r rel.Relation) rel.Relation
+
+	// op is the name of an operation that follows another client side one,
+	// prev, which it is applied to instead of input
+	op   string
+	prev *clientSide
+}
+
+// Restrict is evaluated client side
+func (c *clientSide) Restrict(p rel.Predicate) rel.Relation {
+	return c.then("Restrict", func(r rel.Relation) rel.Relation {
+		return rel.NewRestrict(r, p)
+	})
+}
+
+// Project is evaluated client side
+func (c *clientSide) Project(z2 interface{}) rel.Relation {
+	return c.then("Project", func(r rel.Relation) rel.Relation {
+		return rel.NewProject(r, z2)
+	})
+}
+
+// Rename is evaluated client side
+func (c *clientSide) Rename(z2 interface{}) rel.Relation {
+	return c.then("Rename", func(r rel.Relation) rel.Relation {
+		return rel.NewRename(r, z2)
+	})
+}
+
+// then returns the client side evaluation of another operation on the
+// relation, which keeps its diagnostics and its input
+func (c *clientSide) then(op string, f func(r rel.Relation) rel.Relation) rel.Relation {
+	return &clientSide{Relation: f(c), diags: c.diags, input: c.input, apply: f, op: op, prev: c}
+}
+
+// bind returns the relation with its operations applied to its input with
+// the procedure called with new arguments
+func (c *clientSide) bind(args []interface{}) rel.Relation {
+	if c.prev != nil {
+		prev, ok := c.prev.bind(args).(*clientSide)
+		if !ok {
+			return prev
+		}
+		return prev.then(c.op, c.apply)
+	}
+	r2 := c.input.Bind(args...).(*sqlTable)
+	if r2.err != nil {
+		return r2
+	}
+	d := c.diags[0].d
+	return r2.fallback(d.Op, d.Expr, d.Reason, c.apply)
+}
+
+// Diagnostics returns the operations of a relation from this package that
+// are evaluated client side, because they couldn't be compiled into sql.  An
+// empty result means that the relation is a single query, or that it wasn't
+// built by this package.
+func Diagnostics(r rel.Relation) []Diagnostic {
+	c, ok := r.(*clientSide)
+	if !ok {
+		return nil
+	}
+	var res []Diagnostic
+	for _, d := range c.diags {
+		res = append(res, d.get())
+	}
+	return res
+}
+
+// fallback returns the client side evaluation of an operation on r1, which
+// is created by f from a copy of r1 that counts the rows it reads.
+func (r1 *sqlTable) fallback(op, expr, reason string, f func(r rel.Relation) rel.Relation) rel.Relation {
+	d := &diagnostic{d: Diagnostic{Op: op, Expr: expr, Reason: reason}}
+	r2 := *r1
+	r2.diag = d
+	return &clientSide{Relation: f(&r2), diags: []*diagnostic{d}, input: &r2, apply: f}
+}
+
+// readDone records the number of rows that the relation read for a client
+// side operation, and logs it.
+func (r1 *sqlTable) readDone(rows int) {
+	if r1.diag == nil {
+		return
+	}
+	atomic.StoreInt64(&r1.diag.rows, int64(rows))
+	if r1.opts.logf != nil {
+		r1.opts.logf("%v", r1.diag.get())
+	}
+	r1.logClientSide(r1.diag.get())
+}
@@ -0,0 +1,65 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+)
+
+// test reporting of restrictions that are evaluated client side
+func TestDiagnostics(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:diagnostics?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type itemTup struct {
+		ID   int
+		Name string
+	}
+	if err := CreateTable(db, "items", itemTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	var tups []itemTup
+	for i := 0; i < 6; i++ {
+		tups = append(tups, itemTup{i, fmt.Sprintf("item%d", i)})
+	}
+	if _, err := Insert(db, "items", rel.New(tups, [][]string{[]string{"ID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var logged []string
+	logf := func(format string, args ...interface{}) {
+		logged = append(logged, fmt.Sprintf(format, args...))
+	}
+	items := New(db, "items", itemTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite), WithLogger(logf))
+
+	// a restriction that is compiled into the query has no diagnostics
+	if diags := Diagnostics(items.Restrict(Attribute("ID").LT(3))); len(diags) != 0 {
+		t.Errorf("Diagnostics() of pushed down restrict => %v", diags)
+	}
+
+	// a predicate from rel is evaluated client side over every row
+	client := items.Restrict(rel.Attribute("ID").EQ(2))
+	diags := Diagnostics(client)
+	if len(diags) != 1 || diags[0].Op != "Restrict" || diags[0].Rows != 0 {
+		t.Errorf("Diagnostics() before evaluation => %v", diags)
+	}
+	ch := make(chan itemTup)
+	client.TupleChan(ch)
+	for range ch {
+	}
+	diags = Diagnostics(client)
+	if len(diags) != 1 || diags[0].Rows != 6 {
+		t.Errorf("Diagnostics() after evaluation => %v", diags)
+	}
+	if len(logged) != 1 || !strings.Contains(logged[0], "evaluated client-side over ~6 rows") {
+		t.Errorf("logged => %v", logged)
+	}
+}
@@ -0,0 +1,242 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// Dialect describes the differences between the sql understood by database
+// engines.  The dialect of a relation is set with the WithDialect option.  If
+// no dialect is given, it is the dialect registered for the driver of the
+// relation's database, which is detected when the relation is created, or
+// ANSI if the driver has none.
+type Dialect interface {
+	// Name returns the name of the dialect
+	Name() string
+
+	// Placeholder returns the placeholder for the i'th argument of a query,
+	// counting from 1.
+	Placeholder(i int) string
+
+	// ReadTx returns true if queries have to be executed in a transaction to
+	// get a consistent read.
+	ReadTx() bool
+}
+
+// ANSI is the dialect used when none is specified.  It uses ? placeholders and
+// reads in a transaction.
+var ANSI Dialect = ansiDialect{}
+
+// ansiDialect is the standard sql dialect
+type ansiDialect struct{}
+
+// Name returns the name of the dialect
+func (ansiDialect) Name() string {
+	return "ansi"
+}
+
+// Placeholder returns the placeholder for the i'th argument of a query
+func (ansiDialect) Placeholder(i int) string {
+	return "?"
+}
+
+// ReadTx returns true, because a query may involve several statements on
+// the server.
+func (ansiDialect) ReadTx() bool {
+	return true
+}
+
+// WithDialect sets the dialect used to compile queries for the relation,
+// instead of the one detected from its database's driver.
+func WithDialect(d Dialect) Option {
+	return func(o *options) {
+		o.dialect = d
+	}
+}
+
+// tableQuoter is implemented by dialects which have to quote table names,
+// for example because they are paths with characters that aren't allowed in
+// plain identifiers.
+type tableQuoter interface {
+	QuoteTable(tableName string) string
+}
+
+// quoteTable returns the table name as it is written in the dialect's sql
+func quoteTable(d Dialect, tableName string) string {
+	if q, ok := d.(tableQuoter); ok {
+		return q.QuoteTable(tableName)
+	}
+	return tableName
+}
+
+// namedArger is implemented by dialects whose placeholders are names instead
+// of positions.  The name of the i'th argument is "p" followed by i.
+type namedArger interface {
+	NamedArgs() bool
+}
+
+// bindArgs returns the arguments of a query in the form that the dialect's
+// placeholders refer to.
+func bindArgs(d Dialect, args []interface{}) []interface{} {
+	if n, ok := d.(namedArger); !ok || !n.NamedArgs() {
+		return args
+	}
+	named := make([]interface{}, len(args))
+	for i, arg := range args {
+		named[i] = sql.Named(fmt.Sprintf("p%d", i+1), arg)
+	}
+	return named
+}
+
+// setOperator is implemented by dialects which use different keywords for
+// the set operations UNION, UNION ALL, EXCEPT and INTERSECT.
+type setOperator interface {
+	SetOperator(op string) string
+}
+
+// setOperatorString returns the keywords of the set operation in the dialect
+func setOperatorString(d Dialect, op string) string {
+	if s, ok := d.(setOperator); ok {
+		return s.SetOperator(op)
+	}
+	return op
+}
+
+// dualer is implemented by dialects which can't select a constant row without
+// a FROM clause.  Dual returns a table with a single row.
+type dualer interface {
+	Dual() string
+}
+
+// fromDual returns the FROM clause, including a leading space, of a select
+// that produces a single constant row in the dialect.
+func fromDual(d Dialect) string {
+	if du, ok := d.(dualer); ok {
+		return " FROM " + du.Dual()
+	}
+	return ""
+}
+
+// identifierFolder is implemented by dialects which change the case of
+// unquoted identifiers, so that the names of columns and keys reported by the
+// database differ from the names they were created with.
+type identifierFolder interface {
+	FoldIdentifier(name string) string
+}
+
+// foldIdentifier returns the name as the dialect reports it
+func foldIdentifier(d Dialect, name string) string {
+	if f, ok := d.(identifierFolder); ok {
+		return f.FoldIdentifier(name)
+	}
+	return name
+}
+
+// sessioner is implemented by dialects which set session parameters before
+// each query.  The statements are executed on the connection that the query
+// is executed on.
+type sessioner interface {
+	SessionStatements() []string
+}
+
+// sessionStatements returns the statements that set up a session
+func sessionStatements(d Dialect) []string {
+	if s, ok := d.(sessioner); ok {
+		return s.SessionStatements()
+	}
+	return nil
+}
+
+// sessionResetter is implemented by dialects whose session statements can be
+// undone, so that the connection they were executed on can go back to the
+// pool.  Connections of other dialects with session statements are closed
+// instead once the query has been read.
+type sessionResetter interface {
+	ResetStatements() []string
+}
+
+// resetStatements returns the statements that undo the session statements,
+// and whether there are any
+func resetStatements(d Dialect) ([]string, bool) {
+	if s, ok := d.(sessionResetter); ok {
+		return s.ResetStatements(), true
+	}
+	return nil, false
+}
+
+// transactioner is implemented by dialects whose drivers can't begin
+// transactions, like those of ClickHouse and some ODBC bridges, which return
+// an error from Begin.  Reads on them execute their statements without one,
+// even if the dialect's ReadTx, or a script's setup, would otherwise start
+// one.
+type transactioner interface {
+	Transactions() bool
+}
+
+// transactions returns true if the dialect's driver can begin transactions
+func transactions(d Dialect) bool {
+	if t, ok := d.(transactioner); ok {
+		return t.Transactions()
+	}
+	return true
+}
+
+// ddlTransactioner is implemented by dialects whose DDL isn't transactional,
+// like MySQL's and Oracle's, which commit the transaction that a DDL statement
+// is executed in, so that it can't be rolled back.
+type ddlTransactioner interface {
+	TransactionalDDL() bool
+}
+
+// transactionalDDL returns true if the dialect's DDL statements are rolled
+// back with the transaction they are executed in
+func transactionalDDL(d Dialect) bool {
+	if t, ok := d.(ddlTransactioner); ok {
+		return t.TransactionalDDL()
+	}
+	return transactions(d)
+}
+
+// distincter is implemented by dialects whose declared keys aren't enforced,
+// so that relations which use them shouldn't trust their candidate keys
+// unless WithDistinct says otherwise.
+type distincter interface {
+	Distinctness() Distinctness
+}
+
+// defaultDistinctness returns the distinctness policy of relations which
+// use the dialect, when none is set with WithDistinct
+func defaultDistinctness(d Dialect) Distinctness {
+	if di, ok := d.(distincter); ok {
+		return di.Distinctness()
+	}
+	return AssumeDistinctByKey
+}
+
+// aliaser is implemented by dialects which name derived tables differently
+// than with AS.  TableAlias returns the clause, including a leading space.
+type aliaser interface {
+	TableAlias(name string) string
+}
+
+// tableAlias returns the clause that names a derived table in the dialect
+func tableAlias(d Dialect, name string) string {
+	if a, ok := d.(aliaser); ok {
+		return a.TableAlias(name)
+	}
+	return " AS " + name
+}
+
+// limiter is implemented by dialects which limit the number of rows of a
+// query with something other than FETCH FIRST.
+type limiter interface {
+	Limit(query string, n int) string
+}
+
+// limitQuery returns the query, limited to its first n rows
+func limitQuery(d Dialect, query string, n int) string {
+	if l, ok := d.(limiter); ok {
+		return l.Limit(query, n)
+	}
+	return fmt.Sprintf("%s FETCH FIRST %d ROWS ONLY", query, n)
+}
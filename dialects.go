@@ -0,0 +1,96 @@
+package relsql
+
+import (
+	"database/sql"
+	"reflect"
+	"sync"
+)
+
+// dialects holds the registered dialects, by the name of the driver that
+// they are used with, and the names of those drivers by their type.
+var dialects = struct {
+	sync.RWMutex
+	m map[string]Dialect
+
+	// types holds the names of the drivers with a dialect by the type of
+	// the driver, which is computed again if a dialect or a driver is
+	// registered.  drivers is the number of registered drivers that it was
+	// computed with, since drivers can't be unregistered.
+	types   map[reflect.Type]string
+	drivers int
+}{m: make(map[string]Dialect)}
+
+// RegisterDialect sets the dialect used with the database/sql driver that is
+// registered as driverName, replacing any dialect that was already
+// registered for it.  Packages that provide dialects for other databases
+// register them in their init functions, like drivers do.
+func RegisterDialect(driverName string, d Dialect) {
+	dialects.Lock()
+	defer dialects.Unlock()
+	dialects.m[driverName] = d
+	dialects.types = nil
+}
+
+// LookupDialect returns the dialect registered for the driver, or ANSI if
+// there is none.
+func LookupDialect(driverName string) Dialect {
+	dialects.RLock()
+	defer dialects.RUnlock()
+	if d, ok := dialects.m[driverName]; ok {
+		return d
+	}
+	return ANSI
+}
+
+// DialectOf returns the dialect registered for the driver of the database,
+// or ANSI if there is none.  database/sql doesn't keep the name a database
+// was opened with, so the driver is found among the registered drivers by
+// its type.
+func DialectOf(db *sql.DB) Dialect {
+	return LookupDialect(driverName(db))
+}
+
+// driverName returns the name of the registered driver with the same type as
+// the driver of the database and a dialect, or an empty string if there is
+// none.
+func driverName(db *sql.DB) string {
+	t := reflect.TypeOf(db.Driver())
+	n := len(sql.Drivers())
+	dialects.RLock()
+	if dialects.types != nil && dialects.drivers == n {
+		defer dialects.RUnlock()
+		return dialects.types[t]
+	}
+	dialects.RUnlock()
+
+	dialects.Lock()
+	defer dialects.Unlock()
+	if dialects.types == nil || dialects.drivers != n {
+		dialects.types, dialects.drivers = driverTypes(), n
+	}
+	return dialects.types[t]
+}
+
+// driverTypes returns the names of the drivers with a dialect by the type of
+// the driver.  database/sql only gives the driver of an open database, so
+// each of them is opened once, which doesn't connect to it.  If drivers of
+// the same type are registered under several names, the first name is used.
+// The caller holds the lock on dialects.
+func driverTypes() map[reflect.Type]string {
+	types := make(map[reflect.Type]string)
+	for _, name := range sql.Drivers() {
+		if _, ok := dialects.m[name]; !ok {
+			continue
+		}
+		db, err := sql.Open(name, "")
+		if err != nil {
+			continue
+		}
+		t := reflect.TypeOf(db.Driver())
+		db.Close()
+		if _, ok := types[t]; !ok {
+			types[t] = name
+		}
+	}
+	return types
+}
@@ -0,0 +1,170 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+)
+
+// pluginDriver is a driver for a database that relsql has no dialect for
+type pluginDriver struct{}
+
+func (pluginDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("relsqlplugin can't connect")
+}
+
+// pluginDialect is an out of tree dialect for pluginDriver
+type pluginDialect struct {
+	ansiDialect
+}
+
+func (pluginDialect) Name() string {
+	return "plugin"
+}
+
+func init() {
+	sql.Register("relsqlplugin", pluginDriver{})
+}
+
+// test registering dialects and looking them up by driver
+func TestRegisterDialect(t *testing.T) {
+	db, err := sql.Open("relsqlplugin", "")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	// unknown drivers use ANSI
+	if d := DialectOf(db); d != ANSI {
+		t.Errorf("DialectOf() => %v, want ANSI", d.Name())
+	}
+	if d := LookupDialect("relsqlplugin"); d != ANSI {
+		t.Errorf("LookupDialect() => %v, want ANSI", d.Name())
+	}
+
+	RegisterDialect("relsqlplugin", pluginDialect{})
+	var dialectsTest = []struct {
+		d    Dialect
+		want string
+	}{
+		{LookupDialect("relsqlplugin"), "plugin"},
+		{DialectOf(db), "plugin"},
+		{LookupDialect("nodriver"), "ansi"},
+	}
+	for i, tt := range dialectsTest {
+		if tt.d.Name() != tt.want {
+			t.Errorf("%d has dialect %s, want %s", i, tt.d.Name(), tt.want)
+		}
+	}
+}
+
+// countedDriver is a driver that counts how many times a database is opened
+// with it
+type countedDriver struct {
+	opens *int
+}
+
+func (d countedDriver) Open(name string) (driver.Conn, error) {
+	return nil, errors.New("relsqlcounted can't connect")
+}
+
+func (d countedDriver) OpenConnector(name string) (driver.Connector, error) {
+	*d.opens++
+	return countedConnector{d}, nil
+}
+
+type countedConnector struct {
+	d countedDriver
+}
+
+func (c countedConnector) Connect(context.Context) (driver.Conn, error) { return c.d.Open("") }
+func (c countedConnector) Driver() driver.Driver                        { return c.d }
+
+var countedOpens int
+
+func init() {
+	sql.Register("relsqlcounted", countedDriver{&countedOpens})
+}
+
+// test that drivers are only opened to find their type once, and only if
+// they have a dialect
+func TestDriverTypes(t *testing.T) {
+	db, err := sql.Open("relsqlcounted", "")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	opens := countedOpens
+	for i := 0; i < 3; i++ {
+		if d := DialectOf(db); d != ANSI {
+			t.Errorf("DialectOf() => %v, want ANSI", d.Name())
+		}
+	}
+	if countedOpens != opens {
+		t.Errorf("driver without a dialect was opened %d times", countedOpens-opens)
+	}
+	RegisterDialect("relsqlcounted", pluginDialect{})
+	for i := 0; i < 3; i++ {
+		if d := DialectOf(db); d.Name() != "plugin" {
+			t.Errorf("DialectOf() => %v, want plugin", d.Name())
+		}
+	}
+	if countedOpens != opens+1 {
+		t.Errorf("driver with a dialect was opened %d times, want 1", countedOpens-opens)
+	}
+}
+
+// test detecting the dialect of relations from their database's driver
+func TestDetectDialect(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:detect?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type tup struct {
+		ID int
+	}
+	var detectTest = []struct {
+		d    Dialect
+		want Dialect
+	}{
+		{DialectOf(db), SQLite},
+		{New(db, "t", tup{}, nil).(*sqlTable).dialect(), SQLite},
+		{New(db, "t", tup{}, nil, WithDialect(Oracle)).(*sqlTable).dialect(), Oracle},
+		{LookupDialect("pgx"), Postgres},
+		{LookupDialect("mysql"), MySQL},
+		{LookupDialect("sqlserver"), SQLServer},
+		{LookupDialect("godror"), Oracle},
+	}
+	for i, tt := range detectTest {
+		if tt.d != tt.want {
+			t.Errorf("%d has dialect %s, want %s", i, tt.d.Name(), tt.want.Name())
+		}
+	}
+
+	// the built in dialects compile to their own sql
+	var sqlTest = []struct {
+		d    Dialect
+		want string
+	}{
+		{Postgres, "SELECT /*+ RECOMPILE */ ID FROM t WHERE ID = $1"},
+		{MySQL, "SELECT /*+ RECOMPILE */ ID FROM t WHERE ID = ?"},
+		{SQLServer, "SELECT ID FROM t WHERE ID = @p1 OPTION (RECOMPILE)"},
+	}
+	for i, tt := range sqlTest {
+		r := New(db, "t", tup{}, [][]string{[]string{"ID"}}, WithDialect(tt.d), WithHints("RECOMPILE")).Restrict(Attribute("ID").EQ(1))
+		if q, _, err := SQL(r); q != tt.want || err != nil {
+			t.Errorf("%d has SQL() => %q, %v, want %q", i, q, err, tt.want)
+		}
+	}
+	if q := limitQuery(SQLServer, "SELECT ID FROM t", 2); q != "SELECT TOP (2) * FROM (SELECT ID FROM t) AS l" {
+		t.Errorf("limitQuery() => %q", q)
+	}
+}
@@ -0,0 +1,164 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationFormat is how a dialect stores time.Duration attributes
+type DurationFormat int
+
+const (
+	// DurationMicros stores durations as a BIGINT number of microseconds
+	DurationMicros DurationFormat = iota
+
+	// DurationSeconds stores durations as a BIGINT number of whole seconds
+	DurationSeconds
+
+	// DurationInterval stores durations in the database's INTERVAL type.
+	// Intervals are bound as text, and scanned from the postgres interval
+	// style, like "1 day 02:03:04.5".  Intervals with months or years can't
+	// be scanned, because they don't have a fixed length.
+	DurationInterval
+)
+
+// durationFormatter is implemented by dialects which don't store durations
+// as microseconds
+type durationFormatter interface {
+	DurationFormat() DurationFormat
+}
+
+// durationFormat returns the format for durations in a dialect
+func durationFormat(d Dialect) DurationFormat {
+	if f, ok := d.(durationFormatter); ok {
+		return f.DurationFormat()
+	}
+	return DurationMicros
+}
+
+// DurationCodec is the codec for time.Duration, which can also be registered
+// for other int64 duration types.
+var DurationCodec Codec = durationCodec{}
+
+// durationCodec converts durations according to the dialect's DurationFormat
+type durationCodec struct{}
+
+// Encode converts a duration to an integer, or to interval text
+func (durationCodec) Encode(d Dialect, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Int64 {
+		return nil, fmt.Errorf("relsql: %T is not a duration", v)
+	}
+	dur := time.Duration(rv.Int())
+	switch durationFormat(d) {
+	case DurationSeconds:
+		if dur%time.Second != 0 {
+			return nil, fmt.Errorf("relsql: duration %v is not a whole number of seconds", dur)
+		}
+		return int64(dur / time.Second), nil
+	case DurationInterval:
+		return fmt.Sprintf("%d microseconds", dur/time.Microsecond), nil
+	}
+	return int64(dur / time.Microsecond), nil
+}
+
+// Decode converts an integer or interval text into a duration
+func (durationCodec) Decode(d Dialect, src interface{}, dst reflect.Value) error {
+	var dur time.Duration
+	switch s := src.(type) {
+	case int64:
+		if durationFormat(d) == DurationSeconds {
+			dur = time.Duration(s) * time.Second
+		} else {
+			dur = time.Duration(s) * time.Microsecond
+		}
+	case string:
+		var err error
+		if dur, err = parseInterval(s); err != nil {
+			return err
+		}
+	case []byte:
+		var err error
+		if dur, err = parseInterval(string(s)); err != nil {
+			return err
+		}
+	case nil:
+		return fmt.Errorf("relsql: can't convert NULL to %v", dst.Type())
+	default:
+		return decodeError(src, dst)
+	}
+	dst.SetInt(int64(dur))
+	return nil
+}
+
+// TypeName returns the column type for durations in the dialect
+func (durationCodec) TypeName(d Dialect) string {
+	if durationFormat(d) == DurationInterval {
+		return "INTERVAL"
+	}
+	return "BIGINT"
+}
+
+// parseInterval parses an interval in the postgres style, which is a list of
+// day counts followed by an optional time of day, like
+// "-1 days +02:03:04.000005".
+func parseInterval(s string) (time.Duration, error) {
+	var dur time.Duration
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Contains(f, ":") {
+			t, err := parseClock(f)
+			if err != nil {
+				return 0, fmt.Errorf("relsql: invalid interval %q", s)
+			}
+			dur += t
+			continue
+		}
+		n, err := strconv.ParseInt(f, 10, 64)
+		if err != nil || i+1 == len(fields) {
+			return 0, fmt.Errorf("relsql: invalid interval %q", s)
+		}
+		i++
+		switch unit := fields[i]; unit {
+		case "day", "days":
+			dur += time.Duration(n) * 24 * time.Hour
+		default:
+			return 0, fmt.Errorf("relsql: can't convert interval %q with %s to a duration", s, unit)
+		}
+	}
+	return dur, nil
+}
+
+// parseClock parses a signed time of day like "-02:03:04.5"
+func parseClock(s string) (time.Duration, error) {
+	neg := strings.HasPrefix(s, "-")
+	parts := strings.Split(strings.TrimLeft(s, "+-"), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("relsql: invalid time %q", s)
+	}
+	h, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	m, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	sec, err := time.ParseDuration(parts[2] + "s")
+	if err != nil || strings.HasPrefix(parts[2], "-") {
+		return 0, fmt.Errorf("relsql: invalid time %q", s)
+	}
+	dur := time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + sec
+	if neg {
+		dur = -dur
+	}
+	return dur, nil
+}
+
+func init() {
+	RegisterCodec(reflect.TypeOf(time.Duration(0)), DurationCodec)
+}
@@ -0,0 +1,105 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// test duration encoding and interval parsing
+func TestDuration(t *testing.T) {
+	dur := 26*time.Hour + 3*time.Minute + 4500*time.Millisecond
+	var formatTest = []struct {
+		d        Dialect
+		typeName string
+		encoded  interface{}
+	}{
+		{ANSI, "BIGINT", int64(93784500000)},
+		{testDialect{duration: DurationSeconds}, "BIGINT", nil},
+		{testDialect{duration: DurationInterval}, "INTERVAL", "93784500000 microseconds"},
+		{Postgres, "INTERVAL", "93784500000 microseconds"},
+	}
+	for i, tt := range formatTest {
+		if typeName := DurationCodec.TypeName(tt.d); typeName != tt.typeName {
+			t.Errorf("%d has TypeName() => %v, want %v", i, typeName, tt.typeName)
+		}
+		v, err := encodeArg(tt.d, dur)
+		if tt.encoded == nil {
+			if err == nil {
+				t.Errorf("%d has encodeArg() => nil error", i)
+			}
+			continue
+		}
+		if v != tt.encoded || err != nil {
+			t.Errorf("%d has encodeArg() => %v, %v, want %v", i, v, err, tt.encoded)
+		}
+	}
+
+	var intervalTest = []struct {
+		in    string
+		out   time.Duration
+		isErr bool
+	}{
+		{"00:00:00", 0, false},
+		{"1 day 02:03:04.5", dur, false},
+		{"26:03:04.5", dur, false},
+		{"-1 days +02:00:00", -22 * time.Hour, false},
+		{"-00:00:00.000001", -time.Microsecond, false},
+		{"3 days", 72 * time.Hour, false},
+		{"1 mon 2 days", 0, true},
+		{"1 day 2", 0, true},
+		{"02:03", 0, true},
+	}
+	for i, tt := range intervalTest {
+		out, err := parseInterval(tt.in)
+		if out != tt.out || (err != nil) != tt.isErr {
+			t.Errorf("%d has parseInterval(%q) => %v, %v, want %v", i, tt.in, out, err, tt.out)
+		}
+	}
+}
+
+// test writing, restricting on, and scanning duration attributes
+func TestDurationColumn(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type taskTup struct {
+		Name    string
+		Timeout time.Duration
+	}
+	if err := CreateTable(db, "tasks", taskTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	tasks := rel.New([]taskTup{{"build", 90 * time.Second}, {"test", 5 * time.Minute}}, [][]string{[]string{"Name"}})
+	if _, err := Insert(db, "tasks", tasks); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var stored int64
+	db.QueryRow("select Timeout from tasks where Name = 'build'").Scan(&stored)
+	if stored != 90000000 {
+		t.Errorf("stored duration => %v, want %v", stored, 90000000)
+	}
+
+	long := New(db, "tasks", taskTup{}, [][]string{[]string{"Name"}}).Restrict(Attribute("Timeout").GT(2 * time.Minute))
+	ch := make(chan taskTup)
+	long.TupleChan(ch)
+	var res []taskTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if want := []taskTup{{"test", 5 * time.Minute}}; !reflect.DeepEqual(res, want) {
+		t.Errorf("restricted tasks => %v, want %v", res, want)
+	}
+	if err := long.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// enum is the set of values allowed for an attribute
+type enum struct {
+	att    string
+	values []interface{}
+}
+
+// WithEnum declares that an attribute may only hold the given values.  Values
+// are checked when tuples are scanned and when they are written, and tables
+// created with the option constrain the column to the values, with the
+// dialect's native enum type if it has one, and a CHECK constraint otherwise.
+// The values have to be convertible to the attribute's type.
+func WithEnum(att string, values ...interface{}) Option {
+	return func(o *options) {
+		o.enums = append(o.enums, enum{att, values})
+	}
+}
+
+// enumTyper is implemented by dialects with inline enum column types, like
+// mysql's ENUM('a', 'b').  EnumType returns an empty string if the values
+// can't be held by an enum type.
+type enumTyper interface {
+	EnumType(values []interface{}) string
+}
+
+// enumValues returns the values allowed for an attribute, or nil if it is not
+// an enum.
+func (o *options) enumValues(att string) []interface{} {
+	for _, en := range o.enums {
+		if en.att == att {
+			return en.values
+		}
+	}
+	return nil
+}
+
+// enumField is an enum attribute at a position in a tuple
+type enumField struct {
+	index int
+	enum
+
+	// redacted is true if the attribute's values can't appear in errors
+	redacted bool
+}
+
+// enumCheck validates the enum attributes of tuples
+type enumCheck []enumField
+
+// enumCheck returns the check for tuples read from or written to the columns.
+// Enums are declared by column name, so that they still apply after the
+// attributes are renamed.
+func (o *options) enumCheck(cols []column) enumCheck {
+	var c enumCheck
+	for i, col := range cols {
+		if values := o.enumValues(col.name); values != nil {
+			c = append(c, enumField{i, enum{col.name, values}, o.isRedacted(col.name)})
+		}
+	}
+	return c
+}
+
+// check returns an error if any enum attribute of the tuple holds a value
+// that is not allowed.
+func (c enumCheck) check(tup reflect.Value) error {
+	for _, f := range c {
+		v := tup.Field(f.index)
+		if enumContains(f.values, v) {
+			continue
+		}
+		if f.redacted {
+			return fmt.Errorf("relsql: %s is not an allowed value of %s", redactedValue, f.att)
+		}
+		return fmt.Errorf("relsql: %v is not an allowed value of %s", v.Interface(), f.att)
+	}
+	return nil
+}
+
+// enumContains returns true if v is one of the values
+func enumContains(values []interface{}, v reflect.Value) bool {
+	for _, a := range values {
+		av := reflect.ValueOf(a)
+		// conversions between strings and integers are not comparisons
+		if (av.Kind() == reflect.String) != (v.Kind() == reflect.String) || !av.Type().ConvertibleTo(v.Type()) {
+			continue
+		}
+		if av.Convert(v.Type()).Interface() == v.Interface() {
+			return true
+		}
+	}
+	return false
+}
+
+// enumConstraint returns the column type and the constraint for an enum
+// column.  The type is empty if the column should use its usual type.
+func enumConstraint(d Dialect, colName string, values []interface{}) (typeName, constraint string) {
+	if t, ok := d.(enumTyper); ok {
+		if typeName = t.EnumType(values); typeName != "" {
+			return typeName, ""
+		}
+	}
+	lits := make([]string, len(values))
+	for i, v := range values {
+		lits[i] = literal(v)
+	}
+	return "", "CHECK (" + colName + " IN (" + strings.Join(lits, ", ") + "))"
+}
@@ -0,0 +1,95 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test enum constraints in generated ddl
+func TestEnumDDL(t *testing.T) {
+	type Color string
+	type paintTup struct {
+		Name  string
+		Color Color
+		Coats int
+	}
+	opts := []Option{WithEnum("Color", "red", "green"), WithEnum("Coats", 1, 2)}
+	var ddlTest = []struct {
+		d    Dialect
+		want string
+	}{
+		{ANSI, "CREATE TABLE paints (Name TEXT NOT NULL, Color TEXT NOT NULL CHECK (Color IN ('red', 'green')), Coats BIGINT NOT NULL CHECK (Coats IN (1, 2)), PRIMARY KEY (Name))"},
+		{MySQL, "CREATE TABLE paints (Name TEXT NOT NULL, Color ENUM('red', 'green') NOT NULL, Coats BIGINT NOT NULL CHECK (Coats IN (1, 2)), PRIMARY KEY (Name))"},
+	}
+	for i, tt := range ddlTest {
+		str, err := CreateTableString(tt.d, "paints", paintTup{}, [][]string{[]string{"Name"}}, opts...)
+		if str != tt.want || err != nil {
+			t.Errorf("%d has CreateTableString() => %v, %v, want %v", i, str, err, tt.want)
+		}
+	}
+
+	c := (&options{enums: []enum{{"Color", []interface{}{"red", "green"}}, {"Coats", []interface{}{1, 2}}}}).enumCheck(colNames(paintTup{}))
+	var checkTest = []struct {
+		tup   paintTup
+		isErr bool
+	}{
+		{paintTup{"a", "red", 1}, false},
+		{paintTup{"a", "blue", 1}, true},
+		{paintTup{"a", "green", 3}, true},
+	}
+	for i, tt := range checkTest {
+		if err := c.check(reflect.ValueOf(tt.tup)); (err != nil) != tt.isErr {
+			t.Errorf("%d has check(%v) => %v, want error %v", i, tt.tup, err, tt.isErr)
+		}
+	}
+}
+
+// test enum validation on write and scan, and IN predicates
+func TestEnumColumn(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type orderTup struct {
+		ID     int
+		Status string
+	}
+	ckeys := [][]string{[]string{"ID"}}
+	statuses := WithEnum("Status", "new", "paid", "shipped")
+	if err := CreateTable(db, "orders", orderTup{}, ckeys, statuses); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	bad := rel.New([]orderTup{{1, "lost"}}, ckeys)
+	if _, err := Insert(db, "orders", bad, statuses); err == nil {
+		t.Errorf("Insert() of invalid status => nil error")
+	}
+	orders := rel.New([]orderTup{{1, "new"}, {2, "paid"}, {3, "shipped"}}, ckeys)
+	if _, err := Insert(db, "orders", orders, statuses); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	open := New(db, "orders", orderTup{}, ckeys, statuses).Restrict(Attribute("Status").In("new", "paid"))
+	q, args, _ := open.(*sqlTable).queryString()
+	if want := "SELECT ID, Status FROM orders WHERE Status IN (?, ?)"; q != want || fmt.Sprint(args) != "[new paid]" {
+		t.Errorf("query => %v %v, want %v [new paid]", q, args, want)
+	}
+	if c := rel.Card(open); c != 2 {
+		t.Errorf("Card() of open orders => %v, want 2", c)
+	}
+
+	// values that bypass the application are caught when they are scanned
+	db.Exec("insert into orders (ID, Status) values (4, 'new')")
+	narrow := New(db, "orders", orderTup{}, ckeys, WithEnum("Status", "paid", "shipped"))
+	rel.Card(narrow)
+	if narrow.Err() == nil {
+		t.Errorf("scan of value outside of enum has Err() => nil")
+	}
+}
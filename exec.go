@@ -0,0 +1,74 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+)
+
+// QueryerContext executes queries.  It is implemented by *sql.DB, *sql.Conn
+// and *sql.Tx, and can be implemented by a stub, or a *sql.DB from a mock
+// driver like go-sqlmock, to test code that composes relations without a
+// real database.
+type QueryerContext interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// ExecerContext executes statements.  It is implemented by *sql.DB, *sql.Conn
+// and *sql.Tx.  If it also has a PrepareContext method, like those types do,
+// full batches are written with a prepared statement.
+type ExecerContext interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// NewQueryer creates a relation like New, which reads from a table by
+// executing its queries with q.  Reads are not wrapped in transactions, and
+// dialect session statements are not executed, so a stub only sees the
+// queries that produce tuples.  If q has a PingContext method, it is used
+// when the relation is read with WithPing.  Relations can only be combined
+// into one query with others from the same q, so its type should be
+// comparable, like a pointer.
+func NewQueryer(q QueryerContext, tableName string, z interface{}, ckeystr [][]string, opts ...Option) rel.Relation {
+	r := New(nil, tableName, z, ckeystr, opts...).(*sqlTable)
+	r.q = q
+	return r
+}
+
+// InsertExec writes every tuple of r into the table as a new row, like
+// Insert, by executing the statements with e.  No transaction is started, so
+// e can be a stub, or a transaction that the caller manages.
+func InsertExec(e ExecerContext, tableName string, r rel.Relation, opts ...Option) (WriteResult, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	p := newProgress(o.progress)
+	if err := checkZero(reflect.TypeOf(r.Zero())); err != nil {
+		return p.result(), err
+	}
+	w, err := newBatchWriter(e, &o, tableName, r.Zero(), p)
+	if err != nil {
+		return p.result(), err
+	}
+	defer w.close()
+	err = forEach(r, w.add)
+	if err == nil {
+		err = w.flush()
+	}
+	if err != nil {
+		p.fail(w.n, err)
+	}
+	return p.result(), err
+}
+
+// sameQueryer returns true if both relations execute their queries with the
+// same QueryerContext, or neither has one.
+func sameQueryer(q1, q2 QueryerContext) bool {
+	if q1 == nil || q2 == nil {
+		return q1 == nil && q2 == nil
+	}
+	t := reflect.TypeOf(q1)
+	return t == reflect.TypeOf(q2) && t.Comparable() && q1 == q2
+}
@@ -0,0 +1,112 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// recorder is a stub executor which records the statements it is given, and
+// answers queries from a database.
+type recorder struct {
+	db    *sql.DB
+	stmts []string
+}
+
+func (r *recorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	r.stmts = append(r.stmts, query)
+	return r.db.QueryContext(ctx, query, args...)
+}
+
+func (r *recorder) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	r.stmts = append(r.stmts, query)
+	return r.db.QueryRowContext(ctx, query, args...)
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.stmts = append(r.stmts, fmt.Sprint(query, args))
+	return driverResult(len(args)), nil
+}
+
+// driverResult is the result of a stub statement
+type driverResult int64
+
+func (r driverResult) LastInsertId() (int64, error) { return 0, nil }
+func (r driverResult) RowsAffected() (int64, error) { return int64(r), nil }
+
+// test reading relations through a stub executor
+func TestNewQueryer(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:queryer?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type partTup struct {
+		PNO    int
+		Colour string
+	}
+	keys := [][]string{[]string{"PNO"}}
+	if err := CreateTable(db, "parts", partTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "parts", rel.New([]partTup{{1, "red"}, {2, "blue"}, {3, "red"}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	rec := &recorder{db: db}
+	parts := NewQueryer(rec, "parts", partTup{}, keys, WithDialect(SQLite))
+	r := parts.Restrict(Attribute("Colour").EQ("red")).Union(parts.Restrict(Attribute("PNO").EQ(2)))
+	if _, ok := r.(*sqlTable); !ok {
+		t.Errorf("Union() => %T, want a single query", r)
+	}
+	ch := make(chan partTup)
+	r.TupleChan(ch)
+	var ids []int
+	for tup := range ch {
+		ids = append(ids, tup.PNO)
+	}
+	sort.Ints(ids)
+	if fmt.Sprint(ids) != "[1 2 3]" {
+		t.Errorf("PNO => %v, want [1 2 3]", ids)
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+	want, _, _ := SQL(r)
+	if len(rec.stmts) != 1 || rec.stmts[0] != want {
+		t.Errorf("executed %q, want [%q]", rec.stmts, want)
+	}
+
+	// relations on other executors are not combined
+	if _, ok := parts.Join(NewQueryer(&recorder{db: db}, "parts", partTup{}, keys), partTup{}).(*sqlTable); ok {
+		t.Errorf("Join() with another executor => a single query")
+	}
+}
+
+// test writing relations through a stub executor
+func TestInsertExec(t *testing.T) {
+	type partTup struct {
+		PNO    int
+		Colour string
+	}
+	rec := &recorder{}
+	parts := rel.New([]partTup{{1, "red"}, {2, "blue"}, {3, "red"}}, [][]string{[]string{"PNO"}})
+	res, err := InsertExec(rec, "parts", parts, WithBatchSize(2))
+	if err != nil {
+		t.Errorf("InsertExec() => %v", err)
+		return
+	}
+	if res.Rows != 3 || res.Batches != 2 {
+		t.Errorf("InsertExec() => %d rows in %d batches, want 3 in 2", res.Rows, res.Batches)
+	}
+	if len(rec.stmts) != 2 {
+		t.Errorf("executed %q, want 2 statements", rec.stmts)
+	}
+}
@@ -0,0 +1,236 @@
+package relsql
+
+import (
+	"bufio"
+	"database/sql/driver"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Encoder writes the tuples of a relation in an export format.  Export calls
+// Heading once with the attribute names, then Tuple with the values of each
+// tuple in heading order, and then Close, which flushes anything buffered but
+// doesn't close the underlying writer.  Encoders for other formats, such as
+// parquet or arrow, can be written outside of this package, and encoders can
+// write to any io.Writer, such as a gzip.Writer or an upload to object
+// storage.
+type Encoder interface {
+	Heading(names []string) error
+	Tuple(values []interface{}) error
+	Close() error
+}
+
+// Export streams the tuples of a relation to an encoder.  Tuples are encoded
+// as they are read, so the relation is never held in memory.
+func Export(r rel.Relation, enc Encoder) error {
+	e := reflect.TypeOf(r.Zero())
+	names := make([]string, e.NumField())
+	for i := range names {
+		names[i] = e.Field(i).Name
+	}
+	if err := enc.Heading(names); err != nil {
+		return err
+	}
+	values := make([]interface{}, len(names))
+	err := forEach(r, func(tup reflect.Value) error {
+		for i := range values {
+			values[i] = tup.Field(i).Interface()
+		}
+		return enc.Tuple(values)
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// exportText returns the text form of a value in text export formats.  Times
+// are in RFC 3339, values with a codec are in their encoded form, and NULLs
+// are empty.
+func exportText(v interface{}) (string, error) {
+	if c := lookupCodec(reflect.TypeOf(v)); c != nil {
+		ev, err := c.Encode(ANSI, v)
+		if err != nil {
+			return "", err
+		}
+		v = ev
+	} else if vr, ok := v.(driver.Valuer); ok {
+		ev, err := vr.Value()
+		if err != nil {
+			return "", err
+		}
+		v = ev
+	}
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case encoding.TextMarshaler:
+		b, err := v.MarshalText()
+		return string(b), err
+	}
+	return fmt.Sprint(v), nil
+}
+
+// csvEncoder writes comma separated values
+type csvEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+// NewCSVEncoder returns an encoder that writes comma separated values, with
+// a header record of the attribute names.
+func NewCSVEncoder(w io.Writer) Encoder {
+	return &csvEncoder{w: csv.NewWriter(w)}
+}
+
+// Heading writes the header record
+func (enc *csvEncoder) Heading(names []string) error {
+	enc.record = make([]string, len(names))
+	return enc.w.Write(names)
+}
+
+// Tuple writes a record
+func (enc *csvEncoder) Tuple(values []interface{}) error {
+	for i, v := range values {
+		s, err := exportText(v)
+		if err != nil {
+			return err
+		}
+		enc.record[i] = s
+	}
+	return enc.w.Write(enc.record)
+}
+
+// Close flushes the buffered records
+func (enc *csvEncoder) Close() error {
+	enc.w.Flush()
+	return enc.w.Error()
+}
+
+// jsonEncoder writes newline delimited json objects
+type jsonEncoder struct {
+	w     *bufio.Writer
+	names []string
+}
+
+// NewJSONEncoder returns an encoder that writes each tuple as a json object
+// on its own line, with its attributes in heading order.
+func NewJSONEncoder(w io.Writer) Encoder {
+	return &jsonEncoder{w: bufio.NewWriter(w)}
+}
+
+// Heading keeps the attribute names, which are the keys of the objects
+func (enc *jsonEncoder) Heading(names []string) error {
+	enc.names = make([]string, len(names))
+	for i, name := range names {
+		b, err := json.Marshal(name)
+		if err != nil {
+			return err
+		}
+		enc.names[i] = string(b)
+	}
+	return nil
+}
+
+// Tuple writes an object
+func (enc *jsonEncoder) Tuple(values []interface{}) error {
+	enc.w.WriteByte('{')
+	for i, v := range values {
+		if i > 0 {
+			enc.w.WriteByte(',')
+		}
+		b, err := exportJSON(v)
+		if err != nil {
+			return err
+		}
+		enc.w.WriteString(enc.names[i])
+		enc.w.WriteByte(':')
+		enc.w.Write(b)
+	}
+	enc.w.WriteString("}\n")
+	return nil
+}
+
+// Close flushes the buffered objects
+func (enc *jsonEncoder) Close() error {
+	return enc.w.Flush()
+}
+
+// exportJSON returns the json form of a value.  Values that marshal
+// themselves are used as is, and otherwise values with a codec are in their
+// encoded form.
+func exportJSON(v interface{}) ([]byte, error) {
+	switch v.(type) {
+	case json.Marshaler, encoding.TextMarshaler:
+	default:
+		if c := lookupCodec(reflect.TypeOf(v)); c != nil {
+			ev, err := c.Encode(ANSI, v)
+			if err != nil {
+				return nil, err
+			}
+			v = ev
+		}
+	}
+	return json.Marshal(v)
+}
+
+// fixedWidthEncoder writes records of fixed width fields
+type fixedWidthEncoder struct {
+	w      *bufio.Writer
+	widths []int
+	names  []string
+}
+
+// NewFixedWidthEncoder returns an encoder that writes each tuple as a line of
+// fields padded with spaces to the given widths, which are in characters.  It
+// writes no header, and values that are wider than their field are an error,
+// rather than being truncated.
+func NewFixedWidthEncoder(w io.Writer, widths ...int) Encoder {
+	return &fixedWidthEncoder{w: bufio.NewWriter(w), widths: widths}
+}
+
+// Heading checks that there is a width for each attribute
+func (enc *fixedWidthEncoder) Heading(names []string) error {
+	if len(names) != len(enc.widths) {
+		return fmt.Errorf("relsql: %d fixed widths for %d attributes", len(enc.widths), len(names))
+	}
+	enc.names = names
+	return nil
+}
+
+// Tuple writes a line
+func (enc *fixedWidthEncoder) Tuple(values []interface{}) error {
+	for i, v := range values {
+		s, err := exportText(v)
+		if err != nil {
+			return err
+		}
+		n := utf8.RuneCountInString(s)
+		if n > enc.widths[i] || strings.ContainsAny(s, "\r\n") {
+			return fmt.Errorf("relsql: %s value %q doesn't fit in a field of width %d", enc.names[i], s, enc.widths[i])
+		}
+		enc.w.WriteString(s)
+		enc.w.WriteString(strings.Repeat(" ", enc.widths[i]-n))
+	}
+	enc.w.WriteByte('\n')
+	return nil
+}
+
+// Close flushes the buffered lines
+func (enc *fixedWidthEncoder) Close() error {
+	return enc.w.Flush()
+}
@@ -0,0 +1,64 @@
+package relsql
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/jonlawlor/rel"
+	"io"
+	"testing"
+	"time"
+)
+
+// test exporting a relation in each of the formats
+func TestExport(t *testing.T) {
+	type exportTup struct {
+		Name  string
+		Count int
+		Loc   Point
+		At    time.Time
+	}
+	r := rel.New([]exportTup{
+		{"a, b", 1, Point{1, 2}, time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"c", 22, Point{0, 0}, time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}, [][]string{[]string{"Name"}})
+
+	var exportTest = []struct {
+		enc      func(w io.Writer) Encoder
+		expected string
+	}{
+		{NewCSVEncoder, "Name,Count,Loc,At\n\"a, b\",1,POINT(1 2),2015-01-02T03:04:05Z\nc,22,POINT(0 0),2016-01-02T00:00:00Z\n"},
+		{NewJSONEncoder, `{"Name":"a, b","Count":1,"Loc":"POINT(1 2)","At":"2015-01-02T03:04:05Z"}` + "\n" +
+			`{"Name":"c","Count":22,"Loc":"POINT(0 0)","At":"2016-01-02T00:00:00Z"}` + "\n"},
+		{func(w io.Writer) Encoder { return NewFixedWidthEncoder(w, 5, 3, 11, 20) },
+			"a, b 1  POINT(1 2) 2015-01-02T03:04:05Z\nc    22 POINT(0 0) 2016-01-02T00:00:00Z\n"},
+	}
+	for i, tt := range exportTest {
+		var buf bytes.Buffer
+		if err := Export(r, tt.enc(&buf)); err != nil || buf.String() != tt.expected {
+			t.Errorf("%d has Export() => %q, %v, want %q", i, buf.String(), err, tt.expected)
+		}
+	}
+
+	// encoders compose with other writers
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := Export(r, NewCSVEncoder(gz)); err != nil {
+		t.Errorf("Export() to gzip => %v", err)
+	}
+	gz.Close()
+	zr, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Errorf("gzip.NewReader() => %v", err)
+		return
+	}
+	if b, _ := io.ReadAll(zr); string(b) != exportTest[0].expected {
+		t.Errorf("gzipped Export() => %q, want %q", b, exportTest[0].expected)
+	}
+
+	if err := Export(r, NewFixedWidthEncoder(io.Discard, 3, 3, 11, 20)); err == nil {
+		t.Errorf("Export() with a narrow field succeeded")
+	}
+	if err := Export(r, NewFixedWidthEncoder(io.Discard, 3)); err == nil {
+		t.Errorf("Export() with missing widths succeeded")
+	}
+}
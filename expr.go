@@ -0,0 +1,282 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Expr is an expression of an attribute, such as the lower case of a string,
+// which can be used as a computed attribute with Compute, or as a join key
+// with JoinExpr, without storing it in a column.
+type Expr struct {
+	// fn is the function applied to the attribute: LOWER, UPPER, TRIM or DATE
+	fn  string
+	att string
+}
+
+// Lower is the lower case of a string attribute
+func Lower(att string) Expr {
+	return Expr{"LOWER", att}
+}
+
+// Upper is the upper case of a string attribute
+func Upper(att string) Expr {
+	return Expr{"UPPER", att}
+}
+
+// Trim is a string attribute without leading and trailing spaces
+func Trim(att string) Expr {
+	return Expr{"TRIM", att}
+}
+
+// DateOf is the start of the day of a time attribute
+func DateOf(att string) Expr {
+	return Expr{"DATE", att}
+}
+
+// String returns a text representation of the expression
+func (x Expr) String() string {
+	return x.fn + "(" + x.att + ")"
+}
+
+// check returns the type of the expression of attributes of tuples of type
+// e, or an error if it can't be computed from them.
+func (x Expr) check(e reflect.Type) (reflect.Type, error) {
+	f, ok := e.FieldByName(x.att)
+	if !ok {
+		return nil, fmt.Errorf("relsql: attribute %s of %v is not in the heading of %v", x.att, x, e)
+	}
+	if x.fn == "DATE" {
+		if f.Type != timeType {
+			return nil, fmt.Errorf("relsql: %v of %v is not a time", x, f.Type)
+		}
+	} else if f.Type.Kind() != reflect.String {
+		return nil, fmt.Errorf("relsql: %v of %v is not a string", x, f.Type)
+	}
+	return f.Type, nil
+}
+
+// sql returns the expression in the dialect, or an empty string if the
+// dialect can't compute it.
+func (x Expr) sql(d Dialect) string {
+	return x.on(d, x.att)
+}
+
+// eval computes the expression of a tuple, and stores it in res
+func (x Expr) eval(tup, res reflect.Value) {
+	v := tup.FieldByName(x.att)
+	switch x.fn {
+	case "LOWER":
+		res.SetString(strings.ToLower(v.String()))
+	case "UPPER":
+		res.SetString(strings.ToUpper(v.String()))
+	case "TRIM":
+		res.SetString(strings.Trim(v.String(), " "))
+	case "DATE":
+		res.Set(reflect.ValueOf(truncateTime(v.Interface().(time.Time), Day)))
+	}
+}
+
+// Compute creates a relation with the tuples of r extended by an attribute
+// named as, which holds the value of the expression.  The type of the tuples
+// is a new struct type with the fields of r's tuples, followed by the new
+// attribute.  If r is from this package, and its dialect can compute the
+// expression, it is computed in the select list of the query.
+func Compute(r rel.Relation, as string, x Expr) rel.Relation {
+	e := reflect.TypeOf(r.Zero())
+	t, err := x.check(e)
+	if err == nil && !isExported(as) {
+		err = fmt.Errorf("relsql: computed attribute %s is not exported", as)
+	}
+	if _, ok := e.FieldByName(as); ok && err == nil {
+		err = fmt.Errorf("relsql: computed attribute %s is already in the heading of %v", as, e)
+	}
+	if err != nil {
+		return &sqlTable{zero: r.Zero(), cKeys: r.CKeys(), err: err}
+	}
+	var expr string
+	if r1, ok := r.(*sqlTable); ok {
+		expr = x.sql(r1.dialect())
+	}
+	return binned(r, as, t, expr, "extend{"+as+" := "+x.String()+"}", x.eval)
+}
+
+// exprKey is the name of the attribute that holds the computed keys of
+// JoinExpr
+const exprKey = "RelsqlJoinKey"
+
+// JoinExpr creates the natural join of r1 and r2 that also matches the tuples
+// whose expressions x1, of r1, and x2, of r2, are equal, like joining users on
+// the Lower of their emails.  zero is the type of the resulting tuples, which
+// don't include the expressions.  If both relations are from this package on
+// the same database, the expressions are computed in the query, and the
+// relations are joined on them in its ON clause.
+func JoinExpr(r1, r2 rel.Relation, zero interface{}, x1, x2 Expr) rel.Relation {
+	c1, c2 := Compute(r1, exprKey, x1), Compute(r2, exprKey, x2)
+	for _, c := range []rel.Relation{c1, c2} {
+		if err := c.Err(); err != nil {
+			return &sqlTable{zero: zero, cKeys: rel.DefaultKeys(zero), err: err}
+		}
+	}
+	e1, e2 := reflect.TypeOf(c1.Zero()), reflect.TypeOf(c2.Zero())
+	k1, _ := e1.FieldByName(exprKey)
+	k2, _ := e2.FieldByName(exprKey)
+	if k1.Type != k2.Type {
+		err := fmt.Errorf("relsql: can't join %v with %v, which have different types", x1, x2)
+		return &sqlTable{zero: zero, cKeys: rel.DefaultKeys(zero), err: err}
+	}
+
+	// the join has every attribute of either side, including the key
+	fields := make([]reflect.StructField, 0, e1.NumField()+e2.NumField())
+	for i := 0; i < e1.NumField(); i++ {
+		fields = append(fields, e1.Field(i))
+	}
+	for i := 0; i < e2.NumField(); i++ {
+		if _, ok := e1.FieldByName(e2.Field(i).Name); !ok {
+			fields = append(fields, e2.Field(i))
+		}
+	}
+	j := reflect.New(reflect.StructOf(fields)).Elem().Interface()
+	return c1.Join(c2, j).Project(zero)
+}
+
+// exprArg is the right side of a comparison of an expression, whose left side
+// is the expression instead of its attribute
+type exprArg struct {
+	x   Expr
+	val interface{}
+}
+
+// on returns the expression in the dialect, applied to the sql expression
+// col that produces its attribute, or an empty string if the dialect can't
+// compute it.
+func (x Expr) on(d Dialect, col string) string {
+	if x.fn == "DATE" {
+		return truncateTimeString(d, col, Day)
+	}
+	return x.fn + "(" + col + ")"
+}
+
+// apply computes the expression of a value of its attribute
+func (x Expr) apply(v interface{}) interface{} {
+	res := reflect.New(reflect.TypeOf(v)).Elem()
+	tup := reflect.New(reflect.StructOf([]reflect.StructField{{Name: x.att, Type: res.Type()}})).Elem()
+	tup.Field(0).Set(reflect.ValueOf(v))
+	x.eval(tup, res)
+	return res.Interface()
+}
+
+// compare creates a predicate that compares the expression to a value with
+// the sql operator op.  The client side form computes the expression of each
+// tuple.
+func (x Expr) compare(op string, v interface{}, test func(c int) bool) Pred {
+	cp := clientPred{rel.Attribute(x.att), fmt.Sprintf("%v %s %v", x, op, v), func(v2 interface{}) bool {
+		c, ok := compareValues(x.apply(v2), v)
+		return ok && test(c)
+	}}
+	return Pred{cp, op, rel.Attribute(x.att), exprArg{x, v}, nil}
+}
+
+// EQ creates a predicate that is true when the expression is equal to the
+// value.  In the database it is written as the expression, like
+// LOWER(Email) = ?, so that an index on the expression can be used.
+func (x Expr) EQ(v interface{}) Pred {
+	return x.compare("=", v, func(c int) bool { return c == 0 })
+}
+
+// NE creates a predicate that is true when the expression is not equal to the
+// value
+func (x Expr) NE(v interface{}) Pred {
+	return x.compare("<>", v, func(c int) bool { return c != 0 })
+}
+
+// LT creates a predicate that is true when the expression is less than the
+// value
+func (x Expr) LT(v interface{}) Pred {
+	return x.compare("<", v, func(c int) bool { return c < 0 })
+}
+
+// LE creates a predicate that is true when the expression is less than or
+// equal to the value
+func (x Expr) LE(v interface{}) Pred {
+	return x.compare("<=", v, func(c int) bool { return c <= 0 })
+}
+
+// GT creates a predicate that is true when the expression is greater than the
+// value
+func (x Expr) GT(v interface{}) Pred {
+	return x.compare(">", v, func(c int) bool { return c > 0 })
+}
+
+// GE creates a predicate that is true when the expression is greater than or
+// equal to the value
+func (x Expr) GE(v interface{}) Pred {
+	return x.compare(">=", v, func(c int) bool { return c >= 0 })
+}
+
+// In creates a predicate that is true when the expression is equal to any of
+// the values.
+func (x Expr) In(v1 interface{}, vs ...interface{}) Pred {
+	vals := append([]interface{}{v1}, vs...)
+	cp := clientPred{rel.Attribute(x.att), fmt.Sprintf("%v IN %v", x, vals), func(v2 interface{}) bool {
+		e := x.apply(v2)
+		for _, v := range vals {
+			if c, ok := compareValues(e, v); ok && c == 0 {
+				return true
+			}
+		}
+		return false
+	}}
+	return Pred{cp, "IN", rel.Attribute(x.att), exprArg{x, vals}, nil}
+}
+
+// WithIndexWarnings makes restrictions on expressions, like LOWER(Email) = ?,
+// log a warning with the relation's logger, because the database can't use
+// an index on the attribute for them.  indexed are the expressions which do
+// have an index on the expression, which are not warned about.
+func WithIndexWarnings(indexed ...Expr) Option {
+	return func(o *options) {
+		o.indexWarnings = true
+		o.exprIndexes = indexed
+	}
+}
+
+// warnExprs logs a warning for each expression in the predicate which is not
+// one of the indexed expressions.
+func (r1 *sqlTable) warnExprs(p Pred) {
+	if !r1.opts.indexWarnings || !r1.opts.logging() {
+		return
+	}
+	for _, p2 := range p.conjuncts() {
+		if p2.preds != nil {
+			// a disjunction, which can only use indexes on every one of its
+			// operands
+			for _, p3 := range p2.preds {
+				r1.warnExprs(p3)
+			}
+			continue
+		}
+		ea, ok := p2.val.(exprArg)
+		if !ok || containsExpr(r1.opts.exprIndexes, ea.x) {
+			continue
+		}
+		hint := "an index on the expression"
+		if ea.x.fn == "DATE" {
+			hint = "a range on " + ea.x.att
+		}
+		r1.opts.warnf("relsql: restriction of %v on %v can't use an index on %s, consider %s", r1, ea.x, ea.x.att, hint)
+	}
+}
+
+// containsExpr returns true if x is one of the expressions
+func containsExpr(xs []Expr, x Expr) bool {
+	for _, x2 := range xs {
+		if x2 == x {
+			return true
+		}
+	}
+	return false
+}
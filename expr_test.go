@@ -0,0 +1,190 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test joining on computed expressions in the database and client side
+func TestJoinExpr(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:joinexpr?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type userTup struct {
+		UserID int
+		Email  string
+	}
+	type signupTup struct {
+		SignupID int
+		Address  string
+	}
+	type matchTup struct {
+		UserID   int
+		SignupID int
+	}
+	users := []userTup{{1, "Ann@Example.com"}, {2, "bob@example.com"}}
+	signups := []signupTup{{10, "ann@example.com"}, {11, "BOB@EXAMPLE.COM"}, {12, "cy@example.com"}}
+	if err := CreateTable(db, "users", userTup{}, [][]string{[]string{"UserID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if err := CreateTable(db, "signups", signupTup{}, [][]string{[]string{"SignupID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "users", rel.New(users, [][]string{[]string{"UserID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "signups", rel.New(signups, [][]string{[]string{"SignupID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	userTable := New(db, "users", userTup{}, [][]string{[]string{"UserID"}}, WithDialect(SQLite))
+	signupTable := New(db, "signups", signupTup{}, [][]string{[]string{"SignupID"}}, WithDialect(SQLite))
+	userMem := rel.New(users, [][]string{[]string{"UserID"}})
+
+	var joinExprTest = []struct {
+		name string
+		r    rel.Relation
+	}{
+		{"table", JoinExpr(userTable, signupTable, matchTup{}, Lower("Email"), Lower("Address"))},
+		{"memory", JoinExpr(userMem, signupTable, matchTup{}, Lower("Email"), Lower("Address"))},
+	}
+	for i, tt := range joinExprTest {
+		if _, ok := tt.r.(*sqlTable); ok != (tt.name == "table") {
+			t.Errorf("%d has JoinExpr() => %T", i, tt.r)
+		}
+		ch := make(chan matchTup)
+		tt.r.TupleChan(ch)
+		var res []matchTup
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		sort.Slice(res, func(i, j int) bool { return res[i].UserID < res[j].UserID })
+		if err := tt.r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+		if fmt.Sprint(res) != "[{1 10} {2 11}]" {
+			t.Errorf("%d %s has tuples %v, want [{1 10} {2 11}]", i, tt.name, res)
+		}
+	}
+
+	// the expressions are in the select lists, and the join is on them
+	q, _, err := SQL(joinExprTest[0].r)
+	if err != nil || !strings.Contains(q, "LOWER(Email) AS RelsqlJoinKey") || !strings.Contains(q, "ON t1.RelsqlJoinKey = t2.RelsqlJoinKey") {
+		t.Errorf("SQL() => %q, %v", q, err)
+	}
+
+	for i, r := range []rel.Relation{
+		JoinExpr(userTable, signupTable, matchTup{}, Lower("Name"), Lower("Address")),
+		JoinExpr(userTable, signupTable, matchTup{}, Lower("Email"), DateOf("Address")),
+		JoinExpr(userTable, signupTable, matchTup{}, Lower("UserID"), Lower("Address")),
+		Compute(userTable, "Email", Lower("Email")),
+		Compute(userTable, "email", Lower("Email")),
+	} {
+		if r.Err() == nil {
+			t.Errorf("%d has Err() => nil, want an error", i)
+		}
+	}
+}
+
+// test restricting on expressions of attributes in the database and client
+// side, and the warnings about expressions that can't use indexes
+func TestRestrictExpr(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:restrictexpr?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type userTup struct {
+		UserID int
+		Email  string
+		Joined time.Time
+	}
+	keys := [][]string{[]string{"UserID"}}
+	day := func(d, hour int) time.Time {
+		return time.Date(2024, 3, d, hour, 0, 0, 0, time.UTC)
+	}
+	users := []userTup{{1, "Ann@Example.com", day(1, 9)}, {2, "bob@example.com", day(1, 23)}, {3, "cy@example.com", day(2, 0)}}
+	if err := CreateTable(db, "users", userTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "users", rel.New(users, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	var warnings []string
+	logf := func(format string, args ...interface{}) {
+		if w := fmt.Sprintf(format, args...); strings.Contains(w, "can't use an index") {
+			warnings = append(warnings, w)
+		}
+	}
+	table := New(db, "users", userTup{}, keys, WithDialect(SQLite), WithLogger(logf), WithIndexWarnings(Lower("Email")))
+	mem := rel.New(users, keys)
+
+	var restrictExprTest = []struct {
+		r    rel.Relation
+		p    Pred
+		sql  string
+		want string
+	}{
+		{table, Lower("Email").EQ("ann@example.com"), "WHERE LOWER(Email) = ?", "[1]"},
+		{mem, Lower("Email").EQ("ann@example.com"), "", "[1]"},
+		{table, Upper("Email").In("ANN@EXAMPLE.COM", "CY@EXAMPLE.COM"), "WHERE UPPER(Email) IN (?, ?)", "[1 3]"},
+		{table, Trim("Email").GE("bob@example.com"), "WHERE TRIM(Email) >= ?", "[2 3]"},
+		{table, Lower("Email").NE("bob@example.com"), "WHERE LOWER(Email) <> ?", "[1 3]"},
+		// sqlite can't truncate times, so the date is compared client side
+		{table, DateOf("Joined").EQ(day(1, 0)), "", "[1 2]"},
+		{mem, DateOf("Joined").GT(day(1, 0)), "", "[3]"},
+	}
+	for i, tt := range restrictExprTest {
+		r := tt.r.Restrict(tt.p)
+		if q, _, err := SQL(r); tt.sql != "" && (err != nil || !strings.Contains(q, tt.sql)) {
+			t.Errorf("%d has SQL() => %q, %v, want %q", i, q, err, tt.sql)
+		}
+		ch := make(chan userTup)
+		r.TupleChan(ch)
+		var ids []int
+		for tup := range ch {
+			ids = append(ids, tup.UserID)
+		}
+		sort.Ints(ids)
+		if err := r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+		if fmt.Sprint(ids) != tt.want {
+			t.Errorf("%d has tuples %v, want %s", i, ids, tt.want)
+		}
+	}
+
+	// Lower(Email) is indexed, and DateOf(Joined) isn't pushed down
+	want := []string{"UPPER(Email)", "TRIM(Email)"}
+	if len(warnings) != len(want) {
+		t.Errorf("warnings => %q, want %d", warnings, len(want))
+		return
+	}
+	for i, w := range warnings {
+		if !strings.Contains(w, want[i]) {
+			t.Errorf("warning %d => %q, want %s", i, w, want[i])
+		}
+	}
+
+	// the expressions are compiled for dialects that truncate times
+	q, _, err := SQL(New(db, "users", userTup{}, keys, WithDialect(ANSI)).Restrict(DateOf("Joined").EQ(day(1, 0))))
+	if err != nil || !strings.Contains(q, "DATE_TRUNC('day', Joined) = ?") {
+		t.Errorf("SQL() => %q, %v", q, err)
+	}
+}
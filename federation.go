@@ -0,0 +1,225 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+)
+
+// DefaultSemiJoinLimit is the largest number of distinct join key values
+// that are sent to a database to reduce the other side of a join, unless
+// the relation was created with WithSemiJoinLimit.
+const DefaultSemiJoinLimit = 1000
+
+// WithSemiJoinLimit sets the largest number of distinct join key values that
+// are sent to the relation's database when it is joined with a relation from
+// another database or engine.  A negative limit disables the reduction, so
+// that joins across databases always read both sides in full.
+func WithSemiJoinLimit(n int) Option {
+	return func(o *options) {
+		o.semiJoinLimit = n
+	}
+}
+
+// semiJoinLimitOrDefault returns the semi join limit of the options
+func (o *options) semiJoinLimitOrDefault() int {
+	if o.semiJoinLimit == 0 {
+		return DefaultSemiJoinLimit
+	}
+	return o.semiJoinLimit
+}
+
+// semiJoin is the join of a relation from New with a relation that can't be
+// combined with it into one query, because it is on another database or
+// isn't from this package at all.  When it is enumerated, the other relation
+// is read first, and if it has few enough distinct values of the join
+// attributes, they are sent to the database as a restriction, so that only
+// the matching rows are read.  The join itself is done client side.
+type semiJoin struct {
+	r1 *sqlTable
+	r2 rel.Relation
+	on []rel.Attribute
+
+	// local is the client side join, which is used for the attributes of
+	// the result, and when the join can't be reduced
+	local rel.Relation
+
+	err error
+}
+
+// newSemiJoin creates the join of r1 with a relation on another database
+func newSemiJoin(r1 *sqlTable, r2 rel.Relation, zero interface{}) rel.Relation {
+	e2 := reflect.TypeOf(r2.Zero())
+	var on []rel.Attribute
+	for _, att := range rel.FieldNames(reflect.TypeOf(r1.zero)) {
+		if _, ok := e2.FieldByName(string(att)); ok {
+			on = append(on, att)
+		}
+	}
+	local := rel.NewJoin(r1, r2, zero)
+	if len(on) == 0 || r1.opts.semiJoinLimitOrDefault() < 0 {
+		return local
+	}
+	return &semiJoin{r1: r1, r2: r2, on: on, local: local}
+}
+
+// reduce reads r2 into memory, and returns r1 restricted to the values of
+// the join attributes in it, along with the in memory copy of r2.  If there
+// are too many values, r1 is returned unrestricted.
+func (r *semiJoin) reduce() (rel.Relation, rel.Relation, error) {
+	e2 := reflect.TypeOf(r.r2.Zero())
+	body := reflect.MakeSlice(reflect.SliceOf(e2), 0, 0)
+	seen := make(map[string]bool)
+	var preds []Pred
+	limit := r.r1.opts.semiJoinLimitOrDefault()
+	err := forEach(r.r2, func(tup reflect.Value) error {
+		body = reflect.Append(body, tup)
+		eqs := make([]Pred, len(r.on))
+		vals := make([]interface{}, len(r.on))
+		for i, att := range r.on {
+			vals[i] = tup.FieldByName(string(att)).Interface()
+			eqs[i] = Attribute(att).EQ(vals[i])
+		}
+		k := fmt.Sprintf("%#v", vals)
+		if !seen[k] {
+			seen[k] = true
+			if len(preds) <= limit {
+				preds = append(preds, And(eqs[0], eqs[1:]...))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	mem := rel.New(body.Interface(), keyStrings(r.r2.CKeys()))
+	switch {
+	case len(preds) == 0:
+		return nil, mem, nil
+	case len(preds) > limit:
+		return r.r1, mem, nil
+	case len(preds) == 1:
+		return r.r1.Restrict(preds[0]), mem, nil
+	case len(r.on) == 1:
+		vals := make([]interface{}, len(preds))
+		for i, p := range preds {
+			vals[i] = p.val
+		}
+		return r.r1.Restrict(Attribute(r.on[0]).In(vals[0], vals[1:]...)), mem, nil
+	}
+	return r.r1.Restrict(Or(preds[0], preds[1:]...)), mem, nil
+}
+
+// TupleChan sends the tuples of the join on the channel
+func (r *semiJoin) TupleChan(t interface{}) chan<- struct{} {
+	cancel := make(chan struct{})
+	chv := reflect.ValueOf(t)
+	if err := rel.EnsureChan(chv.Type(), r.local.Zero()); err != nil {
+		r.err = err
+		return cancel
+	}
+	go func() {
+		r1, mem, err := r.reduce()
+		if err != nil {
+			r.err = err
+			chv.Close()
+			return
+		}
+		if r1 == nil {
+			// there are no join values, so the join is empty
+			chv.Close()
+			return
+		}
+		j := rel.NewJoin(r1, mem, r.local.Zero())
+		ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, reflect.TypeOf(r.local.Zero())), 0)
+		jcancel := j.TupleChan(ch.Interface())
+		canSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cancel)}
+		for {
+			tup, ok := ch.Recv()
+			if !ok {
+				break
+			}
+			resSel := reflect.SelectCase{Dir: reflect.SelectSend, Chan: chv, Send: tup}
+			if chosen, _, _ := reflect.Select([]reflect.SelectCase{canSel, resSel}); chosen == 0 {
+				close(jcancel)
+				return
+			}
+		}
+		if err := j.Err(); err != nil {
+			r.err = err
+		}
+		chv.Close()
+	}()
+	return cancel
+}
+
+// Zero returns the zero value of the join's tuples
+func (r *semiJoin) Zero() interface{} {
+	return r.local.Zero()
+}
+
+// CKeys returns the candidate keys of the join
+func (r *semiJoin) CKeys() rel.CandKeys {
+	return r.local.CKeys()
+}
+
+// GoString returns a text representation of the join
+func (r *semiJoin) GoString() string {
+	return fmt.Sprintf("relsql.semiJoin{%#v, %#v, %v}", r.r1, r.r2, r.on)
+}
+
+// String returns a text representation of the join
+func (r *semiJoin) String() string {
+	return r.local.String()
+}
+
+// Project is evaluated client side
+func (r *semiJoin) Project(z2 interface{}) rel.Relation {
+	return rel.NewProject(r, z2)
+}
+
+// Restrict is evaluated client side
+func (r *semiJoin) Restrict(p rel.Predicate) rel.Relation {
+	return rel.NewRestrict(r, p)
+}
+
+// Rename is evaluated client side
+func (r *semiJoin) Rename(z2 interface{}) rel.Relation {
+	return rel.NewRename(r, z2)
+}
+
+// Union is evaluated client side
+func (r *semiJoin) Union(r2 rel.Relation) rel.Relation {
+	return rel.NewUnion(r, r2)
+}
+
+// Diff is evaluated client side
+func (r *semiJoin) Diff(r2 rel.Relation) rel.Relation {
+	return rel.NewDiff(r, r2)
+}
+
+// Join is evaluated client side
+func (r *semiJoin) Join(r2 rel.Relation, zero interface{}) rel.Relation {
+	return rel.NewJoin(r, r2, zero)
+}
+
+// GroupBy is evaluated client side
+func (r *semiJoin) GroupBy(t2, gfcn interface{}) rel.Relation {
+	return rel.NewGroupBy(r, t2, gfcn)
+}
+
+// Map is evaluated client side
+func (r *semiJoin) Map(mfcn interface{}, ckeystr [][]string) rel.Relation {
+	return rel.NewMap(r, mfcn, ckeystr)
+}
+
+// Err returns the first error of the join or its inputs
+func (r *semiJoin) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.r1.Err(); err != nil {
+		return err
+	}
+	return r.r2.Err()
+}
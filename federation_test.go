@@ -0,0 +1,125 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// test joins of relations from different databases and engines
+func TestFederatedJoin(t *testing.T) {
+	salesDB, err := sql.Open("sqlite3", "file:fedsales?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer salesDB.Close()
+	regionDB, err := sql.Open("sqlite3", "file:fedregions?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer regionDB.Close()
+
+	type saleTup struct {
+		ID     int
+		Store  string
+		Amount int
+	}
+	type storeTup struct {
+		Store  string
+		Region string
+	}
+	type saleRegionTup struct {
+		ID     int
+		Store  string
+		Amount int
+		Region string
+	}
+	if err := CreateTable(salesDB, "sales", saleTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	var sales []saleTup
+	for i := 0; i < 12; i++ {
+		sales = append(sales, saleTup{i, fmt.Sprintf("s%d", i%4), 10 * i})
+	}
+	if _, err := Insert(salesDB, "sales", rel.New(sales, [][]string{[]string{"ID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	stores := []storeTup{{"s1", "north"}, {"s3", "south"}}
+	if err := CreateTable(regionDB, "stores", storeTup{}, [][]string{[]string{"Store"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(regionDB, "stores", rel.New(stores, [][]string{[]string{"Store"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	ids := func(r rel.Relation) []int {
+		ch := make(chan saleRegionTup)
+		r.TupleChan(ch)
+		var res []int
+		for tup := range ch {
+			res = append(res, tup.ID)
+		}
+		sort.Ints(res)
+		return res
+	}
+
+	var joinTest = []struct {
+		r1    *sqlTable
+		r2    rel.Relation
+		semi  bool
+		ids   string
+		where string
+	}{
+		// another database
+		{New(salesDB, "sales", saleTup{}, [][]string{[]string{"ID"}}).(*sqlTable),
+			New(regionDB, "stores", storeTup{}, [][]string{[]string{"Store"}}), true, "[1 3 5 7 9 11]", "Store IN (?, ?)"},
+		// another engine
+		{New(salesDB, "sales", saleTup{}, [][]string{[]string{"ID"}}).(*sqlTable),
+			rel.New(stores[:1], [][]string{[]string{"Store"}}), true, "[1 5 9]", "Store = ?"},
+		// too many join values to reduce the join
+		{New(salesDB, "sales", saleTup{}, [][]string{[]string{"ID"}}, WithSemiJoinLimit(1)).(*sqlTable),
+			rel.New(stores, [][]string{[]string{"Store"}}), true, "[1 3 5 7 9 11]", ""},
+		// disabled
+		{New(salesDB, "sales", saleTup{}, [][]string{[]string{"ID"}}, WithSemiJoinLimit(-1)).(*sqlTable),
+			rel.New(stores, [][]string{[]string{"Store"}}), false, "[1 3 5 7 9 11]", ""},
+		// no join values
+		{New(salesDB, "sales", saleTup{}, [][]string{[]string{"ID"}}).(*sqlTable),
+			rel.New([]storeTup{}, [][]string{[]string{"Store"}}), true, "[]", ""},
+	}
+	for i, tt := range joinTest {
+		j := tt.r1.Join(tt.r2, saleRegionTup{})
+		sj, ok := j.(*semiJoin)
+		if ok != tt.semi {
+			t.Errorf("%d has semi join => %v, want %v", i, ok, tt.semi)
+			continue
+		}
+		if ok {
+			r1, _, err := sj.reduce()
+			if err != nil {
+				t.Errorf("%d has reduce() => %v", i, err)
+			}
+			where := ""
+			if rt, ok := r1.(*sqlTable); ok && rt != tt.r1 {
+				var b builder
+				where = whereString(&b, rt.where)
+			}
+			if where != tt.where {
+				t.Errorf("%d has reduced where => %q, want %q", i, where, tt.where)
+			}
+		}
+		if res := ids(j); fmt.Sprint(res) != tt.ids {
+			t.Errorf("%d has ids => %v, want %v", i, res, tt.ids)
+		}
+		if err := j.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+	}
+}
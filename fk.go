@@ -0,0 +1,169 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sort"
+)
+
+// ForeignKey is a foreign key of a table, which refers from its Columns to
+// the RefColumns of RefTable, in the same order.
+type ForeignKey struct {
+	Columns    []string
+	RefTable   string
+	RefColumns []string
+}
+
+// foreignKeyer is implemented by dialects that can find the foreign keys of a
+// table in the database's catalog.
+type foreignKeyer interface {
+	ForeignKeys(db *sql.DB, tableName string) ([]ForeignKey, error)
+}
+
+// WithForeignKeys declares the foreign keys of the relation's table.  They
+// are used to check the attributes that natural joins match on, see Join.
+// NewSQLite finds them in the catalog instead.
+func WithForeignKeys(fks ...ForeignKey) Option {
+	return func(o *options) {
+		o.foreignKeys = fks
+	}
+}
+
+// ForeignKeys returns the foreign keys of a table, if the dialect can find
+// them in the database's catalog.
+func ForeignKeys(db *sql.DB, d Dialect, tableName string) ([]ForeignKey, error) {
+	fk, ok := d.(foreignKeyer)
+	if !ok {
+		return nil, fmt.Errorf("relsql: dialect %s can't find foreign keys", d.Name())
+	}
+	return fk.ForeignKeys(db, tableName)
+}
+
+// JoinOn creates the natural join of two relations, like r1.Join(r2, zero),
+// after checking that the attributes that the relations have in common are
+// exactly the attributes in on.  This catches joins that would also match on
+// attributes which only have the same name by coincidence, or that would be
+// cross joins because none of the attributes are shared.
+func JoinOn(r1, r2 rel.Relation, zero interface{}, on ...string) rel.Relation {
+	common := commonAttributes(reflect.TypeOf(r1.Zero()), reflect.TypeOf(r2.Zero()))
+	want := make([]rel.Attribute, len(on))
+	for i, att := range on {
+		want[i] = rel.Attribute(att)
+	}
+	sort.Sort(attributeSlice(common))
+	sort.Sort(attributeSlice(want))
+	if fmt.Sprint(common) != fmt.Sprint(want) {
+		return &sqlTable{
+			zero:  zero,
+			cKeys: rel.DefaultKeys(zero),
+			err:   fmt.Errorf("relsql: join of %v and %v would match on %v, not %v", r1, r2, common, want),
+		}
+	}
+	return r1.Join(r2, zero)
+}
+
+// attributeSlice sorts attributes by name
+type attributeSlice []rel.Attribute
+
+func (s attributeSlice) Len() int           { return len(s) }
+func (s attributeSlice) Less(i, j int) bool { return s[i] < s[j] }
+func (s attributeSlice) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+
+// commonAttributes returns the attributes of e1 which are also in e2
+func commonAttributes(e1, e2 reflect.Type) []rel.Attribute {
+	var on []rel.Attribute
+	for _, att := range rel.FieldNames(e1) {
+		if _, ok := e2.FieldByName(string(att)); ok {
+			on = append(on, att)
+		}
+	}
+	return on
+}
+
+// joinBacked returns true if a join of r1 with r2 on the attributes matches
+// on a candidate key of either relation, or on a foreign key of one of their
+// tables which refers to the other.
+func (r1 *sqlTable) joinBacked(r2 *sqlTable, on []rel.Attribute) bool {
+	set := make(map[rel.Attribute]bool)
+	for _, att := range on {
+		set[att] = true
+	}
+	for _, cKeys := range []rel.CandKeys{r1.cKeys, r2.cKeys} {
+		for _, ck := range cKeys {
+			if coversAttributes(set, ck) {
+				return true
+			}
+		}
+	}
+	return r1.refersTo(r2, set) || r2.refersTo(r1, set)
+}
+
+// coversAttributes returns true if every one of the attributes is in set
+func coversAttributes(set map[rel.Attribute]bool, atts []rel.Attribute) bool {
+	for _, att := range atts {
+		if !set[att] {
+			return false
+		}
+	}
+	return true
+}
+
+// refersTo returns true if one of the foreign keys of r1's table refers to
+// r2's table, and its columns produce exactly the attributes in set on both
+// sides.
+func (r1 *sqlTable) refersTo(r2 *sqlTable, set map[rel.Attribute]bool) bool {
+	t2, ok := r2.src.(tableSource)
+	if _, ok1 := r1.src.(tableSource); !ok || !ok1 {
+		return false
+	}
+	for _, fk := range r1.opts.foreignKeys {
+		if fk.RefTable != string(t2) || len(fk.Columns) != len(set) {
+			continue
+		}
+		match := true
+		for i := range fk.Columns {
+			a1, ok1 := r1.attributeOf(fk.Columns[i])
+			a2, ok2 := r2.attributeOf(fk.RefColumns[i])
+			if !ok1 || !ok2 || a1 != a2 || !set[a1] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// attributeOf returns the attribute produced by a column of the relation's
+// table
+func (r1 *sqlTable) attributeOf(name string) (rel.Attribute, bool) {
+	e := reflect.TypeOf(r1.zero)
+	for i, c := range r1.cols {
+		if c.table == "" && c.name == name {
+			return rel.Attribute(e.Field(i).Name), true
+		}
+	}
+	return "", false
+}
+
+// SQLiteForeignKeys returns the foreign keys of a sqlite table
+func SQLiteForeignKeys(db *sql.DB, tableName string) ([]ForeignKey, error) {
+	var fks []ForeignKey
+	ids := make(map[int]int)
+	err := pragma(db, "foreign_key_list", tableName, func(row map[string]interface{}) {
+		id := asInt(row["id"])
+		i, ok := ids[id]
+		if !ok {
+			i = len(fks)
+			ids[id] = i
+			fks = append(fks, ForeignKey{RefTable: asString(row["table"])})
+		}
+		fks[i].Columns = append(fks[i].Columns, asString(row["from"]))
+		fks[i].RefColumns = append(fks[i].RefColumns, asString(row["to"]))
+	})
+	return fks, err
+}
@@ -0,0 +1,113 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"testing"
+)
+
+// test finding foreign keys, and checking the attributes of joins
+func TestForeignKeys(t *testing.T) {
+	dsn := "file:fk?mode=memory&cache=shared"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	for _, stmt := range []string{
+		"CREATE TABLE suppliers (SNO INTEGER PRIMARY KEY, Name TEXT, City TEXT)",
+		"CREATE TABLE parts (PNO INTEGER PRIMARY KEY, Name TEXT, City TEXT)",
+		"CREATE TABLE shipments (SNO INTEGER REFERENCES suppliers (SNO), PNO INTEGER REFERENCES parts (PNO), Qty INTEGER, PRIMARY KEY (SNO, PNO))",
+	} {
+		if _, err := db.Exec(stmt); err != nil {
+			t.Errorf("Exec() => %v", err)
+			return
+		}
+	}
+	fks, err := ForeignKeys(db, SQLite, "shipments")
+	if err != nil {
+		t.Errorf("ForeignKeys() => %v", err)
+		return
+	}
+	if got := fmt.Sprint(fks); got != "[{[PNO] parts [PNO]} {[SNO] suppliers [SNO]}]" {
+		t.Errorf("ForeignKeys() => %v", got)
+	}
+	if _, err := ForeignKeys(db, ANSI, "shipments"); err == nil {
+		t.Errorf("ForeignKeys() with ANSI => nil error")
+	}
+
+	type supplierTup struct {
+		SNO  int
+		Name string
+		City string
+	}
+	type partTup struct {
+		PNO  int
+		Name string
+		City string
+	}
+	type shipmentTup struct {
+		SNO int
+		PNO int
+		Qty int
+	}
+	type supplierShipmentTup struct {
+		SNO  int
+		Name string
+		City string
+		PNO  int
+		Qty  int
+	}
+	type supplierPartTup struct {
+		SNO  int
+		Name string
+		City string
+		PNO  int
+	}
+	var warnings []string
+	logf := func(format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf(format, args...))
+	}
+	suppliers := NewSQLite(db, dsn, "suppliers", supplierTup{}, WithLogger(logf))
+	parts := NewSQLite(db, dsn, "parts", partTup{}, WithLogger(logf))
+	shipments := NewSQLite(db, dsn, "shipments", shipmentTup{}, WithLogger(logf))
+
+	// a join along a foreign key doesn't warn
+	shipments.Join(suppliers, supplierShipmentTup{})
+	if len(warnings) != 0 {
+		t.Errorf("Join() along a foreign key => warnings %v", warnings)
+	}
+
+	// without declared keys, the foreign key backs the join
+	New(db, "shipments", shipmentTup{}, nil, WithForeignKeys(fks...), WithLogger(logf)).Join(New(db, "suppliers", supplierTup{}, nil), supplierShipmentTup{})
+	if len(warnings) != 0 {
+		t.Errorf("Join() along a declared foreign key => warnings %v", warnings)
+	}
+	New(db, "shipments", shipmentTup{}, nil, WithLogger(logf)).Join(New(db, "suppliers", supplierTup{}, nil), supplierShipmentTup{})
+	if len(warnings) != 1 {
+		t.Errorf("Join() without keys => warnings %v, want 1", warnings)
+	}
+	warnings = nil
+
+	// suppliers and parts only share Name and City by coincidence
+	suppliers.Join(parts, supplierPartTup{})
+	if len(warnings) != 1 {
+		t.Errorf("Join() on coincidental attributes => warnings %v, want 1", warnings)
+	}
+
+	var joinOnTest = []struct {
+		on   []string
+		fail bool
+	}{
+		{[]string{"SNO"}, false},
+		{[]string{"SNO", "Name"}, true},
+		{nil, true},
+	}
+	for i, tt := range joinOnTest {
+		r := JoinOn(shipments, suppliers, supplierShipmentTup{}, tt.on...)
+		if err := r.Err(); (err != nil) != tt.fail {
+			t.Errorf("%d has JoinOn(%v) error %v, want failure %v", i, tt.on, err, tt.fail)
+		}
+	}
+}
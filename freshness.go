@@ -0,0 +1,168 @@
+package relsql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"time"
+)
+
+// freshness is the probe of when a table was last modified.  Either column is
+// the name of a timestamp column whose maximum is the time, or query is a
+// query that returns the time.
+type freshness struct {
+	column string
+	query  string
+}
+
+// WithFreshness sets the timestamp column, such as updated_at, whose maximum
+// value is the time that the relation's table was last modified.
+func WithFreshness(column string) Option {
+	return func(o *options) {
+		o.freshness = freshness{column: column}
+	}
+}
+
+// WithFreshnessProbe sets a query that returns the time that the relation's
+// table was last modified, as a single value, for tables whose modification
+// time is tracked elsewhere, like in a load log.
+func WithFreshnessProbe(query string) Option {
+	return func(o *options) {
+		o.freshness = freshness{query: query}
+	}
+}
+
+// timeLayouts are the text forms of timestamps that drivers return from
+// aggregates, which lose the type of the column, and that are in the text
+// form of records.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// LastModified returns the time that the sources of a relation were last
+// modified, which is the latest time returned by the freshness probes of the
+// tables it reads from.  Caches can compare it with the time of a previous
+// evaluation to skip evaluating the relation again.  It returns the zero time
+// if the tables are empty, and an error if any of the tables has no probe, or
+// if the relation isn't from this package.
+func LastModified(ctx context.Context, r rel.Relation) (time.Time, error) {
+	var last time.Time
+	var err error
+	Inspect(r, func(n Node) bool {
+		if err != nil {
+			return false
+		}
+		switch n := n.(type) {
+		case *Query:
+			r1 := n.Relation.(*sqlTable)
+			switch src := n.Source.(type) {
+			case *Table:
+				err = r1.probe(ctx, []*Table{src}, &last)
+			case *Partitions:
+				err = r1.probe(ctx, src.Tables, &last)
+			case *Call, *Script:
+				err = r1.probe(ctx, nil, &last)
+			}
+		case *Other:
+			err = fmt.Errorf("relsql: %v is not from relsql, so its modification time is unknown", n.Relation)
+		}
+		return err == nil
+	})
+	return last, err
+}
+
+// probe runs the relation's freshness probe on the tables, and updates last
+// if any of them was modified after it.  A timestamp column is only read from
+// the rows that the relation's mandatory predicates allow, so that the probe
+// doesn't reveal when rows that the relation can't see were modified.  A
+// probe query can't be restricted, so it isn't run for a relation with
+// mandatory predicates.
+func (r1 *sqlTable) probe(ctx context.Context, tables []*Table, last *time.Time) error {
+	f := r1.opts.freshness
+	b := builder{dialect: r1.dialect()}
+	var queries []string
+	switch {
+	case f.query != "" && len(r1.mandatory) > 0:
+		return fmt.Errorf("relsql: the freshness probe of %v can't apply its mandatory predicates", r1)
+	case f.query != "":
+		queries = []string{f.query}
+	case f.column != "" && len(tables) > 0:
+		var where string
+		if len(r1.mandatory) > 0 {
+			where = " WHERE " + whereString(&b, r1.mandatory)
+		}
+		for _, t := range tables {
+			queries = append(queries, "SELECT MAX("+f.column+") FROM "+quoteTable(r1.dialect(), t.Name)+where)
+		}
+	default:
+		return fmt.Errorf("relsql: %v has no freshness probe", r1)
+	}
+	if b.err != nil {
+		return b.err
+	}
+	for _, q := range queries {
+		q, args, err := r1.applyPolicies(q, b.args)
+		if err != nil {
+			return err
+		}
+		var v interface{}
+		if err := r1.opts.queryRow(ctx, r1.queryer(), q, bindArgs(r1.dialect(), args), &v); err != nil {
+			return err
+		}
+		t, err := timeValue(v)
+		if err != nil {
+			return err
+		}
+		if t.After(*last) {
+			*last = t
+		}
+	}
+	return nil
+}
+
+// timeValue converts the result of a freshness probe to a time.  Integers are
+// seconds since the unix epoch, and NULL is the zero time.
+func timeValue(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case nil:
+		return time.Time{}, nil
+	case time.Time:
+		return v, nil
+	case int64:
+		return time.Unix(v, 0), nil
+	case []byte:
+		return timeValue(string(v))
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t, nil
+			}
+		}
+	}
+	return time.Time{}, fmt.Errorf("relsql: freshness probe returned %v, which is not a time", v)
+}
+
+// ETag returns a weak entity tag for the current contents of a relation,
+// which is derived from the relation's expression and the time its sources
+// were last modified.  It changes whenever either of them does, so an HTTP
+// handler serving the relation, like Handler, can answer a request whose
+// If-None-Match header holds the tag with 304 Not Modified instead of
+// evaluating it.
+func ETag(ctx context.Context, r rel.Relation) (string, error) {
+	last, err := LastModified(ctx, r)
+	if err != nil {
+		return "", err
+	}
+	expr := r.String()
+	if q, args, err := SQL(r); err == nil {
+		expr = q + argsHash(args)
+	}
+	h := sha256.Sum256([]byte(expr + "@" + last.UTC().Format(time.RFC3339Nano)))
+	return `W/"` + hex.EncodeToString(h[:8]) + `"`, nil
+}
@@ -0,0 +1,179 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"testing"
+	"time"
+)
+
+// test finding when the sources of relations were last modified
+func TestLastModified(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:freshness?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type orderTup struct {
+		ID        int
+		UpdatedAt time.Time
+	}
+	type loadTup struct {
+		Name     string
+		LoadedAt int64
+	}
+	keys := [][]string{[]string{"ID"}}
+	t1 := time.Date(2014, 3, 1, 12, 0, 0, 0, time.UTC)
+	t2 := time.Date(2014, 3, 2, 12, 0, 0, 0, time.UTC)
+	t3 := time.Unix(1400000000, 0)
+	for _, name := range []string{"orders", "returns", "empty"} {
+		if err := CreateTable(db, name, orderTup{}, keys); err != nil {
+			t.Errorf("CreateTable() => %v", err)
+			return
+		}
+	}
+	if _, err := Insert(db, "orders", rel.New([]orderTup{{1, t1}, {2, t2}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if err := CreateTable(db, "loads", loadTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "loads", rel.New([]loadTup{{"returns", t3.Unix()}}, nil)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	orders := New(db, "orders", orderTup{}, keys, WithDialect(SQLite), WithFreshness("UpdatedAt"))
+	returns := New(db, "returns", orderTup{}, keys, WithDialect(SQLite), WithFreshnessProbe("SELECT MAX(LoadedAt) FROM loads WHERE Name = 'returns'"))
+	empty := New(db, "empty", orderTup{}, keys, WithDialect(SQLite), WithFreshness("UpdatedAt"))
+	unprobed := New(db, "orders", orderTup{}, keys, WithDialect(SQLite))
+
+	var lastModifiedTest = []struct {
+		r    rel.Relation
+		want time.Time
+		fail bool
+	}{
+		{orders, t2, false},
+		{orders.Restrict(Attribute("ID").EQ(1)), t2, false},
+		{orders.Union(returns), t3, false},
+		{empty, time.Time{}, false},
+		{unprobed, time.Time{}, true},
+		{rel.New([]orderTup{}, keys), time.Time{}, true},
+	}
+	for i, tt := range lastModifiedTest {
+		got, err := LastModified(context.Background(), tt.r)
+		if (err != nil) != tt.fail {
+			t.Errorf("%d has LastModified() error %v, want failure %v", i, err, tt.fail)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%d has LastModified() => %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+// test that freshness probes only read the rows that mandatory predicates
+// allow
+func TestLastModifiedMandatory(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:freshnessmandatory?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type tenantOrderTup struct {
+		ID        int
+		TenantID  int
+		UpdatedAt int64
+	}
+	type orderTup struct {
+		ID        int
+		UpdatedAt int64
+	}
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "orders", tenantOrderTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "orders", rel.New([]tenantOrderTup{{1, 1, 100}, {2, 2, 200}, {3, 1, 150}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	tenant := func(id int, opt Option) rel.Relation {
+		return New(db, "orders", orderTup{}, keys, WithDialect(SQLite), opt, WithMandatoryPredicate(Attribute("TenantID").EQ(id)))
+	}
+
+	var mandatoryTest = []struct {
+		r    rel.Relation
+		want time.Time
+		fail bool
+	}{
+		{tenant(1, WithFreshness("UpdatedAt")), time.Unix(150, 0), false},
+		{tenant(2, WithFreshness("UpdatedAt")), time.Unix(200, 0), false},
+		{tenant(3, WithFreshness("UpdatedAt")), time.Time{}, false},
+		{tenant(1, WithFreshnessProbe("SELECT MAX(UpdatedAt) FROM orders")), time.Time{}, true},
+	}
+	for i, tt := range mandatoryTest {
+		got, err := LastModified(context.Background(), tt.r)
+		if (err != nil) != tt.fail {
+			t.Errorf("%d has LastModified() error %v, want failure %v", i, err, tt.fail)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("%d has LastModified() => %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+// test that entity tags change with the relation and its sources
+func TestETag(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:etag?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type orderTup struct {
+		ID        int
+		UpdatedAt time.Time
+	}
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "orders", orderTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	orders := New(db, "orders", orderTup{}, keys, WithDialect(SQLite), WithFreshness("UpdatedAt"))
+	ctx := context.Background()
+	tag := func(r rel.Relation) string {
+		s, err := ETag(ctx, r)
+		if err != nil {
+			t.Errorf("ETag() => %v", err)
+		}
+		return s
+	}
+
+	before := tag(orders)
+	if tag(orders) != before {
+		t.Errorf("ETag() changed without a modification")
+	}
+	if tag(orders.Restrict(Attribute("ID").EQ(1))) == before {
+		t.Errorf("ETag() of a restriction => the tag of the table")
+	}
+	if tag(orders.Restrict(Attribute("ID").EQ(1))) == tag(orders.Restrict(Attribute("ID").EQ(2))) {
+		t.Errorf("ETag() of restrictions with different values => the same tag")
+	}
+	if _, err := Insert(db, "orders", rel.New([]orderTup{{1, time.Now()}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if tag(orders) == before {
+		t.Errorf("ETag() unchanged after a modification")
+	}
+}
@@ -0,0 +1,310 @@
+package relsql
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Point is a location in a plane, such as a longitude X and latitude Y.
+type Point struct {
+	X, Y float64
+}
+
+// Polygon is an area bounded by a ring of points, which is closed by joining
+// the last point to the first.
+type Polygon []Point
+
+// Box is a rectangle, which is the bounding box of geometries in predicates.
+type Box struct {
+	Min, Max Point
+}
+
+var (
+	pointType   = reflect.TypeOf(Point{})
+	polygonType = reflect.TypeOf(Polygon(nil))
+)
+
+// spatialer is implemented by dialects with spatial functions, like PostGIS
+// or SpatiaLite.  Spatial returns the sql for a spatial predicate on the
+// geometry column col, where geom is the placeholder of the other geometry
+// in well known text, and dist is a distance, or an empty string if the
+// dialect can't compute the predicate.  The ops are ST_Within, for points in
+// a polygon, ST_DWithin, for points within a distance of a point, and &&, for
+// geometries whose bounding box intersects a box.  With PostGIS they are
+// written like ST_Within(col, ST_GeomFromText(geom)) and
+// col && ST_GeomFromText(geom), which use a spatial index on col.  Other
+// dialects store geometries as text, and spatial predicates are evaluated
+// client side.
+type spatialer interface {
+	Spatial(op, col, geom, dist string) string
+}
+
+// spatialArg is the right side of a spatial predicate
+type spatialArg struct {
+	geom interface{}
+	dist float64
+}
+
+// InPolygon creates a predicate that is true when the attribute, which is a
+// Point, is inside the polygon.
+func (att Attribute) InPolygon(p Polygon) Pred {
+	cp := clientPred{rel.Attribute(att), fmt.Sprintf("ST_Within(%s, %s)", att, p.wkt()), func(v interface{}) bool {
+		pt, ok := v.(Point)
+		return ok && p.contains(pt)
+	}}
+	return Pred{cp, "ST_Within", rel.Attribute(att), spatialArg{geom: p}, nil}
+}
+
+// DWithin creates a predicate that is true when the attribute, which is a
+// Point, is no further than dist from the point.  Distances are in the units
+// of the coordinates.
+func (att Attribute) DWithin(pt Point, dist float64) Pred {
+	cp := clientPred{rel.Attribute(att), fmt.Sprintf("ST_DWithin(%s, %s, %v)", att, pt.wkt(), dist), func(v interface{}) bool {
+		p2, ok := v.(Point)
+		return ok && math.Hypot(p2.X-pt.X, p2.Y-pt.Y) <= dist
+	}}
+	return Pred{cp, "ST_DWithin", rel.Attribute(att), spatialArg{pt, dist}, nil}
+}
+
+// Intersects creates a predicate that is true when the bounding box of the
+// attribute, which is a Point or a Polygon, intersects the box.
+func (att Attribute) Intersects(b Box) Pred {
+	cp := clientPred{rel.Attribute(att), fmt.Sprintf("%s && %s", att, b.polygon().wkt()), func(v interface{}) bool {
+		var b2 Box
+		switch g := v.(type) {
+		case Point:
+			b2 = Box{g, g}
+		case Polygon:
+			if len(g) == 0 {
+				return false
+			}
+			b2 = g.bounds()
+		default:
+			return false
+		}
+		return b2.Min.X <= b.Max.X && b.Min.X <= b2.Max.X && b2.Min.Y <= b.Max.Y && b.Min.Y <= b2.Max.Y
+	}}
+	return Pred{cp, "&&", rel.Attribute(att), spatialArg{geom: b.polygon()}, nil}
+}
+
+// buildSpatial returns the sql of a spatial predicate on the column
+func buildSpatial(b *builder, op, left string, a spatialArg) string {
+	geom := b.arg(a.geom)
+	dist := strconv.FormatFloat(a.dist, 'g', -1, 64)
+	if sp, ok := b.dialect.(spatialer); ok {
+		if str := sp.Spatial(op, left, geom, dist); str != "" {
+			return str
+		}
+	}
+	// only used to compare conditions
+	return op + "(" + left + ", " + geom + ", " + dist + ")"
+}
+
+// spatialPushable returns true if the dialect can compute the spatial
+// predicate
+func spatialPushable(d Dialect, op string) bool {
+	sp, ok := d.(spatialer)
+	return ok && sp.Spatial(op, "c", "g", "0") != ""
+}
+
+// wkt returns the well known text of the point
+func (pt Point) wkt() string {
+	return "POINT(" + pt.coords() + ")"
+}
+
+// coords returns the coordinates of the point in well known text
+func (pt Point) coords() string {
+	return strconv.FormatFloat(pt.X, 'g', -1, 64) + " " + strconv.FormatFloat(pt.Y, 'g', -1, 64)
+}
+
+// wkt returns the well known text of the polygon, with its ring closed
+func (p Polygon) wkt() string {
+	if len(p) == 0 {
+		return "POLYGON EMPTY"
+	}
+	strs := make([]string, 0, len(p)+1)
+	for _, pt := range p {
+		strs = append(strs, pt.coords())
+	}
+	if p[0] != p[len(p)-1] {
+		strs = append(strs, p[0].coords())
+	}
+	return "POLYGON((" + strings.Join(strs, ", ") + "))"
+}
+
+// contains returns true if the point is inside the polygon, by counting the
+// edges that a ray from the point crosses.
+func (p Polygon) contains(pt Point) bool {
+	in := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Y > pt.Y) != (b.Y > pt.Y) && pt.X < (b.X-a.X)*(pt.Y-a.Y)/(b.Y-a.Y)+a.X {
+			in = !in
+		}
+	}
+	return in
+}
+
+// bounds returns the bounding box of a polygon with at least one point
+func (p Polygon) bounds() Box {
+	b := Box{p[0], p[0]}
+	for _, pt := range p[1:] {
+		b.Min.X, b.Min.Y = math.Min(b.Min.X, pt.X), math.Min(b.Min.Y, pt.Y)
+		b.Max.X, b.Max.Y = math.Max(b.Max.X, pt.X), math.Max(b.Max.Y, pt.Y)
+	}
+	return b
+}
+
+// polygon returns the rectangle of the box
+func (b Box) polygon() Polygon {
+	return Polygon{b.Min, {b.Max.X, b.Min.Y}, b.Max, {b.Min.X, b.Max.Y}}
+}
+
+// geometryCodec converts points and polygons to well known text, and parses
+// them from well known text or from the binary forms that spatial databases
+// return, which may be hex encoded.
+type geometryCodec struct{}
+
+// Encode returns the well known text of a geometry
+func (geometryCodec) Encode(d Dialect, v interface{}) (interface{}, error) {
+	switch g := v.(type) {
+	case Point:
+		return g.wkt(), nil
+	case Polygon:
+		return g.wkt(), nil
+	}
+	return nil, fmt.Errorf("relsql: %T is not a geometry", v)
+}
+
+// Decode parses a geometry
+func (geometryCodec) Decode(d Dialect, src interface{}, dst reflect.Value) error {
+	var b []byte
+	switch v := src.(type) {
+	case string:
+		b = []byte(v)
+	case []byte:
+		b = v
+	default:
+		return decodeError(src, dst)
+	}
+	if s := strings.TrimSpace(string(b)); strings.HasPrefix(strings.ToUpper(s), "POINT") || strings.HasPrefix(strings.ToUpper(s), "POLYGON") {
+		return parseWKT(s, dst)
+	}
+	if h, err := hex.DecodeString(string(b)); err == nil {
+		b = h
+	}
+	return parseWKB(b, dst)
+}
+
+// TypeName returns GEOMETRY for spatial dialects, and TEXT otherwise
+func (geometryCodec) TypeName(d Dialect) string {
+	if _, ok := d.(spatialer); ok {
+		return "GEOMETRY"
+	}
+	return "TEXT"
+}
+
+// parseWKT parses the well known text of a point or a polygon with a single
+// ring into dst
+func parseWKT(s string, dst reflect.Value) error {
+	open, close := strings.IndexByte(s, '('), strings.LastIndexByte(s, ')')
+	if open < 0 || close < open {
+		return fmt.Errorf("relsql: invalid geometry %q", s)
+	}
+	kind := strings.ToUpper(strings.TrimSpace(s[:open]))
+	body := strings.Trim(s[open+1:close], "() ")
+	var pts []Point
+	for _, c := range strings.Split(body, ",") {
+		xy := strings.Fields(c)
+		if len(xy) != 2 {
+			return fmt.Errorf("relsql: invalid geometry %q", s)
+		}
+		x, err1 := strconv.ParseFloat(xy[0], 64)
+		y, err2 := strconv.ParseFloat(xy[1], 64)
+		if err1 != nil || err2 != nil {
+			return fmt.Errorf("relsql: invalid geometry %q", s)
+		}
+		pts = append(pts, Point{x, y})
+	}
+	return setGeometry(kind, pts, dst)
+}
+
+// setGeometry sets dst to a point or a polygon, whose closing point is
+// dropped
+func setGeometry(kind string, pts []Point, dst reflect.Value) error {
+	switch {
+	case kind == "POINT" && dst.Type() == pointType && len(pts) == 1:
+		dst.Set(reflect.ValueOf(pts[0]))
+	case kind == "POLYGON" && dst.Type() == polygonType:
+		if len(pts) > 1 && pts[0] == pts[len(pts)-1] {
+			pts = pts[:len(pts)-1]
+		}
+		dst.Set(reflect.ValueOf(Polygon(pts)))
+	default:
+		return fmt.Errorf("relsql: can't convert a %s to %v", strings.ToLower(kind), dst.Type())
+	}
+	return nil
+}
+
+// parseWKB parses the well known binary form, or its extended form with an
+// SRID, of a two dimensional point or a polygon with a single ring into dst
+func parseWKB(b []byte, dst reflect.Value) error {
+	invalid := fmt.Errorf("relsql: invalid geometry %x", b)
+	if len(b) < 5 {
+		return invalid
+	}
+	var order binary.ByteOrder = binary.LittleEndian
+	if b[0] == 0 {
+		order = binary.BigEndian
+	}
+	typ := order.Uint32(b[1:5])
+	b = b[5:]
+	if typ&0x20000000 != 0 {
+		// skip the SRID
+		if len(b) < 4 {
+			return invalid
+		}
+		b = b[4:]
+	}
+	if typ&0xC0000000 != 0 {
+		return fmt.Errorf("relsql: geometries with Z or M coordinates are not supported")
+	}
+	readPoints := func(n int) []Point {
+		if len(b) < 16*n {
+			return nil
+		}
+		pts := make([]Point, n)
+		for i := range pts {
+			pts[i] = Point{math.Float64frombits(order.Uint64(b[16*i:])), math.Float64frombits(order.Uint64(b[16*i+8:]))}
+		}
+		b = b[16*n:]
+		return pts
+	}
+	switch typ & 0xFFFF {
+	case 1:
+		if pts := readPoints(1); pts != nil {
+			return setGeometry("POINT", pts, dst)
+		}
+	case 3:
+		if len(b) < 8 || order.Uint32(b) != 1 {
+			return fmt.Errorf("relsql: only polygons with a single ring are supported")
+		}
+		n := int(order.Uint32(b[4:]))
+		b = b[8:]
+		if pts := readPoints(n); pts != nil {
+			return setGeometry("POLYGON", pts, dst)
+		}
+	}
+	return invalid
+}
+
+func init() {
+	RegisterCodec(pointType, geometryCodec{})
+	RegisterCodec(polygonType, geometryCodec{})
+}
@@ -0,0 +1,115 @@
+package relsql
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test geometry encoding and decoding
+func TestGeometryCodec(t *testing.T) {
+	// POINT(1 2) in little endian extended well known binary, with SRID 4326
+	ewkb, _ := hex.DecodeString("0101000020E6100000000000000000F03F0000000000000040")
+	var codecTest = []struct {
+		v        interface{}
+		encoded  string
+		scanned  interface{}
+		expected interface{}
+	}{
+		{Point{1, 2}, "POINT(1 2)", "POINT (1 2)", Point{1, 2}},
+		{Point{1, 2}, "POINT(1 2)", ewkb, Point{1, 2}},
+		{Point{1, 2}, "POINT(1 2)", "0101000020E6100000000000000000F03F0000000000000040", Point{1, 2}},
+		{Polygon{{0, 0}, {1, 0}, {0, 1}}, "POLYGON((0 0, 1 0, 0 1, 0 0))", []byte("POLYGON((0 0, 1 0, 0 1, 0 0))"), Polygon{{0, 0}, {1, 0}, {0, 1}}},
+	}
+	for i, tt := range codecTest {
+		c := lookupCodec(reflect.TypeOf(tt.v))
+		if c == nil {
+			t.Errorf("%d has no codec for %T", i, tt.v)
+			continue
+		}
+		if v, err := c.Encode(ANSI, tt.v); v != tt.encoded || err != nil {
+			t.Errorf("%d has Encode() => %v, %v, want %v", i, v, err, tt.encoded)
+		}
+		if typeName := c.TypeName(Postgres); typeName != "GEOMETRY" {
+			t.Errorf("%d has spatial TypeName() => %v, want GEOMETRY", i, typeName)
+		}
+		dst := reflect.New(reflect.TypeOf(tt.v)).Elem()
+		if err := c.Decode(ANSI, tt.scanned, dst); err != nil || !reflect.DeepEqual(dst.Interface(), tt.expected) {
+			t.Errorf("%d has Decode(%v) => %v, %v, want %v", i, tt.scanned, dst.Interface(), err, tt.expected)
+		}
+	}
+	dst := reflect.New(pointType).Elem()
+	if err := (geometryCodec{}).Decode(ANSI, "POLYGON((0 0, 1 0, 0 1, 0 0))", dst); err == nil {
+		t.Errorf("decoding a polygon into a point succeeded")
+	}
+}
+
+// test spatial predicates, pushed down and client side
+func TestSpatial(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:spatial?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type siteTup struct {
+		Name string
+		Loc  Point
+	}
+	if err := CreateTable(db, "sites", siteTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	sites := rel.New([]siteTup{
+		{"a", Point{0.5, 0.5}},
+		{"b", Point{3, 4}},
+		{"c", Point{-1, 2}},
+	}, [][]string{[]string{"Name"}})
+	if _, err := Insert(db, "sites", sites); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	square := Polygon{{0, 0}, {2, 0}, {2, 2}, {0, 2}}
+	var spatialTest = []struct {
+		p          Pred
+		postgis    string
+		spatialite string
+		arg        string
+		expected   []string
+	}{
+		{Attribute("Loc").InPolygon(square), "ST_Within(Loc, ST_GeomFromText($1))", "ST_Within(GeomFromText(Loc), GeomFromText(?))", "POLYGON((0 0, 2 0, 2 2, 0 2, 0 0))", []string{"a"}},
+		{Attribute("Loc").DWithin(Point{0, 0}, 5), "ST_DWithin(Loc, ST_GeomFromText($1), 5)", "ST_Distance(GeomFromText(Loc), GeomFromText(?)) <= 5", "POINT(0 0)", []string{"a", "b", "c"}},
+		{Attribute("Loc").DWithin(Point{0, 0}, 1), "ST_DWithin(Loc, ST_GeomFromText($1), 1)", "ST_Distance(GeomFromText(Loc), GeomFromText(?)) <= 1", "POINT(0 0)", []string{"a"}},
+		{Attribute("Loc").Intersects(Box{Point{-2, 1}, Point{0, 5}}), "Loc && ST_GeomFromText($1)", "MbrIntersects(GeomFromText(Loc), GeomFromText(?))", "POLYGON((-2 1, 0 1, 0 5, -2 5, -2 1))", []string{"c"}},
+	}
+	for i, tt := range spatialTest {
+		for _, native := range []struct {
+			d     Dialect
+			where string
+		}{{Postgres, tt.postgis}, {SpatiaLite, tt.spatialite}} {
+			r := New(db, "sites", siteTup{}, [][]string{[]string{"Name"}}, WithDialect(native.d)).Restrict(tt.p)
+			q, args, _ := r.(*sqlTable).queryString()
+			if want := "SELECT Name, Loc FROM sites WHERE " + native.where; q != want || len(args) != 1 || args[0] != tt.arg {
+				t.Errorf("%d has %s query => %v %v, want %v [%v]", i, native.d.Name(), q, args, want, tt.arg)
+			}
+		}
+
+		internal := New(db, "sites", siteTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite)).Restrict(tt.p)
+		if _, ok := internal.(*sqlTable); ok {
+			t.Errorf("%d was pushed down without spatial functions", i)
+		}
+		ch := make(chan siteTup)
+		internal.TupleChan(ch)
+		var res []string
+		for tup := range ch {
+			res = append(res, tup.Name)
+		}
+		if !reflect.DeepEqual(res, tt.expected) {
+			t.Errorf("%d has sites => %v, want %v", i, res, tt.expected)
+		}
+	}
+}
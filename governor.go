@@ -0,0 +1,78 @@
+package relsql
+
+import (
+	"sync"
+)
+
+// MemoryGovernor limits the memory held by the tuples that relations read
+// ahead of their consumers, across all of the relations that share it, like
+// the concurrent scans of a process.  When the tuples held by all of them
+// reach its budget, each scan waits until tuples are consumed elsewhere
+// before reading more, except that a scan may always hold one tuple, so that
+// every scan makes progress.
+type MemoryGovernor struct {
+	mu     sync.Mutex
+	budget int64
+	used   int64
+
+	// freed is closed, and replaced, when tuples are released
+	freed chan struct{}
+}
+
+// NewMemoryGovernor creates a governor with a budget in bytes, which is
+// compared to the estimated size of the held tuples.
+func NewMemoryGovernor(budget int64) *MemoryGovernor {
+	return &MemoryGovernor{budget: budget, freed: make(chan struct{})}
+}
+
+// WithMemoryGovernor makes the relation read ahead of its consumer within the
+// governor's budget, which it shares with other relations.  It can be
+// combined with WithPrefetchMemory, to also limit the relation's own tuples,
+// and with WithPrefetch.
+func WithMemoryGovernor(g *MemoryGovernor) Option {
+	return func(o *options) {
+		o.governor = g
+	}
+}
+
+// InUse returns the estimated size of the tuples that are held.
+func (g *MemoryGovernor) InUse() int64 {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.used
+}
+
+// room returns true if the held tuples are within the budget, and otherwise a
+// channel that is closed when some of them are released.  A nil governor
+// always has room.
+func (g *MemoryGovernor) room() (bool, <-chan struct{}) {
+	if g == nil {
+		return true, nil
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.used < g.budget, g.freed
+}
+
+// hold records that a tuple of size n is held
+func (g *MemoryGovernor) hold(n int64) {
+	if g == nil {
+		return
+	}
+	g.mu.Lock()
+	g.used += n
+	g.mu.Unlock()
+}
+
+// release records that tuples of size n are no longer held, and wakes the
+// scans that are waiting for room.
+func (g *MemoryGovernor) release(n int64) {
+	if g == nil || n == 0 {
+		return
+	}
+	g.mu.Lock()
+	g.used -= n
+	close(g.freed)
+	g.freed = make(chan struct{})
+	g.mu.Unlock()
+}
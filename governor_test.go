@@ -0,0 +1,72 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// test sharing a memory budget between concurrent scans
+func TestMemoryGovernor(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:governor?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type docTup struct {
+		N    int
+		Body []byte
+	}
+	var tups []docTup
+	for i := 0; i < 10; i++ {
+		tups = append(tups, docTup{i, make([]byte, 1000)})
+	}
+	if err := CreateTable(db, "docs", docTup{}, [][]string{[]string{"N"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "docs", rel.New(tups, [][]string{[]string{"N"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	g := NewMemoryGovernor(4000)
+	size := tupleSize(reflect.ValueOf(tups[0]))
+	var chs []chan docTup
+	var cancels []chan<- struct{}
+	for i := 0; i < 3; i++ {
+		r := New(db, "docs", docTup{}, [][]string{[]string{"N"}}, WithDialect(SQLite), WithMemoryGovernor(g))
+		ch := make(chan docTup)
+		cancels = append(cancels, r.TupleChan(ch))
+		<-ch
+		chs = append(chs, ch)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	// each scan may exceed the budget by one tuple
+	if used := g.InUse(); used < 4000 || used > 4000+3*size {
+		t.Errorf("InUse() of stalled scans => %d, want at most %d", used, 4000+3*size)
+	}
+	if n := db.Stats().InUse; n != 3 {
+		t.Errorf("stalled scans hold %d connections, want 3", n)
+	}
+
+	// consuming one scan lets the others read ahead
+	n := 1
+	for range chs[0] {
+		n++
+	}
+	if n != len(tups) {
+		t.Errorf("scan read %d tuples, want %d", n, len(tups))
+	}
+	close(cancels[1])
+	close(cancels[2])
+	time.Sleep(20 * time.Millisecond)
+	if used := g.InUse(); used != 0 {
+		t.Errorf("InUse() after the scans => %d, want 0", used)
+	}
+}
@@ -0,0 +1,51 @@
+package relsql
+
+import (
+	"github.com/jonlawlor/rel"
+	"net/http"
+	"strings"
+)
+
+// Handler returns an http.Handler that serves the tuples of a relation as
+// newline delimited json objects, like NewJSONEncoder writes.  Responses carry
+// the relation's ETag, and a conditional GET whose If-None-Match header holds
+// the current tag is answered with 304 Not Modified without evaluating the
+// relation.  If the relation's tag can't be found, for example because one
+// of its tables has no freshness probe, responses have no tag and the
+// relation is always evaluated.  A failure while the tuples are being written
+// aborts the response, since its status has already been sent.
+func Handler(r rel.Relation) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		if tag, err := ETag(req.Context(), r); err == nil {
+			w.Header().Set("ETag", tag)
+			if noneMatch(req.Header.Get("If-None-Match"), tag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		if req.Method == http.MethodHead {
+			return
+		}
+		if err := Export(r, NewJSONEncoder(w)); err != nil {
+			panic(http.ErrAbortHandler)
+		}
+	})
+}
+
+// noneMatch returns true if an If-None-Match header matches the tag, using
+// the weak comparison of RFC 9110.
+func noneMatch(header, tag string) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" || strings.TrimPrefix(t, "W/") == strings.TrimPrefix(tag, "W/") {
+			return true
+		}
+	}
+	return false
+}
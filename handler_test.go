@@ -0,0 +1,77 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// test serving a relation with conditional GETs
+func TestHandler(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:handler?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type orderTup struct {
+		ID        int
+		UpdatedAt int64
+	}
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "orders", orderTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "orders", rel.New([]orderTup{{1, 100}, {2, 200}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	h := Handler(New(db, "orders", orderTup{}, keys, WithDialect(SQLite), WithFreshness("UpdatedAt")))
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("")
+	tag := w.Header().Get("ETag")
+	if want := "{\"ID\":1,\"UpdatedAt\":100}\n{\"ID\":2,\"UpdatedAt\":200}\n"; w.Code != http.StatusOK || w.Body.String() != want || tag == "" {
+		t.Errorf("GET => %d %q with tag %q, want %d %q", w.Code, w.Body.String(), tag, http.StatusOK, want)
+	}
+	var getTest = []struct {
+		ifNoneMatch string
+		code        int
+	}{
+		{tag, http.StatusNotModified},
+		{`"other", ` + tag, http.StatusNotModified},
+		{"*", http.StatusNotModified},
+		{`W/"other"`, http.StatusOK},
+	}
+	for i, tt := range getTest {
+		w := get(tt.ifNoneMatch)
+		if w.Code != tt.code || w.Header().Get("ETag") != tag {
+			t.Errorf("%d has GET => %d with tag %q, want %d with tag %q", i, w.Code, w.Header().Get("ETag"), tt.code, tag)
+		}
+		if tt.code == http.StatusNotModified && w.Body.Len() != 0 {
+			t.Errorf("%d has a body in a 304 response", i)
+		}
+	}
+
+	// a modification changes the tag, so the old one no longer matches
+	if _, err := Insert(db, "orders", rel.New([]orderTup{{3, time.Now().Unix()}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if w := get(tag); w.Code != http.StatusOK || w.Header().Get("ETag") == tag {
+		t.Errorf("GET after a modification => %d with tag %q", w.Code, w.Header().Get("ETag"))
+	}
+}
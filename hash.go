@@ -0,0 +1,104 @@
+package relsql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"hash/fnv"
+	"reflect"
+)
+
+// Checksum is an order independent checksum of the tuples of a relation.
+// Checksums are only comparable if they were computed by the same method,
+// which is the name of the dialect that computed it on the server, or
+// "client" if the tuples were hashed as they were read.
+type Checksum struct {
+	Rows   int64
+	Sum    uint64
+	Method string
+}
+
+// hashAggregater is implemented by dialects that can compute an order
+// independent hash of rows on the server.  HashAggregate returns the
+// aggregate expression that hashes the columns of each row and combines the
+// hashes into a single integer.
+type hashAggregater interface {
+	HashAggregate(cols []string) string
+}
+
+// Hash computes a checksum of the tuples of a relation, which can be used to
+// detect changes, or to compare the replicas of a table.  If the relation is
+// a single query and its dialect can hash rows, the checksum is computed by
+// the database, and only a single row is read.  Otherwise every tuple is read
+// and hashed client side.  Lazy attributes are not included.
+func Hash(ctx context.Context, r rel.Relation) (Checksum, error) {
+	if r1, ok := r.(*sqlTable); ok && r1.err == nil && r1.composable() && !r1.touchesEncrypted(headingNames(reflect.TypeOf(r1.zero))...) {
+		if h, ok := r1.dialect().(hashAggregater); ok {
+			return r1.serverHash(ctx, h)
+		}
+	}
+	return clientHash(ctx, r)
+}
+
+// hashedFields returns the indexes of the fields of tuples of type e which are
+// hashed
+func hashedFields(e reflect.Type) []int {
+	var res []int
+	for i := 0; i < e.NumField(); i++ {
+		if e.Field(i).Type != lazyType {
+			res = append(res, i)
+		}
+	}
+	return res
+}
+
+// serverHash computes the checksum of the relation with the dialect's hash
+// aggregate.
+func (r1 *sqlTable) serverHash(ctx context.Context, h hashAggregater) (Checksum, error) {
+	e := reflect.TypeOf(r1.zero)
+	var cols []string
+	for _, i := range hashedFields(e) {
+		cols = append(cols, e.Field(i).Name)
+	}
+	b := builder{dialect: r1.dialect()}
+	q := "SELECT COUNT(*), " + h.HashAggregate(cols) + " FROM (" + r1.build(&b, nil, true) + ")" + b.alias("h")
+	if b.err != nil {
+		return Checksum{}, b.err
+	}
+	q, args, err := r1.applyPolicies(q, b.args)
+	if err != nil {
+		return Checksum{}, err
+	}
+	var rows int64
+	var sum *int64
+	if err := r1.opts.queryRow(ctx, r1.queryer(), q, bindArgs(r1.dialect(), args), &rows, &sum); err != nil {
+		return Checksum{}, err
+	}
+	c := Checksum{Rows: rows, Method: r1.dialect().Name()}
+	if sum != nil {
+		c.Sum = uint64(*sum)
+	}
+	return c, nil
+}
+
+// clientHash reads the tuples of the relation and sums their hashes
+func clientHash(ctx context.Context, r rel.Relation) (Checksum, error) {
+	c := Checksum{Method: "client"}
+	fields := hashedFields(reflect.TypeOf(r.Zero()))
+	err := forEach(r, func(tup reflect.Value) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		h := fnv.New64a()
+		for _, i := range fields {
+			fmt.Fprintf(h, "%T:%v\x00", tup.Field(i).Interface(), tup.Field(i).Interface())
+		}
+		c.Rows++
+		c.Sum += h.Sum64()
+		return nil
+	})
+	if err != nil {
+		return Checksum{}, err
+	}
+	return c, nil
+}
@@ -0,0 +1,80 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+)
+
+// sumDialect is sqlite with a hash aggregate that sums an expression of the
+// columns, which is enough to tell which rows were hashed.
+type sumDialect struct {
+	sqliteDialect
+}
+
+func (sumDialect) HashAggregate(cols []string) string {
+	return "SUM(" + strings.Join(cols, " * 1000 + ") + ")"
+}
+
+// test computing checksums of relations client and server side
+func TestHash(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:hash?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type partTup struct {
+		PNO    int
+		Weight int
+	}
+	keys := [][]string{[]string{"PNO"}}
+	for _, name := range []string{"parts", "replica"} {
+		if err := CreateTable(db, name, partTup{}, keys); err != nil {
+			t.Errorf("CreateTable() => %v", err)
+			return
+		}
+	}
+	if _, err := Insert(db, "parts", rel.New([]partTup{{1, 12}, {2, 17}, {3, 17}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "replica", rel.New([]partTup{{3, 17}, {1, 12}, {2, 17}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	ctx := context.Background()
+	parts := New(db, "parts", partTup{}, keys, WithDialect(SQLite))
+	replica := New(db, "replica", partTup{}, keys, WithDialect(SQLite))
+
+	h1, err := Hash(ctx, parts)
+	if err != nil {
+		t.Errorf("Hash() => %v", err)
+		return
+	}
+	h2, err := Hash(ctx, replica)
+	if err != nil {
+		t.Errorf("Hash() => %v", err)
+		return
+	}
+	if h1 != h2 || h1.Rows != 3 || h1.Method != "client" {
+		t.Errorf("Hash() => %v and %v, want equal client checksums of 3 rows", h1, h2)
+	}
+	if h3, _ := Hash(ctx, parts.Restrict(Attribute("PNO").NE(2))); h3 == h1 || h3.Rows != 2 {
+		t.Errorf("Hash() of a restriction => %v, want a different checksum of 2 rows", h3)
+	}
+
+	// the dialect's aggregate computes the checksum in the database
+	server := New(db, "parts", partTup{}, keys, WithDialect(sumDialect{}))
+	h4, err := Hash(ctx, server.Restrict(Attribute("PNO").GT(1)))
+	if err != nil {
+		t.Errorf("Hash() => %v", err)
+		return
+	}
+	if h4 != (Checksum{2, 2*1000 + 17 + 3*1000 + 17, "sqlite3"}) {
+		t.Errorf("Hash() => %v, want the server checksum", h4)
+	}
+}
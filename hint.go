@@ -0,0 +1,83 @@
+package relsql
+
+import (
+	"strings"
+)
+
+// HintStyle is how a dialect attaches optimizer hints to a query
+type HintStyle int
+
+const (
+	// HintComment writes the hints in a comment after the first SELECT, like
+	// SELECT /*+ INDEX(t1 users_email) */ ..., which is how mysql, Oracle and
+	// postgres' pg_hint_plan read them.  Databases without hints ignore the
+	// comment.
+	HintComment HintStyle = iota
+
+	// HintOption appends the hints in an OPTION clause, like
+	// ... OPTION (RECOMPILE, MAXDOP 1), which is how SQL Server reads them.
+	HintOption
+
+	// HintNone drops the hints, for databases which would reject them.
+	HintNone
+)
+
+// hintStyler is implemented by dialects which attach hints other than in a
+// comment
+type hintStyler interface {
+	HintStyle() HintStyle
+}
+
+// hintStyle returns how the dialect attaches hints
+func hintStyle(d Dialect) HintStyle {
+	if h, ok := d.(hintStyler); ok {
+		return h.HintStyle()
+	}
+	return HintComment
+}
+
+// WithHints attaches optimizer hints to the queries that read the relation,
+// so that the plan of a hot query can be pinned.  The hints are written as
+// the relation's dialect expects them, and those of every relation in a
+// larger query, like a join, are attached to it.  They are passed to the
+// server verbatim, so they have to refer to the tables and aliases of the
+// compiled query, which SQL shows.
+func WithHints(hints ...string) Option {
+	return func(o *options) {
+		o.hints = append(o.hints, hints...)
+	}
+}
+
+// hints returns the hints of the relations that the query reads from, in
+// order and without repeats.
+func (r1 *sqlTable) hints() []string {
+	var hints []string
+	seen := make(map[string]bool)
+	Inspect(r1, func(n Node) bool {
+		if q, ok := n.(*Query); ok {
+			for _, h := range q.Relation.(*sqlTable).opts.hints {
+				if !seen[h] {
+					seen[h] = true
+					hints = append(hints, h)
+				}
+			}
+		}
+		return true
+	})
+	return hints
+}
+
+// addHints attaches the hints to a query in the dialect.  Queries which
+// aren't a SELECT, like CALLs, are left alone.
+func addHints(d Dialect, q string, hints []string) string {
+	if len(hints) == 0 || !strings.HasPrefix(q, "SELECT ") {
+		return q
+	}
+	switch hintStyle(d) {
+	case HintComment:
+		return "SELECT /*+ " + strings.Join(hints, " ") + " */ " + q[len("SELECT "):]
+	case HintOption:
+		return q + " OPTION (" + strings.Join(hints, ", ") + ")"
+	}
+	return q
+}
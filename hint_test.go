@@ -0,0 +1,96 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"testing"
+)
+
+// optionHintDialect attaches hints in an OPTION clause, like SQL Server
+type optionHintDialect struct {
+	ansiDialect
+}
+
+func (optionHintDialect) HintStyle() HintStyle {
+	return HintOption
+}
+
+// noHintDialect drops hints
+type noHintDialect struct {
+	ansiDialect
+}
+
+func (noHintDialect) HintStyle() HintStyle {
+	return HintNone
+}
+
+// test attaching optimizer hints to the queries of relations
+func TestHints(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:hints?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type userTup struct {
+		UserID int
+		Email  string
+	}
+	type orderTup struct {
+		OrderID int
+		UserID  int
+	}
+	type joinTup struct {
+		UserID  int
+		Email   string
+		OrderID int
+	}
+	userKeys, orderKeys := [][]string{[]string{"UserID"}}, [][]string{[]string{"OrderID"}}
+	users := func(d Dialect, hints ...string) rel.Relation {
+		return New(db, "users", userTup{}, userKeys, WithDialect(d), WithHints(hints...))
+	}
+	orders := func(d Dialect, hints ...string) rel.Relation {
+		return New(db, "orders", orderTup{}, orderKeys, WithDialect(d), WithHints(hints...))
+	}
+
+	var hintsTest = []struct {
+		r    rel.Relation
+		want string
+	}{
+		{users(ANSI), "SELECT UserID, Email FROM users"},
+		{users(ANSI, "INDEX(users users_email)"), "SELECT /*+ INDEX(users users_email) */ UserID, Email FROM users"},
+		{users(Oracle, "FULL(users)", "PARALLEL(4)").Restrict(Attribute("UserID").EQ(1)), "SELECT /*+ FULL(users) PARALLEL(4) */ UserID, Email FROM users WHERE UserID = :1"},
+		{users(optionHintDialect{}, "RECOMPILE", "MAXDOP 1"), "SELECT UserID, Email FROM users OPTION (RECOMPILE, MAXDOP 1)"},
+		{users(noHintDialect{}, "RECOMPILE"), "SELECT UserID, Email FROM users"},
+		// the hints of both sides of a join are attached to it, once
+		{users(ANSI, "LEADING(t1)").Join(orders(ANSI, "LEADING(t1)", "USE_NL(t2)"), joinTup{}), "SELECT /*+ LEADING(t1) USE_NL(t2) */ "},
+	}
+	for i, tt := range hintsTest {
+		q, _, err := SQL(tt.r)
+		if err != nil || len(q) < len(tt.want) || q[:len(tt.want)] != tt.want {
+			t.Errorf("%d has SQL() => %q, %v, want %q", i, q, err, tt.want)
+		}
+	}
+
+	// databases without hints ignore the comment
+	if err := CreateTable(db, "users", userTup{}, userKeys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "users", rel.New([]userTup{{1, "ann@example.com"}}, userKeys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	r := users(SQLite, "INDEX(users users_email)")
+	ch := make(chan userTup)
+	r.TupleChan(ch)
+	var res []userTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if err := r.Err(); err != nil || fmt.Sprint(res) != "[{1 ann@example.com}]" {
+		t.Errorf("TupleChan() => %v, %v", res, err)
+	}
+}
@@ -0,0 +1,234 @@
+package relsql
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// MapFormat is how a dialect stores map[string]string attributes
+type MapFormat int
+
+const (
+	// MapJSON stores maps as JSON objects in a TEXT column
+	MapJSON MapFormat = iota
+
+	// MapHStore stores maps in postgres' hstore type
+	MapHStore
+)
+
+// mapFormatter is implemented by dialects which don't store maps as JSON
+type mapFormatter interface {
+	MapFormat() MapFormat
+}
+
+// mapFormat returns the format for maps in a dialect
+func mapFormat(d Dialect) MapFormat {
+	if f, ok := d.(mapFormatter); ok {
+		return f.MapFormat()
+	}
+	return MapJSON
+}
+
+// mapKeyer is implemented by dialects which can look up a key in a map
+// column.  MapKey returns the sql expression for the value of the key in the
+// column, like Tags -> 'env' for hstore.
+type mapKeyer interface {
+	MapKey(col, key string) string
+}
+
+// mapType is the type of map attributes
+var mapType = reflect.TypeOf(map[string]string(nil))
+
+// MapCodec is the codec for map[string]string, which can also be registered
+// for other string map types.
+var MapCodec Codec = mapCodec{}
+
+// mapCodec converts maps according to the dialect's MapFormat
+type mapCodec struct{}
+
+// Encode converts a map to hstore or JSON text.  A nil map is encoded as an
+// empty one.
+func (mapCodec) Encode(d Dialect, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String || rv.Type().Elem().Kind() != reflect.String {
+		return nil, fmt.Errorf("relsql: %T is not a string map", v)
+	}
+	m := make(map[string]string, rv.Len())
+	for _, k := range rv.MapKeys() {
+		m[k.String()] = rv.MapIndex(k).String()
+	}
+	if mapFormat(d) == MapHStore {
+		return hstoreString(m), nil
+	}
+	b, err := json.Marshal(m)
+	return string(b), err
+}
+
+// Decode parses hstore or JSON text into a map
+func (mapCodec) Decode(d Dialect, src interface{}, dst reflect.Value) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+		return fmt.Errorf("relsql: can't convert NULL to %v", dst.Type())
+	default:
+		return decodeError(src, dst)
+	}
+	var m map[string]string
+	if mapFormat(d) == MapHStore {
+		var err error
+		if m, err = parseHStore(s); err != nil {
+			return err
+		}
+	} else if err := json.Unmarshal([]byte(s), &m); err != nil {
+		return fmt.Errorf("relsql: invalid json object %q: %v", s, err)
+	}
+	res := reflect.MakeMapWithSize(dst.Type(), len(m))
+	for k, v := range m {
+		res.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), reflect.ValueOf(v).Convert(dst.Type().Elem()))
+	}
+	dst.Set(res)
+	return nil
+}
+
+// TypeName returns the column type for maps in the dialect
+func (mapCodec) TypeName(d Dialect) string {
+	if mapFormat(d) == MapHStore {
+		return "HSTORE"
+	}
+	return "TEXT"
+}
+
+// hstoreString returns the hstore text for a map, with sorted keys
+func hstoreString(m map[string]string) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, k := range keys {
+		pairs[i] = hstoreQuote(k) + "=>" + hstoreQuote(m[k])
+	}
+	return strings.Join(pairs, ", ")
+}
+
+// hstoreQuote quotes a key or value of an hstore
+func hstoreQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// parseHStore parses the hstore text form, like "a"=>"1", "b"=>NULL.  NULL
+// values become empty strings.
+func parseHStore(s string) (map[string]string, error) {
+	m := make(map[string]string)
+	rest := strings.TrimSpace(s)
+	for rest != "" {
+		k, r, err := hstoreToken(rest)
+		if err != nil || !strings.HasPrefix(r, "=>") {
+			return nil, fmt.Errorf("relsql: invalid hstore %q", s)
+		}
+		v, r, err := hstoreToken(strings.TrimSpace(r[2:]))
+		if err != nil {
+			return nil, fmt.Errorf("relsql: invalid hstore %q", s)
+		}
+		m[k] = v
+		rest = strings.TrimSpace(r)
+		if rest != "" {
+			if rest[0] != ',' {
+				return nil, fmt.Errorf("relsql: invalid hstore %q", s)
+			}
+			rest = strings.TrimSpace(rest[1:])
+		}
+	}
+	return m, nil
+}
+
+// hstoreToken reads a quoted string or NULL from the start of s, and returns
+// it along with the remaining text.
+func hstoreToken(s string) (string, string, error) {
+	if strings.HasPrefix(s, "NULL") {
+		return "", strings.TrimSpace(s[4:]), nil
+	}
+	if !strings.HasPrefix(s, `"`) {
+		return "", "", fmt.Errorf("relsql: expected quoted string")
+	}
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+			if i < len(s) {
+				b.WriteByte(s[i])
+			}
+		case '"':
+			return b.String(), strings.TrimSpace(s[i+1:]), nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", "", fmt.Errorf("relsql: unterminated string")
+}
+
+// MapKey is the value of a key in a map attribute, which can be compared to
+// a string.
+type MapKey struct {
+	att Attribute
+	key string
+}
+
+// Key returns the value of a key in a map attribute
+func (att Attribute) Key(key string) MapKey {
+	return MapKey{att, key}
+}
+
+// mapLookup is the right side of a comparison with a MapKey, which holds the
+// key along with the value it is compared to.
+type mapLookup struct {
+	key string
+	val string
+}
+
+// EQ creates a predicate that is true when the map has the key with the
+// value.  It is pushed down for dialects which can look up map keys.
+func (k MapKey) EQ(v string) Pred {
+	cp := clientPred{rel.Attribute(k.att), fmt.Sprintf("%s[%q] == %q", k.att, k.key, v), func(m interface{}) bool {
+		v2, ok := lookupKey(m, k.key)
+		return ok && v2 == v
+	}}
+	return Pred{cp, "=", rel.Attribute(k.att), mapLookup{k.key, v}, nil}
+}
+
+// NE creates a predicate that is true when the map has the key with a value
+// other than v.  It is pushed down for dialects which can look up map keys.
+func (k MapKey) NE(v string) Pred {
+	cp := clientPred{rel.Attribute(k.att), fmt.Sprintf("%s[%q] != %q", k.att, k.key, v), func(m interface{}) bool {
+		v2, ok := lookupKey(m, k.key)
+		return ok && v2 != v
+	}}
+	return Pred{cp, "<>", rel.Attribute(k.att), mapLookup{k.key, v}, nil}
+}
+
+// lookupKey returns the value of a key in a string map
+func lookupKey(m interface{}, key string) (string, bool) {
+	rv := reflect.ValueOf(m)
+	if rv.Kind() != reflect.Map {
+		return "", false
+	}
+	v := rv.MapIndex(reflect.ValueOf(key).Convert(rv.Type().Key()))
+	if !v.IsValid() {
+		return "", false
+	}
+	return v.String(), true
+}
+
+func init() {
+	RegisterCodec(mapType, MapCodec)
+}
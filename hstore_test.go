@@ -0,0 +1,106 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test map encoding and hstore parsing
+func TestMapCodec(t *testing.T) {
+	m := map[string]string{"b": `say "hi"`, "a": `C:\`}
+	var formatTest = []struct {
+		d        Dialect
+		typeName string
+		encoded  string
+	}{
+		{ANSI, "TEXT", `{"a":"C:\\","b":"say \"hi\""}`},
+		{Postgres, "HSTORE", `"a"=>"C:\\", "b"=>"say \"hi\""`},
+	}
+	for i, tt := range formatTest {
+		if typeName := MapCodec.TypeName(tt.d); typeName != tt.typeName {
+			t.Errorf("%d has TypeName() => %v, want %v", i, typeName, tt.typeName)
+		}
+		v, err := encodeArg(tt.d, m)
+		if v != tt.encoded || err != nil {
+			t.Errorf("%d has encodeArg() => %v, %v, want %v", i, v, err, tt.encoded)
+		}
+		dst := reflect.New(mapType).Elem()
+		if err := MapCodec.Decode(tt.d, []byte(tt.encoded), dst); err != nil || !reflect.DeepEqual(dst.Interface(), m) {
+			t.Errorf("%d has Decode() => %v, %v, want %v", i, dst.Interface(), err, m)
+		}
+	}
+
+	var parseTest = []struct {
+		in    string
+		out   map[string]string
+		isErr bool
+	}{
+		{``, map[string]string{}, false},
+		{`"a"=>"1",  "b" => NULL`, map[string]string{"a": "1", "b": ""}, false},
+		{`"a"=>"1" "b"=>"2"`, nil, true},
+		{`"a"=>"1`, nil, true},
+		{`a=>1`, nil, true},
+	}
+	for i, tt := range parseTest {
+		out, err := parseHStore(tt.in)
+		if !reflect.DeepEqual(out, tt.out) || (err != nil) != tt.isErr {
+			t.Errorf("%d has parseHStore(%q) => %v, %v, want %v", i, tt.in, out, err, tt.out)
+		}
+	}
+}
+
+// test key lookup predicates, pushed down and client side
+func TestMapKey(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type serverTup struct {
+		Name string
+		Tags map[string]string
+	}
+	if err := CreateTable(db, "servers", serverTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	servers := rel.New([]serverTup{
+		{"a", map[string]string{"env": "prod", "team": "x"}},
+		{"b", map[string]string{"env": "dev"}},
+		{"c", nil},
+	}, [][]string{[]string{"Name"}})
+	if _, err := Insert(db, "servers", servers); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	p := Attribute("Tags").Key("env").EQ("prod")
+	pg := New(db, "servers", serverTup{}, [][]string{[]string{"Name"}}, WithDialect(Postgres)).Restrict(p)
+	q, args, _ := pg.(*sqlTable).queryString()
+	if want := "SELECT Name, Tags FROM servers WHERE Tags -> 'env' = $1"; q != want || len(args) != 1 || args[0] != "prod" {
+		t.Errorf("hstore query => %v %v, want %v [prod]", q, args, want)
+	}
+	if _, ok := New(db, "servers", serverTup{}, nil, WithDialect(ANSI)).Restrict(p).(*sqlTable); ok {
+		t.Errorf("key lookup was pushed down without dialect support")
+	}
+
+	want := []serverTup{{"a", map[string]string{"env": "prod", "team": "x"}}}
+	for _, r := range []rel.Relation{
+		New(db, "servers", serverTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite)).Restrict(p),
+		New(db, "servers", serverTup{}, [][]string{[]string{"Name"}}, WithDialect(ANSI)).Restrict(p),
+	} {
+		ch := make(chan serverTup)
+		r.TupleChan(ch)
+		var res []serverTup
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		if !reflect.DeepEqual(res, want) || r.Err() != nil {
+			t.Errorf("%v => %v, %v, want %v", r, res, r.Err(), want)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package relsql
+
+import (
+	"errors"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+)
+
+// Outer refers to an attribute of the outer relation of a lateral join.  It
+// is used as the value of a comparison in a restriction of the inner
+// relation, like Attribute("Time").LE(Outer("Time")), which is then
+// evaluated once for each tuple of the outer relation.
+type Outer string
+
+// lateraler is implemented by dialects that can join a table to a subquery
+// which refers to the table's columns.  LateralJoin returns the operator that
+// does so, like CROSS JOIN LATERAL or CROSS APPLY, or an empty string if the
+// dialect can't.
+type lateraler interface {
+	LateralJoin() string
+}
+
+// lateralJoinString returns the dialect's lateral join operator, or an empty
+// string if it has none.  ANSI sql has had LATERAL since SQL:1999.
+func lateralJoinString(d Dialect) string {
+	if l, ok := d.(lateraler); ok {
+		return l.LateralJoin()
+	}
+	if _, ok := d.(ansiDialect); ok {
+		return "CROSS JOIN LATERAL"
+	}
+	return ""
+}
+
+// outerRef returns the reference to an attribute of the outer relation of
+// the lateral join that is being built.
+func (b *builder) outerRef(att Outer) string {
+	if b.outer == "" {
+		if b.err == nil {
+			b.err = fmt.Errorf("relsql: outer attribute %s is only defined in a lateral join", string(att))
+		}
+		return "outer." + string(att)
+	}
+	return b.outer + "." + string(att)
+}
+
+// lateralSource is a lateral join, in which the restrictions of r2 can refer
+// to the attributes of r1.  The outer relation has the alias lo, and the
+// inner relation li.
+type lateralSource struct {
+	r1, r2 *sqlTable
+
+	// outer holds the attributes of r1 that r2 refers to
+	outer []rel.Attribute
+}
+
+// build returns the lateral join of the two relations.  The outer relation
+// keeps the attributes that the inner one refers to.
+func (l *lateralSource) build(b *builder, needed map[column]bool) string {
+	n1 := neededAttributes(needed, "lo")
+	n2 := neededAttributes(needed, "li")
+	if n1 != nil {
+		for _, att := range l.outer {
+			n1[att] = true
+		}
+	}
+	s1 := l.r1.build(b, n1, true)
+	outer := b.outer
+	b.outer = "lo"
+	s2 := l.r2.build(b, n2, true)
+	b.outer = outer
+	return "(" + s1 + ")" + b.alias("lo") + " " + lateralJoinString(b.dialectOrANSI()) + " (" + s2 + ")" + b.alias("li")
+}
+
+// String returns a text representation of the lateral join
+func (l *lateralSource) String() string {
+	return l.r1.expr() + " ⋈ LATERAL " + l.r2.expr()
+}
+
+// Lateral creates a relation with the tuples of r2 for each tuple of r1,
+// where the restrictions of r2 can refer to the attributes of r1 with Outer.
+// zero is the type of the resulting tuples, whose attributes come from either
+// relation, which may not have any attributes in common.  r2 has to be from
+// this package.
+//
+// If both relations are on the same database, and the dialect has a lateral
+// join, the relation is compiled into a single query.  Otherwise r2 is
+// queried once for each tuple of r1, with the outer attributes bound to the
+// tuple's values.
+func Lateral(r1, r2 rel.Relation, zero interface{}) rel.Relation {
+	fail := func(err error) rel.Relation {
+		return &sqlTable{zero: zero, cKeys: rel.DefaultKeys(zero), err: err}
+	}
+	inner, ok := r2.(*sqlTable)
+	if !ok {
+		return fail(fmt.Errorf("relsql: inner relation of lateral join %v is not from relsql", r2))
+	}
+	e1, e2, e3 := reflect.TypeOf(r1.Zero()), reflect.TypeOf(r2.Zero()), reflect.TypeOf(zero)
+	if err := checkZero(e3); err != nil {
+		return fail(err)
+	}
+	if common := commonAttributes(e1, e2); len(common) > 0 {
+		return fail(fmt.Errorf("relsql: lateral join of %v and %v has common attributes %v", r1, r2, common))
+	}
+	outer := inner.outerRefs()
+	for _, att := range outer {
+		if _, ok := e1.FieldByName(string(att)); !ok {
+			return fail(fmt.Errorf("relsql: outer attribute %s is not in the heading of %v", att, e1))
+		}
+	}
+	cols := make([]column, e3.NumField())
+	for i := range cols {
+		name := e3.Field(i).Name
+		if _, ok := e1.FieldByName(name); ok {
+			cols[i] = column{"lo", name}
+		} else if _, ok := e2.FieldByName(name); ok {
+			cols[i] = column{"li", name}
+		} else {
+			return fail(fmt.Errorf("relsql: attribute %s of lateral join is in neither %v nor %v", name, e1, e2))
+		}
+	}
+	if o, ok := r1.(*sqlTable); ok && Supports(o.dialect(), FeatureLateral) {
+		if i, ok := o.sameDB(inner); ok {
+			return &sqlTable{
+				db:             o.db,
+				conn:           o.conn,
+				q:              o.q,
+				src:            &lateralSource{o, i, outer},
+				cols:           cols,
+				zero:           zero,
+				cKeys:          joinKeys(o.cKeys, i.cKeys),
+				sourceDistinct: true,
+				opts:           o.opts,
+			}
+		}
+	}
+	return &lateralJoin{r1: r1, r2: inner, outer: outer, cols: cols, zero: zero, cKeys: joinKeys(r1.CKeys(), inner.cKeys)}
+}
+
+// outerRefs returns the outer attributes that the relation's restrictions
+// refer to, including those of the relations it is derived from.  The inner
+// relations of lateral joins are skipped, because their outer attributes
+// refer to the lateral join's own outer relation.
+func (r1 *sqlTable) outerRefs() []rel.Attribute {
+	var res []rel.Attribute
+	for _, c := range r1.where {
+		for _, att := range c.pred.outerRefs() {
+			if !containsAttribute(res, att) {
+				res = append(res, att)
+			}
+		}
+	}
+	var subs []*sqlTable
+	switch src := r1.src.(type) {
+	case *setSource:
+		subs = []*sqlTable{src.r1, src.r2}
+	case *joinSource:
+		subs = []*sqlTable{src.r1, src.r2}
+	case *lateralSource:
+		subs = []*sqlTable{src.r1}
+	case *topNSource:
+		subs = []*sqlTable{src.r}
+	case *summarySource:
+		subs = []*sqlTable{src.r}
+	case *runningSource:
+		subs = []*sqlTable{src.r}
+	case *binSource:
+		subs = []*sqlTable{src.r}
+	}
+	for _, s := range subs {
+		for _, att := range s.outerRefs() {
+			if !containsAttribute(res, att) {
+				res = append(res, att)
+			}
+		}
+	}
+	return res
+}
+
+// outerRefs returns the outer attributes that the predicate compares with
+func (p Pred) outerRefs() []rel.Attribute {
+	var res []rel.Attribute
+	for _, p2 := range p.preds {
+		res = append(res, p2.outerRefs()...)
+	}
+	if o, ok := p.val.(Outer); ok {
+		res = append(res, rel.Attribute(o))
+	}
+	return res
+}
+
+// comparisons creates the comparisons of an attribute with a value by their
+// sql operator
+var comparisons = map[string]func(Attribute, interface{}) Pred{
+	"=":  Attribute.EQ,
+	"<>": Attribute.NE,
+	"<":  Attribute.LT,
+	"<=": Attribute.LE,
+	">":  Attribute.GT,
+	">=": Attribute.GE,
+}
+
+// bindOuter returns the predicate with its comparisons to outer attributes
+// replaced by comparisons to their values.
+func (p Pred) bindOuter(vals map[rel.Attribute]interface{}) Pred {
+	if p.preds != nil {
+		ps := make([]Pred, len(p.preds))
+		for i, p2 := range p.preds {
+			ps[i] = p2.bindOuter(vals)
+		}
+		if p.op == "AND" {
+			return And(ps[0], ps[1:]...)
+		}
+		return Or(ps[0], ps[1:]...)
+	}
+	if o, ok := p.val.(Outer); ok {
+		return comparisons[p.op](Attribute(p.att), vals[rel.Attribute(o)])
+	}
+	return p
+}
+
+// bindOuter returns the relation with the outer attributes in its
+// restrictions, and in those of the relations it is derived from, bound to
+// their values.
+func (r1 *sqlTable) bindOuter(vals map[rel.Attribute]interface{}) *sqlTable {
+	r2 := *r1
+	r2.where = make([]condition, len(r1.where))
+	for i, c := range r1.where {
+		r2.where[i] = condition{c.pred.bindOuter(vals), c.cols}
+	}
+	switch src := r1.src.(type) {
+	case *setSource:
+		r2.src = &setSource{src.op, src.r1.bindOuter(vals), src.r2.bindOuter(vals)}
+	case *joinSource:
+		r2.src = &joinSource{src.r1.bindOuter(vals), src.r2.bindOuter(vals), src.on}
+	case *lateralSource:
+		r2.src = &lateralSource{src.r1.bindOuter(vals), src.r2, src.outer}
+	case *topNSource:
+		r2.src = &topNSource{src.r.bindOuter(vals), src.n, src.group, src.order}
+	case *summarySource:
+		r2.src = &summarySource{src.r.bindOuter(vals), src.group, src.aggs}
+	case *runningSource:
+		r2.src = &runningSource{src.r.bindOuter(vals), src.group, src.order, src.aggs}
+	case *binSource:
+		r2.src = &binSource{src.r.bindOuter(vals), src.expr, src.as, src.op}
+	}
+	return &r2
+}
+
+// lateralJoin is a lateral join which is evaluated client side, by querying
+// the inner relation once for each tuple of the outer relation.
+type lateralJoin struct {
+	r1    rel.Relation
+	r2    *sqlTable
+	outer []rel.Attribute
+
+	// cols holds the side, lo or li, and the attribute of each field of the
+	// resulting tuples
+	cols  []column
+	zero  interface{}
+	cKeys rel.CandKeys
+	err   error
+}
+
+// errStopped is returned by the functions given to forEach to stop reading
+// because the consumer cancelled.
+var errStopped = errors.New("relsql: stopped")
+
+// TupleChan sends the tuples of the join on the channel
+func (r *lateralJoin) TupleChan(t interface{}) chan<- struct{} {
+	cancel := make(chan struct{})
+	chv := reflect.ValueOf(t)
+	if err := rel.EnsureChan(chv.Type(), r.zero); err != nil {
+		r.err = err
+		return cancel
+	}
+	if err := r.Err(); err != nil {
+		chv.Close()
+		return cancel
+	}
+	go func() {
+		e3 := reflect.TypeOf(r.zero)
+		canSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cancel)}
+		err := forEach(r.r1, func(t1 reflect.Value) error {
+			vals := make(map[rel.Attribute]interface{})
+			for _, att := range r.outer {
+				vals[att] = t1.FieldByName(string(att)).Interface()
+			}
+			return forEach(r.r2.bindOuter(vals), func(t2 reflect.Value) error {
+				tup := reflect.New(e3).Elem()
+				for i, c := range r.cols {
+					if c.table == "lo" {
+						tup.Field(i).Set(t1.FieldByName(c.name))
+					} else {
+						tup.Field(i).Set(t2.FieldByName(c.name))
+					}
+				}
+				resSel := reflect.SelectCase{Dir: reflect.SelectSend, Chan: chv, Send: tup}
+				if chosen, _, _ := reflect.Select([]reflect.SelectCase{canSel, resSel}); chosen == 0 {
+					return errStopped
+				}
+				return nil
+			})
+		})
+		if err == errStopped {
+			return
+		}
+		if err != nil {
+			r.err = err
+		}
+		chv.Close()
+	}()
+	return cancel
+}
+
+// Zero returns the zero value of the join's tuples
+func (r *lateralJoin) Zero() interface{} {
+	return r.zero
+}
+
+// CKeys returns the candidate keys of the join
+func (r *lateralJoin) CKeys() rel.CandKeys {
+	return r.cKeys
+}
+
+// GoString returns a text representation of the join
+func (r *lateralJoin) GoString() string {
+	return fmt.Sprintf("relsql.lateralJoin{%#v, %#v, %v}", r.r1, r.r2, r.outer)
+}
+
+// String returns a text representation of the join
+func (r *lateralJoin) String() string {
+	return exprString(r.r1) + " ⋈ LATERAL " + exprString(r.r2)
+}
+
+// Project is evaluated client side
+func (r *lateralJoin) Project(z2 interface{}) rel.Relation {
+	return rel.NewProject(r, z2)
+}
+
+// Restrict is evaluated client side
+func (r *lateralJoin) Restrict(p rel.Predicate) rel.Relation {
+	return rel.NewRestrict(r, p)
+}
+
+// Rename is evaluated client side
+func (r *lateralJoin) Rename(z2 interface{}) rel.Relation {
+	return rel.NewRename(r, z2)
+}
+
+// Union is evaluated client side
+func (r *lateralJoin) Union(r2 rel.Relation) rel.Relation {
+	return rel.NewUnion(r, r2)
+}
+
+// Diff is evaluated client side
+func (r *lateralJoin) Diff(r2 rel.Relation) rel.Relation {
+	return rel.NewDiff(r, r2)
+}
+
+// Join is evaluated client side
+func (r *lateralJoin) Join(r2 rel.Relation, zero interface{}) rel.Relation {
+	return rel.NewJoin(r, r2, zero)
+}
+
+// GroupBy is evaluated client side
+func (r *lateralJoin) GroupBy(t2, gfcn interface{}) rel.Relation {
+	return rel.NewGroupBy(r, t2, gfcn)
+}
+
+// Map is evaluated client side
+func (r *lateralJoin) Map(mfcn interface{}, ckeystr [][]string) rel.Relation {
+	return rel.NewMap(r, mfcn, ckeystr)
+}
+
+// Err returns the first error of the join or its inputs
+func (r *lateralJoin) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	if err := r.r1.Err(); err != nil {
+		return err
+	}
+	return r.r2.Err()
+}
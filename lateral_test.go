@@ -0,0 +1,101 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// test lateral joins, compiled and evaluated client side
+func TestLateral(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:lateral?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type tradeTup struct {
+		TradeID int
+		Sym     string
+		Time    int
+	}
+	type quoteTup struct {
+		QSym  string
+		QTime int
+		Price int
+	}
+	type resultTup struct {
+		TradeID int
+		Time    int
+		QTime   int
+		Price   int
+	}
+	if err := CreateTable(db, "trades", tradeTup{}, [][]string{[]string{"TradeID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if err := CreateTable(db, "quotes", quoteTup{}, [][]string{[]string{"QSym", "QTime"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "trades", rel.New([]tradeTup{{1, "A", 10}, {2, "B", 10}, {3, "A", 5}}, nil)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "quotes", rel.New([]quoteTup{{"A", 4, 100}, {"A", 8, 101}, {"B", 12, 200}}, nil)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	earlier := And(Attribute("QSym").EQ(Outer("Sym")), Attribute("QTime").LE(Outer("Time")))
+
+	// ANSI compiles the join into a single query
+	trades := New(db, "trades", tradeTup{}, [][]string{[]string{"TradeID"}}, WithDialect(ANSI))
+	quotes := New(db, "quotes", quoteTup{}, [][]string{[]string{"QSym", "QTime"}}, WithDialect(ANSI))
+	q, _, err := SQL(Lateral(trades, quotes.Restrict(earlier), resultTup{}))
+	want := "SELECT lo.TradeID, lo.Time, li.QTime, li.Price FROM (SELECT TradeID, Sym, Time FROM trades) AS lo CROSS JOIN LATERAL (SELECT QTime, Price FROM quotes WHERE QSym = lo.Sym AND QTime <= lo.Time) AS li"
+	if err != nil || q != want {
+		t.Errorf("SQL() => %s, %v, want %s", q, err, want)
+	}
+
+	// sqlite has no lateral join, so the quotes are queried for each trade
+	trades = New(db, "trades", tradeTup{}, [][]string{[]string{"TradeID"}}, WithDialect(SQLite))
+	quotes = New(db, "quotes", quoteTup{}, [][]string{[]string{"QSym", "QTime"}}, WithDialect(SQLite))
+	r := Lateral(trades, quotes.Restrict(earlier), resultTup{})
+	if _, ok := r.(*lateralJoin); !ok {
+		t.Errorf("Lateral() => %T, want a client side join", r)
+	}
+	ch := make(chan resultTup)
+	r.TupleChan(ch)
+	var got []string
+	for tup := range ch {
+		got = append(got, fmt.Sprint(tup))
+	}
+	sort.Strings(got)
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+	if fmt.Sprint(got) != "[{1 10 4 100} {1 10 8 101} {3 5 4 100}]" {
+		t.Errorf("tuples => %v", got)
+	}
+
+	var errorTest = []rel.Relation{
+		quotes.Restrict(earlier),
+		Lateral(trades, quotes.Restrict(Attribute("QTime").LE(Outer("Date"))), resultTup{}),
+		Lateral(trades, trades, tradeTup{}),
+		Lateral(trades, rel.New([]quoteTup{}, nil), resultTup{}),
+	}
+	for i, r := range errorTest {
+		if err := drainErr(r); err == nil {
+			t.Errorf("%d has Err() => nil, want an error", i)
+		}
+	}
+}
+
+// drainErr reads every tuple of r, and returns its error
+func drainErr(r rel.Relation) error {
+	return forEach(r, func(reflect.Value) error { return nil })
+}
@@ -0,0 +1,153 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Lazy is an attribute whose value is fetched on demand, instead of being
+// scanned with the rest of the tuple.  It is meant for columns which can be
+// very large, like blobs and documents, so that enumerating a wide table
+// doesn't read all of them.  When a relation from New has a Lazy attribute,
+// the column is left out of the query, and each tuple instead refers to its
+// row by the relation's first candidate key, which has to be part of the
+// heading.  The value is read by Bytes or Text, each of which executes a
+// query.  Lazy values are only supported for relations read directly from a
+// table, optionally restricted, projected or renamed.
+type Lazy struct {
+	ref *lazyRef
+}
+
+// lazyRef is the location of a lazy value, or the value itself
+type lazyRef struct {
+	db    QueryerContext
+	query string
+	args  []interface{}
+
+	// opts audits the query
+	opts *options
+
+	// data is the value, if it is held in memory
+	data []byte
+}
+
+// LazyBytes returns a Lazy holding a value in memory, which can be used to
+// write large values with Insert.
+func LazyBytes(b []byte) Lazy {
+	return Lazy{&lazyRef{data: b}}
+}
+
+// Bytes fetches the value.  The zero Lazy has an empty value.
+func (l Lazy) Bytes() ([]byte, error) {
+	if l.ref == nil {
+		return nil, nil
+	}
+	if l.ref.db == nil {
+		return l.ref.data, nil
+	}
+	var b []byte
+	err := l.ref.opts.queryRow(context.Background(), l.ref.db, l.ref.query, l.ref.args, &b)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("relsql: row of lazy value no longer exists")
+	}
+	return b, err
+}
+
+// Text fetches the value as a string.
+func (l Lazy) Text() (string, error) {
+	b, err := l.Bytes()
+	return string(b), err
+}
+
+// Value implements the driver.Valuer interface, so that lazy values can be
+// written to another table.  It fetches the value.
+func (l Lazy) Value() (driver.Value, error) {
+	b, err := l.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		b = []byte{}
+	}
+	return b, nil
+}
+
+// lazyType is the type of Lazy
+var lazyType = reflect.TypeOf(Lazy{})
+
+// lazyLoader sets the lazy fields of scanned tuples
+type lazyLoader struct {
+	r1 *sqlTable
+	db QueryerContext
+
+	// fields are the indexes of the lazy fields, and queries are the queries
+	// that fetch each of them
+	fields  []int
+	queries []string
+
+	// keys are the indexes of the fields which identify a row
+	keys []int
+}
+
+// lazyLoader returns the loader for the relation's lazy fields, or nil if it
+// has none.
+func (r1 *sqlTable) lazyLoader() (*lazyLoader, error) {
+	e := reflect.TypeOf(r1.zero)
+	l := &lazyLoader{r1: r1, db: r1.queryer()}
+	for i := 0; i < e.NumField(); i++ {
+		if e.Field(i).Type == lazyType {
+			l.fields = append(l.fields, i)
+		}
+	}
+	if len(l.fields) == 0 {
+		return nil, nil
+	}
+	table, ok := r1.src.(tableSource)
+	if !ok {
+		return nil, fmt.Errorf("relsql: lazy attributes of %v require a table source", e)
+	}
+	if len(r1.cKeys) == 0 || !r1.sourceDistinct {
+		return nil, fmt.Errorf("relsql: lazy attributes of %v require a candidate key", e)
+	}
+	d := r1.dialect()
+	conds := make([]string, len(r1.cKeys[0]))
+	for i, att := range r1.cKeys[0] {
+		f, _ := e.FieldByName(string(att))
+		if f.Type == lazyType {
+			return nil, fmt.Errorf("relsql: lazy attribute %s can't be part of a key", att)
+		}
+		l.keys = append(l.keys, f.Index[0])
+		conds[i] = r1.cols[f.Index[0]].name + " = " + d.Placeholder(i+1)
+	}
+	where := strings.Join(conds, " AND ")
+	for _, i := range l.fields {
+		l.queries = append(l.queries, "SELECT "+r1.cols[i].name+" FROM "+quoteTable(d, string(table))+" WHERE "+where)
+	}
+	return l, nil
+}
+
+// load sets the lazy fields of a tuple to refer to its row, with the queries
+// that fetch them checked by the relation's policies
+func (l *lazyLoader) load(d Dialect, tup reflect.Value) error {
+	args := make([]interface{}, len(l.keys))
+	for i, j := range l.keys {
+		v, err := encodeArg(d, tup.Field(j).Interface())
+		if err != nil {
+			return err
+		}
+		args[i] = v
+	}
+	args = bindArgs(d, args)
+	for i, j := range l.fields {
+		q, args, err := l.r1.applyPolicies(l.queries[i], args)
+		if err != nil {
+			return err
+		}
+		tup.Field(j).Set(reflect.ValueOf(Lazy{&lazyRef{db: l.db, query: q, args: args, opts: &l.r1.opts}}))
+	}
+	return nil
+}
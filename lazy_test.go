@@ -0,0 +1,57 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"testing"
+)
+
+// test scanning and fetching lazy attributes
+func TestLazy(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type docTup struct {
+		ID   int
+		Body Lazy
+	}
+	if err := CreateTable(db, "docs", docTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	docs := rel.New([]docTup{{1, LazyBytes([]byte("first"))}, {2, LazyBytes([]byte("second"))}}, [][]string{[]string{"ID"}})
+	if _, err := Insert(db, "docs", docs); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	r := New(db, "docs", docTup{}, [][]string{[]string{"ID"}}).Restrict(Attribute("ID").EQ(2))
+	q, _, _ := r.(*sqlTable).queryString()
+	if want := "SELECT ID, NULL AS Body FROM docs WHERE ID = ?"; q != want {
+		t.Errorf("query => %v, want %v", q, want)
+	}
+	ch := make(chan docTup)
+	r.TupleChan(ch)
+	var res []docTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if err := r.Err(); err != nil || len(res) != 1 {
+		t.Errorf("lazy docs => %v, %v, want 1 tuple", res, err)
+		return
+	}
+	if body, err := res[0].Body.Text(); body != "second" || err != nil {
+		t.Errorf("Text() => %v, %v, want second", body, err)
+	}
+
+	// without a key, the rows can't be found again
+	noKey := New(db, "docs", docTup{}, nil)
+	rel.Card(noKey)
+	if noKey.Err() == nil {
+		t.Errorf("lazy attribute without a key has Err() => nil")
+	}
+}
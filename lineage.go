@@ -0,0 +1,105 @@
+package relsql
+
+import (
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sort"
+)
+
+// Lineage returns the source columns that each attribute of a relation is
+// computed from, as sorted table.column names.  Attributes of relations that
+// aren't from this package have no source columns.  Operations that are
+// evaluated client side are assumed to pass attributes through by name, so
+// the attributes computed by a client side Map or GroupBy may be reported
+// with the columns of the attributes they replaced.
+func Lineage(r rel.Relation) map[rel.Attribute][]string {
+	res := make(map[rel.Attribute][]string)
+	for att, cols := range lineage(newNode(r)) {
+		names := make([]string, 0, len(cols))
+		for c := range cols {
+			names = append(names, c)
+		}
+		sort.Strings(names)
+		res[att] = names
+	}
+	for _, att := range rel.FieldNames(reflect.TypeOf(r.Zero())) {
+		if _, ok := res[att]; !ok {
+			res[att] = nil
+		}
+	}
+	return res
+}
+
+// lineage returns the set of source columns of each attribute of the node
+func lineage(n Node) map[rel.Attribute]map[string]bool {
+	res := make(map[rel.Attribute]map[string]bool)
+	switch n := n.(type) {
+	case *Query:
+		for _, c := range n.Columns {
+			res[c.Attribute] = sourceColumns(n.Source, c)
+		}
+	case *Client:
+		for _, att := range rel.FieldNames(reflect.TypeOf(n.Relation.Zero())) {
+			res[att] = make(map[string]bool)
+		}
+		for _, in := range n.Inputs {
+			for att, cols := range lineage(in) {
+				if s, ok := res[att]; ok {
+					for c := range cols {
+						s[c] = true
+					}
+				}
+			}
+		}
+	}
+	return res
+}
+
+// sourceColumns returns the columns of the tables under the source that
+// produce the column c.
+func sourceColumns(src Node, c Column) map[string]bool {
+	res := make(map[string]bool)
+	switch src := src.(type) {
+	case *Table:
+		res[src.Name+"."+c.Name] = true
+	case *Partitions:
+		for _, t := range src.Tables {
+			res[t.Name+"."+c.Name] = true
+		}
+	case *Call:
+		res[src.Proc+"."+c.Name] = true
+	case *SetOp:
+		for _, q := range []*Query{src.Left, src.Right} {
+			for col := range lineage(q)[rel.Attribute(c.Name)] {
+				res[col] = true
+			}
+		}
+	case *Join:
+		q := src.Left
+		if c.Table == "t2" {
+			q = src.Right
+		}
+		res = lineage(q)[rel.Attribute(c.Name)]
+	case *Window:
+		res = lineage(src.Input)[rel.Attribute(c.Name)]
+	case *Summary:
+		att := c.Name
+		for _, a := range src.Aggs {
+			if a.As == c.Name {
+				// aggregates derive from the aggregated attribute, and
+				// counts from no column at all
+				att = a.att
+			}
+		}
+		if att != "" {
+			res = lineage(src.Input)[rel.Attribute(att)]
+		}
+	case *LateralJoin:
+		q := src.Left
+		if c.Table == "li" {
+			q = src.Right
+		}
+		res = lineage(q)[rel.Attribute(c.Name)]
+	}
+	return res
+}
@@ -0,0 +1,69 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"testing"
+)
+
+// test tracing the attributes of relations back to their source columns
+func TestLineage(t *testing.T) {
+	type supplierTup struct {
+		SNO    int
+		SName  string
+		Status int
+		City   string
+	}
+	type shipmentTup struct {
+		SNO int
+		PNO int
+		Qty int
+	}
+	type cityTup struct {
+		SNO  int
+		Town string
+	}
+	type cityShipTup struct {
+		SNO  int
+		Town string
+		PNO  int
+		Qty  int
+	}
+	suppliers := New(nil, "suppliers", supplierTup{}, [][]string{[]string{"SNO"}})
+	shipments := New(nil, "shipments", shipmentTup{}, [][]string{[]string{"SNO", "PNO"}})
+	archive := New(nil, "archive", shipmentTup{}, [][]string{[]string{"SNO", "PNO"}})
+	cities := suppliers.Project(struct {
+		SNO  int
+		City string
+	}{}).Rename(cityTup{})
+
+	var lineageTest = []struct {
+		r    rel.Relation
+		want map[rel.Attribute][]string
+	}{
+		{cities, map[rel.Attribute][]string{
+			"SNO":  {"suppliers.SNO"},
+			"Town": {"suppliers.City"},
+		}},
+		{cities.Join(shipments.Union(archive), cityShipTup{}), map[rel.Attribute][]string{
+			"SNO":  {"suppliers.SNO"},
+			"Town": {"suppliers.City"},
+			"PNO":  {"archive.PNO", "shipments.PNO"},
+			"Qty":  {"archive.Qty", "shipments.Qty"},
+		}},
+		{shipments.Restrict(rel.Attribute("Qty").GT(100)), map[rel.Attribute][]string{
+			"SNO": {"shipments.SNO"},
+			"PNO": {"shipments.PNO"},
+			"Qty": {"shipments.Qty"},
+		}},
+		{rel.New([]cityTup{}, nil), map[rel.Attribute][]string{
+			"SNO":  nil,
+			"Town": nil,
+		}},
+	}
+	for i, tt := range lineageTest {
+		if got := Lineage(tt.r); fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%d has Lineage() => %v, want %v", i, got, tt.want)
+		}
+	}
+}
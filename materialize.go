@@ -0,0 +1,155 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"time"
+)
+
+// WriteMode is how Materialize treats the existing rows of its target table.
+type WriteMode int
+
+const (
+	// Append adds the tuples to the rows already in the table.
+	Append WriteMode = iota
+
+	// Truncate deletes the rows in the table before the tuples are written,
+	// within the same transaction.
+	Truncate
+
+	// Swap writes the tuples into a new staging table, and then replaces the
+	// target table with it by renaming.  Readers see either the old table or
+	// the complete new one, never a partial load, on databases with
+	// transactional DDL, and on MySQL, which renames both tables at once.
+	// The staging table is created with the target's columns, but not its
+	// indexes or constraints.  On databases without transactional DDL, where
+	// it isn't rolled back, it is dropped if the load fails.
+	Swap
+)
+
+// String returns the name of the write mode
+func (m WriteMode) String() string {
+	switch m {
+	case Append:
+		return "Append"
+	case Truncate:
+		return "Truncate"
+	case Swap:
+		return "Swap"
+	}
+	return "WriteMode(?)"
+}
+
+// WithWriteMode sets how Materialize treats the existing rows of the target
+// table.  The default is Append.
+func WithWriteMode(m WriteMode) Option {
+	return func(o *options) {
+		o.writeMode = m
+	}
+}
+
+// swapper is implemented by dialects that need different statements to
+// replace one table with another, for example a single atomic RENAME TABLE.
+type swapper interface {
+	// SwapStatements returns the statements that replace the table with the
+	// staging table, renaming the replaced table to old, which is dropped
+	// afterwards.  The names are not quoted.
+	SwapStatements(tableName, staging, old string) []string
+}
+
+// swapStatements returns the statements that replace the table with the
+// staging table.  The new name of a renamed table can't have a schema or
+// dataset, because it stays in the one it is in.
+func swapStatements(d Dialect, tableName, staging, old string) []string {
+	if s, ok := d.(swapper); ok {
+		return s.SwapStatements(tableName, staging, old)
+	}
+	return []string{
+		"ALTER TABLE " + quoteTable(d, tableName) + " RENAME TO " + quoteTable(d, unqualified(old)),
+		"ALTER TABLE " + quoteTable(d, staging) + " RENAME TO " + quoteTable(d, unqualified(tableName)),
+	}
+}
+
+// unqualified returns the name of a table without its schema, or the table
+// of a BigQuery path without its project and dataset.  A path that is quoted
+// as a whole is unquoted.
+func unqualified(tableName string) string {
+	tableName = strings.Trim(tableName, "`")
+	return tableName[strings.LastIndex(tableName, ".")+1:]
+}
+
+// Materialize writes every tuple of r into the table, treating the rows
+// already in it according to the session's write mode.  The duration of the
+// result includes clearing or replacing the table.
+func (s *Session) Materialize(tableName string, r rel.Relation) (WriteResult, error) {
+	start := time.Now()
+	res, err := s.materialize(tableName, r)
+	res.Duration = time.Since(start)
+	return res, err
+}
+
+// materialize writes the tuples of r according to the write mode
+func (s *Session) materialize(tableName string, r rel.Relation) (WriteResult, error) {
+	switch s.opts.writeMode {
+	case Append:
+		return s.Insert(tableName, r)
+	case Truncate:
+		if _, err := s.exec("DELETE FROM " + quoteTable(s.dialect(), tableName)); err != nil {
+			return WriteResult{}, err
+		}
+		return s.Insert(tableName, r)
+	case Swap:
+		staging := tableName + "_relsql_staging"
+		old := tableName + "_relsql_old"
+		if _, err := s.exec("CREATE TABLE " + quoteTable(s.dialect(), staging) + " AS SELECT * FROM " + quoteTable(s.dialect(), tableName) + " WHERE 1 = 0"); err != nil {
+			return WriteResult{}, err
+		}
+		res, err := s.Insert(staging, r)
+		if err != nil {
+			return res, s.dropStaging(staging, err)
+		}
+		for i, stmt := range swapStatements(s.dialect(), tableName, staging, old) {
+			if _, err := s.exec(stmt); err != nil {
+				if i == 0 {
+					// nothing has been renamed yet
+					err = s.dropStaging(staging, err)
+				}
+				return res, err
+			}
+		}
+		_, err = s.exec("DROP TABLE " + quoteTable(s.dialect(), old))
+		return res, err
+	}
+	return WriteResult{}, fmt.Errorf("relsql: unknown write mode %v", s.opts.writeMode)
+}
+
+// dropStaging drops the staging table of a swap that failed with err, if the
+// dialect's DDL isn't transactional, so that rolling back the session
+// wouldn't remove it.  It returns err, or the error from dropping the table
+// along with it.
+func (s *Session) dropStaging(staging string, err error) error {
+	if transactionalDDL(s.dialect()) {
+		return err
+	}
+	if _, derr := s.exec("DROP TABLE " + quoteTable(s.dialect(), staging)); derr != nil {
+		return fmt.Errorf("%v, and dropping %s failed: %v", err, staging, derr)
+	}
+	return err
+}
+
+// Materialize writes every tuple of r into the table in its own session,
+// treating the rows already in it according to the write mode option.
+func Materialize(db *sql.DB, tableName string, r rel.Relation, opts ...Option) (WriteResult, error) {
+	s, err := Begin(db, opts...)
+	if err != nil {
+		return WriteResult{}, err
+	}
+	res, err := s.Materialize(tableName, r)
+	if err != nil {
+		s.Rollback()
+		return res, err
+	}
+	return res, s.Commit()
+}
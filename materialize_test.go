@@ -0,0 +1,128 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test each of the write modes
+func TestMaterialize(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+	create table cities (Name text not null);
+	insert into cities (Name) values ('London'), ('Paris');
+	`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	type cityTup struct {
+		Name string
+	}
+	cities := rel.New([]cityTup{{"Athens"}, {"Rome"}, {"Oslo"}}, [][]string{[]string{"Name"}})
+
+	var modeTest = []struct {
+		mode WriteMode
+		rows int
+	}{
+		{Append, 5},
+		{Truncate, 3},
+		{Swap, 3},
+		{Append, 6},
+	}
+	for i, tt := range modeTest {
+		if _, err := Materialize(db, "cities", cities, WithWriteMode(tt.mode)); err != nil {
+			t.Errorf("%d has Materialize(%v) => %v", i, tt.mode, err)
+			continue
+		}
+		var n int
+		db.QueryRow("select count(*) from cities").Scan(&n)
+		if n != tt.rows {
+			t.Errorf("%d has %d rows after Materialize(%v), want %d", i, n, tt.mode, tt.rows)
+		}
+	}
+}
+
+// test the statements that swap in a staging table in each dialect
+func TestSwapStatements(t *testing.T) {
+	var swapTest = []struct {
+		d         Dialect
+		tableName string
+		want      []string
+	}{
+		{ANSI, "cities", []string{
+			"ALTER TABLE cities RENAME TO cities_old",
+			"ALTER TABLE cities_staging RENAME TO cities",
+		}},
+		{MySQL, "cities", []string{
+			"RENAME TABLE cities TO cities_old, cities_staging TO cities",
+		}},
+		{Postgres, "geo.cities", []string{
+			"ALTER TABLE geo.cities RENAME TO cities_old",
+			"ALTER TABLE geo.cities_staging RENAME TO cities",
+		}},
+		{BigQuery, "geo.cities", []string{
+			"ALTER TABLE `geo.cities` RENAME TO `cities_old`",
+			"ALTER TABLE `geo.cities_staging` RENAME TO `cities`",
+		}},
+	}
+	for i, tt := range swapTest {
+		stmts := swapStatements(tt.d, tt.tableName, tt.tableName+"_staging", tt.tableName+"_old")
+		if !reflect.DeepEqual(stmts, tt.want) {
+			t.Errorf("%d has swapStatements() => %q, want %q", i, stmts, tt.want)
+		}
+	}
+}
+
+// test that the staging table of a failed swap is dropped when the dialect's
+// DDL isn't transactional
+func TestSwapDropStaging(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:swapstaging?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	if _, err := db.Exec("create table towns (Name text not null)"); err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	// the staging table has no Pop column, so the load fails
+	type townTup struct {
+		Name string
+		Pop  int
+	}
+	towns := rel.New([]townTup{{"Bath", 1}}, [][]string{[]string{"Name"}})
+	var stagingTest = []struct {
+		d       Dialect
+		staging bool
+	}{
+		{SQLite, true},
+		{ddlDialect{}, false},
+	}
+	for i, tt := range stagingTest {
+		s, err := Begin(db, WithDialect(tt.d), WithWriteMode(Swap))
+		if err != nil {
+			t.Errorf("Begin() => %v", err)
+			return
+		}
+		if _, err := s.Materialize("towns", towns); err == nil {
+			t.Errorf("%d has Materialize() => nil error", i)
+		}
+		var n int
+		if err := s.tx.QueryRow("select count(*) from sqlite_master where name = 'towns_relsql_staging'").Scan(&n); err != nil || (n == 1) != tt.staging {
+			t.Errorf("%d has %d staging tables after a failed swap, %v", i, n, err)
+		}
+		s.Rollback()
+	}
+}
@@ -0,0 +1,31 @@
+//go:build !go1.24
+
+package relsql
+
+import (
+	"database/sql"
+	"sync"
+)
+
+// metrics holds the metrics of each database that relsql has queried.  Go
+// releases before 1.24 have no weak pointers, so they are kept for the life
+// of the program, like the database handles usually are.
+var metrics sync.Map
+
+// metricsFor returns the metrics of a database, or nil if there is none
+func metricsFor(db *sql.DB) *dbMetrics {
+	if db == nil {
+		return nil
+	}
+	m, _ := metrics.LoadOrStore(db, new(dbMetrics))
+	return m.(*dbMetrics)
+}
+
+// loadMetrics returns the metrics of a database, or nil if it hasn't been
+// queried
+func loadMetrics(db *sql.DB) *dbMetrics {
+	if m, ok := metrics.Load(db); ok {
+		return m.(*dbMetrics)
+	}
+	return nil
+}
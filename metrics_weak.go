@@ -0,0 +1,40 @@
+//go:build go1.24
+
+package relsql
+
+import (
+	"database/sql"
+	"runtime"
+	"sync"
+	"weak"
+)
+
+// metrics holds the metrics of each database that relsql has queried, by a
+// weak pointer to the database, so that they don't keep it from being
+// collected.  The metrics of a database are removed once it is collected.
+var metrics sync.Map
+
+// metricsFor returns the metrics of a database, or nil if there is none
+func metricsFor(db *sql.DB) *dbMetrics {
+	if db == nil {
+		return nil
+	}
+	k := weak.Make(db)
+	if m, ok := metrics.Load(k); ok {
+		return m.(*dbMetrics)
+	}
+	m, loaded := metrics.LoadOrStore(k, new(dbMetrics))
+	if !loaded {
+		runtime.AddCleanup(db, func(k weak.Pointer[sql.DB]) { metrics.Delete(k) }, k)
+	}
+	return m.(*dbMetrics)
+}
+
+// loadMetrics returns the metrics of a database, or nil if it hasn't been
+// queried
+func loadMetrics(db *sql.DB) *dbMetrics {
+	if m, ok := metrics.Load(weak.Make(db)); ok {
+		return m.(*dbMetrics)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+//go:build go1.24
+
+package relsql
+
+import (
+	"database/sql"
+	"runtime"
+	"testing"
+	"time"
+	"weak"
+)
+
+// test that the metrics of a database are removed once it is collected
+func TestStatsCollected(t *testing.T) {
+	k := func() weak.Pointer[sql.DB] {
+		db, err := sql.Open("sqlite3", "file:statscollected?mode=memory&cache=shared")
+		if err != nil {
+			t.Errorf("Open() => %v", err)
+			return weak.Pointer[sql.DB]{}
+		}
+		defer db.Close()
+		type tup struct {
+			ID int
+		}
+		drainErr(New(db, "missing", tup{}, nil))
+		if s := DatabaseStats(db); s.Queries != 1 {
+			t.Errorf("DatabaseStats() has %d queries, want 1", s.Queries)
+		}
+		return weak.Make(db)
+	}()
+	for i := 0; i < 100; i++ {
+		runtime.GC()
+		if _, ok := metrics.Load(k); !ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Errorf("metrics of a collected database were kept")
+}
@@ -0,0 +1,275 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// WithColumns sets the names of the columns of the attributes, in heading
+// order, for tables whose columns aren't named like the attributes.  It only
+// applies to the relation from New, or a constructor that calls it, and
+// relations derived from it keep reading from the same columns.
+func WithColumns(cols ...string) Option {
+	return func(o *options) {
+		o.columns = cols
+	}
+}
+
+// Model is the relsql form of the model of an ORM: the tuple type of its
+// attributes, the table and columns they are read from, and the candidate
+// keys of the table.
+type Model struct {
+	Table string
+
+	// Zero is the zero value of the tuple type
+	Zero interface{}
+
+	// Columns holds the column of each attribute
+	Columns []string
+
+	Keys [][]string
+}
+
+// New returns a relation that reads the model's table, like New.
+func (m *Model) New(db *sql.DB, opts ...Option) rel.Relation {
+	return New(db, m.Table, m.Zero, m.Keys, append([]Option{WithColumns(m.Columns...)}, opts...)...)
+}
+
+// GORMModel returns the model of a struct with GORM tags, like those passed
+// to gorm's AutoMigrate.  It follows GORM's conventions:
+//
+//   - the table is the result of a TableName method, or the plural snake
+//     case name of the type
+//   - columns are set by the column tag, or are the snake case names of the
+//     fields
+//   - the fields of embedded structs, like gorm.Model, and of fields tagged
+//     embedded, are attributes, with any embeddedPrefix
+//   - fields tagged with "-", and associations, are not attributes
+//   - the key is the fields tagged primaryKey, or the ID field, and fields
+//     tagged unique or uniqueIndex are further candidate keys, where the
+//     fields of a named uniqueIndex form a single key
+//
+// If every field of the struct is an attribute, the tuple type is the type of
+// the struct, and otherwise it is a struct with a field for each attribute.
+func GORMModel(model interface{}) (*Model, error) {
+	e := reflect.TypeOf(model)
+	if e != nil && e.Kind() == reflect.Ptr {
+		e = e.Elem()
+	}
+	if e == nil || e.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("relsql: model %v is not a struct", e)
+	}
+	m := &Model{Table: gormTable(model, e)}
+	var fields []reflect.StructField
+	var primary []string
+	indexes := make(map[string][]string)
+	var indexNames []string
+	seen := make(map[string]bool)
+	var dup string
+	var visit func(e reflect.Type, prefix string)
+	visit = func(e reflect.Type, prefix string) {
+		for i := 0; i < e.NumField(); i++ {
+			f := e.Field(i)
+			tags := gormTags(f.Tag.Get("gorm"))
+			if _, ok := tags["-"]; ok || f.PkgPath != "" && !f.Anonymous {
+				continue
+			}
+			if _, ok := tags["EMBEDDED"]; ok || f.Anonymous && f.Type.Kind() == reflect.Struct && !isValue(f.Type) {
+				visit(f.Type, prefix+tags["EMBEDDEDPREFIX"])
+				continue
+			}
+			if !isValue(f.Type) {
+				// an association with another model
+				continue
+			}
+			if seen[f.Name] {
+				dup = f.Name
+				continue
+			}
+			seen[f.Name] = true
+			col, ok := tags["COLUMN"]
+			if !ok {
+				col = snakeCase(f.Name)
+			}
+			m.Columns = append(m.Columns, prefix+col)
+			fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type, Tag: relsqlTag(f.Tag)})
+			if _, ok := tags["PRIMARYKEY"]; ok {
+				primary = append(primary, f.Name)
+			} else if _, ok := tags["PRIMARY_KEY"]; ok {
+				primary = append(primary, f.Name)
+			}
+			if _, ok := tags["UNIQUE"]; ok {
+				m.Keys = append(m.Keys, []string{f.Name})
+			}
+			if name, ok := tags["UNIQUEINDEX"]; ok {
+				if name == "" {
+					m.Keys = append(m.Keys, []string{f.Name})
+				} else {
+					if _, ok := indexes[name]; !ok {
+						indexNames = append(indexNames, name)
+					}
+					indexes[name] = append(indexes[name], f.Name)
+				}
+			}
+		}
+	}
+	visit(e, "")
+	if dup != "" {
+		return nil, fmt.Errorf("relsql: model %v has more than one field named %s", e, dup)
+	}
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("relsql: model %v has no columns", e)
+	}
+	for _, name := range indexNames {
+		m.Keys = append(m.Keys, indexes[name])
+	}
+	if len(primary) == 0 {
+		for _, f := range fields {
+			if f.Name == "ID" {
+				primary = []string{"ID"}
+			}
+		}
+	}
+	if len(primary) > 0 {
+		m.Keys = append([][]string{primary}, m.Keys...)
+	}
+	m.Zero = modelZero(e, fields)
+	return m, nil
+}
+
+// EntModel returns the model of an entity generated by ent, with the table
+// and columns from the entity's generated package, for example
+//
+//	relsql.EntModel(ent.User{}, user.Table, user.Columns)
+//
+// The attributes are the exported fields of the entity other than its edges,
+// which are in the same order as the columns.  The key is the ID attribute.
+func EntModel(model interface{}, table string, columns []string) (*Model, error) {
+	e := reflect.TypeOf(model)
+	if e != nil && e.Kind() == reflect.Ptr {
+		e = e.Elem()
+	}
+	if e == nil || e.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("relsql: model %v is not a struct", e)
+	}
+	var fields []reflect.StructField
+	for i := 0; i < e.NumField(); i++ {
+		f := e.Field(i)
+		if f.PkgPath != "" || f.Anonymous || f.Name == "Edges" || f.Tag.Get("json") == "-" {
+			continue
+		}
+		fields = append(fields, reflect.StructField{Name: f.Name, Type: f.Type, Tag: relsqlTag(f.Tag)})
+	}
+	if len(fields) != len(columns) {
+		return nil, fmt.Errorf("relsql: model %v has %d fields for %d columns", e, len(fields), len(columns))
+	}
+	m := &Model{Table: table, Zero: modelZero(e, fields), Columns: columns}
+	if _, ok := e.FieldByName("ID"); ok {
+		m.Keys = [][]string{[]string{"ID"}}
+	}
+	return m, nil
+}
+
+// modelZero returns the zero value of the tuple type of a model, which is
+// the model's own type if its fields are the attributes.
+func modelZero(e reflect.Type, fields []reflect.StructField) interface{} {
+	if checkZero(e) == nil && e.NumField() == len(fields) {
+		return reflect.Zero(e).Interface()
+	}
+	return reflect.Zero(reflect.StructOf(fields)).Interface()
+}
+
+// relsqlTag returns the relsql part of a struct tag, so that transformers
+// are kept in the tuple type of a model
+func relsqlTag(tag reflect.StructTag) reflect.StructTag {
+	if v, ok := tag.Lookup("relsql"); ok {
+		return reflect.StructTag(`relsql:"` + v + `"`)
+	}
+	return ""
+}
+
+// isValue returns true if fields of type t hold a column's value, and false
+// if they are associations or embedded structs
+func isValue(t reflect.Type) bool {
+	if t == timeType || reflect.PtrTo(t).Implements(scannerType) || lookupCodec(t) != nil {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return isValue(t.Elem())
+	case reflect.Struct, reflect.Map, reflect.Interface:
+		return false
+	case reflect.Slice, reflect.Array:
+		return t.Elem().Kind() == reflect.Uint8
+	}
+	return true
+}
+
+// gormTags parses a gorm tag, which has settings like "column:name;unique",
+// into a map from the upper case name of each setting to its value.
+func gormTags(tag string) map[string]string {
+	res := make(map[string]string)
+	for _, s := range strings.Split(tag, ";") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		kv := strings.SplitN(s, ":", 2)
+		v := ""
+		if len(kv) == 2 {
+			v = strings.TrimSpace(kv[1])
+		}
+		res[strings.ToUpper(strings.TrimSpace(kv[0]))] = v
+	}
+	return res
+}
+
+// tabler is the interface of models with their own table names
+type tabler interface {
+	TableName() string
+}
+
+// gormTable returns the table of a model
+func gormTable(model interface{}, e reflect.Type) string {
+	if t, ok := model.(tabler); ok {
+		return t.TableName()
+	}
+	if t, ok := reflect.New(e).Interface().(tabler); ok {
+		return t.TableName()
+	}
+	return plural(snakeCase(e.Name()))
+}
+
+// snakeCase returns the snake case form of a Go name, in which initialisms
+// are single words, like user_id for UserID.
+func snakeCase(name string) string {
+	rs := []rune(name)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := rs[i-1]
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && i+1 < len(rs) && unicode.IsLower(rs[i+1]) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// plural returns the plural of an english noun, for the common cases
+func plural(s string) string {
+	switch {
+	case s == "":
+		return s
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsAny(s[len(s)-2:len(s)-1], "aeiou"):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"), strings.HasSuffix(s, "z"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	}
+	return s + "s"
+}
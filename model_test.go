@@ -0,0 +1,177 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// gormBase is like gorm.Model
+type gormBase struct {
+	ID        uint `gorm:"primarykey"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt sql.NullTime `gorm:"index"`
+}
+
+type gormCompany struct {
+	ID   int
+	Name string
+}
+
+type gormAddress struct {
+	Street string
+	City   string
+}
+
+type gormUser struct {
+	gormBase
+	Name      string      `gorm:"column:user_name"`
+	Email     string      `gorm:"uniqueIndex"`
+	TenantID  int         `gorm:"uniqueIndex:idx_tenant_login"`
+	Login     string      `gorm:"uniqueIndex:idx_tenant_login"`
+	Nick      *string     `relsql:"upper"`
+	Home      gormAddress `gorm:"embedded;embeddedPrefix:home_"`
+	CompanyID int
+	Company   gormCompany
+	Friends   []*gormUser
+	Scratch   string `gorm:"-"`
+}
+
+type gormCategory struct {
+	Code  string `gorm:"primaryKey"`
+	Label string
+}
+
+func (gormCategory) TableName() string { return "category_codes" }
+
+// test deriving the models of gorm structs
+func TestGORMModel(t *testing.T) {
+	var modelTest = []struct {
+		model   interface{}
+		table   string
+		fields  []string
+		columns []string
+		keys    [][]string
+		own     bool
+	}{
+		{
+			gormUser{},
+			"gorm_users",
+			[]string{"ID", "CreatedAt", "UpdatedAt", "DeletedAt", "Name", "Email", "TenantID", "Login", "Nick", "Street", "City", "CompanyID"},
+			[]string{"id", "created_at", "updated_at", "deleted_at", "user_name", "email", "tenant_id", "login", "nick", "home_street", "home_city", "company_id"},
+			[][]string{{"ID"}, {"Email"}, {"TenantID", "Login"}},
+			false,
+		},
+		{
+			&gormCategory{},
+			"category_codes",
+			[]string{"Code", "Label"},
+			[]string{"code", "label"},
+			[][]string{{"Code"}},
+			true,
+		},
+		{
+			gormCompany{},
+			"gorm_companies",
+			[]string{"ID", "Name"},
+			[]string{"id", "name"},
+			[][]string{{"ID"}},
+			true,
+		},
+	}
+	for i, tt := range modelTest {
+		m, err := GORMModel(tt.model)
+		if err != nil {
+			t.Errorf("%d has error %v", i, err)
+			continue
+		}
+		e := reflect.TypeOf(m.Zero)
+		var fields []string
+		for j := 0; j < e.NumField(); j++ {
+			fields = append(fields, e.Field(j).Name)
+		}
+		own := e == reflect.Indirect(reflect.ValueOf(tt.model)).Type()
+		if m.Table != tt.table || !reflect.DeepEqual(fields, tt.fields) || !reflect.DeepEqual(m.Columns, tt.columns) ||
+			!reflect.DeepEqual(m.Keys, tt.keys) || own != tt.own {
+			t.Errorf("%d has GORMModel() => %+v with fields %v, want %+v", i, m, fields, tt)
+		}
+	}
+	if f, _ := reflect.TypeOf(mustModel(t, gormUser{}).Zero).FieldByName("Nick"); f.Tag != `relsql:"upper"` {
+		t.Errorf("Nick has tag %q", f.Tag)
+	}
+
+	type dupUser struct {
+		gormBase
+		ID int
+	}
+	for i, model := range []interface{}{3, struct{ Edges []gormUser }{}, dupUser{}} {
+		if _, err := GORMModel(model); err == nil {
+			t.Errorf("%d has no error for %T", i, model)
+		}
+	}
+}
+
+// mustModel returns the model of a gorm struct
+func mustModel(t *testing.T, model interface{}) *Model {
+	m, err := GORMModel(model)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// entUser is like an ent generated entity
+type entUser struct {
+	config       struct{}
+	ID           int       `json:"id,omitempty"`
+	Age          int       `json:"age,omitempty"`
+	Name         string    `json:"name,omitempty"`
+	Created      time.Time `json:"created,omitempty"`
+	Edges        struct{}  `json:"edges"`
+	selectValues map[string]interface{}
+}
+
+// test deriving the models of ent entities, and reading with them
+func TestEntModel(t *testing.T) {
+	m, err := EntModel(&entUser{}, "users", []string{"id", "age", "name", "created_at"})
+	if err != nil {
+		t.Errorf("EntModel() => %v", err)
+		return
+	}
+	if !reflect.DeepEqual(m.Keys, [][]string{{"ID"}}) || m.Table != "users" || reflect.TypeOf(m.Zero).NumField() != 4 {
+		t.Errorf("EntModel() => %+v", m)
+	}
+	if _, err := EntModel(entUser{}, "users", []string{"id", "age"}); err == nil {
+		t.Errorf("EntModel() with missing columns has no error")
+	}
+
+	db, err := sql.Open("sqlite3", "file:entmodel?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER PRIMARY KEY, age INTEGER, name TEXT, created_at TIMESTAMP);
+		INSERT INTO users VALUES (1, 30, 'a', '2020-01-02 03:04:05'), (2, 40, 'b', '2021-01-02 03:04:05')`); err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	users := m.New(db).Restrict(Attribute("Age").GT(35))
+	if q, _, err := SQL(users.Project(struct{ Name string }{})); err != nil || q != "SELECT DISTINCT name FROM users WHERE age > ?" {
+		t.Errorf("SQL() => %q, %v", q, err)
+	}
+	type nameTup struct {
+		Name    string
+		Created time.Time
+	}
+	got, err := Preview[nameTup](context.Background(), users.Project(nameTup{}), 10)
+	if err != nil || len(got) != 1 || got[0].Name != "b" || got[0].Created.Year() != 2021 {
+		t.Errorf("Preview() => %v, %v", got, err)
+	}
+	if r := New(db, "users", entUser{}, nil, WithColumns("id")); r.Err() == nil {
+		t.Errorf("WithColumns() with missing columns has no error")
+	}
+}
@@ -0,0 +1,113 @@
+package relsql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// MySQL is the dialect for MySQL 8 and MariaDB.  Rows are limited with LIMIT,
+// and times are truncated with DATE and DATE_FORMAT, because MySQL has no
+// DATE_TRUNC.
+var MySQL Dialect = mysqlDialect{}
+
+// mysqlDialect is the dialect for MySQL
+type mysqlDialect struct{}
+
+// Name returns the name of the dialect
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+// Placeholder returns the placeholder for the i'th argument of a query
+func (mysqlDialect) Placeholder(i int) string {
+	return "?"
+}
+
+// ReadTx returns true, because a read of several statements needs a
+// transaction to see a single snapshot.
+func (mysqlDialect) ReadTx() bool {
+	return true
+}
+
+// Limit limits the query to its first n rows with LIMIT
+func (mysqlDialect) Limit(query string, n int) string {
+	return fmt.Sprintf("%s LIMIT %d", query, n)
+}
+
+// LateralJoin returns CROSS JOIN LATERAL, which MySQL has had since 8.0.14
+func (mysqlDialect) LateralJoin() string {
+	return "CROSS JOIN LATERAL"
+}
+
+// TruncateTime truncates a time with DATE, or with DATE_FORMAT for the units
+// that aren't whole days, because MySQL has no DATE_TRUNC.  Weeks are counted
+// back to Monday with WEEKDAY.  The result is cast to DATETIME, so that it is
+// read as a time.
+func (mysqlDialect) TruncateTime(col string, unit TimeUnit) string {
+	switch unit {
+	case Hour:
+		return "CAST(DATE_FORMAT(" + col + ", '%Y-%m-%d %H:00:00') AS DATETIME)"
+	case Day:
+		return "CAST(DATE(" + col + ") AS DATETIME)"
+	case Week:
+		return "CAST(DATE(" + col + ") - INTERVAL WEEKDAY(" + col + ") DAY AS DATETIME)"
+	case Month:
+		return "CAST(DATE_FORMAT(" + col + ", '%Y-%m-01') AS DATETIME)"
+	}
+	return ""
+}
+
+// EstimateCard returns the optimizer's estimate of the rows of the query from
+// its EXPLAIN FORMAT=TREE, which MySQL has had since 8.0.16
+func (mysqlDialect) EstimateCard(ctx context.Context, q QueryerContext, table, query string, args []interface{}) (int64, error) {
+	return explainEstimate(ctx, q, "EXPLAIN FORMAT=TREE", query, args)
+}
+
+// UUIDFormat returns UUIDBinary, because MySQL has no uuid type, and 16 bytes
+// index better than the 36 characters of the text form
+func (mysqlDialect) UUIDFormat() UUIDFormat {
+	return UUIDBinary
+}
+
+// AlterColumnType returns MODIFY COLUMN, which MySQL uses instead of ALTER
+// COLUMN ... TYPE.  It redefines the whole column, so NOT NULL is repeated.
+func (mysqlDialect) AlterColumnType(tableName, colName, typeName string, nullable bool) string {
+	stmt := "ALTER TABLE " + tableName + " MODIFY COLUMN " + colName + " " + typeName
+	if !nullable {
+		stmt += " NOT NULL"
+	}
+	return stmt
+}
+
+// TransactionalDDL returns false, because MySQL commits the transaction that
+// a DDL statement is executed in
+func (mysqlDialect) TransactionalDDL() bool {
+	return false
+}
+
+// EnumType returns an inline ENUM of the values, if they are all strings.
+// The values of a MySQL ENUM are strings, and numbers are taken as the
+// positions of its values, so other enums are constrained with CHECK.
+func (mysqlDialect) EnumType(values []interface{}) string {
+	lits := make([]string, len(values))
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			return ""
+		}
+		lits[i] = literal(s)
+	}
+	return "ENUM(" + strings.Join(lits, ", ") + ")"
+}
+
+// SwapStatements returns a single RENAME TABLE, which MySQL applies
+// atomically, because its DDL isn't transactional.
+func (d mysqlDialect) SwapStatements(tableName, staging, old string) []string {
+	return []string{"RENAME TABLE " + quoteTable(d, tableName) + " TO " + quoteTable(d, old) + ", " +
+		quoteTable(d, staging) + " TO " + quoteTable(d, tableName)}
+}
+
+func init() {
+	RegisterDialect("mysql", MySQL)
+}
@@ -0,0 +1,142 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"net"
+	"net/netip"
+	"reflect"
+	"strings"
+)
+
+// networkTyper is implemented by dialects with native network address types,
+// like postgres' inet, cidr and macaddr, which also support the << containment
+// operator.  Other dialects store addresses as text, and containment is
+// evaluated client side.
+type networkTyper interface {
+	NetworkTypes() bool
+}
+
+// networkTypes returns true if the dialect has native network address types
+func networkTypes(d Dialect) bool {
+	n, ok := d.(networkTyper)
+	return ok && n.NetworkTypes()
+}
+
+var (
+	ipType     = reflect.TypeOf(net.IP(nil))
+	addrType   = reflect.TypeOf(netip.Addr{})
+	prefixType = reflect.TypeOf(netip.Prefix{})
+	macType    = reflect.TypeOf(net.HardwareAddr(nil))
+)
+
+// networkCodec converts network addresses to and from their text form.  The
+// addresses are stored in the native column type in dialects with network
+// types, and as text otherwise.
+type networkCodec struct {
+	native, text string
+}
+
+// Encode converts an address to text
+func (networkCodec) Encode(d Dialect, v interface{}) (interface{}, error) {
+	switch a := v.(type) {
+	case net.IP:
+		if a == nil {
+			return nil, fmt.Errorf("relsql: can't convert nil net.IP")
+		}
+		return a.String(), nil
+	case netip.Addr:
+		if !a.IsValid() {
+			return nil, fmt.Errorf("relsql: can't convert invalid netip.Addr")
+		}
+		return a.String(), nil
+	case netip.Prefix:
+		if !a.IsValid() {
+			return nil, fmt.Errorf("relsql: can't convert invalid netip.Prefix")
+		}
+		return a.Masked().String(), nil
+	case net.HardwareAddr:
+		return a.String(), nil
+	}
+	return nil, fmt.Errorf("relsql: %T is not a network address", v)
+}
+
+// Decode parses an address from text
+func (c networkCodec) Decode(d Dialect, src interface{}, dst reflect.Value) error {
+	var s string
+	switch v := src.(type) {
+	case string:
+		s = v
+	case []byte:
+		s = string(v)
+	case nil:
+		return fmt.Errorf("relsql: can't convert NULL to %v", dst.Type())
+	default:
+		return decodeError(src, dst)
+	}
+	switch dst.Type() {
+	case ipType, addrType:
+		// inet values may include a netmask, which is dropped
+		if i := strings.IndexByte(s, '/'); i >= 0 {
+			s = s[:i]
+		}
+		a, err := netip.ParseAddr(s)
+		if err != nil {
+			return err
+		}
+		if dst.Type() == ipType {
+			dst.Set(reflect.ValueOf(net.IP(a.AsSlice())))
+		} else {
+			dst.Set(reflect.ValueOf(a))
+		}
+	case prefixType:
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(p))
+	case macType:
+		m, err := net.ParseMAC(s)
+		if err != nil {
+			return err
+		}
+		dst.Set(reflect.ValueOf(m))
+	default:
+		return decodeError(src, dst)
+	}
+	return nil
+}
+
+// TypeName returns the column type for addresses in the dialect
+func (c networkCodec) TypeName(d Dialect) string {
+	if networkTypes(d) {
+		return c.native
+	}
+	return c.text
+}
+
+// Within creates a predicate that is true when the attribute, which is a
+// net.IP or netip.Addr, is an address in the network prefix.  It is compiled
+// to the << operator in dialects with native network types, and evaluated
+// client side otherwise.
+func (att Attribute) Within(prefix netip.Prefix) Pred {
+	prefix = prefix.Masked()
+	cp := clientPred{rel.Attribute(att), fmt.Sprintf("%s << %v", att, prefix), func(v interface{}) bool {
+		var a netip.Addr
+		switch v := v.(type) {
+		case net.IP:
+			a, _ = netip.AddrFromSlice(v)
+		case netip.Addr:
+			a = v
+		}
+		return a.IsValid() && prefix.Contains(a.Unmap())
+	}}
+	return Pred{cp, "<<", rel.Attribute(att), prefix, nil}
+}
+
+func init() {
+	RegisterCodec(ipType, networkCodec{native: "INET", text: "VARCHAR(45)"})
+	RegisterCodec(addrType, networkCodec{native: "INET", text: "VARCHAR(45)"})
+	RegisterCodec(prefixType, networkCodec{native: "CIDR", text: "VARCHAR(49)"})
+	RegisterCodec(macType, networkCodec{native: "MACADDR", text: "VARCHAR(17)"})
+}
@@ -0,0 +1,103 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"net"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+// netDialect is an ansi dialect with native network types
+type netDialect struct {
+	ansiDialect
+}
+
+func (netDialect) NetworkTypes() bool { return true }
+
+// test network address encoding and decoding
+func TestNetworkCodec(t *testing.T) {
+	mac, _ := net.ParseMAC("00:00:5e:00:53:01")
+	var codecTest = []struct {
+		v        interface{}
+		encoded  string
+		native   string
+		text     string
+		scanned  string
+		expected interface{}
+	}{
+		{net.ParseIP("192.0.2.1"), "192.0.2.1", "INET", "VARCHAR(45)", "192.0.2.1/32", net.IP(netip.MustParseAddr("192.0.2.1").AsSlice())},
+		{netip.MustParseAddr("2001:db8::1"), "2001:db8::1", "INET", "VARCHAR(45)", "2001:db8::1", netip.MustParseAddr("2001:db8::1")},
+		{netip.MustParsePrefix("10.1.2.3/8"), "10.0.0.0/8", "CIDR", "VARCHAR(49)", "10.0.0.0/8", netip.MustParsePrefix("10.0.0.0/8")},
+		{mac, "00:00:5e:00:53:01", "MACADDR", "VARCHAR(17)", "00-00-5E-00-53-01", mac},
+	}
+	for i, tt := range codecTest {
+		c := lookupCodec(reflect.TypeOf(tt.v))
+		if c == nil {
+			t.Errorf("%d has no codec for %T", i, tt.v)
+			continue
+		}
+		if v, err := c.Encode(ANSI, tt.v); v != tt.encoded || err != nil {
+			t.Errorf("%d has Encode() => %v, %v, want %v", i, v, err, tt.encoded)
+		}
+		if typeName := c.TypeName(netDialect{}); typeName != tt.native {
+			t.Errorf("%d has native TypeName() => %v, want %v", i, typeName, tt.native)
+		}
+		if typeName := c.TypeName(ANSI); typeName != tt.text {
+			t.Errorf("%d has TypeName() => %v, want %v", i, typeName, tt.text)
+		}
+		dst := reflect.New(reflect.TypeOf(tt.v)).Elem()
+		if err := c.Decode(ANSI, []byte(tt.scanned), dst); err != nil || !reflect.DeepEqual(dst.Interface(), tt.expected) {
+			t.Errorf("%d has Decode(%q) => %v, %v, want %v", i, tt.scanned, dst.Interface(), err, tt.expected)
+		}
+	}
+}
+
+// test containment predicates, pushed down and client side
+func TestWithin(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type hostTup struct {
+		Name string
+		Addr netip.Addr
+	}
+	if err := CreateTable(db, "hosts", hostTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	hosts := rel.New([]hostTup{
+		{"db", netip.MustParseAddr("10.0.0.5")},
+		{"web", netip.MustParseAddr("192.0.2.80")},
+	}, [][]string{[]string{"Name"}})
+	if _, err := Insert(db, "hosts", hosts); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	p := Attribute("Addr").Within(netip.MustParsePrefix("10.0.0.0/8"))
+	native := New(db, "hosts", hostTup{}, [][]string{[]string{"Name"}}, WithDialect(netDialect{})).Restrict(p)
+	q, args, _ := native.(*sqlTable).queryString()
+	if want := "SELECT Name, Addr FROM hosts WHERE Addr << ?"; q != want || len(args) != 1 || args[0] != "10.0.0.0/8" {
+		t.Errorf("native query => %v %v, want %v [10.0.0.0/8]", q, args, want)
+	}
+
+	internal := New(db, "hosts", hostTup{}, [][]string{[]string{"Name"}}).Restrict(p)
+	if _, ok := internal.(*sqlTable); ok {
+		t.Errorf("containment was pushed down without native network types")
+	}
+	ch := make(chan hostTup)
+	internal.TupleChan(ch)
+	var res []hostTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if want := []hostTup{{"db", netip.MustParseAddr("10.0.0.5")}}; !reflect.DeepEqual(res, want) {
+		t.Errorf("hosts in 10.0.0.0/8 => %v, want %v", res, want)
+	}
+}
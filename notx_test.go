@@ -0,0 +1,111 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// noTxConnector opens sqlite connections which can't begin transactions
+type noTxConnector struct {
+	dsn string
+	d   driver.Driver
+}
+
+func (c noTxConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.d.Open(c.dsn)
+	return noTxConn{conn}, err
+}
+
+func (c noTxConnector) Driver() driver.Driver {
+	return c.d
+}
+
+// noTxConn is a connection whose Begin fails
+type noTxConn struct {
+	driver.Conn
+}
+
+func (noTxConn) Begin() (driver.Tx, error) {
+	return nil, errors.New("transactions are not supported")
+}
+
+// noTxDialect is the ANSI dialect for a driver without transactions
+type noTxDialect struct {
+	ansiDialect
+}
+
+func (noTxDialect) Transactions() bool {
+	return false
+}
+
+// test reading without transactions from drivers that can't begin them
+func TestNoTransactions(t *testing.T) {
+	dsn := "file:notx?mode=memory&cache=shared"
+	setup, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer setup.Close()
+
+	type saleTup struct {
+		ID     int
+		Amount int
+	}
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(setup, "sales", saleTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(setup, "sales", rel.New([]saleTup{{1, 10}, {2, 5}, {3, 7}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	db := sql.OpenDB(noTxConnector{dsn, setup.Driver()})
+	defer db.Close()
+
+	type totalTup struct {
+		Total int
+	}
+	script := []string{
+		"DROP TABLE IF EXISTS temp.big",
+		"CREATE TEMP TABLE big AS SELECT * FROM sales WHERE Amount > 6",
+	}
+	var noTxTest = []struct {
+		r    func() rel.Relation
+		fail bool
+		want string
+	}{
+		// ANSI reads in a transaction
+		{func() rel.Relation { return New(db, "sales", saleTup{}, keys, WithDialect(ANSI)) }, true, "[]"},
+		{func() rel.Relation { return New(db, "sales", saleTup{}, keys, WithDialect(noTxDialect{})) }, false, "[{1 10} {2 5} {3 7}]"},
+		{func() rel.Relation {
+			return NewFromScript(db, script, "SELECT SUM(Amount) AS Total FROM big", totalTup{}, nil, WithDialect(noTxDialect{}))
+		}, false, "[{17}]"},
+	}
+	for i, tt := range noTxTest {
+		// scripts on the same connection have to be repeatable
+		for j := 0; j < 2; j++ {
+			r := tt.r()
+			ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, reflect.TypeOf(r.Zero())), 0)
+			r.TupleChan(ch.Interface())
+			var res []interface{}
+			for {
+				tup, ok := ch.Recv()
+				if !ok {
+					break
+				}
+				res = append(res, tup.Interface())
+			}
+			if (r.Err() != nil) != tt.fail || fmt.Sprint(res) != tt.want {
+				t.Errorf("%d has tuples => %v, %v, want %s", i, res, r.Err(), tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,215 @@
+package relsql
+
+import (
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// Option configures a relation constructed by New.  Options are inherited by
+// every relation derived from it.
+type Option func(*options)
+
+// options holds the configuration of a relation
+type options struct {
+	// distinct is the policy used to decide when a query needs DISTINCT
+	distinct Distinctness
+
+	// distinctSet is true if distinct was set with WithDistinct, instead of
+	// being the dialect's default
+	distinctSet bool
+
+	// dialect is the sql dialect of the database
+	dialect Dialect
+
+	// retry is the policy for retrying failed queries
+	retry RetryPolicy
+
+	// isolation is the isolation level of read transactions
+	isolation sql.IsolationLevel
+
+	// ping is the timeout for pinging the database before a query, or zero
+	// to skip the ping
+	ping time.Duration
+
+	// batchSize is the number of rows written by each INSERT
+	batchSize int
+
+	// workers is the number of concurrent writers used by Insert
+	workers int
+
+	// progress is called after each batch of rows is written
+	progress func(WriteProgress)
+
+	// writeMode is how Materialize treats existing rows
+	writeMode WriteMode
+
+	// exactNumerics makes lossy numeric conversions an error when scanning
+	exactNumerics bool
+
+	// enums are the attributes which may only hold a set of values
+	enums []enum
+
+	// transforms are applied to attribute values as they are read and written
+	transforms []transform
+
+	// ciphers are the transforms of encrypted attributes, which are applied
+	// closest to the database
+	ciphers []transform
+
+	// mandatory are the predicates added to every query of the relation
+	mandatory []Pred
+
+	// redacted are the attributes whose values are masked in text output
+	redacted []string
+
+	// prefetch is the number of tuples that a stream may read ahead of its
+	// consumer
+	prefetch int
+
+	// prefetchMemory is the size in bytes of the tuples that a stream may
+	// read ahead of its consumer
+	prefetchMemory int64
+
+	// governor limits the size of the tuples read ahead by all of the
+	// relations that share it
+	governor *MemoryGovernor
+
+	// borrowed makes streams reuse the memory of the tuples they send
+	borrowed bool
+
+	// auditor records the executed statements, with the caller's tag
+	auditor  Auditor
+	auditTag string
+
+	// ordered sorts queries by the first candidate key
+	ordered bool
+
+	// logf is called with the diagnostics of client side operations, and
+	// with warnings about joins
+	logf func(format string, args ...interface{})
+
+	// slog is sent structured records of queries, client side operations
+	// and warnings
+	slog *slog.Logger
+
+	// indexWarnings logs restrictions on expressions other than exprIndexes,
+	// which the database can't use indexes on the attributes for
+	indexWarnings bool
+	exprIndexes   []Expr
+
+	// hints are the optimizer hints attached to the relation's queries
+	hints []string
+
+	// policies check, and may rewrite, each query before it is executed
+	policies []Policy
+
+	// freshness is the probe of when the relation's table was last modified
+	freshness freshness
+
+	// foreignKeys are the foreign keys of the relation's table
+	foreignKeys []ForeignKey
+
+	// crossJoinLimit is the most rows a cross join may produce, or zero for
+	// no limit
+	crossJoinLimit int64
+
+	// semiJoinLimit is the most join values sent to reduce a join across
+	// databases, or zero for the default
+	semiJoinLimit int
+
+	// scanProgress is called every scanEvery while a query is read
+	scanProgress func(ScanProgress)
+	scanEvery    time.Duration
+
+	// rowThrottle and queryThrottle limit the rates that tuples are sent and
+	// queries are executed
+	rowThrottle   *Throttle
+	queryThrottle *Throttle
+
+	// workload is the class that the relation's queries are scheduled in
+	workload *Workload
+
+	// controller tracks the relation's reads, so that they can be shut down
+	controller *Controller
+
+	// checkoutTimeout is how long a read waits for a connection from the
+	// pool, or zero to wait indefinitely
+	checkoutTimeout time.Duration
+
+	// singleConn executes all of the statements of a read on one connection
+	singleConn bool
+
+	// readTimeout is how long a read may take, or zero for no limit
+	readTimeout time.Duration
+
+	// verbosity is the level of detail of the relation's text
+	// representations
+	verbosity Verbosity
+
+	// columns are the names of the columns of the attributes, for tables
+	// whose columns aren't named like the attributes
+	columns []string
+}
+
+// Distinctness is the policy used to decide whether a compiled query has to
+// remove duplicate rows.
+type Distinctness int
+
+const (
+	// AssumeDistinctByKey trusts the candidate keys supplied to New: rows are
+	// assumed to be distinct while a candidate key is part of the heading, and
+	// DISTINCT is only added once a projection removes every key.  If no
+	// keys were supplied, DISTINCT is always added.
+	AssumeDistinctByKey Distinctness = iota
+
+	// ForceDistinct always adds DISTINCT, for tables whose declared keys are
+	// not enforced by the database.
+	ForceDistinct
+
+	// AllowDuplicates never adds DISTINCT.  The relation may then contain
+	// duplicate tuples, which violates relational semantics but avoids the
+	// cost of duplicate removal on the server.
+	AllowDuplicates
+)
+
+// String returns the name of the policy
+func (d Distinctness) String() string {
+	switch d {
+	case AssumeDistinctByKey:
+		return "AssumeDistinctByKey"
+	case ForceDistinct:
+		return "ForceDistinct"
+	case AllowDuplicates:
+		return "AllowDuplicates"
+	}
+	return "Distinctness(?)"
+}
+
+// WithDistinct sets the policy used to decide when DISTINCT is needed.  The
+// default is AssumeDistinctByKey, except in dialects whose declared keys
+// aren't enforced, like Snowflake, where it is ForceDistinct.
+func WithDistinct(d Distinctness) Option {
+	return func(o *options) {
+		o.distinct = d
+		o.distinctSet = true
+	}
+}
+
+// WithDeterministicOrder sorts the tuples of every query by the relation's
+// first candidate key, so that tests can compare sequences of tuples without
+// sorting them, whatever the database.  Operations that are evaluated client
+// side don't preserve the order.
+func WithDeterministicOrder() Option {
+	return func(o *options) {
+		o.ordered = true
+	}
+}
+
+// dialectOrANSI returns the configured dialect, or ANSI if there is none
+func (o *options) dialectOrANSI() Dialect {
+	if o.dialect == nil {
+		return ANSI
+	}
+	return o.dialect
+}
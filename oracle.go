@@ -0,0 +1,139 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Oracle is the dialect for Oracle 12c and later.  Arguments are bound to
+// numbered placeholders :1, :2, and so on, EXCEPT is written as MINUS,
+// derived tables are named without AS, and constant rows are selected from
+// DUAL.
+var Oracle Dialect = oracleDialect{}
+
+// Oracle11 is the dialect for Oracle releases before 12c, which limit the
+// number of rows with ROWNUM instead of FETCH FIRST.
+var Oracle11 Dialect = oracleDialect{rownum: true}
+
+// oracleDialect is the dialect for Oracle
+type oracleDialect struct {
+	// rownum is true if row limits use ROWNUM
+	rownum bool
+}
+
+// Name returns the name of the dialect
+func (oracleDialect) Name() string {
+	return "oracle"
+}
+
+// Placeholder returns the placeholder for the i'th argument of a query
+func (oracleDialect) Placeholder(i int) string {
+	return fmt.Sprintf(":%d", i)
+}
+
+// ReadTx returns false, because a single Oracle query reads from a
+// consistent snapshot.
+func (oracleDialect) ReadTx() bool {
+	return false
+}
+
+// SetOperator returns MINUS for EXCEPT
+func (oracleDialect) SetOperator(op string) string {
+	if op == "EXCEPT" {
+		return "MINUS"
+	}
+	return op
+}
+
+// TableAlias names a derived table without AS, which Oracle doesn't allow
+// for tables.
+func (oracleDialect) TableAlias(name string) string {
+	return " " + name
+}
+
+// LateralJoin returns CROSS APPLY, which Oracle has had since 12c
+func (d oracleDialect) LateralJoin() string {
+	if d.rownum {
+		return ""
+	}
+	return "CROSS APPLY"
+}
+
+// Dual returns DUAL, Oracle's single row table
+func (oracleDialect) Dual() string {
+	return "DUAL"
+}
+
+// Limit limits the query to its first n rows
+func (d oracleDialect) Limit(query string, n int) string {
+	if d.rownum {
+		return fmt.Sprintf("SELECT * FROM (%s) WHERE ROWNUM <= %d", query, n)
+	}
+	return fmt.Sprintf("%s FETCH FIRST %d ROWS ONLY", query, n)
+}
+
+// TypeName returns Oracle's names for the column types of Go types
+func (oracleDialect) TypeName(t reflect.Type) string {
+	if t == lazyType {
+		return "BLOB"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "NUMBER(1)"
+	case reflect.Int8, reflect.Int16, reflect.Uint8:
+		return "NUMBER(5)"
+	case reflect.Int32, reflect.Uint16:
+		return "NUMBER(10)"
+	case reflect.Int, reflect.Int64, reflect.Uint32:
+		return "NUMBER(19)"
+	case reflect.Uint, reflect.Uint64:
+		return "NUMBER(20)"
+	case reflect.Float32:
+		return "BINARY_FLOAT"
+	case reflect.Float64:
+		return "BINARY_DOUBLE"
+	case reflect.String:
+		return "VARCHAR2(4000)"
+	}
+	return ""
+}
+
+// AlterColumnType returns MODIFY, which Oracle uses instead of ALTER COLUMN
+// ... TYPE, and which keeps the column's NOT NULL
+func (oracleDialect) AlterColumnType(tableName, colName, typeName string, nullable bool) string {
+	return "ALTER TABLE " + tableName + " MODIFY (" + colName + " " + typeName + ")"
+}
+
+// TransactionalDDL returns false, because Oracle commits the transaction that
+// a DDL statement is executed in
+func (oracleDialect) TransactionalDDL() bool {
+	return false
+}
+
+// oracleTruncFormats are the formats of TRUNC for the time units
+var oracleTruncFormats = map[TimeUnit]string{
+	Hour:  "HH24",
+	Day:   "DD",
+	Week:  "IW",
+	Month: "MM",
+}
+
+// TruncateTime returns TRUNC with the format of the unit
+func (oracleDialect) TruncateTime(col string, unit TimeUnit) string {
+	return "TRUNC(" + col + ", '" + oracleTruncFormats[unit] + "')"
+}
+
+// oracleBools stores booleans in NUMBER(1) columns, because Oracle has no
+// boolean column type
+var oracleBools = BoolCodec{int64(1), int64(0), "NUMBER(1)"}
+
+// BoolCodec returns the codec for booleans stored as 1 and 0
+func (oracleDialect) BoolCodec() Codec {
+	return oracleBools
+}
+
+func init() {
+	// godror, and sijms' go-ora
+	RegisterDialect("godror", Oracle)
+	RegisterDialect("oracle", Oracle)
+}
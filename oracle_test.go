@@ -0,0 +1,63 @@
+package relsql
+
+import (
+	"testing"
+)
+
+// test the sql generated for Oracle
+func TestOracle(t *testing.T) {
+	type partTup struct {
+		PNO    int
+		Colour string
+	}
+	type supplyTup struct {
+		PNO int
+		SNO int
+	}
+	type emptyTup struct{}
+	parts := New(nil, "parts", partTup{}, [][]string{[]string{"PNO"}}, WithDialect(Oracle))
+	retired := New(nil, "retired", partTup{}, [][]string{[]string{"PNO"}}, WithDialect(Oracle))
+	supplies := New(nil, "supplies", supplyTup{}, [][]string{[]string{"PNO", "SNO"}}, WithDialect(Oracle))
+
+	var queryTest = []struct {
+		r interface{}
+		q string
+	}{
+		{parts.Restrict(Attribute("Colour").EQ("red")).Restrict(Attribute("PNO").GT(3)),
+			"SELECT PNO, Colour FROM parts WHERE Colour = :1 AND PNO > :2"},
+		{parts.Diff(retired),
+			"SELECT PNO, Colour FROM (SELECT PNO, Colour FROM parts MINUS SELECT PNO, Colour FROM retired) s"},
+		{parts.Join(supplies, struct {
+			PNO    int
+			Colour string
+			SNO    int
+		}{}), "SELECT t1.PNO, t1.Colour, t2.SNO FROM (SELECT PNO, Colour FROM parts) t1 JOIN (SELECT PNO, SNO FROM supplies) t2 ON t1.PNO = t2.PNO"},
+		{parts.Project(emptyTup{}),
+			"SELECT 1 FROM DUAL WHERE EXISTS (SELECT 1 FROM parts)"},
+	}
+	for i, tt := range queryTest {
+		q, _, err := tt.r.(*sqlTable).queryString()
+		if err != nil {
+			t.Errorf("%d has queryString() => %v", i, err)
+		}
+		if q != tt.q {
+			t.Errorf("%d has queryString() => %q, want %q", i, q, tt.q)
+		}
+	}
+
+	var limitTest = []struct {
+		d Dialect
+		q string
+	}{
+		{ANSI, "SELECT PNO FROM parts FETCH FIRST 10 ROWS ONLY"},
+		{Oracle, "SELECT PNO FROM parts FETCH FIRST 10 ROWS ONLY"},
+		{Oracle11, "SELECT * FROM (SELECT PNO FROM parts) WHERE ROWNUM <= 10"},
+		{SQLite, "SELECT PNO FROM parts LIMIT 10"},
+		{BigQuery, "SELECT PNO FROM parts LIMIT 10"},
+	}
+	for i, tt := range limitTest {
+		if q := limitQuery(tt.d, "SELECT PNO FROM parts", 10); q != tt.q {
+			t.Errorf("%d has limitQuery() => %q, want %q", i, q, tt.q)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test sorting queries by candidate key
+func TestDeterministicOrder(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:order?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type cityTup struct {
+		Name string
+		Pop  int
+	}
+	type popTup struct {
+		Pop int
+	}
+	if err := CreateTable(db, "cities", cityTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	tups := []cityTup{{"Oslo", 3}, {"Bergen", 2}, {"Tromso", 1}, {"Alta", 4}}
+	if _, err := Insert(db, "cities", rel.New(tups, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	cities := New(db, "cities", cityTup{}, [][]string{[]string{"Name"}}, WithDeterministicOrder())
+
+	var orderTest = []struct {
+		r rel.Relation
+		q string
+	}{
+		{cities, "SELECT Name, Pop FROM cities ORDER BY Name"},
+		{cities.Restrict(Attribute("Pop").GT(1)), "SELECT Name, Pop FROM cities WHERE Pop > ? ORDER BY Name"},
+		{cities.Project(popTup{}), "SELECT DISTINCT Pop FROM cities ORDER BY Pop"},
+	}
+	for i, tt := range orderTest {
+		if q, _, _ := tt.r.(*sqlTable).queryString(); q != tt.q {
+			t.Errorf("%d has queryString() => %q, want %q", i, q, tt.q)
+		}
+	}
+
+	ch := make(chan cityTup)
+	cities.TupleChan(ch)
+	var res []cityTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	want := []cityTup{{"Alta", 4}, {"Bergen", 2}, {"Oslo", 3}, {"Tromso", 1}}
+	if !reflect.DeepEqual(res, want) {
+		t.Errorf("tuples => %v, want %v", res, want)
+	}
+}
@@ -0,0 +1,353 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"math/big"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Partition is one of the tables of a partitioned relation, which holds the
+// rows whose partition key is in the range from Lo, inclusive, to Hi,
+// exclusive.  A nil bound is unbounded.
+type Partition struct {
+	Table  string
+	Lo, Hi interface{}
+}
+
+// partitionSource is the union of identically shaped tables which partition
+// the rows by the value of a key column.
+type partitionSource struct {
+	key   string
+	parts []Partition
+
+	// empty is true if every partition was pruned, in which case parts holds
+	// one of them to take the shape of the rows from
+	empty bool
+}
+
+// NewPartitioned creates a relation that reads from a list of identically
+// shaped tables, like monthly partitions of an events table, and behaves like
+// their union.  key is the name of the column that the rows are partitioned
+// by.  Restrictions that compare the key to a value are used to skip the
+// partitions that can't hold matching rows, so a query only reads the tables
+// it needs.
+func NewPartitioned(db *sql.DB, key string, parts []Partition, z interface{}, ckeystr [][]string, opts ...Option) rel.Relation {
+	r := New(db, "", z, ckeystr, opts...).(*sqlTable)
+	r.src = &partitionSource{key, parts, false}
+	return r
+}
+
+// partitionLister is implemented by dialects whose catalog describes the
+// partitions of natively partitioned tables.  Partitions returns the key
+// column and the partitions of a table, or no partitions if the table isn't
+// partitioned.
+type partitionLister interface {
+	Partitions(db *sql.DB, tableName string) (key string, parts []Partition, err error)
+}
+
+// NewNativePartitioned creates a relation that reads from a natively
+// partitioned table.  If the dialect can list the table's partitions and
+// their bounds, as Postgres can for range partitions, the relation reads from
+// them directly, like one from NewPartitioned, so partitions that can't
+// satisfy its restrictions are skipped before the query is sent.  Otherwise
+// it reads from the table, like one from New.
+func NewNativePartitioned(db *sql.DB, tableName string, z interface{}, ckeystr [][]string, opts ...Option) rel.Relation {
+	r := New(db, tableName, z, ckeystr, opts...).(*sqlTable)
+	l, ok := r.dialect().(partitionLister)
+	if !ok || r.err != nil {
+		return r
+	}
+	key, parts, err := l.Partitions(db, tableName)
+	if err != nil {
+		r.err = err
+		return r
+	}
+	// the catalog has the key as the database folded it, which may differ in
+	// case from the relation's column
+	for _, c := range r.cols {
+		if strings.EqualFold(c.name, key) {
+			key = c.name
+		}
+	}
+	if len(parts) > 0 {
+		r.src = &partitionSource{key, parts, false}
+	}
+	return r
+}
+
+// build returns the union of the partitions, selecting the needed columns
+// from each of them.
+func (p *partitionSource) build(b *builder, needed map[column]bool) string {
+	sel := "*"
+	if len(needed) > 0 {
+		names := make([]string, 0, len(needed))
+		for c := range needed {
+			names = append(names, c.name)
+		}
+		sort.Strings(names)
+		sel = strings.Join(names, ", ")
+	}
+	if p.empty {
+		return "(SELECT " + sel + " FROM " + quoteTable(b.dialectOrANSI(), p.parts[0].Table) + " WHERE 1 = 0)" + b.alias("p")
+	}
+	strs := make([]string, len(p.parts))
+	for i, part := range p.parts {
+		strs[i] = "SELECT " + sel + " FROM " + quoteTable(b.dialectOrANSI(), part.Table)
+	}
+	return "(" + strings.Join(strs, " UNION ALL ") + ")" + b.alias("p")
+}
+
+// prune returns the source with only the partitions which can hold rows that
+// satisfy the conditions.
+func (p *partitionSource) prune(where []condition) *partitionSource {
+	var parts []Partition
+	for _, part := range p.parts {
+		if p.canMatch(part, where) {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 && len(p.parts) > 0 {
+		return &partitionSource{p.key, p.parts[:1], true}
+	}
+	return &partitionSource{p.key, parts, false}
+}
+
+// canMatch returns false if the partition can't hold any rows that satisfy
+// the conditions.  Comparisons of the partition key with values narrow the
+// range of keys that a row could have, and the partition is skipped if the
+// range becomes empty.  Other conditions, and values that can't be compared
+// to the bounds, are assumed to match.
+func (p *partitionSource) canMatch(part Partition, where []condition) bool {
+	var preds []Pred
+	var cols []map[rel.Attribute]column
+	for _, c := range where {
+		preds = append(preds, c.pred)
+		cols = append(cols, c.cols)
+	}
+	iv := interval{bound{part.Lo, true}, bound{part.Hi, false}}
+	return p.satisfiable(iv, preds, cols)
+}
+
+// satisfiable returns false if no key in the interval can satisfy every one
+// of the predicates, whose attributes are resolved by the corresponding cols.
+func (p *partitionSource) satisfiable(iv interval, preds []Pred, cols []map[rel.Attribute]column) bool {
+	if iv.empty() {
+		return false
+	}
+	if len(preds) == 0 {
+		return true
+	}
+	pr, c := preds[0], cols[0]
+	preds, cols = preds[1:], cols[1:]
+	switch {
+	case pr.op == "AND":
+		for i := len(pr.preds) - 1; i >= 0; i-- {
+			preds = append([]Pred{pr.preds[i]}, preds...)
+			cols = append([]map[rel.Attribute]column{c}, cols...)
+		}
+		return p.satisfiable(iv, preds, cols)
+	case pr.op == "OR":
+		for _, p2 := range pr.preds {
+			if p.satisfiable(iv, append([]Pred{p2}, preds...), append([]map[rel.Attribute]column{c}, cols...)) {
+				return true
+			}
+		}
+		return false
+	case c[pr.att] != (column{name: p.key}):
+		return p.satisfiable(iv, preds, cols)
+	case pr.op == "IN":
+		for _, v := range pr.val.([]interface{}) {
+			if p.satisfiable(iv.narrow("=", v), preds, cols) {
+				return true
+			}
+		}
+		return false
+	}
+	if _, ok := pr.val.(rel.Attribute); ok {
+		return p.satisfiable(iv, preds, cols)
+	}
+	return p.satisfiable(iv.narrow(pr.op, pr.val), preds, cols)
+}
+
+// bound is one end of an interval of keys, which is unbounded if v is nil
+type bound struct {
+	v    interface{}
+	incl bool
+}
+
+// interval is a range of keys
+type interval struct {
+	lo, hi bound
+}
+
+// empty returns true if no key can be in the interval
+func (iv interval) empty() bool {
+	if iv.lo.v == nil || iv.hi.v == nil {
+		return false
+	}
+	c, ok := compareValues(iv.lo.v, iv.hi.v)
+	return ok && (c > 0 || c == 0 && !(iv.lo.incl && iv.hi.incl))
+}
+
+// narrow returns the part of the interval whose keys satisfy key op v.
+// Operators that don't bound the key, and values which can't be compared,
+// leave the interval as it is.
+func (iv interval) narrow(op string, v interface{}) interval {
+	switch op {
+	case "=":
+		return iv.narrow(">=", v).narrow("<=", v)
+	case "<", "<=":
+		b := bound{v, op == "<="}
+		if iv.hi.v == nil {
+			iv.hi = b
+		} else if c, ok := compareValues(v, iv.hi.v); ok && (c < 0 || c == 0 && !b.incl) {
+			iv.hi = b
+		}
+	case ">", ">=":
+		b := bound{v, op == ">="}
+		if iv.lo.v == nil {
+			iv.lo = b
+		} else if c, ok := compareValues(v, iv.lo.v); ok && (c > 0 || c == 0 && !b.incl) {
+			iv.lo = b
+		}
+	}
+	return iv
+}
+
+// compareValues compares two values of the same kind, returning false if they
+// can't be compared.
+func compareValues(a, b interface{}) (int, bool) {
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		if !ok {
+			return 0, false
+		}
+		switch {
+		case ta.Before(tb):
+			return -1, true
+		case ta.After(tb):
+			return 1, true
+		}
+		return 0, true
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	switch {
+	case isInt(va.Type()) && isInt(vb.Type()):
+		return compareInts(va, vb), true
+	case isNumber(va) && isNumber(vb):
+		return compareFloats(numberValue(va), numberValue(vb)), true
+	case va.Type() == decimalType || vb.Type() == decimalType:
+		ra, rb := ratValue(va), ratValue(vb)
+		if ra == nil || rb == nil {
+			return 0, false
+		}
+		return ra.Cmp(rb), true
+	case va.Kind() == reflect.String && vb.Kind() == reflect.String:
+		return strings.Compare(va.String(), vb.String()), true
+	}
+	return 0, false
+}
+
+// ratValue returns a Decimal, integer or float as an exact number, or nil if
+// it isn't one
+func ratValue(v reflect.Value) *big.Rat {
+	switch {
+	case v.Type() == decimalType:
+		r, ok := new(big.Rat).SetString(v.String())
+		if !ok {
+			return nil
+		}
+		return r
+	case isUint(v):
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(v.Uint()))
+	case isInt(v.Type()):
+		return new(big.Rat).SetInt64(v.Int())
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		// nil for infinities and NaN
+		return new(big.Rat).SetFloat64(v.Float())
+	}
+	return nil
+}
+
+// isUint returns true if the value is an unsigned integer
+func isUint(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// compareInts compares signed or unsigned integers exactly
+func compareInts(a, b reflect.Value) int {
+	switch {
+	case !isUint(a) && !isUint(b):
+		return compareSign(a.Int(), b.Int())
+	case isUint(a) && isUint(b):
+		switch {
+		case a.Uint() < b.Uint():
+			return -1
+		case a.Uint() > b.Uint():
+			return 1
+		}
+		return 0
+	case isUint(a):
+		return -compareInts(b, a)
+	}
+	if a.Int() < 0 {
+		return -1
+	}
+	return compareInts(reflect.ValueOf(uint64(a.Int())), b)
+}
+
+// compareSign returns -1, 0 or 1 as a is less than, equal to, or greater
+// than b.
+func compareSign(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// intValue returns a signed or unsigned integer as a float64
+func intValue(v reflect.Value) float64 {
+	if isUint(v) {
+		return float64(v.Uint())
+	}
+	return float64(v.Int())
+}
+
+// isNumber returns true for integers and floats
+func isNumber(v reflect.Value) bool {
+	return isInt(v.Type()) || v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64
+}
+
+// numberValue returns an integer or float as a float64
+func numberValue(v reflect.Value) float64 {
+	if isInt(v.Type()) {
+		return intValue(v)
+	}
+	return v.Float()
+}
+
+// compareFloats returns -1, 0 or 1 as a is less than, equal to, or greater
+// than b.
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
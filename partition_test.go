@@ -0,0 +1,170 @@
+package relsql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// test that restrictions on the partition key skip partitions
+func TestPartitioned(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+	create table events_01 (ID integer primary key, Day text not null);
+	create table events_02 (ID integer primary key, Day text not null);
+	create table events_03 (ID integer primary key, Day text not null);
+	insert into events_01 values (1, '2024-01-05');
+	insert into events_02 values (2, '2024-02-10'), (3, '2024-02-20');
+	insert into events_03 values (4, '2024-03-01');
+	`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	type eventTup struct {
+		ID  int
+		Day string
+	}
+	parts := []Partition{
+		{"events_01", nil, "2024-02-01"},
+		{"events_02", "2024-02-01", "2024-03-01"},
+		{"events_03", "2024-03-01", nil},
+	}
+	events := NewPartitioned(db, "Day", parts, eventTup{}, [][]string{[]string{"ID"}})
+
+	var pruneTest = []struct {
+		p    Pred
+		from string
+		ids  []int
+	}{
+		{Attribute("Day").EQ("2024-02-10"), "(SELECT Day, ID FROM events_02) AS p", []int{2}},
+		{Attribute("Day").GE("2024-02-15"), "(SELECT Day, ID FROM events_02 UNION ALL SELECT Day, ID FROM events_03) AS p", []int{3, 4}},
+		{Attribute("Day").LT("2024-02-01"), "(SELECT Day, ID FROM events_01) AS p", []int{1}},
+		{Attribute("ID").GT(1), "(SELECT Day, ID FROM events_01 UNION ALL SELECT Day, ID FROM events_02 UNION ALL SELECT Day, ID FROM events_03) AS p", []int{2, 3, 4}},
+		{And(Attribute("Day").GE("2024-02-01"), Attribute("Day").LT("2024-02-01")), "(SELECT Day, ID FROM events_01 WHERE 1 = 0) AS p", nil},
+		{And(Attribute("Day").GT("2024-01-02"), Attribute("Day").LT("2024-01-01")), "(SELECT Day, ID FROM events_01 WHERE 1 = 0) AS p", nil},
+		{Or(Attribute("Day").LT("2024-01-10"), Attribute("Day").GE("2024-03-01")), "(SELECT Day, ID FROM events_01 UNION ALL SELECT Day, ID FROM events_03) AS p", []int{1, 4}},
+		{Attribute("Day").In("2024-01-05", "2024-03-01"), "(SELECT Day, ID FROM events_01 UNION ALL SELECT Day, ID FROM events_03) AS p", []int{1, 4}},
+		{And(Attribute("Day").GT("2024-02-15"), Or(Attribute("ID").EQ(3), Attribute("Day").LE("2024-02-01"))), "(SELECT Day, ID FROM events_02 UNION ALL SELECT Day, ID FROM events_03) AS p", []int{3}},
+	}
+	for i, tt := range pruneTest {
+		r := events.Restrict(tt.p).(*sqlTable)
+		b := builder{dialect: ANSI}
+		if from := r.src.(*partitionSource).prune(r.where).build(&b, map[column]bool{{name: "ID"}: true, {name: "Day"}: true}); from != tt.from {
+			t.Errorf("%d has FROM => %v, want %v", i, from, tt.from)
+		}
+		ch := make(chan eventTup)
+		r.TupleChan(ch)
+		var ids []int
+		for tup := range ch {
+			ids = append(ids, tup.ID)
+		}
+		if !reflect.DeepEqual(ids, tt.ids) || r.Err() != nil {
+			t.Errorf("%d has ids => %v, %v, want %v", i, ids, r.Err(), tt.ids)
+		}
+	}
+
+	var intervalTest = []struct {
+		iv    interval
+		empty bool
+	}{
+		{interval{bound{1, true}, bound{1, true}}, false},
+		{interval{bound{1, true}, bound{1, false}}, true},
+		{interval{bound{2, true}, bound{1, true}}, true},
+		{interval{bound{nil, true}, bound{1, false}}, false},
+		{interval{bound{1, true}, bound{nil, false}}.narrow("<", 1), true},
+		{interval{bound{1, true}, bound{5, false}}.narrow("=", 3), false},
+		{interval{bound{1, true}, bound{5, false}}.narrow("=", 5), true},
+	}
+	for i, tt := range intervalTest {
+		if empty := tt.iv.empty(); empty != tt.empty {
+			t.Errorf("%d has empty() => %v, want %v", i, empty, tt.empty)
+		}
+	}
+
+	var compareTest = []struct {
+		a, b interface{}
+		c    int
+		ok   bool
+	}{
+		{int64(1) << 60, int64(1)<<60 + 1, -1, true},
+		{uint64(1) << 63, int64(-1), 1, true},
+		{2, 1.5, 1, true},
+		{"a", 1, 0, false},
+		{Decimal("10.5"), Decimal("9.75"), 1, true},
+		{Decimal("100"), 99, 1, true},
+		{1.5, Decimal("1.50"), 0, true},
+		{Decimal("x"), Decimal("1"), 0, false},
+	}
+	for i, tt := range compareTest {
+		if c, ok := compareValues(tt.a, tt.b); c != tt.c || ok != tt.ok {
+			t.Errorf("%d has compareValues(%v, %v) => %v, %v, want %v, %v", i, tt.a, tt.b, c, ok, tt.c, tt.ok)
+		}
+	}
+}
+
+// partDialect is an ansi dialect that lists the partitions of a table
+type partDialect struct {
+	ansiDialect
+}
+
+func (partDialect) Partitions(db *sql.DB, tableName string) (string, []Partition, error) {
+	if tableName != "events" {
+		return "", nil, nil
+	}
+	// the key is folded to lower case, like postgres' catalog has it
+	return "day", []Partition{
+		{"events_01", nil, "2024-02-01"},
+		{"events_02", "2024-02-01", "2024-03-01"},
+		{"events_03", "2024-03-01", nil},
+	}, nil
+}
+
+// test relations over natively partitioned tables
+func TestNativePartitioned(t *testing.T) {
+	type eventTup struct {
+		ID  int
+		Day string
+	}
+	r := NewNativePartitioned(nil, "events", eventTup{}, nil, WithDialect(partDialect{})).Restrict(Attribute("Day").EQ("2024-03-05"))
+	q, _, _ := r.(*sqlTable).queryString()
+	if want := "SELECT DISTINCT ID, Day FROM (SELECT Day, ID FROM events_03) AS p WHERE Day = ?"; q != want {
+		t.Errorf("partitioned query => %v, want %v", q, want)
+	}
+	r = NewNativePartitioned(nil, "others", eventTup{}, nil, WithDialect(partDialect{}))
+	if q, _, _ := r.(*sqlTable).queryString(); q != "SELECT DISTINCT ID, Day FROM others" {
+		t.Errorf("unpartitioned query => %v", q)
+	}
+}
+
+// test parsing the bounds of postgres range partitions
+func TestParseRangeBound(t *testing.T) {
+	jan, _ := time.Parse("2006-01-02", "2024-01-01")
+	feb, _ := time.Parse("2006-01-02", "2024-02-01")
+	var boundTest = []struct {
+		spec   string
+		lo, hi interface{}
+		isErr  bool
+	}{
+		{"FOR VALUES FROM ('2024-01-01') TO ('2024-02-01')", jan, feb, false},
+		{"FOR VALUES FROM (MINVALUE) TO (100)", nil, int64(100), false},
+		{"FOR VALUES FROM (100) TO (MAXVALUE)", int64(100), nil, false},
+		{"FOR VALUES FROM (0.5) TO (1.25)", Decimal("0.5"), Decimal("1.25"), false},
+		{"FOR VALUES FROM ('a') TO ('it''s')", "a", "it's", false},
+		{"DEFAULT", nil, nil, false},
+		{"FOR VALUES IN (1, 2)", nil, nil, true},
+	}
+	for i, tt := range boundTest {
+		lo, hi, err := parseRangeBound(tt.spec)
+		if !reflect.DeepEqual(lo, tt.lo) || !reflect.DeepEqual(hi, tt.hi) || (err != nil) != tt.isErr {
+			t.Errorf("%d has parseRangeBound(%q) => %v, %v, %v, want %v, %v", i, tt.spec, lo, hi, err, tt.lo, tt.hi)
+		}
+	}
+}
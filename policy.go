@@ -0,0 +1,72 @@
+package relsql
+
+import (
+	"fmt"
+)
+
+// Plan is a query that is about to be executed, along with the expression
+// tree that it was compiled from.
+type Plan struct {
+	Query *Query
+	SQL   string
+	Args  []interface{}
+}
+
+// Policy checks a query before it is executed.  It can reject the query by
+// returning an error, for example if it scans a table without a tenant
+// restriction, or rewrite it by changing the SQL and Args of the plan, for
+// example to add optimizer hints.
+type Policy func(p *Plan) error
+
+// PolicyError is the error of a query that was rejected by a policy
+type PolicyError struct {
+	SQL string
+	Err error
+}
+
+// Error returns the reason that the query was rejected
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("relsql: query rejected by policy: %v", e.Err)
+}
+
+// Unwrap returns the error of the policy
+func (e *PolicyError) Unwrap() error {
+	return e.Err
+}
+
+// WithPolicy adds a policy that every query of the relation has to pass
+// before it is executed.  Policies are applied in the order that they are
+// added, and each one sees the plan as rewritten by the ones before it.  The
+// SQL that is audited is the rewritten query.
+//
+// Besides the query that reads the relation's tuples, policies check the
+// queries that are made about the relation: the counts of the cross join
+// guard and of EstimateCard, the dialect's cardinality estimates, the hash
+// aggregate of Hash, freshness probes, and the queries that fetch lazy
+// values.  The plan of each of these has the relation's expression tree as
+// its Query, with the SQL of the query that is run.  A lazy value's query is
+// checked when its tuple is read, with the tuple's key as its Args.
+//
+// Statements that aren't about the relation's rows are exempt: the session
+// statements of a dialect, such as ALTER SESSION, pings, and the catalog
+// queries that find the keys and columns of tables.
+func WithPolicy(p Policy) Option {
+	return func(o *options) {
+		o.policies = append(o.policies, p)
+	}
+}
+
+// applyPolicies checks the relation's query with its policies, and returns
+// the query as rewritten by them.
+func (r1 *sqlTable) applyPolicies(q string, args []interface{}) (string, []interface{}, error) {
+	if len(r1.opts.policies) == 0 {
+		return q, args, nil
+	}
+	p := &Plan{r1.node(), q, args}
+	for _, policy := range r1.opts.policies {
+		if err := policy(p); err != nil {
+			return "", nil, &PolicyError{p.SQL, err}
+		}
+	}
+	return p.SQL, p.Args, nil
+}
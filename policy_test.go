@@ -0,0 +1,175 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+)
+
+// test rejecting and rewriting queries with policies
+func TestPolicy(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:policy?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type orderTup struct {
+		ID     int
+		Tenant string
+	}
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "orders", orderTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "orders", rel.New([]orderTup{{1, "acme"}, {2, "initech"}, {3, "acme"}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	// queries of the orders table have to be restricted to a tenant
+	errNoTenant := errors.New("orders must be restricted by tenant")
+	tenant := func(p *Plan) error {
+		for _, c := range p.Query.Where {
+			for _, col := range c.Columns {
+				if col.Name == "Tenant" {
+					return nil
+				}
+			}
+		}
+		return errNoTenant
+	}
+	var seen string
+	hint := func(p *Plan) error {
+		p.SQL = "/* reporting */ " + p.SQL
+		seen = p.SQL
+		return nil
+	}
+	orders := New(db, "orders", orderTup{}, keys, WithDialect(SQLite), WithPolicy(tenant), WithPolicy(hint))
+
+	var policyTest = []struct {
+		r    rel.Relation
+		n    int
+		fail bool
+	}{
+		{orders, 0, true},
+		{orders.Restrict(Attribute("ID").EQ(1)), 0, true},
+		{orders.Restrict(Attribute("Tenant").EQ("acme")), 2, false},
+	}
+	for i, tt := range policyTest {
+		seen = ""
+		ch := make(chan orderTup)
+		tt.r.TupleChan(ch)
+		n := 0
+		for range ch {
+			n++
+		}
+		err := tt.r.Err()
+		if n != tt.n || (err != nil) != tt.fail {
+			t.Errorf("%d read %d tuples with error %v, want %d and failure %v", i, n, err, tt.n, tt.fail)
+		}
+		var perr *PolicyError
+		if tt.fail && (!errors.As(err, &perr) || !errors.Is(err, errNoTenant)) {
+			t.Errorf("%d has Err() => %v, want a PolicyError", i, err)
+		}
+		if !tt.fail && !strings.HasPrefix(seen, "/* reporting */ SELECT") {
+			t.Errorf("%d executed %q, want the hint", i, seen)
+		}
+	}
+}
+
+// test that policies check the queries made about a relation, besides the
+// one that reads it
+func TestPolicyQueries(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:policyqueries?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type docTup struct {
+		ID        int
+		Body      Lazy
+		UpdatedAt int64
+	}
+	type tagTup struct {
+		Tag string
+	}
+	if err := CreateTable(db, "docs", docTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if err := CreateTable(db, "tags", tagTup{}, [][]string{[]string{"Tag"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "docs", rel.New([]docTup{{1, LazyBytes([]byte("body")), 100}}, nil)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var checked []string
+	mark := func(p *Plan) error {
+		p.SQL = "/* checked */ " + p.SQL
+		checked = append(checked, p.SQL)
+		return nil
+	}
+	opts := []Option{WithDialect(SQLite), WithPolicy(mark), WithFreshness("UpdatedAt"), WithCrossJoinLimit(100)}
+	docs := New(db, "docs", docTup{}, [][]string{[]string{"ID"}}, opts...)
+	tags := New(db, "tags", tagTup{}, [][]string{[]string{"Tag"}}, opts...)
+	ctx := context.Background()
+
+	var queryTest = []struct {
+		doc  string
+		f    func() error
+		want string
+	}{
+		{"cross join guard", func() error {
+			return drainErr(CrossJoin(docs.Project(struct{ ID int }{}), tags, struct {
+				ID  int
+				Tag string
+			}{}))
+		}, "/* checked */ SELECT COUNT(*) FROM (SELECT Tag FROM tags)"},
+		{"EstimateCard", func() error {
+			_, err := EstimateCard(ctx, docs)
+			return err
+		}, "/* checked */ SELECT ID, NULL AS Body, UpdatedAt FROM docs"},
+		{"LastModified", func() error {
+			_, err := LastModified(ctx, docs)
+			return err
+		}, "/* checked */ SELECT MAX(UpdatedAt) FROM docs"},
+		{"lazy value", func() error {
+			ch := make(chan docTup)
+			docs.TupleChan(ch)
+			var body string
+			var err error
+			for tup := range ch {
+				body, err = tup.Body.Text()
+			}
+			if err == nil && body != "body" {
+				err = errors.New("lazy value is " + body)
+			}
+			return err
+		}, "/* checked */ SELECT Body FROM docs WHERE ID = ?"},
+	}
+	for _, tt := range queryTest {
+		checked = nil
+		if err := tt.f(); err != nil {
+			t.Errorf("%s => %v", tt.doc, err)
+			continue
+		}
+		found := false
+		for _, q := range checked {
+			found = found || strings.HasPrefix(q, tt.want)
+		}
+		if !found {
+			t.Errorf("%s checked %q, want %q", tt.doc, checked, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package relsql
+
+import (
+	"reflect"
+)
+
+// WithBorrowedTuples makes the relation reuse the memory of the tuples it
+// sends, instead of allocating each of them, for consumers that read a large
+// number of tuples and copy what they need.  Tuples are still sent by value,
+// but their []byte attributes share memory with tuples that are sent later,
+// so they are only valid until the next tuple is received from the channel.
+func WithBorrowedTuples() Option {
+	return func(o *options) {
+		o.borrowed = true
+	}
+}
+
+// borrowedCount is the number of tuple buffers that a borrowing stream cycles
+// through, in addition to those queued in channels.  One is held by the
+// consumer while the next is being scanned.
+const borrowedCount = 2
+
+// tupleBuffers provides the tuples that rows are scanned into, along with
+// their scan destinations.
+type tupleBuffers struct {
+	d     Dialect
+	e     reflect.Type
+	exact bool
+
+	// values are the scan destinations of a tuple that isn't borrowed, which
+	// are reused for each row
+	values []interface{}
+
+	// borrowed are the buffers of a borrowing stream, and i is the index of
+	// the next one
+	borrowed *borrowedBuffers
+	i        int
+}
+
+// borrowedBuffers are the tuples, and their scan destinations, which are
+// reused for each row by a borrowing stream.
+type borrowedBuffers struct {
+	ptrs   []reflect.Value
+	values [][]interface{}
+}
+
+// newTupleBuffers returns the buffers for scanning rows into tuples of type e.
+// If borrow is more than zero, it is the number of tuples that are reused.
+func newTupleBuffers(d Dialect, e reflect.Type, exact bool, borrow int) *tupleBuffers {
+	b := &tupleBuffers{d: d, e: e, exact: exact}
+	n := e.NumField()
+	if n == 0 {
+		// zero degree relations still return a constant column
+		b.values = []interface{}{new(int)}
+		return b
+	}
+	b.values = make([]interface{}, n)
+	if borrow <= 0 {
+		return b
+	}
+	// the buffers belong to the stream, and aren't shared with other streams,
+	// because the last tuples it sends may still be queued after it finishes
+	bb := &borrowedBuffers{
+		ptrs:   make([]reflect.Value, borrow),
+		values: make([][]interface{}, borrow),
+	}
+	for i := range bb.values {
+		bb.ptrs[i] = reflect.New(e)
+		bb.values[i] = b.scanDests(bb.ptrs[i].Elem(), true)
+	}
+	b.borrowed = bb
+	return b
+}
+
+// scanDests returns the scan destinations for each field of the tuple
+func (b *tupleBuffers) scanDests(tup reflect.Value, borrow bool) []interface{} {
+	values := b.values
+	if borrow {
+		values = make([]interface{}, len(b.values))
+	}
+	for i := range values {
+		f := tup.Field(i)
+		if borrow && f.Type() == bytesType {
+			values[i] = &bytesScanner{f}
+		} else {
+			values[i] = scanDest(b.d, f, b.exact)
+		}
+	}
+	return values
+}
+
+// next returns a pointer to the tuple that the next row is scanned into, and
+// its scan destinations.
+func (b *tupleBuffers) next() (reflect.Value, []interface{}) {
+	if b.borrowed != nil {
+		i := b.i
+		b.i = (b.i + 1) % len(b.borrowed.values)
+		return b.borrowed.ptrs[i], b.borrowed.values[i]
+	}
+	ptr := reflect.New(b.e)
+	if b.e.NumField() == 0 {
+		return ptr, b.values
+	}
+	return ptr, b.scanDests(ptr.Elem(), false)
+}
+
+// bytesType is the type of []byte
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// bytesScanner scans a value into a []byte, reusing its memory
+type bytesScanner struct {
+	dst reflect.Value
+}
+
+// Scan copies the value into the []byte
+func (s *bytesScanner) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case []byte:
+		s.dst.SetBytes(append(s.dst.Bytes()[:0], v...))
+	case string:
+		s.dst.SetBytes(append(s.dst.Bytes()[:0], v...))
+	case nil:
+		s.dst.SetBytes(nil)
+	default:
+		return decodeError(src, s.dst)
+	}
+	return nil
+}
+
+// borrowCount returns the number of tuples that a stream of the relation
+// reuses when it sends them on a channel with the given capacity, or zero if
+// it doesn't borrow them.  Tuples that are queued in the channel, or by
+// prefetching along with the one being forwarded, are also still in use.
+func (r1 *sqlTable) borrowCount(capacity int) int {
+	if !r1.opts.borrowed || r1.opts.prefetchMemory > 0 || r1.opts.governor != nil {
+		// tuples that are read ahead by size can't be counted in advance
+		return 0
+	}
+	n := borrowedCount + capacity
+	if r1.opts.prefetch > 0 {
+		n += r1.opts.prefetch + 1
+	}
+	return n
+}
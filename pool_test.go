@@ -0,0 +1,124 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// test reading with borrowed tuples
+func TestBorrowedTuples(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:borrowed?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type blobTup struct {
+		ID   int
+		Data []byte
+	}
+	var tups []blobTup
+	for i := 0; i < 5; i++ {
+		tups = append(tups, blobTup{i, []byte{byte(i), byte(i), byte(i)}})
+	}
+	if err := CreateTable(db, "blobs", blobTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "blobs", rel.New(tups, [][]string{[]string{"ID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	r := New(db, "blobs", blobTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite), WithBorrowedTuples())
+	ch := make(chan blobTup)
+	r.TupleChan(ch)
+	var res []blobTup
+	var data []*byte
+	var shared bool
+	for tup := range ch {
+		if len(data) >= borrowedCount && &tup.Data[0] == data[len(data)-borrowedCount] {
+			shared = true
+		}
+		data = append(data, &tup.Data[0])
+		if !reflect.DeepEqual(tup, tups[tup.ID]) {
+			t.Errorf("borrowed tuple => %v, want %v", tup, tups[tup.ID])
+		}
+
+		// the consumer copies what it needs before the next tuple
+		tup.Data = append([]byte(nil), tup.Data...)
+		res = append(res, tup)
+	}
+	if len(res) != len(tups) {
+		t.Errorf("borrowed tuples => %d, want %d", len(res), len(tups))
+	}
+	if !shared {
+		t.Errorf("borrowed tuples don't share memory")
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+
+}
+
+// test that borrowed tuples aren't overwritten while they are queued
+func TestBorrowedTuplesQueued(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:borrowedqueued?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type blobTup struct {
+		ID   int
+		Data []byte
+	}
+	var tups []blobTup
+	for i := 0; i < 20; i++ {
+		tups = append(tups, blobTup{i, []byte{byte(i), byte(i), byte(i)}})
+	}
+	if err := CreateTable(db, "blobs", blobTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "blobs", rel.New(tups, [][]string{[]string{"ID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var tests = []struct {
+		name     string
+		capacity int
+		opts     []Option
+	}{
+		{"buffered", 4, nil},
+		{"prefetch", 0, []Option{WithPrefetch(3)}},
+		{"buffered prefetch", 4, []Option{WithPrefetch(3)}},
+	}
+	for _, tt := range tests {
+		opts := append([]Option{WithDialect(SQLite), WithBorrowedTuples()}, tt.opts...)
+		r := New(db, "blobs", blobTup{}, [][]string{[]string{"ID"}}, opts...)
+		ch := make(chan blobTup, tt.capacity)
+		r.TupleChan(ch)
+		n := 0
+		for tup := range ch {
+			// let the stream fill the queue before the tuple is checked
+			time.Sleep(time.Millisecond)
+			if !reflect.DeepEqual(tup, tups[tup.ID]) {
+				t.Errorf("%s: queued borrowed tuple => %v, want %v", tt.name, tup, tups[tup.ID])
+			}
+			n++
+		}
+		if n != len(tups) {
+			t.Errorf("%s: queued borrowed tuples => %d, want %d", tt.name, n, len(tups))
+		}
+		if err := r.Err(); err != nil {
+			t.Errorf("%s: Err() => %v", tt.name, err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Postgres is the dialect for PostgreSQL.  Arguments are bound to numbered
+// placeholders $1, $2, and so on, and it has native uuid and network types,
+// INSERT ... RETURNING and lateral joins.
+var Postgres Dialect = postgresDialect{}
+
+// postgresDialect is the dialect for PostgreSQL
+type postgresDialect struct{}
+
+// Name returns the name of the dialect
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+// Placeholder returns the placeholder for the i'th argument of a query
+func (postgresDialect) Placeholder(i int) string {
+	return fmt.Sprintf("$%d", i)
+}
+
+// ReadTx returns true, because each statement in postgres' default READ
+// COMMITTED isolation sees its own snapshot, so a read of several statements
+// needs a transaction to be consistent.
+func (postgresDialect) ReadTx() bool {
+	return true
+}
+
+// Returning returns true, because postgres supports INSERT ... RETURNING
+func (postgresDialect) Returning() bool {
+	return true
+}
+
+// NetworkTypes returns true, because postgres has inet, cidr and macaddr
+func (postgresDialect) NetworkTypes() bool {
+	return true
+}
+
+// LateralJoin returns CROSS JOIN LATERAL
+func (postgresDialect) LateralJoin() string {
+	return "CROSS JOIN LATERAL"
+}
+
+// Features declares TABLESAMPLE, which postgres has had since 9.5
+func (postgresDialect) Features() map[Feature]bool {
+	return map[Feature]bool{FeatureTableSample: true}
+}
+
+// UUIDFormat returns UUIDNative, because postgres has a uuid type
+func (postgresDialect) UUIDFormat() UUIDFormat {
+	return UUIDNative
+}
+
+// DurationFormat returns DurationInterval, because postgres has an interval
+// type, whose default output style is the one that intervals are scanned from
+func (postgresDialect) DurationFormat() DurationFormat {
+	return DurationInterval
+}
+
+// MapFormat returns MapHStore, so that maps are stored in the hstore type of
+// the hstore extension
+func (postgresDialect) MapFormat() MapFormat {
+	return MapHStore
+}
+
+// MapKey returns the value of a key in an hstore column with ->
+func (postgresDialect) MapKey(col, key string) string {
+	return col + " -> " + literal(key)
+}
+
+// Spatial returns the PostGIS function or operator for a spatial predicate,
+// so geometries are stored in PostGIS' geometry type
+func (postgresDialect) Spatial(op, col, geom, dist string) string {
+	switch op {
+	case "ST_DWithin":
+		return "ST_DWithin(" + col + ", ST_GeomFromText(" + geom + "), " + dist + ")"
+	case "&&":
+		return col + " && ST_GeomFromText(" + geom + ")"
+	}
+	return op + "(" + col + ", ST_GeomFromText(" + geom + "))"
+}
+
+// EstimateCard returns the planner's estimate of the rows of the query from
+// its EXPLAIN, which is as good as the table's statistics
+func (postgresDialect) EstimateCard(ctx context.Context, q QueryerContext, table, query string, args []interface{}) (int64, error) {
+	return explainEstimate(ctx, q, "EXPLAIN", query, args)
+}
+
+// TypeName returns BYTEA for byte slices and lazy values, because postgres
+// has no BLOB, and the ANSI names for other types
+func (postgresDialect) TypeName(t reflect.Type) string {
+	if t == lazyType || (t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8) {
+		return "BYTEA"
+	}
+	return ""
+}
+
+// ProcStyle returns ProcSelect, because postgres' set returning functions are
+// used in the FROM clause.
+func (postgresDialect) ProcStyle() ProcStyle {
+	return ProcSelect
+}
+
+// Partitions returns the key column and the partitions of a table that is
+// range partitioned by a single column, from the catalog.  Tables that are
+// partitioned by list, hash or several columns are read as a whole, as are
+// unpartitioned ones.
+func (postgresDialect) Partitions(db *sql.DB, tableName string) (key string, parts []Partition, err error) {
+	err = db.QueryRow(`SELECT a.attname FROM pg_partitioned_table p
+JOIN pg_attribute a ON a.attrelid = p.partrelid AND a.attnum = p.partattrs[0]
+WHERE p.partrelid = $1::regclass AND p.partstrat = 'r' AND p.partnatts = 1`, tableName).Scan(&key)
+	if err == sql.ErrNoRows {
+		return "", nil, nil
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	rows, err := db.Query(`SELECT c.oid::regclass::text, pg_get_expr(c.relpartbound, c.oid)
+FROM pg_inherits i JOIN pg_class c ON c.oid = i.inhrelid
+WHERE i.inhparent = $1::regclass ORDER BY 1`, tableName)
+	if err != nil {
+		return "", nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var part Partition
+		var spec string
+		if err := rows.Scan(&part.Table, &spec); err != nil {
+			return "", nil, err
+		}
+		if part.Lo, part.Hi, err = parseRangeBound(spec); err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, part)
+	}
+	return key, parts, rows.Err()
+}
+
+// parseRangeBound parses the bounds of a postgres range partition, like
+// FOR VALUES FROM ('2024-01-01') TO ('2024-02-01').  MINVALUE, MAXVALUE and
+// the DEFAULT partition are unbounded.  Quoted values are times if they have
+// the form of one, and otherwise text, and unquoted values are numbers.
+func parseRangeBound(spec string) (lo, hi interface{}, err error) {
+	if spec == "DEFAULT" {
+		return nil, nil, nil
+	}
+	const from, to = "FOR VALUES FROM (", ") TO ("
+	i := strings.Index(spec, to)
+	if !strings.HasPrefix(spec, from) || i < 0 || !strings.HasSuffix(spec, ")") {
+		return nil, nil, fmt.Errorf("relsql: invalid partition bound %q", spec)
+	}
+	if lo, err = parseBoundValue(spec[len(from):i]); err != nil {
+		return nil, nil, err
+	}
+	if hi, err = parseBoundValue(spec[i+len(to) : len(spec)-1]); err != nil {
+		return nil, nil, err
+	}
+	return lo, hi, nil
+}
+
+// parseBoundValue parses one value of a partition bound
+func parseBoundValue(s string) (interface{}, error) {
+	switch {
+	case s == "MINVALUE" || s == "MAXVALUE":
+		return nil, nil
+	case strings.HasPrefix(s, "'") && strings.HasSuffix(s, "'") && len(s) > 1:
+		text := strings.Replace(s[1:len(s)-1], "''", "'", -1)
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, text); err == nil {
+				return t, nil
+			}
+		}
+		return text, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return n, nil
+	}
+	if _, ok := new(big.Rat).SetString(s); ok {
+		return Decimal(s), nil
+	}
+	return nil, fmt.Errorf("relsql: invalid partition bound value %q", s)
+}
+
+func init() {
+	// lib/pq, and pgx's database/sql driver
+	RegisterDialect("postgres", Postgres)
+	RegisterDialect("pgx", Postgres)
+}
@@ -0,0 +1,350 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+)
+
+// Attribute is a named attribute whose comparisons can be compiled into sql.
+// It mirrors rel.Attribute, but the predicates it produces can be pushed down
+// to the database when they restrict a relation from New.
+type Attribute string
+
+// Pred is a predicate which can be compiled into a sql WHERE clause.  It also
+// implements rel.Predicate, so it can restrict any relation, in which case it
+// is evaluated client side.
+type Pred struct {
+	// Predicate is the equivalent client side predicate
+	rel.Predicate
+
+	// op is the sql comparison operator, or AND / OR for compound predicates
+	op string
+
+	// att is the attribute on the left side of a comparison
+	att rel.Attribute
+
+	// val is the right side of a comparison, which is either a rel.Attribute
+	// or a literal value that is bound as a query argument.
+	val interface{}
+
+	// preds are the operands of a compound predicate
+	preds []Pred
+}
+
+// attOrVal converts relsql attributes to rel attributes, and leaves any
+// other value alone.
+func attOrVal(v interface{}) interface{} {
+	if att, ok := v.(Attribute); ok {
+		return rel.Attribute(att)
+	}
+	return v
+}
+
+// EQ creates an equality predicate from an attribute and another attribute or
+// value
+func (att Attribute) EQ(v interface{}) Pred {
+	v = attOrVal(v)
+	return Pred{rel.Attribute(att).EQ(v), "=", rel.Attribute(att), v, nil}
+}
+
+// NE creates a not equal predicate from an attribute and another attribute or
+// value
+func (att Attribute) NE(v interface{}) Pred {
+	v = attOrVal(v)
+	return Pred{rel.Attribute(att).NE(v), "<>", rel.Attribute(att), v, nil}
+}
+
+// LT creates a less than predicate from an attribute and another attribute or
+// value
+func (att Attribute) LT(v interface{}) Pred {
+	v = attOrVal(v)
+	return Pred{rel.Attribute(att).LT(v), "<", rel.Attribute(att), v, nil}
+}
+
+// LE creates a less than or equal predicate from an attribute and another
+// attribute or value
+func (att Attribute) LE(v interface{}) Pred {
+	v = attOrVal(v)
+	return Pred{rel.Attribute(att).LE(v), "<=", rel.Attribute(att), v, nil}
+}
+
+// GT creates a greater than predicate from an attribute and another attribute
+// or value
+func (att Attribute) GT(v interface{}) Pred {
+	v = attOrVal(v)
+	return Pred{rel.Attribute(att).GT(v), ">", rel.Attribute(att), v, nil}
+}
+
+// GE creates a greater than or equal predicate from an attribute and another
+// attribute or value
+func (att Attribute) GE(v interface{}) Pred {
+	v = attOrVal(v)
+	return Pred{rel.Attribute(att).GE(v), ">=", rel.Attribute(att), v, nil}
+}
+
+// In creates a predicate that is true when the attribute is equal to any of
+// the values.
+func (att Attribute) In(v1 interface{}, vs ...interface{}) Pred {
+	var rp rel.Predicate = rel.Attribute(att).EQ(v1)
+	for _, v := range vs {
+		rp = rp.Or(rel.Attribute(att).EQ(v))
+	}
+	return Pred{rp, "IN", rel.Attribute(att), append([]interface{}{v1}, vs...), nil}
+}
+
+// And creates a predicate that is true when all of the input predicates are
+// true.
+func And(p1 Pred, ps ...Pred) Pred {
+	if len(ps) == 0 {
+		return p1
+	}
+	var rp rel.Predicate = p1.Predicate
+	for _, p := range ps {
+		rp = rp.And(p.Predicate)
+	}
+	return Pred{Predicate: rp, op: "AND", preds: append([]Pred{p1}, ps...)}
+}
+
+// Or creates a predicate that is true when any of the input predicates are
+// true.
+func Or(p1 Pred, ps ...Pred) Pred {
+	if len(ps) == 0 {
+		return p1
+	}
+	var rp rel.Predicate = p1.Predicate
+	for _, p := range ps {
+		rp = rp.Or(p.Predicate)
+	}
+	return Pred{Predicate: rp, op: "OR", preds: append([]Pred{p1}, ps...)}
+}
+
+// clientPred is the client side form of a predicate on a single attribute
+// which rel has no equivalent for.
+type clientPred struct {
+	att rel.Attribute
+	str string
+
+	// test is applied to the value of the attribute
+	test func(v interface{}) bool
+}
+
+// Domain returns the attribute of the predicate
+func (p clientPred) Domain() []rel.Attribute {
+	return []rel.Attribute{p.att}
+}
+
+// String returns a text representation of the predicate
+func (p clientPred) String() string {
+	return p.str
+}
+
+// And creates a predicate that is true when both predicates are true.
+// Conjunction commutes, so the other predicate's And can be used.
+func (p clientPred) And(p2 rel.Predicate) rel.AndPred {
+	return p2.And(p)
+}
+
+// Or creates a predicate that is true when either predicate is true.
+// Disjunction commutes, so the other predicate's Or can be used.
+func (p clientPred) Or(p2 rel.Predicate) rel.OrPred {
+	return p2.Or(p)
+}
+
+// EvalFunc returns a function that applies the test to a tuple's attribute
+func (p clientPred) EvalFunc(e reflect.Type) func(t interface{}) bool {
+	f, _ := e.FieldByName(string(p.att))
+	return func(t interface{}) bool {
+		return p.test(reflect.ValueOf(t).FieldByIndex(f.Index).Interface())
+	}
+}
+
+// conjuncts splits a predicate into the predicates that are and'ed together
+// in it.
+func (p Pred) conjuncts() []Pred {
+	if p.op != "AND" {
+		return []Pred{p}
+	}
+	var res []Pred
+	for _, p2 := range p.preds {
+		res = append(res, p2.conjuncts()...)
+	}
+	return res
+}
+
+// attributes returns the attributes referenced by the predicate
+func (p Pred) attributes() []rel.Attribute {
+	if p.preds != nil {
+		var res []rel.Attribute
+		for _, p2 := range p.preds {
+			res = append(res, p2.attributes()...)
+		}
+		return res
+	}
+	if att2, ok := p.val.(rel.Attribute); ok {
+		return []rel.Attribute{p.att, att2}
+	}
+	return []rel.Attribute{p.att}
+}
+
+// swapped holds the operator to use when the sides of a comparison swap
+var swapped = map[string]string{
+	"=":  "=",
+	"<>": "<>",
+	"<":  ">",
+	"<=": ">=",
+	">":  "<",
+	">=": "<=",
+}
+
+// build returns the sql form of the predicate, with literal values replaced by
+// placeholders whose arguments are added to the builder.  cols maps the
+// attributes to the sql expressions that produce them.  Comparisons between
+// two attributes are written in a canonical order so that equivalent
+// predicates produce identical sql.
+func (p Pred) build(b *builder, cols map[rel.Attribute]column) string {
+	if p.preds != nil {
+		strs := make([]string, len(p.preds))
+		for i, p2 := range p.preds {
+			strs[i] = p2.build(b, cols)
+		}
+		return "(" + strings.Join(strs, " "+p.op+" ") + ")"
+	}
+	left := cols[p.att].String()
+	if ea, ok := p.val.(exprArg); ok {
+		left, p.val = ea.x.on(b.dialect, left), ea.val
+	}
+	if att2, ok := p.val.(rel.Attribute); ok {
+		op, right := p.op, cols[att2].String()
+		if right < left {
+			left, op, right = right, swapped[op], left
+		}
+		return left + " " + op + " " + right
+	}
+	if l, ok := p.val.(mapLookup); ok {
+		if m, ok := b.dialect.(mapKeyer); ok {
+			left = m.MapKey(left, l.key)
+		} else {
+			// only used to compare conditions
+			left += "[" + literal(l.key) + "]"
+		}
+		return left + " " + p.op + " " + b.arg(l.val)
+	}
+	if sa, ok := p.val.(spatialArg); ok {
+		return buildSpatial(b, p.op, left, sa)
+	}
+	if fa, ok := p.val.(fieldAccess); ok {
+		return "(" + left + ")." + fa.field + " " + p.op + " " + b.arg(fa.val)
+	}
+	if o, ok := p.val.(Outer); ok {
+		return left + " " + p.op + " " + b.outerRef(o)
+	}
+	if p.op == "IN" {
+		vs := p.val.([]interface{})
+		strs := make([]string, len(vs))
+		for i, v := range vs {
+			strs[i] = b.arg(v)
+		}
+		return left + " IN (" + strings.Join(strs, ", ") + ")"
+	}
+	return left + " " + p.op + " " + b.arg(p.val)
+}
+
+// pushable returns true if the predicate can be compiled into sql for the
+// dialect.
+func (p Pred) pushable(d Dialect) bool {
+	for _, p2 := range p.preds {
+		if !p2.pushable(d) {
+			return false
+		}
+	}
+	if _, ok := p.val.(mapLookup); ok {
+		if _, ok := d.(mapKeyer); !ok {
+			return false
+		}
+	}
+	if _, ok := p.val.(spatialArg); ok {
+		return spatialPushable(d, p.op)
+	}
+	if ea, ok := p.val.(exprArg); ok && ea.x.sql(d) == "" {
+		return false
+	}
+	return p.op != "<<" || networkTypes(d)
+}
+
+// condition is a predicate in a WHERE clause, along with the sql expressions
+// for the attributes in the predicate when it was applied.  The attributes have
+// to be resolved at that point because later projections and renames may
+// remove or rename them.
+type condition struct {
+	pred Pred
+	cols map[rel.Attribute]column
+}
+
+// newCondition resolves the attributes in p against the heading of e and the
+// corresponding sql column expressions.
+func newCondition(p Pred, e reflect.Type, colNames []column) (condition, error) {
+	cols := make(map[rel.Attribute]column)
+	for _, att := range p.attributes() {
+		f, ok := e.FieldByName(string(att))
+		if !ok {
+			return condition{}, fmt.Errorf("relsql: predicate attribute %s is not in the heading of %v", att, e)
+		}
+		cols[att] = colNames[f.Index[0]]
+	}
+	return condition{p, cols}, nil
+}
+
+// key returns a normalized representation of the condition, which is used to
+// detect duplicate conditions.
+func (c condition) key() string {
+	var b builder
+	str := c.pred.build(&b, c.cols)
+	for _, arg := range b.args {
+		str += fmt.Sprintf(" %T(%#v)", arg, arg)
+	}
+	return str
+}
+
+// addConditions returns the conditions in where, followed by the conjuncts of
+// the new condition c which are not already present.
+func addConditions(where []condition, c condition) []condition {
+	seen := make(map[string]bool)
+	for _, c1 := range where {
+		seen[c1.key()] = true
+	}
+	res := make([]condition, len(where), len(where)+1)
+	copy(res, where)
+	for _, p := range c.pred.conjuncts() {
+		c2 := condition{p, c.cols}
+		if k := c2.key(); !seen[k] {
+			seen[k] = true
+			res = append(res, c2)
+		}
+	}
+	return res
+}
+
+// whereString returns the body of a WHERE clause for the conditions.
+func whereString(b *builder, where []condition) string {
+	strs := make([]string, len(where))
+	for i, c := range where {
+		strs[i] = c.pred.build(b, c.cols)
+	}
+	return strings.Join(strs, " AND ")
+}
+
+// predString returns the text representation of the conditions, which is
+// used in the String of a restricted relation.  Values compared to redacted
+// columns are masked.
+func predString(where []condition, o *options) string {
+	strs := make([]string, len(where))
+	for i, c := range where {
+		cols := c.cols
+		strs[i] = c.pred.redactedString(func(att rel.Attribute) bool {
+			return o.isRedacted(cols[att].name)
+		})
+	}
+	return strings.Join(strs, " ∧ ")
+}
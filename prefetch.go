@@ -0,0 +1,129 @@
+package relsql
+
+import (
+	"reflect"
+)
+
+// WithPrefetch lets a relation read up to n tuples ahead of its consumer, so
+// that a slow consumer doesn't hold the database cursor open while it works,
+// and a fast consumer doesn't wait on a round trip to the database for each
+// tuple.  The tuples are queued separately from the channel passed to
+// TupleChan, which can still be unbuffered.
+func WithPrefetch(n int) Option {
+	return func(o *options) {
+		o.prefetch = n
+	}
+}
+
+// prefetch returns a channel with a buffer of n tuples, which are forwarded
+// to res until the returned channel is closed, which then closes res, or
+// until cancel is closed.
+func prefetch(res reflect.Value, n int, cancel <-chan struct{}) reflect.Value {
+	queue := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, res.Type().Elem()), n)
+	go func() {
+		canSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cancel)}
+		queueSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: queue}
+		resSel := reflect.SelectCase{Dir: reflect.SelectSend, Chan: res}
+		for {
+			chosen, tup, ok := reflect.Select([]reflect.SelectCase{canSel, queueSel})
+			if chosen == 0 {
+				return
+			}
+			if !ok {
+				res.Close()
+				return
+			}
+			resSel.Send = tup
+			if chosen, _, _ := reflect.Select([]reflect.SelectCase{canSel, resSel}); chosen == 0 {
+				return
+			}
+		}
+	}()
+	return queue
+}
+
+// WithPrefetchMemory lets a relation read ahead of its consumer like
+// WithPrefetch, but up to a budget of bytes instead of a number of tuples, so
+// that narrow tuples are read far ahead while wide ones, with large strings or
+// blobs, aren't.  The size of each tuple is estimated from its type and the
+// lengths of its strings and byte slices, and a tuple larger than the budget
+// is still read one at a time.  If WithPrefetch is also set, it limits the
+// number of tuples as well.  Tuples read ahead this way are never borrowed.
+func WithPrefetchMemory(budget int64) Option {
+	return func(o *options) {
+		o.prefetchMemory = budget
+	}
+}
+
+// tupleSize estimates the number of bytes held by a tuple
+func tupleSize(tup reflect.Value) int64 {
+	n := int64(tup.Type().Size())
+	for i := 0; i < tup.NumField(); i++ {
+		switch f := tup.Field(i); f.Kind() {
+		case reflect.String:
+			n += int64(f.Len())
+		case reflect.Slice:
+			if f.Type().Elem().Kind() == reflect.Uint8 {
+				n += int64(f.Len())
+			}
+		}
+	}
+	return n
+}
+
+// prefetchMemory returns a channel whose tuples are held until they are sent
+// to res, while their size is within the budget, if it is more than zero,
+// and the governor's, and while there are fewer than max of them, if it is
+// more than zero.  res is closed once the returned channel has been closed and
+// the held tuples have been sent, and nothing more is sent once cancel is
+// closed.
+func prefetchMemory(res reflect.Value, budget int64, max int, g *MemoryGovernor, cancel <-chan struct{}) reflect.Value {
+	queue := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, res.Type().Elem()), 0)
+	go func() {
+		var held []reflect.Value
+		var sizes []int64
+		var used int64
+		defer func() { g.release(used) }()
+		open := true
+		canSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cancel)}
+		queueSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: queue}
+		for open || len(held) > 0 {
+			cases := []reflect.SelectCase{canSel}
+			room := len(held) == 0 || (budget <= 0 || used < budget) && (max <= 0 || len(held) < max)
+			if open && room && len(held) > 0 {
+				// wait for the governor, unless this scan holds nothing
+				var freed <-chan struct{}
+				if room, freed = g.room(); !room {
+					cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(freed)})
+				}
+			}
+			if open && room {
+				cases = append(cases, queueSel)
+			}
+			if len(held) > 0 {
+				cases = append(cases, reflect.SelectCase{Dir: reflect.SelectSend, Chan: res, Send: held[0]})
+			}
+			chosen, tup, ok := reflect.Select(cases)
+			switch {
+			case chosen == 0:
+				return
+			case cases[chosen].Chan != queue && cases[chosen].Dir == reflect.SelectRecv:
+				// the governor has room again
+			case cases[chosen].Dir == reflect.SelectRecv && !ok:
+				open = false
+			case cases[chosen].Dir == reflect.SelectRecv:
+				size := tupleSize(tup)
+				held = append(held, tup)
+				sizes = append(sizes, size)
+				used += size
+				g.hold(size)
+			default:
+				used -= sizes[0]
+				g.release(sizes[0])
+				held, sizes = held[1:], sizes[1:]
+			}
+		}
+		res.Close()
+	}()
+	return queue
+}
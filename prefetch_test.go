@@ -0,0 +1,132 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// test reading ahead of a slow consumer
+func TestPrefetch(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:prefetch?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type numTup struct {
+		N int
+	}
+	var tups []numTup
+	for i := 0; i < 10; i++ {
+		tups = append(tups, numTup{i})
+	}
+	if err := CreateTable(db, "nums", numTup{}, [][]string{[]string{"N"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "nums", rel.New(tups, [][]string{[]string{"N"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var prefetchTest = []struct {
+		opts []Option
+
+		// done is true if the whole result fits in the prefetch queue, so
+		// the query finishes before the consumer reads most of it
+		done bool
+	}{
+		{[]Option{WithPrefetch(20)}, true},
+		{[]Option{WithPrefetch(4), WithBorrowedTuples()}, false},
+		{nil, false},
+	}
+	for i, tt := range prefetchTest {
+		r := New(db, "nums", numTup{}, [][]string{[]string{"N"}}, append(tt.opts, WithDialect(SQLite))...)
+		ch := make(chan numTup)
+		r.TupleChan(ch)
+		res := []numTup{<-ch}
+		time.Sleep(20 * time.Millisecond)
+		if done := db.Stats().InUse == 0; done != tt.done {
+			t.Errorf("%d has query done => %v, want %v", i, done, tt.done)
+		}
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		if !reflect.DeepEqual(res, tups) {
+			t.Errorf("%d has tuples => %v, want %v", i, res, tups)
+		}
+		if err := r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+	}
+
+	// cancelling stops the producer and the forwarder
+	r := New(db, "nums", numTup{}, [][]string{[]string{"N"}}, WithDialect(SQLite), WithPrefetch(2))
+	ch := make(chan numTup)
+	cancel := r.TupleChan(ch)
+	<-ch
+	close(cancel)
+	time.Sleep(20 * time.Millisecond)
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("connections in use after cancel => %d", n)
+	}
+}
+
+// test reading ahead up to a memory budget
+func TestPrefetchMemory(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:prefetchmemory?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type docTup struct {
+		N    int
+		Body []byte
+	}
+	var narrow, wide []docTup
+	for i := 0; i < 10; i++ {
+		narrow = append(narrow, docTup{i, []byte("x")})
+		wide = append(wide, docTup{i, make([]byte, 1000)})
+	}
+	var prefetchTest = []struct {
+		table string
+		tups  []docTup
+		opts  []Option
+		done  bool
+	}{
+		{"narrow", narrow, []Option{WithPrefetchMemory(2000)}, true},
+		{"wide", wide, []Option{WithPrefetchMemory(2000)}, false},
+		{"narrowmax", narrow, []Option{WithPrefetchMemory(2000), WithPrefetch(3)}, false},
+		{"wideborrowed", wide, []Option{WithPrefetchMemory(20000), WithBorrowedTuples()}, true},
+	}
+	for i, tt := range prefetchTest {
+		if err := CreateTable(db, tt.table, docTup{}, [][]string{[]string{"N"}}); err != nil {
+			t.Errorf("CreateTable() => %v", err)
+			return
+		}
+		if _, err := Insert(db, tt.table, rel.New(tt.tups, [][]string{[]string{"N"}})); err != nil {
+			t.Errorf("Insert() => %v", err)
+			return
+		}
+		r := New(db, tt.table, docTup{}, [][]string{[]string{"N"}}, append(tt.opts, WithDialect(SQLite))...)
+		ch := make(chan docTup)
+		r.TupleChan(ch)
+		res := []docTup{<-ch}
+		time.Sleep(20 * time.Millisecond)
+		if done := db.Stats().InUse == 0; done != tt.done {
+			t.Errorf("%d has query done => %v, want %v", i, done, tt.done)
+		}
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		if !reflect.DeepEqual(res, tt.tups) || r.Err() != nil {
+			t.Errorf("%d has tuples => %d, %v, want %d", i, len(res), r.Err(), len(tt.tups))
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package relsql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+)
+
+// Preview returns the first n tuples of a relation, for a quick look at it
+// in a test or a notebook.  T is the type of the relation's tuples.  If the
+// relation is a single query, it is limited to n rows by the database, with
+// the dialect's LIMIT or FETCH FIRST, so the rest of the table isn't read.
+// Any other relation stops being read once it has produced n tuples.  The
+// tuples are in the order that the database returns them, which is only
+// stable for ordered relations.  Reading stops with ctx's error when it is
+// canceled.
+func Preview[T any](ctx context.Context, r rel.Relation, n int) ([]T, error) {
+	if e := reflect.TypeOf(*new(T)); e != reflect.TypeOf(r.Zero()) {
+		return nil, fmt.Errorf("relsql: can't preview tuples of %v as %v", reflect.TypeOf(r.Zero()), e)
+	}
+	if err := r.Err(); err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	res := make([]T, 0, n)
+	if n <= 0 {
+		return res, nil
+	}
+	if r1, ok := r.(*sqlTable); ok && r1.composable() {
+		r2 := *r1
+		r2.limit = n
+		r = &r2
+	}
+	ch := make(chan T)
+	cancel := r.TupleChan(ch)
+	for len(res) < n {
+		select {
+		case tup, ok := <-ch:
+			if !ok {
+				return res, r.Err()
+			}
+			res = append(res, tup)
+		case <-ctx.Done():
+			close(cancel)
+			return res, ctx.Err()
+		}
+	}
+	close(cancel)
+	return res, nil
+}
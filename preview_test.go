@@ -0,0 +1,70 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"testing"
+)
+
+// test previewing the first tuples of relations
+func TestPreview(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:preview?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type itemTup struct {
+		ID  int
+		Qty int
+	}
+	keys := [][]string{[]string{"ID"}}
+	items := []itemTup{{1, 5}, {2, 0}, {3, 7}, {4, 2}, {5, 9}}
+	if err := CreateTable(db, "items", itemTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "items", rel.New(items, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "items", itemTup{}, keys, WithDeterministicOrder())
+	ctx := context.Background()
+
+	var previewTest = []struct {
+		r    rel.Relation
+		n    int
+		want string
+	}{
+		{table, 2, "[{1 5} {2 0}]"},
+		{table.Restrict(Attribute("Qty").GT(4)), 2, "[{1 5} {3 7}]"},
+		{table, 10, "[{1 5} {2 0} {3 7} {4 2} {5 9}]"},
+		{table, 0, "[]"},
+		{rel.New(items, keys).Restrict(rel.Attribute("Qty").LT(3)), 1, "[{2 0}]"},
+	}
+	for i, tt := range previewTest {
+		res, err := Preview[itemTup](ctx, tt.r, tt.n)
+		if err != nil || fmt.Sprint(res) != tt.want {
+			t.Errorf("%d has Preview() => %v, %v, want %s", i, res, err, tt.want)
+		}
+	}
+
+	// the database limits the query
+	r2 := *table.(*sqlTable)
+	r2.limit = 2
+	if q, _, err := r2.queryString(); q != "SELECT ID, Qty FROM items ORDER BY ID LIMIT 2" || err != nil {
+		t.Errorf("queryString() => %q, %v", q, err)
+	}
+
+	if _, err := Preview[struct{ ID int }](ctx, table, 1); err == nil {
+		t.Errorf("Preview() with the wrong tuple type succeeded")
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := Preview[itemTup](canceled, table, 1); err != context.Canceled {
+		t.Errorf("Preview() with a canceled context => %v", err)
+	}
+}
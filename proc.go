@@ -0,0 +1,122 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"strings"
+)
+
+// ProcStyle is how a dialect invokes stored procedures and set returning
+// functions.
+type ProcStyle int
+
+const (
+	// ProcCall executes a CALL statement.  The result can't be used in a
+	// larger query, so any operations on it are evaluated client side.
+	ProcCall ProcStyle = iota
+
+	// ProcSelect selects from the function in the FROM clause, like
+	// SELECT * FROM fn(?, ?), so that the result can be restricted,
+	// projected and joined by the database like a table.
+	ProcSelect
+
+	// ProcTable is like ProcSelect, but the function is wrapped in TABLE,
+	// like SELECT * FROM TABLE(fn(?, ?)).
+	ProcTable
+)
+
+// procStyler is implemented by dialects which can select from functions
+type procStyler interface {
+	ProcStyle() ProcStyle
+}
+
+// procStyle returns how the dialect invokes procedures
+func procStyle(d Dialect) ProcStyle {
+	if p, ok := d.(procStyler); ok {
+		return p.ProcStyle()
+	}
+	return ProcCall
+}
+
+// procSource is a stored procedure or set returning function, with the
+// arguments it is called with.
+type procSource struct {
+	name string
+	args []interface{}
+
+	// style is how the procedure is invoked
+	style ProcStyle
+}
+
+// build returns the invocation of the procedure, with its arguments added to
+// the builder.
+func (p *procSource) build(b *builder, needed map[column]bool) string {
+	strs := make([]string, len(p.args))
+	for i, arg := range p.args {
+		strs[i] = b.arg(arg)
+	}
+	call := p.name + "(" + strings.Join(strs, ", ") + ")"
+	if p.style == ProcTable {
+		return "TABLE(" + call + ")"
+	}
+	return call
+}
+
+// NewFromProc creates a relation from the rows returned by a stored procedure
+// or set returning function, called with the arguments.  The columns of the
+// result are matched to the attributes of z by name in dialects with the
+// ProcSelect or ProcTable style, and by position in dialects that use CALL.
+// The procedure is executed each time the relation is enumerated, and its
+// arguments can be replaced with Bind.
+func NewFromProc(db *sql.DB, procName string, args []interface{}, z interface{}, ckeystr [][]string, opts ...Option) rel.Relation {
+	r := New(db, procName, z, ckeystr, opts...).(*sqlTable)
+	style := procStyle(r.dialect())
+	r.src = &procSource{procName, args, style}
+	if r.err == nil && style == ProcCall && len(r.where) > 0 {
+		r.err = fmt.Errorf("relsql: mandatory predicates can't be applied to CALL %s", procName)
+	}
+	return r
+}
+
+// composable returns true if the relation's query can be used in a larger
+// query, which is true unless it calls a procedure.
+func (r1 *sqlTable) composable() bool {
+	p, ok := r1.src.(*procSource)
+	return !ok || p.style != ProcCall
+}
+
+// Bind returns a relation like r1, but with the procedure or function that
+// it reads from called with new arguments.  Restrictions, projections and
+// renames of r1 are kept, so a parameterized dataset can be defined once and
+// enumerated with different parameters.
+func (r1 *sqlTable) Bind(args ...interface{}) rel.Relation {
+	r2 := *r1
+	p, ok := r1.src.(*procSource)
+	if !ok {
+		r2.err = fmt.Errorf("relsql: can't bind arguments of %v, which is not read from a function", r1)
+		return &r2
+	}
+	r2.src = &procSource{p.name, args, p.style}
+	return &r2
+}
+
+// Bind returns a relation like r, but with the procedure or function that it
+// reads from called with new arguments.  r has to be derived from
+// NewFromProc through restrictions, projections and renames, which in
+// dialects that use CALL are evaluated client side, and are applied again to
+// the rebound procedure.  rel.Relation has no Bind, so this is provided as a
+// function.
+func Bind(r rel.Relation, args ...interface{}) rel.Relation {
+	switch r1 := r.(type) {
+	case *sqlTable:
+		return r1.Bind(args...)
+	case *clientSide:
+		return r1.bind(args)
+	}
+	return &sqlTable{
+		zero:  r.Zero(),
+		cKeys: r.CKeys(),
+		err:   fmt.Errorf("relsql: can't bind arguments of %v, which is not read from a function", r),
+	}
+}
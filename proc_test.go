@@ -0,0 +1,164 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test relations from set returning functions
+func TestNewFromProc(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type elemTup struct {
+		Key   int
+		Value string
+	}
+	ckeys := [][]string{[]string{"Key"}}
+	elems := NewFromProc(db, "json_each", []interface{}{`["a","b","c"]`}, elemTup{}, ckeys, WithDialect(SQLite))
+	r := elems.Restrict(Attribute("Key").GE(1))
+	q, args, _ := r.(*sqlTable).queryString()
+	if want := "SELECT Key, Value FROM json_each(?) WHERE Key >= ?"; q != want || len(args) != 2 {
+		t.Errorf("query => %v %v, want %v", q, args, want)
+	}
+	ch := make(chan elemTup)
+	r.TupleChan(ch)
+	var res []elemTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if want := []elemTup{{1, "b"}, {2, "c"}}; !reflect.DeepEqual(res, want) || r.Err() != nil {
+		t.Errorf("elements => %v, %v, want %v", res, r.Err(), want)
+	}
+
+	// dialects without table functions use CALL, which can't be composed
+	call := NewFromProc(db, "list_elems", []interface{}{1, "x"}, elemTup{}, ckeys, WithDialect(ANSI))
+	if q, _, _ := call.(*sqlTable).queryString(); q != "CALL list_elems(?, ?)" {
+		t.Errorf("call query => %v", q)
+	}
+	if _, ok := call.Restrict(Attribute("Key").EQ(1)).(*sqlTable); ok {
+		t.Errorf("restriction of a procedure call was pushed down")
+	}
+	if _, ok := call.Project(struct{ Value string }{}).(*sqlTable); ok {
+		t.Errorf("projection of a procedure call was pushed down")
+	}
+}
+
+// test the queries of function sources in each dialect
+func TestProcStyle(t *testing.T) {
+	type elemTup struct {
+		Key   int
+		Value string
+	}
+	ckeys := [][]string{[]string{"Key"}}
+	var procTest = []struct {
+		d     Dialect
+		style ProcStyle
+		want  string
+	}{
+		{SQLite, ProcSelect, "SELECT Key, Value FROM elems(?, ?) WHERE Key >= ?"},
+		{Postgres, ProcSelect, "SELECT Key, Value FROM elems($1, $2) WHERE Key >= $3"},
+		{BigQuery, ProcTable, "SELECT Key, Value FROM TABLE(elems(@p1, @p2)) WHERE Key >= @p3"},
+		{Snowflake, ProcTable, "SELECT DISTINCT Key, Value FROM TABLE(elems(?, ?)) WHERE Key >= ?"},
+	}
+	for i, tt := range procTest {
+		if style := procStyle(tt.d); style != tt.style {
+			t.Errorf("%d has procStyle() => %v, want %v", i, style, tt.style)
+		}
+		r := NewFromProc(nil, "elems", []interface{}{1, "x"}, elemTup{}, ckeys, WithDialect(tt.d)).Restrict(Attribute("Key").GE(1))
+		r1, ok := r.(*sqlTable)
+		if !ok {
+			t.Errorf("%d has restriction of %v, which was not pushed down", i, tt.d.Name())
+			continue
+		}
+		if q, args, err := r1.queryString(); q != tt.want || len(args) != 3 || err != nil {
+			t.Errorf("%d has query => %v %v %v, want %v", i, q, args, err, tt.want)
+		}
+	}
+}
+
+// test rebinding the arguments of a function source
+func TestBind(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type elemTup struct {
+		Key   int
+		Value string
+	}
+	type valueTup struct {
+		Value string
+	}
+	elems := NewFromProc(db, "json_each", nil, elemTup{}, [][]string{[]string{"Key"}}, WithDialect(SQLite))
+	tail := elems.Restrict(Attribute("Key").GE(1)).Project(valueTup{})
+
+	var bindTest = []struct {
+		doc  string
+		want []valueTup
+	}{
+		{`["a","b"]`, []valueTup{{"b"}}},
+		{`["x","y","z"]`, []valueTup{{"y"}, {"z"}}},
+	}
+	for i, tt := range bindTest {
+		r := Bind(tail, tt.doc)
+		ch := make(chan valueTup)
+		r.TupleChan(ch)
+		var res []valueTup
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		if !reflect.DeepEqual(res, tt.want) || r.Err() != nil {
+			t.Errorf("%d has values => %v, %v, want %v", i, res, r.Err(), tt.want)
+		}
+	}
+
+	if r := Bind(New(db, "elems", elemTup{}, nil), 1); r.Err() == nil {
+		t.Errorf("Bind() of a table has Err() => nil")
+	}
+
+	// restrictions and projections of procedure calls are evaluated client
+	// side, and are applied again to the rebound call
+	call := NewFromProc(db, "list_elems", []interface{}{1}, elemTup{}, nil, WithDialect(ANSI))
+	var callTest = []rel.Relation{
+		call.Restrict(Attribute("Key").GE(1)),
+		call.Project(valueTup{}),
+		call.Restrict(Attribute("Key").GE(1)).Project(valueTup{}).Rename(struct{ V string }{}),
+	}
+	for i, r := range callTest {
+		r2 := Bind(r, 2)
+		c, ok := r2.(*clientSide)
+		if !ok || r2.Err() != nil {
+			t.Errorf("%d has Bind() => %v, %v", i, r2, r2.Err())
+			continue
+		}
+		if args := c.input.src.(*procSource).args; !reflect.DeepEqual(args, []interface{}{2}) {
+			t.Errorf("%d has Bind() call arguments => %v, want [2]", i, args)
+		}
+		var ops, ops2 []string
+		Inspect(r, func(n Node) bool {
+			if c, ok := n.(*Client); ok {
+				ops = append(ops, c.Op)
+			}
+			return true
+		})
+		Inspect(r2, func(n Node) bool {
+			if c, ok := n.(*Client); ok {
+				ops2 = append(ops2, c.Op)
+			}
+			return true
+		})
+		if !reflect.DeepEqual(ops2, ops) || len(ops) == 0 || rel.HeadingString(r2) != rel.HeadingString(r) || len(Diagnostics(r2)) != 1 {
+			t.Errorf("%d has Bind() => %v with client side %v, want %v with %v", i, r2, ops2, r, ops)
+		}
+	}
+}
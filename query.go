@@ -0,0 +1,316 @@
+package relsql
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// builder accumulates the arguments of a query, and the first error that
+// occurs, while the query is being compiled.
+type builder struct {
+	dialect Dialect
+	args    []interface{}
+	err     error
+
+	// outer is the alias of the outer relation of the lateral join that is
+	// being built, if any
+	outer string
+}
+
+// dialectOrANSI returns the dialect of the query, or ANSI if there is none
+func (b *builder) dialectOrANSI() Dialect {
+	if b.dialect == nil {
+		return ANSI
+	}
+	return b.dialect
+}
+
+// alias returns the clause that names a derived table, including a leading
+// space.
+func (b *builder) alias(name string) string {
+	return tableAlias(b.dialectOrANSI(), name)
+}
+
+// arg adds an argument to the query and returns its placeholder.
+func (b *builder) arg(v interface{}) string {
+	d := b.dialectOrANSI()
+	v, err := encodeArg(d, v)
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	b.args = append(b.args, v)
+	return d.Placeholder(len(b.args))
+}
+
+// column is a column of the source of a query
+type column struct {
+	// table is the alias of the subquery that the column comes from, which is
+	// only needed when the source is a join.
+	table string
+
+	// name is the name of the column
+	name string
+}
+
+// String returns the sql reference to the column
+func (c column) String() string {
+	if c.table == "" {
+		return c.name
+	}
+	return c.table + "." + c.name
+}
+
+// source is the FROM clause of a query.  It is either a table in the
+// database, or a derived table built from other relations on the same
+// database.
+type source interface {
+	// build returns the sql for the source.  needed is the set of columns
+	// that the enclosing query refers to, which derived sources use to narrow
+	// their own select lists.
+	build(b *builder, needed map[column]bool) string
+}
+
+// tableSource is a table in the database
+type tableSource string
+
+// build returns the table name
+func (t tableSource) build(b *builder, needed map[column]bool) string {
+	return quoteTable(b.dialectOrANSI(), string(t))
+}
+
+// setSource is a set operation between two relations with the same heading.
+type setSource struct {
+	// op is the sql set operator: UNION, UNION ALL, EXCEPT, or INTERSECT
+	op string
+
+	r1, r2 *sqlTable
+}
+
+// build returns the compound select for the set operation.  Projection
+// distributes over union, so the operands of a union are narrowed to the
+// needed attributes, but the operands of the other set operations have to
+// keep their full heading.
+func (s *setSource) build(b *builder, needed map[column]bool) string {
+	var n map[rel.Attribute]bool
+	if s.op == "UNION" || s.op == "UNION ALL" {
+		n = neededAttributes(needed, "")
+	}
+	op := setOperatorString(b.dialectOrANSI(), s.op)
+	return "(" + s.r1.build(b, n, true) + " " + op + " " + s.r2.build(b, n, true) + ")" + b.alias("s")
+}
+
+// setSymbols are the relational symbols for the set operators, which are used
+// in String
+var setSymbols = map[string]string{
+	"UNION":     " ∪ ",
+	"UNION ALL": " ∪ ",
+	"EXCEPT":    " − ",
+	"INTERSECT": " ∩ ",
+}
+
+// String returns a text representation of the set operation
+func (s *setSource) String() string {
+	return s.r1.expr() + setSymbols[s.op] + s.r2.expr()
+}
+
+// joinSource is a natural join between two relations.
+type joinSource struct {
+	r1, r2 *sqlTable
+
+	// on holds the attributes common to both relations
+	on []rel.Attribute
+}
+
+// build returns the join of the two relations.  Each side is narrowed to the
+// attributes that are needed above the join, plus the join attributes.
+func (j *joinSource) build(b *builder, needed map[column]bool) string {
+	n1 := neededAttributes(needed, "t1")
+	n2 := neededAttributes(needed, "t2")
+	if n1 != nil {
+		for _, att := range j.on {
+			n1[att] = true
+			n2[att] = true
+		}
+	}
+	s1 := j.r1.build(b, n1, true)
+	s2 := j.r2.build(b, n2, true)
+	if len(j.on) == 0 {
+		return "(" + s1 + ")" + b.alias("t1") + " CROSS JOIN (" + s2 + ")" + b.alias("t2")
+	}
+	on := make([]string, len(j.on))
+	for i, att := range j.on {
+		on[i] = "t1." + string(att) + " = t2." + string(att)
+	}
+	return "(" + s1 + ")" + b.alias("t1") + " JOIN (" + s2 + ")" + b.alias("t2") + " ON " + strings.Join(on, " AND ")
+}
+
+// String returns a text representation of the join
+func (j *joinSource) String() string {
+	return j.r1.expr() + " ⋈ " + j.r2.expr()
+}
+
+// neededAttributes returns the names of the columns in needed which belong to
+// the table alias.  If needed is nil, which means every column is needed, it
+// returns nil.
+func neededAttributes(needed map[column]bool, table string) map[rel.Attribute]bool {
+	if needed == nil {
+		return nil
+	}
+	n := make(map[rel.Attribute]bool)
+	for c := range needed {
+		if c.table == table {
+			n[rel.Attribute(c.name)] = true
+		}
+	}
+	return n
+}
+
+// selectStatement is a very simple sql select statement.  The relation's
+// source, whether it is a table or a derived table, is rendered into the
+// From field.
+type selectStatement struct {
+	Distinct bool
+	ColNames string
+	From     string
+	Where    string
+}
+
+// selectTemplate renders a selectStatement
+var selectTemplate = template.Must(template.New("select").Parse(
+	"SELECT{{if .Distinct}} DISTINCT{{end}} {{.ColNames}} FROM {{.From}}{{if .Where}} WHERE {{.Where}}{{end}}"))
+
+// queryString constructs a query string from a selectStatement.
+func (s *selectStatement) queryString() (str string, err error) {
+	var b bytes.Buffer
+	err = selectTemplate.Execute(&b, s)
+	str = b.String()
+	return
+}
+
+// distinct returns true if the query for the relation has to remove
+// duplicate rows, according to the relation's distinctness policy.
+func (r1 *sqlTable) distinct() bool {
+	switch r1.opts.distinct {
+	case ForceDistinct:
+		return true
+	case AllowDuplicates:
+		return false
+	}
+	return !r1.sourceDistinct
+}
+
+// dialect returns the sql dialect of the relation
+func (r1 *sqlTable) dialect() Dialect {
+	return r1.opts.dialectOrANSI()
+}
+
+// build compiles the relation into a select statement.  If needed is not nil,
+// only the attributes in it are selected.  If alias is true, the statement is
+// going to be used as a derived table, so columns are renamed to the
+// attribute names where they differ.
+func (r1 *sqlTable) build(b *builder, needed map[rel.Attribute]bool, alias bool) string {
+	if !r1.composable() {
+		return "CALL " + r1.src.build(b, nil)
+	}
+	e := reflect.TypeOf(r1.zero)
+	srcNeeded := make(map[column]bool)
+	var sel []string
+	for i, c := range r1.cols {
+		name := e.Field(i).Name
+		if needed != nil && !needed[rel.Attribute(name)] {
+			continue
+		}
+		if e.Field(i).Type == lazyType {
+			// lazy values are fetched separately, so only a placeholder
+			// column is selected
+			sel = append(sel, "NULL AS "+name)
+			continue
+		}
+		srcNeeded[c] = true
+		if alias && c.name != name {
+			sel = append(sel, c.String()+" AS "+name)
+		} else {
+			sel = append(sel, c.String())
+		}
+	}
+	for _, c := range r1.where {
+		for _, col := range c.cols {
+			srcNeeded[col] = true
+		}
+	}
+	src := r1.src
+	if p, ok := src.(*partitionSource); ok {
+		src = p.prune(r1.where)
+	}
+	from := src.build(b, srcNeeded)
+	where := whereString(b, r1.where)
+	if len(sel) == 0 {
+		// a relation with no attributes is either TABLE_DEE, with a single
+		// empty tuple, or TABLE_DUM, with none, depending on whether the
+		// source has any rows.  sql has no empty select list, so that is
+		// expressed as a single constant row that only exists if the
+		// source is not empty.
+		str, err := (&selectStatement{false, "1", from, where}).queryString()
+		if err != nil && b.err == nil {
+			b.err = err
+		}
+		return "SELECT 1" + fromDual(b.dialectOrANSI()) + " WHERE EXISTS (" + str + ")"
+	}
+	str, err := (&selectStatement{r1.distinct(), strings.Join(sel, ", "), from, where}).queryString()
+	if err != nil && b.err == nil {
+		b.err = err
+	}
+	return str
+}
+
+// queryString returns the sql query that produces the relation's tuples, and
+// the arguments for its placeholders.
+func (r1 *sqlTable) queryString() (string, []interface{}, error) {
+	b := builder{dialect: r1.dialect()}
+	q := r1.build(&b, nil, false)
+	if r1.opts.ordered {
+		q += r1.orderBy()
+	}
+	if r1.limit > 0 {
+		q = limitQuery(b.dialect, q, r1.limit)
+	}
+	q = addHints(b.dialect, q, r1.hints())
+	return q, b.args, b.err
+}
+
+// orderBy returns the ORDER BY clause, including a leading space, that sorts
+// the relation's query by its first candidate key, or an empty string if the
+// query can't be ordered.
+func (r1 *sqlTable) orderBy() string {
+	e := reflect.TypeOf(r1.zero)
+	if !r1.composable() || e.NumField() == 0 || len(r1.cKeys) == 0 {
+		return ""
+	}
+	cols := make([]string, len(r1.cKeys[0]))
+	for i, att := range r1.cKeys[0] {
+		f, ok := e.FieldByName(string(att))
+		if !ok || f.Type == lazyType {
+			return ""
+		}
+		cols[i] = r1.cols[f.Index[0]].String()
+	}
+	return " ORDER BY " + strings.Join(cols, ", ")
+}
+
+// SQL returns the query that a relation from this package executes to read
+// its tuples, along with the arguments of the query.  It returns an error if
+// r isn't from this package, or is partly evaluated client side.
+func SQL(r rel.Relation) (string, []interface{}, error) {
+	r1, ok := r.(*sqlTable)
+	if !ok {
+		return "", nil, fmt.Errorf("relsql: %v is not a single query", r)
+	}
+	if r1.err != nil {
+		return "", nil, r1.err
+	}
+	return r1.queryString()
+}
@@ -0,0 +1,93 @@
+package relsql
+
+import (
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+)
+
+// redactedValue replaces the values of redacted attributes in text output
+const redactedValue = "<redacted>"
+
+// WithRedacted marks attributes whose values must not appear in text output.
+// The attributes are still scanned, written and compared as usual, but the
+// values they are compared to are masked in the String of restricted
+// relations, and their values are masked in error messages.
+func WithRedacted(atts ...string) Option {
+	return func(o *options) {
+		o.redacted = append(o.redacted, atts...)
+	}
+}
+
+// isRedacted returns true if the column's values are redacted
+func (o *options) isRedacted(colName string) bool {
+	for _, att := range o.redacted {
+		if att == colName {
+			return true
+		}
+	}
+	return false
+}
+
+// relOps maps sql comparison operators to the operators rel uses in the text
+// form of predicates
+var relOps = map[string]string{
+	"=":  "==",
+	"<>": "!=",
+}
+
+// redactedString returns the text form of the predicate, with the values
+// compared to redacted attributes masked.  Predicates without redacted
+// attributes have their usual text form.
+func (p Pred) redactedString(redacted func(rel.Attribute) bool) string {
+	if p.preds != nil {
+		found := false
+		for _, att := range p.attributes() {
+			found = found || redacted(att)
+		}
+		if !found {
+			return p.String()
+		}
+		sep := " ∧ "
+		if p.op == "OR" {
+			sep = " ∨ "
+		}
+		strs := make([]string, len(p.preds))
+		for i, p2 := range p.preds {
+			strs[i] = "(" + p2.redactedString(redacted) + ")"
+		}
+		return strings.Join(strs, sep)
+	}
+	if _, ok := p.val.(rel.Attribute); ok || !redacted(p.att) {
+		return p.String()
+	}
+	op, ok := relOps[p.op]
+	if !ok {
+		op = p.op
+	}
+	left := string(p.att)
+	if ea, ok := p.val.(exprArg); ok {
+		left = ea.x.String()
+	}
+	return left + " " + op + " " + redactedValue
+}
+
+// predicateString returns the text form of a predicate on the relation, with
+// the values compared to its redacted or encrypted attributes masked.  It is
+// used where the predicate is reported, such as the Diagnostic of a
+// restriction that is evaluated client side.
+func (r1 *sqlTable) predicateString(p rel.Predicate) string {
+	p1, ok := p.(Pred)
+	if !ok {
+		return p.String()
+	}
+	e := reflect.TypeOf(r1.zero)
+	return p1.redactedString(func(att rel.Attribute) bool {
+		f, ok := e.FieldByName(string(att))
+		if !ok {
+			return false
+		}
+		name := r1.cols[f.Index[0]].name
+		return r1.opts.isRedacted(name) || r1.opts.encrypted(name)
+	})
+}
@@ -0,0 +1,68 @@
+package relsql
+
+import (
+	"database/sql"
+	"net/netip"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// test masking of redacted values in text output
+func TestRedacted(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type personTup struct {
+		ID    int
+		SSN   string
+		Grade string
+	}
+	people := New(db, "people", personTup{}, [][]string{[]string{"ID"}}, WithRedacted("SSN", "Grade"))
+	var stringTest = []struct {
+		p    Pred
+		want string
+	}{
+		{Attribute("ID").EQ(1), "ID == 1"},
+		{Attribute("SSN").EQ("123-45-6789"), "SSN == <redacted>"},
+		{Or(Attribute("SSN").NE("1"), Attribute("ID").GT(2)), "(SSN != <redacted>) ∨ (ID > 2)"},
+		{Attribute("Grade").In("A", "B"), "Grade IN <redacted>"},
+	}
+	for i, tt := range stringTest {
+		if str := people.Restrict(tt.p).String(); str != "σ{"+tt.want+"}(Relation(ID, SSN, Grade))" {
+			t.Errorf("%d has String() => %v, want σ{%v}(...)", i, str, tt.want)
+		}
+	}
+
+	// values are masked in errors, even after a rename
+	type renamedTup struct {
+		ID    int
+		SSN   string
+		Level string
+	}
+	c := (&options{enums: []enum{{"Grade", []interface{}{"A"}}}, redacted: []string{"Grade"}}).enumCheck(colNames(personTup{}))
+	err = c.check(reflect.ValueOf(personTup{1, "x", "F"}))
+	if err == nil || strings.Contains(err.Error(), "F") {
+		t.Errorf("check() of redacted value => %v", err)
+	}
+	if str := people.Rename(renamedTup{}).Restrict(Attribute("Level").EQ("A")).String(); !strings.Contains(str, "Level == <redacted>") {
+		t.Errorf("renamed String() => %v", str)
+	}
+
+	// restrictions evaluated client side are reported without the values
+	secret := New(db, "people", personTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite), WithRedacted("Grade"), WithEncryption("SSN", xorCipher(7)))
+	var clientTest = []Pred{
+		And(Attribute("Grade").EQ("F"), Attribute("ID").Within(netip.MustParsePrefix("10.0.0.0/8"))),
+		Attribute("SSN").EQ("123-45-6789"),
+	}
+	for i, p := range clientTest {
+		diags := Diagnostics(secret.Restrict(p))
+		if len(diags) != 1 || strings.Contains(diags[0].Expr, "F") || strings.Contains(diags[0].Expr, "123-45-6789") || !strings.Contains(diags[0].Expr, redactedValue) {
+			t.Errorf("%d has Diagnostics() => %v", i, diags)
+		}
+	}
+}
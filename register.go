@@ -0,0 +1,65 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+)
+
+// NewConn creates a relation like New, which reads from a table through a
+// single connection instead of a connection pool.  Relations on the same
+// connection can be combined into one query, including with temporary tables
+// created by Register, which are only visible to that connection.
+func NewConn(conn *sql.Conn, tableName string, z interface{}, ckeystr [][]string, opts ...Option) rel.Relation {
+	r := New(nil, tableName, z, ckeystr, opts...).(*sqlTable)
+	r.conn = conn
+	return r
+}
+
+// Register copies the tuples of r, which is usually held in memory, into a
+// new temporary table in the connection's session, and returns a relation
+// that reads from it.  The relation can be joined with, or otherwise combined
+// with, other relations created by NewConn on the same connection, and the
+// whole expression is evaluated by the database.  The table is dropped by the
+// database when the session ends, or it can be dropped explicitly.
+func Register(conn *sql.Conn, tableName string, r rel.Relation, opts ...Option) rel.Relation {
+	z := r.Zero()
+	ckeystr := keyStrings(r.CKeys())
+	res := NewConn(conn, tableName, z, ckeystr, opts...).(*sqlTable)
+	if res.err != nil {
+		return res
+	}
+	res.err = res.load(conn, r, ckeystr, opts)
+	return res
+}
+
+// load creates the temporary table of the relation, with the candidate keys of
+// r, and writes the tuples of r into it.
+func (r1 *sqlTable) load(conn *sql.Conn, r rel.Relation, ckeystr [][]string, opts []Option) error {
+	tableName := string(r1.src.(tableSource))
+	stmt, err := CreateTableString(r1.dialect(), tableName, r1.zero, ckeystr, opts...)
+	if err != nil {
+		return err
+	}
+	stmt = "CREATE TEMPORARY TABLE" + strings.TrimPrefix(stmt, "CREATE TABLE")
+	ctx := context.Background()
+	if _, err := r1.opts.exec(ctx, conn, stmt); err != nil {
+		return err
+	}
+	size := r1.opts.batchSize
+	if size < 1 {
+		size = 1
+	}
+	ft, err := r1.opts.fieldTransforms(reflect.TypeOf(r1.zero), r1.cols)
+	if err != nil {
+		return err
+	}
+	w := &batchWriter{tx: conn, d: r1.dialect(), tableName: tableName, cols: r1.cols, size: size, enums: r1.opts.enumCheck(r1.cols), transforms: ft, progress: newProgress(r1.opts.progress), audit: r1.opts.audit}
+	defer w.close()
+	if err := forEach(r, w.add); err != nil {
+		return err
+	}
+	return w.flush()
+}
@@ -0,0 +1,76 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// test joining a table with an in memory relation in a temporary table
+func TestRegister(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:register?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type orderTup struct {
+		ID       int
+		Customer string
+	}
+	type vipTup struct {
+		Customer string
+	}
+	if err := CreateTable(db, "orders", orderTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	orders := []orderTup{{1, "ann"}, {2, "bob"}, {3, "ann"}, {4, "cy"}}
+	if _, err := Insert(db, "orders", rel.New(orders, [][]string{[]string{"ID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Errorf("Conn() => %v", err)
+		return
+	}
+	defer conn.Close()
+
+	vips := rel.New([]vipTup{{"ann"}, {"cy"}}, [][]string{[]string{"Customer"}})
+	r := Register(conn, "vips", vips)
+	if err := r.Err(); err != nil {
+		t.Errorf("Register() => %v", err)
+		return
+	}
+	j := NewConn(conn, "orders", orderTup{}, [][]string{[]string{"ID"}}).Join(r, orderTup{})
+	if _, ok := j.(*sqlTable); !ok {
+		t.Errorf("Join() => %T, want a query on the connection", j)
+	}
+	ch := make(chan orderTup)
+	j.TupleChan(ch)
+	var ids []int
+	for tup := range ch {
+		ids = append(ids, tup.ID)
+	}
+	sort.Ints(ids)
+	if fmt.Sprint(ids) != "[1 3 4]" {
+		t.Errorf("joined ids => %v, want [1 3 4]", ids)
+	}
+	if err := j.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+
+	// the temporary table is not visible to other connections
+	if _, ok := New(db, "orders", orderTup{}, nil).Join(r, orderTup{}).(*sqlTable); ok {
+		t.Errorf("Join() with another connection => a single query")
+	}
+	if _, err := db.Exec("select * from vips"); err == nil {
+		t.Errorf("temporary table is visible outside of the session")
+	}
+}
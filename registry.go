@@ -0,0 +1,84 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"sync"
+)
+
+// Entry is a relation in a registry, with its metadata
+type Entry struct {
+	Name        string
+	Description string
+	Tags        []string
+	Relation    rel.Relation
+}
+
+// Registry holds relations under unique names, with descriptions and tags,
+// so that a set of relation definitions can be discovered and served by
+// name.  It is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]Entry)}
+}
+
+// Register adds a relation to the registry.  It returns an error if the name
+// is empty or already registered.
+func (g *Registry) Register(name string, r rel.Relation, description string, tags ...string) error {
+	if name == "" {
+		return fmt.Errorf("relsql: registered relation has no name")
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, ok := g.entries[name]; ok {
+		return fmt.Errorf("relsql: relation %s is already registered", name)
+	}
+	g.entries[name] = Entry{name, description, append([]string(nil), tags...), r}
+	return nil
+}
+
+// Lookup returns the entry with the name, and whether there is one
+func (g *Registry) Lookup(name string) (Entry, bool) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	e, ok := g.entries[name]
+	return e, ok
+}
+
+// Entries returns the registered entries, sorted by name.  If any tags are
+// given, only the entries which have all of them are returned.
+func (g *Registry) Entries(tags ...string) []Entry {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	var res []Entry
+	for _, e := range g.entries {
+		if hasTags(e.Tags, tags) {
+			res = append(res, e)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// hasTags returns true if have includes every one of want
+func hasTags(have, want []string) bool {
+	for _, w := range want {
+		found := false
+		for _, h := range have {
+			if h == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
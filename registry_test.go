@@ -0,0 +1,55 @@
+package relsql
+
+import (
+	"fmt"
+	"testing"
+)
+
+// test registering and finding relations
+func TestRegistry(t *testing.T) {
+	type supplierTup struct {
+		SNO  int
+		City string
+	}
+	suppliers := New(nil, "suppliers", supplierTup{}, [][]string{[]string{"SNO"}})
+	g := NewRegistry()
+	if err := g.Register("suppliers", suppliers, "every supplier", "core"); err != nil {
+		t.Errorf("Register() => %v", err)
+	}
+	if err := g.Register("paris", suppliers.Restrict(Attribute("City").EQ("Paris")), "suppliers in Paris", "core", "france"); err != nil {
+		t.Errorf("Register() => %v", err)
+	}
+	if err := g.Register("paris", suppliers, "duplicate"); err == nil {
+		t.Errorf("Register() with a duplicate name => nil error")
+	}
+	if err := g.Register("", suppliers, "no name"); err == nil {
+		t.Errorf("Register() with no name => nil error")
+	}
+
+	e, ok := g.Lookup("paris")
+	if !ok || e.Description != "suppliers in Paris" || Tables(e.Relation)[0] != "suppliers" {
+		t.Errorf("Lookup(paris) => %v, %v", e, ok)
+	}
+	if _, ok := g.Lookup("london"); ok {
+		t.Errorf("Lookup(london) => found")
+	}
+
+	var entriesTest = []struct {
+		tags []string
+		want string
+	}{
+		{nil, "[paris suppliers]"},
+		{[]string{"core"}, "[paris suppliers]"},
+		{[]string{"core", "france"}, "[paris]"},
+		{[]string{"uk"}, "[]"},
+	}
+	for i, tt := range entriesTest {
+		var names []string
+		for _, e := range g.Entries(tt.tags...) {
+			names = append(names, e.Name)
+		}
+		if fmt.Sprint(names) != tt.want {
+			t.Errorf("%d has Entries(%v) => %v, want %s", i, tt.tags, names, tt.want)
+		}
+	}
+}
@@ -4,32 +4,120 @@
 package relsql
 
 import (
-	"bytes"
+	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"github.com/jonlawlor/rel"
 	"reflect"
-	"strings"
-	"text/template"
+	"time"
 )
 
 // New creates a relation that reads from an sql table, with one tuple per row.
-func New(db *sql.DB, tableName string, z interface{}, ckeystr [][]string) rel.Relation {
+// If no candidate keys are provided, the whole heading is used as the key and
+// the table is not assumed to be distinct.
+func New(db *sql.DB, tableName string, z interface{}, ckeystr [][]string, opts ...Option) rel.Relation {
+	r := &sqlTable{db: db, src: tableSource(tableName), zero: z}
+	for _, opt := range opts {
+		opt(&r.opts)
+	}
+	if r.opts.dialect == nil && db != nil {
+		r.opts.dialect = DialectOf(db)
+	}
+	if !r.opts.distinctSet {
+		r.opts.distinct = defaultDistinctness(r.dialect())
+	}
+	if r.err = checkZero(reflect.TypeOf(z)); r.err != nil {
+		return r
+	}
+	r.cols = colNames(z)
+	if cols := r.opts.columns; cols != nil {
+		if len(cols) != len(r.cols) {
+			r.err = fmt.Errorf("relsql: %d columns for the %d attributes of %v", len(cols), len(r.cols), reflect.TypeOf(z))
+			return r
+		}
+		for i, c := range cols {
+			r.cols[i].name = c
+		}
+	}
 	if len(ckeystr) == 0 {
-		return &sqlTable{db, tableName, colNames(z), z, rel.DefaultKeys(z), false, nil}
+		r.cKeys = rel.DefaultKeys(z)
+	} else {
+		r.cKeys = rel.String2CandKeys(ckeystr)
+		r.err = checkKeys(reflect.TypeOf(z), r.cKeys)
+		rel.OrderCandidateKeys(r.cKeys)
+		r.sourceDistinct = true
+	}
+	if r.err == nil && r.opts.logging() {
+		for _, att := range nullableKeys(r.dialect(), reflect.TypeOf(z), r.cKeys) {
+			r.opts.warnf("relsql: candidate key attribute %s of %s can be NULL, and unique constraints don't stop rows with NULL keys from repeating", att, tableName)
+		}
+	}
+	if r.err == nil {
+		r.err = r.restrictMandatory()
+	}
+	return r
+}
+
+// checkZero returns an error if tuples of type e can't be scanned from sql
+// rows.  Anonymous structs are allowed, but every field has to be exported and
+// not embedded so that it can be scanned into as a single column.
+func checkZero(e reflect.Type) error {
+	if e == nil || e.Kind() != reflect.Struct {
+		return fmt.Errorf("relsql: tuple type %v is not a struct", e)
+	}
+	for i := 0; i < e.NumField(); i++ {
+		f := e.Field(i)
+		if f.PkgPath != "" {
+			return fmt.Errorf("relsql: tuple type %v has unexported field %s", e, f.Name)
+		}
+		if f.Anonymous {
+			return fmt.Errorf("relsql: tuple type %v has embedded field %s", e, f.Name)
+		}
+	}
+	return nil
+}
+
+// checkKeys returns an error if any of the candidate keys refer to attributes
+// that are not in the heading of tuples of type e.
+func checkKeys(e reflect.Type, cKeys rel.CandKeys) error {
+	for _, ck := range cKeys {
+		for _, att := range ck {
+			if _, ok := e.FieldByName(string(att)); !ok {
+				return fmt.Errorf("relsql: candidate key attribute %s is not in the heading of %v", att, e)
+			}
+		}
 	}
-	ckeys := rel.String2CandKeys(ckeystr)
-	rel.OrderCandidateKeys(ckeys)
-	return &sqlTable{db, tableName, colNames(z), z, rel.DefaultKeys(z), true, nil}
+	return nil
 }
 
-// colNames returns the names of the fields from a source tuple
-func colNames(v interface{}) []string {
+// nullableKeys returns the attributes of the candidate keys whose columns
+// can hold NULL, according to the types of their fields.  Unique constraints
+// treat NULLs as distinct from each other, so a key with such an attribute
+// doesn't guarantee that the rows of the table are distinct.
+func nullableKeys(d Dialect, e reflect.Type, cKeys rel.CandKeys) []rel.Attribute {
+	var res []rel.Attribute
+	for _, ck := range cKeys {
+		for _, att := range ck {
+			f, ok := e.FieldByName(string(att))
+			if !ok || containsAttribute(res, att) {
+				continue
+			}
+			if _, nullable, err := columnType(d, f.Type); err == nil && nullable {
+				res = append(res, att)
+			}
+		}
+	}
+	return res
+}
+
+// colNames returns the columns for the fields from a source tuple
+func colNames(v interface{}) []column {
 	e := reflect.TypeOf(v)
 	n := e.NumField()
-	names := make([]string, n)
+	names := make([]column, n)
 	for i := 0; i < n; i++ {
-		names[i] = e.Field(i).Name
+		names[i] = column{name: e.Field(i).Name}
 	}
 	return names
 }
@@ -39,13 +127,22 @@ type sqlTable struct {
 	// the *sql.DB connection, produced by an sql driver
 	db *sql.DB
 
-	// tablename is the name of the table in the database
-	tableName string
+	// conn is the single connection that the relation reads from instead
+	// of db, for tables which are only visible within one session
+	conn *sql.Conn
+
+	// q executes the relation's queries instead of db, for relations from
+	// NewQueryer
+	q QueryerContext
 
-	// the columns available in the table
-	colNames []string
+	// src is the FROM clause of the query, which is either a table in the
+	// database or another query that has been pushed down to the database.
+	src source
 
-	// colNames and zero should always represent the same number of fields
+	// the columns of src which produce each of the fields in zero
+	cols []column
+
+	// cols and zero should always represent the same number of fields
 
 	// the type of the tuples returned by the relation
 	zero interface{}
@@ -53,33 +150,53 @@ type sqlTable struct {
 	// set of candidate keys
 	cKeys rel.CandKeys
 
-	// sourceDistinct indicates if the source sql was already distinct or if a
-	// distinct has to be performed
+	// sourceDistinct indicates if the rows from the source are known to be
+	// distinct, because one of the declared candidate keys is still part of
+	// the heading.  Whether a DISTINCT is actually performed is decided by
+	// the distinctness policy in opts.
 	sourceDistinct bool
 
+	// where holds the restrictions that have been pushed down to the query
+	where []condition
+
+	// mandatory holds the conditions of the mandatory predicates, which are
+	// also in where, for the queries of the relation that don't read its
+	// rows, like freshness probes
+	mandatory []condition
+
+	// limit is the most rows that the query returns, or zero for no limit
+	limit int
+
+	// opts is the configuration of the relation
+	opts options
+
+	// diag is the client side operation that reads the relation, if any
+	diag *diagnostic
+
 	// err holds the errors returned during query execution
 	err error
 }
 
-// selectStatement is a very simple sql select statement.  This will be
-// replaced with a more general verion(s) to allow relsql to perform query
-// rewrite using restrict, join, union, and diff.  I'm not sure if it will ever
-// use rewrite for map and groupby.  Maybe It should depend upon sqlx, for the
-// parameters?
-type selectStatement struct {
-	SourceDistinct bool
-	ColNames       string
-	TableName      string
-}
-
-// queryString constructs a query string from a selectStatement.
-func (s *selectStatement) queryString() (str string, err error) {
-	const selectTemplate = "SELECT{{if .SourceDistinct}} {{else}} DISTINCT {{end}}{{.ColNames}} FROM {{.TableName}}"
-	var b bytes.Buffer
-	t := template.Must(template.New("select").Parse(selectTemplate))
-	err = t.Execute(&b, s)
-	str = b.String()
-	return
+// PartialError is the error recorded on a relation when a tuple stream
+// stopped because of an error instead of running to completion.  Any tuples
+// that were sent before the error are valid, but the result is incomplete.
+// A channel that closes while Err() returns nil was a complete result.
+type PartialError struct {
+	// Sent is the number of tuples that were sent before the error.
+	Sent int
+
+	// Err is the underlying error.
+	Err error
+}
+
+// Error implements the error interface
+func (e *PartialError) Error() string {
+	return fmt.Sprintf("relsql: result truncated after %d tuples: %v", e.Sent, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *PartialError) Unwrap() error {
+	return e.Err
 }
 
 // TupleChan returns the tuples from the sql query represented by the relation
@@ -97,70 +214,393 @@ func (r1 *sqlTable) TupleChan(t interface{}) chan<- struct{} {
 		chv.Close()
 		return cancel
 	}
-	go func(db *sql.DB, res reflect.Value) {
-		// construct the select query string
-		q, err := (&selectStatement{r1.sourceDistinct, strings.Join(r1.colNames, ", "), r1.tableName}).queryString()
-		if err != nil {
-			r1.err = err
-			res.Close()
+	untrack, err := r1.opts.controller.track()
+	if err != nil {
+		r1.err = err
+		chv.Close()
+		return cancel
+	}
+	go func(res reflect.Value) {
+		defer untrack()
+		borrow := r1.borrowCount(res.Cap())
+		switch {
+		case r1.opts.prefetchMemory > 0 || r1.opts.governor != nil:
+			res = prefetchMemory(res, r1.opts.prefetchMemory, r1.opts.prefetch, r1.opts.governor, cancel)
+		case r1.opts.prefetch > 0:
+			res = prefetch(res, r1.opts.prefetch, cancel)
+		}
+		// the read is stopped by its consumer, or when its context is done
+		ctx, stop := r1.readContext()
+		defer stop()
+		halt, unwatch := watch(cancel, ctx.Done())
+		defer unwatch()
+		var sent int
+		var cancelled bool
+		var err error
+		for attempt := 1; ; attempt++ {
+			sent, cancelled, err = r1.stream(ctx, res, borrow, halt)
+			if err == nil || sent > 0 || ctx.Err() != nil || !r1.opts.retry.retryable(attempt, err) {
+				break
+			}
+			delay := r1.opts.retry.delay(attempt)
+			r1.logRetry(ctx, attempt, delay, err)
+			// the backoff ends early if the read is stopped
+			select {
+			case <-time.After(delay):
+				continue
+			case <-cancel:
+				cancelled = true
+			case <-ctx.Done():
+			}
+			break
+		}
+		r1.readDone(sent)
+		if cancelled || err != nil {
+			// a consumer that stopped the read knows it, but one that was
+			// stopped by the read's context has to be told
+			if serr := stopError(ctx, cancel); serr != nil {
+				err, cancelled = serr, false
+			}
+		}
+		if cancelled {
 			return
 		}
-
-		// start a transaction
-		tx, err := db.Begin()
 		if err != nil {
-			r1.err = err
-			res.Close()
-			return
+			r1.logReadFailed(ctx, sent, err)
+			// the error has to be recorded before the channel is closed so
+			// that consumers can tell a truncated result from a complete one.
+			r1.err = &PartialError{sent, err}
 		}
+		res.Close()
+	}(chv)
+
+	return cancel
+}
+
+// stream executes the query in ctx and sends the resulting tuples on res until
+// the rows are exhausted, an error occurs, or cancel is closed.  It returns the
+// number of tuples sent, whether the stream was cancelled, and the first error
+// encountered during query execution, scanning, or commit.  If borrow is more
+// than zero, it is the number of tuples that are reused.
+func (r1 *sqlTable) stream(ctx context.Context, res reflect.Value, borrow int, cancel <-chan struct{}) (sent int, cancelled bool, err error) {
+	// construct the select query string
+	q, args, err := r1.queryString()
+	if err != nil {
+		return
+	}
+	if q, args, err = r1.applyPolicies(q, args); err != nil {
+		return
+	}
+	lazy, err := r1.lazyLoader()
+	if err != nil {
+		return
+	}
+	transforms, err := r1.opts.fieldTransforms(reflect.TypeOf(r1.zero), r1.cols)
+	if err != nil {
+		return
+	}
 
-		// execute the query
-		rows, err := tx.Query(q)
+	if err = r1.ping(); err != nil {
+		return
+	}
 
-		if err != nil {
-			r1.err = err
-			res.Close()
-			return
-		}
+	if !r1.opts.queryThrottle.Wait(cancel) {
+		return 0, true, nil
+	}
+	release, ok, err := r1.opts.workload.acquire(cancel)
+	if !ok || err != nil {
+		return 0, !ok, err
+	}
+	defer release()
 
-		e1 := reflect.TypeOf(r1.zero)
-		resSel := reflect.SelectCase{Dir: reflect.SelectSend, Chan: res}
-		canSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cancel)}
-		n := e1.NumField()
-		// assign the records to the result tuples
-		for rows.Next() {
+	rc, done, err := r1.planConn(ctx)
+	if err != nil {
+		return
+	}
+	defer done()
+
+	// start a transaction, if the dialect needs one
+	tx, err := rc.begin(ctx)
+	if err != nil {
+		return
+	}
+	if err = r1.checkCrossJoins(tx.ctx, tx.executor()); err != nil {
+		tx.Rollback()
+		return
+	}
 
-			// construct the result value
-			tup := reflect.Indirect(reflect.New(e1))
-			values := []interface{}{}
+	// execute the query, recording it once the rows have been read
+	start := time.Now()
+	defer func() {
+		if aerr := r1.opts.audit(q, args, start, int64(sent), err); err == nil {
+			err = aerr
+		}
+	}()
+	m := metricsFor(r1.db)
+	m.start()
+	defer func() {
+		m.finish(start, sent, err)
+		r1.logQuery(ctx, q, args, start, sent, err)
+	}()
+	rows, err := tx.Query(q, bindArgs(r1.dialect(), args)...)
+	if err != nil {
+		tx.Rollback()
+		return
+	}
+	if err = r1.checkSchema(rows); err != nil {
+		rows.Close()
+		tx.Rollback()
+		return
+	}
+	sp := r1.startScanProgress(q, bindArgs(r1.dialect(), args))
+	defer func() { sp.stop(err) }()
 
-			for i := 0; i < n; i++ {
-				values = append(values, tup.Field(i).Addr().Interface())
-			}
+	d := r1.dialect()
+	enums := r1.opts.enumCheck(r1.cols)
+	e1 := reflect.TypeOf(r1.zero)
+	send := fastSender(res, e1)
+	bufs := newTupleBuffers(d, e1, r1.opts.exactNumerics, borrow)
+	resSel := reflect.SelectCase{Dir: reflect.SelectSend, Chan: res}
+	canSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cancel)}
+	// assign the records to the result tuples
+	for rows.Next() {
 
-			if err := rows.Scan(values...); err != nil {
-				r1.err = err
-				tx.Commit()
-				res.Close()
+		// construct the result value
+		ptr, values := bufs.next()
+		tup := ptr.Elem()
 
+		if err = rows.Scan(values...); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return
+		}
+		if lazy != nil {
+			if err = lazy.load(d, tup); err != nil {
+				rows.Close()
+				tx.Rollback()
 				return
 			}
-			// send the value on the results channel, or cancel
+		}
+		if err = transforms.read(tup); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return
+		}
+		if err = enums.check(tup); err != nil {
+			rows.Close()
+			tx.Rollback()
+			return
+		}
+		// send the value on the results channel, or cancel
+		ok := r1.opts.rowThrottle.Wait(cancel)
+		if ok && send != nil {
+			ok = send(ptr.Interface(), cancel)
+		} else if ok {
 			resSel.Send = tup
 			chosen, _, _ := reflect.Select([]reflect.SelectCase{canSel, resSel})
-			if chosen == 0 {
-				// cancel has been closed, so close the query results
-				tx.Commit()
-				rows.Close()
-				return
-			}
+			ok = chosen == 1
+		}
+		if !ok {
+			// cancel has been closed, so close the query results
+			rows.Close()
+			tx.Rollback()
+			return sent, true, nil
 		}
-		tx.Commit()
+		sent++
+		sp.add()
+	}
+	// rows.Next returns false on both exhaustion and failure, so the
+	// iteration error has to be checked to know that the result is complete.
+	if err = rows.Err(); err != nil {
 		rows.Close()
-		res.Close()
-	}(r1.db, chv)
+		tx.Rollback()
+		return
+	}
+	if err = rows.Close(); err != nil {
+		tx.Rollback()
+		return
+	}
+	err = tx.Commit()
+	return
+}
 
-	return cancel
+// queryer returns the executor, database or session connection of the
+// relation
+func (r1 *sqlTable) queryer() QueryerContext {
+	if r1.q != nil {
+		return r1.q
+	}
+	if r1.conn != nil {
+		return r1.conn
+	}
+	return r1.db
+}
+
+// reader executes the query for a stream, in a transaction if the relation's
+// dialect needs one for a consistent read.
+type reader struct {
+	// ctx is the context of the read, which is canceled to abort the query
+	ctx context.Context
+
+	db QueryerContext
+	tx *sql.Tx
+
+	// conn is the connection that was taken from the pool to set session
+	// parameters, which is released when the read is done
+	conn *sql.Conn
+
+	// reset are the statements that undo the session parameters before conn
+	// is released, and dirty is true if they can't be undone, in which case
+	// conn is closed instead of returned to the pool
+	reset []string
+	dirty bool
+
+	// opts audits the statements that the reader executes
+	opts *options
+
+	// discard rolls back the transaction instead of committing it
+	discard bool
+}
+
+// begin starts reading from the relation's database
+func (r1 *sqlTable) begin(ctx context.Context) (*reader, error) {
+	rd := &reader{ctx: ctx, db: r1.queryer(), opts: &r1.opts}
+	if r1.q != nil {
+		// the executor has no sessions or transactions to start
+		return rd, nil
+	}
+	conn := r1.conn
+	if conn == nil && r1.opts.checkoutTimeout > 0 {
+		// take the connection up front, so that waiting for it is timed
+		// separately from the query
+		var err error
+		if conn, err = r1.checkout(ctx); err != nil {
+			return nil, err
+		}
+		rd.conn = conn
+		rd.db = conn
+	}
+	stmts := sessionStatements(r1.dialect())
+	settings := r1.opts.workload.settings()
+	setup := r1.scriptSetup()
+	inTx := Supports(r1.dialect(), FeatureTransactions) && (r1.dialect().ReadTx() || len(setup) > 0)
+	_, resettable := resetStatements(r1.dialect())
+	if !inTx {
+		// without a transaction, the settings and the script's setup apply
+		// to the connection, and can't be undone
+		resettable = resettable && len(settings) == 0 && len(setup) == 0
+		stmts = append(append(append([]string(nil), stmts...), settings...), setup...)
+		settings, setup = nil, nil
+	}
+	if len(stmts) > 0 {
+		if conn == nil {
+			var err error
+			if conn, err = r1.db.Conn(ctx); err != nil {
+				return nil, err
+			}
+			rd.conn = conn
+		}
+		rd.db = conn
+		if rd.conn != nil {
+			// the connection returns to the pool once the query is read, so
+			// the session has to be undone first, or the connection closed
+			rd.reset, _ = resetStatements(r1.dialect())
+			rd.dirty = !resettable
+		}
+		for _, stmt := range stmts {
+			if _, err := r1.opts.exec(ctx, conn, stmt); err != nil {
+				rd.dirty = rd.conn != nil
+				rd.release()
+				return nil, err
+			}
+		}
+	}
+	if !inTx {
+		return rd, nil
+	}
+	txOpts := &sql.TxOptions{Isolation: r1.opts.isolation, ReadOnly: len(setup) == 0}
+	var err error
+	if conn != nil {
+		rd.tx, err = conn.BeginTx(ctx, txOpts)
+	} else {
+		rd.tx, err = r1.db.BeginTx(ctx, txOpts)
+	}
+	if err != nil {
+		rd.release()
+		return nil, err
+	}
+	for _, stmts := range [][]string{settings, setup} {
+		for _, stmt := range stmts {
+			if _, err := r1.opts.exec(ctx, rd.tx, stmt); err != nil {
+				rd.Rollback()
+				return nil, err
+			}
+		}
+	}
+	// the effects of scripts are rolled back once the query has been read
+	rd.discard = len(setup) > 0
+	return rd, nil
+}
+
+// Query executes a query that returns rows
+func (rd *reader) Query(q string, args ...interface{}) (*sql.Rows, error) {
+	if rd.tx == nil {
+		return rd.db.QueryContext(rd.ctx, q, args...)
+	}
+	return rd.tx.QueryContext(rd.ctx, q, args...)
+}
+
+// executor returns the transaction, or the connection if there is none
+func (rd *reader) executor() QueryerContext {
+	if rd.tx == nil {
+		return rd.db
+	}
+	return rd.tx
+}
+
+// Rollback aborts the transaction, if there is one
+func (rd *reader) Rollback() error {
+	defer rd.release()
+	if rd.tx == nil {
+		return nil
+	}
+	return rd.tx.Rollback()
+}
+
+// Commit commits the transaction, if there is one
+func (rd *reader) Commit() error {
+	defer rd.release()
+	switch {
+	case rd.tx == nil:
+		return nil
+	case rd.discard:
+		return rd.tx.Rollback()
+	}
+	return rd.tx.Commit()
+}
+
+// release returns the reader's connection to the pool, if it took one, after
+// undoing its session parameters.  A connection whose session can't be undone
+// is closed instead, by reporting it as bad to the pool.
+func (rd *reader) release() {
+	if rd.conn == nil {
+		return
+	}
+	for _, stmt := range rd.reset {
+		if rd.dirty {
+			break
+		}
+		// the read's context may already be done
+		if _, err := rd.opts.exec(context.Background(), rd.conn, stmt); err != nil {
+			rd.dirty = true
+		}
+	}
+	if rd.dirty {
+		rd.conn.Raw(func(interface{}) error {
+			return driver.ErrBadConn
+		})
+	}
+	rd.conn.Close()
 }
 
 // Zero returns the zero value of the relation (a blank tuple)
@@ -173,24 +613,98 @@ func (r1 *sqlTable) CKeys() rel.CandKeys {
 	return r1.cKeys
 }
 
-// GoString returns a text representation of the Relation
+// keyStrings converts candidate keys to the attribute names that New and
+// rel.New take.
+func keyStrings(cKeys rel.CandKeys) [][]string {
+	ckeystr := make([][]string, len(cKeys))
+	for i, ck := range cKeys {
+		ckeystr[i] = make([]string, len(ck))
+		for j, att := range ck {
+			ckeystr[i][j] = string(att)
+		}
+	}
+	return ckeystr
+}
+
+// GoString returns a text representation of the Relation, which has its
+// candidate keys unless the relation has another verbosity.
 func (r1 *sqlTable) GoString() string {
-	return fmt.Sprintf("relsql.sqlTable{sql.DB, %s, %v, %v, %v, %v, %v}", r1.tableName, r1.colNames, r1.zero, r1.cKeys, r1.sourceDistinct, r1.err)
+	return r1.format(r1.opts.verbosity, VerbosityKeys)
 }
 
-// String returns a text representation of the Relation
+// String returns a text representation of the Relation, which is its
+// relational expression unless the relation has another verbosity.
 func (r1 *sqlTable) String() string {
-	return "Relation(" + rel.HeadingString(r1) + ")"
+	return r1.format(r1.opts.verbosity, VerbosityHeading)
+}
+
+// expr returns the relational expression of the Relation
+func (r1 *sqlTable) expr() string {
+	var str string
+	switch src := r1.src.(type) {
+	case *setSource:
+		str = src.String()
+		if rel.HeadingString(r1) != rel.HeadingString(src.r1) {
+			str = "π{" + rel.HeadingString(r1) + "}(" + str + ")"
+		}
+	case *joinSource:
+		str = src.String()
+		if len(r1.cols) != len(src.r1.cols)+len(src.r2.cols)-len(src.on) {
+			str = "π{" + rel.HeadingString(r1) + "}(" + str + ")"
+		}
+	case *topNSource:
+		str = src.String()
+		if len(r1.cols) != len(src.r.cols) {
+			str = "π{" + rel.HeadingString(r1) + "}(" + str + ")"
+		}
+	case *runningSource:
+		str = src.String()
+		if len(r1.cols) != len(src.r.cols)+len(src.aggs) {
+			str = "π{" + rel.HeadingString(r1) + "}(" + str + ")"
+		}
+	case *binSource:
+		str = src.String()
+		if len(r1.cols) != len(src.r.cols)+1 {
+			str = "π{" + rel.HeadingString(r1) + "}(" + str + ")"
+		}
+	case *summarySource:
+		str = src.String()
+		if len(r1.cols) != len(src.group)+len(src.aggs) {
+			str = "π{" + rel.HeadingString(r1) + "}(" + str + ")"
+		}
+	case *lateralSource:
+		str = src.String()
+		if len(r1.cols) != len(src.r1.cols)+len(src.r2.cols) {
+			str = "π{" + rel.HeadingString(r1) + "}(" + str + ")"
+		}
+	default:
+		str = "Relation(" + rel.HeadingString(r1) + ")"
+	}
+	if len(r1.where) > 0 {
+		return "σ{" + predString(r1.where, &r1.opts) + "}(" + str + ")"
+	}
+	return str
 }
 
 // Project creates a new relation with less than or equal degree
 // t2 has to be a new type which is a subdomain of r.
 // this can be passed through to the sql server
 func (r1 *sqlTable) Project(z2 interface{}) rel.Relation {
+	if !r1.composable() {
+		project := func(r rel.Relation) rel.Relation {
+			return rel.NewProject(r, z2)
+		}
+		return r1.fallback("Project", "π{"+rel.HeadingString(project(r1))+"}", "the relation is a procedure call", project)
+	}
 
 	// determine the location of the attributes that remain
 	e1 := reflect.TypeOf(r1.zero)
 	e2 := reflect.TypeOf(z2)
+	if err := checkZero(e2); err != nil {
+		r2 := *r1
+		r2.err = err
+		return &r2
+	}
 
 	if e1.AssignableTo(e2) {
 		// nothing to do
@@ -198,38 +712,94 @@ func (r1 *sqlTable) Project(z2 interface{}) rel.Relation {
 	}
 	fMap := rel.FieldMap(e1, e2)
 
-	// update the column names
+	// update the columns
 	// it is important that they are in the same order as the new zero.
-	colNames2 := colNames(z2)
+	cols2 := make([]column, e2.NumField())
+	for i := range cols2 {
+		f, _ := e1.FieldByName(e2.Field(i).Name)
+		cols2[i] = r1.cols[f.Index[0]]
+	}
 
 	// update the candidate keys
 	cKeys := rel.SubsetCandidateKeys(r1.cKeys, rel.Heading(r1), fMap)
-	sourceDistinct := true
+	// the rows remain distinct as long as one of the candidate keys survives
+	// the projection.
+	sourceDistinct := r1.sourceDistinct
 	// every relation except dee and dum have at least one candidate key
 	if len(cKeys) == 0 {
 		cKeys = rel.DefaultKeys(z2)
 		sourceDistinct = false
 	}
 
-	return &sqlTable{r1.db, r1.tableName, colNames2, z2, cKeys, sourceDistinct, r1.err}
+	r2 := *r1
+	r2.cols = cols2
+	r2.zero = z2
+	r2.cKeys = cKeys
+	r2.sourceDistinct = sourceDistinct
+	if r2.distinct() && r2.touchesEncrypted(headingNames(e2)...) {
+		return r1.fallback("Project", "π{"+rel.HeadingString(&r2)+"}", "DISTINCT would compare the ciphertext of encrypted attributes", func(r rel.Relation) rel.Relation {
+			return rel.NewProject(r, z2)
+		})
+	}
+	return &r2
 
 }
 
 // Restrict creates a new relation with less than or equal cardinality
 // p has to be a func(tup T) bool where tup is a subdomain of the input r.
+// Predicates built from relsql Attributes are added to the WHERE clause of the
+// query, and successive restrictions are and'ed together in a single WHERE.
+// Any other predicate, or one that the dialect can't express, is evaluated
+// client side.
 func (r1 *sqlTable) Restrict(p rel.Predicate) rel.Relation {
-	// TODO(jonlawlor): rewrite through to sql server
-	return rel.NewRestrict(r1, p)
+	p1, ok := p.(Pred)
+	reason := ""
+	switch {
+	case !ok:
+		reason = "the predicate is not from this package"
+	case !p1.pushable(r1.dialect()):
+		reason = "the predicate can't be expressed in " + r1.dialect().Name()
+	case !r1.composable():
+		reason = "the relation is a procedure call"
+	}
+	if reason != "" {
+		return r1.fallback("Restrict", r1.predicateString(p), reason, func(r rel.Relation) rel.Relation {
+			return rel.NewRestrict(r, p)
+		})
+	}
+	r2 := *r1
+	c, err := newCondition(p1, reflect.TypeOf(r1.zero), r1.cols)
+	if err != nil {
+		r2.err = err
+		return &r2
+	}
+	var atts []string
+	for att := range c.cols {
+		atts = append(atts, string(att))
+	}
+	if r1.touchesEncrypted(atts...) {
+		return r1.fallback("Restrict", r1.predicateString(p), "the predicate refers to encrypted attributes", func(r rel.Relation) rel.Relation {
+			return rel.NewRestrict(r, p)
+		})
+	}
+	r1.warnExprs(p1)
+	r2.where = addConditions(r1.where, c)
+	return &r2
 }
 
 // Rename creates a new relation with new column names
 // this can be handled during the scanner call
 func (r1 *sqlTable) Rename(z2 interface{}) rel.Relation {
-
-	// TODO(jonlawlor): check that the rename has the same size
-
 	e2 := reflect.TypeOf(z2)
 
+	// the scan is positional, so the new heading has to line up with the old
+	// one field by field.
+	if err := checkRename(reflect.TypeOf(r1.zero), e2); err != nil {
+		r2 := *r1
+		r2.err = err
+		return &r2
+	}
+
 	// figure out the new names
 	names2 := rel.FieldNames(e2)
 
@@ -253,29 +823,207 @@ func (r1 *sqlTable) Rename(z2 interface{}) rel.Relation {
 	// order the keys
 	rel.OrderCandidateKeys(cKeys2)
 
-	return &sqlTable{r1.db, r1.tableName, r1.colNames, z2, cKeys2, r1.sourceDistinct, r1.err}
+	r2 := *r1
+	r2.zero = z2
+	r2.cKeys = cKeys2
+	return &r2
+
+}
+
+// checkRename returns an error if tuples of type e1 can't be renamed to tuples
+// of type e2, because they have a different degree or because a field's type
+// can't be converted to the corresponding field in the new heading.
+func checkRename(e1, e2 reflect.Type) error {
+	if err := checkZero(e2); err != nil {
+		return err
+	}
+	if n1, n2 := e1.NumField(), e2.NumField(); n1 != n2 {
+		return fmt.Errorf("relsql: rename from degree %d to degree %d", n1, n2)
+	}
+	for i := 0; i < e1.NumField(); i++ {
+		f1, f2 := e1.Field(i), e2.Field(i)
+		if !f1.Type.ConvertibleTo(f2.Type) {
+			return fmt.Errorf("relsql: rename of %s (%v) to %s (%v) has incompatible types", f1.Name, f1.Type, f2.Name, f2.Type)
+		}
+	}
+	return nil
+}
 
+// sameDB returns r2 as a *sqlTable if it can be combined with r1 into a single
+// query, because they are both from the same database, or the same session
+// connection, and neither has an error.  Relations with encrypted attributes
+// can't be combined, because their columns hold ciphertext, and neither can
+// procedure calls.
+func (r1 *sqlTable) sameDB(r2 rel.Relation) (*sqlTable, bool) {
+	r3, ok := r2.(*sqlTable)
+	if !ok || r3.db != r1.db || r3.conn != r1.conn || !sameQueryer(r3.q, r1.q) || r1.err != nil || r3.err != nil {
+		return nil, false
+	}
+	if r1.opts.anyEncrypted(r1.cols) || r3.opts.anyEncrypted(r3.cols) {
+		return nil, false
+	}
+	if !r1.composable() || !r3.composable() {
+		return nil, false
+	}
+	return r3, true
+}
+
+// setOp creates a relation from a sql set operation on two relations with the
+// same heading, if they are on the same server.
+func (r1 *sqlTable) setOp(op string, r2 rel.Relation) (*sqlTable, bool) {
+	r3, ok := r1.sameDB(r2)
+	if !ok || reflect.TypeOf(r1.zero) != reflect.TypeOf(r3.zero) {
+		return nil, false
+	}
+	if f := Feature(op); (f == FeatureExcept || f == FeatureIntersect) && !Supports(r1.dialect(), f) {
+		return nil, false
+	}
+	return &sqlTable{
+		db:             r1.db,
+		conn:           r1.conn,
+		q:              r1.q,
+		src:            &setSource{op, r1, r3},
+		cols:           colNames(r1.zero),
+		zero:           r1.zero,
+		cKeys:          rel.DefaultKeys(r1.zero),
+		sourceDistinct: true,
+		opts:           r1.opts,
+	}, true
 }
 
 // Union creates a new relation by unioning the bodies of both inputs
+// If both r1 and r2 are on the same server, it is passed through to the
+// source database.
 func (r1 *sqlTable) Union(r2 rel.Relation) rel.Relation {
-	// TODO(jonlawlor): if both r1 and r2 are on the same server, pass it
-	// through to the source database.
+	op := "UNION"
+	if r1.opts.distinct == AllowDuplicates {
+		op = "UNION ALL"
+	}
+	if r3, ok := r1.setOp(op, r2); ok {
+		return r3
+	}
 	return rel.NewUnion(r1, r2)
 }
 
 // Diff creates a new relation by set minusing the two inputs
+// If both r1 and r2 are on the same server, it is passed through to the
+// source database.
 func (r1 *sqlTable) Diff(r2 rel.Relation) rel.Relation {
-	// TODO(jonlawlor): if both r1 and r2 are on the same server, pass it
-	// through to the source database.
+	if r3, ok := r1.setOp("EXCEPT", r2); ok {
+		// the difference can't introduce new duplicates, so it keeps the
+		// candidate keys of r1.
+		r3.cKeys = r1.cKeys
+		return r3
+	}
 	return rel.NewDiff(r1, r2)
 }
 
+// Intersect creates a new relation with the tuples that are in both inputs
+// If both r1 and r2 are on the same server, it is passed through to the
+// source database, otherwise it is evaluated client side as a join.
+func (r1 *sqlTable) Intersect(r2 rel.Relation) rel.Relation {
+	if reflect.TypeOf(r1.zero) != reflect.TypeOf(r2.Zero()) {
+		r3 := *r1
+		r3.err = fmt.Errorf("relsql: intersect of %v with %v requires identical headings", r1.zero, r2.Zero())
+		return &r3
+	}
+	if r3, ok := r1.setOp("INTERSECT", r2); ok {
+		// the intersection is a subset of r1, so it keeps its candidate keys.
+		r3.cKeys = r1.cKeys
+		return r3
+	}
+	return rel.NewJoin(r1, r2, r1.zero)
+}
+
+// Intersect creates a new relation with the tuples that are in both r1 and r2,
+// which must have the same heading.  rel.Relation has no intersection, so
+// this is provided as a function.  If r1 is a relation from this package and
+// r2 is on the same server, it is compiled to INTERSECT, otherwise it is
+// evaluated as a natural join on every attribute.
+func Intersect(r1, r2 rel.Relation) rel.Relation {
+	if r3, ok := r1.(*sqlTable); ok {
+		return r3.Intersect(r2)
+	}
+	return r1.Join(r2, r1.Zero())
+}
+
 // Join creates a new relation by performing a natural join on the inputs
+// If both r1 and r2 are on the same server, it is passed through to the
+// source database.  Otherwise, r2 may be on another database or engine, or in
+// memory, and the join is done client side, after restricting r1 to the join
+// values of r2 when there are few enough of them.  See WithSemiJoinLimit.
 func (r1 *sqlTable) Join(r2 rel.Relation, zero interface{}) rel.Relation {
-	// TODO(jonlawlor): if both r1 and r2 are on the same server, pass it
-	// through to the source database.
-	return rel.NewJoin(r1, r2, zero)
+	r3, ok := r1.sameDB(r2)
+	e3 := reflect.TypeOf(zero)
+	if checkZero(e3) != nil || r1.err != nil || r2.Err() != nil {
+		return rel.NewJoin(r1, r2, zero)
+	}
+	if !ok {
+		return newSemiJoin(r1, r2, zero)
+	}
+	e1 := reflect.TypeOf(r1.zero)
+	e2 := reflect.TypeOf(r3.zero)
+
+	// the join is on the attributes common to both relations, which should
+	// be a key of one of them, or a foreign key between them
+	on := commonAttributes(e1, e2)
+	if r1.opts.logging() && len(on) > 0 && !r1.joinBacked(r3, on) {
+		r1.opts.warnf("relsql: join of %v and %v matches on %v, which is not a key or foreign key", r1, r3, on)
+	}
+
+	// each attribute of the result comes from one side of the join
+	cols := make([]column, e3.NumField())
+	for i := range cols {
+		name := e3.Field(i).Name
+		if _, ok := e1.FieldByName(name); ok {
+			cols[i] = column{"t1", name}
+		} else if _, ok := e2.FieldByName(name); ok {
+			cols[i] = column{"t2", name}
+		} else {
+			// let rel report the invalid join
+			return rel.NewJoin(r1, r2, zero)
+		}
+	}
+	return &sqlTable{
+		db:             r1.db,
+		conn:           r1.conn,
+		q:              r1.q,
+		src:            &joinSource{r1, r3, on},
+		cols:           cols,
+		zero:           zero,
+		cKeys:          joinKeys(r1.cKeys, r3.cKeys),
+		sourceDistinct: true,
+		opts:           r1.opts,
+	}
+}
+
+// joinKeys returns the candidate keys of the join of two relations, which are
+// the unions of each pair of candidate keys from the inputs.
+func joinKeys(cKeys1, cKeys2 rel.CandKeys) rel.CandKeys {
+	var cKeys rel.CandKeys
+	for _, ck1 := range cKeys1 {
+		for _, ck2 := range cKeys2 {
+			ck := append([]rel.Attribute{}, ck1...)
+			for _, att := range ck2 {
+				if !containsAttribute(ck1, att) {
+					ck = append(ck, att)
+				}
+			}
+			cKeys = append(cKeys, ck)
+		}
+	}
+	rel.OrderCandidateKeys(cKeys)
+	return cKeys
+}
+
+// containsAttribute returns true if att is in atts
+func containsAttribute(atts []rel.Attribute, att rel.Attribute) bool {
+	for _, a := range atts {
+		if a == att {
+			return true
+		}
+	}
+	return false
 }
 
 // GroupBy creates a new relation by grouping and applying a user defined func
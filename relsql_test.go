@@ -6,6 +6,7 @@ import (
 	"database/sql"
 	"github.com/jonlawlor/rel"
 	_ "github.com/mattn/go-sqlite3"
+	"reflect"
 	"testing"
 )
 
@@ -17,8 +18,9 @@ func TestSelect(t *testing.T) {
 		statement *selectStatement
 		query     string
 	}{
-		{&selectStatement{true, "foo, bar", "baz"}, "SELECT foo, bar FROM baz"},
-		{&selectStatement{false, "foo", "baz"}, "SELECT DISTINCT foo FROM baz"},
+		{&selectStatement{false, "foo, bar", "baz", ""}, "SELECT foo, bar FROM baz"},
+		{&selectStatement{true, "foo", "baz", ""}, "SELECT DISTINCT foo FROM baz"},
+		{&selectStatement{false, "foo", "baz", "foo = ?"}, "SELECT foo FROM baz WHERE foo = ?"},
 	}
 	for i, tt := range queryTest {
 		if str, _ := tt.statement.queryString(); str != tt.query {
@@ -27,6 +29,222 @@ func TestSelect(t *testing.T) {
 	}
 }
 
+// test the distinctness policies against projections of key and non-key
+// subsets of the heading
+func TestDistinctPolicy(t *testing.T) {
+	type supplierTup struct {
+		SNO    int
+		SName  string
+		Status int
+		City   string
+	}
+	type keyTup struct {
+		SNO   int
+		SName string
+	}
+	type nonKeyTup struct {
+		SName string
+		City  string
+	}
+	keys := [][]string{[]string{"SNO"}}
+
+	var queryTest = []struct {
+		rel   rel.Relation
+		query string
+	}{
+		{New(nil, "suppliers", supplierTup{}, keys), "SELECT SNO, SName, Status, City FROM suppliers"},
+		{New(nil, "suppliers", supplierTup{}, nil), "SELECT DISTINCT SNO, SName, Status, City FROM suppliers"},
+		{New(nil, "suppliers", supplierTup{}, keys).Project(keyTup{}), "SELECT SNO, SName FROM suppliers"},
+		{New(nil, "suppliers", supplierTup{}, keys).Project(nonKeyTup{}), "SELECT DISTINCT SName, City FROM suppliers"},
+		{New(nil, "suppliers", supplierTup{}, nil).Project(keyTup{}), "SELECT DISTINCT SNO, SName FROM suppliers"},
+		{New(nil, "suppliers", supplierTup{}, keys, WithDistinct(ForceDistinct)), "SELECT DISTINCT SNO, SName, Status, City FROM suppliers"},
+		{New(nil, "suppliers", supplierTup{}, keys, WithDistinct(ForceDistinct)).Project(keyTup{}), "SELECT DISTINCT SNO, SName FROM suppliers"},
+		{New(nil, "suppliers", supplierTup{}, nil, WithDistinct(AllowDuplicates)), "SELECT SNO, SName, Status, City FROM suppliers"},
+		{New(nil, "suppliers", supplierTup{}, keys, WithDistinct(AllowDuplicates)).Project(nonKeyTup{}), "SELECT SName, City FROM suppliers"},
+	}
+	for i, tt := range queryTest {
+		if str, _, _ := tt.rel.(*sqlTable).queryString(); str != tt.query {
+			t.Errorf("%d has queryString() => %v, want %v", i, str, tt.query)
+		}
+	}
+}
+
+// test that stacked restrictions collapse into a single WHERE clause
+func TestRestrict(t *testing.T) {
+	type supplierTup struct {
+		SNO    int
+		SName  string
+		Status int
+		City   string
+	}
+	suppliers := New(nil, "suppliers", supplierTup{}, [][]string{[]string{"SNO"}})
+	sno := Attribute("SNO")
+	city := Attribute("City")
+	status := Attribute("Status")
+
+	var queryTest = []struct {
+		rel   rel.Relation
+		query string
+		args  []interface{}
+	}{
+		{suppliers.Restrict(sno.EQ(1)), "SELECT SNO, SName, Status, City FROM suppliers WHERE SNO = ?", []interface{}{1}},
+		{suppliers.Restrict(sno.GT(1)).Restrict(city.EQ("London")), "SELECT SNO, SName, Status, City FROM suppliers WHERE SNO > ? AND City = ?", []interface{}{1, "London"}},
+		{suppliers.Restrict(sno.GT(1)).Restrict(sno.GT(1)), "SELECT SNO, SName, Status, City FROM suppliers WHERE SNO > ?", []interface{}{1}},
+		{suppliers.Restrict(And(sno.GT(1), city.EQ("London"))).Restrict(city.EQ("London")), "SELECT SNO, SName, Status, City FROM suppliers WHERE SNO > ? AND City = ?", []interface{}{1, "London"}},
+		{suppliers.Restrict(sno.LT(status)).Restrict(status.GT(sno)), "SELECT SNO, SName, Status, City FROM suppliers WHERE SNO < Status", nil},
+		{suppliers.Restrict(Or(sno.EQ(1), sno.EQ(2))), "SELECT SNO, SName, Status, City FROM suppliers WHERE (SNO = ? OR SNO = ?)", []interface{}{1, 2}},
+	}
+	for i, tt := range queryTest {
+		str, args, _ := tt.rel.(*sqlTable).queryString()
+		if str != tt.query {
+			t.Errorf("%d has queryString() => %v, want %v", i, str, tt.query)
+		}
+		if !reflect.DeepEqual(args, tt.args) {
+			t.Errorf("%d has args %v, want %v", i, args, tt.args)
+		}
+	}
+	if err := suppliers.Restrict(Attribute("PNO").EQ(1)).Err(); err == nil {
+		t.Errorf("Restrict on missing attribute has Err() => nil")
+	}
+}
+
+// test that projections narrow the select lists of derived tables
+func TestProjectPushdown(t *testing.T) {
+	type supplierTup struct {
+		SNO    int
+		SName  string
+		Status int
+		City   string
+	}
+	type orderTup struct {
+		PNO int
+		SNO int
+		Qty int
+	}
+	type joinTup struct {
+		SNO    int
+		SName  string
+		Status int
+		City   string
+		PNO    int
+		Qty    int
+	}
+	type nameQtyTup struct {
+		SName string
+		Qty   int
+	}
+	type cityTup struct {
+		City string
+	}
+	suppliers := New(nil, "suppliers", supplierTup{}, [][]string{[]string{"SNO"}})
+	orders := New(nil, "orders", orderTup{}, [][]string{[]string{"PNO", "SNO"}})
+	london := suppliers.Restrict(Attribute("City").EQ("London"))
+
+	var queryTest = []struct {
+		rel   rel.Relation
+		query string
+	}{
+		{suppliers.Union(london), "SELECT SNO, SName, Status, City FROM (SELECT SNO, SName, Status, City FROM suppliers UNION SELECT SNO, SName, Status, City FROM suppliers WHERE City = ?) AS s"},
+		{suppliers.Union(london).Project(cityTup{}), "SELECT DISTINCT City FROM (SELECT City FROM suppliers UNION SELECT City FROM suppliers WHERE City = ?) AS s"},
+		{Intersect(suppliers, london), "SELECT SNO, SName, Status, City FROM (SELECT SNO, SName, Status, City FROM suppliers INTERSECT SELECT SNO, SName, Status, City FROM suppliers WHERE City = ?) AS s"},
+		{suppliers.Project(struct{}{}), "SELECT 1 WHERE EXISTS (SELECT 1 FROM suppliers)"},
+		{london.Project(struct{}{}), "SELECT 1 WHERE EXISTS (SELECT 1 FROM suppliers WHERE City = ?)"},
+		{suppliers.Union(london).Project(struct{}{}), "SELECT 1 WHERE EXISTS (SELECT 1 FROM (SELECT 1 WHERE EXISTS (SELECT 1 FROM suppliers) UNION SELECT 1 WHERE EXISTS (SELECT 1 FROM suppliers WHERE City = ?)) AS s)"},
+		{suppliers.Diff(london).Project(cityTup{}), "SELECT DISTINCT City FROM (SELECT SNO, SName, Status, City FROM suppliers EXCEPT SELECT SNO, SName, Status, City FROM suppliers WHERE City = ?) AS s"},
+		{suppliers.Join(orders, joinTup{}), "SELECT t1.SNO, t1.SName, t1.Status, t1.City, t2.PNO, t2.Qty FROM (SELECT SNO, SName, Status, City FROM suppliers) AS t1 JOIN (SELECT PNO, SNO, Qty FROM orders) AS t2 ON t1.SNO = t2.SNO"},
+		{suppliers.Join(orders, joinTup{}).Project(nameQtyTup{}), "SELECT DISTINCT t1.SName, t2.Qty FROM (SELECT SNO, SName FROM suppliers) AS t1 JOIN (SELECT SNO, Qty FROM orders) AS t2 ON t1.SNO = t2.SNO"},
+		{london.Join(orders, joinTup{}).Project(nameQtyTup{}), "SELECT DISTINCT t1.SName, t2.Qty FROM (SELECT SNO, SName FROM suppliers WHERE City = ?) AS t1 JOIN (SELECT SNO, Qty FROM orders) AS t2 ON t1.SNO = t2.SNO"},
+	}
+	for i, tt := range queryTest {
+		if str, _, _ := tt.rel.(*sqlTable).queryString(); str != tt.query {
+			t.Errorf("%d has queryString() => %v, want %v", i, str, tt.query)
+		}
+	}
+}
+
+// test that tuple types and candidate keys are checked on construction
+func TestTupleType(t *testing.T) {
+	type unexportedTup struct {
+		SNO   int
+		sName string
+	}
+	type embedTup struct {
+		SNO int
+		unexportedTup
+	}
+	anonTup := struct {
+		SNO   int
+		SName string
+	}{}
+
+	var zeroTest = []struct {
+		zero  interface{}
+		keys  [][]string
+		isErr bool
+	}{
+		{anonTup, [][]string{[]string{"SNO"}}, false},
+		{anonTup, nil, false},
+		{anonTup, [][]string{[]string{"PNO"}}, true},
+		{unexportedTup{}, nil, true},
+		{embedTup{}, nil, true},
+		{1, nil, true},
+	}
+	for i, tt := range zeroTest {
+		if err := New(nil, "suppliers", tt.zero, tt.keys).Err(); (err != nil) != tt.isErr {
+			t.Errorf("%d has New(%T).Err() => %v, want error %v", i, tt.zero, err, tt.isErr)
+		}
+	}
+}
+
+// test that renames are checked against the original heading
+func TestRename(t *testing.T) {
+	type supplierTup struct {
+		SNO    int
+		SName  string
+		Status int
+		City   string
+	}
+	type titleCaseTup struct {
+		Sno    int
+		SName  string
+		Status int
+		City   string
+	}
+	type shortTup struct {
+		Sno   int
+		SName string
+	}
+	type badTypeTup struct {
+		Sno    int
+		SName  []int
+		Status int
+		City   string
+	}
+	type convertTup struct {
+		Sno    int64
+		SName  string
+		Status float64
+		City   string
+	}
+	suppliers := New(nil, "suppliers", supplierTup{}, [][]string{[]string{"SNO"}})
+
+	var renameTest = []struct {
+		zero  interface{}
+		isErr bool
+	}{
+		{titleCaseTup{}, false},
+		{convertTup{}, false},
+		{shortTup{}, true},
+		{badTypeTup{}, true},
+		{1, true},
+	}
+	for i, tt := range renameTest {
+		if err := suppliers.Rename(tt.zero).Err(); (err != nil) != tt.isErr {
+			t.Errorf("%d has Rename(%T).Err() => %v, want error %v", i, tt.zero, err, tt.isErr)
+		}
+	}
+}
+
 // test database connection and tuple generation
 func TestSQL(t *testing.T) {
 
@@ -87,6 +305,7 @@ func TestSQL(t *testing.T) {
 
 	// create a new relation from that table
 	suppliers := New(db, "suppliers", supplierTup{}, [][]string{[]string{"SNO"}})
+	london := suppliers.Restrict(Attribute("City").EQ("London"))
 
 	// orders relation, with candidate keys {PNO, SNO}
 	type orderTup struct {
@@ -178,12 +397,25 @@ func TestSQL(t *testing.T) {
 	}{
 		{suppliers, "Relation(SNO, SName, Status, City)", 4, 5},
 		{suppliers.Restrict(rel.Attribute("SNO").EQ(1)), "σ{SNO == 1}(Relation(SNO, SName, Status, City))", 4, 1},
+		{suppliers.Restrict(Attribute("SNO").GT(1)).Restrict(Attribute("City").EQ("Paris")), "σ{SNO > 1 ∧ City == Paris}(Relation(SNO, SName, Status, City))", 4, 2},
 		{suppliers.Project(distinctTup{}), "Relation(SNO, SName)", 2, 5},
 		{suppliers.Project(nonDistinctTup{}), "Relation(SName, City)", 2, 5},
+		{suppliers.Project(struct {
+			SNO  int
+			City string
+		}{}), "Relation(SNO, City)", 2, 5},
 		{suppliers.Rename(titleCaseTup{}), "Relation(Sno, SName, Status, City)", 4, 5},
 		{suppliers.Diff(suppliers.Restrict(rel.Attribute("SNO").EQ(1))), "Relation(SNO, SName, Status, City) − σ{SNO == 1}(Relation(SNO, SName, Status, City))", 4, 4},
 		{suppliers.Union(suppliers.Restrict(rel.Attribute("SNO").EQ(1))), "Relation(SNO, SName, Status, City) ∪ σ{SNO == 1}(Relation(SNO, SName, Status, City))", 4, 5},
 		{suppliers.Join(orders, joinTup{}), "Relation(SNO, SName, Status, City) ⋈ Relation(PNO, SNO, Qty)", 6, 11},
+		{suppliers.Union(london), "Relation(SNO, SName, Status, City) ∪ σ{City == London}(Relation(SNO, SName, Status, City))", 4, 5},
+		{suppliers.Diff(london), "Relation(SNO, SName, Status, City) − σ{City == London}(Relation(SNO, SName, Status, City))", 4, 3},
+		{suppliers.Diff(london).Project(nonDistinctTup{}), "π{SName, City}(Relation(SNO, SName, Status, City) − σ{City == London}(Relation(SNO, SName, Status, City)))", 2, 3},
+		{suppliers.Project(struct{}{}), "Relation()", 0, 1},
+		{suppliers.Restrict(Attribute("SNO").GT(5)).Project(struct{}{}), "σ{SNO > 5}(Relation())", 0, 0},
+		{Intersect(suppliers, london), "Relation(SNO, SName, Status, City) ∩ σ{City == London}(Relation(SNO, SName, Status, City))", 4, 2},
+		{Intersect(suppliers, suppliers.Restrict(rel.Attribute("SNO").EQ(1))), "Relation(SNO, SName, Status, City) ⋈ σ{SNO == 1}(Relation(SNO, SName, Status, City))", 4, 1},
+		{suppliers.Join(suppliers.Project(distinctTup{}), supplierTup{}), "Relation(SNO, SName, Status, City) ⋈ Relation(SNO, SName)", 4, 5},
 		{suppliers.GroupBy(groupByTup{}, groupFcn), "Relation(SNO, SName, Status, City).GroupBy({City, Status}->{Status})", 2, 3},
 		{suppliers.Map(mapFcn, mapKeys), "Relation(SNO, SName, Status, City).Map({SNO, SName, Status, City}->{SNO, SName, Status2, City})", 4, 5},
 		{suppliers.Map(mapFcn, [][]string{}), "Relation(SNO, SName, Status, City).Map({SNO, SName, Status, City}->{SNO, SName, Status2, City})", 4, 5},
@@ -204,3 +436,43 @@ func TestSQL(t *testing.T) {
 
 	}
 }
+
+// test that a stream which fails part way through is reported as partial
+func TestPartialResult(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	// the null name can't be scanned into a string, which truncates the
+	// results after the first row
+	_, err = db.Exec(`
+	create table parts (PNO integer not null primary key, PName text);
+	insert into parts (PNO, PName) values (1, 'Nut'), (2, NULL), (3, 'Screw');
+	`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	type partTup struct {
+		PNO   int
+		PName string
+	}
+
+	parts := New(db, "parts", partTup{}, [][]string{[]string{"PNO"}})
+	if card := rel.Card(parts); card != 1 {
+		t.Errorf("Card() => %v, want %v", card, 1)
+	}
+	err = parts.Err()
+	perr, ok := err.(*PartialError)
+	if !ok {
+		t.Errorf("Err() => %v, want *PartialError", err)
+		return
+	}
+	if perr.Sent != 1 {
+		t.Errorf("PartialError.Sent => %v, want %v", perr.Sent, 1)
+	}
+}
@@ -0,0 +1,217 @@
+// Package relsqlbench generates synthetic tables and measures the relations
+// that relsql builds over them, so that changes to the query compiler can be
+// checked for performance regressions.  The benchmarks run against any
+// database with a driver: tables are created and read through relsql, so the
+// relsql options, such as the dialect, determine the sql that is used.
+//
+// The helpers Scan, Restrict and Join are meant to be called from Benchmark
+// functions, and work
+// with the go test profiling flags, for example
+//
+//	go test -bench Scan -cpuprofile cpu.out
+package relsqlbench
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"github.com/jonlawlor/relsql"
+	"reflect"
+	"testing"
+)
+
+// Table describes a synthetic table.  Every table has an integer ID, which is
+// its key, and an integer Grp, which takes Groups distinct values, followed by
+// Width text columns.
+type Table struct {
+	Name   string
+	Rows   int
+	Width  int
+	Groups int
+}
+
+// Tuple returns the zero tuple of the table, which is an anonymous struct
+// with fields ID, Grp, and C1 through CWidth.
+func (t Table) Tuple() interface{} {
+	return reflect.Zero(t.tupleType()).Interface()
+}
+
+// tupleType returns the type of the tuples of the table
+func (t Table) tupleType() reflect.Type {
+	fields := []reflect.StructField{
+		{Name: "ID", Type: reflect.TypeOf(int64(0))},
+		{Name: "Grp", Type: reflect.TypeOf(int64(0))},
+	}
+	for i := 1; i <= t.Width; i++ {
+		fields = append(fields, reflect.StructField{Name: fmt.Sprintf("C%d", i), Type: reflect.TypeOf("")})
+	}
+	return reflect.StructOf(fields)
+}
+
+// groups returns the number of groups, which is at least one
+func (t Table) groups() int {
+	if t.Groups < 1 {
+		return 1
+	}
+	return t.Groups
+}
+
+// Generate creates the table in the database, fills it with rows, and returns
+// the relation that reads from it.
+func Generate(db *sql.DB, t Table, opts ...relsql.Option) (rel.Relation, error) {
+	e := t.tupleType()
+	ckeystr := [][]string{[]string{"ID"}}
+	if err := relsql.CreateTable(db, t.Name, t.Tuple(), ckeystr, opts...); err != nil {
+		return nil, err
+	}
+	body := reflect.MakeSlice(reflect.SliceOf(e), t.Rows, t.Rows)
+	for i := 0; i < t.Rows; i++ {
+		tup := body.Index(i)
+		tup.Field(0).SetInt(int64(i))
+		tup.Field(1).SetInt(int64(i % t.groups()))
+		for j := 2; j < e.NumField(); j++ {
+			tup.Field(j).SetString(fmt.Sprintf("r%dc%d", i, j-1))
+		}
+	}
+	if _, err := relsql.Insert(db, t.Name, rel.New(body.Interface(), ckeystr), opts...); err != nil {
+		return nil, err
+	}
+	return relsql.New(db, t.Name, t.Tuple(), ckeystr, opts...), nil
+}
+
+// GenerateGroups creates a table with one row for each group of t, with the
+// attributes Grp and Label, and returns the relation that reads from it.  It
+// is joined with t by the join benchmarks.
+func GenerateGroups(db *sql.DB, name string, t Table, opts ...relsql.Option) (rel.Relation, error) {
+	type groupTup struct {
+		Grp   int64
+		Label string
+	}
+	ckeystr := [][]string{[]string{"Grp"}}
+	if err := relsql.CreateTable(db, name, groupTup{}, ckeystr, opts...); err != nil {
+		return nil, err
+	}
+	body := make([]groupTup, t.groups())
+	for i := range body {
+		body[i] = groupTup{int64(i), fmt.Sprintf("group %d", i)}
+	}
+	if _, err := relsql.Insert(db, name, rel.New(body, ckeystr), opts...); err != nil {
+		return nil, err
+	}
+	return relsql.New(db, name, groupTup{}, ckeystr, opts...), nil
+}
+
+// Drain reads every tuple of r, and returns the number of tuples and the
+// relation's error.
+func Drain(r rel.Relation) (int, error) {
+	ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, reflect.TypeOf(r.Zero())), 0)
+	r.TupleChan(ch.Interface())
+	n := 0
+	for {
+		if _, ok := ch.Recv(); !ok {
+			break
+		}
+		n++
+	}
+	return n, r.Err()
+}
+
+// run drains r b.N times, and reports the number of tuples per second.
+func run(b *testing.B, r func() rel.Relation) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	rows := 0
+	for i := 0; i < b.N; i++ {
+		n, err := Drain(r())
+		if err != nil {
+			b.Fatal(err)
+		}
+		rows += n
+	}
+	b.StopTimer()
+	if s := b.Elapsed().Seconds(); s > 0 {
+		b.ReportMetric(float64(rows)/s, "tuples/s")
+	}
+}
+
+// Scan measures reading every tuple of r.
+func Scan(b *testing.B, r rel.Relation) {
+	run(b, func() rel.Relation { return r })
+}
+
+// Restrict measures reading the tuples of a generated table in its
+// first group.  If pushdown is true, the restriction is compiled into the
+// query, and otherwise it is evaluated client side on every tuple.
+func Restrict(b *testing.B, r rel.Relation, pushdown bool) {
+	p := relsql.Attribute("Grp").EQ(int64(0))
+	run(b, func() rel.Relation {
+		if pushdown {
+			return r.Restrict(p)
+		}
+		return rel.NewRestrict(r, p)
+	})
+}
+
+// JoinStrategy is the way that Join evaluates a join.
+type JoinStrategy int
+
+const (
+	// Pushdown compiles the join into a single query, which requires both
+	// relations to be on the same database.
+	Pushdown JoinStrategy = iota
+
+	// SemiJoin reads the groups into memory, and joins them with the table
+	// after restricting it to their values.
+	SemiJoin
+
+	// ClientSide reads both relations in full and joins them in memory.
+	ClientSide
+)
+
+// String returns the name of the strategy
+func (s JoinStrategy) String() string {
+	switch s {
+	case Pushdown:
+		return "Pushdown"
+	case SemiJoin:
+		return "SemiJoin"
+	case ClientSide:
+		return "ClientSide"
+	}
+	return "JoinStrategy(?)"
+}
+
+// Join measures the join of a generated table r with a subset of
+// its groups, using a join strategy.  groups is from GenerateGroups, and only
+// its first group is joined, so that the semi join can reduce the table.
+func Join(b *testing.B, r, groups rel.Relation, s JoinStrategy) {
+	e := reflect.TypeOf(r.Zero())
+	fields := make([]reflect.StructField, 0, e.NumField()+1)
+	for i := 0; i < e.NumField(); i++ {
+		fields = append(fields, reflect.StructField{Name: e.Field(i).Name, Type: e.Field(i).Type})
+	}
+	fields = append(fields, reflect.StructField{Name: "Label", Type: reflect.TypeOf("")})
+	zero := reflect.Zero(reflect.StructOf(fields)).Interface()
+	first := groups.Restrict(relsql.Attribute("Grp").EQ(int64(0)))
+	run(b, func() rel.Relation {
+		switch s {
+		case SemiJoin:
+			// a copy of the groups in memory is on another engine, so it
+			// can't be pushed down
+			body := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(first.Zero())), 0, 1)
+			ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, reflect.TypeOf(first.Zero())), 0)
+			first.TupleChan(ch.Interface())
+			for {
+				tup, ok := ch.Recv()
+				if !ok {
+					break
+				}
+				body = reflect.Append(body, tup)
+			}
+			return r.Join(rel.New(body.Interface(), [][]string{[]string{"Grp"}}), zero)
+		case ClientSide:
+			return rel.NewJoin(r, first, zero)
+		}
+		return r.Join(first, zero)
+	})
+}
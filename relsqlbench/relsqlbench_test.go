@@ -0,0 +1,84 @@
+package relsqlbench
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"github.com/jonlawlor/relsql"
+	_ "github.com/mattn/go-sqlite3"
+	"testing"
+)
+
+// table is the synthetic table used by the benchmarks
+var table = Table{Name: "bench", Rows: 2000, Width: 4, Groups: 20}
+
+// open creates the benchmark tables in a new sqlite database
+func open(tb testing.TB, name string) (*sql.DB, rel.Relation, rel.Relation) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?mode=memory&cache=shared", name))
+	if err != nil {
+		tb.Fatal(err)
+	}
+	opts := []relsql.Option{relsql.WithDialect(relsql.SQLite), relsql.WithBatchSize(100)}
+	r, err := Generate(db, table, opts...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	groups, err := GenerateGroups(db, "bench_groups", table, opts...)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db, r, groups
+}
+
+// test that the generated tables and the benchmarked relations have the
+// expected tuples
+func TestGenerate(t *testing.T) {
+	db, r, groups := open(t, "generate")
+	defer db.Close()
+
+	var countTest = []struct {
+		r rel.Relation
+		n int
+	}{
+		{r, table.Rows},
+		{groups, table.Groups},
+		{r.Restrict(relsql.Attribute("Grp").EQ(int64(0))), table.Rows / table.Groups},
+	}
+	for i, tt := range countTest {
+		n, err := Drain(tt.r)
+		if err != nil {
+			t.Errorf("%d has Drain() => %v", i, err)
+		}
+		if n != tt.n {
+			t.Errorf("%d has Drain() => %d tuples, want %d", i, n, tt.n)
+		}
+	}
+}
+
+func BenchmarkScan(b *testing.B) {
+	db, r, _ := open(b, "scan")
+	defer db.Close()
+	Scan(b, r)
+}
+
+func BenchmarkRestrictPushdown(b *testing.B) {
+	db, r, _ := open(b, "pushdown")
+	defer db.Close()
+	Restrict(b, r, true)
+}
+
+func BenchmarkRestrictClientSide(b *testing.B) {
+	db, r, _ := open(b, "clientside")
+	defer db.Close()
+	Restrict(b, r, false)
+}
+
+func BenchmarkJoin(b *testing.B) {
+	for _, s := range []JoinStrategy{Pushdown, SemiJoin, ClientSide} {
+		b.Run(s.String(), func(b *testing.B) {
+			db, r, groups := open(b, "join"+s.String())
+			defer db.Close()
+			Join(b, r, groups, s)
+		})
+	}
+}
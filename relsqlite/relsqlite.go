@@ -0,0 +1,406 @@
+//go:build sqlite_vtable || vtable
+
+// Package relsqlite is a sqlite virtual table module which is backed by
+// relations, so that plain sql run in a sqlite session can query and join
+// in-memory Go relations, and relations from other databases, along with the
+// session's own tables.
+//
+// It uses the virtual table API of github.com/mattn/go-sqlite3, which is only
+// built with the sqlite_vtable (or vtable) build tag, so this package needs
+// the same tag.
+package relsqlite
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"github.com/jonlawlor/relsql"
+	"github.com/mattn/go-sqlite3"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ModuleName is the name that Register gives the module in each connection,
+// which is used in CREATE VIRTUAL TABLE statements.
+const ModuleName = "rel"
+
+// Module is a sqlite virtual table module whose tables read from relations.
+// A virtual table is created with
+//
+//	CREATE VIRTUAL TABLE name USING rel(relation)
+//
+// where relation is the name the relation was added to the module with.  If
+// it is omitted, the name of the virtual table is used.  The columns of the
+// table are the attributes of the relation.
+type Module struct {
+	mu   sync.RWMutex
+	rels map[string]rel.Relation
+}
+
+// NewModule returns a module with no relations
+func NewModule() *Module {
+	return &Module{rels: make(map[string]rel.Relation)}
+}
+
+// Add adds a relation to the module with a name, replacing any relation that
+// was already added with it.
+func (m *Module) Add(name string, r rel.Relation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rels[name] = r
+}
+
+// Register registers a sqlite3 driver with the name driverName, whose
+// connections have the module, and a temporary virtual table for each of the
+// module's relations, which has the name the relation was added with.  Only
+// the relations that have been added when a connection is opened have
+// tables in it.
+func Register(driverName string, m *Module) {
+	sql.Register(driverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(c *sqlite3.SQLiteConn) error {
+			if err := c.CreateModule(ModuleName, m); err != nil {
+				return err
+			}
+			for _, name := range m.names() {
+				q := "CREATE VIRTUAL TABLE temp." + quote(name) + " USING " + ModuleName + "(" + quote(name) + ")"
+				if _, err := c.Exec(q, nil); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	})
+}
+
+// names returns the sorted names of the module's relations
+func (m *Module) names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var names []string
+	for name := range m.rels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Create implements the sqlite3.Module interface
+func (m *Module) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Connect(c, args)
+}
+
+// Connect implements the sqlite3.Module interface.  The arguments are the
+// name of the module, the database and the table, followed by the arguments
+// of the CREATE VIRTUAL TABLE statement.
+func (m *Module) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	name := unquote(args[2])
+	if len(args) > 3 {
+		name = unquote(args[3])
+	}
+	m.mu.RLock()
+	r, ok := m.rels[name]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("relsqlite: no relation named %s", name)
+	}
+	e := reflect.TypeOf(r.Zero())
+	cols := make([]string, e.NumField())
+	for i := range cols {
+		cols[i] = quote(e.Field(i).Name) + columnType(e.Field(i).Type)
+	}
+	if err := c.DeclareVTab("CREATE TABLE x(" + strings.Join(cols, ", ") + ")"); err != nil {
+		return nil, err
+	}
+	return &table{r}, nil
+}
+
+// DestroyModule implements the sqlite3.Module interface
+func (m *Module) DestroyModule() {}
+
+// columnType returns the declared type of the column of an attribute, which
+// sets its affinity, or nothing if it has none.
+func columnType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return columnType(t.Elem())
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return " INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return " REAL"
+	case reflect.String:
+		return " TEXT"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return " BLOB"
+		}
+	}
+	return ""
+}
+
+// table is a virtual table that reads from a relation
+type table struct {
+	r rel.Relation
+}
+
+// BestIndex implements the sqlite3.VTab interface.  Equality constraints on
+// attributes of comparable kinds are restrictions of the relation, which are
+// evaluated by it, and so may be compiled into the query of a relation from
+// relsql.  The columns of the constraints are in IdxStr.
+func (t *table) BestIndex(cst []sqlite3.InfoConstraint, ob []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	e := reflect.TypeOf(t.r.Zero())
+	res := &sqlite3.IndexResult{Used: make([]bool, len(cst)), EstimatedCost: 1e6, EstimatedRows: 1e6}
+	var cols []string
+	for i, c := range cst {
+		if c.Usable && c.Op == sqlite3.OpEQ && c.Column >= 0 && restrictable(e.Field(c.Column).Type) {
+			res.Used[i] = true
+			cols = append(cols, strconv.Itoa(c.Column))
+		}
+	}
+	if len(cols) > 0 {
+		res.IdxStr = strings.Join(cols, ",")
+		res.EstimatedCost, res.EstimatedRows = 1e3, 10
+	}
+	return res, nil
+}
+
+// restrictable returns true if the relation can evaluate equality on
+// attributes of type t
+func restrictable(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// Disconnect implements the sqlite3.VTab interface
+func (t *table) Disconnect() error { return nil }
+
+// Destroy implements the sqlite3.VTab interface
+func (t *table) Destroy() error { return nil }
+
+// Open implements the sqlite3.VTab interface
+func (t *table) Open() (sqlite3.VTabCursor, error) {
+	return &cursor{t: t}, nil
+}
+
+// cursor reads the tuples of a virtual table's relation
+type cursor struct {
+	t     *table
+	rows  *relsql.Rows
+	tup   []interface{}
+	rowid int64
+	eof   bool
+}
+
+// Filter implements the sqlite3.VTabCursor interface.  It starts reading the
+// relation, restricted by the values of the equality constraints that
+// BestIndex used.
+func (c *cursor) Filter(idxNum int, idxStr string, vals []interface{}) error {
+	c.Close()
+	r := c.t.r
+	e := reflect.TypeOf(r.Zero())
+	if idxStr != "" {
+		for i, s := range strings.Split(idxStr, ",") {
+			col, err := strconv.Atoi(s)
+			if err != nil {
+				return err
+			}
+			f := e.Field(col)
+			v, ok := convert(vals[i], f.Type)
+			if !ok {
+				// no tuple can equal the value
+				c.eof = true
+				return nil
+			}
+			r = r.Restrict(rel.Attribute(f.Name).EQ(v))
+		}
+	}
+	c.rows = relsql.NewRows(r)
+	c.tup = make([]interface{}, e.NumField())
+	c.rowid = 0
+	return c.Next()
+}
+
+// convert converts a sqlite value to the type of an attribute, and returns
+// false if no value of the type equals it.
+func convert(v interface{}, t reflect.Type) (interface{}, bool) {
+	switch v := v.(type) {
+	case int64:
+		switch t.Kind() {
+		case reflect.Bool:
+			return reflect.ValueOf(v != 0).Convert(t).Interface(), v == 0 || v == 1
+		case reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(v).Convert(t).Interface(), true
+		}
+		return convertInt(v, t)
+	case float64:
+		switch t.Kind() {
+		case reflect.Float32, reflect.Float64:
+			return reflect.ValueOf(v).Convert(t).Interface(), true
+		}
+		if v != math.Trunc(v) || math.Abs(v) >= 1<<63 {
+			return nil, false
+		}
+		return convert(int64(v), t)
+	case string:
+		if t.Kind() == reflect.String {
+			return reflect.ValueOf(v).Convert(t).Interface(), true
+		}
+	case []byte:
+		return convert(string(v), t)
+	}
+	return nil, false
+}
+
+// convertInt converts an integer to an integer type, and returns false if it
+// isn't representable in it
+func convertInt(v int64, t reflect.Type) (interface{}, bool) {
+	rv := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if rv.OverflowInt(v) {
+			return nil, false
+		}
+		rv.SetInt(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v < 0 || rv.OverflowUint(uint64(v)) {
+			return nil, false
+		}
+		rv.SetUint(uint64(v))
+	default:
+		return nil, false
+	}
+	return rv.Interface(), true
+}
+
+// Next implements the sqlite3.VTabCursor interface
+func (c *cursor) Next() error {
+	if c.rows == nil || !c.rows.Next() {
+		c.eof = true
+		if c.rows != nil {
+			return c.rows.Err()
+		}
+		return nil
+	}
+	dest := make([]interface{}, len(c.tup))
+	for i := range dest {
+		dest[i] = &c.tup[i]
+	}
+	if err := c.rows.Scan(dest...); err != nil {
+		return err
+	}
+	c.rowid++
+	c.eof = false
+	return nil
+}
+
+// EOF implements the sqlite3.VTabCursor interface
+func (c *cursor) EOF() bool {
+	return c.eof
+}
+
+// Column implements the sqlite3.VTabCursor interface
+func (c *cursor) Column(ctx *sqlite3.SQLiteContext, col int) error {
+	return result(ctx, c.tup[col])
+}
+
+// result sets the result of a column to a value of an attribute
+func result(ctx *sqlite3.SQLiteContext, v interface{}) error {
+	if vr, ok := v.(driver.Valuer); ok {
+		if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr && rv.IsNil() {
+			ctx.ResultNull()
+			return nil
+		}
+		dv, err := vr.Value()
+		if err != nil {
+			return err
+		}
+		if _, ok := dv.(driver.Valuer); ok {
+			return fmt.Errorf("relsqlite: %T has a Value of its own type", v)
+		}
+		return result(ctx, dv)
+	}
+	switch v := v.(type) {
+	case nil:
+		ctx.ResultNull()
+		return nil
+	case []byte:
+		ctx.ResultBlob(v)
+		return nil
+	case time.Time:
+		ctx.ResultText(v.Format(time.RFC3339Nano))
+		return nil
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr:
+		if rv.IsNil() {
+			ctx.ResultNull()
+			return nil
+		}
+		return result(ctx, rv.Elem().Interface())
+	case reflect.Bool:
+		ctx.ResultBool(rv.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		ctx.ResultInt64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := rv.Uint(); u > math.MaxInt64 {
+			ctx.ResultText(strconv.FormatUint(u, 10))
+		} else {
+			ctx.ResultInt64(int64(u))
+		}
+	case reflect.Float32, reflect.Float64:
+		ctx.ResultDouble(rv.Float())
+	case reflect.String:
+		ctx.ResultText(rv.String())
+	default:
+		ctx.ResultText(fmt.Sprint(v))
+	}
+	return nil
+}
+
+// Rowid implements the sqlite3.VTabCursor interface.  Rows are numbered in
+// the order they are read, so the ids aren't stable between scans.
+func (c *cursor) Rowid() (int64, error) {
+	return c.rowid, nil
+}
+
+// Close implements the sqlite3.VTabCursor interface
+func (c *cursor) Close() error {
+	if c.rows == nil {
+		return nil
+	}
+	err := c.rows.Close()
+	c.rows = nil
+	return err
+}
+
+// quote quotes an identifier
+func quote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// unquote removes the quotes of an identifier in the arguments of a CREATE
+// VIRTUAL TABLE statement
+func unquote(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) >= 2 {
+		switch q := s[0]; {
+		case q == '"' && s[len(s)-1] == '"', q == '\'' && s[len(s)-1] == '\'', q == '`' && s[len(s)-1] == '`':
+			return strings.Replace(s[1:len(s)-1], string(q)+string(q), string(q), -1)
+		case q == '[' && s[len(s)-1] == ']':
+			return s[1 : len(s)-1]
+		}
+	}
+	return s
+}
@@ -0,0 +1,142 @@
+//go:build sqlite_vtable || vtable
+
+package relsqlite
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"github.com/jonlawlor/relsql"
+	"reflect"
+	"testing"
+)
+
+type supplierTup struct {
+	SNO    int
+	SName  string
+	Status int
+	City   string
+}
+
+type shipmentTup struct {
+	SNO int
+	PNO int
+	Qty int
+}
+
+// testModule is the module of the driver used by the tests, which can only be
+// registered once
+var testModule = NewModule()
+
+func init() {
+	Register("sqlite3_relsqlite_test", testModule)
+}
+
+// test joining sqlite tables with relations
+func TestModule(t *testing.T) {
+	suppliers := rel.New([]supplierTup{
+		{1, "Smith", 20, "London"},
+		{2, "Jones", 10, "Paris"},
+		{3, "Blake", 30, "Paris"},
+	}, [][]string{[]string{"SNO"}})
+
+	// a relation on another database, which the restrictions are pushed into
+	other, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	other.SetMaxOpenConns(1)
+	if _, err := other.Exec(`CREATE TABLE shipments (SNO INTEGER, PNO INTEGER, Qty INTEGER, PRIMARY KEY (SNO, PNO));
+		INSERT INTO shipments VALUES (1, 1, 300), (1, 2, 200), (2, 1, 300), (3, 2, 200)`); err != nil {
+		t.Fatal(err)
+	}
+	shipments := relsql.New(other, "shipments", shipmentTup{}, [][]string{[]string{"SNO", "PNO"}})
+
+	testModule.Add("suppliers", suppliers)
+	testModule.Add("shipments", shipments)
+	db, err := sql.Open("sqlite3_relsqlite_test", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE parts (PNO INTEGER PRIMARY KEY, PName TEXT);
+		INSERT INTO parts VALUES (1, 'Nut'), (2, 'Bolt');
+		CREATE VIRTUAL TABLE temp.sups USING rel(suppliers)`); err != nil {
+		t.Fatal(err)
+	}
+
+	var moduleTest = []struct {
+		q    string
+		want [][]interface{}
+	}{
+		{
+			"SELECT SName FROM suppliers WHERE City = 'Paris' ORDER BY SName",
+			[][]interface{}{{"Blake"}, {"Jones"}},
+		},
+		{
+			"SELECT SName, Status FROM sups WHERE SNO = 2",
+			[][]interface{}{{"Jones", int64(10)}},
+		},
+		{
+			"SELECT SName FROM suppliers WHERE SNO = 2.5",
+			nil,
+		},
+		{
+			`SELECT s.SName, p.PName, sh.Qty FROM suppliers s
+			JOIN shipments sh ON sh.SNO = s.SNO
+			JOIN parts p ON p.PNO = sh.PNO
+			WHERE s.City = 'Paris' ORDER BY s.SName`,
+			[][]interface{}{{"Blake", "Bolt", int64(200)}, {"Jones", "Nut", int64(300)}},
+		},
+		{
+			"SELECT COUNT(*), SUM(Qty) FROM shipments WHERE PNO = 2",
+			[][]interface{}{{int64(2), int64(400)}},
+		},
+	}
+	for i, tt := range moduleTest {
+		got, err := query(db, tt.q)
+		if err != nil {
+			t.Errorf("%d has error %v", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%d has %v, want %v", i, got, tt.want)
+		}
+	}
+
+	if _, err := db.Exec("CREATE VIRTUAL TABLE temp.missing USING rel(nothing)"); err == nil {
+		t.Errorf("virtual table of a missing relation has no error")
+	}
+}
+
+// query returns the rows of a query
+func query(db *sql.DB, q string) ([][]interface{}, error) {
+	rows, err := db.Query(q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var res [][]interface{}
+	for rows.Next() {
+		row := make([]interface{}, len(cols))
+		dest := make([]interface{}, len(cols))
+		for i := range dest {
+			dest[i] = &row[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		for i, v := range row {
+			if b, ok := v.([]byte); ok {
+				row[i] = string(b)
+			}
+		}
+		res = append(res, row)
+	}
+	return res, rows.Err()
+}
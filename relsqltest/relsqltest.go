@@ -0,0 +1,110 @@
+// Package relsqltest has helpers for testing code that builds relsql
+// relations.
+package relsqltest
+
+import (
+	"flag"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"github.com/jonlawlor/relsql"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// update is the -relsqltest.update flag, which rewrites golden files instead
+// of comparing with them.  It has its own name so that it can't clash with a
+// test binary's flags.
+var update = flag.Bool("relsqltest.update", false, "update relsqltest golden files")
+
+// updating returns true if golden files are rewritten, which they are with
+// -relsqltest.update, or with the test binary's own boolean -update flag if it
+// has one.  The flags are looked up when they are used, after they have been
+// parsed.
+func updating() bool {
+	if *update {
+		return true
+	}
+	if f := flag.Lookup("update"); f != nil {
+		if getter, ok := f.Value.(flag.Getter); ok {
+			if b, ok := getter.Get().(bool); ok {
+				return b
+			}
+		}
+	}
+	return false
+}
+
+// spaces matches runs of whitespace
+var spaces = regexp.MustCompile(`\s+`)
+
+// placeholders matches the numbered placeholders of the dialects, like $1,
+// :1 and @p1, but not the digits in a time such as '12:30'.
+var placeholders = regexp.MustCompile(`(^|[^\w'])(\$|:|@p)(\d+)`)
+
+// Normalize collapses the whitespace in a query, and renumbers its numbered
+// placeholders in the order that they first appear.
+func Normalize(q string) string {
+	q = strings.TrimSpace(spaces.ReplaceAllString(q, " "))
+	numbers := make(map[string]int)
+	return placeholders.ReplaceAllStringFunc(q, func(m string) string {
+		sub := placeholders.FindStringSubmatch(m)
+		key := sub[2] + sub[3]
+		n, ok := numbers[key]
+		if !ok {
+			n = len(numbers) + 1
+			numbers[key] = n
+		}
+		return sub[1] + sub[2] + strconv.Itoa(n)
+	})
+}
+
+// Render returns the normalized query of a relation, followed by a line for
+// each of its arguments.
+func Render(r rel.Relation) (string, error) {
+	q, args, err := relsql.SQL(r)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	b.WriteString(Normalize(q))
+	b.WriteString("\n")
+	for i, arg := range args {
+		fmt.Fprintf(&b, "-- %d: %T %#v\n", i+1, arg, arg)
+	}
+	return b.String(), nil
+}
+
+// Golden compares the rendered query of a relation with the golden file at
+// path, which is usually in the testdata directory.  When the tests are run
+// with -relsqltest.update, or with -update if the test binary defines it, the
+// file is written instead.
+func Golden(t testing.TB, r rel.Relation, path string) {
+	t.Helper()
+	got, err := Render(r)
+	if err != nil {
+		t.Errorf("Render() => %v", err)
+		return
+	}
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("golden file %s => %v", path, err)
+			return
+		}
+		if err := os.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Errorf("golden file %s => %v", path, err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Errorf("golden file %s => %v, run with -update to create it", path, err)
+		return
+	}
+	if got != string(want) {
+		t.Errorf("query of %v =>\n%s\nwant golden file %s:\n%s", r, got, path, want)
+	}
+}
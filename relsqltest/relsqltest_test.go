@@ -0,0 +1,62 @@
+package relsqltest
+
+import (
+	"flag"
+	"github.com/jonlawlor/relsql"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// ownUpdate is a test binary's own -update flag, which can be defined along
+// with the package's flag
+var ownUpdate = flag.Bool("update", false, "update this package's golden files")
+
+// test normalizing queries
+func TestNormalize(t *testing.T) {
+	var normalizeTest = []struct {
+		q, want string
+	}{
+		{"SELECT  a,\n\tb FROM t ", "SELECT a, b FROM t"},
+		{"SELECT a FROM t WHERE a = $3 AND b = $7 AND c = $3", "SELECT a FROM t WHERE a = $1 AND b = $2 AND c = $1"},
+		{"SELECT a FROM t WHERE a = :2 AND b = '12:30'", "SELECT a FROM t WHERE a = :1 AND b = '12:30'"},
+		{"SELECT a FROM t WHERE a = @p5 AND b = ?", "SELECT a FROM t WHERE a = @p1 AND b = ?"},
+	}
+	for i, tt := range normalizeTest {
+		if got := Normalize(tt.q); got != tt.want {
+			t.Errorf("%d has Normalize(%q) => %q, want %q", i, tt.q, got, tt.want)
+		}
+	}
+}
+
+// test the compiled queries of each dialect against golden files
+func TestGolden(t *testing.T) {
+	type partTup struct {
+		PNO    int
+		Colour string
+	}
+	for _, d := range []relsql.Dialect{relsql.ANSI, relsql.SQLite, relsql.BigQuery, relsql.Oracle} {
+		parts := relsql.New(nil, "parts", partTup{}, [][]string{[]string{"PNO"}}, relsql.WithDialect(d))
+		r := parts.Restrict(relsql.Attribute("Colour").EQ("red")).Diff(parts.Restrict(relsql.Attribute("PNO").GT(3)))
+		Golden(t, r, "testdata/"+d.Name()+".golden")
+	}
+}
+
+// test that the test binary's own -update flag rewrites golden files
+func TestGoldenOwnUpdate(t *testing.T) {
+	type partTup struct {
+		PNO int
+	}
+	defer flag.Set("update", "false")
+	if err := flag.Set("update", "true"); err != nil || !*ownUpdate {
+		t.Errorf("flag.Set() => %v", err)
+		return
+	}
+	path := filepath.Join(t.TempDir(), "parts.golden")
+	r := relsql.New(nil, "parts", partTup{}, nil, relsql.WithDialect(relsql.ANSI))
+	Golden(t, r, path)
+	got, err := os.ReadFile(path)
+	if want, _ := Render(r); err != nil || string(got) != want {
+		t.Errorf("golden file => %q, %v, want %q", got, err, want)
+	}
+}
@@ -0,0 +1,87 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+)
+
+// RenameAttributes renames the attributes of a relation according to names,
+// which maps old attribute names to new ones, without declaring a tuple type
+// for the result.  Attributes which aren't in names keep their names.  Two
+// tables with attributes that have the same name, but which aren't meant to
+// be joined on, can be joined once one side's attributes are renamed, and the
+// join is still compiled into a single query.
+func RenameAttributes(r rel.Relation, names map[string]string) rel.Relation {
+	e := reflect.TypeOf(r.Zero())
+	for old := range names {
+		if _, ok := e.FieldByName(old); !ok {
+			return &sqlTable{
+				zero:  r.Zero(),
+				cKeys: r.CKeys(),
+				err:   fmt.Errorf("relsql: renamed attribute %s is not in the heading of %v", old, e),
+			}
+		}
+	}
+	return renameFields(r, func(name string) string {
+		if n, ok := names[name]; ok {
+			return n
+		}
+		return name
+	})
+}
+
+// SelfJoin joins a relation with a copy of itself whose attributes are
+// renamed according to names, like an employees table with the employees'
+// managers.  The copy is joined on the attributes that keep, or are renamed
+// to, the names of attributes of r.  Each side of a join in the database is
+// a derived table with its own alias, so the join is compiled into a single
+// query.
+func SelfJoin(r rel.Relation, names map[string]string, zero interface{}) rel.Relation {
+	return r.Join(RenameAttributes(r, names), zero)
+}
+
+// Prefix renames every attribute of a relation, except for those in keep, by
+// adding the prefix to its name.  It is usually used to give the attributes
+// of one side of a join distinct names, keeping the attributes that it is
+// joined on.
+func Prefix(r rel.Relation, prefix string, keep ...string) rel.Relation {
+	kept := make(map[string]bool)
+	for _, name := range keep {
+		kept[name] = true
+	}
+	return renameFields(r, func(name string) string {
+		if kept[name] {
+			return name
+		}
+		return prefix + name
+	})
+}
+
+// renameFields renames the relation to a tuple type with the fields of its
+// tuples renamed by f.  Tags are kept, so that transforms still apply.
+func renameFields(r rel.Relation, f func(string) string) rel.Relation {
+	e := reflect.TypeOf(r.Zero())
+	fields := make([]reflect.StructField, e.NumField())
+	seen := make(map[string]bool)
+	for i := range fields {
+		fields[i] = e.Field(i)
+		fields[i].Name = f(fields[i].Name)
+		fields[i].Index = nil
+		fields[i].Offset = 0
+		if seen[fields[i].Name] || !isExported(fields[i].Name) {
+			return &sqlTable{
+				zero:  r.Zero(),
+				cKeys: r.CKeys(),
+				err:   fmt.Errorf("relsql: can't rename %v to have attribute %s", e, fields[i].Name),
+			}
+		}
+		seen[fields[i].Name] = true
+	}
+	return r.Rename(reflect.New(reflect.StructOf(fields)).Elem().Interface())
+}
+
+// isExported returns true if the name is an exported identifier
+func isExported(name string) bool {
+	return name != "" && name[0] >= 'A' && name[0] <= 'Z'
+}
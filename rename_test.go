@@ -0,0 +1,178 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// test joining tables whose other attributes have the same names
+func TestPrefix(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:prefix?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type supplierTup struct {
+		SNO  int
+		Name string
+		City string
+	}
+	type partTup struct {
+		PNO  int
+		Name string
+		City string
+	}
+	type shipmentTup struct {
+		SNO int
+		PNO int
+	}
+	type resultTup struct {
+		SNO   int
+		PNO   int
+		SName string
+		SCity string
+		PName string
+		PCity string
+	}
+	for name, z := range map[string]interface{}{"suppliers": supplierTup{}, "parts": partTup{}, "shipments": shipmentTup{}} {
+		if err := CreateTable(db, name, z, nil); err != nil {
+			t.Errorf("CreateTable() => %v", err)
+			return
+		}
+	}
+	inserts := map[string]rel.Relation{
+		"suppliers": rel.New([]supplierTup{{1, "Smith", "London"}, {2, "Jones", "Paris"}}, nil),
+		"parts":     rel.New([]partTup{{1, "Nut", "London"}, {2, "Bolt", "Paris"}}, nil),
+		"shipments": rel.New([]shipmentTup{{1, 2}, {2, 1}}, nil),
+	}
+	for name, r := range inserts {
+		if _, err := Insert(db, name, r); err != nil {
+			t.Errorf("Insert() => %v", err)
+			return
+		}
+	}
+
+	suppliers := Prefix(New(db, "suppliers", supplierTup{}, [][]string{[]string{"SNO"}}), "S", "SNO")
+	parts := RenameAttributes(New(db, "parts", partTup{}, [][]string{[]string{"PNO"}}), map[string]string{"Name": "PName", "City": "PCity"})
+	shipments := New(db, "shipments", shipmentTup{}, [][]string{[]string{"SNO", "PNO"}})
+	if got := rel.HeadingString(suppliers); got != "SNO, SName, SCity" {
+		t.Errorf("Prefix() heading => %s", got)
+	}
+	r := shipments.Join(suppliers, struct {
+		SNO   int
+		PNO   int
+		SName string
+		SCity string
+	}{}).Join(parts, resultTup{})
+	if _, ok := r.(*sqlTable); !ok {
+		t.Errorf("Join() => %T, want a single query", r)
+	}
+	ch := make(chan resultTup)
+	r.TupleChan(ch)
+	got := make(map[int]resultTup)
+	for tup := range ch {
+		got[tup.SNO] = tup
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+	want := "map[1:{1 2 Smith London Bolt Paris} 2:{2 1 Jones Paris Nut London}]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("tuples => %v, want %s", got, want)
+	}
+
+	if err := RenameAttributes(shipments, map[string]string{"QTY": "Qty"}).Err(); err == nil {
+		t.Errorf("RenameAttributes() of a missing attribute => nil error")
+	}
+	if err := RenameAttributes(shipments, map[string]string{"SNO": "PNO"}).Err(); err == nil {
+		t.Errorf("RenameAttributes() to a duplicate attribute => nil error")
+	}
+}
+
+// test joining a table with a renamed copy of itself
+func TestSelfJoin(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:selfjoin?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type empTup struct {
+		EmpID int
+		Name  string
+		MgrID int
+	}
+	type chainTup struct {
+		EmpID   int
+		Name    string
+		MgrID   int
+		MgrName string
+		TopID   int
+	}
+	keys := [][]string{[]string{"EmpID"}}
+	if err := CreateTable(db, "employees", empTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	emps := []empTup{{1, "ann", 1}, {2, "bob", 1}, {3, "cy", 2}}
+	if _, err := Insert(db, "employees", rel.New(emps, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	employees := New(db, "employees", empTup{}, keys)
+	r := SelfJoin(employees, map[string]string{"EmpID": "MgrID", "Name": "MgrName", "MgrID": "TopID"}, chainTup{})
+	if _, ok := r.(*sqlTable); !ok {
+		t.Errorf("Join() => %T, want a single query", r)
+	}
+	q, _, _ := SQL(r)
+	ch := make(chan chainTup)
+	r.TupleChan(ch)
+	got := make(map[int]chainTup)
+	for tup := range ch {
+		got[tup.EmpID] = tup
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() => %v in %s", err, q)
+	}
+	want := "map[1:{1 ann 1 ann 1} 2:{2 bob 1 ann 1} 3:{3 cy 2 bob 1}]"
+	if fmt.Sprint(got) != want {
+		t.Errorf("tuples => %v, want %s from %s", got, want, q)
+	}
+
+	// the managers' managers, restricted after the joins
+	type topTup struct {
+		EmpID   int
+		Name    string
+		MgrID   int
+		MgrName string
+		TopID   int
+		TopName string
+	}
+	tops := RenameAttributes(employees.Project(struct {
+		EmpID int
+		Name  string
+	}{}), map[string]string{"EmpID": "TopID", "Name": "TopName"})
+	r2 := r.Join(tops, topTup{}).Restrict(Attribute("TopName").EQ("ann")).Restrict(Attribute("Name").NE("ann"))
+	if _, ok := r2.(*sqlTable); !ok {
+		t.Errorf("Join() => %T, want a single query", r2)
+	}
+	ch2 := make(chan topTup)
+	r2.TupleChan(ch2)
+	var names []string
+	for tup := range ch2 {
+		names = append(names, tup.Name+"/"+tup.MgrName+"/"+tup.TopName)
+	}
+	sort.Strings(names)
+	if err := r2.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+	if fmt.Sprint(names) != "[bob/ann/ann cy/bob/ann]" {
+		t.Errorf("chains => %v", names)
+	}
+}
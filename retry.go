@@ -0,0 +1,190 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls when a failed query is issued again.  A stream is only
+// retried if no tuples had been sent before it failed, so a retry is never
+// visible to the consumer except as a delay.
+type RetryPolicy struct {
+	// Attempts is the maximum number of times that the query is issued.
+	Attempts int
+
+	// Backoff is the delay before the first retry, which doubles for each
+	// subsequent retry.
+	Backoff time.Duration
+
+	// SerializationFailures also retries transactions that were aborted
+	// because of a serialization failure or a deadlock, which is expected
+	// from time to time under serializable isolation.
+	SerializationFailures bool
+
+	// LockTimeouts also retries statements that timed out waiting for a
+	// lock.  A lock wait usually means contention that a retry makes worse,
+	// and MySQL only rolls back the statement, not the transaction, so this
+	// is off unless it is set.
+	LockTimeouts bool
+
+	// Retryable reports whether an error may be retried.  If it is nil,
+	// IsStaleConn is used, along with IsSerializationFailure if
+	// SerializationFailures is set, and IsLockTimeout if LockTimeouts is
+	// set.
+	Retryable func(error) bool
+}
+
+// WithRetry sets the policy for retrying queries that fail before any tuples
+// have been sent.
+func WithRetry(p RetryPolicy) Option {
+	return func(o *options) {
+		o.retry = p
+	}
+}
+
+// WithPing pings the database before each query is issued, failing if it does
+// not respond within the timeout.  A failed ping is retried according to the
+// retry policy, which lets the connection pool discard connections that were
+// closed by the server while idle.
+func WithPing(timeout time.Duration) Option {
+	return func(o *options) {
+		o.ping = timeout
+	}
+}
+
+// IsStaleConn returns true if err indicates that the connection to the
+// database had been closed, which typically happens when the server or a proxy
+// reaps idle connections.
+func IsStaleConn(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE)
+}
+
+// serializationStates are the SQLSTATE codes for transactions that can
+// succeed if they are retried: serialization_failure, and deadlock_detected.
+var serializationStates = map[string]bool{
+	"40001": true,
+	"40P01": true,
+}
+
+// serializationMessages are the error messages from drivers that don't report
+// a SQLSTATE for serialization failures and deadlocks, such as mysql.
+var serializationMessages = []string{
+	"Error 1213", // ER_LOCK_DEADLOCK
+	"Deadlock found",
+	"could not serialize access",
+}
+
+// IsSerializationFailure returns true if err indicates that a transaction was
+// aborted because it could not be serialized with concurrent transactions, or
+// because it was chosen as the victim of a deadlock.  Drivers that report a
+// SQLSTATE through a SQLState method, like pgx and pq, are checked by state,
+// and others by their error messages.
+func IsSerializationFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	var state interface {
+		SQLState() string
+	}
+	if errors.As(err, &state) {
+		return serializationStates[state.SQLState()]
+	}
+	for _, msg := range serializationMessages {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// lockTimeoutStates are the SQLSTATE codes for statements that timed out
+// waiting for a lock: lock_not_available.
+var lockTimeoutStates = map[string]bool{
+	"55P03": true,
+}
+
+// lockTimeoutMessages are the error messages from drivers that don't report a
+// SQLSTATE for lock timeouts, such as mysql.
+var lockTimeoutMessages = []string{
+	"Error 1205", // ER_LOCK_WAIT_TIMEOUT
+	"Lock wait timeout exceeded",
+}
+
+// IsLockTimeout returns true if err indicates that a statement timed out
+// waiting for a lock held by another transaction.  Drivers are checked by
+// SQLSTATE or by error message, like IsSerializationFailure.
+func IsLockTimeout(err error) bool {
+	if err == nil {
+		return false
+	}
+	var state interface {
+		SQLState() string
+	}
+	if errors.As(err, &state) {
+		return lockTimeoutStates[state.SQLState()]
+	}
+	for _, msg := range lockTimeoutMessages {
+		if strings.Contains(err.Error(), msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryable returns true if the stream should be issued again after failing
+// with err on the given attempt, counting from 1.
+func (p RetryPolicy) retryable(attempt int, err error) bool {
+	if attempt >= p.Attempts {
+		return false
+	}
+	if p.Retryable == nil {
+		return IsStaleConn(err) || (p.SerializationFailures && IsSerializationFailure(err)) ||
+			(p.LockTimeouts && IsLockTimeout(err))
+	}
+	return p.Retryable(err)
+}
+
+// delay returns the time to wait before retrying after the given attempt.
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	return p.Backoff << uint(attempt-1)
+}
+
+// WithIsolation sets the isolation level of the read transaction.  Under
+// serializable isolation, set SerializationFailures in the retry policy so
+// that transactions which the server aborts are issued again.
+func WithIsolation(level sql.IsolationLevel) Option {
+	return func(o *options) {
+		o.isolation = level
+	}
+}
+
+// ping checks that the relation's database is reachable, if pings are enabled
+func (r1 *sqlTable) ping() error {
+	if r1.opts.ping <= 0 {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), r1.opts.ping)
+	defer cancel()
+	if r1.q != nil {
+		if p, ok := r1.q.(interface {
+			PingContext(ctx context.Context) error
+		}); ok {
+			return p.PingContext(ctx)
+		}
+		return nil
+	}
+	if r1.conn != nil {
+		return r1.conn.PingContext(ctx)
+	}
+	return r1.db.PingContext(ctx)
+}
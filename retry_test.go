@@ -0,0 +1,162 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"testing"
+	"time"
+)
+
+// test which errors are retried
+func TestRetryPolicy(t *testing.T) {
+	errOther := errors.New("syntax error")
+	var retryTest = []struct {
+		policy  RetryPolicy
+		attempt int
+		err     error
+		retry   bool
+	}{
+		{RetryPolicy{}, 1, driver.ErrBadConn, false},
+		{RetryPolicy{Attempts: 3}, 1, driver.ErrBadConn, true},
+		{RetryPolicy{Attempts: 3}, 2, fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{RetryPolicy{Attempts: 3}, 3, driver.ErrBadConn, false},
+		{RetryPolicy{Attempts: 3}, 1, errOther, false},
+		{RetryPolicy{Attempts: 3, Retryable: func(error) bool { return true }}, 1, errOther, true},
+	}
+	for i, tt := range retryTest {
+		if retry := tt.policy.retryable(tt.attempt, tt.err); retry != tt.retry {
+			t.Errorf("%d has retryable(%d, %v) => %v, want %v", i, tt.attempt, tt.err, retry, tt.retry)
+		}
+	}
+	p := RetryPolicy{Backoff: time.Millisecond}
+	if d := p.delay(3); d != 4*time.Millisecond {
+		t.Errorf("delay(3) => %v, want %v", d, 4*time.Millisecond)
+	}
+}
+
+// sqlStateError is an error with a SQLSTATE, like the errors from pgx
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+// test detection of serialization failures
+func TestSerializationFailure(t *testing.T) {
+	var errTest = []struct {
+		err     error
+		failure bool
+	}{
+		{sqlStateError("40001"), true},
+		{fmt.Errorf("commit: %w", sqlStateError("40P01")), true},
+		{sqlStateError("23505"), false},
+		{errors.New("Error 1213: Deadlock found when trying to get lock"), true},
+		{errors.New("no such table"), false},
+		{nil, false},
+	}
+	for i, tt := range errTest {
+		if failure := IsSerializationFailure(tt.err); failure != tt.failure {
+			t.Errorf("%d has IsSerializationFailure(%v) => %v, want %v", i, tt.err, failure, tt.failure)
+		}
+	}
+	p := RetryPolicy{Attempts: 2}
+	if p.retryable(1, sqlStateError("40001")) {
+		t.Errorf("retryable without SerializationFailures => true")
+	}
+	p.SerializationFailures = true
+	if !p.retryable(1, sqlStateError("40001")) {
+		t.Errorf("retryable with SerializationFailures => false")
+	}
+}
+
+// test detection of lock timeouts, which are only retried if asked for
+func TestLockTimeout(t *testing.T) {
+	lockWait := errors.New("Error 1205: Lock wait timeout exceeded; try restarting transaction")
+	var errTest = []struct {
+		err           error
+		lockTimeout   bool
+		serialization bool
+	}{
+		{lockWait, true, false},
+		{sqlStateError("55P03"), true, false},
+		{sqlStateError("40001"), false, true},
+		{errors.New("Error 1213: Deadlock found when trying to get lock"), false, true},
+		{nil, false, false},
+	}
+	for i, tt := range errTest {
+		if lockTimeout := IsLockTimeout(tt.err); lockTimeout != tt.lockTimeout {
+			t.Errorf("%d has IsLockTimeout(%v) => %v, want %v", i, tt.err, lockTimeout, tt.lockTimeout)
+		}
+		if failure := IsSerializationFailure(tt.err); failure != tt.serialization {
+			t.Errorf("%d has IsSerializationFailure(%v) => %v, want %v", i, tt.err, failure, tt.serialization)
+		}
+	}
+	p := RetryPolicy{Attempts: 2, SerializationFailures: true}
+	if p.retryable(1, lockWait) {
+		t.Errorf("retryable without LockTimeouts => true")
+	}
+	p.LockTimeouts = true
+	if !p.retryable(1, lockWait) {
+		t.Errorf("retryable with LockTimeouts => false")
+	}
+}
+
+// test that failing queries are issued again according to the retry policy
+func TestRetry(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type missingTup struct {
+		A int
+	}
+
+	attempts := 0
+	policy := RetryPolicy{
+		Attempts: 3,
+		Retryable: func(error) bool {
+			attempts++
+			return true
+		},
+	}
+	missing := New(db, "missing", missingTup{}, nil, WithRetry(policy), WithPing(time.Second))
+	rel.Card(missing)
+	if missing.Err() == nil {
+		t.Errorf("Err() => nil, want error")
+	}
+	if attempts != 2 {
+		t.Errorf("retried %d times, want %d", attempts, 2)
+	}
+}
+
+// test that the backoff between retries ends when the read is stopped
+func TestRetryBackoffStopped(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type missingTup struct {
+		A int
+	}
+	policy := RetryPolicy{Attempts: 2, Backoff: time.Hour, Retryable: func(error) bool { return true }}
+	missing := New(db, "missing", missingTup{}, nil, WithRetry(policy), WithReadTimeout(50*time.Millisecond))
+	ch := make(chan missingTup)
+	missing.TupleChan(ch)
+	select {
+	case <-ch:
+		if err := missing.Err(); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("Err() => %v, want %v", err, context.DeadlineExceeded)
+		}
+	case <-time.After(5 * time.Second):
+		t.Errorf("read was not stopped during the backoff")
+	}
+}
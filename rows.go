@@ -0,0 +1,212 @@
+package relsql
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+// RowReader is the part of *sql.Rows that code which reads query results
+// usually needs.  Both *sql.Rows and the *Rows of a relation implement it, so
+// code written against it can read from either a query or a relation.
+type RowReader interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+	Close() error
+}
+
+// Rows reads the tuples of a relation with the semantics of *sql.Rows.  The
+// columns are the attributes of the relation, in heading order.
+type Rows struct {
+	r      rel.Relation
+	names  []string
+	ch     reflect.Value
+	cancel chan<- struct{}
+	tup    reflect.Value
+	closed bool
+	err    error
+}
+
+// NewRows starts reading the tuples of a relation as rows, which have to be
+// closed if they aren't read to the end, like *sql.Rows.
+func NewRows(r rel.Relation) *Rows {
+	e := reflect.TypeOf(r.Zero())
+	rows := &Rows{r: r, names: make([]string, e.NumField())}
+	for i := range rows.names {
+		rows.names[i] = e.Field(i).Name
+	}
+	if rows.err = r.Err(); rows.err != nil {
+		rows.closed = true
+		return rows
+	}
+	rows.ch = reflect.MakeChan(reflect.ChanOf(reflect.BothDir, e), 0)
+	rows.cancel = r.TupleChan(rows.ch.Interface())
+	return rows
+}
+
+// Columns returns the names of the attributes
+func (rows *Rows) Columns() ([]string, error) {
+	if rows.closed && rows.err == nil {
+		return nil, errors.New("relsql: Rows are closed")
+	}
+	return rows.names, nil
+}
+
+// Next reads the next tuple, which Scan then copies.  It returns false when
+// there are no more tuples, or the relation failed, which Err reports.
+func (rows *Rows) Next() bool {
+	if rows.closed {
+		return false
+	}
+	tup, ok := rows.ch.Recv()
+	if !ok {
+		rows.closed = true
+		rows.err = rows.r.Err()
+		return false
+	}
+	rows.tup = tup
+	return true
+}
+
+// Scan copies the attributes of the current tuple into dest, which has a
+// pointer for each attribute.  Values are converted like database/sql does:
+// a destination of the attribute's type gets the value as is, a
+// sql.Scanner, like sql.NullString, gets its driver value, and strings,
+// byte slices, numbers and booleans are converted between each other.
+func (rows *Rows) Scan(dest ...interface{}) error {
+	if !rows.tup.IsValid() || rows.closed {
+		return errors.New("relsql: Scan called without calling Next")
+	}
+	if len(dest) != len(rows.names) {
+		return fmt.Errorf("relsql: expected %d destination arguments in Scan, not %d", len(rows.names), len(dest))
+	}
+	for i, d := range dest {
+		if err := assign(d, rows.tup.Field(i).Interface()); err != nil {
+			return fmt.Errorf("relsql: Scan error on attribute %s: %v", rows.names[i], err)
+		}
+	}
+	return nil
+}
+
+// Err returns the error of the relation, if reading it failed
+func (rows *Rows) Err() error {
+	return rows.err
+}
+
+// Close stops reading the relation
+func (rows *Rows) Close() error {
+	if !rows.closed {
+		rows.closed = true
+		close(rows.cancel)
+	}
+	return nil
+}
+
+// driverValue returns the value that a driver would return for an attribute
+// value, using the codec of its type if it has one.
+func driverValue(v interface{}) (driver.Value, error) {
+	ev, err := encodeArg(ANSI, v)
+	if err != nil {
+		return nil, err
+	}
+	return driver.DefaultParameterConverter.ConvertValue(ev)
+}
+
+// assign stores the attribute value v in the destination pointer dest
+func assign(dest, v interface{}) error {
+	if p, ok := dest.(*interface{}); ok {
+		*p = v
+		return nil
+	}
+	dv := reflect.ValueOf(dest)
+	if dv.Kind() != reflect.Ptr || dv.IsNil() {
+		return errors.New("destination not a pointer")
+	}
+	if v != nil && reflect.TypeOf(v).AssignableTo(dv.Type().Elem()) {
+		dv.Elem().Set(reflect.ValueOf(v))
+		return nil
+	}
+	src, err := driverValue(v)
+	if err != nil {
+		return err
+	}
+	if s, ok := dest.(sql.Scanner); ok {
+		return s.Scan(src)
+	}
+	dst := dv.Elem()
+	if src == nil {
+		if k := dst.Kind(); k == reflect.Ptr || k == reflect.Slice || k == reflect.Map || k == reflect.Interface {
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return fmt.Errorf("converting NULL to %v is unsupported", dst.Type())
+	}
+	if dst.Kind() == reflect.Ptr {
+		p := reflect.New(dst.Type().Elem())
+		if err := assign(p.Interface(), v); err != nil {
+			return err
+		}
+		dst.Set(p)
+		return nil
+	}
+	return assignValue(dst, src)
+}
+
+// assignValue stores a driver value in dst, converting between strings,
+// byte slices, numbers and booleans.
+func assignValue(dst reflect.Value, src driver.Value) error {
+	var str string
+	switch s := src.(type) {
+	case string:
+		str = s
+	case []byte:
+		str = string(s)
+	case time.Time:
+		str = s.Format(time.RFC3339Nano)
+	default:
+		str = fmt.Sprint(s)
+	}
+	fail := func(err error) error {
+		return fmt.Errorf("converting %T (%v) to %v: %v", src, src, dst.Type(), err)
+	}
+	switch {
+	case dst.Kind() == reflect.String:
+		dst.SetString(str)
+	case dst.Type() == reflect.TypeOf([]byte(nil)):
+		dst.SetBytes([]byte(str))
+	case dst.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(str)
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetBool(b)
+	case dst.CanInt():
+		n, err := strconv.ParseInt(str, 10, dst.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetInt(n)
+	case dst.CanUint():
+		n, err := strconv.ParseUint(str, 10, dst.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetUint(n)
+	case dst.CanFloat():
+		x, err := strconv.ParseFloat(str, dst.Type().Bits())
+		if err != nil {
+			return fail(err)
+		}
+		dst.SetFloat(x)
+	default:
+		return fmt.Errorf("unsupported Scan, storing %T into %v", src, dst.Type())
+	}
+	return nil
+}
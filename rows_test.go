@@ -0,0 +1,149 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readRows reads every row with the reader, scanning into fresh copies of the
+// destinations that newDest returns.
+func readRows(rr RowReader, newDest func() []interface{}) ([]string, error) {
+	defer rr.Close()
+	var res []string
+	for rr.Next() {
+		dest := newDest()
+		if err := rr.Scan(dest...); err != nil {
+			return res, err
+		}
+		strs := make([]string, len(dest))
+		for i, d := range dest {
+			switch d := d.(type) {
+			case *sql.NullString:
+				strs[i] = d.String
+				if !d.Valid {
+					strs[i] = "NULL"
+				}
+			case *interface{}:
+				strs[i] = fmt.Sprint(*d)
+			default:
+				strs[i] = fmt.Sprint(reflectElem(d))
+			}
+		}
+		res = append(res, strings.Join(strs, " "))
+	}
+	return res, rr.Err()
+}
+
+// reflectElem dereferences a pointer
+func reflectElem(p interface{}) interface{} {
+	switch p := p.(type) {
+	case *string:
+		return *p
+	case *int:
+		return *p
+	case *float64:
+		return *p
+	case *time.Time:
+		return p.Format("2006-01-02")
+	case **string:
+		if *p == nil {
+			return "nil"
+		}
+		return **p
+	}
+	return p
+}
+
+// test reading relations as rows, and that they read like sql.Rows
+func TestRows(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:rows?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type itemTup struct {
+		ID    int
+		Name  string
+		Price float64
+		Note  *string
+	}
+	keys := [][]string{[]string{"ID"}}
+	note := "new"
+	items := []itemTup{{1, "ann", 2.5, &note}, {2, "bob", 3, nil}}
+	mem := rel.New(items, keys)
+	if err := CreateTable(db, "items", itemTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "items", mem); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "items", itemTup{}, keys, WithDeterministicOrder())
+
+	var rowsTest = []struct {
+		name    string
+		newDest func() []interface{}
+		want    string
+	}{
+		{"same types", func() []interface{} {
+			return []interface{}{new(int), new(string), new(float64), new(*string)}
+		}, "[1 ann 2.5 new 2 bob 3 nil]"},
+		{"converted", func() []interface{} {
+			return []interface{}{new(string), new(sql.NullString), new(string), new(sql.NullString)}
+		}, "[1 ann 2.5 new 2 bob 3 NULL]"},
+		{"interface", func() []interface{} {
+			return []interface{}{new(interface{}), new(interface{}), new(interface{}), new(sql.NullString)}
+		}, "[1 ann 2.5 new 2 bob 3 NULL]"},
+	}
+	for i, tt := range rowsTest {
+		for _, rr := range []RowReader{NewRows(table), NewRows(mem)} {
+			res, err := readRows(rr, tt.newDest)
+			if err != nil || fmt.Sprint(res) != tt.want {
+				t.Errorf("%d %s with %T => %v, %v, want %s", i, tt.name, rr, res, err, tt.want)
+			}
+		}
+	}
+
+	// the same code reads a query
+	q, err := db.Query("SELECT ID, Name, Price, Note FROM items ORDER BY ID")
+	if err != nil {
+		t.Errorf("Query() => %v", err)
+		return
+	}
+	res, err := readRows(q, rowsTest[1].newDest)
+	if err != nil || fmt.Sprint(res) != rowsTest[1].want {
+		t.Errorf("sql.Rows => %v, %v", res, err)
+	}
+
+	// conversions that fail
+	for i, dest := range [][]interface{}{
+		{new(int), new(int), new(float64), new(*string)},
+		{new(int), new(string), new(int), new(*string)},
+		{new(int), new(string), new(float64)},
+	} {
+		rows := NewRows(table)
+		if !rows.Next() {
+			t.Errorf("%d has Next() => false, %v", i, rows.Err())
+		}
+		if err := rows.Scan(dest...); err == nil {
+			t.Errorf("%d has Scan() => nil, want an error", i)
+		}
+		rows.Close()
+	}
+
+	cols, err := NewRows(table).Columns()
+	if fmt.Sprint(cols) != "[ID Name Price Note]" || err != nil {
+		t.Errorf("Columns() => %v, %v", cols, err)
+	}
+	rows := NewRows(New(db, "missing", itemTup{}, keys))
+	if rows.Next() || rows.Err() == nil {
+		t.Errorf("rows of a missing table => %v", rows.Err())
+	}
+}
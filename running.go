@@ -0,0 +1,287 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Ordered declares that the tuples of r are sent in the given order, such as
+// the rows of a time series that are read in time order.  Running uses it to
+// compute running aggregates client side as the tuples arrive, instead of
+// reading and sorting all of them first.  Operations on the result are
+// operations on r, and don't keep the declaration.
+func Ordered(r rel.Relation, order ...OrderBy) rel.Relation {
+	return &orderedRelation{r, order}
+}
+
+// orderedRelation is a relation whose tuples are known to be in order
+type orderedRelation struct {
+	rel.Relation
+	order []OrderBy
+}
+
+// orderedBy returns true if the tuples of the relation are sorted by the
+// ordering, which means that it is a prefix of the declared order.
+func (r *orderedRelation) orderedBy(order []OrderBy) bool {
+	if len(order) > len(r.order) {
+		return false
+	}
+	for i, o := range order {
+		if o != r.order[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Running creates a relation that extends each tuple of r with running
+// aggregates, such as a cumulative sum, over the tuples of its group that
+// come before it in the given order, including itself.  Tuples are grouped
+// by the values of the group attributes, and with no group attributes the
+// whole relation is one group.  Ties are broken by r's first candidate key.
+// zero is the type of the resulting tuples, which has the attributes of r
+// and an attribute for each of the aggregates, named by its As.  Only Count,
+// Sum, Avg, Min and Max can be running aggregates.
+//
+// If r is from this package, the aggregates are compiled into window
+// functions with a frame that ends at the current row.  Otherwise they are
+// computed client side, as the tuples arrive if r was declared Ordered by
+// the same order, and after sorting all of the tuples if it wasn't.
+func Running(r rel.Relation, group []string, order []OrderBy, zero interface{}, aggs ...Agg) rel.Relation {
+	e1, e2 := reflect.TypeOf(r.Zero()), reflect.TypeOf(zero)
+	fail := func(err error) rel.Relation {
+		return &sqlTable{zero: zero, cKeys: r.CKeys(), err: err}
+	}
+	if err := checkZero(e2); err != nil {
+		return fail(err)
+	}
+	if err := checkRunning(e1, e2, group, order, aggs); err != nil {
+		return fail(err)
+	}
+	src := r
+	if o, ok := r.(*orderedRelation); ok {
+		src = o.Relation
+	}
+	tieOrder := breakTies(order, r.CKeys())
+	atts := append(append(append([]string{}, group...), orderAttributes(tieOrder)...), aggAttributes(aggs)...)
+	if r1, ok := src.(*sqlTable); ok && r1.err == nil && r1.composable() && Supports(r1.dialect(), FeatureWindowFunctions) && !r1.touchesEncrypted(atts...) {
+		return &sqlTable{
+			db:             r1.db,
+			conn:           r1.conn,
+			q:              r1.q,
+			src:            &runningSource{r1, group, tieOrder, aggs},
+			cols:           colNames(zero),
+			zero:           zero,
+			cKeys:          r1.cKeys,
+			sourceDistinct: true,
+			opts:           r1.opts,
+		}
+	}
+	o, streaming := r.(*orderedRelation)
+	streaming = streaming && o.orderedBy(order)
+	return &clientOp{
+		op:     "Running",
+		str:    runningString(r, group, aggs),
+		inputs: []rel.Relation{r},
+		zero:   zero,
+		cKeys:  r.CKeys(),
+		run: func(send func(reflect.Value) error) error {
+			return running(r, group, tieOrder, streaming, e2, aggs, send)
+		},
+	}
+}
+
+// checkRunning returns an error if the running aggregates of tuples of type
+// e1 don't match the fields of e2.
+func checkRunning(e1, e2 reflect.Type, group []string, order []OrderBy, aggs []Agg) error {
+	if len(order) == 0 {
+		return fmt.Errorf("relsql: running aggregates of %v have no order", e1)
+	}
+	for _, att := range group {
+		if _, ok := e1.FieldByName(att); !ok {
+			return fmt.Errorf("relsql: group attribute %s is not in the heading of %v", att, e1)
+		}
+	}
+	for _, o := range order {
+		if _, ok := e1.FieldByName(o.Attribute); !ok {
+			return fmt.Errorf("relsql: order attribute %s is not in the heading of %v", o.Attribute, e1)
+		}
+	}
+	for i := 0; i < e1.NumField(); i++ {
+		f1 := e1.Field(i)
+		if f2, ok := e2.FieldByName(f1.Name); !ok || f2.Type != f1.Type {
+			return fmt.Errorf("relsql: attribute %s is not in the heading of %v", f1.Name, e2)
+		}
+	}
+	names := make(map[string]bool)
+	for _, a := range aggs {
+		if a.fn == "PERCENTILE" {
+			return fmt.Errorf("relsql: %v can't be a running aggregate", a)
+		}
+		if a.fn != "COUNT" {
+			if _, ok := e1.FieldByName(a.att); !ok {
+				return fmt.Errorf("relsql: aggregated attribute %s is not in the heading of %v", a.att, e1)
+			}
+		}
+		_, clash := e1.FieldByName(a.As)
+		if _, ok := e2.FieldByName(a.As); !ok || clash || names[a.As] {
+			return fmt.Errorf("relsql: aggregate %s is not a new attribute of %v", a.As, e2)
+		}
+		names[a.As] = true
+	}
+	if e1.NumField()+len(aggs) != e2.NumField() {
+		return fmt.Errorf("relsql: %v has attributes which are neither in %v nor aggregated", e2, e1)
+	}
+	return nil
+}
+
+// runningString returns the text representation of running aggregates
+func runningString(r rel.Relation, group []string, aggs []Agg) string {
+	strs := make([]string, len(aggs))
+	for i, a := range aggs {
+		strs[i] = a.String()
+	}
+	return "running{" + strings.Join(group, ", ") + "; " + strings.Join(strs, ", ") + "}(" + exprString(r) + ")"
+}
+
+// runningSource extends the rows of a relation with running aggregates
+type runningSource struct {
+	r     *sqlTable
+	group []string
+	order []OrderBy
+	aggs  []Agg
+}
+
+// build returns a query with a window function for each aggregate, whose
+// frame runs from the first row of the partition to the current row.
+func (s *runningSource) build(b *builder, needed map[column]bool) string {
+	n := neededAttributes(needed, "")
+	if n != nil {
+		for _, att := range s.group {
+			n[rel.Attribute(att)] = true
+		}
+		for _, o := range s.order {
+			n[rel.Attribute(o.Attribute)] = true
+		}
+		for _, a := range s.aggs {
+			if a.att != "" {
+				n[rel.Attribute(a.att)] = true
+			}
+		}
+	}
+	over := ""
+	if len(s.group) > 0 {
+		over = "PARTITION BY " + strings.Join(s.group, ", ") + " "
+	}
+	over += "ORDER BY " + orderString(s.order) + " ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW"
+	d := b.dialectOrANSI()
+	sel := []string{"w.*"}
+	for _, a := range s.aggs {
+		sel = append(sel, a.sql(d)+" OVER ("+over+") AS "+a.As)
+	}
+	return "(SELECT " + strings.Join(sel, ", ") + " FROM (" + s.r.build(b, n, true) + ")" + b.alias("w") + ")" + b.alias("c")
+}
+
+// String returns a text representation of the running aggregates
+func (s *runningSource) String() string {
+	return runningString(s.r, s.group, s.aggs)
+}
+
+// accumulator holds the running aggregates of a group
+type accumulator struct {
+	n    int
+	sums []float64
+	best []reflect.Value
+}
+
+// add accumulates a tuple, and sets the aggregate fields of res
+func (acc *accumulator) add(tup, res reflect.Value, aggs []Agg) error {
+	acc.n++
+	for i, a := range aggs {
+		f := res.FieldByName(a.As)
+		var v reflect.Value
+		if a.att != "" {
+			v = tup.FieldByName(a.att)
+		}
+		switch a.fn {
+		case "COUNT":
+			if err := setNumber(f, float64(acc.n)); err != nil {
+				return err
+			}
+		case "SUM", "AVG":
+			if !isNumber(v) {
+				return fmt.Errorf("relsql: can't compute %s of %v", a.fn, v.Type())
+			}
+			acc.sums[i] += numberValue(v)
+			x := acc.sums[i]
+			if a.fn == "AVG" {
+				x /= float64(acc.n)
+			}
+			if err := setNumber(f, x); err != nil {
+				return err
+			}
+		case "MIN", "MAX":
+			if acc.n == 1 {
+				acc.best[i] = v
+			} else if c, _ := compareValues(v.Interface(), acc.best[i].Interface()); (a.fn == "MIN" && c < 0) || (a.fn == "MAX" && c > 0) {
+				acc.best[i] = v
+			}
+			if !v.Type().ConvertibleTo(f.Type()) {
+				return fmt.Errorf("relsql: can't convert %s of %v to %v", a.fn, v.Type(), f.Type())
+			}
+			f.Set(acc.best[i].Convert(f.Type()))
+		}
+	}
+	return nil
+}
+
+// running reads the tuples of r, and sends each of them extended with the
+// running aggregates of its group as tuples of type e.  If the tuples are
+// streaming in order they are sent as they are read, and otherwise they are
+// sorted first.
+func running(r rel.Relation, group []string, order []OrderBy, streaming bool, e reflect.Type, aggs []Agg, send func(reflect.Value) error) error {
+	accs := make(map[string]*accumulator)
+	extend := func(tup reflect.Value) error {
+		vals := make([]interface{}, len(group))
+		for i, att := range group {
+			vals[i] = tup.FieldByName(att).Interface()
+		}
+		k := fmt.Sprintf("%#v", vals)
+		acc, ok := accs[k]
+		if !ok {
+			acc = &accumulator{sums: make([]float64, len(aggs)), best: make([]reflect.Value, len(aggs))}
+			accs[k] = acc
+		}
+		res := reflect.New(e).Elem()
+		for i := 0; i < tup.NumField(); i++ {
+			res.FieldByName(tup.Type().Field(i).Name).Set(tup.Field(i))
+		}
+		if err := acc.add(tup, res, aggs); err != nil {
+			return err
+		}
+		return send(res)
+	}
+	if streaming {
+		return forEach(r, extend)
+	}
+	var tups []reflect.Value
+	err := forEach(r, func(tup reflect.Value) error {
+		tups = append(tups, tup)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Slice(tups, func(i, j int) bool {
+		return compareTuples(tups[i], tups[j], order) < 0
+	})
+	for _, tup := range tups {
+		if err := extend(tup); err != nil {
+			return err
+		}
+	}
+	return nil
+}
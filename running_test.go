@@ -0,0 +1,106 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// test running aggregates in the database, and client side with and without
+// ordered input
+func TestRunning(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:running?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type pointTup struct {
+		Series string
+		Day    int
+		Value  int
+	}
+	type runTup struct {
+		Series string
+		Day    int
+		Value  int
+		Total  int
+		Mean   float64
+		Peak   int
+	}
+	keys := [][]string{[]string{"Series", "Day"}}
+	points := []pointTup{
+		{"a", 3, 30}, {"a", 1, 10}, {"a", 2, 5},
+		{"b", 1, 4}, {"b", 2, 8},
+	}
+	if err := CreateTable(db, "points", pointTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "points", rel.New(points, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "points", pointTup{}, keys, WithDialect(SQLite))
+	mem := rel.New(points, keys)
+	series := []string{"Series"}
+	byDay := []OrderBy{{"Day", false}}
+	aggs := []Agg{Sum("Value", "Total"), Avg("Value", "Mean"), Max("Value", "Peak")}
+	want := "[{a 1 10 10 10 10} {a 2 5 15 7.5 10} {a 3 30 45 15 30} {b 1 4 4 4 4} {b 2 8 12 6 8}]"
+
+	var runningTest = []struct {
+		name string
+		r    rel.Relation
+	}{
+		{"table", Running(table, series, byDay, runTup{}, aggs...)},
+		{"memory", Running(mem, series, byDay, runTup{}, aggs...)},
+		{"ordered", Running(Ordered(table.Project(pointTup{}), OrderBy{"Day", false}), series, byDay, runTup{}, aggs...)},
+	}
+	for i, tt := range runningTest {
+		if _, ok := tt.r.(*sqlTable); ok != (tt.name != "memory") {
+			t.Errorf("%d has Running() => %T", i, tt.r)
+		}
+		ch := make(chan runTup)
+		tt.r.TupleChan(ch)
+		var res []string
+		for tup := range ch {
+			res = append(res, fmt.Sprint(tup))
+		}
+		sort.Strings(res)
+		if err := tt.r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+		if fmt.Sprint(res) != want {
+			t.Errorf("%d %s has tuples %v, want %s", i, tt.name, res, want)
+		}
+	}
+
+	// tuples that arrive in order are extended as they arrive
+	ordered := []pointTup{{"a", 1, 10}, {"b", 1, 4}, {"a", 2, 5}}
+	r := Running(Ordered(rel.New(ordered, keys), OrderBy{"Day", false}, OrderBy{"Series", false}), series, byDay, runTup{}, Sum("Value", "Total"), Count("Mean"), Min("Value", "Peak"))
+	ch := make(chan runTup)
+	r.TupleChan(ch)
+	var res []runTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if fmt.Sprint(res) != "[{a 1 10 10 1 10} {b 1 4 4 1 4} {a 2 5 15 2 5}]" {
+		t.Errorf("ordered Running() has tuples %v", res)
+	}
+
+	for i, r := range []rel.Relation{
+		Running(table, series, nil, runTup{}, aggs...),
+		Running(table, []string{"City"}, byDay, runTup{}, aggs...),
+		Running(table, series, []OrderBy{{"Hour", false}}, runTup{}, aggs...),
+		Running(table, series, byDay, runTup{}, Sum("Value", "Total")),
+		Running(table, series, byDay, runTup{}, Sum("Value", "Total"), Median("Value", "Mean"), Max("Value", "Peak")),
+		Running(table, series, byDay, runTup{}, Sum("Value", "Value"), Avg("Value", "Mean"), Max("Value", "Peak")),
+	} {
+		if r.Err() == nil {
+			t.Errorf("%d has Err() => nil, want an error", i)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package relsql
+
+import (
+	"context"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"regexp"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ScanProgress describes how far a relation's query has been read.  It is
+// passed to the function set by WithScanProgress.
+type ScanProgress struct {
+	// Rows is the number of tuples sent so far
+	Rows int64
+
+	// Elapsed is the time since the query was executed
+	Elapsed time.Duration
+
+	// Estimate is the estimated number of tuples of the query, or zero if the
+	// dialect can't estimate it
+	Estimate int64
+
+	// Done is true for the last report of a scan, which is made when it ends
+	// for any reason, and Err is the error it ended with, if any
+	Done bool
+	Err  error
+}
+
+// Fraction returns the estimated fraction of the scan that is complete, which
+// is at most 1, or -1 if there is no estimate.
+func (p ScanProgress) Fraction() float64 {
+	switch {
+	case p.Done:
+		return 1
+	case p.Estimate <= 0:
+		return -1
+	case p.Rows >= p.Estimate:
+		return 1
+	}
+	return float64(p.Rows) / float64(p.Estimate)
+}
+
+// WithScanProgress sets a function that is called every interval while the
+// relation's query is read, and once more when the read ends.  It is called
+// even when no tuples have arrived since the last report, so a stalled query
+// can be told apart from a slow consumer by the Rows not changing.
+func WithScanProgress(every time.Duration, f func(ScanProgress)) Option {
+	return func(o *options) {
+		o.scanEvery = every
+		o.scanProgress = f
+	}
+}
+
+// cardEstimator is implemented by dialects that can estimate the number of
+// rows of a query cheaply, for example from the planner's statistics with
+// EXPLAIN, without executing it.  table is the table that the relation reads
+// from, or empty if it reads from a derived table, for dialects that estimate
+// from the catalog instead.
+type cardEstimator interface {
+	EstimateCard(ctx context.Context, q QueryerContext, table, query string, args []interface{}) (int64, error)
+}
+
+// EstimateCard returns the estimated number of tuples of a relation, from the
+// dialect's statistics, without executing its query.  It fails if the dialect
+// can't estimate it.
+func EstimateCard(ctx context.Context, r rel.Relation) (int64, error) {
+	r1, ok := r.(*sqlTable)
+	if !ok {
+		return 0, fmt.Errorf("relsql: %v is not from relsql, so its cardinality can't be estimated", r)
+	}
+	if r1.err != nil {
+		return 0, r1.err
+	}
+	ce, ok := r1.dialect().(cardEstimator)
+	if !ok {
+		return 0, fmt.Errorf("relsql: the %s dialect can't estimate cardinality", r1.dialect().Name())
+	}
+	q, args, err := r1.queryString()
+	if err != nil {
+		return 0, err
+	}
+	if q, args, err = r1.applyPolicies(q, args); err != nil {
+		return 0, err
+	}
+	return r1.estimate(ctx, ce, q, bindArgs(r1.dialect(), args))
+}
+
+// estimate returns the dialect's estimate of the number of rows of the
+// relation's query, and audits the queries made for it
+func (r1 *sqlTable) estimate(ctx context.Context, ce cardEstimator, query string, args []interface{}) (int64, error) {
+	table, _ := r1.src.(tableSource)
+	aq := &auditedQueryer{q: r1.queryer(), o: &r1.opts}
+	n, err := ce.EstimateCard(ctx, aq, string(table), query, args)
+	if err == nil {
+		err = aq.err
+	}
+	return n, err
+}
+
+// explainRows matches the planner's estimate of the rows of a plan node, like
+// rows=2550 in the plans of Postgres and MySQL
+var explainRows = regexp.MustCompile(`rows=([0-9.e+]+)`)
+
+// explainEstimate estimates the rows of a query from the first plan node of
+// its EXPLAIN, which is the node that produces the query's rows.  explain is
+// the statement that is prefixed to the query.
+func explainEstimate(ctx context.Context, q QueryerContext, explain, query string, args []interface{}) (int64, error) {
+	var plan string
+	if err := q.QueryRowContext(ctx, explain+" "+query, args...).Scan(&plan); err != nil {
+		return 0, err
+	}
+	return planRows(plan)
+}
+
+// planRows returns the estimated rows of the first node of a plan
+func planRows(plan string) (int64, error) {
+	m := explainRows.FindStringSubmatch(plan)
+	if m == nil {
+		return 0, fmt.Errorf("relsql: plan %q has no row estimate", plan)
+	}
+	n, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("relsql: plan %q has row estimate %s: %v", plan, m[1], err)
+	}
+	return int64(n), nil
+}
+
+// scanProgress reports the progress of one scan from a separate goroutine,
+// so that reports continue while the scan is blocked
+type scanProgress struct {
+	start    time.Time
+	rows     int64
+	estimate int64
+	f        func(ScanProgress)
+	stopped  chan struct{}
+	wg       sync.WaitGroup
+}
+
+// startScanProgress starts reporting the progress of the relation's query,
+// or returns nil if the relation has no progress function.  The estimate is
+// made in the background, so that it doesn't delay the query.
+func (r1 *sqlTable) startScanProgress(q string, args []interface{}) *scanProgress {
+	f, every := r1.opts.scanProgress, r1.opts.scanEvery
+	if f == nil || every <= 0 {
+		return nil
+	}
+	sp := &scanProgress{start: time.Now(), f: f, stopped: make(chan struct{})}
+	ce, estimate := r1.dialect().(cardEstimator)
+	sp.wg.Add(1)
+	go func() {
+		defer sp.wg.Done()
+		if estimate {
+			if n, err := r1.estimate(context.Background(), ce, q, args); err == nil {
+				atomic.StoreInt64(&sp.estimate, n)
+			}
+		}
+		t := time.NewTicker(every)
+		defer t.Stop()
+		for {
+			select {
+			case <-sp.stopped:
+				return
+			case <-t.C:
+				f(sp.report(false, nil))
+			}
+		}
+	}()
+	return sp
+}
+
+// report returns the progress so far
+func (sp *scanProgress) report(done bool, err error) ScanProgress {
+	return ScanProgress{
+		Rows:     atomic.LoadInt64(&sp.rows),
+		Elapsed:  time.Since(sp.start),
+		Estimate: atomic.LoadInt64(&sp.estimate),
+		Done:     done,
+		Err:      err,
+	}
+}
+
+// add records that a tuple has been sent
+func (sp *scanProgress) add() {
+	if sp != nil {
+		atomic.AddInt64(&sp.rows, 1)
+	}
+}
+
+// stop ends the periodic reports, and makes the last one
+func (sp *scanProgress) stop(err error) {
+	if sp == nil {
+		return
+	}
+	close(sp.stopped)
+	sp.wg.Wait()
+	sp.f(sp.report(true, err))
+}
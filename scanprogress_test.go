@@ -0,0 +1,131 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"sync"
+	"testing"
+	"time"
+)
+
+// estDialect is a sqlite dialect that estimates every query at 4 rows
+type estDialect struct {
+	sqliteDialect
+}
+
+func (estDialect) EstimateCard(ctx context.Context, q QueryerContext, table, query string, args []interface{}) (int64, error) {
+	return 4, nil
+}
+
+// test progress reports during a scan
+func TestScanProgress(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:scanprogress?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type scanTup struct {
+		Name  string
+		Count int
+	}
+	if err := CreateTable(db, "scans", scanTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "scans", rel.New([]scanTup{{"a", 1}, {"b", 2}, {"c", 3}}, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var mu sync.Mutex
+	var reports []ScanProgress
+	record := func(p ScanProgress) {
+		mu.Lock()
+		reports = append(reports, p)
+		mu.Unlock()
+	}
+	r := New(db, "scans", scanTup{}, [][]string{[]string{"Name"}}, WithDialect(estDialect{}), WithScanProgress(time.Millisecond, record))
+	ch := make(chan scanTup)
+	r.TupleChan(ch)
+	for range ch {
+		// a slow consumer, so that there are reports during the scan
+		time.Sleep(10 * time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(reports) < 2 {
+		t.Errorf("WithScanProgress() => %v, want reports during the scan", reports)
+		return
+	}
+	last := reports[len(reports)-1]
+	if !last.Done || last.Rows != 3 || last.Err != nil || last.Fraction() != 1 {
+		t.Errorf("last progress report => %+v", last)
+	}
+	during := reports[len(reports)-2]
+	if during.Done || during.Estimate != 4 || during.Fraction() != float64(during.Rows)/4 {
+		t.Errorf("progress report during the scan => %+v, fraction %v", during, during.Fraction())
+	}
+
+	// sqlite estimates from the largest rowid until the table is analyzed,
+	// and neither estimate is a count of the rows
+	if _, err := db.Exec("DELETE FROM scans WHERE Name = 'a'"); err != nil {
+		t.Errorf("DELETE => %v", err)
+		return
+	}
+	scans := New(db, "scans", scanTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite))
+	var estimateTest = []struct {
+		analyze  bool
+		r        rel.Relation
+		expected int64
+	}{
+		{false, r, 4},
+		{false, scans.Restrict(Attribute("Count").GT(2)), 3},
+		{true, scans.Restrict(Attribute("Count").GT(2)), 2},
+	}
+	for i, tt := range estimateTest {
+		if tt.analyze {
+			if _, err := db.Exec("ANALYZE"); err != nil {
+				t.Errorf("ANALYZE => %v", err)
+				return
+			}
+		}
+		if n, err := EstimateCard(context.Background(), tt.r); n != tt.expected || err != nil {
+			t.Errorf("%d has EstimateCard() => %v, %v, want %v", i, n, err, tt.expected)
+		}
+	}
+	var failTest = []rel.Relation{
+		rel.New([]scanTup{}, nil),
+		New(db, "scans", scanTup{}, [][]string{[]string{"Name"}}, WithDialect(ANSI)),
+		scans.Union(scans),
+	}
+	for i, r := range failTest {
+		if n, err := EstimateCard(context.Background(), r); err == nil {
+			t.Errorf("%d has EstimateCard() => %v, want an error", i, n)
+		}
+	}
+}
+
+// test the row estimates of EXPLAIN plans
+func TestPlanRows(t *testing.T) {
+	var planTest = []struct {
+		plan     string
+		expected int64
+		ok       bool
+	}{
+		{"Seq Scan on scans  (cost=0.00..35.50 rows=2550 width=36)", 2550, true},
+		{"Hash Join  (cost=1.09..2.19 rows=3 width=8)\n  ->  Seq Scan on a  (cost=0.00..1.05 rows=5 width=4)", 3, true},
+		{"-> Filter: (scans.Count > 1)  (cost=0.55 rows=1.67)\n    -> Table scan on scans  (cost=0.55 rows=5)", 1, true},
+		{"-> Table scan on scans  (cost=1e+06 rows=1.2e+06)", 1200000, true},
+		{"SCAN scans", 0, false},
+	}
+	for i, tt := range planTest {
+		if n, err := planRows(tt.plan); n != tt.expected || (err == nil) != tt.ok {
+			t.Errorf("%d has planRows(%q) => %v, %v, want %v", i, tt.plan, n, err, tt.expected)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ColumnMismatch describes a column of a query's result which can't be
+// scanned into the attribute of the tuples that it is read into.
+type ColumnMismatch struct {
+	// Index is the position of the column in the result, starting from 0
+	Index int
+
+	// Column is the name of the column reported by the driver, and
+	// Attribute is the attribute that it is read into.  Either may be empty
+	// if the result has a different number of columns than the tuples have
+	// attributes.
+	Column    string
+	Attribute string
+
+	Reason string
+}
+
+// String returns a description of the mismatch
+func (m ColumnMismatch) String() string {
+	return fmt.Sprintf("column %d (%s) -> attribute %s: %s", m.Index, m.Column, m.Attribute, m.Reason)
+}
+
+// SchemaError is returned, before any tuples are sent, when the columns of a
+// query's result don't match the tuples of the relation.  This usually means
+// that the table has changed since the relation's tuple type was written.
+type SchemaError struct {
+	Type       reflect.Type
+	Mismatches []ColumnMismatch
+}
+
+// Error implements the error interface, with a line for each mismatch
+func (e *SchemaError) Error() string {
+	strs := make([]string, len(e.Mismatches))
+	for i, m := range e.Mismatches {
+		strs[i] = "\n\t" + m.String()
+	}
+	return fmt.Sprintf("relsql: query result doesn't match %v:%s", e.Type, strings.Join(strs, ""))
+}
+
+// nullabilityReporter is implemented by dialects whose drivers don't report
+// the nullability of result columns reliably.  If ReportsNullability returns
+// false, nullable columns aren't logged.
+type nullabilityReporter interface {
+	ReportsNullability() bool
+}
+
+// checkSchema compares the column types of a result with the fields of the
+// relation's tuples, which are scanned from them in order.  Columns whose
+// names or types can't match the fields are returned in a *SchemaError, and
+// nullable columns which are read into fields that can't hold NULL are logged,
+// since they only fail if a NULL is actually read.  Drivers that don't report
+// column types aren't checked.
+func (r1 *sqlTable) checkSchema(rows *sql.Rows) error {
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return nil
+	}
+	e := reflect.TypeOf(r1.zero)
+	if e.NumField() == 0 {
+		// the result is a single constant column
+		return nil
+	}
+	var ms []ColumnMismatch
+	n := len(cts)
+	if e.NumField() > n {
+		n = e.NumField()
+	}
+	nulls := true
+	if nr, ok := r1.dialect().(nullabilityReporter); ok {
+		nulls = nr.ReportsNullability()
+	}
+	for i := 0; i < n; i++ {
+		m := ColumnMismatch{Index: i}
+		if i < len(cts) {
+			m.Column = cts[i].Name()
+		}
+		if i < e.NumField() {
+			m.Attribute = e.Field(i).Name
+		}
+		switch {
+		case i >= len(cts):
+			m.Reason = "missing column"
+		case i >= e.NumField():
+			m.Reason = "extra column"
+		case r1.composable() && m.Column != "" && !strings.EqualFold(m.Column, r1.cols[i].name) && !strings.EqualFold(m.Column, m.Attribute):
+			m.Reason = "expected column " + r1.cols[i].name
+		default:
+			f := e.Field(i).Type
+			if reason := scanMismatch(cts[i].ScanType(), f); reason != "" {
+				m.Reason = reason
+			} else if nullable, ok := cts[i].Nullable(); ok && nullable && nulls && !holdsNull(f) && r1.opts.logging() {
+				r1.opts.warnf("relsql: nullable %v", m)
+			}
+		}
+		if m.Reason != "" {
+			ms = append(ms, m)
+		}
+	}
+	if len(ms) > 0 {
+		return &SchemaError{e, ms}
+	}
+	return nil
+}
+
+// scannerType is the type of sql.Scanner
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+// holdsNull returns true if a NULL can be scanned into a field of type t
+func holdsNull(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return true
+	}
+	return lookupCodec(t) != nil || reflect.PtrTo(t).Implements(scannerType)
+}
+
+// scanMismatch returns the reason that a column whose values have type src
+// can't be scanned into a field of type dst, or an empty string if it can.
+// Fields with codecs or Scan methods decode values themselves, and columns
+// whose type isn't reported could have values of any type, so they always
+// match.
+func scanMismatch(src, dst reflect.Type) string {
+	if src == nil || dst == lazyType || lookupCodec(dst) != nil || reflect.PtrTo(dst).Implements(scannerType) {
+		return ""
+	}
+	if t, ok := nullTypes[src]; ok {
+		src = t
+	}
+	if dst.Kind() == reflect.Ptr {
+		dst = dst.Elem()
+	}
+	if src.Kind() == reflect.Ptr || src.Kind() == reflect.Interface || dst.Kind() == reflect.Interface {
+		return ""
+	}
+	timeSrc := src == timeType
+	switch k := dst.Kind(); {
+	case dst == timeType:
+		if !timeSrc {
+			return fmt.Sprintf("can't scan %v into a time", src)
+		}
+	case k == reflect.String || (k == reflect.Slice && dst.Elem().Kind() == reflect.Uint8):
+	case k == reflect.Bool || isInt(dst) || k == reflect.Float32 || k == reflect.Float64:
+		if timeSrc {
+			return fmt.Sprintf("can't scan a time into %v", dst)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package relsql
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test that results which don't match the tuples fail before any are sent
+func TestCheckSchema(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:checkschema?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	if _, err := db.Exec("CREATE TABLE legacy (ID INTEGER NOT NULL, Name TEXT NOT NULL, At TEXT NOT NULL, Seen DATETIME NOT NULL)"); err != nil {
+		t.Errorf("CREATE TABLE => %v", err)
+		return
+	}
+	if _, err := db.Exec("INSERT INTO legacy VALUES (1, 'ann', '2024-01-01', '2024-01-01 00:00:00')"); err != nil {
+		t.Errorf("INSERT => %v", err)
+		return
+	}
+
+	type goodTup struct {
+		ID   int
+		Name string
+		At   string
+		Seen time.Time
+	}
+	type badTup struct {
+		ID   int
+		Name string
+		At   time.Time
+		Seen int
+	}
+	var logs []string
+	logf := func(format string, args ...interface{}) {
+		logs = append(logs, format)
+	}
+	r := New(db, "legacy", goodTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite), WithLogger(logf))
+	ch := make(chan goodTup)
+	r.TupleChan(ch)
+	n := 0
+	for range ch {
+		n++
+	}
+	if err := r.Err(); err != nil || n != 1 {
+		t.Errorf("matching schema read %d tuples, with Err() => %v", n, err)
+	}
+	if len(logs) != 0 {
+		t.Errorf("sqlite logged %d nullable columns, want none", len(logs))
+	}
+
+	bad := New(db, "legacy", badTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite))
+	bch := make(chan badTup)
+	bad.TupleChan(bch)
+	n = 0
+	for range bch {
+		n++
+	}
+	var serr *SchemaError
+	if !errors.As(bad.Err(), &serr) || n != 0 {
+		t.Errorf("mismatched schema read %d tuples, with Err() => %v, want a *SchemaError", n, bad.Err())
+		return
+	}
+	if len(serr.Mismatches) != 2 || serr.Mismatches[0].Attribute != "At" || serr.Mismatches[1].Attribute != "Seen" {
+		t.Errorf("Mismatches => %v, want At and Seen", serr.Mismatches)
+	}
+	if msg := serr.Error(); !strings.Contains(msg, "column 2 (At) -> attribute At") {
+		t.Errorf("Error() => %q", msg)
+	}
+
+	// dialects that trust the driver log columns that may be NULL
+	logs = nil
+	r = New(db, "legacy", goodTup{}, [][]string{[]string{"ID"}}, WithDialect(ANSI), WithLogger(logf))
+	ch = make(chan goodTup)
+	r.TupleChan(ch)
+	for range ch {
+	}
+	if len(logs) != 4 {
+		t.Errorf("logged %d nullable columns, want 4", len(logs))
+	}
+}
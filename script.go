@@ -0,0 +1,55 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"strings"
+)
+
+// scriptSource is a query that is preceded by setup statements
+type scriptSource struct {
+	setup []string
+	query string
+}
+
+// build returns the query as a derived table
+func (s *scriptSource) build(b *builder, needed map[column]bool) string {
+	return "(" + s.query + ")" + b.alias("s")
+}
+
+// NewFromScript creates a relation from the rows of a query which reads from
+// the results of setup statements, like a CREATE TEMPORARY TABLE, INSERT or
+// ANALYZE, as in a multi step extract.  The columns of the query are matched
+// to the attributes of z by name, and the relation can be restricted,
+// projected and joined by the database like a table.  Each time the relation
+// is enumerated, the setup statements and the query are executed in one
+// transaction, on one connection, which is rolled back once the query has
+// been read, so that the setup's effects aren't seen by anything else and
+// the script can run again.  In dialects whose drivers can't begin
+// transactions, the setup is executed on the query's connection and isn't
+// rolled back, so it has to be safe to repeat, like DROP TABLE IF EXISTS
+// followed by CREATE TEMPORARY TABLE.
+func NewFromScript(db *sql.DB, setup []string, query string, z interface{}, ckeystr [][]string, opts ...Option) rel.Relation {
+	r := New(db, "script", z, ckeystr, opts...).(*sqlTable)
+	r.src = &scriptSource{setup, query}
+	return r
+}
+
+// scriptSetup returns the setup statements of the scripts that the relation
+// reads from, in order.  A script that is read more than once, like in a self
+// join, is set up once.
+func (r1 *sqlTable) scriptSetup() []string {
+	var setup []string
+	seen := make(map[string]bool)
+	Inspect(r1, func(n Node) bool {
+		if s, ok := n.(*Script); ok {
+			k := strings.Join(s.Setup, ";\n")
+			if !seen[k] {
+				seen[k] = true
+				setup = append(setup, s.Setup...)
+			}
+		}
+		return true
+	})
+	return setup
+}
@@ -0,0 +1,80 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test relations from scripts of setup statements and a query
+func TestScript(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:script?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type saleTup struct {
+		ID     int
+		Region string
+		Amount int
+	}
+	if err := CreateTable(db, "sales", saleTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	sales := rel.New([]saleTup{{1, "east", 10}, {2, "east", 5}, {3, "west", 7}}, [][]string{[]string{"ID"}})
+	if _, err := Insert(db, "sales", sales); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	type totalTup struct {
+		Region string
+		Total  int
+	}
+	setup := []string{
+		"CREATE TEMP TABLE totals (Region TEXT, Total INTEGER)",
+		"INSERT INTO totals SELECT Region, SUM(Amount) FROM sales GROUP BY Region",
+	}
+	r := NewFromScript(db, setup, "SELECT Region, Total FROM totals", totalTup{}, [][]string{[]string{"Region"}}, WithDialect(SQLite))
+	var scriptTest = []struct {
+		r        rel.Relation
+		query    string
+		expected []totalTup
+	}{
+		{r, "SELECT Region, Total FROM (SELECT Region, Total FROM totals) AS s", []totalTup{{"east", 15}, {"west", 7}}},
+		{r.Restrict(Attribute("Total").GT(10)), "SELECT Region, Total FROM (SELECT Region, Total FROM totals) AS s WHERE Total > ?", []totalTup{{"east", 15}}},
+	}
+	for i, tt := range scriptTest {
+		if q, _, err := SQL(tt.r); q != tt.query || err != nil {
+			t.Errorf("%d has SQL() => %v, %v, want %v", i, q, err, tt.query)
+		}
+		// each enumeration runs the script again
+		for j := 0; j < 2; j++ {
+			ch := make(chan totalTup)
+			tt.r.TupleChan(ch)
+			var res []totalTup
+			for tup := range ch {
+				res = append(res, tup)
+			}
+			if !reflect.DeepEqual(res, tt.expected) || tt.r.Err() != nil {
+				t.Errorf("%d has tuples => %v, %v, want %v", i, res, tt.r.Err(), tt.expected)
+			}
+		}
+	}
+	if _, err := db.Exec("SELECT * FROM totals"); err == nil {
+		t.Errorf("the temporary table of the script outlived its enumeration")
+	}
+
+	bad := NewFromScript(db, []string{"CREATE TEMP TABLE"}, "SELECT 1 AS Region, 2 AS Total", totalTup{}, nil, WithDialect(SQLite))
+	ch := make(chan totalTup)
+	bad.TupleChan(ch)
+	for range ch {
+	}
+	if bad.Err() == nil {
+		t.Errorf("script with an invalid setup statement succeeded")
+	}
+}
@@ -0,0 +1,47 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+)
+
+// WithMandatoryPredicate adds a predicate to every query of a relation from
+// New, and of every relation derived from it, like a row level security
+// policy.  For example, WithMandatoryPredicate(Attribute("TenantID").EQ(id))
+// keeps a relation to the rows of a single tenant.  The predicate's
+// attributes refer to columns of the table, which don't have to be in the
+// heading of the relation.  It is an error if the predicate can't be compiled
+// into sql for the relation's dialect, because a mandatory predicate is never
+// evaluated client side.
+func WithMandatoryPredicate(p Pred) Option {
+	return func(o *options) {
+		o.mandatory = append(o.mandatory, p)
+	}
+}
+
+// restrictMandatory adds the mandatory predicates to the WHERE clause of a
+// relation from New.  Attributes that are not in the heading are resolved to
+// the table's columns of the same name.
+func (r *sqlTable) restrictMandatory() error {
+	e := reflect.TypeOf(r.zero)
+	for _, p := range r.opts.mandatory {
+		if !p.pushable(r.dialect()) {
+			return fmt.Errorf("relsql: mandatory predicate %v can't be compiled for %s", p, r.dialect().Name())
+		}
+		cols := make(map[rel.Attribute]column)
+		for _, att := range p.attributes() {
+			if r.opts.encrypted(string(att)) {
+				return fmt.Errorf("relsql: mandatory predicate %v refers to encrypted attribute %s", p, att)
+			}
+			if f, ok := e.FieldByName(string(att)); ok {
+				cols[att] = r.cols[f.Index[0]]
+			} else {
+				cols[att] = column{name: string(att)}
+			}
+		}
+		r.mandatory = append(r.mandatory, condition{p, cols})
+		r.where = addConditions(r.where, condition{p, cols})
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package relsql
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+// test that mandatory predicates apply to derived relations
+func TestMandatoryPredicate(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+	create table invoices (TenantID integer not null, No integer not null, Amount integer not null, primary key (TenantID, No));
+	insert into invoices values (1, 1, 100), (1, 2, 200), (2, 1, 300);
+	`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	type invoiceTup struct {
+		No     int
+		Amount int
+	}
+	tenant := WithMandatoryPredicate(Attribute("TenantID").EQ(1))
+	invoices := New(db, "invoices", invoiceTup{}, [][]string{[]string{"No"}}, tenant)
+
+	type amountTup struct {
+		Amount int
+	}
+	var queryTest = []struct {
+		r    *sqlTable
+		want string
+	}{
+		{invoices.(*sqlTable), "SELECT No, Amount FROM invoices WHERE TenantID = ?"},
+		{invoices.Project(amountTup{}).(*sqlTable), "SELECT DISTINCT Amount FROM invoices WHERE TenantID = ?"},
+		{invoices.Restrict(Attribute("No").EQ(1)).(*sqlTable), "SELECT No, Amount FROM invoices WHERE TenantID = ? AND No = ?"},
+	}
+	for i, tt := range queryTest {
+		if q, _, _ := tt.r.queryString(); q != tt.want {
+			t.Errorf("%d has query => %v, want %v", i, q, tt.want)
+		}
+	}
+
+	ch := make(chan amountTup)
+	all := invoices.Project(amountTup{})
+	all.TupleChan(ch)
+	var res []amountTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if want := []amountTup{{100}, {200}}; !reflect.DeepEqual(res, want) {
+		t.Errorf("tenant amounts => %v, want %v", res, want)
+	}
+
+	enc := WithEncryption("TenantID", xorCipher(1))
+	if r := New(db, "invoices", invoiceTup{}, nil, enc, tenant); r.Err() == nil {
+		t.Errorf("mandatory predicate on encrypted attribute has Err() => nil")
+	}
+}
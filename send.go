@@ -0,0 +1,51 @@
+package relsql
+
+import (
+	"reflect"
+	"sync"
+)
+
+// sender sends the tuple that ptr points to on a channel, and returns false
+// if cancel was closed first.
+type sender func(ptr interface{}, cancel <-chan struct{}) bool
+
+// senderFactories holds a function for each registered tuple type, which
+// returns the sender for a channel of that type, or nil if the channel has
+// another type.
+var senderFactories sync.Map
+
+// RegisterTuple registers the tuple type T, so that relations from this
+// package send tuples of that type with a plain channel send instead of
+// reflection, when the channel passed to TupleChan is a chan T.  It is safe to
+// register a type more than once.
+func RegisterTuple[T any]() {
+	senderFactories.Store(reflect.TypeOf(*new(T)), func(ch interface{}) sender {
+		var c chan<- T
+		switch ch := ch.(type) {
+		case chan T:
+			c = ch
+		case chan<- T:
+			c = ch
+		default:
+			return nil
+		}
+		return func(ptr interface{}, cancel <-chan struct{}) bool {
+			select {
+			case c <- *ptr.(*T):
+				return true
+			case <-cancel:
+				return false
+			}
+		}
+	})
+}
+
+// fastSender returns the sender for tuples of type e on the channel res, or
+// nil if e has not been registered.
+func fastSender(res reflect.Value, e reflect.Type) sender {
+	f, ok := senderFactories.Load(e)
+	if !ok {
+		return nil
+	}
+	return f.(func(interface{}) sender)(res.Interface())
+}
@@ -0,0 +1,66 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test sending registered tuple types without reflection
+func TestRegisterTuple(t *testing.T) {
+	type fastTup struct {
+		ID   int
+		Name string
+	}
+	type slowTup struct {
+		ID int
+	}
+	RegisterTuple[fastTup]()
+
+	e := reflect.TypeOf(fastTup{})
+	if fastSender(reflect.ValueOf(make(chan fastTup)), e) == nil {
+		t.Errorf("fastSender() of chan fastTup => nil")
+	}
+	if fastSender(reflect.ValueOf((chan<- fastTup)(make(chan fastTup))), e) == nil {
+		t.Errorf("fastSender() of chan<- fastTup => nil")
+	}
+	if fastSender(reflect.ValueOf(make(chan slowTup)), reflect.TypeOf(slowTup{})) != nil {
+		t.Errorf("fastSender() of unregistered type => not nil")
+	}
+
+	db, err := sql.Open("sqlite3", "file:send?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	if err := CreateTable(db, "fast", fastTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	tups := []fastTup{{1, "a"}, {2, "b"}, {3, "c"}}
+	if _, err := Insert(db, "fast", rel.New(tups, [][]string{[]string{"ID"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	r := New(db, "fast", fastTup{}, [][]string{[]string{"ID"}}, WithDialect(SQLite))
+	ch := make(chan fastTup)
+	r.TupleChan(ch)
+	var res []fastTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if !reflect.DeepEqual(res, tups) {
+		t.Errorf("tuples => %v, want %v", res, tups)
+	}
+
+	// cancelling stops the stream without closing the channel
+	ch = make(chan fastTup)
+	cancel := r.TupleChan(ch)
+	<-ch
+	close(cancel)
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() after cancel => %v", err)
+	}
+}
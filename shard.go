@@ -0,0 +1,266 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Sharder returns the index of the shard that holds the rows with a value of
+// the shard key.
+type Sharder func(key interface{}) int
+
+// shardedTable is a relation over the same table in several databases, each
+// of which holds the rows of some of the values of a shard key.
+type shardedTable struct {
+	// shards holds the relation on each database, or nil for the shards
+	// that can't hold any of the relation's tuples
+	shards []rel.Relation
+
+	// key is the shard key attribute, or empty if it has been projected away
+	key rel.Attribute
+
+	shard Sharder
+	zero  interface{}
+	cKeys rel.CandKeys
+	err   error
+}
+
+// NewSharded creates a relation over a table which is sharded across the
+// databases by the key attribute, with shard mapping each key value to the
+// index of the database holding its rows.  Enumerating the relation reads
+// from every shard concurrently.  Restrictions are applied to each shard,
+// and a restriction which requires the key to equal a value, or one of a set
+// of values, only reads from the shards holding those values.  Projections
+// and groupings that keep the key are also applied to each shard, and other
+// operations are evaluated client side.
+func NewSharded(dbs []*sql.DB, tableName string, key string, shard Sharder, z interface{}, ckeystr [][]string, opts ...Option) rel.Relation {
+	r := &shardedTable{key: rel.Attribute(key), shard: shard, zero: z}
+	for _, db := range dbs {
+		r.shards = append(r.shards, New(db, tableName, z, ckeystr, opts...))
+	}
+	if len(r.shards) > 0 {
+		r.cKeys = r.shards[0].CKeys()
+	} else {
+		r.cKeys = rel.DefaultKeys(z)
+	}
+	return r
+}
+
+// derive returns a relation with op applied to each of the shards
+func (r1 *shardedTable) derive(op func(rel.Relation) rel.Relation) *shardedTable {
+	r2 := *r1
+	r2.shards = make([]rel.Relation, len(r1.shards))
+	for i, s := range r1.shards {
+		if s != nil {
+			r2.shards[i] = op(s)
+		}
+	}
+	for _, s := range r2.shards {
+		if s != nil {
+			r2.zero = s.Zero()
+			r2.cKeys = s.CKeys()
+			break
+		}
+	}
+	return &r2
+}
+
+// TupleChan sends the tuples of every shard on the channel, reading from the
+// shards concurrently.
+func (r1 *shardedTable) TupleChan(t interface{}) chan<- struct{} {
+	cancel := make(chan struct{})
+	chv := reflect.ValueOf(t)
+	if err := rel.EnsureChan(chv.Type(), r1.zero); err != nil {
+		r1.err = err
+		return cancel
+	}
+	var wg sync.WaitGroup
+	var cancels []chan<- struct{}
+	for _, s := range r1.shards {
+		if s == nil {
+			continue
+		}
+		ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, reflect.TypeOf(r1.zero)), 0)
+		cancels = append(cancels, s.TupleChan(ch.Interface()))
+		wg.Add(1)
+		go func(ch reflect.Value) {
+			defer wg.Done()
+			canSel := reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(cancel)}
+			for {
+				tup, ok := ch.Recv()
+				if !ok {
+					return
+				}
+				resSel := reflect.SelectCase{Dir: reflect.SelectSend, Chan: chv, Send: tup}
+				if chosen, _, _ := reflect.Select([]reflect.SelectCase{canSel, resSel}); chosen == 0 {
+					return
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		select {
+		case <-cancel:
+			for _, c := range cancels {
+				close(c)
+			}
+		default:
+			chv.Close()
+		}
+	}()
+	return cancel
+}
+
+// Zero returns the zero value of the relation's tuples
+func (r1 *shardedTable) Zero() interface{} {
+	return r1.zero
+}
+
+// CKeys returns the candidate keys of the relation
+func (r1 *shardedTable) CKeys() rel.CandKeys {
+	return r1.cKeys
+}
+
+// GoString returns a text representation of the relation
+func (r1 *shardedTable) GoString() string {
+	strs := make([]string, len(r1.shards))
+	for i, s := range r1.shards {
+		if s == nil {
+			strs[i] = "nil"
+		} else {
+			strs[i] = s.GoString()
+		}
+	}
+	return fmt.Sprintf("relsql.shardedTable{[%s], %v, %v, %v}", strings.Join(strs, ", "), r1.key, r1.zero, r1.cKeys)
+}
+
+// String returns a text representation of the relation, which is the union
+// of the shards that are read.
+func (r1 *shardedTable) String() string {
+	var strs []string
+	for _, s := range r1.shards {
+		if s != nil {
+			strs = append(strs, s.String())
+		}
+	}
+	if len(strs) == 0 {
+		return "Relation(" + rel.HeadingString(r1) + ")"
+	}
+	return strings.Join(strs, " ∪ ")
+}
+
+// Project applies the projection to each shard if it keeps the shard key,
+// because the tuples from different shards are then still distinct.
+// Otherwise it is evaluated client side.
+func (r1 *shardedTable) Project(z2 interface{}) rel.Relation {
+	if r1.key == "" || !containsAttribute(rel.FieldNames(reflect.TypeOf(z2)), r1.key) {
+		return rel.NewProject(r1, z2)
+	}
+	return r1.derive(func(s rel.Relation) rel.Relation {
+		return s.Project(z2)
+	})
+}
+
+// Restrict applies the restriction to each shard, and skips the shards which
+// can't hold any of the values of the shard key that it allows.
+func (r1 *shardedTable) Restrict(p rel.Predicate) rel.Relation {
+	r2 := r1.derive(func(s rel.Relation) rel.Relation {
+		return s.Restrict(p)
+	})
+	p1, ok := p.(Pred)
+	if !ok || r1.key == "" {
+		return r2
+	}
+	for _, c := range p1.conjuncts() {
+		var values []interface{}
+		switch _, isAtt := c.val.(rel.Attribute); {
+		case c.att != r1.key || isAtt:
+			continue
+		case c.op == "=":
+			values = []interface{}{c.val}
+		case c.op == "IN":
+			values = c.val.([]interface{})
+		default:
+			continue
+		}
+		keep := make(map[int]bool)
+		for _, v := range values {
+			keep[r1.shard(v)] = true
+		}
+		for i := range r2.shards {
+			if !keep[i] {
+				r2.shards[i] = nil
+			}
+		}
+	}
+	return r2
+}
+
+// Rename applies the rename to each shard
+func (r1 *shardedTable) Rename(z2 interface{}) rel.Relation {
+	r2 := r1.derive(func(s rel.Relation) rel.Relation {
+		return s.Rename(z2)
+	})
+	// the shard key is renamed with the field in the same position
+	if f, ok := reflect.TypeOf(r1.zero).FieldByName(string(r1.key)); ok && r2.zero != r1.zero {
+		if e2 := reflect.TypeOf(r2.zero); e2.Kind() == reflect.Struct && e2.NumField() > f.Index[0] {
+			r2.key = rel.Attribute(e2.Field(f.Index[0]).Name)
+		}
+	}
+	return r2
+}
+
+// Union is evaluated client side
+func (r1 *shardedTable) Union(r2 rel.Relation) rel.Relation {
+	return rel.NewUnion(r1, r2)
+}
+
+// Diff is evaluated client side
+func (r1 *shardedTable) Diff(r2 rel.Relation) rel.Relation {
+	return rel.NewDiff(r1, r2)
+}
+
+// Join is evaluated client side
+func (r1 *shardedTable) Join(r2 rel.Relation, zero interface{}) rel.Relation {
+	return rel.NewJoin(r1, r2, zero)
+}
+
+// GroupBy groups each shard separately if the shard key is one of the
+// grouping attributes, because every group is then held by a single shard.
+// Otherwise a group can span shards, and the group function can't be split
+// into partial results that are merged, so the tuples of every shard are
+// grouped together client side.
+func (r1 *shardedTable) GroupBy(t2, gfcn interface{}) rel.Relation {
+	if r1.key == "" || !containsAttribute(rel.FieldNames(reflect.TypeOf(t2)), r1.key) {
+		return rel.NewGroupBy(r1, t2, gfcn)
+	}
+	return r1.derive(func(s rel.Relation) rel.Relation {
+		return s.GroupBy(t2, gfcn)
+	})
+}
+
+// Map is evaluated client side
+func (r1 *shardedTable) Map(mfcn interface{}, ckeystr [][]string) rel.Relation {
+	return rel.NewMap(r1, mfcn, ckeystr)
+}
+
+// Err returns the first error of the relation or any of its shards
+func (r1 *shardedTable) Err() error {
+	if r1.err != nil {
+		return r1.err
+	}
+	for _, s := range r1.shards {
+		if s == nil {
+			continue
+		}
+		if err := s.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
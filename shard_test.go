@@ -0,0 +1,106 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// test routing and fan out of sharded relations
+func TestSharded(t *testing.T) {
+	var dbs []*sql.DB
+	for i := 0; i < 3; i++ {
+		db, err := sql.Open("sqlite3", fmt.Sprintf("file:shard%d?mode=memory&cache=shared", i))
+		if err != nil {
+			t.Errorf(err.Error())
+			return
+		}
+		defer db.Close()
+		dbs = append(dbs, db)
+	}
+
+	type accountTup struct {
+		ID      int
+		Balance int
+	}
+	ckeys := [][]string{[]string{"ID"}}
+	byID := func(key interface{}) int {
+		return key.(int) % 3
+	}
+	for i, db := range dbs {
+		if err := CreateTable(db, "accounts", accountTup{}, ckeys); err != nil {
+			t.Errorf("CreateTable() => %v", err)
+			return
+		}
+		var tups []accountTup
+		for id := i; id < 9; id += 3 {
+			tups = append(tups, accountTup{id, 10 * id})
+		}
+		if _, err := Insert(db, "accounts", rel.New(tups, ckeys)); err != nil {
+			t.Errorf("Insert() => %v", err)
+			return
+		}
+	}
+	accounts := NewSharded(dbs, "accounts", "ID", byID, accountTup{}, ckeys)
+
+	ids := func(r rel.Relation) []int {
+		ch := make(chan accountTup)
+		r.TupleChan(ch)
+		var res []int
+		for tup := range ch {
+			res = append(res, tup.ID)
+		}
+		sort.Ints(res)
+		return res
+	}
+	if res := ids(accounts); fmt.Sprint(res) != "[0 1 2 3 4 5 6 7 8]" {
+		t.Errorf("all accounts => %v", res)
+	}
+
+	var routeTest = []struct {
+		p      Pred
+		shards int
+		ids    string
+	}{
+		{Attribute("ID").EQ(4), 1, "[4]"},
+		{Attribute("ID").In(1, 7, 2), 2, "[1 2 7]"},
+		{Attribute("Balance").GE(60), 3, "[6 7 8]"},
+		{And(Attribute("ID").EQ(4), Attribute("ID").EQ(5)), 0, "[]"},
+	}
+	for i, tt := range routeTest {
+		r := accounts.Restrict(tt.p).(*shardedTable)
+		n := 0
+		for _, s := range r.shards {
+			if s != nil {
+				n++
+			}
+		}
+		if n != tt.shards {
+			t.Errorf("%d reads from %d shards, want %d", i, n, tt.shards)
+		}
+		if res := ids(r); fmt.Sprint(res) != tt.ids {
+			t.Errorf("%d has ids => %v, want %v", i, res, tt.ids)
+		}
+	}
+
+	// projections without the shard key are merged client side
+	type balanceTup struct {
+		Balance int
+	}
+	if _, ok := accounts.Project(balanceTup{}).(*shardedTable); ok {
+		t.Errorf("projection without the shard key was applied to each shard")
+	}
+	type renamedTup struct {
+		No      int
+		Balance int
+	}
+	renamed := accounts.Rename(renamedTup{}).(*shardedTable)
+	if renamed.key != "No" {
+		t.Errorf("renamed shard key => %v, want No", renamed.key)
+	}
+	if c := rel.Card(renamed.Restrict(Attribute("No").EQ(2))); c != 1 {
+		t.Errorf("Card() of renamed account => %v, want 1", c)
+	}
+}
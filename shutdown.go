@@ -0,0 +1,87 @@
+package relsql
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrShutdown is the error of relations whose reads were stopped by a
+// Controller's Shutdown, or which were read after it.
+var ErrShutdown = errors.New("relsql: shut down")
+
+// Controller tracks the reads of the relations that use it, so that a service
+// can stop them all when it exits, without leaking connections or leaving
+// transactions open.
+type Controller struct {
+	mu       sync.Mutex
+	shutdown bool
+	wg       sync.WaitGroup
+
+	// ctx is the context of the reads, which is canceled to abort them
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewController creates a controller with no reads.
+func NewController() *Controller {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Controller{ctx: ctx, cancel: cancel}
+}
+
+// WithController makes the controller track the relation's reads.
+func WithController(c *Controller) Option {
+	return func(o *options) {
+		o.controller = c
+	}
+}
+
+// Shutdown stops new reads of the controller's relations, and waits for the
+// reads in flight to finish.  If ctx is done first, it aborts their queries,
+// rolls back their transactions, and waits until their connections are
+// released, before returning the context's error.  Aborted reads close their
+// channels, and the relations' Err is a PartialError of ErrShutdown.  To
+// cancel the reads without draining them, pass a context that is already
+// done.
+func (c *Controller) Shutdown(ctx context.Context) error {
+	c.mu.Lock()
+	c.shutdown = true
+	c.mu.Unlock()
+	drained := make(chan struct{})
+	go func() {
+		c.wg.Wait()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+	}
+	c.cancel()
+	<-drained
+	return ctx.Err()
+}
+
+// context returns the context of the controller's reads, or the background
+// context if there is no controller.
+func (c *Controller) context() context.Context {
+	if c == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+// track starts tracking a read, and returns a function that ends the
+// tracking.  It returns ErrShutdown if the controller has been shut down.
+func (c *Controller) track() (func(), error) {
+	if c == nil {
+		return func() {}, nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.shutdown {
+		return nil, ErrShutdown
+	}
+	c.wg.Add(1)
+	return c.wg.Done, nil
+}
@@ -0,0 +1,74 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/jonlawlor/rel"
+	"testing"
+	"time"
+)
+
+// test draining and canceling reads with a controller
+func TestShutdown(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:shutdown?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type downTup struct {
+		Name string
+	}
+	if err := CreateTable(db, "down", downTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "down", rel.New([]downTup{{"a"}, {"b"}, {"c"}}, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	read := func(r rel.Relation) (int, error) {
+		ch := make(chan downTup)
+		r.TupleChan(ch)
+		n := 0
+		for range ch {
+			n++
+		}
+		return n, r.Err()
+	}
+
+	// reads that finish are drained
+	c := NewController()
+	r := New(db, "down", downTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithController(c))
+	if n, err := read(r); n != 3 || err != nil {
+		t.Errorf("read => %d, %v, want 3 tuples", n, err)
+	}
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Errorf("Shutdown() => %v", err)
+	}
+	if _, err := read(New(db, "down", downTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithController(c))); err != ErrShutdown {
+		t.Errorf("read after Shutdown() => %v, want %v", err, ErrShutdown)
+	}
+
+	// reads whose consumers stall are canceled once the context is done
+	c = NewController()
+	r = New(db, "down", downTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithController(c))
+	ch := make(chan downTup)
+	r.TupleChan(ch)
+	<-ch
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown() of a stalled read => %v, want %v", err, context.DeadlineExceeded)
+	}
+	for range ch {
+	}
+	if err := r.Err(); !errors.Is(err, ErrShutdown) {
+		t.Errorf("stalled read after Shutdown() => %v, want %v", err, ErrShutdown)
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("Shutdown() left %d connections in use", n)
+	}
+}
@@ -0,0 +1,37 @@
+package relsql
+
+import (
+	"context"
+)
+
+// WithSingleConnection makes each read of the relation execute all of its
+// statements, which are the counts that check cross joins, the dialect's
+// session statements, and the query itself, on one connection taken from the
+// pool, instead of taking a connection for each of them.  It reduces the
+// pressure on the pool, and guarantees that the statements see the same
+// session state, like temporary tables.
+func WithSingleConnection() Option {
+	return func(o *options) {
+		o.singleConn = true
+	}
+}
+
+// planConn returns the relation that executes the statements of a read, which
+// is a copy of the relation on a single connection from the pool if it was
+// configured to use one, along with a function that returns the connection.
+func (r1 *sqlTable) planConn(ctx context.Context) (*sqlTable, func(), error) {
+	if !r1.opts.singleConn || r1.conn != nil || r1.q != nil || r1.db == nil {
+		return r1, func() {}, nil
+	}
+	var err error
+	r2 := *r1
+	if r1.opts.checkoutTimeout > 0 {
+		r2.conn, err = r1.checkout(ctx)
+	} else {
+		r2.conn, err = r1.db.Conn(ctx)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return &r2, func() { r2.conn.Close() }, nil
+}
@@ -0,0 +1,97 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"github.com/jonlawlor/rel"
+	"sync/atomic"
+	"testing"
+)
+
+// countingConnector opens sqlite connections, and counts them
+type countingConnector struct {
+	dsn   string
+	d     driver.Driver
+	opens *int64
+}
+
+func (c countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	atomic.AddInt64(c.opens, 1)
+	return c.d.Open(c.dsn)
+}
+
+func (c countingConnector) Driver() driver.Driver {
+	return c.d
+}
+
+// test executing the statements of a read on a single connection
+func TestSingleConnection(t *testing.T) {
+	dsn := "file:singleconn?mode=memory&cache=shared"
+	setup, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer setup.Close()
+
+	type storeTup struct {
+		Store string
+	}
+	type dayTup struct {
+		Day int
+	}
+	type storeDayTup struct {
+		Store string
+		Day   int
+	}
+	if err := CreateTable(setup, "stores", storeTup{}, [][]string{[]string{"Store"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if err := CreateTable(setup, "days", dayTup{}, [][]string{[]string{"Day"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(setup, "stores", rel.New([]storeTup{{"a"}, {"b"}}, [][]string{[]string{"Store"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	if _, err := Insert(setup, "days", rel.New([]dayTup{{1}, {2}}, [][]string{[]string{"Day"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var singleTest = []struct {
+		opts  []Option
+		opens int64
+	}{
+		// the counts of the two sides of the cross join, and the query
+		{nil, 3},
+		{[]Option{WithSingleConnection()}, 1},
+	}
+	for i, tt := range singleTest {
+		// without idle connections, every statement that takes a connection
+		// from the pool opens a new one
+		var opens int64
+		db := sql.OpenDB(countingConnector{dsn, setup.Driver(), &opens})
+		db.SetMaxIdleConns(0)
+		opts := append([]Option{WithDialect(SQLite), WithCrossJoinLimit(100)}, tt.opts...)
+		stores := New(db, "stores", storeTup{}, [][]string{[]string{"Store"}}, opts...)
+		days := New(db, "days", dayTup{}, [][]string{[]string{"Day"}}, opts...)
+		r := CrossJoin(stores, days, storeDayTup{})
+		ch := make(chan storeDayTup)
+		r.TupleChan(ch)
+		n := 0
+		for range ch {
+			n++
+		}
+		if n != 4 || r.Err() != nil {
+			t.Errorf("%d has %d tuples, %v, want 4 tuples", i, n, r.Err())
+		}
+		if opens != tt.opens {
+			t.Errorf("%d opened %d connections, want %d", i, opens, tt.opens)
+		}
+		db.Close()
+	}
+}
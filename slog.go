@@ -0,0 +1,88 @@
+package relsql
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// WithSlog sets a structured logger, which is sent a record for each query
+// and client side operation, as well as the warnings of WithLogger.  The
+// records and their attributes are:
+//
+//   - "relsql query" at Debug, with relation, sql, args (the number of
+//     arguments), rows, duration and, if the query failed, error
+//   - "relsql client side" at Info, for an operation that couldn't be pushed
+//     down to the database, with relation, op, expr, reason and rows
+//   - "relsql retry" at Warn, with relation, attempt, delay and error
+//   - "relsql read failed" at Error, with relation, rows and error
+//   - "relsql warning" at Warn, with detail
+//
+// Query arguments aren't logged, since they may hold sensitive values.
+func WithSlog(l *slog.Logger) Option {
+	return func(o *options) {
+		o.slog = l
+	}
+}
+
+// logging returns true if warnings are logged
+func (o *options) logging() bool {
+	return o.logf != nil || o.slog != nil
+}
+
+// warnf logs a warning, which starts with "relsql: ", with the logger
+// function and the structured logger.
+func (o *options) warnf(format string, args ...interface{}) {
+	if o.logf != nil {
+		o.logf(format, args...)
+	}
+	if o.slog != nil {
+		o.slog.Warn("relsql warning", "detail", strings.TrimPrefix(fmt.Sprintf(format, args...), "relsql: "))
+	}
+}
+
+// logQuery logs a query that was executed at start, and sent rows tuples
+func (r1 *sqlTable) logQuery(ctx context.Context, q string, args []interface{}, start time.Time, rows int, err error) {
+	l := r1.opts.slog
+	if l == nil || !l.Enabled(ctx, slog.LevelDebug) {
+		return
+	}
+	attrs := []slog.Attr{
+		slog.String("relation", r1.String()),
+		slog.String("sql", q),
+		slog.Int("args", len(args)),
+		slog.Int("rows", rows),
+		slog.Duration("duration", time.Since(start)),
+	}
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	l.LogAttrs(ctx, slog.LevelDebug, "relsql query", attrs...)
+}
+
+// logRetry logs a read that is retried after it failed with err
+func (r1 *sqlTable) logRetry(ctx context.Context, attempt int, delay time.Duration, err error) {
+	if l := r1.opts.slog; l != nil {
+		l.LogAttrs(ctx, slog.LevelWarn, "relsql retry",
+			slog.String("relation", r1.String()), slog.Int("attempt", attempt), slog.Duration("delay", delay), slog.Any("error", err))
+	}
+}
+
+// logReadFailed logs a read that failed after sending rows tuples
+func (r1 *sqlTable) logReadFailed(ctx context.Context, rows int, err error) {
+	if l := r1.opts.slog; l != nil {
+		l.LogAttrs(ctx, slog.LevelError, "relsql read failed",
+			slog.String("relation", r1.String()), slog.Int("rows", rows), slog.Any("error", err))
+	}
+}
+
+// logClientSide logs the evaluation of a client side operation
+func (r1 *sqlTable) logClientSide(d Diagnostic) {
+	if l := r1.opts.slog; l != nil {
+		l.LogAttrs(context.Background(), slog.LevelInfo, "relsql client side",
+			slog.String("relation", r1.String()), slog.String("op", d.Op), slog.String("expr", d.Expr),
+			slog.String("reason", d.Reason), slog.Int64("rows", d.Rows))
+	}
+}
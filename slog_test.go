@@ -0,0 +1,131 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"log/slog"
+	"sync"
+	"testing"
+)
+
+// recordHandler is a slog.Handler that keeps the records it handles
+type recordHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *recordHandler) Enabled(context.Context, slog.Level) bool { return true }
+func (h *recordHandler) WithAttrs([]slog.Attr) slog.Handler       { return h }
+func (h *recordHandler) WithGroup(string) slog.Handler            { return h }
+
+func (h *recordHandler) Handle(_ context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+	return nil
+}
+
+// take returns the records with a message, as maps of their attributes, and
+// forgets every record
+func (h *recordHandler) take(msg string) []map[string]string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var res []map[string]string
+	for _, r := range h.records {
+		if r.Message != msg {
+			continue
+		}
+		attrs := map[string]string{"level": r.Level.String()}
+		r.Attrs(func(a slog.Attr) bool {
+			attrs[a.Key] = a.Value.String()
+			return true
+		})
+		res = append(res, attrs)
+	}
+	h.records = nil
+	return res
+}
+
+// test the records sent to a structured logger
+func TestSlog(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:slog?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type itemTup struct {
+		ID   int
+		Name string
+	}
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "items", itemTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "items", rel.New([]itemTup{{1, "a"}, {2, "b"}, {3, "c"}}, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	h := &recordHandler{}
+	items := New(db, "items", itemTup{}, keys, WithSlog(slog.New(h)))
+
+	if err := drainErr(items.Restrict(Attribute("ID").GT(1))); err != nil {
+		t.Errorf("drainErr() => %v", err)
+	}
+	qs := h.take("relsql query")
+	if len(qs) != 1 || qs[0]["level"] != "DEBUG" || qs[0]["sql"] != "SELECT ID, Name FROM items WHERE ID > ?" ||
+		qs[0]["args"] != "1" || qs[0]["rows"] != "2" || qs[0]["duration"] == "" || qs[0]["error"] != "" {
+		t.Errorf("query records => %v", qs)
+	}
+
+	// a restriction that isn't pushed down
+	if err := drainErr(items.Restrict(rel.Attribute("ID").EQ(2))); err != nil {
+		t.Errorf("drainErr() => %v", err)
+	}
+	cs := h.take("relsql client side")
+	if len(cs) != 1 || cs[0]["level"] != "INFO" || cs[0]["op"] != "Restrict" || cs[0]["rows"] != "3" || cs[0]["reason"] == "" {
+		t.Errorf("client side records => %v", cs)
+	}
+
+	// a read that fails, and is retried once
+	type missingTup struct {
+		ID int
+	}
+	policy := RetryPolicy{Attempts: 2, Retryable: func(error) bool { return true }}
+	if err := drainErr(New(db, "missing", missingTup{}, nil, WithSlog(slog.New(h)), WithRetry(policy))); err == nil {
+		t.Errorf("reading a missing table has no error")
+	}
+	h.mu.Lock()
+	var msgs []string
+	for _, r := range h.records {
+		msgs = append(msgs, r.Message)
+	}
+	h.mu.Unlock()
+	if fmt.Sprint(msgs) != "[relsql query relsql retry relsql query relsql read failed]" {
+		t.Errorf("failed read records => %v", msgs)
+	}
+	rs := h.take("relsql retry")
+	if len(rs) != 1 || rs[0]["level"] != "WARN" || rs[0]["attempt"] != "1" || rs[0]["error"] == "" {
+		t.Errorf("retry records => %v", rs)
+	}
+
+	// warnings are sent with the same logger
+	type otherTup struct {
+		Name  string
+		Other int
+	}
+	others := New(db, "others", otherTup{}, [][]string{[]string{"Other"}}, WithSlog(slog.New(h)))
+	items.Join(others, struct {
+		ID    int
+		Name  string
+		Other int
+	}{})
+	ws := h.take("relsql warning")
+	if len(ws) != 1 || ws[0]["level"] != "WARN" || ws[0]["detail"] == "" {
+		t.Errorf("warning records => %v", ws)
+	}
+}
@@ -0,0 +1,88 @@
+package relsql
+
+import (
+	"encoding/gob"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"io"
+	"reflect"
+)
+
+// snapshotHeader is the start of a snapshot, which is followed by its tuples
+type snapshotHeader struct {
+	Names []string
+	Types []string
+	Keys  [][]string
+}
+
+// Save writes a snapshot of the heading, candidate keys, and tuples of a
+// relation to w in gob's binary format, which Load reads back.  The tuples are
+// streamed, so the relation isn't held in memory.  Each tuple is preceded by a
+// marker, and a final marker ends the snapshot, so that a snapshot that was
+// cut short is an error when it is loaded.
+func Save(w io.Writer, r rel.Relation) error {
+	e := reflect.TypeOf(r.Zero())
+	h := snapshotHeader{
+		Names: make([]string, e.NumField()),
+		Types: make([]string, e.NumField()),
+		Keys:  keyStrings(r.CKeys()),
+	}
+	for i := range h.Names {
+		h.Names[i] = e.Field(i).Name
+		h.Types[i] = e.Field(i).Type.String()
+	}
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(h); err != nil {
+		return err
+	}
+	err := forEach(r, func(tup reflect.Value) error {
+		if err := enc.Encode(true); err != nil {
+			return err
+		}
+		return enc.EncodeValue(tup)
+	})
+	if err != nil {
+		return err
+	}
+	return enc.Encode(false)
+}
+
+// Load reads a snapshot written by Save into an in memory relation with
+// tuples of the same type as z, and the snapshot's candidate keys.  It returns
+// an error if the heading of the snapshot differs from z's, or if the snapshot
+// is incomplete.
+func Load(rd io.Reader, z interface{}) (rel.Relation, error) {
+	dec := gob.NewDecoder(rd)
+	var h snapshotHeader
+	if err := dec.Decode(&h); err != nil {
+		return nil, err
+	}
+	e := reflect.TypeOf(z)
+	if e.NumField() != len(h.Names) {
+		return nil, fmt.Errorf("relsql: snapshot has %d attributes, and %v has %d", len(h.Names), e, e.NumField())
+	}
+	for i, name := range h.Names {
+		if f := e.Field(i); f.Name != name || f.Type.String() != h.Types[i] {
+			return nil, fmt.Errorf("relsql: snapshot attribute %s %s doesn't match %s %v", name, h.Types[i], f.Name, f.Type)
+		}
+	}
+	tups := reflect.MakeSlice(reflect.SliceOf(e), 0, 0)
+	for {
+		var more bool
+		if err := dec.Decode(&more); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		if !more {
+			break
+		}
+		tup := reflect.New(e)
+		if err := dec.DecodeValue(tup); err != nil {
+			return nil, err
+		}
+		tups = reflect.Append(tups, tup.Elem())
+	}
+	return rel.New(tups.Interface(), h.Keys), nil
+}
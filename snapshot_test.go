@@ -0,0 +1,59 @@
+package relsql
+
+import (
+	"bytes"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// test saving and loading snapshots
+func TestSnapshot(t *testing.T) {
+	type snapTup struct {
+		Name string
+		Loc  Point
+		At   time.Time
+	}
+	tups := []snapTup{
+		{"a", Point{1, 2}, time.Date(2015, 1, 2, 3, 4, 5, 0, time.UTC)},
+		{"b", Point{}, time.Time{}},
+	}
+	r := rel.New(tups, [][]string{[]string{"Name"}})
+	var buf bytes.Buffer
+	if err := Save(&buf, r); err != nil {
+		t.Errorf("Save() => %v", err)
+		return
+	}
+	snapshot := buf.Bytes()
+
+	r2, err := Load(bytes.NewReader(snapshot), snapTup{})
+	if err != nil {
+		t.Errorf("Load() => %v", err)
+		return
+	}
+	ch := make(chan snapTup)
+	r2.TupleChan(ch)
+	var res []snapTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if !reflect.DeepEqual(res, tups) {
+		t.Errorf("Load() => %v, want %v", res, tups)
+	}
+	if !reflect.DeepEqual(r2.CKeys(), r.CKeys()) {
+		t.Errorf("Load() has keys %v, want %v", r2.CKeys(), r.CKeys())
+	}
+
+	type otherTup struct {
+		Name string
+		Loc  Point
+		At   string
+	}
+	if _, err := Load(bytes.NewReader(snapshot), otherTup{}); err == nil {
+		t.Errorf("Load() into a different heading succeeded")
+	}
+	if _, err := Load(bytes.NewReader(snapshot[:len(snapshot)-3]), snapTup{}); err == nil {
+		t.Errorf("Load() of a truncated snapshot succeeded")
+	}
+}
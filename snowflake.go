@@ -0,0 +1,194 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Snowflake is the dialect for Snowflake, without any session parameters.
+// Snowflake folds unquoted identifiers to upper case, so columns created from
+// attribute names are reported in upper case, which is taken into account
+// when migrating tables and finding their keys.
+var Snowflake Dialect = &snowflakeDialect{}
+
+// NewSnowflake returns a Snowflake dialect that sets session parameters, like
+// QUERY_TAG or TIMEZONE, with ALTER SESSION on the connection of each query.
+func NewSnowflake(params map[string]string) Dialect {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	d := &snowflakeDialect{}
+	for _, name := range names {
+		d.session = append(d.session, "ALTER SESSION SET "+name+" = "+literal(params[name]))
+	}
+	if len(names) > 0 {
+		d.reset = []string{"ALTER SESSION UNSET " + strings.Join(names, ", ")}
+	}
+	return d
+}
+
+// snowflakeDialect is the dialect for Snowflake
+type snowflakeDialect struct {
+	// session holds the statements that set the session parameters, and
+	// reset those that unset them again
+	session []string
+	reset   []string
+}
+
+// Name returns the name of the dialect
+func (*snowflakeDialect) Name() string {
+	return "snowflake"
+}
+
+// Placeholder returns the placeholder for the i'th argument of a query
+func (*snowflakeDialect) Placeholder(i int) string {
+	return "?"
+}
+
+// ReadTx returns false, because a single Snowflake query reads from a
+// consistent snapshot.
+func (*snowflakeDialect) ReadTx() bool {
+	return false
+}
+
+// SessionStatements returns the statements that set the session parameters
+func (d *snowflakeDialect) SessionStatements() []string {
+	return d.session
+}
+
+// ResetStatements returns the statement that unsets the session parameters,
+// so that the connection can be reused by queries without them
+func (d *snowflakeDialect) ResetStatements() []string {
+	return d.reset
+}
+
+// ProcStyle returns ProcTable, because Snowflake's table functions are
+// selected from with TABLE(fn(...)).
+func (*snowflakeDialect) ProcStyle() ProcStyle {
+	return ProcTable
+}
+
+// Distinctness returns ForceDistinct, because Snowflake doesn't enforce
+// primary keys or unique constraints, so a table may have duplicate rows
+// whatever its declared keys.
+func (*snowflakeDialect) Distinctness() Distinctness {
+	return ForceDistinct
+}
+
+// FoldIdentifier returns the upper case name, unless it is quoted
+func (*snowflakeDialect) FoldIdentifier(name string) string {
+	if strings.HasPrefix(name, `"`) {
+		return strings.Trim(name, `"`)
+	}
+	return strings.ToUpper(name)
+}
+
+// Keys returns the primary and unique keys declared for a Snowflake table.
+// Snowflake doesn't enforce them, so they are only used to plan migrations,
+// and relations don't trust their keys unless WithDistinct says to.
+func (*snowflakeDialect) Keys(db *sql.DB, tableName string, opts *options) ([][]string, error) {
+	ctx := context.Background()
+
+	// the results of SHOW are read with RESULT_SCAN, which has to be in the
+	// same session
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	var ckeystr [][]string
+	for _, kind := range []string{"PRIMARY", "UNIQUE"} {
+		if _, err := opts.exec(ctx, conn, "SHOW "+kind+" KEYS IN TABLE "+tableName); err != nil {
+			return nil, err
+		}
+		if err := snowflakeKeyColumns(ctx, conn, opts, &ckeystr); err != nil {
+			return nil, err
+		}
+	}
+	return ckeystr, nil
+}
+
+// snowflakeKeyColumns appends the columns of each key in the result of the
+// last SHOW KEYS statement to ckeystr, and audits the query that reads them
+func snowflakeKeyColumns(ctx context.Context, conn *sql.Conn, opts *options, ckeystr *[][]string) (err error) {
+	q := `SELECT "constraint_name", "column_name" FROM ` + SnowflakeResultScan("") + ` ORDER BY "constraint_name", "key_sequence"`
+	start := time.Now()
+	var n int64
+	defer func() {
+		if aerr := opts.audit(q, nil, start, n, err); err == nil {
+			err = aerr
+		}
+	}()
+	rows, err := conn.QueryContext(ctx, q)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	first, last := true, ""
+	for rows.Next() {
+		var constraint, col string
+		if err = rows.Scan(&constraint, &col); err != nil {
+			return err
+		}
+		if first || constraint != last {
+			*ckeystr = append(*ckeystr, nil)
+			first, last = false, constraint
+		}
+		(*ckeystr)[len(*ckeystr)-1] = append((*ckeystr)[len(*ckeystr)-1], col)
+		n++
+	}
+	return rows.Err()
+}
+
+// SnowflakeResultScan returns a table expression for the cached result of a
+// Snowflake query, which can be used as the table name of New, so that a
+// large result can be restricted and projected without running the query
+// again.  If queryID is empty, it refers to the last query of the session.
+func SnowflakeResultScan(queryID string) string {
+	if queryID == "" {
+		return "TABLE(RESULT_SCAN(LAST_QUERY_ID()))"
+	}
+	return "TABLE(RESULT_SCAN(" + literal(queryID) + "))"
+}
+
+// HashAggregate returns the order independent hash of the columns of the
+// rows in a group
+func (*snowflakeDialect) HashAggregate(cols []string) string {
+	return "HASH_AGG(" + strings.Join(cols, ", ") + ")"
+}
+
+// Features declares TABLESAMPLE, which Snowflake also calls SAMPLE
+func (*snowflakeDialect) Features() map[Feature]bool {
+	return map[Feature]bool{FeatureTableSample: true}
+}
+
+// LateralJoin returns the comma join with a LATERAL subquery, which is how
+// Snowflake writes lateral joins
+func (*snowflakeDialect) LateralJoin() string {
+	return ", LATERAL"
+}
+
+// Percentile returns APPROX_PERCENTILE for approximate percentiles, and the
+// standard ordered set aggregates otherwise
+func (*snowflakeDialect) Percentile(col string, p float64, disc, approx bool) string {
+	if approx {
+		return fmt.Sprintf("APPROX_PERCENTILE(%s, %v)", col, p)
+	}
+	return orderedPercentile(col, p, disc)
+}
+
+// TransactionalDDL returns false, because Snowflake commits the transaction
+// that a DDL statement is executed in
+func (*snowflakeDialect) TransactionalDDL() bool {
+	return false
+}
+
+func init() {
+	RegisterDialect("snowflake", Snowflake)
+}
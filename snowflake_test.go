@@ -0,0 +1,166 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// foldDialect is sqlite with Snowflake's identifier case folding and session
+// statements
+type foldDialect struct {
+	sqliteDialect
+	session []string
+}
+
+func (d foldDialect) FoldIdentifier(name string) string { return strings.ToUpper(name) }
+func (d foldDialect) SessionStatements() []string       { return d.session }
+
+// test Snowflake session parameters, case folding, and result scans
+func TestSnowflake(t *testing.T) {
+	d := NewSnowflake(map[string]string{"TIMEZONE": "UTC", "QUERY_TAG": "relsql's"})
+	want := []string{"ALTER SESSION SET QUERY_TAG = 'relsql''s'", "ALTER SESSION SET TIMEZONE = 'UTC'"}
+	if stmts := sessionStatements(d); !reflect.DeepEqual(stmts, want) {
+		t.Errorf("SessionStatements() => %v, want %v", stmts, want)
+	}
+	if stmts := sessionStatements(Snowflake); len(stmts) != 0 {
+		t.Errorf("SessionStatements() without parameters => %v", stmts)
+	}
+	if name := foldIdentifier(Snowflake, "Qty"); name != "QTY" {
+		t.Errorf("FoldIdentifier() => %v, want QTY", name)
+	}
+	if name := foldIdentifier(Snowflake, `"Qty"`); name != "Qty" {
+		t.Errorf("FoldIdentifier() of quoted name => %v, want Qty", name)
+	}
+
+	type partTup struct {
+		PNO  int64
+		Name string
+	}
+	q, _, _ := New(nil, SnowflakeResultScan("01a2-b3"), partTup{}, nil, WithDialect(Snowflake)).Restrict(Attribute("PNO").EQ(1)).(*sqlTable).queryString()
+	if want := "SELECT DISTINCT PNO, Name FROM TABLE(RESULT_SCAN('01a2-b3')) WHERE PNO = ?"; q != want {
+		t.Errorf("queryString() => %q, want %q", q, want)
+	}
+
+	// declared keys aren't enforced, so they are only trusted if asked to be
+	keys := [][]string{[]string{"PNO"}}
+	var distinctTest = []struct {
+		opts []Option
+		want string
+	}{
+		{[]Option{WithDialect(Snowflake)}, "SELECT DISTINCT PNO, Name FROM parts"},
+		{[]Option{WithDialect(Snowflake), WithDistinct(AssumeDistinctByKey)}, "SELECT PNO, Name FROM parts"},
+		{[]Option{WithDistinct(AssumeDistinctByKey), WithDialect(Snowflake)}, "SELECT PNO, Name FROM parts"},
+		{[]Option{WithDialect(SQLite)}, "SELECT PNO, Name FROM parts"},
+	}
+	for i, tt := range distinctTest {
+		if q, _, _ := New(nil, "parts", partTup{}, keys, tt.opts...).(*sqlTable).queryString(); q != tt.want {
+			t.Errorf("%d has queryString() => %q, want %q", i, q, tt.want)
+		}
+	}
+
+	db, err := sql.Open("sqlite3", "file:snowflake?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	// the columns are reported in upper case, so the table is already up to
+	// date
+	if _, err := db.Exec("create table parts (PNO integer not null primary key, NAME text not null)"); err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	stmts, err := PlanMigration(db, "parts", partTup{}, [][]string{[]string{"PNO"}}, WithDialect(foldDialect{}))
+	if err != nil || len(stmts) != 0 {
+		t.Errorf("PlanMigration() => %v, %v, want no statements", stmts, err)
+	}
+
+	// session statements are executed on the connection that reads
+	type markerTup struct {
+		N int
+	}
+	fd := foldDialect{session: []string{"CREATE TEMP TABLE IF NOT EXISTS marker AS SELECT 7 AS N"}}
+	ch := make(chan markerTup)
+	r := New(db, "marker", markerTup{}, nil, WithDialect(fd))
+	r.TupleChan(ch)
+	var res []markerTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if len(res) != 1 || res[0].N != 7 {
+		t.Errorf("tuples after session statements => %v, want [{7}]", res)
+	}
+	if err := r.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+}
+
+// resetDialect is sqlite with session statements that can be undone
+type resetDialect struct {
+	foldDialect
+	reset []string
+}
+
+func (d resetDialect) ResetStatements() []string { return d.reset }
+
+// test that session statements don't remain on pooled connections
+func TestSessionReset(t *testing.T) {
+	d := NewSnowflake(map[string]string{"TIMEZONE": "UTC", "QUERY_TAG": "relsql"})
+	if stmts, _ := resetStatements(d); !reflect.DeepEqual(stmts, []string{"ALTER SESSION UNSET QUERY_TAG, TIMEZONE"}) {
+		t.Errorf("ResetStatements() => %v", stmts)
+	}
+
+	db, err := sql.Open("sqlite3", "file:sessionreset?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type markTup struct {
+		ID int
+	}
+	if err := CreateTable(db, "marks", markTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	// one connection keeps the database open while the other is pooled, and
+	// holds a temporary table that shows whether it was reused
+	keep, err := db.Conn(context.Background())
+	if err != nil {
+		t.Errorf("Conn() => %v", err)
+		return
+	}
+	defer keep.Close()
+	db.SetMaxOpenConns(2)
+
+	session := []string{"CREATE TEMP TABLE session_marker (x)"}
+	var tests = []struct {
+		name   string
+		d      Dialect
+		reused bool
+	}{
+		{"closed", foldDialect{session: session}, false},
+		{"reset", resetDialect{foldDialect{session: session}, []string{"DROP TABLE temp.session_marker"}}, true},
+	}
+	for _, tt := range tests {
+		if _, err := db.Exec("CREATE TEMP TABLE IF NOT EXISTS pooled (x)"); err != nil {
+			t.Errorf("%s: Exec() => %v", tt.name, err)
+			continue
+		}
+		for i := 0; i < 2; i++ {
+			r := New(db, "marks", markTup{}, [][]string{[]string{"ID"}}, WithDialect(tt.d))
+			if err := drainErr(r); err != nil {
+				t.Errorf("%s: read %d => %v", tt.name, i, err)
+			}
+		}
+		_, err := db.Exec("SELECT * FROM temp.pooled")
+		if reused := err == nil; reused != tt.reused {
+			t.Errorf("%s: connection reused => %v, want %v", tt.name, reused, tt.reused)
+		}
+	}
+}
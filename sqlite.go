@@ -0,0 +1,373 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SQLite is the dialect for sqlite3.  Every sqlite statement is executed in
+// its own implicit transaction, so reads are done without an explicit one.
+var SQLite Dialect = sqliteDialect{}
+
+// sqliteDialect is the dialect for sqlite3
+type sqliteDialect struct{}
+
+// SpatiaLite is the dialect for sqlite3 with the SpatiaLite extension loaded,
+// such as by a driver registered with mod_spatialite in its Extensions.
+// Geometries are stored as well known text, like they are in sqlite, and
+// spatial predicates parse them with GeomFromText, so they don't use a
+// spatial index.  It isn't registered for a driver, because the extension is
+// loaded by the same driver type as plain sqlite3.
+var SpatiaLite Dialect = spatialiteDialect{}
+
+// spatialiteDialect is the dialect for sqlite3 with SpatiaLite
+type spatialiteDialect struct {
+	sqliteDialect
+}
+
+// Spatial returns the SpatiaLite function for a spatial predicate, which has
+// no ST_DWithin, so the distance is compared instead, and whose bounding box
+// intersection is MbrIntersects
+func (spatialiteDialect) Spatial(op, col, geom, dist string) string {
+	switch op {
+	case "ST_Within":
+		return "ST_Within(GeomFromText(" + col + "), GeomFromText(" + geom + "))"
+	case "ST_DWithin":
+		return "ST_Distance(GeomFromText(" + col + "), GeomFromText(" + geom + ")) <= " + dist
+	case "&&":
+		return "MbrIntersects(GeomFromText(" + col + "), GeomFromText(" + geom + "))"
+	}
+	return ""
+}
+
+// Name returns the name of the dialect
+func (sqliteDialect) Name() string {
+	return "sqlite3"
+}
+
+// Placeholder returns the placeholder for the i'th argument of a query
+func (sqliteDialect) Placeholder(i int) string {
+	return "?"
+}
+
+// ReadTx returns false, because a single select statement is already a
+// consistent read.
+func (sqliteDialect) ReadTx() bool {
+	return false
+}
+
+// Returning returns true, because sqlite supports INSERT ... RETURNING.
+func (sqliteDialect) Returning() bool {
+	return true
+}
+
+// AlterColumnType returns an empty string, because sqlite can't change the
+// type of a column without rebuilding the table.
+func (sqliteDialect) AlterColumnType(tableName, colName, typeName string, nullable bool) string {
+	return ""
+}
+
+// MapKey returns the json_extract of a key from a map column, which sqlite
+// stores as a JSON object.
+func (sqliteDialect) MapKey(col, key string) string {
+	return "json_extract(" + col + ", " + literal("$."+strconv.Quote(key)) + ")"
+}
+
+// Limit limits the query to its first n rows with LIMIT
+func (sqliteDialect) Limit(query string, n int) string {
+	return query + " LIMIT " + strconv.Itoa(n)
+}
+
+// ProcStyle returns ProcSelect, because sqlite's table valued functions, like
+// json_each, are used in the FROM clause.
+func (sqliteDialect) ProcStyle() ProcStyle {
+	return ProcSelect
+}
+
+// Keys returns the candidate keys declared for a sqlite table
+func (sqliteDialect) Keys(db *sql.DB, tableName string, opts *options) ([][]string, error) {
+	return SQLiteKeys(db, tableName)
+}
+
+// EstimateCard estimates the rows of a relation from the table it reads from,
+// because sqlite's plans have no row estimates.  The estimate is the count
+// that ANALYZE recorded in sqlite_stat1, or, for a table that hasn't been
+// analyzed, its largest rowid, which is read from the end of its b-tree.
+// Neither takes the relation's restrictions into account.
+func (d sqliteDialect) EstimateCard(ctx context.Context, q QueryerContext, table, query string, args []interface{}) (int64, error) {
+	if table == "" {
+		return 0, errors.New("relsql: sqlite can only estimate the cardinality of a relation that reads from a table")
+	}
+	var stat string
+	if err := q.QueryRowContext(ctx, "SELECT stat FROM sqlite_stat1 WHERE tbl = ?", table).Scan(&stat); err == nil {
+		if f := strings.Fields(stat); len(f) > 0 {
+			if n, err := strconv.ParseInt(f[0], 10, 64); err == nil {
+				return n, nil
+			}
+		}
+	}
+	var n sql.NullInt64
+	err := q.QueryRowContext(ctx, "SELECT MAX(_rowid_) FROM "+quoteTable(d, table)).Scan(&n)
+	return n.Int64, err
+}
+
+// ForeignKeys returns the foreign keys declared for a sqlite table
+func (sqliteDialect) ForeignKeys(db *sql.DB, tableName string) ([]ForeignKey, error) {
+	return SQLiteForeignKeys(db, tableName)
+}
+
+// NewSQLite creates a relation that reads from a sqlite table, with one tuple
+// per row.  dsn is the data source name that db was opened with.  The
+// candidate keys are inferred from the table's primary key and unique
+// indexes over columns that can't be NULL, and the foreign keys are read from
+// the catalog.
+//
+// relsql reads on concurrent connections, which fails for in memory databases
+// unless they use a shared cache, because each connection would otherwise see
+// its own empty database.  Such a dsn results in an error relation.
+func NewSQLite(db *sql.DB, dsn string, tableName string, z interface{}, opts ...Option) rel.Relation {
+	opts = append([]Option{WithDialect(SQLite)}, opts...)
+	if err := CheckSQLiteDSN(dsn); err != nil {
+		r := New(db, tableName, z, nil, opts...).(*sqlTable)
+		r.err = err
+		return r
+	}
+	ckeystr, err := SQLiteKeys(db, tableName)
+	if err != nil {
+		r := New(db, tableName, z, nil, opts...).(*sqlTable)
+		r.err = err
+		return r
+	}
+
+	fks, err := SQLiteForeignKeys(db, tableName)
+	if err != nil {
+		r := New(db, tableName, z, nil, opts...).(*sqlTable)
+		r.err = err
+		return r
+	}
+	opts = append([]Option{WithForeignKeys(fks...)}, opts...)
+
+	nullable, err := sqliteNullable(db, tableName)
+	if err != nil {
+		r := New(db, tableName, z, nil, opts...).(*sqlTable)
+		r.err = err
+		return r
+	}
+
+	// only keep keys that are entirely in the heading, and which can't have
+	// NULLs, because sqlite's unique indexes allow any number of rows with
+	// NULL keys
+	heading := colNames(z)
+	var keys [][]string
+	for _, ck := range ckeystr {
+		if containsColumns(heading, ck) && !anyNullable(nullable, ck) {
+			keys = append(keys, ck)
+		}
+	}
+	return New(db, tableName, z, keys, opts...)
+}
+
+// sqliteNullable returns the columns of a sqlite table which can hold NULL.
+// Primary key columns can, unless they are declared NOT NULL, except for an
+// INTEGER PRIMARY KEY, which is the table's rowid.
+func sqliteNullable(db *sql.DB, tableName string) (map[string]bool, error) {
+	nullable := make(map[string]bool)
+	var pk []string
+	rowid := false
+	err := pragma(db, "table_info", tableName, func(row map[string]interface{}) {
+		name := asString(row["name"])
+		if asInt(row["notnull"]) == 0 {
+			nullable[name] = true
+		}
+		if asInt(row["pk"]) > 0 {
+			pk = append(pk, name)
+			rowid = strings.EqualFold(asString(row["type"]), "INTEGER")
+		}
+	})
+	if len(pk) == 1 && rowid {
+		delete(nullable, pk[0])
+	}
+	return nullable, err
+}
+
+// anyNullable returns true if any of the columns can hold NULL
+func anyNullable(nullable map[string]bool, names []string) bool {
+	for _, name := range names {
+		if nullable[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// containsColumns returns true if every one of the names is in cols
+func containsColumns(cols []column, names []string) bool {
+	for _, name := range names {
+		found := false
+		for _, c := range cols {
+			if c.name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckSQLiteDSN returns an error if a sqlite data source name refers to an in
+// memory database without a shared cache.
+func CheckSQLiteDSN(dsn string) error {
+	memory := strings.HasPrefix(dsn, ":memory:") ||
+		strings.HasPrefix(dsn, "file::memory:") ||
+		strings.Contains(dsn, "mode=memory")
+	if memory && !strings.Contains(dsn, "cache=shared") {
+		return fmt.Errorf("relsql: sqlite in memory database %q requires cache=shared for concurrent reads", dsn)
+	}
+	return nil
+}
+
+// SQLiteKeys returns the candidate keys of a sqlite table, from its primary
+// key and its unique indexes.
+func SQLiteKeys(db *sql.DB, tableName string) ([][]string, error) {
+	var ckeystr [][]string
+
+	// the primary key columns are numbered by their position in the key
+	pk := make(map[int]string)
+	err := pragma(db, "table_info", tableName, func(row map[string]interface{}) {
+		if n := asInt(row["pk"]); n > 0 {
+			pk[n] = asString(row["name"])
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(pk) > 0 {
+		pos := make([]int, 0, len(pk))
+		for n := range pk {
+			pos = append(pos, n)
+		}
+		sort.Ints(pos)
+		ck := make([]string, len(pos))
+		for i, n := range pos {
+			ck[i] = pk[n]
+		}
+		ckeystr = append(ckeystr, ck)
+	}
+
+	// unique indexes other than the primary key's are also keys, as long as
+	// they aren't partial
+	var indexes []string
+	err = pragma(db, "index_list", tableName, func(row map[string]interface{}) {
+		if asInt(row["unique"]) == 1 && asString(row["origin"]) != "pk" && asInt(row["partial"]) == 0 {
+			indexes = append(indexes, asString(row["name"]))
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		var ck []string
+		err = pragma(db, "index_info", index, func(row map[string]interface{}) {
+			ck = append(ck, asString(row["name"]))
+		})
+		if err != nil {
+			return nil, err
+		}
+		ckeystr = append(ckeystr, ck)
+	}
+	return ckeystr, nil
+}
+
+// pragma executes a sqlite PRAGMA with an argument, and calls f for each of
+// the resulting rows, keyed by column name.
+func pragma(db *sql.DB, name, arg string, f func(map[string]interface{})) error {
+	rows, err := db.Query("PRAGMA " + name + "(" + sqliteQuote(arg) + ")")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return err
+		}
+		row := make(map[string]interface{})
+		for i, col := range cols {
+			row[col] = values[i]
+		}
+		f(row)
+	}
+	return rows.Err()
+}
+
+// sqliteQuote quotes an identifier for sqlite
+func sqliteQuote(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// asInt converts an integer scanned from the driver to an int
+func asInt(v interface{}) int {
+	if n, ok := v.(int64); ok {
+		return int(n)
+	}
+	return 0
+}
+
+// asString converts a string scanned from the driver to a string
+func asString(v interface{}) string {
+	switch s := v.(type) {
+	case string:
+		return s
+	case []byte:
+		return string(s)
+	}
+	return ""
+}
+
+func init() {
+	// mattn's go-sqlite3, and modernc's sqlite
+	RegisterDialect("sqlite3", SQLite)
+	RegisterDialect("sqlite", SQLite)
+}
+
+// Percentile returns an empty string, because sqlite has no percentile
+// aggregates, so they are computed client side
+func (sqliteDialect) Percentile(col string, p float64, disc, approx bool) string {
+	return ""
+}
+
+// TruncateTime returns an empty string, because sqlite stores times as text
+// which its date functions can't return as timestamps, so times are truncated
+// client side
+func (sqliteDialect) TruncateTime(col string, unit TimeUnit) string {
+	return ""
+}
+
+// ReportsNullability returns false, because go-sqlite3 reports every column
+// as nullable
+func (sqliteDialect) ReportsNullability() bool {
+	return false
+}
+
+// EncodeUint returns an error, because sqlite integers have 64 bits and larger
+// numbers would be stored as inexact reals
+func (sqliteDialect) EncodeUint(v uint64) (interface{}, error) {
+	return nil, fmt.Errorf("relsql: %d overflows sqlite's integers", v)
+}
@@ -0,0 +1,121 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// test sqlite data source name validation
+func TestCheckSQLiteDSN(t *testing.T) {
+	var dsnTest = []struct {
+		dsn   string
+		isErr bool
+	}{
+		{"file::memory:?cache=shared", false},
+		{"file:test.db?cache=shared&mode=memory", false},
+		{"./test.db", false},
+		{":memory:", true},
+		{"file::memory:", true},
+		{"file:test.db?mode=memory", true},
+	}
+	for i, tt := range dsnTest {
+		if err := CheckSQLiteDSN(tt.dsn); (err != nil) != tt.isErr {
+			t.Errorf("%d has CheckSQLiteDSN(%q) => %v, want error %v", i, tt.dsn, err, tt.isErr)
+		}
+	}
+}
+
+// test candidate key inference from sqlite table metadata
+func TestSQLiteKeys(t *testing.T) {
+	const dsn = "file::memory:?cache=shared"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+	create table shipments (PNO integer not null, SNO integer not null, Tracking text not null, Qty integer, primary key (PNO, SNO));
+	create unique index shipments_tracking on shipments (Tracking);
+	insert into shipments (PNO, SNO, Tracking, Qty) values (1, 1, 'a', 300), (1, 2, 'b', 200);
+	`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	ckeystr, err := SQLiteKeys(db, "shipments")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	if want := [][]string{[]string{"PNO", "SNO"}, []string{"Tracking"}}; !reflect.DeepEqual(ckeystr, want) {
+		t.Errorf("SQLiteKeys() => %v, want %v", ckeystr, want)
+	}
+
+	type shipmentTup struct {
+		PNO int
+		SNO int
+		Qty int
+	}
+	shipments := NewSQLite(db, dsn, "shipments", shipmentTup{})
+	if err := shipments.Err(); err != nil {
+		t.Errorf("NewSQLite has Err() => %v", err)
+	}
+	if want := (rel.CandKeys{[]rel.Attribute{"PNO", "SNO"}}); !reflect.DeepEqual(shipments.CKeys(), want) {
+		t.Errorf("CKeys() => %v, want %v", shipments.CKeys(), want)
+	}
+	if card := rel.Card(shipments); card != 2 {
+		t.Errorf("Card() => %v, want %v", card, 2)
+	}
+	if err := NewSQLite(db, ":memory:", "shipments", shipmentTup{}).Err(); err == nil {
+		t.Errorf("NewSQLite with unshared memory dsn has Err() => nil")
+	}
+}
+
+// test that keys which can be NULL aren't inferred, and are logged when they
+// are declared
+func TestNullableKeys(t *testing.T) {
+	dsn := "file:nullablekeys?mode=memory&cache=shared"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+	create table accounts (ID integer primary key, Email text, Login text not null);
+	create unique index accounts_email on accounts (Email);
+	create unique index accounts_login on accounts (Login);
+	insert into accounts (ID, Email, Login) values (1, null, 'a'), (2, null, 'b');
+	`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	type accountTup struct {
+		ID    int
+		Email *string
+		Login string
+	}
+	accounts := NewSQLite(db, dsn, "accounts", accountTup{})
+	if want := (rel.CandKeys{[]rel.Attribute{"ID"}, []rel.Attribute{"Login"}}); !reflect.DeepEqual(accounts.CKeys(), want) {
+		t.Errorf("CKeys() => %v, want %v", accounts.CKeys(), want)
+	}
+
+	var logs []string
+	logf := func(format string, args ...interface{}) {
+		logs = append(logs, fmt.Sprintf(format, args...))
+	}
+	New(db, "accounts", accountTup{}, [][]string{[]string{"Email"}, []string{"ID"}}, WithLogger(logf))
+	if len(logs) != 1 || !strings.Contains(logs[0], "attribute Email of accounts can be NULL") {
+		t.Errorf("New() logged %q, want a nullable Email", logs)
+	}
+}
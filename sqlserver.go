@@ -0,0 +1,111 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// SQLServer is the dialect for Microsoft SQL Server.  Arguments are bound to
+// named parameters @p1, @p2, and so on, rows are limited with TOP, lateral
+// joins are written with CROSS APPLY, and optimizer hints are attached in an
+// OPTION clause.
+var SQLServer Dialect = sqlServerDialect{}
+
+// sqlServerDialect is the dialect for SQL Server
+type sqlServerDialect struct{}
+
+// Name returns the name of the dialect
+func (sqlServerDialect) Name() string {
+	return "sqlserver"
+}
+
+// Placeholder returns the named parameter for the i'th argument of a query
+func (sqlServerDialect) Placeholder(i int) string {
+	return fmt.Sprintf("@p%d", i)
+}
+
+// ReadTx returns true, because a read of several statements needs a
+// transaction to be consistent.
+func (sqlServerDialect) ReadTx() bool {
+	return true
+}
+
+// NamedArgs returns true, because SQL Server's placeholders are named
+func (sqlServerDialect) NamedArgs() bool {
+	return true
+}
+
+// Limit limits the query to its first n rows with TOP, because FETCH FIRST
+// requires an ORDER BY in SQL Server.
+func (sqlServerDialect) Limit(query string, n int) string {
+	return fmt.Sprintf("SELECT TOP (%d) * FROM (%s) AS l", n, query)
+}
+
+// LateralJoin returns CROSS APPLY
+func (sqlServerDialect) LateralJoin() string {
+	return "CROSS APPLY"
+}
+
+// HintStyle returns HintOption, because SQL Server reads query hints from an
+// OPTION clause
+func (sqlServerDialect) HintStyle() HintStyle {
+	return HintOption
+}
+
+// TruncateTime adds the whole number of units between the start of 1900 and
+// the time back to the start of 1900, because DATETRUNC is only in SQL Server
+// 2022.  1900-01-01 was a Monday, so weeks are counted as seven days from it,
+// which doesn't depend on DATEFIRST.
+func (sqlServerDialect) TruncateTime(col string, unit TimeUnit) string {
+	switch unit {
+	case Hour, Day, Month:
+		part := string(unit)
+		return "DATEADD(" + part + ", DATEDIFF(" + part + ", 0, " + col + "), 0)"
+	case Week:
+		return "DATEADD(day, DATEDIFF(day, 0, " + col + ") / 7 * 7, 0)"
+	}
+	return ""
+}
+
+// TypeName returns SQL Server's names for the column types of Go types which
+// don't have the ANSI name.  Its TIMESTAMP is a row version, not a time.
+func (sqlServerDialect) TypeName(t reflect.Type) string {
+	switch t {
+	case timeType:
+		return "DATETIME2"
+	case lazyType:
+		return "VARBINARY(MAX)"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "BIT"
+	case reflect.String:
+		return "NVARCHAR(MAX)"
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "VARBINARY(MAX)"
+		}
+	}
+	return ""
+}
+
+// AlterColumnType returns ALTER COLUMN without TYPE.  It redefines the whole
+// column, so NOT NULL is repeated.
+func (sqlServerDialect) AlterColumnType(tableName, colName, typeName string, nullable bool) string {
+	stmt := "ALTER TABLE " + tableName + " ALTER COLUMN " + colName + " " + typeName
+	if !nullable {
+		stmt += " NOT NULL"
+	}
+	return stmt
+}
+
+// Features declares TABLESAMPLE
+func (sqlServerDialect) Features() map[Feature]bool {
+	return map[Feature]bool{FeatureTableSample: true}
+}
+
+func init() {
+	// microsoft's go-mssqldb registers both names
+	RegisterDialect("sqlserver", SQLServer)
+	RegisterDialect("mssql", SQLServer)
+}
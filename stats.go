@@ -0,0 +1,121 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the statistics of the queries that relsql has executed on a
+// database, along with the statistics of the database's connection pool.
+// Streams hold a connection until their consumer has read every tuple, or
+// stops the read, so a pool that is exhausted while ActiveStreams is close
+// to InUse usually has consumers that are slow, or that don't stop reading.
+type Stats struct {
+	// DB are the statistics of the connection pool
+	DB sql.DBStats
+
+	// Queries is the number of queries that have been executed
+	Queries int64
+
+	// Errors is the number of queries that failed
+	Errors int64
+
+	// ActiveStreams is the number of queries whose rows are being read
+	ActiveStreams int64
+
+	// Rows is the number of tuples that have been sent to consumers
+	Rows int64
+
+	// StreamDuration is the total time that queries have taken, from when
+	// they were executed until their rows were closed
+	StreamDuration time.Duration
+}
+
+// String returns a one line summary of the statistics, for logs
+func (s Stats) String() string {
+	return fmt.Sprintf("queries=%d errors=%d active=%d rows=%d stream_time=%v open=%d/%d in_use=%d idle=%d waits=%d wait_time=%v",
+		s.Queries, s.Errors, s.ActiveStreams, s.Rows, s.StreamDuration, s.DB.OpenConnections, s.DB.MaxOpenConnections,
+		s.DB.InUse, s.DB.Idle, s.DB.WaitCount, s.DB.WaitDuration)
+}
+
+// add adds the statistics of another database
+func (s *Stats) add(s2 Stats) {
+	s.DB.MaxOpenConnections += s2.DB.MaxOpenConnections
+	s.DB.OpenConnections += s2.DB.OpenConnections
+	s.DB.InUse += s2.DB.InUse
+	s.DB.Idle += s2.DB.Idle
+	s.DB.WaitCount += s2.DB.WaitCount
+	s.DB.WaitDuration += s2.DB.WaitDuration
+	s.DB.MaxIdleClosed += s2.DB.MaxIdleClosed
+	s.DB.MaxIdleTimeClosed += s2.DB.MaxIdleTimeClosed
+	s.DB.MaxLifetimeClosed += s2.DB.MaxLifetimeClosed
+	s.Queries += s2.Queries
+	s.Errors += s2.Errors
+	s.ActiveStreams += s2.ActiveStreams
+	s.Rows += s2.Rows
+	s.StreamDuration += s2.StreamDuration
+}
+
+// dbMetrics counts the queries executed on a database
+type dbMetrics struct {
+	queries, errors, active, rows, nanos int64
+}
+
+// start records the execution of a query
+func (m *dbMetrics) start() {
+	if m == nil {
+		return
+	}
+	atomic.AddInt64(&m.queries, 1)
+	atomic.AddInt64(&m.active, 1)
+}
+
+// finish records the end of a query started at start, which sent rows
+// tuples
+func (m *dbMetrics) finish(start time.Time, rows int, err error) {
+	if m == nil {
+		return
+	}
+	atomic.AddInt64(&m.active, -1)
+	atomic.AddInt64(&m.rows, int64(rows))
+	atomic.AddInt64(&m.nanos, int64(time.Since(start)))
+	if err != nil {
+		atomic.AddInt64(&m.errors, 1)
+	}
+}
+
+// DatabaseStats returns the statistics of the queries that relsql has
+// executed on a database, and of its connection pool.
+func DatabaseStats(db *sql.DB) Stats {
+	s := Stats{DB: db.Stats()}
+	if m := loadMetrics(db); m != nil {
+		s.Queries = atomic.LoadInt64(&m.queries)
+		s.Errors = atomic.LoadInt64(&m.errors)
+		s.ActiveStreams = atomic.LoadInt64(&m.active)
+		s.Rows = atomic.LoadInt64(&m.rows)
+		s.StreamDuration = time.Duration(atomic.LoadInt64(&m.nanos))
+	}
+	return s
+}
+
+// RelationStats returns the sum of the statistics of the databases that a
+// relation reads from.  They include the queries of every relation on those
+// databases, not only of r.  Relations on a single connection, or from
+// NewQueryer, have no database, so they aren't included.
+func RelationStats(r rel.Relation) Stats {
+	var s Stats
+	seen := make(map[*sql.DB]bool)
+	Inspect(r, func(n Node) bool {
+		if q, ok := n.(*Query); ok {
+			if db := q.Relation.(*sqlTable).db; db != nil && !seen[db] {
+				seen[db] = true
+				s.add(DatabaseStats(db))
+			}
+		}
+		return true
+	})
+	return s
+}
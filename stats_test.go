@@ -0,0 +1,81 @@
+package relsql
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test the statistics of databases and relations
+func TestStats(t *testing.T) {
+	var dbs []*sql.DB
+	for _, dsn := range []string{"file:stats1?mode=memory&cache=shared", "file:stats2?mode=memory&cache=shared"} {
+		db, err := sql.Open("sqlite3", dsn)
+		if err != nil {
+			t.Errorf(err.Error())
+			return
+		}
+		defer db.Close()
+		if _, err := db.Exec(`CREATE TABLE nums (N INTEGER PRIMARY KEY);
+			INSERT INTO nums VALUES (1), (2), (3)`); err != nil {
+			t.Errorf(err.Error())
+			return
+		}
+		dbs = append(dbs, db)
+	}
+	type numTup struct {
+		N int
+	}
+	keys := [][]string{[]string{"N"}}
+	nums1 := New(dbs[0], "nums", numTup{}, keys)
+	nums2 := New(dbs[1], "nums", numTup{}, keys)
+
+	if s := DatabaseStats(dbs[0]); s.Queries != 0 || s.Rows != 0 || s.DB.OpenConnections == 0 {
+		t.Errorf("DatabaseStats() before reading => %+v", s)
+	}
+
+	// a stream that isn't read holds its connection
+	ch := make(chan numTup)
+	cancel := nums1.TupleChan(ch)
+	<-ch
+	waitStats(t, dbs[0], func(s Stats) bool { return s.ActiveStreams == 1 && s.DB.InUse == 1 })
+	close(cancel)
+	waitStats(t, dbs[0], func(s Stats) bool { return s.ActiveStreams == 0 && s.DB.InUse == 0 })
+
+	if err := drainErr(nums1); err != nil {
+		t.Errorf("drainErr() => %v", err)
+	}
+	if err := drainErr(New(dbs[0], "missing", numTup{}, keys)); err == nil {
+		t.Errorf("reading a missing table has no error")
+	}
+	s := DatabaseStats(dbs[0])
+	if s.Queries != 3 || s.Errors != 1 || s.ActiveStreams != 0 || s.Rows < 4 || s.StreamDuration <= 0 {
+		t.Errorf("DatabaseStats() => %+v", s)
+	}
+	if str := s.String(); !strings.HasPrefix(str, "queries=3 errors=1 active=0 ") {
+		t.Errorf("String() => %q", str)
+	}
+
+	if err := drainErr(nums2); err != nil {
+		t.Errorf("drainErr() => %v", err)
+	}
+	rs := RelationStats(nums1.Join(nums2, numTup{}))
+	if rs.Queries != 4 || rs.Errors != 1 || rs.Rows != s.Rows+3 || rs.DB.OpenConnections < 2 {
+		t.Errorf("RelationStats() => %+v", rs)
+	}
+	if rs := RelationStats(nums2.Restrict(Attribute("N").GT(1))); rs.Queries != 1 || rs.Rows != 3 {
+		t.Errorf("RelationStats() of one database => %+v", rs)
+	}
+}
+
+// waitStats waits until the statistics of a database satisfy ok
+func waitStats(t *testing.T, db *sql.DB, ok func(Stats) bool) {
+	for i := 0; i < 200; i++ {
+		if ok(DatabaseStats(db)) {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Errorf("DatabaseStats() => %+v", DatabaseStats(db))
+}
@@ -0,0 +1,90 @@
+package relsql
+
+import (
+	"sync"
+	"time"
+)
+
+// Throttle limits the rate of an event, like reading a tuple or executing a
+// query, with a token bucket.  Relations that share a throttle share its rate,
+// so one throttle passed to every relation on a database limits the load that
+// all of them put on it.
+type Throttle struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewThrottle creates a throttle that allows n events per interval on
+// average, and bursts of up to burst events at once, which it starts with.
+// For example, NewThrottle(1000, time.Second, 100) allows 1000 tuples per
+// second in bursts of 100, and NewThrottle(10, time.Minute, 1) allows a
+// query every 6 seconds.
+func NewThrottle(n int, per time.Duration, burst int) *Throttle {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Throttle{
+		rate:   float64(n) / per.Seconds(),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// reserve takes a token, and returns how long to wait before the event it is
+// for may happen.
+func (t *Throttle) reserve() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+	t.last = now
+	t.tokens--
+	if t.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-t.tokens / t.rate * float64(time.Second))
+}
+
+// Wait blocks until an event is allowed, and returns true, or returns false
+// if cancel is closed first.  A nil throttle allows every event.
+func (t *Throttle) Wait(cancel <-chan struct{}) bool {
+	if t == nil {
+		return true
+	}
+	d := t.reserve()
+	if d <= 0 {
+		return true
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-cancel:
+		return false
+	}
+}
+
+// WithRowThrottle limits the rate that the relation sends tuples, so that a
+// background job reading a large table doesn't starve other queries of the
+// database.  The query's rows are read no faster than the tuples are sent.
+func WithRowThrottle(t *Throttle) Option {
+	return func(o *options) {
+		o.rowThrottle = t
+	}
+}
+
+// WithQueryThrottle limits the rate that the relation executes queries,
+// including retries.
+func WithQueryThrottle(t *Throttle) Option {
+	return func(o *options) {
+		o.queryThrottle = t
+	}
+}
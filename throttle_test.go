@@ -0,0 +1,90 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"testing"
+	"time"
+)
+
+// test the token bucket of a throttle
+func TestThrottle(t *testing.T) {
+	var throttleTest = []struct {
+		n, burst int
+		per      time.Duration
+		events   int
+		min      time.Duration
+	}{
+		{100, 5, time.Second, 5, 0},
+		{100, 5, time.Second, 8, 30 * time.Millisecond},
+		{600, 1, time.Minute, 3, 200 * time.Millisecond},
+	}
+	for i, tt := range throttleTest {
+		th := NewThrottle(tt.n, tt.per, tt.burst)
+		start := time.Now()
+		for j := 0; j < tt.events; j++ {
+			th.Wait(nil)
+		}
+		if d := time.Since(start); d < tt.min || d > tt.min+200*time.Millisecond {
+			t.Errorf("%d has %d events in %v, want %v", i, tt.events, d, tt.min)
+		}
+	}
+
+	th := NewThrottle(1, time.Hour, 1)
+	th.Wait(nil)
+	cancel := make(chan struct{})
+	close(cancel)
+	if th.Wait(cancel) {
+		t.Errorf("Wait() with a closed cancel => true, want false")
+	}
+	if !(*Throttle)(nil).Wait(nil) {
+		t.Errorf("Wait() of a nil throttle => false, want true")
+	}
+}
+
+// test throttling the tuples and queries of relations
+func TestThrottledRelation(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:throttle?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type throttleTup struct {
+		Name string
+	}
+	if err := CreateTable(db, "throttled", throttleTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "throttled", rel.New([]throttleTup{{"a"}, {"b"}, {"c"}, {"d"}}, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	read := func(r rel.Relation) (int, time.Duration) {
+		start := time.Now()
+		ch := make(chan throttleTup)
+		r.TupleChan(ch)
+		n := 0
+		for range ch {
+			n++
+		}
+		return n, time.Since(start)
+	}
+
+	rows := New(db, "throttled", throttleTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithRowThrottle(NewThrottle(50, time.Second, 1)))
+	if n, d := read(rows); n != 4 || d < 60*time.Millisecond {
+		t.Errorf("row throttled read => %d tuples in %v, want 4 in at least 60ms", n, d)
+	}
+
+	// relations that share a throttle share its rate
+	th := NewThrottle(20, time.Second, 1)
+	queries := New(db, "throttled", throttleTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithQueryThrottle(th))
+	start := time.Now()
+	read(queries)
+	read(queries.Restrict(Attribute("Name").EQ("a")))
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("query throttled reads => %v, want at least 50ms", d)
+	}
+}
@@ -0,0 +1,94 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"time"
+)
+
+// TimeUnit is the length of the time buckets of TimeBuckets
+type TimeUnit string
+
+// The units of time that TimeBuckets can truncate times to.  Weeks start on
+// Monday.
+const (
+	Hour  TimeUnit = "hour"
+	Day   TimeUnit = "day"
+	Week  TimeUnit = "week"
+	Month TimeUnit = "month"
+)
+
+// timeTruncater is implemented by dialects whose function for truncating a
+// timestamp differs from DATE_TRUNC.  TruncateTime returns the expression, or
+// an empty string if the dialect can't truncate times in the database, in
+// which case the buckets are computed client side.
+type timeTruncater interface {
+	TruncateTime(col string, unit TimeUnit) string
+}
+
+// truncateTimeString returns the dialect's expression that truncates the
+// column to the unit, which is DATE_TRUNC by default.
+func truncateTimeString(d Dialect, col string, unit TimeUnit) string {
+	if tt, ok := d.(timeTruncater); ok {
+		return tt.TruncateTime(col, unit)
+	}
+	return "DATE_TRUNC('" + string(unit) + "', " + col + ")"
+}
+
+// truncateTime truncates a time to the start of the unit that holds it, in
+// the time's location.
+func truncateTime(t time.Time, unit TimeUnit) time.Time {
+	y, m, d := t.Date()
+	switch unit {
+	case Hour:
+		return time.Date(y, m, d, t.Hour(), 0, 0, 0, t.Location())
+	case Day:
+		return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+	case Week:
+		return time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	}
+	return time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+}
+
+// TimeBuckets groups the tuples of r by the time attribute att truncated to
+// the unit, such as the day of an event's timestamp.  Each tuple of the
+// result has the start of the bucket in the attribute named as, and the
+// aggregates of the tuples in the bucket.  zero is the type of the resulting
+// tuples, whose attribute as is a time.Time.  Buckets without any tuples are
+// left out.
+//
+// If r is from this package, and its dialect can truncate times, the buckets
+// are computed with the dialect's equivalent of DATE_TRUNC in a GROUP BY
+// query.
+func TimeBuckets(r rel.Relation, att string, unit TimeUnit, as string, zero interface{}, aggs ...Agg) rel.Relation {
+	e1, e2 := reflect.TypeOf(r.Zero()), reflect.TypeOf(zero)
+	fail := func(err error) rel.Relation {
+		return &sqlTable{zero: zero, cKeys: rel.DefaultKeys(zero), err: err}
+	}
+	if err := checkZero(e2); err != nil {
+		return fail(err)
+	}
+	if f, ok := e1.FieldByName(att); !ok || f.Type != timeType {
+		return fail(fmt.Errorf("relsql: %s is not a time attribute of %v", att, e1))
+	}
+	switch unit {
+	case Hour, Day, Week, Month:
+	default:
+		return fail(fmt.Errorf("relsql: unknown time unit %q", unit))
+	}
+	if f, ok := e2.FieldByName(as); !ok || f.Type != timeType {
+		return fail(fmt.Errorf("relsql: bucket attribute %s is not a time attribute of %v", as, e2))
+	}
+	if _, ok := e1.FieldByName(as); ok {
+		return fail(fmt.Errorf("relsql: bucket attribute %s is already in the heading of %v", as, e1))
+	}
+	var expr string
+	if r1, ok := r.(*sqlTable); ok {
+		expr = truncateTimeString(r1.dialect(), att, unit)
+	}
+	b := binned(r, as, timeType, expr, fmt.Sprintf("trunc{%s; %s}", att, unit), func(tup, res reflect.Value) {
+		res.Set(reflect.ValueOf(truncateTime(tup.FieldByName(att).Interface().(time.Time), unit)))
+	})
+	return Summarize(b, []string{as}, zero, aggs...)
+}
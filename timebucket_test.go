@@ -0,0 +1,108 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test grouping by truncated times client side, and the sql of the dialects
+// that truncate times in the database
+func TestTimeBuckets(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:timebuckets?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type eventTup struct {
+		ID int
+		At time.Time
+	}
+	type bucketTup struct {
+		Start time.Time
+		N     int
+	}
+	keys := [][]string{[]string{"ID"}}
+	at := func(day, hour int) time.Time {
+		return time.Date(2024, 1, day, hour, 30, 0, 0, time.UTC)
+	}
+	// January 1st 2024 is a Monday
+	events := []eventTup{{1, at(1, 1)}, {2, at(1, 1)}, {3, at(1, 5)}, {4, at(7, 23)}, {5, at(8, 0)}}
+	if err := CreateTable(db, "events", eventTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "events", rel.New(events, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "events", eventTup{}, keys, WithDialect(SQLite))
+	mem := rel.New(events, keys)
+
+	var timeBucketsTest = []struct {
+		r    rel.Relation
+		unit TimeUnit
+		want string
+	}{
+		{table, Hour, "[01T01:00 2 01T05:00 1 07T23:00 1 08T00:00 1]"},
+		{mem, Hour, "[01T01:00 2 01T05:00 1 07T23:00 1 08T00:00 1]"},
+		{table, Day, "[01T00:00 3 07T00:00 1 08T00:00 1]"},
+		{table, Week, "[01T00:00 4 08T00:00 1]"},
+		{mem, Month, "[01T00:00 5]"},
+	}
+	for i, tt := range timeBucketsTest {
+		r := TimeBuckets(tt.r, "At", tt.unit, "Start", bucketTup{}, Count("N"))
+		ch := make(chan bucketTup)
+		r.TupleChan(ch)
+		var res []bucketTup
+		for tup := range ch {
+			res = append(res, tup)
+		}
+		sort.Slice(res, func(i, j int) bool { return res[i].Start.Before(res[j].Start) })
+		if err := r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+		var strs []string
+		for _, tup := range res {
+			strs = append(strs, tup.Start.UTC().Format("02T15:04"), fmt.Sprint(tup.N))
+		}
+		if fmt.Sprint(strs) != tt.want {
+			t.Errorf("%d has buckets %v, want %s", i, strs, tt.want)
+		}
+	}
+
+	var sqlTest = []struct {
+		d    Dialect
+		want string
+	}{
+		{ANSI, "DATE_TRUNC('week', At)"},
+		{Oracle, "TRUNC(At, 'IW')"},
+		{BigQuery, "TIMESTAMP_TRUNC(At, ISOWEEK)"},
+		{MySQL, "CAST(DATE(At) - INTERVAL WEEKDAY(At) DAY AS DATETIME)"},
+		{SQLServer, "DATEADD(day, DATEDIFF(day, 0, At) / 7 * 7, 0)"},
+	}
+	for i, tt := range sqlTest {
+		r := TimeBuckets(New(db, "events", eventTup{}, keys, WithDialect(tt.d)), "At", Week, "Start", bucketTup{}, Count("N"))
+		q, _, err := SQL(r)
+		if err != nil || !strings.Contains(q, tt.want+" AS Start") || !strings.Contains(q, "GROUP BY Start") {
+			t.Errorf("%d has SQL() => %q, %v, want %s", i, q, err, tt.want)
+		}
+	}
+
+	for i, r := range []rel.Relation{
+		TimeBuckets(table, "ID", Day, "Start", bucketTup{}, Count("N")),
+		TimeBuckets(table, "At", "fortnight", "Start", bucketTup{}, Count("N")),
+		TimeBuckets(table, "At", Day, "N", bucketTup{}, Count("Start")),
+		TimeBuckets(table, "At", Day, "At", bucketTup{}, Count("N")),
+	} {
+		if r.Err() == nil {
+			t.Errorf("%d has Err() => nil, want an error", i)
+		}
+	}
+}
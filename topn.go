@@ -0,0 +1,197 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OrderBy is an attribute that tuples are ordered by, in descending order if
+// Desc is true.
+type OrderBy struct {
+	Attribute string
+	Desc      bool
+}
+
+// String returns the sql form of the ordering
+func (o OrderBy) String() string {
+	if o.Desc {
+		return o.Attribute + " DESC"
+	}
+	return o.Attribute
+}
+
+// TopN creates a relation with the first n tuples of each group of r, in the
+// order given by order.  Tuples are grouped by the values of the group
+// attributes, and with no group attributes the whole relation is one group.
+// Ties are broken by r's first candidate key, so the result is
+// deterministic.  If r is from this package, it is compiled into a query with
+// a ROW_NUMBER window function, and otherwise it is evaluated client side.
+func TopN(r rel.Relation, n int, group []string, order ...OrderBy) rel.Relation {
+	e := reflect.TypeOf(r.Zero())
+	fail := func(err error) rel.Relation {
+		return &sqlTable{zero: r.Zero(), cKeys: r.CKeys(), err: err}
+	}
+	if n < 1 {
+		return fail(fmt.Errorf("relsql: top %d of %v is empty", n, r))
+	}
+	if len(order) == 0 {
+		return fail(fmt.Errorf("relsql: top %d of %v has no order", n, r))
+	}
+	for _, att := range group {
+		if _, ok := e.FieldByName(att); !ok {
+			return fail(fmt.Errorf("relsql: group attribute %s is not in the heading of %v", att, e))
+		}
+	}
+	for _, o := range order {
+		if _, ok := e.FieldByName(o.Attribute); !ok {
+			return fail(fmt.Errorf("relsql: order attribute %s is not in the heading of %v", o.Attribute, e))
+		}
+	}
+
+	order = breakTies(order, r.CKeys())
+
+	atts := append(append([]string{}, group...), orderAttributes(order)...)
+	if r1, ok := r.(*sqlTable); ok && r1.err == nil && r1.composable() && Supports(r1.dialect(), FeatureWindowFunctions) && !r1.touchesEncrypted(atts...) {
+		return &sqlTable{
+			db:             r1.db,
+			conn:           r1.conn,
+			q:              r1.q,
+			src:            &topNSource{r1, n, group, order},
+			cols:           colNames(r1.zero),
+			zero:           r1.zero,
+			cKeys:          r1.cKeys,
+			sourceDistinct: true,
+			opts:           r1.opts,
+		}
+	}
+	return &clientOp{
+		op:     "TopN",
+		str:    fmt.Sprintf("top%d{%s}(%v)", n, strings.Join(group, ", "), r),
+		inputs: []rel.Relation{r},
+		zero:   r.Zero(),
+		cKeys:  r.CKeys(),
+		run: func(send func(reflect.Value) error) error {
+			return topN(r, n, group, order, send)
+		},
+	}
+}
+
+// breakTies returns the ordering followed by the attributes of the first
+// candidate key that it doesn't already include, so that no two distinct
+// tuples are tied.
+func breakTies(order []OrderBy, cKeys rel.CandKeys) []OrderBy {
+	order = append([]OrderBy(nil), order...)
+	if len(cKeys) == 0 {
+		return order
+	}
+	for _, att := range cKeys[0] {
+		found := false
+		for _, o := range order {
+			found = found || o.Attribute == string(att)
+		}
+		if !found {
+			order = append(order, OrderBy{Attribute: string(att)})
+		}
+	}
+	return order
+}
+
+// orderString returns the ORDER BY list of the ordering
+func orderString(order []OrderBy) string {
+	strs := make([]string, len(order))
+	for i, o := range order {
+		strs[i] = o.String()
+	}
+	return strings.Join(strs, ", ")
+}
+
+// topNSource is the first n rows of each group of a relation
+type topNSource struct {
+	r     *sqlTable
+	n     int
+	group []string
+	order []OrderBy
+}
+
+// build numbers the rows of each group with ROW_NUMBER, and keeps the first n
+// of them.
+func (t *topNSource) build(b *builder, needed map[column]bool) string {
+	n := neededAttributes(needed, "")
+	if n != nil {
+		for _, att := range t.group {
+			n[rel.Attribute(att)] = true
+		}
+		for _, o := range t.order {
+			n[rel.Attribute(o.Attribute)] = true
+		}
+	}
+	over := ""
+	if len(t.group) > 0 {
+		over = "PARTITION BY " + strings.Join(t.group, ", ") + " "
+	}
+	over += "ORDER BY " + orderString(t.order)
+	inner := "SELECT w.*, ROW_NUMBER() OVER (" + over + ") AS relsql_rn FROM (" + t.r.build(b, n, true) + ")" + b.alias("w")
+	return "(SELECT * FROM (" + inner + ")" + b.alias("r") + " WHERE relsql_rn <= " + strconv.Itoa(t.n) + ")" + b.alias("n")
+}
+
+// String returns a text representation of the operation
+func (t *topNSource) String() string {
+	return fmt.Sprintf("top%d{%s}(%s)", t.n, strings.Join(t.group, ", "), t.r.expr())
+}
+
+// topN reads the tuples of r, and sends the first n of each group
+func topN(r rel.Relation, n int, group []string, order []OrderBy, send func(reflect.Value) error) error {
+	groups := make(map[string][]reflect.Value)
+	var keys []string
+	err := forEach(r, func(tup reflect.Value) error {
+		vals := make([]interface{}, len(group))
+		for i, att := range group {
+			vals[i] = tup.FieldByName(att).Interface()
+		}
+		k := fmt.Sprintf("%#v", vals)
+		if _, ok := groups[k]; !ok {
+			keys = append(keys, k)
+		}
+		groups[k] = append(groups[k], tup)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		tups := groups[k]
+		sort.Slice(tups, func(i, j int) bool {
+			return compareTuples(tups[i], tups[j], order) < 0
+		})
+		if len(tups) > n {
+			tups = tups[:n]
+		}
+		for _, tup := range tups {
+			if err := send(tup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// compareTuples compares two tuples by the ordering, returning -1, 0 or 1 as
+// a is before, tied with, or after b.  Values that can't be compared are
+// tied.
+func compareTuples(a, b reflect.Value, order []OrderBy) int {
+	for _, o := range order {
+		c, ok := compareValues(a.FieldByName(o.Attribute).Interface(), b.FieldByName(o.Attribute).Interface())
+		if !ok || c == 0 {
+			continue
+		}
+		if o.Desc {
+			return -c
+		}
+		return c
+	}
+	return 0
+}
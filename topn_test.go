@@ -0,0 +1,83 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"sort"
+	"testing"
+)
+
+// test the first tuples of each group, in the database and client side
+func TestTopN(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:topn?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type saleTup struct {
+		Region string
+		Rep    string
+		Amount int
+	}
+	keys := [][]string{[]string{"Rep"}}
+	sales := []saleTup{
+		{"east", "ann", 10}, {"east", "bob", 30}, {"east", "cy", 20},
+		{"west", "dee", 5}, {"west", "eve", 5}, {"west", "fay", 1},
+	}
+	if err := CreateTable(db, "sales", saleTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "sales", rel.New(sales, keys)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "sales", saleTup{}, keys, WithDialect(SQLite))
+	mem := rel.New(sales, keys)
+	byAmount := OrderBy{"Amount", true}
+
+	var topNTest = []struct {
+		name string
+		r    rel.Relation
+		want string
+	}{
+		{"table", TopN(table, 2, []string{"Region"}, byAmount), "[bob cy dee eve]"},
+		{"memory", TopN(mem, 2, []string{"Region"}, byAmount), "[bob cy dee eve]"},
+		{"table", TopN(table, 1, nil, OrderBy{"Amount", false}), "[fay]"},
+		{"memory", TopN(mem, 1, nil, OrderBy{"Amount", false}), "[fay]"},
+		{"table", TopN(table, 1, []string{"Region"}, byAmount).Restrict(Attribute("Region").EQ("west")), "[dee]"},
+		{"memory", TopN(mem, 1, []string{"Region"}, byAmount).Restrict(rel.Attribute("Region").EQ("west")), "[dee]"},
+	}
+	for i, tt := range topNTest {
+		if _, ok := tt.r.(*sqlTable); ok != (tt.name == "table") {
+			t.Errorf("%d has TopN() => %T", i, tt.r)
+		}
+		ch := make(chan saleTup)
+		tt.r.TupleChan(ch)
+		var reps []string
+		for tup := range ch {
+			reps = append(reps, tup.Rep)
+		}
+		sort.Strings(reps)
+		if err := tt.r.Err(); err != nil {
+			t.Errorf("%d has Err() => %v", i, err)
+		}
+		if fmt.Sprint(reps) != tt.want {
+			t.Errorf("%d %s has reps %v, want %s", i, tt.name, reps, tt.want)
+		}
+	}
+
+	for i, r := range []rel.Relation{
+		TopN(table, 0, nil, byAmount),
+		TopN(table, 1, nil),
+		TopN(table, 1, []string{"City"}, byAmount),
+		TopN(table, 1, nil, OrderBy{"Price", false}),
+	} {
+		if r.Err() == nil {
+			t.Errorf("%d has Err() => nil, want an error", i)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package relsql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Transformer converts the values of an attribute as they are read from and
+// written to the database, which keeps data cleaning with the definition of
+// a relation.  Read is applied to each scanned value, and Write to each value
+// before it is written.  Predicates that are pushed down compare the values
+// stored in the database.
+type Transformer interface {
+	Read(v interface{}) (interface{}, error)
+	Write(v interface{}) (interface{}, error)
+}
+
+// TransformFunc is a Transformer which applies the same function when
+// reading and writing.
+type TransformFunc func(v interface{}) (interface{}, error)
+
+// Read applies the function to a scanned value
+func (f TransformFunc) Read(v interface{}) (interface{}, error) {
+	return f(v)
+}
+
+// Write applies the function to a value that is going to be written
+func (f TransformFunc) Write(v interface{}) (interface{}, error) {
+	return f(v)
+}
+
+// stringTransform returns a TransformFunc that applies f to strings, and
+// leaves other values alone.
+func stringTransform(f func(string) string) TransformFunc {
+	return func(v interface{}) (interface{}, error) {
+		rv := reflect.ValueOf(v)
+		if rv.Kind() != reflect.String {
+			return v, nil
+		}
+		return reflect.ValueOf(f(rv.String())).Convert(rv.Type()).Interface(), nil
+	}
+}
+
+// transformers holds the named transformers, which are used in struct tags
+var transformers = struct {
+	sync.RWMutex
+	m map[string]Transformer
+}{m: map[string]Transformer{
+	"trim":  stringTransform(strings.TrimSpace),
+	"lower": stringTransform(strings.ToLower),
+	"upper": stringTransform(strings.ToUpper),
+}}
+
+// RegisterTransformer names a transformer so that it can be applied to
+// attributes with a struct tag, like `relsql:"trim,lower"`.  The trim, lower
+// and upper transformers are registered by default.
+func RegisterTransformer(name string, t Transformer) {
+	transformers.Lock()
+	defer transformers.Unlock()
+	transformers.m[name] = t
+}
+
+// WithTransform applies a transformer to an attribute, after any that are
+// given in the attribute's struct tag.
+func WithTransform(att string, t Transformer) Option {
+	return func(o *options) {
+		o.transforms = append(o.transforms, transform{att, t})
+	}
+}
+
+// transform is a transformer applied to an attribute by an option
+type transform struct {
+	att string
+	t   Transformer
+}
+
+// fieldTransforms holds the transformers for each field of a tuple, which
+// are applied in order when reading, and in reverse order when writing.
+type fieldTransforms [][]Transformer
+
+// fieldTransforms returns the transformers for tuples of type e read from or
+// written to the columns, or nil if there are none.  Like enums, options
+// refer to attributes by column name.
+func (o *options) fieldTransforms(e reflect.Type, cols []column) (fieldTransforms, error) {
+	var res fieldTransforms
+	for i, col := range cols {
+		var ts []Transformer
+		for _, tr := range o.ciphers {
+			if tr.att == col.name {
+				ts = append(ts, tr.t)
+			}
+		}
+		if tag := e.Field(i).Tag.Get("relsql"); tag != "" {
+			transformers.RLock()
+			for _, name := range strings.Split(tag, ",") {
+				t, ok := transformers.m[strings.TrimSpace(name)]
+				if !ok {
+					transformers.RUnlock()
+					return nil, fmt.Errorf("relsql: unknown transformer %q for %s", name, col.name)
+				}
+				ts = append(ts, t)
+			}
+			transformers.RUnlock()
+		}
+		for _, tr := range o.transforms {
+			if tr.att == col.name {
+				ts = append(ts, tr.t)
+			}
+		}
+		if ts != nil && res == nil {
+			res = make(fieldTransforms, len(cols))
+		}
+		if ts != nil {
+			res[i] = ts
+		}
+	}
+	return res, nil
+}
+
+// read applies the read transformers to the fields of a scanned tuple
+func (ft fieldTransforms) read(tup reflect.Value) error {
+	for i, ts := range ft {
+		if ts == nil {
+			continue
+		}
+		f := tup.Field(i)
+		v := f.Interface()
+		for _, t := range ts {
+			var err error
+			if v, err = t.Read(v); err != nil {
+				return err
+			}
+		}
+		rv := reflect.ValueOf(v)
+		if !rv.IsValid() || !rv.Type().ConvertibleTo(f.Type()) {
+			return fmt.Errorf("relsql: transformer returned %T for field of type %v", v, f.Type())
+		}
+		f.Set(rv.Convert(f.Type()))
+	}
+	return nil
+}
+
+// write applies the write transformers of the i'th field to a value
+func (ft fieldTransforms) write(i int, v interface{}) (interface{}, error) {
+	if ft == nil {
+		return v, nil
+	}
+	ts := ft[i]
+	for j := len(ts) - 1; j >= 0; j-- {
+		var err error
+		if v, err = ts[j].Write(v); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
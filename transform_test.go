@@ -0,0 +1,69 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// test transformers on write and scan
+func TestTransform(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type userTup struct {
+		ID    int
+		Email string `relsql:"trim,lower"`
+		Nick  string
+	}
+	ckeys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "users", userTup{}, ckeys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+
+	// nicknames are stored reversed, and read back in order
+	reverse := TransformFunc(func(v interface{}) (interface{}, error) {
+		r := []rune(v.(string))
+		for i, j := 0, len(r)-1; i < j; i, j = i+1, j-1 {
+			r[i], r[j] = r[j], r[i]
+		}
+		return string(r), nil
+	})
+	users := rel.New([]userTup{{1, "  Ann@Example.COM ", "annie"}}, ckeys)
+	if _, err := Insert(db, "users", users, WithTransform("Nick", reverse)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	var email, nick string
+	db.QueryRow("select Email, Nick from users where ID = 1").Scan(&email, &nick)
+	if email != "ann@example.com" || nick != "einna" {
+		t.Errorf("stored values => %q, %q, want %q, %q", email, nick, "ann@example.com", "einna")
+	}
+
+	db.Exec("update users set Email = 'ANN@EXAMPLE.COM ' where ID = 1")
+	r := New(db, "users", userTup{}, ckeys, WithTransform("Nick", reverse))
+	ch := make(chan userTup)
+	r.TupleChan(ch)
+	var res []userTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if want := []userTup{{1, "ann@example.com", "annie"}}; !reflect.DeepEqual(res, want) {
+		t.Errorf("transformed users => %v, want %v", res, want)
+	}
+
+	type badTup struct {
+		ID   int
+		Name string `relsql:"rot13"`
+	}
+	if _, err := (&options{}).fieldTransforms(reflect.TypeOf(badTup{}), colNames(badTup{})); err == nil || !strings.Contains(err.Error(), "rot13") {
+		t.Errorf("unknown transformer => %v", err)
+	}
+}
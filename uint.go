@@ -0,0 +1,63 @@
+package relsql
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+// uintEncoder is implemented by dialects which store unsigned integers that
+// are too large for an int64, which drivers can't bind, differently than as
+// a decimal string.
+type uintEncoder interface {
+	EncodeUint(v uint64) (interface{}, error)
+}
+
+// encodeUint converts an unsigned integer argument into an int64 if it fits,
+// and otherwise into the dialect's representation, which is a decimal string
+// by default, since numeric columns accept them.
+func encodeUint(d Dialect, v uint64) (interface{}, error) {
+	if v <= math.MaxInt64 {
+		return int64(v), nil
+	}
+	if ue, ok := d.(uintEncoder); ok {
+		return ue.EncodeUint(v)
+	}
+	return strconv.FormatUint(v, 10), nil
+}
+
+// uintScanner scans a column into an unsigned integer field, which drivers
+// return as int64, float64, or decimal text.  Values that are negative,
+// fractional, or too large for the field are errors, instead of being
+// wrapped or truncated.
+type uintScanner struct {
+	dst reflect.Value
+}
+
+// Scan implements the sql.Scanner interface
+func (s *uintScanner) Scan(src interface{}) error {
+	var n uint64
+	ok := false
+	switch v := src.(type) {
+	case int64:
+		n, ok = uint64(v), v >= 0
+	case uint64:
+		n, ok = v, true
+	case float64:
+		n, ok = uint64(v), v >= 0 && v < math.MaxUint64 && v == math.Trunc(v)
+	case []byte:
+		var err error
+		n, err = strconv.ParseUint(string(v), 10, 64)
+		ok = err == nil
+	case string:
+		var err error
+		n, err = strconv.ParseUint(v, 10, 64)
+		ok = err == nil
+	}
+	if !ok || s.dst.OverflowUint(n) {
+		return fmt.Errorf("relsql: %v (%T) overflows %v", src, src, s.dst.Type())
+	}
+	s.dst.SetUint(n)
+	return nil
+}
@@ -0,0 +1,105 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// test unsigned integers, which drivers can't bind or return above
+// math.MaxInt64
+func TestUint(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:uint?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type counterTup struct {
+		ID    uint
+		Count uint64
+		Small uint8
+	}
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "counters", counterTup{}, keys, WithDialect(SQLite)); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	counters := []counterTup{{1, math.MaxInt64, 255}, {2, 7, 0}}
+	if _, err := Insert(db, "counters", rel.New(counters, keys), WithDialect(SQLite)); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	table := New(db, "counters", counterTup{}, keys, WithDialect(SQLite))
+	ch := make(chan counterTup)
+	r := table.Restrict(Attribute("Count").GT(uint64(7)))
+	r.TupleChan(ch)
+	var res []counterTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if err := r.Err(); err != nil || fmt.Sprint(res) != "[{1 9223372036854775807 255}]" {
+		t.Errorf("Restrict() => %v, %v", res, err)
+	}
+
+	// values above math.MaxInt64 can't be stored in sqlite
+	big := rel.New([]counterTup{{3, math.MaxUint64, 1}}, keys)
+	if _, err := Insert(db, "counters", big, WithDialect(SQLite)); err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Errorf("Insert() of MaxUint64 => %v, want an overflow", err)
+	}
+
+	// values that don't fit the field fail instead of wrapping
+	if _, err := db.Exec("INSERT INTO counters VALUES (4, -1, 256)"); err != nil {
+		t.Errorf("INSERT => %v", err)
+		return
+	}
+	ch = make(chan counterTup)
+	r = table.Restrict(Attribute("ID").EQ(uint(4)))
+	r.TupleChan(ch)
+	for range ch {
+	}
+	if err := r.Err(); err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Errorf("Err() of negative count => %v, want an overflow", err)
+	}
+
+	var encodeUintTest = []struct {
+		d    Dialect
+		v    interface{}
+		want interface{}
+	}{
+		{ANSI, uint8(3), int64(3)},
+		{ANSI, uint64(math.MaxUint64), "18446744073709551615"},
+		{Oracle, uint(math.MaxInt64), int64(math.MaxInt64)},
+	}
+	for i, tt := range encodeUintTest {
+		if v, err := encodeArg(tt.d, tt.v); err != nil || !reflect.DeepEqual(v, tt.want) {
+			t.Errorf("%d has encodeArg() => %#v, %v, want %#v", i, v, err, tt.want)
+		}
+	}
+	for i, tt := range []struct {
+		src  interface{}
+		want string
+	}{
+		{int64(255), "255"},
+		{[]byte("200"), "200"},
+		{float64(12), "12"},
+		{int64(256), "overflows"},
+		{float64(1.5), "overflows"},
+		{"-3", "overflows"},
+	} {
+		var n uint8
+		err := (&uintScanner{reflect.ValueOf(&n).Elem()}).Scan(tt.src)
+		got := fmt.Sprint(n)
+		if err != nil {
+			got = err.Error()
+		}
+		if !strings.Contains(got, tt.want) {
+			t.Errorf("%d has Scan() => %s, want %s", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package relsql
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// UUID is a universally unique identifier.  Attributes of type UUID are stored
+// in the dialect's native uuid type where there is one, and otherwise as text
+// or binary, according to the dialect's UUIDFormat.  Other uuid types with
+// the same [16]byte representation, such as github.com/google/uuid.UUID, can
+// use the same mapping by registering UUIDCodec for them.
+type UUID [16]byte
+
+// String returns the canonical text form of the uuid, like
+// 6ba7b810-9dad-11d1-80b4-00c04fd430c8.
+func (u UUID) String() string {
+	var b [36]byte
+	hex.Encode(b[0:8], u[0:4])
+	b[8] = '-'
+	hex.Encode(b[9:13], u[4:6])
+	b[13] = '-'
+	hex.Encode(b[14:18], u[6:8])
+	b[18] = '-'
+	hex.Encode(b[19:23], u[8:10])
+	b[23] = '-'
+	hex.Encode(b[24:], u[10:])
+	return string(b[:])
+}
+
+// ParseUUID parses the text form of a uuid, with or without hyphens and
+// braces.
+func ParseUUID(s string) (UUID, error) {
+	var u UUID
+	h := strings.Replace(strings.Trim(s, "{}"), "-", "", -1)
+	if len(h) != 32 {
+		return u, fmt.Errorf("relsql: invalid uuid %q", s)
+	}
+	if _, err := hex.Decode(u[:], []byte(h)); err != nil {
+		return u, fmt.Errorf("relsql: invalid uuid %q: %v", s, err)
+	}
+	return u, nil
+}
+
+// UUIDFormat is how a dialect stores uuids
+type UUIDFormat int
+
+const (
+	// UUIDText stores uuids in their canonical text form, in a CHAR(36)
+	UUIDText UUIDFormat = iota
+
+	// UUIDNative stores uuids in the database's UUID type, which is bound as
+	// text.
+	UUIDNative
+
+	// UUIDBinary stores the 16 bytes of uuids in a BINARY(16)
+	UUIDBinary
+)
+
+// uuidFormatter is implemented by dialects which don't store uuids as text
+type uuidFormatter interface {
+	UUIDFormat() UUIDFormat
+}
+
+// uuidFormat returns the format for uuids in a dialect
+func uuidFormat(d Dialect) UUIDFormat {
+	if f, ok := d.(uuidFormatter); ok {
+		return f.UUIDFormat()
+	}
+	return UUIDText
+}
+
+// UUIDCodec is the codec for UUID, which can also be registered for other
+// [16]byte uuid types.
+var UUIDCodec Codec = uuidCodec{}
+
+// uuidCodec converts uuids according to the dialect's UUIDFormat
+type uuidCodec struct{}
+
+// Encode converts a uuid to text, or to bytes for binary dialects
+func (uuidCodec) Encode(d Dialect, v interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Array || rv.Len() != 16 || rv.Type().Elem().Kind() != reflect.Uint8 {
+		return nil, fmt.Errorf("relsql: %T is not a uuid", v)
+	}
+	var u UUID
+	reflect.Copy(reflect.ValueOf(&u).Elem(), rv)
+	if uuidFormat(d) == UUIDBinary {
+		return u[:], nil
+	}
+	return u.String(), nil
+}
+
+// Decode converts text or bytes into a uuid
+func (uuidCodec) Decode(d Dialect, src interface{}, dst reflect.Value) error {
+	var u UUID
+	switch s := src.(type) {
+	case string:
+		var err error
+		if u, err = ParseUUID(s); err != nil {
+			return err
+		}
+	case []byte:
+		if len(s) == 16 {
+			copy(u[:], s)
+			break
+		}
+		var err error
+		if u, err = ParseUUID(string(s)); err != nil {
+			return err
+		}
+	case nil:
+		return fmt.Errorf("relsql: can't convert NULL to %v", dst.Type())
+	default:
+		return decodeError(src, dst)
+	}
+	reflect.Copy(dst, reflect.ValueOf(u))
+	return nil
+}
+
+// TypeName returns the column type for uuids in the dialect
+func (uuidCodec) TypeName(d Dialect) string {
+	switch uuidFormat(d) {
+	case UUIDNative:
+		return "UUID"
+	case UUIDBinary:
+		return "BINARY(16)"
+	}
+	return "CHAR(36)"
+}
+
+func init() {
+	RegisterCodec(reflect.TypeOf(UUID{}), UUIDCodec)
+}
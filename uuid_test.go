@@ -0,0 +1,117 @@
+package relsql
+
+import (
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"testing"
+)
+
+// testDialect is an ansi dialect with configurable features
+type testDialect struct {
+	ansiDialect
+	uuid     UUIDFormat
+	duration DurationFormat
+}
+
+func (d testDialect) UUIDFormat() UUIDFormat         { return d.uuid }
+func (d testDialect) DurationFormat() DurationFormat { return d.duration }
+
+// test uuid parsing, formatting, and encoding
+func TestUUID(t *testing.T) {
+	const str = "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+	u, err := ParseUUID(str)
+	if err != nil {
+		t.Errorf("ParseUUID() => %v", err)
+	}
+	if u.String() != str {
+		t.Errorf("String() => %v, want %v", u.String(), str)
+	}
+	if u2, _ := ParseUUID("{6BA7B8109DAD11D180B400C04FD430C8}"); u2 != u {
+		t.Errorf("ParseUUID() without hyphens => %v, want %v", u2, u)
+	}
+	if _, err := ParseUUID("6ba7b810"); err == nil {
+		t.Errorf("ParseUUID() of short uuid => nil error")
+	}
+
+	var formatTest = []struct {
+		d        Dialect
+		typeName string
+		encoded  interface{}
+	}{
+		{ANSI, "CHAR(36)", str},
+		{testDialect{uuid: UUIDNative}, "UUID", str},
+		{testDialect{uuid: UUIDBinary}, "BINARY(16)", u[:]},
+		{Postgres, "UUID", str},
+		{MySQL, "BINARY(16)", u[:]},
+	}
+	for i, tt := range formatTest {
+		if typeName := UUIDCodec.TypeName(tt.d); typeName != tt.typeName {
+			t.Errorf("%d has TypeName() => %v, want %v", i, typeName, tt.typeName)
+		}
+		v, err := encodeArg(tt.d, u)
+		if err != nil {
+			t.Errorf("%d has encodeArg() => %v", i, err)
+		}
+		if s, ok := v.(string); ok && s != tt.encoded {
+			t.Errorf("%d has encodeArg() => %v, want %v", i, v, tt.encoded)
+		}
+		if b, ok := v.([]byte); ok && string(b) != string(tt.encoded.([]byte)) {
+			t.Errorf("%d has encodeArg() => %v, want %v", i, v, tt.encoded)
+		}
+	}
+}
+
+// test writing, restricting on, and scanning uuid attributes
+func TestUUIDColumn(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type deviceTup struct {
+		ID   UUID
+		Name string
+	}
+	if err := CreateTable(db, "devices", deviceTup{}, [][]string{[]string{"ID"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	u1, _ := ParseUUID("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	u2, _ := ParseUUID("6ba7b811-9dad-11d1-80b4-00c04fd430c8")
+	devices := rel.New([]deviceTup{{u1, "phone"}, {u2, "tablet"}}, [][]string{[]string{"ID"}})
+	if _, err := Insert(db, "devices", devices); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+
+	var stored string
+	db.QueryRow("select ID from devices where Name = 'tablet'").Scan(&stored)
+	if stored != u2.String() {
+		t.Errorf("stored uuid => %v, want %v", stored, u2.String())
+	}
+
+	tablet := New(db, "devices", deviceTup{}, [][]string{[]string{"ID"}}).Restrict(Attribute("ID").EQ(u2))
+	ch := make(chan deviceTup)
+	tablet.TupleChan(ch)
+	var res []deviceTup
+	for tup := range ch {
+		res = append(res, tup)
+	}
+	if len(res) != 1 || res[0].ID != u2 || res[0].Name != "tablet" {
+		t.Errorf("restricted devices => %v, want %v", res, deviceTup{u2, "tablet"})
+	}
+	if err := tablet.Err(); err != nil {
+		t.Errorf("Err() => %v", err)
+	}
+
+	// a native uuid column isn't retyped when migrating to postgres
+	if _, err := db.Exec("create table native_devices (ID UUID not null, Name text not null)"); err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	if stmts, err := PlanMigration(db, "native_devices", deviceTup{}, nil, WithDialect(Postgres)); len(stmts) != 0 || err != nil {
+		t.Errorf("PlanMigration() of a uuid column => %v, %v, want no statements", stmts, err)
+	}
+}
@@ -0,0 +1,117 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"strings"
+)
+
+// Verbosity is how much of a relation's state its text representations
+// show.  Each level adds to the ones before it.
+type Verbosity int
+
+const (
+	// DefaultVerbosity is each representation's own level: VerbosityHeading
+	// for String, VerbosityKeys for GoString, and VerbositySQL for Describe.
+	DefaultVerbosity Verbosity = iota
+
+	// VerbosityHeading shows the relational expression, with the heading of
+	// each of the relations it reads from.
+	VerbosityHeading
+
+	// VerbosityKeys adds the candidate keys.
+	VerbosityKeys
+
+	// VerbositySQL adds the compiled query, which shows what was pushed down
+	// to the database.  Query arguments are not shown.
+	VerbositySQL
+
+	// VerbosityInternal adds the internal state of the relation, for
+	// debugging relsql itself.
+	VerbosityInternal
+)
+
+// String returns the name of the level
+func (v Verbosity) String() string {
+	switch v {
+	case DefaultVerbosity:
+		return "DefaultVerbosity"
+	case VerbosityHeading:
+		return "VerbosityHeading"
+	case VerbosityKeys:
+		return "VerbosityKeys"
+	case VerbositySQL:
+		return "VerbositySQL"
+	case VerbosityInternal:
+		return "VerbosityInternal"
+	}
+	return "Verbosity(?)"
+}
+
+// WithVerbosity sets the level of detail of the relation's String, GoString
+// and Describe, instead of each of their defaults.
+func WithVerbosity(v Verbosity) Option {
+	return func(o *options) {
+		o.verbosity = v
+	}
+}
+
+// format returns the text representation of the relation at verbosity v,
+// where def is used for DefaultVerbosity.
+func (r1 *sqlTable) format(v, def Verbosity) string {
+	if v == DefaultVerbosity {
+		v = def
+	}
+	str := r1.expr()
+	if v >= VerbosityKeys {
+		str += " keys " + keysText(keyStrings(r1.cKeys))
+	}
+	if v >= VerbositySQL {
+		if q, _, err := r1.queryString(); err == nil {
+			str += " sql: " + q
+		}
+	}
+	if v >= VerbosityInternal {
+		str += " " + r1.internal()
+	}
+	return str
+}
+
+// internal returns the internal state of the relation
+func (r1 *sqlTable) internal() string {
+	return fmt.Sprintf("relsql.sqlTable{sql.DB, %v, %v, %v, %v, %v, %v, where: %s, limit: %d, %v}",
+		r1.src, r1.cols, r1.zero, r1.cKeys, r1.sourceDistinct, r1.opts.distinct, predString(r1.where, &r1.opts), r1.limit, r1.err)
+}
+
+// exprString returns the relational expression of a relation, without the
+// detail of the verbosity of relations from this package, for the text of
+// the operations that read it.
+func exprString(r rel.Relation) string {
+	if r1, ok := r.(*sqlTable); ok {
+		return r1.expr()
+	}
+	return r.String()
+}
+
+// keysText returns the text form of candidate keys, like {A, B} {C}
+func keysText(keys [][]string) string {
+	strs := make([]string, len(keys))
+	for i, k := range keys {
+		strs[i] = "{" + strings.Join(k, ", ") + "}"
+	}
+	return strings.Join(strs, " ")
+}
+
+// relationVerbosity returns the verbosity of the first query in the
+// relation, or DefaultVerbosity if it has none.
+func relationVerbosity(r rel.Relation) Verbosity {
+	v := DefaultVerbosity
+	found := false
+	Inspect(r, func(n Node) bool {
+		if q, ok := n.(*Query); ok && !found {
+			v, found = q.Relation.(*sqlTable).opts.verbosity, true
+		}
+		return !found
+	})
+	return v
+}
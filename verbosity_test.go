@@ -0,0 +1,105 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// test the text representations of relations at each verbosity
+func TestVerbosity(t *testing.T) {
+	type itemTup struct {
+		ID   int
+		Name string
+	}
+	type priceTup struct {
+		ID    int
+		Price int
+	}
+	keys := [][]string{[]string{"ID"}}
+	items := func(v Verbosity) *sqlTable {
+		return New(nil, "items", itemTup{}, keys, WithDialect(SQLite), WithVerbosity(v)).Restrict(Attribute("ID").GT(1)).(*sqlTable)
+	}
+	expr := "σ{ID > 1}(Relation(ID, Name))"
+	var verbosityTest = []struct {
+		v          Verbosity
+		str, goStr string
+	}{
+		{DefaultVerbosity, expr, expr + " keys {ID}"},
+		{VerbosityHeading, expr, expr},
+		{VerbosityKeys, expr + " keys {ID}", expr + " keys {ID}"},
+		{VerbositySQL, expr + " keys {ID} sql: SELECT ID, Name FROM items WHERE ID > ?", expr + " keys {ID} sql: SELECT ID, Name FROM items WHERE ID > ?"},
+	}
+	for i, tt := range verbosityTest {
+		r := items(tt.v)
+		if str := r.String(); str != tt.str {
+			t.Errorf("%d has String() => %q, want %q", i, str, tt.str)
+		}
+		if str := fmt.Sprintf("%#v", r); str != tt.goStr {
+			t.Errorf("%d has GoString() => %q, want %q", i, str, tt.goStr)
+		}
+	}
+	if str := items(VerbosityInternal).String(); !strings.HasPrefix(str, expr+" keys {ID} sql: ") || !strings.Contains(str, "relsql.sqlTable{") {
+		t.Errorf("String() at VerbosityInternal => %q", str)
+	}
+
+	// the relations that an operation reads from are only expressions
+	prices := New(nil, "prices", priceTup{}, keys, WithDialect(SQLite), WithVerbosity(VerbositySQL))
+	join := items(VerbositySQL).Join(prices, struct {
+		ID    int
+		Name  string
+		Price int
+	}{})
+	if str := join.String(); strings.Count(str, "sql:") != 1 || !strings.HasPrefix(str, expr+" ⋈ Relation(ID, Price) keys {ID} sql: SELECT") {
+		t.Errorf("String() of join => %q", str)
+	}
+}
+
+// test the detail of descriptions at each verbosity
+func TestDescribeVerbosity(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:describeverbosity?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	type itemTup struct {
+		ID   int
+		Name string
+	}
+	keys := [][]string{[]string{"ID"}}
+	if err := CreateTable(db, "items", itemTup{}, keys); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	var describeTest = []struct {
+		v      Verbosity
+		has    []string
+		hasNot []string
+	}{
+		{DefaultVerbosity, []string{"keys: {ID}", "sql: SELECT"}, []string{"internal:"}},
+		{VerbosityHeading, []string{"Relation(ID, Name)\n", "  ID int"}, []string{"keys:", "sql:"}},
+		{VerbosityKeys, []string{"keys: {ID}", "cardinality:"}, []string{"dialect:", "sql:"}},
+		{VerbosityInternal, []string{"sql: SELECT", "internal: Relation(ID, Name) keys {ID} sql: SELECT"}, nil},
+	}
+	for i, tt := range describeTest {
+		desc, err := Describe(context.Background(), New(db, "items", itemTup{}, keys, WithVerbosity(tt.v)))
+		if err != nil {
+			t.Errorf("%d has Describe() => %v", i, err)
+			continue
+		}
+		str := desc.String()
+		for _, s := range tt.has {
+			if !strings.Contains(str, s) {
+				t.Errorf("%d has String() => %q, want %q", i, str, s)
+			}
+		}
+		for _, s := range tt.hasNot {
+			if strings.Contains(str, s) {
+				t.Errorf("%d has String() => %q, which has %q", i, str, s)
+			}
+		}
+	}
+}
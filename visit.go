@@ -0,0 +1,308 @@
+package relsql
+
+import (
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sort"
+)
+
+// Node is a node in the expression tree of a relation: a *Query, *Condition,
+// *Table, *Partitions, *SetOp, *Join, *LateralJoin, *Window,
+// *Summary, *Call, *Script, *Client, or *Other.
+type Node interface {
+	node()
+}
+
+// Query is a relation that is compiled into a single sql query.  Projections,
+// renames and restrictions are folded into it, so its children are its
+// conditions and the source that it selects from.
+type Query struct {
+	Relation rel.Relation
+
+	// Columns holds the column that produces each attribute of the heading
+	Columns []Column
+
+	// Where holds the restrictions that are applied in the query
+	Where []*Condition
+
+	// Distinct is true if the query removes duplicate rows
+	Distinct bool
+
+	Source Node
+}
+
+// Column is a column of the source of a query.  Table is the alias of the
+// side of a join that the column comes from, t1 or t2, and is empty for other
+// sources.
+type Column struct {
+	Attribute rel.Attribute
+	Table     string
+	Name      string
+}
+
+// Condition is a restriction in the WHERE clause of a query, along with the
+// columns that the attributes in the predicate refer to.
+type Condition struct {
+	Pred    Pred
+	Columns []Column
+}
+
+// Table is a table in the database
+type Table struct {
+	Name string
+}
+
+// Partitions is the union of the partitions of a table which a query reads
+// from, after the partitions that can't satisfy its restrictions are pruned.
+type Partitions struct {
+	Key    string
+	Tables []*Table
+}
+
+// SetOp is a sql set operation, UNION, EXCEPT or INTERSECT, of two queries
+type SetOp struct {
+	Op          string
+	Left, Right *Query
+}
+
+// Join is the natural join of two queries on their common attributes.  The
+// columns of the left query have the alias t1, and those of the right t2.
+type Join struct {
+	On          []rel.Attribute
+	Left, Right *Query
+}
+
+// LateralJoin is a lateral join, in which the restrictions of the right query
+// refer to the attributes of the left one.  The columns of the left query
+// have the alias lo, and those of the right li.
+type LateralJoin struct {
+	Left, Right *Query
+}
+
+// Window is an operation on a query which is computed with window functions
+// or expressions, like TopN, Running or Bucketize.  The columns of the window
+// have the names of the attributes of the input query, and of any attributes
+// it adds.
+type Window struct {
+	Op    string
+	Input *Query
+}
+
+// Summary is a GROUP BY query which aggregates its input, from Summarize
+type Summary struct {
+	Group []string
+	Aggs  []Agg
+	Input *Query
+}
+
+// Call is a stored procedure or set returning function
+type Call struct {
+	Proc string
+	Args []interface{}
+}
+
+// Script is a query that is preceded by setup statements, from NewFromScript
+type Script struct {
+	Setup []string
+	Query string
+}
+
+// Client is an operation that is evaluated client side, such as a restriction
+// that can't be compiled into sql, a join of relations on different
+// databases, or the union of the shards of a sharded table.
+type Client struct {
+	Relation rel.Relation
+	Op       string
+	Inputs   []Node
+}
+
+// Other is a relation that isn't from this package, whose expression can't be
+// inspected.
+type Other struct {
+	Relation rel.Relation
+}
+
+func (*Query) node()       {}
+func (*Condition) node()   {}
+func (*Table) node()       {}
+func (*Partitions) node()  {}
+func (*SetOp) node()       {}
+func (*Join) node()        {}
+func (*LateralJoin) node() {}
+func (*Window) node()      {}
+func (*Summary) node()     {}
+func (*Call) node()        {}
+func (*Script) node()      {}
+func (*Client) node()      {}
+func (*Other) node()       {}
+
+// A Visitor's Visit method is called for each node encountered by Walk.  If
+// the result visitor w is not nil, Walk visits each of the children of the
+// node with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(n Node) (w Visitor)
+}
+
+// Walk traverses the expression tree of a relation in depth first order.  It
+// starts by calling v.Visit with the root node.
+func Walk(v Visitor, r rel.Relation) {
+	walk(v, newNode(r))
+}
+
+// walk traverses the tree rooted at n
+func walk(v Visitor, n Node) {
+	if v = v.Visit(n); v == nil {
+		return
+	}
+	switch n := n.(type) {
+	case *Query:
+		walk(v, n.Source)
+		for _, c := range n.Where {
+			walk(v, c)
+		}
+	case *Partitions:
+		for _, t := range n.Tables {
+			walk(v, t)
+		}
+	case *SetOp:
+		walk(v, n.Left)
+		walk(v, n.Right)
+	case *Join:
+		walk(v, n.Left)
+		walk(v, n.Right)
+	case *LateralJoin:
+		walk(v, n.Left)
+		walk(v, n.Right)
+	case *Window:
+		walk(v, n.Input)
+	case *Summary:
+		walk(v, n.Input)
+	case *Client:
+		for _, in := range n.Inputs {
+			walk(v, in)
+		}
+	}
+	v.Visit(nil)
+}
+
+// inspector is a Visitor from a function
+type inspector func(Node) bool
+
+// Visit calls the function, and continues with the children if it returns
+// true
+func (f inspector) Visit(n Node) Visitor {
+	if f(n) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses the expression tree of a relation in depth first order,
+// calling f for each node, like Walk.  If f returns true, Inspect continues
+// with the children of the node, followed by a call of f(nil).
+func Inspect(r rel.Relation, f func(Node) bool) {
+	Walk(inspector(f), r)
+}
+
+// Tables returns the sorted names of the tables that a relation reads from.
+// Relations that aren't from this package, and procedures, are not included.
+func Tables(r rel.Relation) []string {
+	seen := make(map[string]bool)
+	var res []string
+	Inspect(r, func(n Node) bool {
+		if t, ok := n.(*Table); ok && !seen[t.Name] {
+			seen[t.Name] = true
+			res = append(res, t.Name)
+		}
+		return true
+	})
+	sort.Strings(res)
+	return res
+}
+
+// newNode returns the root of the expression tree of a relation
+func newNode(r rel.Relation) Node {
+	switch r := r.(type) {
+	case *sqlTable:
+		return r.node()
+	case *clientSide:
+		if r.prev != nil {
+			return &Client{r, r.op, []Node{newNode(r.prev)}}
+		}
+		return &Client{r, r.diags[0].d.Op, []Node{r.input.node()}}
+	case *semiJoin:
+		return &Client{r, "Join", []Node{r.r1.node(), newNode(r.r2)}}
+	case *lateralJoin:
+		return &Client{r, "Lateral", []Node{newNode(r.r1), r.r2.node()}}
+	case *clientOp:
+		var in []Node
+		for _, r2 := range r.inputs {
+			in = append(in, newNode(r2))
+		}
+		return &Client{r, r.op, in}
+	case *orderedRelation:
+		return newNode(r.Relation)
+	case *shardedTable:
+		var in []Node
+		for _, s := range r.shards {
+			if s != nil {
+				in = append(in, newNode(s))
+			}
+		}
+		return &Client{r, "Union", in}
+	}
+	return &Other{r}
+}
+
+// node returns the query node of the relation
+func (r1 *sqlTable) node() *Query {
+	q := &Query{Relation: r1, Distinct: r1.distinct()}
+	e := reflect.TypeOf(r1.zero)
+	for i, c := range r1.cols {
+		q.Columns = append(q.Columns, Column{rel.Attribute(e.Field(i).Name), c.table, c.name})
+	}
+	for _, c := range r1.where {
+		cond := &Condition{Pred: c.pred}
+		seen := make(map[rel.Attribute]bool)
+		for _, att := range c.pred.attributes() {
+			if !seen[att] {
+				seen[att] = true
+				col := c.cols[att]
+				cond.Columns = append(cond.Columns, Column{att, col.table, col.name})
+			}
+		}
+		q.Where = append(q.Where, cond)
+	}
+	switch src := r1.src.(type) {
+	case tableSource:
+		q.Source = &Table{string(src)}
+	case *partitionSource:
+		p := src.prune(r1.where)
+		n := &Partitions{Key: p.key}
+		if !p.empty {
+			for _, part := range p.parts {
+				n.Tables = append(n.Tables, &Table{part.Table})
+			}
+		}
+		q.Source = n
+	case *setSource:
+		q.Source = &SetOp{src.op, src.r1.node(), src.r2.node()}
+	case *joinSource:
+		q.Source = &Join{src.on, src.r1.node(), src.r2.node()}
+	case *lateralSource:
+		q.Source = &LateralJoin{src.r1.node(), src.r2.node()}
+	case *topNSource:
+		q.Source = &Window{"TopN", src.r.node()}
+	case *runningSource:
+		q.Source = &Window{"Running", src.r.node()}
+	case *binSource:
+		q.Source = &Window{"Bin", src.r.node()}
+	case *summarySource:
+		q.Source = &Summary{src.group, src.aggs, src.r.node()}
+	case *procSource:
+		q.Source = &Call{src.name, src.args}
+	case *scriptSource:
+		q.Source = &Script{src.setup, src.query}
+	}
+	return q
+}
@@ -0,0 +1,89 @@
+package relsql
+
+import (
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+)
+
+// test walking the expression trees of relations
+func TestWalk(t *testing.T) {
+	type supplierTup struct {
+		SNO    int
+		SName  string
+		Status int
+		City   string
+	}
+	type shipmentTup struct {
+		SNO int
+		PNO int
+		Qty int
+	}
+	type joinTup struct {
+		SNO    int
+		SName  string
+		Status int
+		City   string
+		PNO    int
+		Qty    int
+	}
+	suppliers := New(nil, "suppliers", supplierTup{}, [][]string{[]string{"SNO"}})
+	shipments := New(nil, "shipments", shipmentTup{}, [][]string{[]string{"SNO", "PNO"}})
+	other := rel.New([]shipmentTup{}, [][]string{[]string{"SNO", "PNO"}})
+
+	var walkTest = []struct {
+		r      rel.Relation
+		nodes  string
+		tables []string
+	}{
+		{suppliers, "Query Table", []string{"suppliers"}},
+		{
+			suppliers.Restrict(Attribute("City").EQ("Paris")).Join(shipments, joinTup{}),
+			"Query Join Query Table Condition Query Table",
+			[]string{"shipments", "suppliers"},
+		},
+		{
+			shipments.Union(shipments.Restrict(Attribute("Qty").GT(100))),
+			"Query SetOp Query Table Query Table Condition",
+			[]string{"shipments"},
+		},
+		{
+			suppliers.Restrict(rel.Attribute("City").EQ("Paris")),
+			"Client Query Table",
+			[]string{"suppliers"},
+		},
+		{other, "Other", nil},
+	}
+	for i, tt := range walkTest {
+		var nodes []string
+		Inspect(tt.r, func(n Node) bool {
+			if n != nil {
+				nodes = append(nodes, strings.TrimPrefix(fmt.Sprintf("%T", n), "*relsql."))
+			}
+			return true
+		})
+		if got := strings.Join(nodes, " "); got != tt.nodes {
+			t.Errorf("%d has nodes %s, want %s", i, got, tt.nodes)
+		}
+		if got := Tables(tt.r); fmt.Sprint(got) != fmt.Sprint(tt.tables) {
+			t.Errorf("%d has Tables() => %v, want %v", i, got, tt.tables)
+		}
+	}
+
+	// the condition refers to the source column of the attribute
+	r := suppliers.Rename(struct {
+		SNO    int
+		SName  string
+		Status int
+		Town   string
+	}{}).Restrict(Attribute("Town").EQ("Paris"))
+	Inspect(r, func(n Node) bool {
+		if c, ok := n.(*Condition); ok {
+			if len(c.Columns) != 1 || c.Columns[0] != (Column{"Town", "", "City"}) {
+				t.Errorf("condition columns => %v, want [{Town  City}]", c.Columns)
+			}
+		}
+		return true
+	})
+}
@@ -0,0 +1,70 @@
+package relsql
+
+import (
+	"context"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// PrefetchResult is the outcome of prefetching one relation.
+type PrefetchResult struct {
+	// Relation holds the prefetched tuples in memory, or is the original
+	// relation if prefetching it failed
+	Relation rel.Relation
+
+	// Tuples is the number of tuples read
+	Tuples int
+
+	// Duration is how long the relation took to read
+	Duration time.Duration
+
+	// Err is the error that prefetching the relation failed with, if any
+	Err error
+}
+
+// Prefetch evaluates the relations concurrently and holds their tuples in
+// memory, for example at startup so that the first requests that use them
+// don't wait on cold queries.  It returns a result for each relation, in the
+// same order, whose Relation can be used in place of the original.  Relations
+// that fail, or that are still being read when ctx is done, are reported with
+// their error, and their result is the original relation.  To keep a copy
+// that outlives the process instead, use a DiskCache or Save.
+func Prefetch(ctx context.Context, rels ...rel.Relation) []PrefetchResult {
+	res := make([]PrefetchResult, len(rels))
+	var wg sync.WaitGroup
+	for i, r := range rels {
+		wg.Add(1)
+		go func(i int, r rel.Relation) {
+			defer wg.Done()
+			res[i] = prefetchRelation(ctx, r)
+		}(i, r)
+	}
+	wg.Wait()
+	return res
+}
+
+// prefetchRelation reads the tuples of a relation into memory
+func prefetchRelation(ctx context.Context, r rel.Relation) PrefetchResult {
+	start := time.Now()
+	tups := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(r.Zero())), 0, 0)
+	err := forEach(r, func(tup reflect.Value) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		tups = reflect.Append(tups, tup)
+		return nil
+	})
+	if err == nil {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return PrefetchResult{Relation: r, Tuples: tups.Len(), Duration: time.Since(start), Err: err}
+	}
+	return PrefetchResult{
+		Relation: rel.New(tups.Interface(), keyStrings(r.CKeys())),
+		Tuples:   tups.Len(),
+		Duration: time.Since(start),
+	}
+}
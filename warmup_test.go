@@ -0,0 +1,72 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"testing"
+)
+
+// test prefetching a set of relations into memory
+func TestPrefetchRelations(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:warmup?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	type warmTup struct {
+		Name  string
+		Count int
+	}
+	if err := CreateTable(db, "warm", warmTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	tups := []warmTup{{"a", 1}, {"b", 2}, {"c", 3}}
+	if _, err := Insert(db, "warm", rel.New(tups, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	r := New(db, "warm", warmTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite))
+	failed := &sqlTable{zero: warmTup{}, err: fmt.Errorf("unreachable")}
+
+	res := Prefetch(context.Background(), r, r.Restrict(Attribute("Count").GT(1)), failed)
+	var prefetchTest = []struct {
+		tuples int
+		failed bool
+	}{
+		{3, false},
+		{2, false},
+		{0, true},
+	}
+	for i, tt := range prefetchTest {
+		if res[i].Tuples != tt.tuples || (res[i].Err != nil) != tt.failed {
+			t.Errorf("%d has Prefetch() => %d tuples, %v, want %d tuples", i, res[i].Tuples, res[i].Err, tt.tuples)
+		}
+	}
+	if _, ok := res[0].Relation.(*sqlTable); ok {
+		t.Errorf("Prefetch() returned a relation that reads from the database")
+	}
+	if res[2].Relation != rel.Relation(failed) {
+		t.Errorf("Prefetch() of a failed relation => %v, want the original", res[2].Relation)
+	}
+	ch := make(chan warmTup)
+	res[0].Relation.TupleChan(ch)
+	var got []warmTup
+	for tup := range ch {
+		got = append(got, tup)
+	}
+	if !reflect.DeepEqual(got, tups) {
+		t.Errorf("prefetched tuples => %v, want %v", got, tups)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if res := Prefetch(ctx, r); res[0].Err != context.Canceled {
+		t.Errorf("Prefetch() with a canceled context => %v, want %v", res[0].Err, context.Canceled)
+	}
+}
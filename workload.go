@@ -0,0 +1,84 @@
+package relsql
+
+import (
+	"fmt"
+	"time"
+)
+
+// Workload is a class of queries, such as interactive or batch, which are
+// scheduled together.  Queries of a class are limited to a number running at
+// once, with the rest waiting in a queue, and each of them runs with the
+// class's settings.  Relations of the same class share its limit, and
+// separate classes keep batch jobs from taking every connection of the pool
+// from interactive requests.
+type Workload struct {
+	// Name identifies the class in errors
+	Name string
+
+	// MaxConcurrent is the most queries of the class that run at once, or
+	// zero for no limit
+	MaxConcurrent int
+
+	// QueueTimeout is how long a query waits for one of the others to
+	// finish before it fails, or zero to wait as long as it takes
+	QueueTimeout time.Duration
+
+	// Settings are statements executed before each query of the class, like
+	// SET LOCAL statement_timeout = '5s' or a resource group hint.  They are
+	// executed in the read transaction of dialects that read in one, and
+	// otherwise on the query's connection, which is then closed instead of
+	// returned to the pool, unless it was given to NewConn.
+	Settings []string
+
+	// slots holds a token for each running query
+	slots chan struct{}
+}
+
+// NewWorkload creates a workload class that runs at most maxConcurrent
+// queries at once, with the settings.
+func NewWorkload(name string, maxConcurrent int, settings ...string) *Workload {
+	w := &Workload{Name: name, MaxConcurrent: maxConcurrent, Settings: settings}
+	if maxConcurrent > 0 {
+		w.slots = make(chan struct{}, maxConcurrent)
+	}
+	return w
+}
+
+// WithWorkload sets the workload class of the relation's queries.
+func WithWorkload(w *Workload) Option {
+	return func(o *options) {
+		o.workload = w
+	}
+}
+
+// acquire waits until a query of the class may run, and returns a function
+// that ends it.  It returns false if cancel is closed while the query is
+// queued, and an error if it waits longer than the queue timeout.  A nil
+// workload runs every query immediately.
+func (w *Workload) acquire(cancel <-chan struct{}) (release func(), ok bool, err error) {
+	if w == nil || w.slots == nil {
+		return func() {}, true, nil
+	}
+	var timeout <-chan time.Time
+	if w.QueueTimeout > 0 {
+		t := time.NewTimer(w.QueueTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	select {
+	case w.slots <- struct{}{}:
+		return func() { <-w.slots }, true, nil
+	case <-cancel:
+		return nil, false, nil
+	case <-timeout:
+		return nil, true, fmt.Errorf("relsql: %s query waited more than %v for one of %d running queries", w.Name, w.QueueTimeout, w.MaxConcurrent)
+	}
+}
+
+// settings returns the statements to execute before a query of the class
+func (w *Workload) settings() []string {
+	if w == nil {
+		return nil
+	}
+	return w.Settings
+}
@@ -0,0 +1,81 @@
+package relsql
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jonlawlor/rel"
+	"strings"
+	"testing"
+	"time"
+)
+
+// test scheduling queries in workload classes
+func TestWorkload(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:workload?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	// reads with settings close their connections, so another one keeps the
+	// in-memory database open
+	keep, err := db.Conn(context.Background())
+	if err != nil {
+		t.Errorf("Conn() => %v", err)
+		return
+	}
+	defer keep.Close()
+
+	type workTup struct {
+		Name string
+	}
+	if err := CreateTable(db, "work", workTup{}, [][]string{[]string{"Name"}}); err != nil {
+		t.Errorf("CreateTable() => %v", err)
+		return
+	}
+	if _, err := Insert(db, "work", rel.New([]workTup{{"a"}, {"b"}}, [][]string{[]string{"Name"}})); err != nil {
+		t.Errorf("Insert() => %v", err)
+		return
+	}
+	read := func(r rel.Relation) (int, error) {
+		ch := make(chan workTup)
+		r.TupleChan(ch)
+		n := 0
+		for range ch {
+			n++
+		}
+		return n, r.Err()
+	}
+
+	batch := NewWorkload("batch", 1, "PRAGMA busy_timeout = 1000")
+	batch.QueueTimeout = 20 * time.Millisecond
+	// a failed read is recorded in its relation, so each read uses a new one
+	newBatch := func() rel.Relation {
+		return New(db, "work", workTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithWorkload(batch))
+	}
+	if n, err := read(newBatch()); n != 2 || err != nil {
+		t.Errorf("batch read => %d, %v, want 2 tuples", n, err)
+	}
+
+	// a query that isn't consumed holds the only slot of the class
+	held := make(chan workTup)
+	cancel := newBatch().TupleChan(held)
+	<-held
+	if _, err := read(newBatch()); err == nil || !strings.Contains(err.Error(), "batch query waited") {
+		t.Errorf("read of a saturated class => %v, want a queue timeout", err)
+	}
+	interactive := New(db, "work", workTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithWorkload(NewWorkload("interactive", 4)))
+	if n, err := read(interactive); n != 2 || err != nil {
+		t.Errorf("interactive read while batch is saturated => %d, %v, want 2 tuples", n, err)
+	}
+	close(cancel)
+	time.Sleep(10 * time.Millisecond)
+	if n, err := read(newBatch()); n != 2 || err != nil {
+		t.Errorf("batch read after the slot is released => %d, %v, want 2 tuples", n, err)
+	}
+
+	bad := New(db, "work", workTup{}, [][]string{[]string{"Name"}}, WithDialect(SQLite), WithWorkload(NewWorkload("bad", 0, "SET x = 1")))
+	if _, err := read(bad); err == nil {
+		t.Errorf("read with an invalid setting succeeded")
+	}
+}
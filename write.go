@@ -0,0 +1,339 @@
+package relsql
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jonlawlor/rel"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Session writes relations into tables within a single transaction.  Writes
+// that belong together can be grouped in savepoints, so that a failure in one
+// group can be rolled back without aborting the whole transaction.
+type Session struct {
+	tx   *sql.Tx
+	opts options
+
+	// savepoints counts the savepoints created in the session, which is used
+	// to give each of them a unique name
+	savepoints int
+}
+
+// Begin starts a write session on the database.
+func Begin(db *sql.DB, opts ...Option) (*Session, error) {
+	s := &Session{}
+	for _, opt := range opts {
+		opt(&s.opts)
+	}
+	if s.opts.dialect == nil {
+		s.opts.dialect = DialectOf(db)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	s.tx = tx
+	return s, nil
+}
+
+// Commit commits the session's transaction
+func (s *Session) Commit() error {
+	return s.tx.Commit()
+}
+
+// Rollback aborts the session's transaction
+func (s *Session) Rollback() error {
+	return s.tx.Rollback()
+}
+
+// exec executes a statement in the session's transaction, and audits it
+func (s *Session) exec(q string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	res, err := s.tx.Exec(q, args...)
+	var rows int64
+	if err == nil {
+		rows, _ = res.RowsAffected()
+	}
+	if aerr := s.opts.audit(q, args, start, rows, err); err == nil {
+		err = aerr
+	}
+	return res, err
+}
+
+// dialect returns the sql dialect of the session
+func (s *Session) dialect() Dialect {
+	return s.opts.dialectOrANSI()
+}
+
+// Insert writes every tuple of r into the table as a new row.  The table's
+// columns are named after the attributes of r.
+// The tuples are written in batches of the session's batch size, and the
+// session's progress function is called after each batch.
+func (s *Session) Insert(tableName string, r rel.Relation) (WriteResult, error) {
+	p := newProgress(s.opts.progress)
+	if err := checkZero(reflect.TypeOf(r.Zero())); err != nil {
+		return p.result(), err
+	}
+	w, err := s.newBatchWriter(tableName, r.Zero(), p)
+	if err != nil {
+		return p.result(), err
+	}
+	defer w.close()
+	err = forEach(r, w.add)
+	if err == nil {
+		err = w.flush()
+	}
+	if err != nil {
+		p.fail(w.n, err)
+	}
+	return p.result(), err
+}
+
+// InsertReturning writes every tuple of r into the table as a new row, like
+// Insert, and returns the written tuples joined with the keys that the
+// database generated for them.  z2 is the type of the resulting tuples, which
+// has all of the attributes of r, plus the generated attributes.  The
+// generated attributes are a candidate key of the result, which makes it
+// possible to load child tables that refer to them.
+//
+// Dialects that support FeatureReturning use an INSERT ... RETURNING
+// statement.  Otherwise the driver's LastInsertId is used, which
+// requires a single generated integer attribute.
+func (s *Session) InsertReturning(tableName string, r rel.Relation, z2 interface{}) (rel.Relation, error) {
+	e1 := reflect.TypeOf(r.Zero())
+	e2 := reflect.TypeOf(z2)
+	if err := checkZero(e1); err != nil {
+		return nil, err
+	}
+	if err := checkZero(e2); err != nil {
+		return nil, err
+	}
+
+	// every attribute of r is copied into the result, so z2 must have it,
+	// with the same type
+	index := make([]int, e1.NumField())
+	for i := range index {
+		f1 := e1.Field(i)
+		f2, ok := e2.FieldByName(f1.Name)
+		if !ok {
+			return nil, fmt.Errorf("relsql: insert returning %v has no attribute %s", e2, f1.Name)
+		}
+		if f2.Type != f1.Type {
+			return nil, fmt.Errorf("relsql: insert returning %v has attribute %s of type %v, want %v", e2, f1.Name, f2.Type, f1.Type)
+		}
+		index[i] = f2.Index[0]
+	}
+
+	// the generated attributes are the ones which are not in r
+	var gen []int
+	var genNames []string
+	for i := 0; i < e2.NumField(); i++ {
+		f := e2.Field(i)
+		if _, ok := e1.FieldByName(f.Name); !ok {
+			gen = append(gen, i)
+			genNames = append(genNames, f.Name)
+		}
+	}
+	if len(gen) == 0 {
+		return nil, fmt.Errorf("relsql: insert returning %v has no generated attributes", e2)
+	}
+	q := insertString(s.dialect(), tableName, colNames(r.Zero()))
+	returning := false
+	if Supports(s.dialect(), FeatureReturning) {
+		returning = true
+		q += " RETURNING " + strings.Join(genNames, ", ")
+	} else if len(gen) != 1 || !isInt(e2.Field(gen[0]).Type) {
+		return nil, fmt.Errorf("relsql: insert returning %v requires a single integer attribute without RETURNING", e2)
+	}
+
+	stmt, err := s.tx.Prepare(q)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+
+	res := reflect.MakeSlice(reflect.SliceOf(e2), 0, 0)
+	values := make([]interface{}, e1.NumField())
+	enums := s.opts.enumCheck(colNames(r.Zero()))
+	ft, err := s.opts.fieldTransforms(e1, colNames(r.Zero()))
+	if err != nil {
+		return nil, err
+	}
+	err = forEach(r, func(tup reflect.Value) error {
+		if err := enums.check(tup); err != nil {
+			return err
+		}
+		for i := range values {
+			v, err := ft.write(i, tup.Field(i).Interface())
+			if err != nil {
+				return err
+			}
+			if v, err = encodeArg(s.dialect(), v); err != nil {
+				return err
+			}
+			values[i] = v
+		}
+		tup2 := reflect.New(e2).Elem()
+		for i, j := range index {
+			tup2.Field(j).Set(tup.Field(i))
+		}
+		start := time.Now()
+		if returning {
+			dest := make([]interface{}, len(gen))
+			for i, j := range gen {
+				dest[i] = scanDest(s.dialect(), tup2.Field(j), s.opts.exactNumerics)
+			}
+			err := stmt.QueryRow(bindArgs(s.dialect(), values)...).Scan(dest...)
+			if aerr := s.opts.audit(q, values, start, 1, err); err == nil {
+				err = aerr
+			}
+			if err != nil {
+				return err
+			}
+		} else {
+			result, err := stmt.Exec(bindArgs(s.dialect(), values)...)
+			if aerr := s.opts.audit(q, values, start, 1, err); err == nil {
+				err = aerr
+			}
+			if err != nil {
+				return err
+			}
+			id, err := result.LastInsertId()
+			if err != nil {
+				return err
+			}
+			setInt(tup2.Field(gen[0]), id)
+		}
+		res = reflect.Append(res, tup2)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// the generated attributes are a key, as are any of the keys of r
+	ckeystr := [][]string{genNames}
+	for _, ck := range r.CKeys() {
+		k := make([]string, len(ck))
+		for i, att := range ck {
+			k[i] = string(att)
+		}
+		ckeystr = append(ckeystr, k)
+	}
+	return rel.New(res.Interface(), ckeystr), nil
+}
+
+// isInt returns true if the type is a signed or unsigned integer
+func isInt(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// setInt sets a signed or unsigned integer value
+func setInt(v reflect.Value, n int64) {
+	switch v.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v.SetUint(uint64(n))
+	default:
+		v.SetInt(n)
+	}
+}
+
+// insertString returns an INSERT statement with a placeholder for each of
+// the columns.
+func insertString(d Dialect, tableName string, cols []column) string {
+	return insertRowsString(d, tableName, cols, 1)
+}
+
+// insertRowsString returns an INSERT statement for the given number of rows,
+// with a placeholder for each of the columns in each row.
+func insertRowsString(d Dialect, tableName string, cols []column, rows int) string {
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.name
+	}
+	values := make([]string, rows)
+	placeholders := make([]string, len(cols))
+	for j := range values {
+		for i := range cols {
+			placeholders[i] = d.Placeholder(j*len(cols) + i + 1)
+		}
+		values[j] = "(" + strings.Join(placeholders, ", ") + ")"
+	}
+	return "INSERT INTO " + quoteTable(d, tableName) + " (" + strings.Join(names, ", ") + ") VALUES " + strings.Join(values, ", ")
+}
+
+// forEach calls f with each of the tuples of r, stopping at the first error.
+func forEach(r rel.Relation, f func(tup reflect.Value) error) error {
+	if err := r.Err(); err != nil {
+		return err
+	}
+	e := reflect.TypeOf(r.Zero())
+	ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, e), 0)
+	cancel := r.TupleChan(ch.Interface())
+	for {
+		tup, ok := ch.Recv()
+		if !ok {
+			break
+		}
+		if err := f(tup); err != nil {
+			close(cancel)
+			return err
+		}
+	}
+	return r.Err()
+}
+
+// Savepoint is a point within a session's transaction that can be rolled back
+// to without aborting the rest of the transaction.
+type Savepoint struct {
+	s    *Session
+	name string
+}
+
+// Savepoint creates a new savepoint in the session.  Savepoints can be
+// nested, and rolling back to one also discards any savepoints created after
+// it.
+func (s *Session) Savepoint() (*Savepoint, error) {
+	s.savepoints++
+	sp := &Savepoint{s, fmt.Sprintf("relsql_sp%d", s.savepoints)}
+	if _, err := s.exec("SAVEPOINT " + sp.name); err != nil {
+		return nil, err
+	}
+	return sp, nil
+}
+
+// Release keeps the writes made since the savepoint, and removes it.
+func (sp *Savepoint) Release() error {
+	_, err := sp.s.exec("RELEASE SAVEPOINT " + sp.name)
+	return err
+}
+
+// Rollback discards the writes made since the savepoint.
+func (sp *Savepoint) Rollback() error {
+	_, err := sp.s.exec("ROLLBACK TO SAVEPOINT " + sp.name)
+	return err
+}
+
+// InSavepoint calls f within a new savepoint.  If f returns an error, the
+// writes made by f are rolled back and the error is returned, but the session
+// can continue with other writes.  Otherwise the savepoint is released.
+func (s *Session) InSavepoint(f func() error) error {
+	sp, err := s.Savepoint()
+	if err != nil {
+		return err
+	}
+	if err := f(); err != nil {
+		if rerr := sp.Rollback(); rerr != nil {
+			return rerr
+		}
+		return err
+	}
+	return sp.Release()
+}
@@ -0,0 +1,337 @@
+package relsql
+
+import (
+	"database/sql"
+	"errors"
+	"github.com/jonlawlor/rel"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// test insert statement generation
+func TestInsertString(t *testing.T) {
+	type partTup struct {
+		PNO   int
+		PName string
+	}
+	want := "INSERT INTO parts (PNO, PName) VALUES (?, ?)"
+	if str := insertString(ANSI, "parts", colNames(partTup{})); str != want {
+		t.Errorf("insertString() => %v, want %v", str, want)
+	}
+}
+
+// test multiple row insert statement generation
+func TestInsertRowsString(t *testing.T) {
+	type partTup struct {
+		PNO   int
+		PName string
+	}
+	want := "INSERT INTO parts (PNO, PName) VALUES (?, ?), (?, ?), (?, ?)"
+	if str := insertRowsString(ANSI, "parts", colNames(partTup{}), 3); str != want {
+		t.Errorf("insertRowsString() => %v, want %v", str, want)
+	}
+}
+
+// test batched inserts and progress reporting
+func TestBatchInsert(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`create table numbers (N integer not null primary key);`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	type numTup struct {
+		N int
+	}
+	nums := make([]numTup, 5)
+	for i := range nums {
+		nums[i].N = i
+	}
+
+	var reports []int64
+	res, err := Insert(db, "numbers", rel.New(nums, [][]string{[]string{"N"}}), WithBatchSize(2), WithProgress(func(p WriteProgress) {
+		reports = append(reports, p.Rows)
+	}))
+	if err != nil {
+		t.Errorf("Insert() => %v", err)
+	}
+	if res.Rows != 5 || res.Batches != 3 || len(res.Errors) != 0 {
+		t.Errorf("Insert() => %+v, want 5 rows in 3 batches", res)
+	}
+
+	// the failed batch of a write is reported in its result
+	res, err = Insert(db, "numbers", rel.New(nums[3:], [][]string{[]string{"N"}}), WithBatchSize(2))
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Rows != 2 || res.Errors[0].Err != err {
+		t.Errorf("Insert() of duplicates => %+v, %v, want a failed batch of 2 rows", res, err)
+	}
+
+	// batches before the failed one are rolled back, so they aren't counted
+	res, err = Insert(db, "numbers", rel.New([]numTup{{5}, {6}, {7}, {0}}, [][]string{[]string{"N"}}), WithBatchSize(2))
+	if err == nil || res.Rows != 0 || res.Batches != 0 || len(res.Errors) != 1 {
+		t.Errorf("Insert() of a late duplicate => %+v, %v, want no rows and a failed batch", res, err)
+	}
+	if want := []int64{2, 4, 5}; !reflect.DeepEqual(reports, want) {
+		t.Errorf("progress => %v, want %v", reports, want)
+	}
+	var n int
+	db.QueryRow("select count(*) from numbers").Scan(&n)
+	if n != 5 {
+		t.Errorf("numbers has %d rows, want %d", n, 5)
+	}
+
+	// partitioning is deterministic, and covers every worker
+	seen := make(map[int]bool)
+	for i := 0; i < 100; i++ {
+		tup := reflect.ValueOf(numTup{i})
+		w := partition(tup, []int{0}, 4)
+		if w != partition(tup, []int{0}, 4) {
+			t.Errorf("partition of %v is not deterministic", i)
+		}
+		seen[w] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("partition uses %d workers, want %d", len(seen), 4)
+	}
+}
+
+// failingRelation is a relation whose stream fails after its first n tuples
+type failingRelation struct {
+	rel.Relation
+	n   int
+	err error
+}
+
+// TupleChan sends the first n tuples, and then fails
+func (r *failingRelation) TupleChan(t interface{}) chan<- struct{} {
+	res := reflect.ValueOf(t)
+	ch := reflect.MakeChan(reflect.ChanOf(reflect.BothDir, reflect.TypeOf(r.Zero())), 0)
+	cancel := r.Relation.TupleChan(ch.Interface())
+	go func() {
+		for i := 0; i < r.n; i++ {
+			tup, ok := ch.Recv()
+			if !ok {
+				break
+			}
+			res.Send(tup)
+		}
+		close(cancel)
+		r.err = errors.New("source failed")
+		res.Close()
+	}()
+	return cancel
+}
+
+// Err returns the error of the stream
+func (r *failingRelation) Err() error {
+	return r.err
+}
+
+// test that workers roll back when the relation they write fails
+func TestInsertWorkersSourceError(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:"+filepath.Join(t.TempDir(), "workers.db")+"?_busy_timeout=5000")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	if _, err := db.Exec(`create table numbers (N integer not null primary key);`); err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	type numTup struct {
+		N int
+	}
+	nums := make([]numTup, 20)
+	for i := range nums {
+		nums[i].N = i
+	}
+	r := &failingRelation{Relation: rel.New(nums, [][]string{[]string{"N"}}), n: 10}
+	res, err := Insert(db, "numbers", r, WithWorkers(2), WithBatchSize(2))
+	if err == nil || err.Error() != "source failed" || res.Rows != 0 {
+		t.Errorf("Insert() => %+v, %v, want the source's error and no rows", res, err)
+	}
+	var n int
+	db.QueryRow("select count(*) from numbers").Scan(&n)
+	if n != 0 {
+		t.Errorf("numbers has %d rows, want %d", n, 0)
+	}
+}
+
+// test that a failed write within a savepoint only rolls back that write
+func TestSavepoint(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`
+	create table colors (Name text not null primary key);
+	create table sizes (Name text not null primary key);
+	`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	type nameTup struct {
+		Name string
+	}
+	colors := rel.New([]nameTup{{"red"}, {"green"}}, [][]string{[]string{"Name"}})
+
+	s, err := Begin(db)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	err = s.InSavepoint(func() error {
+		_, err := s.Insert("colors", colors)
+		return err
+	})
+	if err != nil {
+		t.Errorf("first insert => %v", err)
+	}
+	err = s.InSavepoint(func() error {
+		if _, err := s.Insert("sizes", colors); err != nil {
+			return err
+		}
+		// the duplicate keys fail, which rolls back the sizes as well
+		_, err := s.Insert("colors", colors)
+		return err
+	})
+	if err == nil {
+		t.Errorf("duplicate insert => nil, want error")
+	}
+	if err := s.Commit(); err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	var n int
+	db.QueryRow("select count(*) from colors").Scan(&n)
+	if n != 2 {
+		t.Errorf("colors has %d rows, want %d", n, 2)
+	}
+	db.QueryRow("select count(*) from sizes").Scan(&n)
+	if n != 0 {
+		t.Errorf("sizes has %d rows, want %d", n, 0)
+	}
+}
+
+// test that generated keys are returned with the inserted tuples
+func TestInsertReturning(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+
+	_, err = db.Exec(`create table customers (ID integer primary key autoincrement, Name text not null);`)
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	type nameTup struct {
+		Name string
+	}
+	type customerTup struct {
+		ID   int64
+		Name string
+	}
+	names := rel.New([]nameTup{{"Smith"}, {"Jones"}}, [][]string{[]string{"Name"}})
+
+	for _, d := range []Dialect{ANSI, SQLite} {
+		s, err := Begin(db, WithDialect(d))
+		if err != nil {
+			t.Errorf(err.Error())
+			return
+		}
+		customers, err := s.InsertReturning("customers", names, customerTup{})
+		if err != nil {
+			t.Errorf("%s has InsertReturning() => %v", d.Name(), err)
+			s.Rollback()
+			continue
+		}
+		if err := s.Commit(); err != nil {
+			t.Errorf(err.Error())
+		}
+		if card := rel.Card(customers); card != 2 {
+			t.Errorf("%s has Card() => %v, want %v", d.Name(), card, 2)
+		}
+		ids := rel.Card(customers.Restrict(rel.Attribute("ID").GT(int64(0))))
+		if ids != 2 {
+			t.Errorf("%s has %v generated ids, want %v", d.Name(), ids, 2)
+		}
+	}
+
+	// result types without the attributes of r are rejected before writing
+	type missingTup struct {
+		ID int64
+	}
+	type retypedTup struct {
+		ID   int64
+		Name []byte
+	}
+	for _, z2 := range []interface{}{missingTup{}, retypedTup{}} {
+		s, err := Begin(db, WithDialect(SQLite))
+		if err != nil {
+			t.Errorf(err.Error())
+			return
+		}
+		if _, err := s.InsertReturning("customers", names, z2); err == nil {
+			t.Errorf("InsertReturning() into %T succeeded", z2)
+		}
+		s.Rollback()
+	}
+}
+
+// test that the result of workers only counts the rows that they committed
+func TestInsertWorkerCommitted(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file:workercommitted?mode=memory&cache=shared")
+	if err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+	defer db.Close()
+	if _, err := db.Exec(`create table numbers (N integer not null primary key);`); err != nil {
+		t.Errorf(err.Error())
+		return
+	}
+
+	type numTup struct {
+		N int
+	}
+	write := func(p *progress, nums ...int) error {
+		ch := make(chan reflect.Value)
+		go func() {
+			for _, n := range nums {
+				ch <- reflect.ValueOf(numTup{n})
+			}
+			close(ch)
+		}()
+		return insertWorker(db, "numbers", numTup{}, p, ch, make(chan struct{}), []Option{WithBatchSize(2)})
+	}
+	p := newProgress(nil)
+	if err := write(p, 1, 2, 3); err != nil {
+		t.Errorf("insertWorker() => %v", err)
+	}
+	// the worker that fails has written a batch before, which is rolled back
+	if err := write(p, 4, 5, 1, 6); err == nil {
+		t.Errorf("insertWorker() of a duplicate succeeded")
+	}
+	if res := p.committed(); res.Rows != 3 || res.Batches != 2 || len(res.Errors) != 1 {
+		t.Errorf("committed() => %+v, want 3 rows in 2 batches", res)
+	}
+}